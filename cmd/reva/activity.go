@@ -0,0 +1,141 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/activity"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/utils/resourceid"
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/pkg/errors"
+)
+
+func activityCommand() *command {
+	cmd := newCommand("activity")
+	cmd.Description = func() string { return "list your activities or the activities on a file or folder" }
+	cmd.Usage = func() string { return "Usage: activity [-flags] [<path>]" }
+	endpoint := cmd.String("endpoint", "", "the URL of the activity service, e.g. https://localhost:19001/activity")
+	limit := cmd.Int("limit", 0, "the maximum number of activities to list")
+
+	cmd.ResetFlags = func() {
+		*endpoint, *limit = "", 0
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		if *endpoint == "" {
+			return errors.New("The activity service endpoint is required: " + cmd.Usage())
+		}
+
+		q := url.Values{}
+		if *limit > 0 {
+			q.Set("limit", strconv.Itoa(*limit))
+		}
+		if cmd.NArg() > 0 {
+			ctx := getAuthContext()
+			gatewayClient, err := getClient()
+			if err != nil {
+				return err
+			}
+			res, err := gatewayClient.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{Path: cmd.Args()[0]}})
+			if err != nil {
+				return err
+			}
+			if res.Status.Code != rpc.Code_CODE_OK {
+				return formatError(res.Status)
+			}
+			q.Set("resource", resourceid.OwnCloudResourceIDWrap(res.Info.Id))
+		}
+
+		t, err := readToken()
+		if err != nil {
+			return err
+		}
+		u := *endpoint
+		if len(q) > 0 {
+			u += "?" + q.Encode()
+		}
+		req, err := http.NewRequest(http.MethodGet, u, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(ctxpkg.TokenHeader, t)
+
+		httpRes, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer httpRes.Body.Close()
+		body, err := ioutil.ReadAll(httpRes.Body)
+		if err != nil {
+			return err
+		}
+		if httpRes.StatusCode != http.StatusOK {
+			return fmt.Errorf("error listing activities: %s %s", httpRes.Status, strings.TrimSpace(string(body)))
+		}
+
+		activities := []*activity.Activity{}
+		if err := json.Unmarshal(body, &activities); err != nil {
+			return errors.Wrap(err, "error decoding activities")
+		}
+
+		if len(w) == 0 {
+			tw := table.NewWriter()
+			tw.SetOutputMirror(os.Stdout)
+			tw.AppendHeader(table.Row{"#", "Time", "Type", "Executant", "Path", "ResourceId", "Details"})
+			for _, a := range activities {
+				tw.AppendRow(table.Row{a.ID, a.Timestamp.Local(), a.Type, a.Executant.GetOpaqueId(), a.Path,
+					a.ResourceID.String(), formatDetails(a.Details)})
+			}
+			tw.Render()
+		} else {
+			enc := json.NewEncoder(w[0])
+			if err := enc.Encode(activities); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return cmd
+}
+
+func formatDetails(details map[string]string) string {
+	keys := make([]string, 0, len(details))
+	for k := range details {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+details[k])
+	}
+	return strings.Join(parts, " ")
+}
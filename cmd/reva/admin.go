@@ -0,0 +1,220 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/pkg/errors"
+)
+
+func adminCommand() *command {
+	cmd := newCommand("admin")
+	cmd.Description = func() string { return "manage users and groups through the ocs provisioning API" }
+	cmd.Usage = func() string {
+		return `Usage: admin [-flags] <action> [<args>]
+
+Actions:
+  user-list [<search>]
+  user-get <username>
+  user-create <username>       uses -password, -email, -displayname and -groups
+  user-update <username>       sets -password, -email and -displayname if given
+  user-delete <username>
+  user-groups <username>
+  user-add-group <username> <group>
+  user-remove-group <username> <group>
+  group-list [<search>]
+  group-create <group>         uses -displayname
+  group-members <group>
+  group-delete <group>`
+	}
+	endpoint := cmd.String("endpoint", "", "the URL of the ocs service, e.g. https://localhost:19001/ocs")
+	password := cmd.String("password", "", "the password of the user")
+	email := cmd.String("email", "", "the email address of the user")
+	displayName := cmd.String("displayname", "", "the display name of the user or group")
+	groups := cmd.String("groups", "", "comma separated groups to add a new user to")
+
+	cmd.ResetFlags = func() {
+		*endpoint, *password, *email, *displayName, *groups = "", "", "", "", ""
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 1 || *endpoint == "" {
+			return errors.New("Invalid arguments: " + cmd.Usage())
+		}
+		action, args := cmd.Args()[0], cmd.Args()[1:]
+		nargs := map[string]int{
+			"user-list": 0, "user-get": 1, "user-create": 1, "user-update": 1, "user-delete": 1,
+			"user-groups": 1, "user-add-group": 2, "user-remove-group": 2,
+			"group-list": 0, "group-create": 1, "group-members": 1, "group-delete": 1,
+		}
+		n, ok := nargs[action]
+		if !ok || len(args) < n {
+			return errors.New("Invalid arguments: " + cmd.Usage())
+		}
+		search := ""
+		if n == 0 && len(args) > 0 {
+			search = args[0]
+		}
+		ocs := &ocsClient{endpoint: strings.TrimSuffix(*endpoint, "/")}
+
+		var data interface{}
+		var err error
+		switch action {
+		case "user-list":
+			data, err = ocs.list("/cloud/users", search, "users")
+		case "user-get":
+			var u map[string]interface{}
+			err = ocs.do(http.MethodGet, "/cloud/users/"+url.PathEscape(args[0]), nil, &u)
+			data = u
+		case "user-create":
+			form := url.Values{"userid": {args[0]}, "password": {*password}, "email": {*email}, "displayname": {*displayName}}
+			for _, g := range strings.Split(*groups, ",") {
+				if g = strings.TrimSpace(g); g != "" {
+					form.Add("groups[]", g)
+				}
+			}
+			err = ocs.do(http.MethodPost, "/cloud/users", form, nil)
+		case "user-update":
+			changes := map[string]string{"email": *email, "displayname": *displayName, "password": *password}
+			for _, key := range []string{"email", "displayname", "password"} {
+				if changes[key] == "" {
+					continue
+				}
+				form := url.Values{"key": {key}, "value": {changes[key]}}
+				if err = ocs.do(http.MethodPut, "/cloud/users/"+url.PathEscape(args[0]), form, nil); err != nil {
+					break
+				}
+			}
+		case "user-delete":
+			err = ocs.do(http.MethodDelete, "/cloud/users/"+url.PathEscape(args[0]), nil, nil)
+		case "user-groups":
+			data, err = ocs.list("/cloud/users/"+url.PathEscape(args[0])+"/groups", "", "groups")
+		case "user-add-group":
+			err = ocs.do(http.MethodPost, "/cloud/users/"+url.PathEscape(args[0])+"/groups", url.Values{"groupid": {args[1]}}, nil)
+		case "user-remove-group":
+			err = ocs.do(http.MethodDelete, "/cloud/users/"+url.PathEscape(args[0])+"/groups", url.Values{"groupid": {args[1]}}, nil)
+		case "group-list":
+			data, err = ocs.list("/cloud/groups", search, "groups")
+		case "group-create":
+			err = ocs.do(http.MethodPost, "/cloud/groups", url.Values{"groupid": {args[0]}, "displayname": {*displayName}}, nil)
+		case "group-members":
+			data, err = ocs.list("/cloud/groups/"+url.PathEscape(args[0]), "", "users")
+		case "group-delete":
+			err = ocs.do(http.MethodDelete, "/cloud/groups/"+url.PathEscape(args[0]), nil, nil)
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(w) > 0 {
+			return json.NewEncoder(w[0]).Encode(data)
+		}
+		switch d := data.(type) {
+		case nil:
+			fmt.Println("OK")
+		case []string:
+			for _, s := range d {
+				fmt.Println(s)
+			}
+		case map[string]interface{}:
+			for _, k := range []string{"id", "displayname", "email", "user-type"} {
+				fmt.Printf("%s: %v\n", k, d[k])
+			}
+		}
+		return nil
+	}
+	return cmd
+}
+
+// ocsClient calls the ocs API v2 with the token of the logged in user.
+type ocsClient struct {
+	endpoint string
+}
+
+func (c *ocsClient) do(method, path string, form url.Values, data interface{}) error {
+	t, err := readToken()
+	if err != nil {
+		return err
+	}
+
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	req, err := http.NewRequest(method, c.endpoint+"/v2.php"+path+sep+"format=json", body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(ctxpkg.TokenHeader, t)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	var payload struct {
+		OCS struct {
+			Meta response.Meta   `json:"meta"`
+			Data json.RawMessage `json:"data"`
+		} `json:"ocs"`
+	}
+	if err := json.Unmarshal(b, &payload); err != nil {
+		return fmt.Errorf("unexpected response %s: %s", res.Status, strings.TrimSpace(string(b)))
+	}
+	if payload.OCS.Meta.Status != "ok" {
+		return fmt.Errorf("error %d: %s", payload.OCS.Meta.StatusCode, payload.OCS.Meta.Message)
+	}
+	if data != nil && len(payload.OCS.Data) > 0 {
+		return json.Unmarshal(payload.OCS.Data, data)
+	}
+	return nil
+}
+
+// list returns the names in the given field of the response.
+func (c *ocsClient) list(path, search, field string) ([]string, error) {
+	if search != "" {
+		path += "?search=" + url.QueryEscape(search)
+	}
+	var d map[string][]string
+	if err := c.do(http.MethodGet, path, nil, &d); err != nil {
+		return nil, err
+	}
+	return d[field], nil
+}
@@ -85,6 +85,8 @@ var (
 		appTokensListCommand(),
 		appTokensRemoveCommand(),
 		appTokensCreateCommand(),
+		activityCommand(),
+		adminCommand(),
 		helpCommand(),
 	}
 )
@@ -19,15 +19,70 @@
 package config
 
 import (
+	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 	"github.com/pkg/errors"
 )
 
+// includesKey is the top-level key listing the configuration files
+// the current file is layered on top of.
+const includesKey = "includes"
+
+// secretFilePrefix marks a value that has to be replaced with the content
+// of the referenced file, e.g. file:///run/secrets/jwt.
+const secretFilePrefix = "file://"
+
+// envVarExpr matches ${VAR} and ${VAR:-default} references.
+var envVarExpr = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
 // Read reads the configuration from the reader.
+// Included files are resolved relative to the current working directory.
 func Read(r io.Reader) (map[string]interface{}, error) {
+	v, err := read(r, "", map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	return interpolate(v)
+}
+
+// ReadFile reads the configuration from the given file.
+// Included files are resolved relative to the directory of the file.
+func ReadFile(file string) (map[string]interface{}, error) {
+	v, err := readFile(file, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	return interpolate(v)
+}
+
+func readFile(file string, seen map[string]bool) (map[string]interface{}, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "config: error resolving path "+file)
+	}
+	if seen[abs] {
+		return nil, fmt.Errorf("config: include cycle detected at %s", abs)
+	}
+	seen[abs] = true
+	defer delete(seen, abs)
+
+	fd, err := os.Open(abs)
+	if err != nil {
+		return nil, errors.Wrap(err, "config: error opening file "+abs)
+	}
+	defer fd.Close()
+
+	return read(fd, filepath.Dir(abs), seen)
+}
+
+func read(r io.Reader, dir string, seen map[string]bool) (map[string]interface{}, error) {
 	data, err := ioutil.ReadAll(r)
 	if err != nil {
 		err = errors.Wrap(err, "config: error reading from reader")
@@ -41,5 +96,129 @@ func Read(r io.Reader) (map[string]interface{}, error) {
 		return nil, err
 	}
 
+	includes, err := getIncludes(v)
+	if err != nil {
+		return nil, err
+	}
+	delete(v, includesKey)
+
+	base := map[string]interface{}{}
+	for _, inc := range includes {
+		if !filepath.IsAbs(inc) && dir != "" {
+			inc = filepath.Join(dir, inc)
+		}
+		iv, err := readFile(inc, seen)
+		if err != nil {
+			return nil, err
+		}
+		merge(base, iv)
+	}
+	merge(base, v)
+
+	return base, nil
+}
+
+func getIncludes(v map[string]interface{}) ([]string, error) {
+	raw, ok := v[includesKey]
+	if !ok {
+		return nil, nil
+	}
+
+	switch t := raw.(type) {
+	case string:
+		return []string{t}, nil
+	case []interface{}:
+		includes := make([]string, 0, len(t))
+		for _, i := range t {
+			s, ok := i.(string)
+			if !ok {
+				return nil, fmt.Errorf("config: %s must be a list of strings", includesKey)
+			}
+			includes = append(includes, s)
+		}
+		return includes, nil
+	default:
+		return nil, fmt.Errorf("config: %s must be a string or a list of strings", includesKey)
+	}
+}
+
+// merge deep merges src into dst. Tables are merged recursively,
+// any other value in src overrides the one in dst.
+func merge(dst, src map[string]interface{}) {
+	for k, sv := range src {
+		if sm, ok := sv.(map[string]interface{}); ok {
+			if dm, ok := dst[k].(map[string]interface{}); ok {
+				merge(dm, sm)
+				continue
+			}
+		}
+		dst[k] = sv
+	}
+}
+
+func interpolate(v map[string]interface{}) (map[string]interface{}, error) {
+	for k, val := range v {
+		nv, err := interpolateValue(val)
+		if err != nil {
+			return nil, errors.Wrapf(err, "config: error resolving key %q", k)
+		}
+		v[k] = nv
+	}
 	return v, nil
 }
+
+func interpolateValue(v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case string:
+		return interpolateString(t)
+	case map[string]interface{}:
+		return interpolate(t)
+	case []map[string]interface{}:
+		for i := range t {
+			if _, err := interpolate(t[i]); err != nil {
+				return nil, err
+			}
+		}
+		return t, nil
+	case []interface{}:
+		for i := range t {
+			nv, err := interpolateValue(t[i])
+			if err != nil {
+				return nil, err
+			}
+			t[i] = nv
+		}
+		return t, nil
+	default:
+		return v, nil
+	}
+}
+
+func interpolateString(s string) (string, error) {
+	var missing []string
+	s = envVarExpr.ReplaceAllStringFunc(s, func(m string) string {
+		groups := envVarExpr.FindStringSubmatch(m)
+		if val, ok := os.LookupEnv(groups[1]); ok && (val != "" || groups[2] == "") {
+			return val
+		}
+		if groups[2] != "" {
+			return groups[3]
+		}
+		missing = append(missing, groups[1])
+		return ""
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable(s) not set: %s", strings.Join(missing, ", "))
+	}
+
+	if strings.HasPrefix(s, secretFilePrefix) {
+		file := strings.TrimPrefix(s, secretFilePrefix)
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", errors.Wrap(err, "error reading secret file "+file)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	return s, nil
+}
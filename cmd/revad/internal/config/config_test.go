@@ -0,0 +1,117 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadEnv(t *testing.T) {
+	os.Setenv("REVA_TEST_SECRET", "s3cr3t")
+	defer os.Unsetenv("REVA_TEST_SECRET")
+	os.Unsetenv("REVA_TEST_UNSET")
+
+	v, err := Read(strings.NewReader(`
+[shared]
+jwt_secret = "${REVA_TEST_SECRET}"
+gatewaysvc = "${REVA_TEST_UNSET:-localhost:19000}"
+
+[grpc.services.authprovider]
+drivers = ["a-${REVA_TEST_SECRET}"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shared := v["shared"].(map[string]interface{})
+	if got := shared["jwt_secret"]; got != "s3cr3t" {
+		t.Fatalf("expected %q got %q", "s3cr3t", got)
+	}
+	if got := shared["gatewaysvc"]; got != "localhost:19000" {
+		t.Fatalf("expected %q got %q", "localhost:19000", got)
+	}
+	drivers := v["grpc"].(map[string]interface{})["services"].(map[string]interface{})["authprovider"].(map[string]interface{})["drivers"].([]interface{})
+	if got := drivers[0]; got != "a-s3cr3t" {
+		t.Fatalf("expected %q got %q", "a-s3cr3t", got)
+	}
+
+	if _, err := Read(strings.NewReader(`key = "${REVA_TEST_UNSET}"`)); err == nil {
+		t.Fatal("expected error for unset environment variable")
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reva-config-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	secret := write("secret", "from-file\n")
+	write("base.toml", `
+[shared]
+jwt_secret = "file://`+secret+`"
+gatewaysvc = "localhost:19000"
+
+[grpc]
+address = "0.0.0.0:19000"
+`)
+	main := write("main.toml", `
+includes = ["base.toml"]
+
+[grpc]
+address = "0.0.0.0:29000"
+`)
+
+	v, err := ReadFile(main)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := v[includesKey]; ok {
+		t.Fatalf("expected %q to be removed from the configuration", includesKey)
+	}
+	shared := v["shared"].(map[string]interface{})
+	if got := shared["jwt_secret"]; got != "from-file" {
+		t.Fatalf("expected %q got %q", "from-file", got)
+	}
+	if got := shared["gatewaysvc"]; got != "localhost:19000" {
+		t.Fatalf("expected %q got %q", "localhost:19000", got)
+	}
+	if got := v["grpc"].(map[string]interface{})["address"]; got != "0.0.0.0:29000" {
+		t.Fatalf("expected %q got %q", "0.0.0.0:29000", got)
+	}
+
+	cycle := write("cycle.toml", `includes = ["cycle.toml"]`)
+	if _, err := ReadFile(cycle); err == nil {
+		t.Fatal("expected error for include cycle")
+	}
+}
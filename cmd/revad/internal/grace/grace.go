@@ -44,6 +44,7 @@ type Watcher struct {
 	ss        map[string]Server
 	pidFile   string
 	childPIDs []int
+	reload    func() error
 }
 
 // Option represent an option.
@@ -63,6 +64,14 @@ func WithPIDFile(fn string) Option {
 	}
 }
 
+// WithReloadFunc specifies the function called to reload
+// the configuration in-process.
+func WithReloadFunc(f func() error) Option {
+	return func(w *Watcher) {
+		w.reload = f
+	}
+}
+
 // NewWatcher creates a Watcher.
 func NewWatcher(opts ...Option) *Watcher {
 	w := &Watcher{
@@ -260,7 +269,7 @@ type Server interface {
 // TrapSignals captures the OS signal.
 func (w *Watcher) TrapSignals() {
 	signalCh := make(chan os.Signal, 1024)
-	signal.Notify(signalCh, syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT)
+	signal.Notify(signalCh, syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGUSR1)
 	for {
 		s := <-signalCh
 		w.log.Info().Msgf("%v signal received", s)
@@ -279,6 +288,18 @@ func (w *Watcher) TrapSignals() {
 				w.childPIDs = append(w.childPIDs, p.Pid)
 			}
 
+		case syscall.SIGUSR1:
+			if w.reload == nil {
+				w.log.Warn().Msg("in-process reload not available, ignoring signal")
+				continue
+			}
+			w.log.Info().Msg("reloading configuration in-process...")
+			if err := w.reload(); err != nil {
+				w.log.Error().Err(err).Msg("error reloading configuration")
+			} else {
+				w.log.Info().Msg("configuration reloaded")
+			}
+
 		case syscall.SIGQUIT:
 			w.log.Info().Msg("preparing for a graceful shutdown with deadline of 10 seconds")
 			go func() {
@@ -39,7 +39,7 @@ import (
 var (
 	versionFlag = flag.Bool("version", false, "show version and exit")
 	testFlag    = flag.Bool("t", false, "test configuration and exit")
-	signalFlag  = flag.String("s", "", "send signal to a master process: stop, quit, reload")
+	signalFlag  = flag.String("s", "", "send signal to a master process: stop, quit, reload, reconfigure")
 	configFlag  = flag.String("c", "/etc/revad/revad.toml", "set configuration file")
 	pidFlag     = flag.String("p", "", "pid file. If empty defaults to a random file in the OS temporary directory")
 	logFlag     = flag.String("log", "", "log messages with the given severity or above. One of: [trace, debug, info, warn, error, fatal, panic]")
@@ -61,7 +61,7 @@ func main() {
 	handleVersionFlag()
 	handleSignalFlag()
 
-	confs, err := getConfigs()
+	files, confs, err := getConfigs()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error reading the configuration file(s): %s\n", err.Error())
 		os.Exit(1)
@@ -80,7 +80,7 @@ func main() {
 		os.Exit(0)
 	}
 
-	runConfigs(confs)
+	runConfigs(files, confs)
 }
 
 func handleVersionFlag() {
@@ -105,6 +105,8 @@ func handleSignalFlag() {
 		switch *signalFlag {
 		case "reload":
 			signal = syscall.SIGHUP
+		case "reconfigure":
+			signal = syscall.SIGUSR1
 		case "quit":
 			signal = syscall.SIGQUIT
 		case "stop":
@@ -135,13 +137,13 @@ func handleSignalFlag() {
 	}
 }
 
-func getConfigs() ([]map[string]interface{}, error) {
+func getConfigs() ([]string, []map[string]interface{}, error) {
 	var confs []string
 	// give priority to read from dev-dir
 	if *dirFlag != "" {
 		cfgs, err := getConfigsFromDir(*dirFlag)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		confs = append(confs, cfgs...)
 	} else {
@@ -156,10 +158,10 @@ func getConfigs() ([]map[string]interface{}, error) {
 
 	configs, err := readConfigs(confs)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return configs, nil
+	return confs, configs, nil
 }
 
 func getConfigsFromDir(dir string) (confs []string, err error) {
@@ -182,13 +184,7 @@ func getConfigsFromDir(dir string) (confs []string, err error) {
 func readConfigs(files []string) ([]map[string]interface{}, error) {
 	confs := make([]map[string]interface{}, 0, len(files))
 	for _, conf := range files {
-		fd, err := os.Open(conf)
-		if err != nil {
-			return nil, err
-		}
-		defer fd.Close()
-
-		v, err := config.Read(fd)
+		v, err := config.ReadFile(conf)
 		if err != nil {
 			return nil, err
 		}
@@ -197,21 +193,28 @@ func readConfigs(files []string) ([]map[string]interface{}, error) {
 	return confs, nil
 }
 
-func runConfigs(confs []map[string]interface{}) {
+func runConfigs(files []string, confs []map[string]interface{}) {
 	if len(confs) == 1 {
-		runSingle(confs[0])
+		runSingle(files[0], confs[0])
 		return
 	}
 
-	runMultiple(confs)
+	runMultiple(files, confs)
 }
 
-func runSingle(conf map[string]interface{}) {
+func runSingle(file string, conf map[string]interface{}) {
 	if *pidFlag == "" {
 		*pidFlag = getPidfile()
 	}
 
-	runtime.Run(conf, *pidFlag, *logFlag)
+	runtime.Run(conf, *pidFlag, *logFlag, withConfigFile(file))
+}
+
+// withConfigFile allows the runtime to reload the configuration from the given file.
+func withConfigFile(file string) runtime.Option {
+	return runtime.WithConfigLoader(func() (map[string]interface{}, error) {
+		return config.ReadFile(file)
+	})
 }
 
 func getPidfile() string {
@@ -221,15 +224,15 @@ func getPidfile() string {
 	return path.Join(os.TempDir(), name)
 }
 
-func runMultiple(confs []map[string]interface{}) {
+func runMultiple(files []string, confs []map[string]interface{}) {
 	var wg sync.WaitGroup
-	for _, conf := range confs {
+	for i, conf := range confs {
 		wg.Add(1)
 		pidfile := getPidfile()
-		go func(wg *sync.WaitGroup, conf map[string]interface{}) {
+		go func(wg *sync.WaitGroup, file string, conf map[string]interface{}) {
 			defer wg.Done()
-			runtime.Run(conf, pidfile, *logFlag)
-		}(&wg, conf)
+			runtime.Run(conf, pidfile, *logFlag, withConfigFile(file))
+		}(&wg, files[i], conf)
 	}
 	wg.Wait()
 	os.Exit(0)
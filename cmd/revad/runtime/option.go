@@ -28,8 +28,11 @@ type Option func(o *Options)
 
 // Options defines the available options for this package.
 type Options struct {
-	Logger   *zerolog.Logger
-	Registry registry.Registry
+	Logger       *zerolog.Logger
+	Registry     registry.Registry
+	ConfigLoader func() (map[string]interface{}, error)
+
+	reloadHooks []func(mainConf map[string]interface{}) error
 }
 
 // newOptions initializes the available default options.
@@ -56,3 +59,18 @@ func WithRegistry(r registry.Registry) Option {
 		o.Registry = r
 	}
 }
+
+// WithConfigLoader provides a function to load the configuration again,
+// enabling the in-process reload of the services.
+func WithConfigLoader(f func() (map[string]interface{}, error)) Option {
+	return func(o *Options) {
+		o.ConfigLoader = f
+	}
+}
+
+// withReloadHook adds a function called with the new configuration on reload.
+func withReloadHook(f func(mainConf map[string]interface{}) error) Option {
+	return func(o *Options) {
+		o.reloadHooks = append(o.reloadHooks, f)
+	}
+}
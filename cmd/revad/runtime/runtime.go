@@ -42,10 +42,23 @@ import (
 )
 
 // Run runs a reva server with the given config file and pid file.
-func Run(mainConf map[string]interface{}, pidFile, logLevel string) {
+func Run(mainConf map[string]interface{}, pidFile, logLevel string, opts ...Option) {
 	logConf := parseLogConfOrDie(mainConf["log"], logLevel)
-	logger := initLogger(logConf)
-	RunWithOptions(mainConf, pidFile, WithLogger(logger))
+	level := logger.NewLevel(getLogLevel(logConf))
+	log := initLogger(logConf, level)
+
+	// the log level is the only setting of the log configuration that can be reloaded
+	reloadLogLevel := func(mainConf map[string]interface{}) error {
+		c, err := parseLogConf(mainConf["log"], logLevel)
+		if err != nil {
+			return err
+		}
+		level.Set(getLogLevel(c))
+		return nil
+	}
+
+	opts = append(opts, WithLogger(log), withReloadHook(reloadLogLevel))
+	RunWithOptions(mainConf, pidFile, opts...)
 }
 
 // RunWithOptions runs a reva server with the given config file, pid file and options.
@@ -69,7 +82,7 @@ func RunWithOptions(mainConf map[string]interface{}, pidFile string, opts ...Opt
 		}
 	}
 
-	run(mainConf, coreConf, options.Logger, pidFile)
+	run(mainConf, coreConf, options, pidFile)
 }
 
 type coreConf struct {
@@ -83,7 +96,8 @@ type coreConf struct {
 	TracingService string `mapstructure:"tracing_service"`
 }
 
-func run(mainConf map[string]interface{}, coreConf *coreConf, logger *zerolog.Logger, filename string) {
+func run(mainConf map[string]interface{}, coreConf *coreConf, options Options, filename string) {
+	logger := options.Logger
 	host, _ := os.Hostname()
 	logger.Info().Msgf("host info: %s", host)
 
@@ -93,7 +107,7 @@ func run(mainConf map[string]interface{}, coreConf *coreConf, logger *zerolog.Lo
 	initCPUCount(coreConf, logger)
 
 	servers := initServers(mainConf, logger)
-	watcher, err := initWatcher(logger, filename)
+	watcher, err := initWatcher(logger, filename, getReloadFunc(options, servers))
 	if err != nil {
 		log.Panic(err)
 	}
@@ -111,8 +125,8 @@ func initListeners(watcher *grace.Watcher, servers map[string]grace.Server, log
 	return listeners
 }
 
-func initWatcher(log *zerolog.Logger, filename string) (*grace.Watcher, error) {
-	watcher, err := handlePIDFlag(log, filename)
+func initWatcher(log *zerolog.Logger, filename string, reload func() error) (*grace.Watcher, error) {
+	watcher, err := handlePIDFlag(log, filename, reload)
 	// TODO(labkode): maybe pidfile can be created later on? like once a server is going to be created?
 	if err != nil {
 		log.Error().Err(err).Msg("error creating grace watcher")
@@ -162,8 +176,46 @@ func initCPUCount(conf *coreConf, log *zerolog.Logger) {
 	log.Info().Msgf("running on %d cpus", ncpus)
 }
 
-func initLogger(conf *logConf) *zerolog.Logger {
-	log, err := newLogger(conf)
+// getReloadFunc returns the function reloading the configuration of the running
+// servers in-process, or nil if no configuration loader has been provided.
+func getReloadFunc(options Options, servers map[string]grace.Server) func() error {
+	if options.ConfigLoader == nil {
+		return nil
+	}
+
+	return func() error {
+		mainConf, err := options.ConfigLoader()
+		if err != nil {
+			return errors.Wrap(err, "error loading configuration")
+		}
+
+		var errs []string
+		for _, hook := range options.reloadHooks {
+			if err := hook(mainConf); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+
+		if s, ok := servers["grpc"]; ok {
+			if err := s.(*rgrpc.Server).Reload(mainConf["grpc"]); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		if s, ok := servers["http"]; ok {
+			if err := s.(*rhttp.Server).Reload(mainConf["http"]); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+
+		if len(errs) > 0 {
+			return errors.New(strings.Join(errs, "; "))
+		}
+		return nil
+	}
+}
+
+func initLogger(conf *logConf, level *logger.Level) *zerolog.Logger {
+	log, err := newLogger(conf, level)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error creating logger, exiting ...")
 		os.Exit(1)
@@ -171,10 +223,13 @@ func initLogger(conf *logConf) *zerolog.Logger {
 	return log
 }
 
-func handlePIDFlag(l *zerolog.Logger, pidFile string) (*grace.Watcher, error) {
+func handlePIDFlag(l *zerolog.Logger, pidFile string, reload func() error) (*grace.Watcher, error) {
 	var opts []grace.Option
 	opts = append(opts, grace.WithPIDFile(pidFile))
 	opts = append(opts, grace.WithLogger(l.With().Str("pkg", "grace").Logger()))
+	if reload != nil {
+		opts = append(opts, grace.WithReloadFunc(reload))
+	}
 	w := grace.NewWatcher(opts...)
 	err := w.WritePID()
 	if err != nil {
@@ -204,15 +259,9 @@ func start(mainConf map[string]interface{}, servers map[string]grace.Server, lis
 	watcher.TrapSignals()
 }
 
-func newLogger(conf *logConf) (*zerolog.Logger, error) {
-	// TODO(labkode): use debug level rather than info as default until reaching a stable version.
-	// Helps having smaller development files.
-	if conf.Level == "" {
-		conf.Level = zerolog.DebugLevel.String()
-	}
-
+func newLogger(conf *logConf, level *logger.Level) (*zerolog.Logger, error) {
 	var opts []logger.Option
-	opts = append(opts, logger.WithLevel(conf.Level))
+	opts = append(opts, logger.WithDynamicLevel(level))
 
 	w, err := getWriter(conf.Output)
 	if err != nil {
@@ -322,11 +371,19 @@ func parseSharedConfOrDie(v interface{}) {
 }
 
 func parseLogConfOrDie(v interface{}, logLevel string) *logConf {
-	c := &logConf{}
-	if err := mapstructure.Decode(v, c); err != nil {
+	c, err := parseLogConf(v, logLevel)
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "error decoding log config: %s\n", err.Error())
 		os.Exit(1)
 	}
+	return c
+}
+
+func parseLogConf(v interface{}, logLevel string) (*logConf, error) {
+	c := &logConf{}
+	if err := mapstructure.Decode(v, c); err != nil {
+		return nil, err
+	}
 
 	// if mode is not set, we use console mode, easier for devs
 	if c.Mode == "" {
@@ -338,7 +395,16 @@ func parseLogConfOrDie(v interface{}, logLevel string) *logConf {
 		c.Level = logLevel
 	}
 
-	return c
+	return c, nil
+}
+
+func getLogLevel(conf *logConf) string {
+	// TODO(labkode): use debug level rather than info as default until reaching a stable version.
+	// Helps having smaller development files.
+	if conf.Level == "" {
+		return zerolog.DebugLevel.String()
+	}
+	return conf.Level
 }
 
 type logConf struct {
@@ -0,0 +1,93 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/cs3org/reva/pkg/siteacc/data"
+	"github.com/rs/zerolog"
+)
+
+var (
+	fileFlag   = flag.String("file", "", "the JSON accounts file")
+	driverFlag = flag.String("driver", "sqlite3", "the SQL driver to use; one of: [sqlite3, mysql]")
+	dsnFlag    = flag.String("dsn", "", "the data source name of the SQL database")
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-flags] import|export\n\n", os.Args[0])
+	fmt.Fprintln(flag.CommandLine.Output(), "  import\tcopies all accounts from the JSON file into the SQL database")
+	fmt.Fprintln(flag.CommandLine.Output(), "  export\tcopies all accounts from the SQL database into the JSON file")
+	fmt.Fprintln(flag.CommandLine.Output())
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() != 1 || *fileFlag == "" || *dsnFlag == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0)); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(action string) error {
+	conf := &config.Configuration{}
+	conf.Storage.File.File = *fileFlag
+	conf.Storage.SQL.Driver = *driverFlag
+	conf.Storage.SQL.DSN = *dsnFlag
+
+	log := zerolog.New(os.Stderr).With().Timestamp().Logger()
+
+	fileStorage, err := data.NewFileStorage(conf, &log)
+	if err != nil {
+		return err
+	}
+	sqlStorage, err := data.NewSQLStorage(conf, &log)
+	if err != nil {
+		return err
+	}
+	defer sqlStorage.Close()
+
+	var count int
+	switch action {
+	case "import":
+		count, err = data.MigrateAccounts(fileStorage, sqlStorage)
+	case "export":
+		count, err = data.MigrateAccounts(sqlStorage, fileStorage)
+	default:
+		return fmt.Errorf("unknown action %v", action)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d accounts migrated\n", count)
+	return nil
+}
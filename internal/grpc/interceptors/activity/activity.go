@@ -0,0 +1,338 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package activity provides an interceptor recording the actions users
+// perform on files and shares.
+package activity
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	ocm "github.com/cs3org/go-cs3apis/cs3/sharing/ocm/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
+	"github.com/cs3org/reva/pkg/activity"
+	"github.com/cs3org/reva/pkg/activity/manager/registry"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/rgrpc"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+const (
+	defaultPriority = 300
+	gatewayPrefix   = "/cs3.gateway.v1beta1.GatewayAPI/"
+)
+
+func init() {
+	rgrpc.RegisterUnaryInterceptor("activity", NewUnary)
+}
+
+type config struct {
+	Driver     string                            `mapstructure:"driver" docs:"json;The driver used to store the activities."`
+	Drivers    map[string]map[string]interface{} `mapstructure:"drivers"`
+	GatewaySvc string                            `mapstructure:"gatewaysvc"`
+	Priority   int                               `mapstructure:"priority" docs:"300;The priority of the interceptor."`
+}
+
+func (c *config) init() {
+	if c.Driver == "" {
+		c.Driver = "json"
+	}
+	if c.Priority == 0 {
+		c.Priority = defaultPriority
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+}
+
+func getManager(c *config) (activity.Manager, error) {
+	if f, ok := registry.NewFuncs[c.Driver]; ok {
+		return f(c.Drivers[c.Driver])
+	}
+	return nil, fmt.Errorf("driver not found: %s", c.Driver)
+}
+
+type interceptor struct {
+	c  *config
+	am activity.Manager
+}
+
+// NewUnary returns a new unary interceptor that records the successful file
+// and sharing operations of the gateway in an activity manager.
+func NewUnary(m map[string]interface{}) (grpc.UnaryServerInterceptor, int, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, 0, errors.Wrap(err, "activity: error decoding conf")
+	}
+	c.init()
+
+	am, err := getManager(c)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "activity: error creating the activity manager")
+	}
+	i := &interceptor{c: c, am: am}
+	return i.intercept, c.Priority, nil
+}
+
+func (i *interceptor) intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	// the gateway forwards the requests to the providers, which might run in
+	// the same process, so only the gateway requests are recorded
+	if !strings.HasPrefix(info.FullMethod, gatewayPrefix) {
+		return handler(ctx, req)
+	}
+
+	// the resources and shares removed or moved by the request have to be
+	// looked up before the request is handled
+	var before *provider.ResourceInfo
+	var share *collaboration.Share
+	var publicShare *link.PublicShare
+	switch r := req.(type) {
+	case *provider.DeleteRequest:
+		before = i.stat(ctx, r.Ref)
+	case *provider.MoveRequest:
+		before = i.stat(ctx, r.Source)
+	case *collaboration.RemoveShareRequest:
+		share = i.getShare(ctx, r.Ref)
+	case *link.RemovePublicShareRequest:
+		publicShare = i.getPublicShare(ctx, r.Ref)
+	}
+
+	res, err := handler(ctx, req)
+	if err != nil {
+		return res, err
+	}
+	if s, ok := res.(interface{ GetStatus() *rpc.Status }); !ok || s.GetStatus().GetCode() != rpc.Code_CODE_OK {
+		return res, err
+	}
+
+	var a *activity.Activity
+	switch r := req.(type) {
+	case *provider.CreateContainerRequest:
+		a = i.resourceActivity(activity.TypeCreateContainer, r.Ref, i.stat(ctx, r.Ref))
+	case *provider.TouchFileRequest:
+		a = i.resourceActivity(activity.TypeTouch, r.Ref, i.stat(ctx, r.Ref))
+	case *provider.DeleteRequest:
+		a = i.resourceActivity(activity.TypeDelete, r.Ref, before)
+	case *provider.MoveRequest:
+		a = i.resourceActivity(activity.TypeMove, r.Source, before)
+		dst := i.stat(ctx, r.Destination)
+		if dst != nil {
+			a.Details = map[string]string{"destination": dst.Path}
+			if a.ResourceID == nil {
+				a.ResourceID = dst.Id
+			}
+		} else {
+			a.Details = map[string]string{"destination": r.Destination.GetPath()}
+		}
+	case *provider.RestoreRecycleItemRequest:
+		ref := r.RestoreRef
+		if ref == nil {
+			ref = r.Ref
+		}
+		a = i.resourceActivity(activity.TypeRestoreRecycle, ref, i.stat(ctx, ref))
+		a.Details = map[string]string{"key": r.Key}
+	case *provider.RestoreFileVersionRequest:
+		a = i.resourceActivity(activity.TypeRestoreVersion, r.Ref, i.stat(ctx, r.Ref))
+		a.Details = map[string]string{"key": r.Key}
+	case *collaboration.CreateShareRequest:
+		a = i.shareActivity(activity.TypeShareCreated, res.(*collaboration.CreateShareResponse).Share)
+		if a.Path == "" && r.ResourceInfo != nil {
+			a.Path = r.ResourceInfo.Path
+		}
+	case *collaboration.UpdateShareRequest:
+		a = i.shareActivity(activity.TypeShareUpdated, res.(*collaboration.UpdateShareResponse).Share)
+	case *collaboration.RemoveShareRequest:
+		a = i.shareActivity(activity.TypeShareRemoved, share)
+	case *link.CreatePublicShareRequest:
+		a = i.publicShareActivity(activity.TypeLinkCreated, res.(*link.CreatePublicShareResponse).Share)
+		if r.ResourceInfo != nil {
+			a.Path = r.ResourceInfo.Path
+		}
+	case *link.UpdatePublicShareRequest:
+		a = i.publicShareActivity(activity.TypeLinkUpdated, res.(*link.UpdatePublicShareResponse).Share)
+	case *link.RemovePublicShareRequest:
+		a = i.publicShareActivity(activity.TypeLinkRemoved, publicShare)
+	case *ocm.CreateOCMShareRequest:
+		a = i.ocmShareActivity(activity.TypeFederatedShareCreated, res.(*ocm.CreateOCMShareResponse).Share)
+	default:
+		return res, err
+	}
+
+	i.record(ctx, a)
+	return res, err
+}
+
+func (i *interceptor) record(ctx context.Context, a *activity.Activity) {
+	log := appctx.GetLogger(ctx)
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok {
+		log.Warn().Str("type", a.Type).Msg("activity: no user in context, not recording activity")
+		return
+	}
+	a.Executant = u.Id
+	a.Timestamp = time.Now()
+	if a.Path == "" && a.ResourceID != nil {
+		if info := i.stat(ctx, &provider.Reference{ResourceId: a.ResourceID}); info != nil {
+			a.Path = info.Path
+		}
+	}
+	if err := i.am.Record(ctx, a); err != nil {
+		log.Error().Err(err).Str("type", a.Type).Msg("activity: error recording activity")
+	}
+}
+
+func (i *interceptor) resourceActivity(t string, ref *provider.Reference, info *provider.ResourceInfo) *activity.Activity {
+	a := &activity.Activity{Type: t}
+	if info != nil {
+		a.ResourceID = info.Id
+		a.Path = info.Path
+		return a
+	}
+	// the resource does not exist (anymore)
+	if ref.GetPath() != "" && ref.GetResourceId() == nil {
+		a.Path = ref.GetPath()
+	}
+	a.ResourceID = ref.GetResourceId()
+	return a
+}
+
+func (i *interceptor) shareActivity(t string, s *collaboration.Share) *activity.Activity {
+	a := &activity.Activity{Type: t, Details: map[string]string{}}
+	if s == nil {
+		return a
+	}
+	a.ResourceID = s.ResourceId
+	a.Details["share_id"] = s.GetId().GetOpaqueId()
+	addGranteeDetails(a, s.Grantee)
+	if s.Permissions != nil {
+		a.Details["role"] = conversions.RoleFromResourcePermissions(s.Permissions.Permissions).Name
+	}
+	return a
+}
+
+func (i *interceptor) publicShareActivity(t string, s *link.PublicShare) *activity.Activity {
+	a := &activity.Activity{Type: t, Details: map[string]string{}}
+	if s == nil {
+		return a
+	}
+	a.ResourceID = s.ResourceId
+	a.Details["share_id"] = s.GetId().GetOpaqueId()
+	if s.Permissions != nil {
+		a.Details["role"] = conversions.RoleFromResourcePermissions(s.Permissions.Permissions).Name
+	}
+	if s.Expiration != nil {
+		a.Details["expiration"] = time.Unix(int64(s.Expiration.Seconds), 0).UTC().Format(time.RFC3339)
+	}
+	return a
+}
+
+func (i *interceptor) ocmShareActivity(t string, s *ocm.Share) *activity.Activity {
+	a := &activity.Activity{Type: t, Details: map[string]string{}}
+	if s == nil {
+		return a
+	}
+	a.ResourceID = s.ResourceId
+	a.Details["share_id"] = s.GetId().GetOpaqueId()
+	addGranteeDetails(a, s.Grantee)
+	if s.Permissions != nil {
+		a.Details["role"] = conversions.RoleFromResourcePermissions(s.Permissions.Permissions).Name
+	}
+	return a
+}
+
+func addGranteeDetails(a *activity.Activity, g *provider.Grantee) {
+	if g == nil {
+		return
+	}
+	uid, gid := utils.ExtractGranteeID(g)
+	switch {
+	case uid != nil:
+		a.Details["grantee_type"] = "user"
+		a.Details["grantee"] = uid.OpaqueId
+		a.Details["grantee_idp"] = uid.Idp
+	case gid != nil:
+		a.Details["grantee_type"] = "group"
+		a.Details["grantee"] = gid.OpaqueId
+		a.Details["grantee_idp"] = gid.Idp
+	}
+}
+
+func (i *interceptor) getClient(ctx context.Context) gateway.GatewayAPIClient {
+	client, err := pool.GetGatewayServiceClient(i.c.GatewaySvc)
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("activity: error getting gateway client")
+		return nil
+	}
+	return client
+}
+
+// stat looks up a resource, the errors are only logged as they must not
+// affect the intercepted request.
+func (i *interceptor) stat(ctx context.Context, ref *provider.Reference) *provider.ResourceInfo {
+	client := i.getClient(ctx)
+	if client == nil || ref == nil {
+		return nil
+	}
+	res, err := client.Stat(ctx, &provider.StatRequest{Ref: ref})
+	switch {
+	case err != nil:
+		appctx.GetLogger(ctx).Debug().Err(err).Interface("ref", ref).Msg("activity: error stating resource")
+		return nil
+	case res.Status.Code != rpc.Code_CODE_OK:
+		return nil
+	}
+	return res.Info
+}
+
+func (i *interceptor) getShare(ctx context.Context, ref *collaboration.ShareReference) *collaboration.Share {
+	client := i.getClient(ctx)
+	if client == nil {
+		return nil
+	}
+	res, err := client.GetShare(ctx, &collaboration.GetShareRequest{Ref: ref})
+	if err != nil || res.Status.Code != rpc.Code_CODE_OK {
+		appctx.GetLogger(ctx).Debug().Err(err).Msg("activity: error getting share")
+		return nil
+	}
+	return res.Share
+}
+
+func (i *interceptor) getPublicShare(ctx context.Context, ref *link.PublicShareReference) *link.PublicShare {
+	client := i.getClient(ctx)
+	if client == nil {
+		return nil
+	}
+	res, err := client.GetPublicShare(ctx, &link.GetPublicShareRequest{Ref: ref})
+	if err != nil || res.Status.Code != rpc.Code_CODE_OK {
+		appctx.GetLogger(ctx).Debug().Err(err).Msg("activity: error getting public share")
+		return nil
+	}
+	return res.Share
+}
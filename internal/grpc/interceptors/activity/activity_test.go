@@ -0,0 +1,71 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package activity
+
+import (
+	"context"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/activity"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"google.golang.org/grpc"
+)
+
+type recorder struct {
+	activity.Manager
+	recorded []*activity.Activity
+}
+
+func (r *recorder) Record(ctx context.Context, a *activity.Activity) error {
+	r.recorded = append(r.recorded, a)
+	return nil
+}
+
+func TestInterceptOnlyGatewayRequests(t *testing.T) {
+	ctx := ctxpkg.ContextSetUser(context.Background(), &userpb.User{Id: &userpb.UserId{OpaqueId: "einstein"}})
+	req := &collaboration.CreateShareRequest{ResourceInfo: &provider.ResourceInfo{Path: "/home/file"}}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &collaboration.CreateShareResponse{
+			Status: &rpc.Status{Code: rpc.Code_CODE_OK},
+			Share:  &collaboration.Share{Id: &collaboration.ShareId{OpaqueId: "1"}},
+		}, nil
+	}
+
+	tests := []struct {
+		method   string
+		recorded int
+	}{
+		{"/cs3.gateway.v1beta1.GatewayAPI/CreateShare", 1},
+		{"/cs3.sharing.collaboration.v1beta1.CollaborationAPI/CreateShare", 0},
+	}
+	for _, tt := range tests {
+		r := &recorder{}
+		i := &interceptor{c: &config{}, am: r}
+		if _, err := i.intercept(ctx, req, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler); err != nil {
+			t.Fatal(err)
+		}
+		if len(r.recorded) != tt.recorded {
+			t.Errorf("%s: recorded %d activities, expected %d", tt.method, len(r.recorded), tt.recorded)
+		}
+	}
+}
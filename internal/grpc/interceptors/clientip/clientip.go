@@ -0,0 +1,82 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package clientip
+
+import (
+	"context"
+	"net"
+
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// NewUnary returns a new unary interceptor that reads the IP address of the
+// client from requests of trusted proxies and forwards it to outgoing requests.
+func NewUnary(trusted []*net.IPNet) grpc.UnaryServerInterceptor {
+	interceptor := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(withClientIP(ctx, trusted), req)
+	}
+	return interceptor
+}
+
+// NewStream returns a new server stream interceptor that reads the IP address
+// of the client from requests of trusted proxies and forwards it to outgoing
+// requests.
+func NewStream(trusted []*net.IPNet) grpc.StreamServerInterceptor {
+	interceptor := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		wrapped := newWrappedServerStream(withClientIP(ss.Context(), trusted), ss)
+		return handler(srv, wrapped)
+	}
+	return interceptor
+}
+
+// withClientIP stores the client IP sent by a trusted peer in the context.
+// The header of other peers is ignored as they could send any address.
+func withClientIP(ctx context.Context, trusted []*net.IPNet) context.Context {
+	p, ok := peer.FromContext(ctx)
+	if !ok || !utils.IsTrustedProxy(p.Addr.String(), trusted) {
+		return ctx
+	}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	lst := md.Get(ctxpkg.ClientIPHeader)
+	if len(lst) == 0 || net.ParseIP(lst[0]) == nil {
+		return ctx
+	}
+	ctx = ctxpkg.ContextSetClientIP(ctx, lst[0])
+	return metadata.AppendToOutgoingContext(ctx, ctxpkg.ClientIPHeader, lst[0])
+}
+
+func newWrappedServerStream(ctx context.Context, ss grpc.ServerStream) *wrappedServerStream {
+	return &wrappedServerStream{ServerStream: ss, newCtx: ctx}
+}
+
+type wrappedServerStream struct {
+	grpc.ServerStream
+	newCtx context.Context
+}
+
+func (ss *wrappedServerStream) Context() context.Context {
+	return ss.newCtx
+}
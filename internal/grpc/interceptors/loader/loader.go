@@ -20,6 +20,7 @@ package loader
 
 import (
 	// Load core GRPC services
+	_ "github.com/cs3org/reva/internal/grpc/interceptors/activity"
 	_ "github.com/cs3org/reva/internal/grpc/interceptors/readonly"
 	// Add your own service here
 )
@@ -0,0 +1,79 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package lockapp
+
+import (
+	"context"
+	"net"
+
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// NewUnary returns a new unary interceptor that adds the name of the app
+// holding locks sent by trusted peers to the context.
+func NewUnary(trusted []*net.IPNet) grpc.UnaryServerInterceptor {
+	interceptor := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(withLockApp(ctx, trusted), req)
+	}
+	return interceptor
+}
+
+// NewStream returns a new server stream interceptor that adds the name of
+// the app holding locks sent by trusted peers to the context.
+func NewStream(trusted []*net.IPNet) grpc.StreamServerInterceptor {
+	interceptor := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		wrapped := newWrappedServerStream(withLockApp(ss.Context(), trusted), ss)
+		return handler(srv, wrapped)
+	}
+	return interceptor
+}
+
+// withLockApp stores the app name sent by a trusted peer in the context.
+// The header of other peers is ignored, they could take over the locks of
+// any app.
+func withLockApp(ctx context.Context, trusted []*net.IPNet) context.Context {
+	p, ok := peer.FromContext(ctx)
+	if !ok || !utils.IsTrustedProxy(p.Addr.String(), trusted) {
+		return ctx
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if lst, ok := md[ctxpkg.LockAppHeader]; ok && len(lst) != 0 && lst[0] != "" {
+			ctx = ctxpkg.ContextSetLockApp(ctx, lst[0])
+			ctx = metadata.AppendToOutgoingContext(ctx, ctxpkg.LockAppHeader, lst[0])
+		}
+	}
+	return ctx
+}
+
+func newWrappedServerStream(ctx context.Context, ss grpc.ServerStream) *wrappedServerStream {
+	return &wrappedServerStream{ServerStream: ss, newCtx: ctx}
+}
+
+type wrappedServerStream struct {
+	grpc.ServerStream
+	newCtx context.Context
+}
+
+func (ss *wrappedServerStream) Context() context.Context {
+	return ss.newCtx
+}
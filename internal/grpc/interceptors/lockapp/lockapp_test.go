@@ -0,0 +1,66 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package lockapp
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/utils"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestWithLockApp(t *testing.T) {
+	trusted, err := utils.ParseTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		peer string
+		app  string
+	}{
+		{"trusted peer", "10.1.2.3", "wopi"},
+		{"untrusted peer", "192.168.1.1", ""},
+	}
+	for _, tt := range tests {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(tt.peer), Port: 1234}})
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(ctxpkg.LockAppHeader, "wopi"))
+		ctx = withLockApp(ctx, trusted)
+
+		app, _ := ctxpkg.ContextGetLockApp(ctx)
+		if app != tt.app {
+			t.Errorf("%s: expected app %q, got %q", tt.name, tt.app, app)
+		}
+		md, _ := metadata.FromOutgoingContext(ctx)
+		if forwarded := md.Get(ctxpkg.LockAppHeader); (len(forwarded) > 0) != (tt.app != "") {
+			t.Errorf("%s: unexpected forwarded header %v", tt.name, forwarded)
+		}
+	}
+
+	// requests without a peer are not trusted
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(ctxpkg.LockAppHeader, "wopi"))
+	if _, ok := ctxpkg.ContextGetLockApp(withLockApp(ctx, trusted)); ok {
+		t.Error("expected the app of a request without peer to be ignored")
+	}
+}
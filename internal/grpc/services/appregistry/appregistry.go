@@ -20,6 +20,7 @@ package appregistry
 
 import (
 	"context"
+	"sync"
 
 	"google.golang.org/grpc"
 
@@ -38,6 +39,66 @@ func init() {
 
 type svc struct {
 	reg app.Registry
+	// defaults holds the default app providers set at runtime, by mime type
+	defaults map[string]*registrypb.ProviderInfo
+	mu       sync.RWMutex
+}
+
+func (s *svc) registry() app.Registry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.reg
+}
+
+// Reload replaces the registry with one built from the given configuration.
+// The app providers that registered themselves and the default app providers set at runtime
+// are carried over to the new registry.
+func (s *svc) Reload(m map[string]interface{}) error {
+	c, err := parseConfig(m)
+	if err != nil {
+		return err
+	}
+
+	reg, err := getRegistry(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	configured := map[string]bool{}
+	providers, err := reg.ListProviders(ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range providers {
+		configured[p.Address] = true
+	}
+
+	registered, err := s.reg.ListProviders(ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range registered {
+		if configured[p.Address] {
+			continue
+		}
+		if err := reg.AddProvider(ctx, p); err != nil {
+			return err
+		}
+	}
+
+	for mimeType, p := range s.defaults {
+		if err := reg.SetDefaultProviderForMimeType(ctx, mimeType, p); err != nil {
+			return err
+		}
+	}
+
+	s.reg = reg
+	return nil
 }
 
 func (s *svc) Close() error {
@@ -77,7 +138,8 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 	}
 
 	svc := &svc{
-		reg: reg,
+		reg:      reg,
+		defaults: map[string]*registrypb.ProviderInfo{},
 	}
 
 	return svc, nil
@@ -100,7 +162,7 @@ func getRegistry(c *config) (app.Registry, error) {
 }
 
 func (s *svc) GetAppProviders(ctx context.Context, req *registrypb.GetAppProvidersRequest) (*registrypb.GetAppProvidersResponse, error) {
-	p, err := s.reg.FindProviders(ctx, req.ResourceInfo.MimeType)
+	p, err := s.registry().FindProviders(ctx, req.ResourceInfo.MimeType)
 	if err != nil {
 		return &registrypb.GetAppProvidersResponse{
 			Status: status.NewInternal(ctx, err, "error looking for the app provider"),
@@ -115,7 +177,10 @@ func (s *svc) GetAppProviders(ctx context.Context, req *registrypb.GetAppProvide
 }
 
 func (s *svc) AddAppProvider(ctx context.Context, req *registrypb.AddAppProviderRequest) (*registrypb.AddAppProviderResponse, error) {
+	// changes are made under the write lock, so that a reload cannot lose them
+	s.mu.Lock()
 	err := s.reg.AddProvider(ctx, req.Provider)
+	s.mu.Unlock()
 	if err != nil {
 		return &registrypb.AddAppProviderResponse{
 			Status: status.NewInternal(ctx, err, "error adding the app provider"),
@@ -129,7 +194,7 @@ func (s *svc) AddAppProvider(ctx context.Context, req *registrypb.AddAppProvider
 }
 
 func (s *svc) ListAppProviders(ctx context.Context, req *registrypb.ListAppProvidersRequest) (*registrypb.ListAppProvidersResponse, error) {
-	providers, err := s.reg.ListProviders(ctx)
+	providers, err := s.registry().ListProviders(ctx)
 	if err != nil {
 		return &registrypb.ListAppProvidersResponse{
 			Status: status.NewInternal(ctx, err, "error listing the app providers"),
@@ -144,7 +209,7 @@ func (s *svc) ListAppProviders(ctx context.Context, req *registrypb.ListAppProvi
 }
 
 func (s *svc) ListSupportedMimeTypes(ctx context.Context, req *registrypb.ListSupportedMimeTypesRequest) (*registrypb.ListSupportedMimeTypesResponse, error) {
-	mimeTypes, err := s.reg.ListSupportedMimeTypes(ctx)
+	mimeTypes, err := s.registry().ListSupportedMimeTypes(ctx)
 	if err != nil {
 		return &registrypb.ListSupportedMimeTypesResponse{
 			Status: status.NewInternal(ctx, err, "error listing the supported mime types"),
@@ -166,7 +231,7 @@ func (s *svc) ListSupportedMimeTypes(ctx context.Context, req *registrypb.ListSu
 }
 
 func (s *svc) GetDefaultAppProviderForMimeType(ctx context.Context, req *registrypb.GetDefaultAppProviderForMimeTypeRequest) (*registrypb.GetDefaultAppProviderForMimeTypeResponse, error) {
-	provider, err := s.reg.GetDefaultProviderForMimeType(ctx, req.MimeType)
+	provider, err := s.registry().GetDefaultProviderForMimeType(ctx, req.MimeType)
 	if err != nil {
 		return &registrypb.GetDefaultAppProviderForMimeTypeResponse{
 			Status: status.NewInternal(ctx, err, "error getting the default app provider for the mimetype"),
@@ -181,7 +246,12 @@ func (s *svc) GetDefaultAppProviderForMimeType(ctx context.Context, req *registr
 }
 
 func (s *svc) SetDefaultAppProviderForMimeType(ctx context.Context, req *registrypb.SetDefaultAppProviderForMimeTypeRequest) (*registrypb.SetDefaultAppProviderForMimeTypeResponse, error) {
+	s.mu.Lock()
 	err := s.reg.SetDefaultProviderForMimeType(ctx, req.MimeType, req.Provider)
+	if err == nil {
+		s.defaults[req.MimeType] = req.Provider
+	}
+	s.mu.Unlock()
 	if err != nil {
 		return &registrypb.SetDefaultAppProviderForMimeTypeResponse{
 			Status: status.NewInternal(ctx, err, "error setting the default app provider for the mimetype"),
@@ -329,7 +329,7 @@ func TestNew(t *testing.T) {
 		name      string
 		m         map[string]interface{}
 		providers map[string]interface{}
-		want      svc
+		want      *svc
 		wantErr   interface{}
 	}{
 		{
@@ -366,3 +366,59 @@ func TestNew(t *testing.T) {
 		})
 	}
 }
+
+func TestReload(t *testing.T) {
+	mimeTypes := []map[string]interface{}{
+		{
+			"mime_type":   "text/json",
+			"extension":   "json",
+			"name":        "JSON File",
+			"description": "JSON File",
+		},
+	}
+	conf := map[string]interface{}{
+		"driver":  "static",
+		"drivers": map[string]interface{}{"static": map[string]interface{}{"mime_types": mimeTypes}},
+	}
+
+	s, err := New(conf, nil)
+	if err != nil {
+		t.Fatalf("could not create service error = %v", err)
+	}
+	ss := s.(*svc)
+
+	provider := &registrypb.ProviderInfo{Address: "ip-1", Name: "some Name", MimeTypes: []string{"text/json"}}
+	res, _ := ss.AddAppProvider(context.Background(), &registrypb.AddAppProviderRequest{Provider: provider})
+	assert.Equal(t, rpcv1beta1.Code_CODE_OK, res.Status.Code)
+	setRes, _ := ss.SetDefaultAppProviderForMimeType(context.Background(), &registrypb.SetDefaultAppProviderForMimeTypeRequest{MimeType: "text/json", Provider: provider})
+	assert.Equal(t, rpcv1beta1.Code_CODE_OK, setRes.Status.Code)
+
+	mimeTypes = append(mimeTypes, map[string]interface{}{
+		"mime_type":   "text/xml",
+		"extension":   "xml",
+		"name":        "XML File",
+		"description": "XML File",
+	})
+	conf["drivers"] = map[string]interface{}{"static": map[string]interface{}{"mime_types": mimeTypes}}
+	if err := ss.Reload(conf); err != nil {
+		t.Fatalf("could not reload service error = %v", err)
+	}
+
+	providers, err := ss.ListAppProviders(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ListAppProviders() error = %v", err)
+	}
+	assert.Equal(t, []*registrypb.ProviderInfo{provider}, providers.Providers)
+
+	mimes, err := ss.ListSupportedMimeTypes(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ListSupportedMimeTypes() error = %v", err)
+	}
+	assert.Equal(t, 2, len(mimes.MimeTypes))
+
+	def, err := ss.GetDefaultAppProviderForMimeType(context.Background(), &registrypb.GetDefaultAppProviderForMimeTypeRequest{MimeType: "text/json"})
+	if err != nil {
+		t.Fatalf("GetDefaultAppProviderForMimeType() error = %v", err)
+	}
+	assert.Equal(t, provider.Address, def.Provider.GetAddress())
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/auth"
 	"github.com/cs3org/reva/pkg/auth/manager/registry"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/plugin"
 	"github.com/cs3org/reva/pkg/rgrpc"
@@ -134,6 +135,9 @@ func (s *service) Authenticate(ctx context.Context, req *provider.AuthenticateRe
 	log := appctx.GetLogger(ctx)
 	username := req.ClientId
 	password := req.ClientSecret
+	if otp, ok := req.Opaque.GetMap()[ctxpkg.OTPOpaqueKey]; ok {
+		ctx = ctxpkg.ContextSetOTP(ctx, string(otp.Value))
+	}
 
 	u, scope, err := s.authmgr.Authenticate(ctx, username, password)
 	switch v := err.(type) {
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"net/url"
 	"path"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -58,9 +59,12 @@ import (
 type transferClaims struct {
 	jwt.StandardClaims
 	Target string `json:"target"`
+	// Length is the length an upload was initiated with, the data gateway
+	// rejects uploads exceeding it. 0 means the length is not limited.
+	Length int64 `json:"length,omitempty"`
 }
 
-func (s *svc) sign(_ context.Context, target string) (string, error) {
+func (s *svc) sign(_ context.Context, target string, length int64) (string, error) {
 	// Tus sends a separate request to the datagateway service for every chunk.
 	// For large files, this can take a long time, so we extend the expiration
 	ttl := time.Duration(s.c.TransferExpires) * time.Second
@@ -71,6 +75,7 @@ func (s *svc) sign(_ context.Context, target string) (string, error) {
 			IssuedAt:  time.Now().Unix(),
 		},
 		Target: target,
+		Length: length,
 	}
 
 	t := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), claims)
@@ -512,7 +517,7 @@ func (s *svc) initiateFileDownload(ctx context.Context, req *provider.InitiateFi
 
 			// TODO(labkode): calculate signature of the whole request? we only sign the URI now. Maybe worth https://tools.ietf.org/html/draft-cavage-http-signatures-11
 			target := u.String()
-			token, err := s.sign(ctx, target)
+			token, err := s.sign(ctx, target, 0)
 			if err != nil {
 				return &gateway.InitiateFileDownloadResponse{
 					Status: status.NewInternal(ctx, err, "error creating signature for download"),
@@ -720,7 +725,7 @@ func (s *svc) initiateFileUpload(ctx context.Context, req *provider.InitiateFile
 
 			// TODO(labkode): calculate signature of the whole request? we only sign the URI now. Maybe worth https://tools.ietf.org/html/draft-cavage-http-signatures-11
 			target := u.String()
-			token, err := s.sign(ctx, target)
+			token, err := s.sign(ctx, target, uploadLength(req.Opaque))
 			if err != nil {
 				return &gateway.InitiateFileUploadResponse{
 					Status: status.NewInternal(ctx, err, "error creating signature for upload"),
@@ -739,6 +744,19 @@ func (s *svc) initiateFileUpload(ctx context.Context, req *provider.InitiateFile
 	}, nil
 }
 
+// uploadLength returns the length an upload is initiated with, 0 if it is
+// deferred or invalid.
+func uploadLength(o *types.Opaque) int64 {
+	if o == nil || o.Map["Upload-Length"] == nil {
+		return 0
+	}
+	length, err := strconv.ParseInt(string(o.Map["Upload-Length"].Value), 10, 64)
+	if err != nil || length < 0 {
+		return 0
+	}
+	return length
+}
+
 func (s *svc) GetPath(ctx context.Context, req *provider.GetPathRequest) (*provider.GetPathResponse, error) {
 	statReq := &provider.StatRequest{Ref: &provider.Reference{ResourceId: req.ResourceId}}
 	statRes, err := s.stat(ctx, statReq)
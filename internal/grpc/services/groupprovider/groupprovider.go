@@ -27,6 +27,7 @@ import (
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/group"
 	"github.com/cs3org/reva/pkg/group/manager/registry"
+	"github.com/cs3org/reva/pkg/provisioning"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/mitchellh/mapstructure"
@@ -41,6 +42,8 @@ func init() {
 type config struct {
 	Driver  string                            `mapstructure:"driver"`
 	Drivers map[string]map[string]interface{} `mapstructure:"drivers"`
+	// AdminGroup is the group of the users allowed to provision groups, provisioning is disabled if it is not set
+	AdminGroup string `mapstructure:"admin_group"`
 }
 
 func (c *config) init() {
@@ -79,13 +82,14 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 		return nil, err
 	}
 
-	svc := &service{groupmgr: groupManager}
+	svc := &service{groupmgr: groupManager, adminGroup: c.AdminGroup}
 
 	return svc, nil
 }
 
 type service struct {
-	groupmgr group.Manager
+	groupmgr   group.Manager
+	adminGroup string
 }
 
 func (s *service) Close() error {
@@ -98,6 +102,9 @@ func (s *service) UnprotectedEndpoints() []string {
 
 func (s *service) Register(ss *grpc.Server) {
 	grouppb.RegisterGroupAPIServer(ss, s)
+	if m, ok := s.groupmgr.(group.ManageableManager); ok && s.adminGroup != "" {
+		provisioning.RegisterGroupServer(ss, m, s.adminGroup)
+	}
 }
 
 func (s *service) GetGroup(ctx context.Context, req *grouppb.GetGroupRequest) (*grouppb.GetGroupResponse, error) {
@@ -20,6 +20,7 @@ package ocmproviderauthorizer
 
 import (
 	"context"
+	"sync"
 
 	ocmprovider "github.com/cs3org/go-cs3apis/cs3/ocm/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/errtypes"
@@ -44,6 +45,33 @@ type config struct {
 type service struct {
 	conf *config
 	pa   provider.Authorizer
+	mu   sync.RWMutex
+}
+
+func (s *service) authorizer() provider.Authorizer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pa
+}
+
+// Reload replaces the provider authorizer with one built from the given configuration.
+func (s *service) Reload(m map[string]interface{}) error {
+	c, err := parseConfig(m)
+	if err != nil {
+		return err
+	}
+	c.init()
+
+	pa, err := getProviderAuthorizer(c)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.conf = c
+	s.pa = pa
+	s.mu.Unlock()
+	return nil
 }
 
 func (c *config) init() {
@@ -105,7 +133,7 @@ func (s *service) UnprotectedEndpoints() []string {
 }
 
 func (s *service) GetInfoByDomain(ctx context.Context, req *ocmprovider.GetInfoByDomainRequest) (*ocmprovider.GetInfoByDomainResponse, error) {
-	domainInfo, err := s.pa.GetInfoByDomain(ctx, req.Domain)
+	domainInfo, err := s.authorizer().GetInfoByDomain(ctx, req.Domain)
 	if err != nil {
 		return &ocmprovider.GetInfoByDomainResponse{
 			Status: status.NewInternal(ctx, err, "error getting provider info"),
@@ -119,7 +147,7 @@ func (s *service) GetInfoByDomain(ctx context.Context, req *ocmprovider.GetInfoB
 }
 
 func (s *service) IsProviderAllowed(ctx context.Context, req *ocmprovider.IsProviderAllowedRequest) (*ocmprovider.IsProviderAllowedResponse, error) {
-	err := s.pa.IsProviderAllowed(ctx, req.Provider)
+	err := s.authorizer().IsProviderAllowed(ctx, req.Provider)
 	if err != nil {
 		return &ocmprovider.IsProviderAllowedResponse{
 			Status: status.NewInternal(ctx, err, "error verifying mesh provider"),
@@ -132,7 +160,7 @@ func (s *service) IsProviderAllowed(ctx context.Context, req *ocmprovider.IsProv
 }
 
 func (s *service) ListAllProviders(ctx context.Context, req *ocmprovider.ListAllProvidersRequest) (*ocmprovider.ListAllProvidersResponse, error) {
-	providers, err := s.pa.ListAllProviders(ctx)
+	providers, err := s.authorizer().ListAllProviders(ctx)
 	if err != nil {
 		return &ocmprovider.ListAllProvidersResponse{
 			Status: status.NewInternal(ctx, err, "error retrieving mesh providers"),
@@ -27,6 +27,7 @@ import (
 	"github.com/cs3org/reva/pkg/appctx"
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/passwordpolicy"
 	"github.com/cs3org/reva/pkg/publicshare"
 	"github.com/cs3org/reva/pkg/publicshare/manager/registry"
 	"github.com/cs3org/reva/pkg/rgrpc"
@@ -44,6 +45,7 @@ type config struct {
 	Driver                string                            `mapstructure:"driver"`
 	Drivers               map[string]map[string]interface{} `mapstructure:"drivers"`
 	AllowedPathsForShares []string                          `mapstructure:"allowed_paths_for_shares"`
+	PasswordPolicy        map[string]interface{}            `mapstructure:"password_policy" docs:";The requirements for the passwords of public links, see pkg/passwordpolicy."`
 }
 
 func (c *config) init() {
@@ -56,6 +58,7 @@ type service struct {
 	conf                  *config
 	sm                    publicshare.Manager
 	allowedPathsForShares []*regexp.Regexp
+	passwordPolicy        *passwordpolicy.Policy
 }
 
 func getShareManager(c *config) (publicshare.Manager, error) {
@@ -110,10 +113,16 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 		allowedPathsForShares = append(allowedPathsForShares, regex)
 	}
 
+	passwordPolicy, err := passwordpolicy.New(c.PasswordPolicy)
+	if err != nil {
+		return nil, err
+	}
+
 	service := &service{
 		conf:                  c,
 		sm:                    sm,
 		allowedPathsForShares: allowedPathsForShares,
+		passwordPolicy:        passwordPolicy,
 	}
 
 	return service, nil
@@ -141,6 +150,14 @@ func (s *service) CreatePublicShare(ctx context.Context, req *link.CreatePublicS
 		}, nil
 	}
 
+	if req.Grant.GetPassword() != "" {
+		if err := s.passwordPolicy.Validate(req.Grant.Password); err != nil {
+			return &link.CreatePublicShareResponse{
+				Status: status.NewInvalidArg(ctx, err.Error()),
+			}, nil
+		}
+	}
+
 	u, ok := ctxpkg.ContextGetUser(ctx)
 	if !ok {
 		log.Error().Msg("error getting user from context")
@@ -245,6 +262,14 @@ func (s *service) UpdatePublicShare(ctx context.Context, req *link.UpdatePublicS
 	log := appctx.GetLogger(ctx)
 	log.Info().Str("publicshareprovider", "update").Msg("update public share")
 
+	if req.GetUpdate().GetType() == link.UpdatePublicShareRequest_Update_TYPE_PASSWORD && req.Update.GetGrant().GetPassword() != "" {
+		if err := s.passwordPolicy.Validate(req.Update.Grant.Password); err != nil {
+			return &link.UpdatePublicShareResponse{
+				Status: status.NewInvalidArg(ctx, err.Error()),
+			}, nil
+		}
+	}
+
 	u, ok := ctxpkg.ContextGetUser(ctx)
 	if !ok {
 		log.Error().Msg("error getting user from context")
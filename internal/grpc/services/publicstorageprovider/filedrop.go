@@ -0,0 +1,229 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package publicstorageprovider
+
+import (
+	"context"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ReneKroon/ttlcache/v2"
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typesv1beta1 "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/utils"
+)
+
+const (
+	// uploaderNameKey is the opaque key carrying the name the uploader
+	// entered when uploading to a file drop link.
+	uploaderNameKey = "X-Uploader-Name"
+	// uploadLengthKey is the opaque key carrying the size of an upload.
+	uploadLengthKey = "Upload-Length"
+	// fileDropOpaqueKey marks the upload responses of file drop shares, whose
+	// uploads can't be stated by the uploaders.
+	fileDropOpaqueKey = "file-drop"
+
+	anonymousUploader = "anonymous"
+	maxUploaderName   = 64
+	maxRenameAttempts = 1000
+
+	// fileDropReservationTTL is how long the target and length of an
+	// upload to a file drop share are reserved if it doesn't finish.
+	fileDropReservationTTL = 24 * time.Hour
+)
+
+// isFileDrop tells whether the public share is a file drop, i.e. the
+// recipients may upload files but neither list nor download the content.
+func isFileDrop(share *link.PublicShare) bool {
+	p := share.GetPermissions().GetPermissions()
+	return p != nil && p.InitiateFileUpload && !p.InitiateFileDownload
+}
+
+// uploaderFolder turns the name given by an uploader into a folder name.
+func uploaderFolder(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || !unicode.IsPrint(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if len([]rune(name)) > maxUploaderName {
+		name = strings.TrimSpace(string([]rune(name)[:maxUploaderName]))
+	}
+	if name == "" || name == "." || name == ".." {
+		return anonymousUploader
+	}
+	return name
+}
+
+func opaqueValue(o *typesv1beta1.Opaque, key string) string {
+	if o == nil || o.Map == nil || o.Map[key] == nil || o.Map[key].Decoder != "plain" {
+		return ""
+	}
+	return string(o.Map[key].Value)
+}
+
+// fileDropUpload is a pending upload to a file drop share. The target of the
+// upload is reserved and its length counts towards the quota of the share
+// until the file exists.
+type fileDropUpload struct {
+	share  string
+	length uint64
+}
+
+// initiateFileDropUpload initiates an upload to a file drop link. Existing
+// files and the targets of pending uploads are never overwritten, the upload
+// is renamed instead. The returned status is set if the upload must be
+// rejected.
+func (s *service) initiateFileDropUpload(ctx context.Context, req *provider.InitiateFileUploadRequest, shareInfo *provider.ResourceInfo, relativePath string) (*gateway.InitiateFileUploadResponse, *rpc.Status, error) {
+	if shareInfo.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER || relativePath == "" {
+		return nil, status.NewPermissionDenied(ctx, nil, "file drop shares only accept uploads into the shared folder"), nil
+	}
+
+	// the data gateway rejects uploads exceeding the length signed into
+	// their transfer token, so the length bounds the bytes which are
+	// actually received
+	var length uint64
+	if s.conf.FileDropMaxUploadSize > 0 || s.conf.FileDropQuota > 0 {
+		var err error
+		length, err = strconv.ParseUint(opaqueValue(req.Opaque, uploadLengthKey), 10, 64)
+		if err != nil || length == 0 {
+			// a length of 0 isn't enforced by the data gateway
+			return nil, status.NewInvalidArg(ctx, "a positive upload length is required for file drop shares"), nil
+		}
+		if s.conf.FileDropMaxUploadSize > 0 && length > s.conf.FileDropMaxUploadSize {
+			return nil, status.NewInsufficientStorage(ctx, nil, "the upload exceeds the maximum upload size of the share"), nil
+		}
+	}
+
+	base := shareInfo.Path
+	if s.conf.FileDropUploaderFolders {
+		base = path.Join(base, uploaderFolder(opaqueValue(req.Opaque, uploaderNameKey)))
+	}
+	target := path.Join("/", base, relativePath)
+	if !strings.HasPrefix(target, path.Join("/", shareInfo.Path)+"/") {
+		return nil, status.NewInvalidArg(ctx, "the upload target is outside of the share"), nil
+	}
+	if st, err := s.createParents(ctx, shareInfo.Path, path.Dir(target)); err != nil || st != nil {
+		return nil, st, err
+	}
+
+	// checking the quota and reserving the target must not interleave
+	s.fileDropMu.Lock()
+	defer s.fileDropMu.Unlock()
+
+	if s.conf.FileDropQuota > 0 {
+		pending, err := s.pendingFileDropBytes(ctx, shareInfo.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if shareInfo.Size+pending+length > s.conf.FileDropQuota {
+			return nil, status.NewInsufficientStorage(ctx, nil, "the upload exceeds the quota of the share"), nil
+		}
+	}
+
+	for i := 1; i <= maxRenameAttempts; i++ {
+		candidate := target
+		if i > 1 {
+			candidate = utils.NumberedName(target, i)
+		}
+		if _, err := s.fileDrops.Get(candidate); err == nil {
+			continue
+		}
+		// the storage provider refuses to overwrite files which were
+		// created in the meantime, e.g. through another instance
+		res, err := s.gateway.InitiateFileUpload(ctx, &provider.InitiateFileUploadRequest{
+			Ref:     &provider.Reference{Path: candidate},
+			Opaque:  req.Opaque,
+			Options: &provider.InitiateFileUploadRequest_IfNotExist{IfNotExist: true},
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		switch res.Status.Code {
+		case rpc.Code_CODE_OK:
+			if err := s.fileDrops.Set(candidate, fileDropUpload{share: shareInfo.Path, length: length}); err != nil {
+				return nil, nil, err
+			}
+			return res, nil, nil
+		case rpc.Code_CODE_ALREADY_EXISTS:
+			continue
+		default:
+			return nil, res.Status, nil
+		}
+	}
+	return nil, status.NewAlreadyExists(ctx, nil, "no free name found for the upload"), nil
+}
+
+// pendingFileDropBytes returns the length of the pending uploads to a share
+// and forgets the uploads which finished, whose size is part of the share.
+func (s *service) pendingFileDropBytes(ctx context.Context, share string) (uint64, error) {
+	var pending uint64
+	for target, v := range s.fileDrops.GetItems() {
+		upload := v.(fileDropUpload)
+		if upload.share != share {
+			continue
+		}
+		res, err := s.gateway.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{Path: target}})
+		if err != nil {
+			return 0, err
+		}
+		if res.Status.Code == rpc.Code_CODE_OK {
+			_ = s.fileDrops.Remove(target)
+			continue
+		}
+		pending += upload.length
+	}
+	return pending, nil
+}
+
+// createParents creates the folders between the root of the share and dir.
+func (s *service) createParents(ctx context.Context, root, dir string) (*rpc.Status, error) {
+	rel := strings.TrimPrefix(dir, root)
+	if rel == dir || rel == "" || rel == "/" {
+		return nil, nil
+	}
+	p := root
+	for _, segment := range strings.Split(strings.Trim(rel, "/"), "/") {
+		p = path.Join(p, segment)
+		res, err := s.gateway.CreateContainer(ctx, &provider.CreateContainerRequest{Ref: &provider.Reference{Path: p}})
+		if err != nil {
+			return nil, err
+		}
+		if res.Status.Code != rpc.Code_CODE_OK && res.Status.Code != rpc.Code_CODE_ALREADY_EXISTS {
+			return res.Status, nil
+		}
+	}
+	return nil, nil
+}
+
+func newFileDropCache() *ttlcache.Cache {
+	c := ttlcache.NewCache()
+	_ = c.SetTTL(fileDropReservationTTL)
+	c.SkipTTLExtensionOnHit(true)
+	return c
+}
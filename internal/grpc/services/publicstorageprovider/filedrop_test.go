@@ -0,0 +1,143 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package publicstorageprovider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typesv1beta1 "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"google.golang.org/grpc"
+)
+
+func TestIsFileDrop(t *testing.T) {
+	tests := map[string]struct {
+		permissions *provider.ResourcePermissions
+		expected    bool
+	}{
+		"nil":      {nil, false},
+		"viewer":   {&provider.ResourcePermissions{Stat: true, ListContainer: true, InitiateFileDownload: true}, false},
+		"editor":   {&provider.ResourcePermissions{Stat: true, ListContainer: true, InitiateFileDownload: true, InitiateFileUpload: true}, false},
+		"uploader": {&provider.ResourcePermissions{Stat: true, ListContainer: true, CreateContainer: true, InitiateFileUpload: true}, true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			share := &link.PublicShare{Permissions: &link.PublicSharePermissions{Permissions: tt.permissions}}
+			if got := isFileDrop(share); got != tt.expected {
+				t.Errorf("isFileDrop() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUploaderFolder(t *testing.T) {
+	tests := map[string]string{
+		"":                      anonymousUploader,
+		"  ":                    anonymousUploader,
+		"..":                    anonymousUploader,
+		"Marie Curie":           "Marie Curie",
+		" ../../etc/passwd ":    "....etcpasswd",
+		"back\\slash":           "backslash",
+		"tab\tand\nnewline":     "tabandnewline",
+		strings.Repeat("a", 70): strings.Repeat("a", maxUploaderName),
+	}
+
+	for name, expected := range tests {
+		if got := uploaderFolder(name); got != expected {
+			t.Errorf("uploaderFolder(%q) = %q, expected %q", name, got, expected)
+		}
+	}
+}
+
+// fileDropGateway is a gateway holding the files of a file drop share. Uploads
+// create their file once they are finished.
+type fileDropGateway struct {
+	gateway.GatewayAPIClient
+	files map[string]bool
+}
+
+func (g *fileDropGateway) Stat(ctx context.Context, req *provider.StatRequest, opts ...grpc.CallOption) (*provider.StatResponse, error) {
+	if g.files[req.Ref.Path] {
+		return &provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}}, nil
+	}
+	return &provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_NOT_FOUND}}, nil
+}
+
+func (g *fileDropGateway) InitiateFileUpload(ctx context.Context, req *provider.InitiateFileUploadRequest, opts ...grpc.CallOption) (*gateway.InitiateFileUploadResponse, error) {
+	if req.GetIfNotExist() && g.files[req.Ref.Path] {
+		return &gateway.InitiateFileUploadResponse{Status: &rpc.Status{Code: rpc.Code_CODE_ALREADY_EXISTS}}, nil
+	}
+	return &gateway.InitiateFileUploadResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}}, nil
+}
+
+func uploadRequest(length string) *provider.InitiateFileUploadRequest {
+	return &provider.InitiateFileUploadRequest{Opaque: &typesv1beta1.Opaque{Map: map[string]*typesv1beta1.OpaqueEntry{
+		uploadLengthKey: {Decoder: "plain", Value: []byte(length)},
+	}}}
+}
+
+func TestInitiateFileDropUpload(t *testing.T) {
+	ctx := context.Background()
+	gw := &fileDropGateway{files: map[string]bool{"/drop/report.pdf": true}}
+	s := &service{
+		conf:      &config{FileDropMaxUploadSize: 100, FileDropQuota: 150},
+		gateway:   gw,
+		fileDrops: newFileDropCache(),
+	}
+	defer s.Close()
+	shareInfo := &provider.ResourceInfo{Type: provider.ResourceType_RESOURCE_TYPE_CONTAINER, Path: "/drop", Size: 10}
+
+	// existing files and pending uploads are not overwritten
+	for _, expected := range []string{"/drop/report (2).pdf", "/drop/report (3).pdf"} {
+		_, st, err := s.initiateFileDropUpload(ctx, uploadRequest("50"), shareInfo, "report.pdf")
+		if err != nil || st != nil {
+			t.Fatalf("unexpected error %v %v", err, st)
+		}
+		if _, err := s.fileDrops.Get(expected); err != nil {
+			t.Fatalf("expected %s to be reserved", expected)
+		}
+	}
+
+	// the pending uploads count towards the quota
+	if _, st, _ := s.initiateFileDropUpload(ctx, uploadRequest("50"), shareInfo, "other.pdf"); st.GetCode() != rpc.Code_CODE_INSUFFICIENT_STORAGE {
+		t.Fatalf("expected the quota to be exceeded, got %v", st)
+	}
+	// finished uploads are part of the size of the share
+	gw.files["/drop/report (2).pdf"] = true
+	shareInfo.Size += 50
+	if _, st, _ := s.initiateFileDropUpload(ctx, uploadRequest("50"), shareInfo, "other.pdf"); st.GetCode() != rpc.Code_CODE_INSUFFICIENT_STORAGE {
+		t.Fatalf("expected the quota to be exceeded, got %v", st)
+	}
+	if _, st, err := s.initiateFileDropUpload(ctx, uploadRequest("40"), shareInfo, "other.pdf"); err != nil || st != nil {
+		t.Fatalf("unexpected error %v %v", err, st)
+	}
+
+	if _, st, _ := s.initiateFileDropUpload(ctx, uploadRequest("101"), shareInfo, "big.pdf"); st.GetCode() != rpc.Code_CODE_INSUFFICIENT_STORAGE {
+		t.Fatalf("expected the maximum upload size to be exceeded, got %v", st)
+	}
+	if _, st, _ := s.initiateFileDropUpload(ctx, uploadRequest("0"), shareInfo, "empty.pdf"); st.GetCode() != rpc.Code_CODE_INVALID_ARGUMENT {
+		t.Fatalf("expected a missing length to be rejected, got %v", st)
+	}
+}
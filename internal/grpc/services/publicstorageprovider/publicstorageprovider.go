@@ -23,7 +23,9 @@ import (
 	"encoding/json"
 	"path"
 	"strings"
+	"sync"
 
+	"github.com/ReneKroon/ttlcache/v2"
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
@@ -49,9 +51,12 @@ func init() {
 }
 
 type config struct {
-	MountPath   string `mapstructure:"mount_path"`
-	MountID     string `mapstructure:"mount_id"`
-	GatewayAddr string `mapstructure:"gateway_addr"`
+	MountPath               string `mapstructure:"mount_path"`
+	MountID                 string `mapstructure:"mount_id"`
+	GatewayAddr             string `mapstructure:"gateway_addr"`
+	FileDropUploaderFolders bool   `mapstructure:"file_drop_uploader_folders" docs:"false;Whether uploads to file drop shares are stored in a subfolder named after the uploader."`
+	FileDropMaxUploadSize   uint64 `mapstructure:"file_drop_max_upload_size" docs:"0;The maximum size in bytes of a single upload to a file drop share. Uploads must declare their length if it is set. 0 means unlimited."`
+	FileDropQuota           uint64 `mapstructure:"file_drop_quota" docs:"0;The maximum size in bytes of the content of a file drop share, including pending uploads. Uploads must declare their length if it is set. 0 means unlimited."`
 }
 
 type service struct {
@@ -59,10 +64,14 @@ type service struct {
 	mountPath string
 	mountID   string
 	gateway   gateway.GatewayAPIClient
+
+	// fileDrops holds the pending uploads to file drop shares by target
+	fileDrops  *ttlcache.Cache
+	fileDropMu sync.Mutex
 }
 
 func (s *service) Close() error {
-	return nil
+	return s.fileDrops.Close()
 }
 
 func (s *service) UnprotectedEndpoints() []string {
@@ -102,13 +111,14 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 		mountPath: mountPath,
 		mountID:   mountID,
 		gateway:   gateway,
+		fileDrops: newFileDropCache(),
 	}
 
 	return service, nil
 }
 
 func (s *service) SetArbitraryMetadata(ctx context.Context, req *provider.SetArbitraryMetadataRequest) (*provider.SetArbitraryMetadataResponse, error) {
-	ref, _, _, st, err := s.translatePublicRefToCS3Ref(ctx, req.Ref)
+	ref, _, ls, st, err := s.translatePublicRefToCS3Ref(ctx, req.Ref)
 	switch {
 	case err != nil:
 		return nil, err
@@ -116,6 +126,10 @@ func (s *service) SetArbitraryMetadata(ctx context.Context, req *provider.SetArb
 		return &provider.SetArbitraryMetadataResponse{
 			Status: st,
 		}, nil
+	case isFileDrop(ls):
+		return &provider.SetArbitraryMetadataResponse{
+			Status: status.NewPermissionDenied(ctx, nil, "file drop shares do not allow to set metadata"),
+		}, nil
 	}
 	return s.gateway.SetArbitraryMetadata(ctx, &provider.SetArbitraryMetadataRequest{Opaque: req.Opaque, Ref: ref, ArbitraryMetadata: req.ArbitraryMetadata})
 }
@@ -260,7 +274,12 @@ func (s *service) initiateFileDownload(ctx context.Context, req *provider.Initia
 }
 
 func (s *service) InitiateFileUpload(ctx context.Context, req *provider.InitiateFileUploadRequest) (*provider.InitiateFileUploadResponse, error) {
-	cs3Ref, _, ls, st, err := s.translatePublicRefToCS3Ref(ctx, req.Ref)
+	tkn, relativePath, err := s.unwrap(ctx, req.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	ls, shareInfo, st, err := s.resolveToken(ctx, tkn)
 	switch {
 	case err != nil:
 		return nil, err
@@ -273,16 +292,36 @@ func (s *service) InitiateFileUpload(ctx context.Context, req *provider.Initiate
 			Status: status.NewPermissionDenied(ctx, nil, "share does not grant InitiateFileUpload permission"),
 		}, nil
 	}
-	uReq := &provider.InitiateFileUploadRequest{
-		Ref:    cs3Ref,
-		Opaque: req.Opaque,
-	}
 
-	uRes, err := s.gateway.InitiateFileUpload(ctx, uReq)
-	if err != nil {
-		return &provider.InitiateFileUploadResponse{
-			Status: status.NewInternal(ctx, err, "gateway: error calling InitiateFileUpload"),
-		}, nil
+	var uRes *gateway.InitiateFileUploadResponse
+	if isFileDrop(ls) {
+		uRes, st, err = s.initiateFileDropUpload(ctx, req, shareInfo, relativePath)
+		switch {
+		case err != nil:
+			return &provider.InitiateFileUploadResponse{
+				Status: status.NewInternal(ctx, err, "error initiating the file drop upload"),
+			}, nil
+		case st != nil:
+			return &provider.InitiateFileUploadResponse{
+				Status: st,
+			}, nil
+		}
+	} else {
+		p := shareInfo.Path
+		if shareInfo.Type != provider.ResourceType_RESOURCE_TYPE_FILE {
+			p = path.Join("/", shareInfo.Path, relativePath)
+		}
+		uReq := &provider.InitiateFileUploadRequest{
+			Ref:    &provider.Reference{Path: p},
+			Opaque: req.Opaque,
+		}
+
+		uRes, err = s.gateway.InitiateFileUpload(ctx, uReq)
+		if err != nil {
+			return &provider.InitiateFileUploadResponse{
+				Status: status.NewInternal(ctx, err, "gateway: error calling InitiateFileUpload"),
+			}, nil
+		}
 	}
 
 	if uRes.Status.Code != rpc.Code_CODE_OK {
@@ -311,6 +350,11 @@ func (s *service) InitiateFileUpload(ctx context.Context, req *provider.Initiate
 		Status:    uRes.Status,
 		Protocols: protocols,
 	}
+	if isFileDrop(ls) {
+		res.Opaque = &typesv1beta1.Opaque{Map: map[string]*typesv1beta1.OpaqueEntry{
+			fileDropOpaqueKey: {Decoder: "plain", Value: []byte("true")},
+		}}
+	}
 
 	return res, nil
 }
@@ -366,6 +410,13 @@ func (s *service) CreateContainer(ctx context.Context, req *provider.CreateConta
 		}, nil
 	}
 
+	if isFileDrop(ls) && s.conf.FileDropUploaderFolders {
+		// the folders are created in the folder of the uploader when uploading files into them
+		return &provider.CreateContainerResponse{
+			Status: status.NewOK(ctx),
+		}, nil
+	}
+
 	var res *provider.CreateContainerResponse
 	// the call has to be made to the gateway instead of the storage.
 	res, err = s.gateway.CreateContainer(ctx, &provider.CreateContainerRequest{
@@ -376,15 +427,18 @@ func (s *service) CreateContainer(ctx context.Context, req *provider.CreateConta
 			Status: status.NewInternal(ctx, err, "gateway: error calling CreateContainer for ref:"+req.Ref.String()),
 		}, nil
 	}
-	if res.Status.Code == rpc.Code_CODE_INTERNAL {
-		return res, nil
+	if res.Status.Code == rpc.Code_CODE_ALREADY_EXISTS && isFileDrop(ls) {
+		// don't reveal the content of file drop shares
+		return &provider.CreateContainerResponse{
+			Status: status.NewOK(ctx),
+		}, nil
 	}
 
 	return res, nil
 }
 
 func (s *service) TouchFile(ctx context.Context, req *provider.TouchFileRequest) (*provider.TouchFileResponse, error) {
-	ref, _, _, st, err := s.translatePublicRefToCS3Ref(ctx, req.Ref)
+	ref, _, ls, st, err := s.translatePublicRefToCS3Ref(ctx, req.Ref)
 	switch {
 	case err != nil:
 		return nil, err
@@ -392,6 +446,10 @@ func (s *service) TouchFile(ctx context.Context, req *provider.TouchFileRequest)
 		return &provider.TouchFileResponse{
 			Status: st,
 		}, nil
+	case isFileDrop(ls):
+		return &provider.TouchFileResponse{
+			Status: status.NewPermissionDenied(ctx, nil, "file drop shares only accept uploads"),
+		}, nil
 	}
 	return s.gateway.TouchFile(ctx, &provider.TouchFileRequest{Opaque: req.Opaque, Ref: ref})
 }
@@ -543,7 +601,15 @@ func (s *service) Stat(ctx context.Context, req *provider.StatRequest) (*provide
 		}, nil
 	}
 
-	if shareInfo.Type == provider.ResourceType_RESOURCE_TYPE_FILE || (relativePath == "" && nodeID == "") || shareInfo.Id.OpaqueId == nodeID {
+	isRoot := shareInfo.Type == provider.ResourceType_RESOURCE_TYPE_FILE || (relativePath == "" && nodeID == "") || shareInfo.Id.OpaqueId == nodeID
+	if !isRoot && isFileDrop(share) {
+		// the content of file drop shares is hidden from the uploaders
+		return &provider.StatResponse{
+			Status: status.NewNotFound(ctx, "gateway: file not found"),
+		}, nil
+	}
+
+	if isRoot {
 		res := &provider.StatResponse{
 			Status: status.NewOK(ctx),
 			Info:   shareInfo,
@@ -641,6 +707,12 @@ func (s *service) ListContainer(ctx context.Context, req *provider.ListContainer
 			Status: status.NewPermissionDenied(ctx, nil, "share does not grant ListContainer permission"),
 		}, nil
 	}
+	if isFileDrop(share) {
+		// the content of file drop shares is hidden from the uploaders
+		return &provider.ListContainerResponse{
+			Status: status.NewOK(ctx),
+		}, nil
+	}
 
 	listContainerR, err := s.gateway.ListContainer(
 		ctx,
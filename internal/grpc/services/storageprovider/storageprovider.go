@@ -219,6 +219,8 @@ func (s *service) SetArbitraryMetadata(ctx context.Context, req *provider.SetArb
 			st = status.NewNotFound(ctx, "path not found when setting arbitrary metadata")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsLocked:
+			st = status.NewLocked(ctx, err)
 		default:
 			st = status.NewInternal(ctx, err, "error setting arbitrary metadata: "+req.Ref.String())
 		}
@@ -249,6 +251,8 @@ func (s *service) UnsetArbitraryMetadata(ctx context.Context, req *provider.Unse
 			st = status.NewNotFound(ctx, "path not found when unsetting arbitrary metadata")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsLocked:
+			st = status.NewLocked(ctx, err)
 		default:
 			st = status.NewInternal(ctx, err, "error unsetting arbitrary metadata: "+req.Ref.String())
 		}
@@ -280,6 +284,10 @@ func (s *service) SetLock(ctx context.Context, req *provider.SetLockRequest) (*p
 			st = status.NewNotFound(ctx, "path not found when setting lock")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsLocked:
+			st = status.NewLocked(ctx, err)
+		case errtypes.IsBadRequest:
+			st = status.NewInvalidArg(ctx, err.Error())
 		default:
 			st = status.NewInternal(ctx, err, "error setting lock: "+req.Ref.String())
 		}
@@ -344,6 +352,10 @@ func (s *service) RefreshLock(ctx context.Context, req *provider.RefreshLockRequ
 			st = status.NewNotFound(ctx, "path not found when refreshing lock")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsLocked:
+			st = status.NewLocked(ctx, err)
+		case errtypes.IsBadRequest:
+			st = status.NewInvalidArg(ctx, err.Error())
 		default:
 			st = status.NewInternal(ctx, err, "error refreshing lock: "+req.Ref.String())
 		}
@@ -375,6 +387,10 @@ func (s *service) Unlock(ctx context.Context, req *provider.UnlockRequest) (*pro
 			st = status.NewNotFound(ctx, "path not found when unlocking")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsLocked:
+			st = status.NewLocked(ctx, err)
+		case errtypes.IsBadRequest:
+			st = status.NewInvalidArg(ctx, err.Error())
 		default:
 			st = status.NewInternal(ctx, err, "error unlocking: "+req.Ref.String())
 		}
@@ -417,6 +433,11 @@ func (s *service) InitiateFileDownload(ctx context.Context, req *provider.Initia
 		u.Path = path.Join(u.Path, "simple", newRef.GetPath())
 	}
 
+	// revisions are downloaded from the endpoint of the file
+	if req.Opaque != nil && req.Opaque.Map["version"] != nil {
+		u.RawQuery = url.Values{"version": {string(req.Opaque.Map["version"].Value)}}.Encode()
+	}
+
 	protocol.DownloadEndpoint = u.String()
 
 	return &provider.InitiateFileDownloadResponse{
@@ -439,6 +460,20 @@ func (s *service) InitiateFileUpload(ctx context.Context, req *provider.Initiate
 			Status: status.NewInternal(ctx, errtypes.BadRequest("can't upload to mount path"), "can't upload to mount path"),
 		}, nil
 	}
+	if req.GetIfNotExist() {
+		_, err := s.storage.GetMD(ctx, newRef, []string{})
+		switch err.(type) {
+		case nil:
+			return &provider.InitiateFileUploadResponse{
+				Status: status.NewAlreadyExists(ctx, nil, "file already exists"),
+			}, nil
+		case errtypes.IsNotFound:
+		default:
+			return &provider.InitiateFileUploadResponse{
+				Status: status.NewInternal(ctx, err, "error checking if the file exists"),
+			}, nil
+		}
+	}
 
 	metadata := map[string]string{}
 	var uploadLength int64
@@ -482,6 +517,8 @@ func (s *service) InitiateFileUpload(ctx context.Context, req *provider.Initiate
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
 		case errtypes.InsufficientStorage:
 			st = status.NewInsufficientStorage(ctx, err, "insufficient storage")
+		case errtypes.IsLocked:
+			st = status.NewLocked(ctx, err)
 		default:
 			st = status.NewInternal(ctx, err, "error getting upload id: "+req.Ref.String())
 		}
@@ -716,6 +753,8 @@ func (s *service) Delete(ctx context.Context, req *provider.DeleteRequest) (*pro
 			st = status.NewNotFound(ctx, "path not found when creating container")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsLocked:
+			st = status.NewLocked(ctx, err)
 		default:
 			st = status.NewInternal(ctx, err, "error deleting file: "+req.Ref.String())
 		}
@@ -751,6 +790,8 @@ func (s *service) Move(ctx context.Context, req *provider.MoveRequest) (*provide
 			st = status.NewNotFound(ctx, "path not found when moving")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsLocked:
+			st = status.NewLocked(ctx, err)
 		default:
 			st = status.NewInternal(ctx, err, "error moving: "+sourceRef.String())
 		}
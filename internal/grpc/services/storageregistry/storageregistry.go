@@ -20,6 +20,7 @@ package storageregistry
 
 import (
 	"context"
+	"sync"
 
 	registrypb "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
@@ -38,6 +39,33 @@ func init() {
 
 type service struct {
 	reg storage.Registry
+	mu  sync.RWMutex
+}
+
+func (s *service) registry() storage.Registry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.reg
+}
+
+// Reload replaces the registry with one built from the given configuration.
+func (s *service) Reload(m map[string]interface{}) error {
+	c, err := parseConfig(m)
+	if err != nil {
+		return err
+	}
+
+	c.init()
+
+	reg, err := getRegistry(c)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.reg = reg
+	s.mu.Unlock()
+	return nil
 }
 
 func (s *service) Close() error {
@@ -100,7 +128,7 @@ func getRegistry(c *config) (storage.Registry, error) {
 }
 
 func (s *service) ListStorageProviders(ctx context.Context, req *registrypb.ListStorageProvidersRequest) (*registrypb.ListStorageProvidersResponse, error) {
-	pinfos, err := s.reg.ListProviders(ctx)
+	pinfos, err := s.registry().ListProviders(ctx)
 	if err != nil {
 		return &registrypb.ListStorageProvidersResponse{
 			Status: status.NewInternal(ctx, err, "error getting list of storage providers"),
@@ -115,7 +143,7 @@ func (s *service) ListStorageProviders(ctx context.Context, req *registrypb.List
 }
 
 func (s *service) GetStorageProviders(ctx context.Context, req *registrypb.GetStorageProvidersRequest) (*registrypb.GetStorageProvidersResponse, error) {
-	p, err := s.reg.FindProviders(ctx, req.Ref)
+	p, err := s.registry().FindProviders(ctx, req.Ref)
 	if err != nil {
 		switch err.(type) {
 		case errtypes.IsNotFound:
@@ -138,7 +166,7 @@ func (s *service) GetStorageProviders(ctx context.Context, req *registrypb.GetSt
 
 func (s *service) GetHome(ctx context.Context, req *registrypb.GetHomeRequest) (*registrypb.GetHomeResponse, error) {
 	log := appctx.GetLogger(ctx)
-	p, err := s.reg.GetHome(ctx)
+	p, err := s.registry().GetHome(ctx)
 	if err != nil {
 		log.Error().Err(err).Msg("error getting home")
 		res := &registrypb.GetHomeResponse{
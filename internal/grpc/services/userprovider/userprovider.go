@@ -25,12 +25,16 @@ import (
 	"sort"
 
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/plugin"
+	"github.com/cs3org/reva/pkg/provisioning"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/cs3org/reva/pkg/user"
 	"github.com/cs3org/reva/pkg/user/manager/registry"
+	"github.com/golang/protobuf/proto"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
@@ -43,6 +47,8 @@ func init() {
 type config struct {
 	Driver  string                            `mapstructure:"driver"`
 	Drivers map[string]map[string]interface{} `mapstructure:"drivers"`
+	// AdminGroup is the group of the users allowed to provision users, provisioning is disabled if it is not set
+	AdminGroup string `mapstructure:"admin_group"`
 }
 
 func (c *config) init() {
@@ -97,16 +103,18 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 		return nil, err
 	}
 	svc := &service{
-		usermgr: userManager,
-		plugin:  plug,
+		usermgr:    userManager,
+		plugin:     plug,
+		adminGroup: c.AdminGroup,
 	}
 
 	return svc, nil
 }
 
 type service struct {
-	usermgr user.Manager
-	plugin  *plugin.RevaPlugin
+	usermgr    user.Manager
+	plugin     *plugin.RevaPlugin
+	adminGroup string
 }
 
 func (s *service) Close() error {
@@ -122,6 +130,9 @@ func (s *service) UnprotectedEndpoints() []string {
 
 func (s *service) Register(ss *grpc.Server) {
 	userpb.RegisterUserAPIServer(ss, s)
+	if m, ok := s.usermgr.(user.ManageableManager); ok && s.adminGroup != "" {
+		provisioning.RegisterUserServer(ss, m, s.adminGroup)
+	}
 }
 
 func (s *service) GetUser(ctx context.Context, req *userpb.GetUserRequest) (*userpb.GetUserResponse, error) {
@@ -137,6 +148,8 @@ func (s *service) GetUser(ctx context.Context, req *userpb.GetUserRequest) (*use
 		return res, nil
 	}
 
+	user = s.withAvatar(ctx, req, user)
+
 	res := &userpb.GetUserResponse{
 		Status: status.NewOK(ctx),
 		User:   user,
@@ -144,6 +157,37 @@ func (s *service) GetUser(ctx context.Context, req *userpb.GetUserRequest) (*use
 	return res, nil
 }
 
+// withAvatar returns a copy of the user holding its picture in the opaque if
+// the request asks for it and the user manager provides one. Failing to look
+// up the picture is not fatal.
+func (s *service) withAvatar(ctx context.Context, req *userpb.GetUserRequest, u *userpb.User) *userpb.User {
+	if req.Opaque == nil || req.Opaque.Map[user.AvatarOpaqueKey] == nil {
+		return u
+	}
+	m, ok := s.usermgr.(user.AvatarManager)
+	if !ok {
+		return u
+	}
+	photo, err := m.GetUserAvatar(ctx, u.Id)
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotFound); !ok {
+			appctx.GetLogger(ctx).Error().Err(err).Interface("user", u.Id).Msg("error getting avatar")
+		}
+		return u
+	}
+
+	// the user may be cached by the manager
+	u = proto.Clone(u).(*userpb.User)
+	if u.Opaque == nil {
+		u.Opaque = &types.Opaque{}
+	}
+	if u.Opaque.Map == nil {
+		u.Opaque.Map = map[string]*types.OpaqueEntry{}
+	}
+	u.Opaque.Map[user.AvatarOpaqueKey] = &types.OpaqueEntry{Decoder: "plain", Value: photo}
+	return u
+}
+
 func (s *service) GetUserByClaim(ctx context.Context, req *userpb.GetUserByClaimRequest) (*userpb.GetUserByClaimResponse, error) {
 	user, err := s.usermgr.GetUserByClaim(ctx, req.Claim, req.Value)
 	if err != nil {
@@ -21,20 +21,27 @@ package usershareprovider
 import (
 	"context"
 	"regexp"
+	"time"
 
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/pkg/share"
 	"github.com/cs3org/reva/pkg/share/manager/registry"
+	"github.com/cs3org/reva/pkg/sharedconf"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 func init() {
@@ -42,21 +49,30 @@ func init() {
 }
 
 type config struct {
-	Driver                string                            `mapstructure:"driver"`
-	Drivers               map[string]map[string]interface{} `mapstructure:"drivers"`
-	AllowedPathsForShares []string                          `mapstructure:"allowed_paths_for_shares"`
+	Driver                     string                            `mapstructure:"driver"`
+	Drivers                    map[string]map[string]interface{} `mapstructure:"drivers"`
+	AllowedPathsForShares      []string                          `mapstructure:"allowed_paths_for_shares"`
+	EnableExpiredSharesCleanup bool                              `mapstructure:"enable_expired_shares_cleanup" docs:"false;Whether to periodically remove expired shares together with their storage grants. Requires gatewaysvc and machine_auth_apikey."`
+	JanitorRunInterval         int                               `mapstructure:"janitor_run_interval" docs:"60;The interval in seconds between two runs of the expired shares cleanup."`
+	GatewayAddr                string                            `mapstructure:"gatewaysvc" docs:";The gateway used to remove expired shares."`
+	MachineAuthAPIKey          string                            `mapstructure:"machine_auth_apikey" docs:";The API key of the machine auth provider, used to remove expired shares in the name of their owners. Required by enable_expired_shares_cleanup, the gateway needs the machine auth provider to be configured with the same key."`
 }
 
 func (c *config) init() {
 	if c.Driver == "" {
 		c.Driver = "json"
 	}
+	if c.JanitorRunInterval == 0 {
+		c.JanitorRunInterval = 60
+	}
+	c.GatewayAddr = sharedconf.GetGatewaySVC(c.GatewayAddr)
 }
 
 type service struct {
 	conf                  *config
 	sm                    share.Manager
 	allowedPathsForShares []*regexp.Regexp
+	quit                  chan struct{}
 }
 
 func getShareManager(c *config) (share.Manager, error) {
@@ -68,6 +84,9 @@ func getShareManager(c *config) (share.Manager, error) {
 
 // TODO(labkode): add ctx to Close.
 func (s *service) Close() error {
+	if s.quit != nil {
+		close(s.quit)
+	}
 	return nil
 }
 
@@ -118,9 +137,134 @@ func New(m map[string]interface{}, ss *grpc.Server) (rgrpc.Service, error) {
 		allowedPathsForShares: allowedPathsForShares,
 	}
 
+	if c.EnableExpiredSharesCleanup {
+		if _, ok := sm.(share.ExpirationManager); !ok {
+			return nil, errors.New("usershareprovider: the share manager " + c.Driver + " does not support expiration dates")
+		}
+		if c.MachineAuthAPIKey == "" {
+			return nil, errors.New("usershareprovider: machine_auth_apikey is required to clean up expired shares")
+		}
+		service.quit = make(chan struct{})
+		go service.startJanitorRun()
+	}
+
 	return service, nil
 }
 
+func (s *service) startJanitorRun() {
+	ticker := time.NewTicker(time.Duration(s.conf.JanitorRunInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-ticker.C:
+			s.cleanupExpiredShares()
+		}
+	}
+}
+
+// cleanupExpiredShares removes the expired shares through the gateway, in the name of
+// their owners, so that the grants are removed from the storage as well.
+func (s *service) cleanupExpiredShares() {
+	log := appctx.GetLogger(context.Background())
+	shares, err := s.sm.(share.ExpirationManager).ListExpiredShares(context.Background(), time.Now())
+	if err != nil {
+		log.Error().Err(err).Msg("usershareprovider: error listing expired shares")
+		return
+	}
+	if len(shares) == 0 {
+		return
+	}
+
+	client, err := pool.GetGatewayServiceClient(s.conf.GatewayAddr)
+	if err != nil {
+		log.Error().Err(err).Msg("usershareprovider: error getting gateway client")
+		return
+	}
+
+	for _, sh := range shares {
+		if err := s.removeExpiredShare(client, sh); err != nil {
+			log.Error().Err(err).Str("share", sh.Id.OpaqueId).Msg("usershareprovider: error removing expired share")
+		}
+	}
+}
+
+func (s *service) removeExpiredShare(client gateway.GatewayAPIClient, sh *collaboration.Share) error {
+	authRes, err := client.Authenticate(context.Background(), &gateway.AuthenticateRequest{
+		Type:         "machine",
+		ClientId:     "userid:" + sh.Owner.GetOpaqueId(),
+		ClientSecret: s.conf.MachineAuthAPIKey,
+	})
+	if err != nil {
+		return err
+	}
+	if authRes.Status.Code != rpc.Code_CODE_OK {
+		return status.NewErrorFromCode(authRes.Status.Code, "usershareprovider")
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), ctxpkg.TokenHeader, authRes.Token)
+	res, err := client.RemoveShare(ctx, &collaboration.RemoveShareRequest{
+		Ref: &collaboration.ShareReference{Spec: &collaboration.ShareReference_Id{Id: sh.Id}},
+	})
+	if err != nil {
+		return err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return status.NewErrorFromCode(res.Status.Code, "usershareprovider")
+	}
+	return nil
+}
+
+// setExpiration sets the expiration date of a share, after parseExpiration checked that the manager supports it.
+func (s *service) setExpiration(ctx context.Context, ref *collaboration.ShareReference, expiration *typespb.Timestamp) error {
+	return s.sm.(share.ExpirationManager).SetExpiration(ctx, ref, expiration)
+}
+
+// expirations returns an opaque with the expiration dates of the given shares.
+// Errors are only logged, as the shares are still valid without them.
+func (s *service) expirations(ctx context.Context, shares ...*collaboration.Share) *typespb.Opaque {
+	em, ok := s.sm.(share.ExpirationManager)
+	if !ok || len(shares) == 0 {
+		return nil
+	}
+
+	ids := make([]*collaboration.ShareId, 0, len(shares))
+	for _, sh := range shares {
+		ids = append(ids, sh.Id)
+	}
+	log := appctx.GetLogger(ctx)
+	expirations, err := em.GetExpirations(ctx, ids)
+	if err != nil {
+		log.Error().Err(err).Msg("error getting share expirations")
+		return nil
+	}
+	o, err := share.ExpirationsToOpaque(expirations)
+	if err != nil {
+		log.Error().Err(err).Msg("error encoding share expirations")
+		return nil
+	}
+	return o
+}
+
+// parseExpiration reads the expiration date from the opaque of a request.
+func (s *service) parseExpiration(ctx context.Context, o *typespb.Opaque) (*typespb.Timestamp, bool, *rpc.Status) {
+	expiration, ok, err := share.ExpirationFromOpaque(o)
+	switch {
+	case err != nil:
+		return nil, ok, status.NewInvalidArg(ctx, "invalid expiration date")
+	case !ok:
+		return nil, false, nil
+	case share.IsExpired(expiration):
+		return nil, ok, status.NewInvalidArg(ctx, "expiration date is in the past")
+	}
+	if _, supported := s.sm.(share.ExpirationManager); !supported {
+		return nil, ok, status.NewUnimplemented(ctx, nil, "the share manager does not support expiration dates")
+	}
+	return expiration, ok, nil
+}
+
 func (s *service) isPathAllowed(path string) bool {
 	if len(s.allowedPathsForShares) == 0 {
 		return true
@@ -147,7 +291,19 @@ func (s *service) CreateShare(ctx context.Context, req *collaboration.CreateShar
 		}, nil
 	}
 
-	share, err := s.sm.Share(ctx, req.ResourceInfo, req.Grant)
+	expiration, _, st := s.parseExpiration(ctx, req.Opaque)
+	if st != nil {
+		return &collaboration.CreateShareResponse{Status: st}, nil
+	}
+
+	var createdShare *collaboration.Share
+	var err error
+	if expiration != nil {
+		// parseExpiration checked that the manager supports expiration dates
+		createdShare, err = s.sm.(share.ExpirationManager).ShareWithExpiration(ctx, req.ResourceInfo, req.Grant, expiration)
+	} else {
+		createdShare, err = s.sm.Share(ctx, req.ResourceInfo, req.Grant)
+	}
 	if err != nil {
 		return &collaboration.CreateShareResponse{
 			Status: status.NewInternal(ctx, err, "error creating share"),
@@ -156,7 +312,8 @@ func (s *service) CreateShare(ctx context.Context, req *collaboration.CreateShar
 
 	res := &collaboration.CreateShareResponse{
 		Status: status.NewOK(ctx),
-		Share:  share,
+		Opaque: s.expirations(ctx, createdShare),
+		Share:  createdShare,
 	}
 	return res, nil
 }
@@ -184,6 +341,7 @@ func (s *service) GetShare(ctx context.Context, req *collaboration.GetShareReque
 
 	return &collaboration.GetShareResponse{
 		Status: status.NewOK(ctx),
+		Opaque: s.expirations(ctx, share),
 		Share:  share,
 	}, nil
 }
@@ -198,12 +356,30 @@ func (s *service) ListShares(ctx context.Context, req *collaboration.ListSharesR
 
 	res := &collaboration.ListSharesResponse{
 		Status: status.NewOK(ctx),
+		Opaque: s.expirations(ctx, shares...),
 		Shares: shares,
 	}
 	return res, nil
 }
 
 func (s *service) UpdateShare(ctx context.Context, req *collaboration.UpdateShareRequest) (*collaboration.UpdateShareResponse, error) {
+	expiration, hasExpiration, st := s.parseExpiration(ctx, req.Opaque)
+	if st != nil {
+		return &collaboration.UpdateShareResponse{Status: st}, nil
+	}
+
+	// the permissions are restored if the expiration cannot be set
+	var previous *collaboration.Share
+	if hasExpiration {
+		var err error
+		previous, err = s.sm.GetShare(ctx, req.Ref)
+		if err != nil {
+			return &collaboration.UpdateShareResponse{
+				Status: status.NewInternal(ctx, err, "error getting share"),
+			}, nil
+		}
+	}
+
 	share, err := s.sm.UpdateShare(ctx, req.Ref, req.Field.GetPermissions()) // TODO(labkode): check what to update
 	if err != nil {
 		return &collaboration.UpdateShareResponse{
@@ -211,8 +387,20 @@ func (s *service) UpdateShare(ctx context.Context, req *collaboration.UpdateShar
 		}, nil
 	}
 
+	if hasExpiration {
+		if err := s.setExpiration(ctx, req.Ref, expiration); err != nil {
+			if _, rerr := s.sm.UpdateShare(ctx, req.Ref, previous.Permissions); rerr != nil {
+				appctx.GetLogger(ctx).Error().Err(rerr).Interface("ref", req.Ref).Msg("error restoring share permissions")
+			}
+			return &collaboration.UpdateShareResponse{
+				Status: status.NewInternal(ctx, err, "error setting share expiration"),
+			}, nil
+		}
+	}
+
 	res := &collaboration.UpdateShareResponse{
 		Status: status.NewOK(ctx),
+		Opaque: s.expirations(ctx, share),
 		Share:  share,
 	}
 	return res, nil
@@ -237,8 +425,13 @@ func (s *service) ListReceivedShares(ctx context.Context, req *collaboration.Lis
 		}, nil
 	}
 
+	received := make([]*collaboration.Share, 0, len(shares))
+	for _, rs := range shares {
+		received = append(received, rs.Share)
+	}
 	res := &collaboration.ListReceivedSharesResponse{
 		Status: status.NewOK(ctx),
+		Opaque: s.expirations(ctx, received...),
 		Shares: shares,
 	}
 	return res, nil
@@ -257,6 +450,7 @@ func (s *service) GetReceivedShare(ctx context.Context, req *collaboration.GetRe
 
 	res := &collaboration.GetReceivedShareResponse{
 		Status: status.NewOK(ctx),
+		Opaque: s.expirations(ctx, share.Share),
 		Share:  share,
 	}
 	return res, nil
@@ -28,6 +28,7 @@ import (
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/internal/http/interceptors/auth/credential/registry"
 	tokenregistry "github.com/cs3org/reva/internal/http/interceptors/auth/token/registry"
 	tokenwriterregistry "github.com/cs3org/reva/internal/http/interceptors/auth/tokenwriter/registry"
@@ -62,6 +63,8 @@ type config struct {
 	TokenManagers          map[string]map[string]interface{} `mapstructure:"token_managers"`
 	TokenWriter            string                            `mapstructure:"token_writer"`
 	TokenWriters           map[string]map[string]interface{} `mapstructure:"token_writers"`
+	// TrustedProxies are the addresses and networks of the proxies whose X-Forwarded-For header is used to determine the client IP
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -103,6 +106,11 @@ func New(m map[string]interface{}, unprotected []string) (global.Middleware, err
 		conf.CredentialsByUserAgent = map[string]string{}
 	}
 
+	trustedProxies, err := utils.ParseTrustedProxies(conf.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
 	userGroupsCache = gcache.New(1000000).LFU().Build()
 
 	credChain := map[string]auth.CredentialStrategy{}
@@ -215,10 +223,23 @@ func New(m map[string]interface{}, unprotected []string) (global.Middleware, err
 					ClientId:     creds.ClientID,
 					ClientSecret: creds.ClientSecret,
 				}
+				if creds.OTP != "" {
+					// only the auth managers requiring a second factor use the one-time password
+					req.Opaque = &types.Opaque{
+						Map: map[string]*types.OpaqueEntry{
+							ctxpkg.OTPOpaqueKey: {Decoder: "plain", Value: []byte(creds.OTP)},
+						},
+					}
+				}
 
 				log.Debug().Msgf("AuthenticateRequest: type: %s, client_id: %s against %s", req.Type, req.ClientId, conf.GatewaySvc)
 
-				res, err := client.Authenticate(ctx, req)
+				// the client IP is needed e.g. to throttle failed authentication attempts
+				authCtx := ctx
+				if ip, err := utils.GetTrustedClientIP(r, trustedProxies); err == nil {
+					authCtx = metadata.AppendToOutgoingContext(ctx, ctxpkg.ClientIPHeader, ip)
+				}
+				res, err := client.Authenticate(authCtx, req)
 				if err != nil {
 					log.Error().Err(err).Msg("error calling Authenticate")
 					w.WriteHeader(http.StatusUnauthorized)
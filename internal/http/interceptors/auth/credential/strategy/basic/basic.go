@@ -24,18 +24,32 @@ import (
 
 	"github.com/cs3org/reva/internal/http/interceptors/auth/credential/registry"
 	"github.com/cs3org/reva/pkg/auth"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
 )
 
 func init() {
 	registry.Register("basic", New)
 }
 
-type strategy struct{}
+type config struct {
+	// OTPHeader is the header carrying a one-time password, which is passed
+	// along with the password to the auth managers requiring a second factor.
+	OTPHeader string `mapstructure:"otp_header"`
+}
+
+type strategy struct {
+	c *config
+}
 
 // New returns a new auth strategy that checks for basic auth.
 // See https://tools.ietf.org/html/rfc7617
 func New(m map[string]interface{}) (auth.CredentialStrategy, error) {
-	return &strategy{}, nil
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+	return &strategy{c: c}, nil
 }
 
 func (s *strategy) GetCredentials(w http.ResponseWriter, r *http.Request) (*auth.Credentials, error) {
@@ -43,7 +57,11 @@ func (s *strategy) GetCredentials(w http.ResponseWriter, r *http.Request) (*auth
 	if !ok {
 		return nil, fmt.Errorf("no basic auth provided")
 	}
-	return &auth.Credentials{Type: "basic", ClientID: id, ClientSecret: secret}, nil
+	creds := &auth.Credentials{Type: "basic", ClientID: id, ClientSecret: secret}
+	if s.c.OTPHeader != "" {
+		creds.OTP = r.Header.Get(s.c.OTPHeader)
+	}
+	return creds, nil
 }
 
 func (s *strategy) AddWWWAuthenticate(w http.ResponseWriter, r *http.Request, realm string) {
@@ -102,12 +102,14 @@ func New(m map[string]interface{}) (global.Middleware, int, error) {
 			"Upload-Checksum",
 			"Upload-Offset",
 			"X-HTTP-Method-Override",
+			"X-Uploader-Name",
 		}
 	}
 
 	if len(conf.ExposedHeaders) == 0 {
 		conf.ExposedHeaders = []string{
 			"Location",
+			"X-Virus-Found",
 		}
 	}
 
@@ -0,0 +1,225 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package activity serves the activities recorded by the activity interceptor.
+package activity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/activity"
+	"github.com/cs3org/reva/pkg/activity/manager/registry"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/utils/resourceid"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+func init() {
+	global.Register("activity", New)
+}
+
+type config struct {
+	Prefix     string                            `mapstructure:"prefix" docs:"activity;The prefix the service is served on."`
+	Driver     string                            `mapstructure:"driver" docs:"json;The driver used to read the activities, it has to be configured like the one of the activity interceptor."`
+	Drivers    map[string]map[string]interface{} `mapstructure:"drivers"`
+	GatewaySvc string                            `mapstructure:"gatewaysvc"`
+	Limit      int                               `mapstructure:"limit" docs:"100;The default number of activities returned."`
+	MaxLimit   int                               `mapstructure:"max_limit" docs:"1000;The maximum number of activities that can be requested at once."`
+}
+
+func (c *config) init() {
+	if c.Prefix == "" {
+		c.Prefix = "activity"
+	}
+	if c.Driver == "" {
+		c.Driver = "json"
+	}
+	if c.Limit == 0 {
+		c.Limit = 100
+	}
+	if c.MaxLimit == 0 {
+		c.MaxLimit = 1000
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+}
+
+type svc struct {
+	c  *config
+	am activity.Manager
+}
+
+func getManager(c *config) (activity.Manager, error) {
+	if f, ok := registry.NewFuncs[c.Driver]; ok {
+		return f(c.Drivers[c.Driver])
+	}
+	return nil, fmt.Errorf("driver not found: %s", c.Driver)
+}
+
+// New returns a new activity service.
+func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "activity: error decoding conf")
+	}
+	c.init()
+
+	am, err := getManager(c)
+	if err != nil {
+		return nil, errors.Wrap(err, "activity: error creating the activity manager")
+	}
+	return &svc{c: c, am: am}, nil
+}
+
+// Close performs cleanup.
+func (s *svc) Close() error {
+	return nil
+}
+
+func (s *svc) Prefix() string {
+	return s.c.Prefix
+}
+
+func (s *svc) Unprotected() []string {
+	return []string{}
+}
+
+// Handler serves the activities of the current user, or the activities on a
+// resource the user has access to if the resource query parameter is set, e.g.
+// GET /activity?resource=<ownCloud file id>&limit=50
+func (s *svc) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := appctx.GetLogger(ctx)
+
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		u, ok := ctxpkg.ContextGetUser(ctx)
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		q := r.URL.Query()
+		filter := &activity.Filter{Limit: s.c.Limit}
+		if l := q.Get("limit"); l != "" {
+			limit, err := strconv.Atoi(l)
+			if err != nil || limit <= 0 {
+				http.Error(w, "invalid limit", http.StatusBadRequest)
+				return
+			}
+			filter.Limit = limit
+		}
+		if filter.Limit > s.c.MaxLimit {
+			filter.Limit = s.c.MaxLimit
+		}
+
+		if rid := q.Get("resource"); rid != "" {
+			id := resourceid.OwnCloudResourceIDUnwrap(rid)
+			if id == nil {
+				http.Error(w, "invalid resource id", http.StatusBadRequest)
+				return
+			}
+
+			// only users with access to the resource may see its activities
+			client, err := pool.GetGatewayServiceClient(s.c.GatewaySvc)
+			if err != nil {
+				log.Error().Err(err).Msg("error getting gateway client")
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			res, err := client.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{ResourceId: id}})
+			switch {
+			case err != nil:
+				log.Error().Err(err).Msg("error stating resource")
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			case res.Status.Code == rpc.Code_CODE_NOT_FOUND, res.Status.Code == rpc.Code_CODE_PERMISSION_DENIED:
+				w.WriteHeader(http.StatusNotFound)
+				return
+			case res.Status.Code != rpc.Code_CODE_OK:
+				log.Error().Interface("status", res.Status).Msg("error stating resource")
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			filter.ResourceID = res.Info.Id
+		} else {
+			filter.Executant = u.Id
+		}
+
+		activities, err := s.am.ListActivities(ctx, filter)
+		if err != nil {
+			log.Error().Err(err).Msg("error listing activities")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		s.resolvePaths(ctx, activities)
+
+		data, err := json.Marshal(activities)
+		if err != nil {
+			log.Error().Err(err).Msg("error encoding activities")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(data); err != nil {
+			log.Error().Err(err).Msg("error writing response")
+		}
+	})
+}
+
+// resolvePaths sets the path of the activities recorded without one, e.g. the
+// uploads recorded by the data providers, to the current path of the resource
+// in the namespace of the user. Resources the user can't access are skipped.
+func (s *svc) resolvePaths(ctx context.Context, activities []*activity.Activity) {
+	client, err := pool.GetGatewayServiceClient(s.c.GatewaySvc)
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("error getting gateway client")
+		return
+	}
+
+	paths := map[string]string{}
+	for _, a := range activities {
+		if a.Path != "" || a.ResourceID == nil {
+			continue
+		}
+		key := resourceid.OwnCloudResourceIDWrap(a.ResourceID)
+		p, ok := paths[key]
+		if !ok {
+			res, err := client.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{ResourceId: a.ResourceID}})
+			if err == nil && res.Status.Code == rpc.Code_CODE_OK {
+				p = res.Info.Path
+			}
+			paths[key] = p
+		}
+		a.Path = p
+	}
+}
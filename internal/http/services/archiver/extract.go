@@ -0,0 +1,270 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package archiver
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/archiver/manager"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+const (
+	formatZip   = "zip"
+	formatTar   = "tar"
+	formatTarGz = "tar.gz"
+
+	// progressInterval is the minimum time between two progress reports sent to the client
+	progressInterval = time.Second
+)
+
+type extractResult struct {
+	manager.Progress
+	Error string `json:"error,omitempty"`
+}
+
+// getArchiveFormat returns the format of the archive, either the requested one
+// or the one derived from the archive name or content type
+func getArchiveFormat(format, name, contentType string) (string, error) {
+	if format == "" {
+		name = strings.ToLower(name)
+		switch {
+		case strings.HasSuffix(name, ".zip"):
+			format = formatZip
+		case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+			format = formatTarGz
+		case strings.HasSuffix(name, ".tar"):
+			format = formatTar
+		default:
+			switch contentType {
+			case "application/zip", "application/x-zip-compressed":
+				format = formatZip
+			case "application/gzip", "application/x-gzip":
+				format = formatTarGz
+			case "application/x-tar":
+				format = formatTar
+			}
+		}
+	}
+
+	switch format {
+	case formatZip, formatTar, formatTarGz:
+		return format, nil
+	case "tgz":
+		return formatTarGz, nil
+	case "":
+		return "", errtypes.BadRequest("could not determine the archive format")
+	default:
+		return "", errtypes.BadRequest(fmt.Sprintf("archive format %s not supported", format))
+	}
+}
+
+// availableQuota returns the bytes that can still be stored in the folder, or -1 if there is no limit.
+// If the folder does not exist yet, the quota of its closest existing parent is used.
+func (s *svc) availableQuota(ctx context.Context, folder string) (int64, error) {
+	for {
+		res, err := s.gtwClient.GetQuota(ctx, &gateway.GetQuotaRequest{
+			Ref: &provider.Reference{
+				Path: folder,
+			},
+		})
+
+		switch {
+		case err != nil:
+			return 0, err
+		case res.Status.Code == rpc.Code_CODE_NOT_FOUND && folder != "/":
+			folder = path.Dir(folder)
+		case res.Status.Code == rpc.Code_CODE_UNIMPLEMENTED:
+			return -1, nil
+		case res.Status.Code != rpc.Code_CODE_OK:
+			return 0, errtypes.InternalError(fmt.Sprintf("error getting quota of %s", folder))
+		case res.TotalBytes == 0:
+			return -1, nil
+		case res.UsedBytes >= res.TotalBytes:
+			return 0, nil
+		default:
+			return int64(res.TotalBytes - res.UsedBytes), nil
+		}
+	}
+}
+
+// extract expands the archive stored in reva, or the one read from body if no archive is given
+func (s *svc) extract(ctx context.Context, e *manager.Extractor, format, archive string, body io.Reader) (manager.Progress, error) {
+	src := body
+	if archive != "" {
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(s.downloader.Download(ctx, archive, pw))
+		}()
+		defer pr.Close()
+		src = pr
+	}
+
+	switch format {
+	case formatZip:
+		// the central directory at the end of a zip archive needs random access
+		f, err := ioutil.TempFile("", "reva-archiver-")
+		if err != nil {
+			return manager.Progress{}, err
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+
+		// compressed entries can make the archive smaller than the extracted files,
+		// so the buffered archive has its own limit
+		size, err := io.Copy(f, io.LimitReader(src, s.config.MaxArchiveSize+1))
+		if err != nil {
+			return manager.Progress{}, err
+		}
+		if size > s.config.MaxArchiveSize {
+			return manager.Progress{}, manager.ErrMaxSize{}
+		}
+		return e.ExtractZip(ctx, f, size)
+	case formatTarGz:
+		gz, err := gzip.NewReader(src)
+		if err != nil {
+			if err == gzip.ErrHeader {
+				return manager.Progress{}, manager.ErrInvalidArchive{Reason: err.Error()}
+			}
+			return manager.Progress{}, err
+		}
+		defer gz.Close()
+		return e.ExtractTar(ctx, gz)
+	default:
+		return e.ExtractTar(ctx, src)
+	}
+}
+
+// handleExtract expands a zip or tar(.gz) archive into the folder given by the `target` query parameter.
+// The archive is either stored in reva and referenced by the `path` or `id` query parameter,
+// or sent as the request body. Clients accepting application/x-ndjson get the progress
+// streamed while the archive is extracted, with the outcome in the last line.
+func (s *svc) handleExtract(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	v := r.URL.Query()
+
+	target := v.Get("target")
+	if target == "" {
+		s.writeHTTPError(rw, errtypes.BadRequest("missing target folder"))
+		return
+	}
+	target = path.Clean(target)
+	if err := s.allAllowed([]string{target}); err != nil {
+		s.writeHTTPError(rw, err)
+		return
+	}
+
+	conflict, err := manager.ParseConflictPolicy(v.Get("conflict"))
+	if err != nil {
+		s.writeHTTPError(rw, err)
+		return
+	}
+
+	var archive string
+	if len(v["path"]) > 0 || len(v["id"]) > 0 {
+		files, err := s.getFiles(ctx, v["path"], v["id"])
+		if err != nil {
+			s.writeHTTPError(rw, err)
+			return
+		}
+		if len(files) != 1 {
+			s.writeHTTPError(rw, errtypes.BadRequest("only one archive can be extracted at a time"))
+			return
+		}
+		archive = files[0]
+	}
+
+	format, err := getArchiveFormat(v.Get("format"), archive, r.Header.Get("Content-Type"))
+	if err != nil {
+		s.writeHTTPError(rw, err)
+		return
+	}
+
+	quota, err := s.availableQuota(ctx, target)
+	if err != nil {
+		s.writeHTTPError(rw, err)
+		return
+	}
+
+	ext, err := manager.NewExtractor(target, s.uploader, manager.ExtractConfig{
+		MaxNumFiles: s.config.MaxNumFiles,
+		MaxSize:     s.config.MaxSize,
+		Quota:       quota,
+		Conflict:    conflict,
+	})
+	if err != nil {
+		s.writeHTTPError(rw, err)
+		return
+	}
+
+	s.log.Debug().Str("archive", archive).Str("target", target).Str("format", format).Msg("extracting archive")
+
+	if !strings.Contains(r.Header.Get("Accept"), "application/x-ndjson") {
+		progress, err := s.extract(ctx, ext, format, archive, r.Body)
+		if err != nil {
+			s.writeHTTPError(rw, err)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(rw).Encode(extractResult{Progress: progress})
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/x-ndjson")
+	rw.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(rw)
+	flusher, _ := rw.(http.Flusher)
+	var last time.Time
+	ext.OnProgress(func(p manager.Progress) {
+		if time.Since(last) < progressInterval {
+			return
+		}
+		last = time.Now()
+		_ = enc.Encode(extractResult{Progress: p})
+		if flusher != nil {
+			flusher.Flush()
+		}
+	})
+
+	progress, err := s.extract(ctx, ext, format, archive, r.Body)
+	res := extractResult{Progress: progress}
+	if err != nil {
+		s.log.Error().Err(err).Str("archive", archive).Str("target", target).Msg("error extracting archive")
+		res.Error = err.Error()
+	}
+	_ = enc.Encode(res)
+}
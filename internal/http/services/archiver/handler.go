@@ -36,8 +36,10 @@ import (
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/pkg/rhttp"
 	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/rhttp/router"
 	"github.com/cs3org/reva/pkg/sharedconf"
 	"github.com/cs3org/reva/pkg/storage/utils/downloader"
+	"github.com/cs3org/reva/pkg/storage/utils/uploader"
 	"github.com/cs3org/reva/pkg/storage/utils/walker"
 	"github.com/cs3org/reva/pkg/utils/resourceid"
 	"github.com/gdexlab/go-render/render"
@@ -52,6 +54,7 @@ type svc struct {
 	log        *zerolog.Logger
 	walker     walker.Walker
 	downloader downloader.Downloader
+	uploader   uploader.Uploader
 
 	allowedFolders []*regexp.Regexp
 }
@@ -65,6 +68,7 @@ type Config struct {
 	Name           string   `mapstructure:"name"`
 	MaxNumFiles    int64    `mapstructure:"max_num_files"`
 	MaxSize        int64    `mapstructure:"max_size"`
+	MaxArchiveSize int64    `mapstructure:"max_archive_size"`
 	AllowedFolders []string `mapstructure:"allowed_folders"`
 }
 
@@ -101,7 +105,8 @@ func New(conf map[string]interface{}, log *zerolog.Logger) (global.Service, erro
 		config:         c,
 		gtwClient:      gtw,
 		downloader:     downloader.NewDownloader(gtw, rhttp.Insecure(c.Insecure), rhttp.Timeout(time.Duration(c.Timeout*int64(time.Second)))),
-		walker:         walker.NewWalker(gtw),
+		uploader:       uploader.NewUploader(gtw, rhttp.Insecure(c.Insecure), rhttp.Timeout(time.Duration(c.Timeout*int64(time.Second)))),
+		walker:         walker.NewConcurrentWalker(gtw, walker.Options{}),
 		log:            log,
 		allowedFolders: allowedFolderRegex,
 	}, nil
@@ -116,6 +121,11 @@ func (c *Config) init() {
 		c.Name = "download"
 	}
 
+	// zip archives to extract are buffered on disk up to this size
+	if c.MaxArchiveSize == 0 {
+		c.MaxArchiveSize = c.MaxSize
+	}
+
 	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
 }
 
@@ -196,8 +206,14 @@ func (s *svc) writeHTTPError(rw http.ResponseWriter, err error) {
 		rw.WriteHeader(http.StatusNotFound)
 	case manager.ErrMaxSize, manager.ErrMaxFileCount:
 		rw.WriteHeader(http.StatusRequestEntityTooLarge)
-	case errtypes.BadRequest:
+	case manager.ErrQuotaExceeded, errtypes.InsufficientStorage:
+		rw.WriteHeader(http.StatusInsufficientStorage)
+	case manager.ErrConflict:
+		rw.WriteHeader(http.StatusConflict)
+	case errtypes.BadRequest, manager.ErrInvalidEntry, manager.ErrInvalidArchive:
 		rw.WriteHeader(http.StatusBadRequest)
+	case errtypes.PermissionDenied:
+		rw.WriteHeader(http.StatusForbidden)
 	default:
 		rw.WriteHeader(http.StatusInternalServerError)
 	}
@@ -207,6 +223,11 @@ func (s *svc) writeHTTPError(rw http.ResponseWriter, err error) {
 
 func (s *svc) Handler() http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if head, _ := router.ShiftPath(r.URL.Path); head == "extract" {
+			s.handleExtract(rw, r)
+			return
+		}
+
 		// get the paths and/or the resources id from the query
 		ctx := r.Context()
 		v := r.URL.Query()
@@ -27,6 +27,24 @@ type ErrMaxSize struct{}
 // ErrEmptyList is the error returned when an empty list is passed when an archiver is created
 type ErrEmptyList struct{}
 
+// ErrQuotaExceeded is the error returned when the extracted files do not fit into the quota of the target folder
+type ErrQuotaExceeded struct{}
+
+// ErrConflict is the error returned when an extracted entry collides with an existing resource
+type ErrConflict struct {
+	Path string
+}
+
+// ErrInvalidEntry is the error returned when an archive entry would be extracted outside of the target folder
+type ErrInvalidEntry struct {
+	Name string
+}
+
+// ErrInvalidArchive is the error returned when an archive cannot be read
+type ErrInvalidArchive struct {
+	Reason string
+}
+
 // Error returns the string error msg for ErrMaxFileCount
 func (ErrMaxFileCount) Error() string {
 	return "reached max files count"
@@ -41,3 +59,23 @@ func (ErrMaxSize) Error() string {
 func (ErrEmptyList) Error() string {
 	return "list of files to archive empty"
 }
+
+// Error returns the string error msg for ErrQuotaExceeded
+func (ErrQuotaExceeded) Error() string {
+	return "quota of the target folder exceeded"
+}
+
+// Error returns the string error msg for ErrConflict
+func (e ErrConflict) Error() string {
+	return "conflict with existing resource " + e.Path
+}
+
+// Error returns the string error msg for ErrInvalidEntry
+func (e ErrInvalidEntry) Error() string {
+	return "invalid archive entry " + e.Name
+}
+
+// Error returns the string error msg for ErrInvalidArchive
+func (e ErrInvalidArchive) Error() string {
+	return "invalid archive: " + e.Reason
+}
@@ -0,0 +1,372 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package manager
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage/utils/uploader"
+	"github.com/cs3org/reva/pkg/utils"
+)
+
+// ConflictPolicy decides what happens to an archive entry
+// when the target folder already contains a file with the same name
+type ConflictPolicy string
+
+const (
+	// ConflictFail aborts the extraction
+	ConflictFail ConflictPolicy = "fail"
+	// ConflictSkip keeps the existing file and skips the entry
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite replaces the existing file with the entry
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictRename extracts the entry next to the existing file, adding a counter to its name
+	ConflictRename ConflictPolicy = "rename"
+)
+
+// maxRenameAttempts is the number of names tried for an entry before giving up
+const maxRenameAttempts = 1000
+
+// ParseConflictPolicy parses a conflict policy, defaulting to ConflictFail
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(s); p {
+	case "":
+		return ConflictFail, nil
+	case ConflictFail, ConflictSkip, ConflictOverwrite, ConflictRename:
+		return p, nil
+	default:
+		return "", errtypes.BadRequest(fmt.Sprintf("unknown conflict policy %s", s))
+	}
+}
+
+// ExtractConfig is the config for the Extractor
+type ExtractConfig struct {
+	MaxNumFiles int64
+	MaxSize     int64
+	// Quota is the number of bytes still available in the target folder, a negative value means unlimited
+	Quota    int64
+	Conflict ConflictPolicy
+}
+
+// Progress reports how far an extraction got
+type Progress struct {
+	Files   int64 `json:"files"`
+	Bytes   int64 `json:"bytes"`
+	Skipped int64 `json:"skipped"`
+	// the totals are only known upfront for zip archives
+	TotalFiles int64 `json:"total_files,omitempty"`
+	TotalBytes int64 `json:"total_bytes,omitempty"`
+}
+
+// Extractor is the struct able to expand an archive into a folder
+type Extractor struct {
+	target     string
+	uploader   uploader.Uploader
+	config     ExtractConfig
+	onProgress func(Progress)
+
+	progress              Progress
+	filesCount, sizeFiles int64
+	dirs                  map[string]bool
+}
+
+// NewExtractor creates a new extractor able to expand archives into the target folder.
+// The target folder is created if it does not exist.
+func NewExtractor(target string, u uploader.Uploader, config ExtractConfig) (*Extractor, error) {
+	if target == "" {
+		return nil, errtypes.BadRequest("missing target folder")
+	}
+	if config.Conflict == "" {
+		config.Conflict = ConflictFail
+	}
+	if _, err := ParseConflictPolicy(string(config.Conflict)); err != nil {
+		return nil, err
+	}
+
+	return &Extractor{
+		target:   path.Clean(target),
+		uploader: u,
+		config:   config,
+		dirs:     map[string]bool{},
+	}, nil
+}
+
+// OnProgress registers a function called after each entry of the archive has been handled
+func (e *Extractor) OnProgress(fn func(Progress)) {
+	e.onProgress = fn
+}
+
+// ExtractZip expands the zip archive of the given size read from r.
+// The limits are checked against the central directory before anything is extracted.
+func (e *Extractor) ExtractZip(ctx context.Context, r io.ReaderAt, size int64) (Progress, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return e.progress, ErrInvalidArchive{err.Error()}
+	}
+
+	for _, f := range zr.File {
+		if f.Mode().IsDir() {
+			e.progress.TotalFiles++
+		} else if f.Mode().IsRegular() {
+			e.progress.TotalFiles++
+			e.progress.TotalBytes += int64(f.UncompressedSize64)
+		}
+	}
+	if err := e.checkLimits(e.progress.TotalFiles, e.progress.TotalBytes); err != nil {
+		return e.progress, err
+	}
+
+	for _, f := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return e.progress, err
+		}
+
+		switch mode := f.Mode(); {
+		case mode.IsDir():
+			err = e.extractDir(ctx, f.Name)
+		case mode.IsRegular():
+			var rc io.ReadCloser
+			if rc, err = f.Open(); err != nil {
+				return e.progress, ErrInvalidArchive{err.Error()}
+			}
+			err = e.extractFile(ctx, f.Name, int64(f.UncompressedSize64), rc)
+			rc.Close()
+		default:
+			// symlinks and other special files are not supported by the storages
+			e.skip()
+		}
+		if err != nil {
+			return e.progress, err
+		}
+	}
+	return e.progress, nil
+}
+
+// ExtractTar expands the tar archive read from r.
+// As the archive is streamed, the limits are checked while extracting,
+// which means that the entries extracted before reaching a limit are kept.
+func (e *Extractor) ExtractTar(ctx context.Context, r io.Reader) (Progress, error) {
+	tr := tar.NewReader(r)
+	for {
+		if err := ctx.Err(); err != nil {
+			return e.progress, err
+		}
+
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break // finished reading the archive
+		}
+		if err != nil {
+			if errors.Is(err, tar.ErrHeader) {
+				return e.progress, ErrInvalidArchive{err.Error()}
+			}
+			return e.progress, err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = e.extractDir(ctx, hdr.Name)
+		case tar.TypeReg, tar.TypeRegA:
+			err = e.extractFile(ctx, hdr.Name, hdr.Size, tr)
+		default:
+			// symlinks and other special files are not supported by the storages
+			e.skip()
+		}
+		if err != nil {
+			return e.progress, err
+		}
+	}
+	return e.progress, nil
+}
+
+func (e *Extractor) checkLimits(filesCount, sizeFiles int64) error {
+	if filesCount > e.config.MaxNumFiles {
+		return ErrMaxFileCount{}
+	}
+	if sizeFiles > e.config.MaxSize {
+		return ErrMaxSize{}
+	}
+	if e.config.Quota >= 0 && sizeFiles > e.config.Quota {
+		return ErrQuotaExceeded{}
+	}
+	return nil
+}
+
+// count adds an entry to the totals and checks them against the limits
+func (e *Extractor) count(size int64) error {
+	e.filesCount++
+	e.sizeFiles += size
+	return e.checkLimits(e.filesCount, e.sizeFiles)
+}
+
+func (e *Extractor) report() {
+	if e.onProgress != nil {
+		e.onProgress(e.progress)
+	}
+}
+
+func (e *Extractor) skip() {
+	e.progress.Skipped++
+	e.report()
+}
+
+// entryPath returns the path an entry is extracted to, or an empty string for the root of the archive
+func (e *Extractor) entryPath(name string) (string, error) {
+	p := path.Clean(name)
+	// entries must not escape the target folder
+	if path.IsAbs(name) || p == ".." || strings.HasPrefix(p, "../") {
+		return "", ErrInvalidEntry{name}
+	}
+	if p == "." {
+		return "", nil
+	}
+	return path.Join(e.target, p), nil
+}
+
+func (e *Extractor) extractDir(ctx context.Context, name string) error {
+	p, err := e.entryPath(name)
+	if err != nil {
+		return err
+	}
+	if p == "" {
+		p = e.target
+	}
+	if err := e.count(0); err != nil {
+		return err
+	}
+	if err := e.ensureDir(ctx, p); err != nil {
+		return err
+	}
+	e.progress.Files++
+	e.report()
+	return nil
+}
+
+func (e *Extractor) extractFile(ctx context.Context, name string, size int64, r io.Reader) error {
+	p, err := e.entryPath(name)
+	if err != nil {
+		return err
+	}
+	if p == "" {
+		return ErrInvalidEntry{name}
+	}
+	if err := e.count(size); err != nil {
+		return err
+	}
+	if err := e.ensureDir(ctx, path.Dir(p)); err != nil {
+		return err
+	}
+
+	p, skip, err := e.resolveConflict(ctx, p)
+	if err != nil {
+		return err
+	}
+	if skip {
+		e.skip()
+		return nil
+	}
+
+	if err := e.uploader.Upload(ctx, p, size, r); err != nil {
+		return err
+	}
+	e.progress.Files++
+	e.progress.Bytes += size
+	e.report()
+	return nil
+}
+
+// ensureDir creates the folder and its parents up to the target folder, if they do not exist.
+// Existing folders are merged with the ones in the archive.
+func (e *Extractor) ensureDir(ctx context.Context, p string) error {
+	if e.dirs[p] {
+		return nil
+	}
+
+	info, err := e.uploader.Stat(ctx, p)
+	switch {
+	case err == nil:
+		if info.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+			return ErrConflict{p}
+		}
+	case isNotFound(err):
+		if p != e.target {
+			if err := e.ensureDir(ctx, path.Dir(p)); err != nil {
+				return err
+			}
+		}
+		if err := e.uploader.CreateDir(ctx, p); err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+
+	e.dirs[p] = true
+	return nil
+}
+
+// resolveConflict returns the path a file is extracted to, applying the conflict policy
+// if the path is already taken, and whether the file has to be skipped
+func (e *Extractor) resolveConflict(ctx context.Context, p string) (string, bool, error) {
+	info, err := e.uploader.Stat(ctx, p)
+	switch {
+	case isNotFound(err):
+		return p, false, nil
+	case err != nil:
+		return "", false, err
+	}
+
+	switch e.config.Conflict {
+	case ConflictSkip:
+		return p, true, nil
+	case ConflictOverwrite:
+		if info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+			// a folder is never replaced by a file
+			return "", false, ErrConflict{p}
+		}
+		return p, false, nil
+	case ConflictRename:
+		for i := 1; i <= maxRenameAttempts; i++ {
+			candidate := utils.NumberedName(p, i)
+			if _, err := e.uploader.Stat(ctx, candidate); err != nil {
+				if isNotFound(err) {
+					return candidate, false, nil
+				}
+				return "", false, err
+			}
+		}
+		return "", false, ErrConflict{p}
+	default:
+		return "", false, ErrConflict{p}
+	}
+}
+
+func isNotFound(err error) bool {
+	_, ok := err.(errtypes.IsNotFound)
+	return ok
+}
@@ -0,0 +1,320 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package manager
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"path"
+	"strings"
+	"testing"
+
+	uploaderMock "github.com/cs3org/reva/pkg/storage/utils/uploader/mock"
+	"github.com/cs3org/reva/pkg/test"
+)
+
+type entry struct {
+	name    string
+	content string
+	dir     bool
+}
+
+func buildTar(t *testing.T, entries []entry) *bytes.Buffer {
+	var b bytes.Buffer
+	w := tar.NewWriter(&b)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(e.content))}
+		if e.dir {
+			hdr = &tar.Header{Name: e.name, Mode: 0755, Typeflag: tar.TypeDir}
+		}
+		if err := w.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(e.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &b
+}
+
+func buildZip(t *testing.T, entries []entry) *bytes.Reader {
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for _, e := range entries {
+		name := e.name
+		if e.dir && !strings.HasSuffix(name, "/") {
+			name += "/"
+		}
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(e.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(b.Bytes())
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing test.Dir
+		entries  []entry
+		config   ExtractConfig
+		expected test.Dir
+		err      error
+	}{
+		{
+			name: "files and folders",
+			entries: []entry{
+				{name: "foo", content: "foo"},
+				{name: "bar", dir: true},
+				{name: "bar/baz", content: "baz"},
+			},
+			config: ExtractConfig{MaxNumFiles: 3, MaxSize: 6, Quota: -1},
+			expected: test.Dir{
+				"foo": test.File{Content: "foo"},
+				"bar": test.Dir{
+					"baz": test.File{Content: "baz"},
+				},
+			},
+		},
+		{
+			name: "missing folder entries",
+			entries: []entry{
+				{name: "a/b/c", content: "c"},
+			},
+			config: ExtractConfig{MaxNumFiles: 1, MaxSize: 1, Quota: -1},
+			expected: test.Dir{
+				"a": test.Dir{
+					"b": test.Dir{
+						"c": test.File{Content: "c"},
+					},
+				},
+			},
+		},
+		{
+			name: "merge existing folders",
+			existing: test.Dir{
+				"bar": test.Dir{
+					"old": test.File{Content: "old"},
+				},
+			},
+			entries: []entry{
+				{name: "bar/", dir: true},
+				{name: "bar/new", content: "new"},
+			},
+			config: ExtractConfig{MaxNumFiles: 2, MaxSize: 3, Quota: -1},
+			expected: test.Dir{
+				"bar": test.Dir{
+					"old": test.File{Content: "old"},
+					"new": test.File{Content: "new"},
+				},
+			},
+		},
+		{
+			name: "error max files reached",
+			entries: []entry{
+				{name: "foo", content: "foo"},
+				{name: "bar", content: "bar"},
+			},
+			config: ExtractConfig{MaxNumFiles: 1, MaxSize: 100, Quota: -1},
+			err:    ErrMaxFileCount{},
+		},
+		{
+			name: "error max size reached",
+			entries: []entry{
+				{name: "foo", content: "foo"},
+			},
+			config: ExtractConfig{MaxNumFiles: 1, MaxSize: 2, Quota: -1},
+			err:    ErrMaxSize{},
+		},
+		{
+			name: "error quota exceeded",
+			entries: []entry{
+				{name: "foo", content: "foo"},
+			},
+			config: ExtractConfig{MaxNumFiles: 1, MaxSize: 100, Quota: 2},
+			err:    ErrQuotaExceeded{},
+		},
+		{
+			name: "error entry outside of target",
+			entries: []entry{
+				{name: "../foo", content: "foo"},
+			},
+			config: ExtractConfig{MaxNumFiles: 1, MaxSize: 100, Quota: -1},
+			err:    ErrInvalidEntry{"../foo"},
+		},
+		{
+			name: "conflict fail",
+			existing: test.Dir{
+				"foo": test.File{Content: "old"},
+			},
+			entries: []entry{
+				{name: "foo", content: "new"},
+			},
+			config: ExtractConfig{MaxNumFiles: 1, MaxSize: 100, Quota: -1},
+			err:    ErrConflict{"foo"},
+		},
+		{
+			name: "conflict skip",
+			existing: test.Dir{
+				"foo": test.File{Content: "old"},
+			},
+			entries: []entry{
+				{name: "foo", content: "new"},
+				{name: "bar", content: "bar"},
+			},
+			config: ExtractConfig{MaxNumFiles: 2, MaxSize: 100, Quota: -1, Conflict: ConflictSkip},
+			expected: test.Dir{
+				"foo": test.File{Content: "old"},
+				"bar": test.File{Content: "bar"},
+			},
+		},
+		{
+			name: "conflict overwrite",
+			existing: test.Dir{
+				"foo": test.File{Content: "old"},
+			},
+			entries: []entry{
+				{name: "foo", content: "new"},
+			},
+			config: ExtractConfig{MaxNumFiles: 1, MaxSize: 100, Quota: -1, Conflict: ConflictOverwrite},
+			expected: test.Dir{
+				"foo": test.File{Content: "new"},
+			},
+		},
+		{
+			name: "conflict rename",
+			existing: test.Dir{
+				"foo.txt":     test.File{Content: "old"},
+				"foo (1).txt": test.File{Content: "old"},
+			},
+			entries: []entry{
+				{name: "foo.txt", content: "new"},
+			},
+			config: ExtractConfig{MaxNumFiles: 1, MaxSize: 100, Quota: -1, Conflict: ConflictRename},
+			expected: test.Dir{
+				"foo.txt":     test.File{Content: "old"},
+				"foo (1).txt": test.File{Content: "old"},
+				"foo (2).txt": test.File{Content: "new"},
+			},
+		},
+		{
+			name: "conflict file replaces folder",
+			existing: test.Dir{
+				"foo": test.Dir{},
+			},
+			entries: []entry{
+				{name: "foo", content: "new"},
+			},
+			config: ExtractConfig{MaxNumFiles: 1, MaxSize: 100, Quota: -1, Conflict: ConflictOverwrite},
+			err:    ErrConflict{"foo"},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, format := range []string{"tar", "zip"} {
+			t.Run(tt.name+" "+format, func(t *testing.T) {
+				target, cleanup, err := test.NewTestDir(tt.existing)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer cleanup()
+
+				e, err := NewExtractor(target, uploaderMock.NewUploader(), tt.config)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if format == "tar" {
+					_, err = e.ExtractTar(context.Background(), buildTar(t, tt.entries))
+				} else {
+					r := buildZip(t, tt.entries)
+					_, err = e.ExtractZip(context.Background(), r, r.Size())
+				}
+
+				expectedErr := tt.err
+				if c, ok := expectedErr.(ErrConflict); ok {
+					// conflicts are reported with the full path
+					expectedErr = ErrConflict{path.Join(target, c.Path)}
+				}
+				if err != expectedErr {
+					t.Fatalf("error result different from expected: got=%v, expected=%v", err, expectedErr)
+				}
+
+				if tt.expected != nil {
+					expected, cleanup, err := test.NewTestDir(tt.expected)
+					if err != nil {
+						t.Fatal(err)
+					}
+					defer cleanup()
+					if !test.DirEquals(target, expected) {
+						t.Fatalf("extracted dir different from expected")
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestExtractProgress(t *testing.T) {
+	target, cleanup, err := test.TmpDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	e, err := NewExtractor(target, uploaderMock.NewUploader(), ExtractConfig{MaxNumFiles: 10, MaxSize: 100, Quota: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reports []Progress
+	e.OnProgress(func(p Progress) {
+		reports = append(reports, p)
+	})
+
+	r := buildZip(t, []entry{
+		{name: "foo", dir: true},
+		{name: "foo/bar", content: "bar"},
+		{name: "empty", content: ""},
+	})
+	p, err := e.ExtractZip(context.Background(), r, r.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Progress{Files: 3, Bytes: 3, TotalFiles: 3, TotalBytes: 3}
+	if p != expected {
+		t.Fatalf("progress different from expected: got=%+v, expected=%+v", p, expected)
+	}
+	if len(reports) != 3 || reports[2] != expected {
+		t.Fatalf("progress reports different from expected: got=%+v", reports)
+	}
+}
@@ -53,6 +53,9 @@ func init() {
 type transferClaims struct {
 	jwt.StandardClaims
 	Target string `json:"target"`
+	// Length is the length an upload was initiated with. 0 means the length
+	// is not limited.
+	Length int64 `json:"length,omitempty"`
 }
 type config struct {
 	Prefix               string `mapstructure:"prefix"`
@@ -297,10 +300,16 @@ func (s *svc) doPut(w http.ResponseWriter, r *http.Request) {
 	targetURL.RawQuery = r.URL.RawQuery
 	target = targetURL.String()
 
+	if claims.Length > 0 && r.ContentLength > claims.Length {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+	body := newLimitedBody(r.Body, claims.Length)
+
 	log.Debug().Str("target", claims.Target).Msg("sending request to internal data server")
 
 	httpClient := s.client
-	httpReq, err := rhttp.NewRequest(ctx, "PUT", target, r.Body)
+	httpReq, err := rhttp.NewRequest(ctx, "PUT", target, body)
 	if err != nil {
 		log.Err(err).Msg("wrong request")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -309,6 +318,10 @@ func (s *svc) doPut(w http.ResponseWriter, r *http.Request) {
 	httpReq.Header = r.Header
 
 	httpRes, err := httpClient.Do(httpReq)
+	if body.exceeded {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
 	if err != nil {
 		log.Err(err).Msg("error doing PUT request to data service")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -391,6 +404,48 @@ func (s *svc) doPatch(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// limitedBody fails reading more than the length an upload was initiated
+// with, so the declared length is enforced whatever the storage driver.
+type limitedBody struct {
+	r        io.ReadCloser
+	left     int64
+	exceeded bool
+}
+
+// newLimitedBody returns the body of an upload limited to length. A length of
+// 0 is not enforced as clients may initiate uploads without a length.
+func newLimitedBody(r io.ReadCloser, length int64) *limitedBody {
+	if length <= 0 {
+		length = -1
+	}
+	return &limitedBody{r: r, left: length}
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.left < 0 {
+		return b.r.Read(p)
+	}
+	if b.left == 0 {
+		// only fail if there is more data
+		n, err := b.r.Read(make([]byte, 1))
+		if n > 0 {
+			b.exceeded = true
+			return 0, errtypes.BadRequest("the upload exceeds its length")
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.left {
+		p = p[:b.left]
+	}
+	n, err := b.r.Read(p)
+	b.left -= int64(n)
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.r.Close()
+}
+
 func copyHeader(dst, src http.Header) {
 	for key, values := range src {
 		for i := range values {
@@ -0,0 +1,88 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package datagateway
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+func TestPutLimitsLength(t *testing.T) {
+	var received string
+	dataServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received = string(b)
+	}))
+	defer dataServer.Close()
+
+	s, err := New(map[string]interface{}{"transfer_shared_secret": "secret"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	put := func(length int64, body string, contentLength int64) int {
+		claims := transferClaims{
+			StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Minute).Unix()},
+			Target:         dataServer.URL + "/simple/upload",
+			Length:         length,
+		}
+		token, err := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), claims).SignedString([]byte("secret"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest("PUT", "/"+token, strings.NewReader(body))
+		r.ContentLength = contentLength
+		w := httptest.NewRecorder()
+		s.Handler().ServeHTTP(w, r)
+		return w.Code
+	}
+
+	tests := []struct {
+		name          string
+		length        int64
+		body          string
+		contentLength int64
+		code          int
+		received      string
+	}{
+		{"within length", 5, "hello", 5, http.StatusOK, "hello"},
+		{"unlimited", 0, "hello world", 11, http.StatusOK, "hello world"},
+		{"declared too long", 5, "hello world", 11, http.StatusRequestEntityTooLarge, ""},
+		{"body too long", 5, "hello world", -1, http.StatusRequestEntityTooLarge, ""},
+	}
+	for _, tt := range tests {
+		received = ""
+		if code := put(tt.length, tt.body, tt.contentLength); code != tt.code {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.code, code)
+		}
+		if received != tt.received {
+			t.Errorf("%s: expected the data server to receive %q, got %q", tt.name, tt.received, received)
+		}
+	}
+}
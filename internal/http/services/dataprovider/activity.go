@@ -0,0 +1,129 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package dataprovider
+
+import (
+	"context"
+	"io"
+	"path"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/activity"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/tuswrap"
+	"github.com/cs3org/reva/pkg/storage"
+	tusd "github.com/tus/tusd/pkg/handler"
+)
+
+// activityFS records the uploads committed through the data provider. The
+// gateway only sees uploads being initiated, not whether they are finished.
+type activityFS struct {
+	storage.FS
+	am activity.Manager
+}
+
+// tusStorage is implemented by the storages supporting the tus protocol.
+type tusStorage interface {
+	UseIn(composer *tusd.StoreComposer)
+	GetUpload(ctx context.Context, id string) (tusd.Upload, error)
+}
+
+// tusActivityFS records the uploads of storages supporting the tus protocol.
+type tusActivityFS struct {
+	*activityFS
+	tus tusStorage
+}
+
+func newActivityFS(fs storage.FS, am activity.Manager) storage.FS {
+	a := &activityFS{FS: fs, am: am}
+	if tus, ok := fs.(tusStorage); ok {
+		return &tusActivityFS{activityFS: a, tus: tus}
+	}
+	return a
+}
+
+// Upload records the upload once it has been committed.
+func (fs *activityFS) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) error {
+	// storages supporting tus identify uploads by an id instead of the target
+	target := ref
+	if tus, ok := fs.FS.(tusStorage); ok {
+		if upload, err := tus.GetUpload(ctx, ref.GetPath()); err == nil {
+			if info, err := upload.GetInfo(ctx); err == nil {
+				target = targetOfInfo(info)
+			}
+		}
+	}
+
+	if err := fs.FS.Upload(ctx, ref, r); err != nil {
+		return err
+	}
+	fs.record(ctx, target)
+	return nil
+}
+
+func (fs *tusActivityFS) UseIn(composer *tusd.StoreComposer) {
+	fs.tus.UseIn(composer)
+	tuswrap.UseIn(composer, func(ctx context.Context, upload tusd.Upload, next func(context.Context) error) error {
+		info, err := upload.GetInfo(ctx)
+		if err != nil {
+			return err
+		}
+		if err := next(ctx); err != nil {
+			return err
+		}
+		fs.record(tuswrap.Uploader(ctx, info), targetOfInfo(info))
+		return nil
+	})
+}
+
+func (fs *tusActivityFS) GetUpload(ctx context.Context, id string) (tusd.Upload, error) {
+	return fs.tus.GetUpload(ctx, id)
+}
+
+// record records the upload of the file at ref. The path is left to be
+// resolved in the namespace of the users reading the activity, the storage
+// only knows its internal one. Errors are only logged, the upload succeeded.
+func (fs *activityFS) record(ctx context.Context, ref *provider.Reference) {
+	log := appctx.GetLogger(ctx)
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok {
+		log.Warn().Msg("dataprovider: no user in context, not recording upload")
+		return
+	}
+	info, err := fs.FS.GetMD(ctx, ref, nil)
+	if err != nil {
+		log.Error().Err(err).Interface("ref", ref).Msg("dataprovider: error stating upload")
+		return
+	}
+	a := &activity.Activity{
+		Type:       activity.TypeUpload,
+		Executant:  u.Id,
+		ResourceID: info.Id,
+		Timestamp:  time.Now(),
+	}
+	if err := fs.am.Record(ctx, a); err != nil {
+		log.Error().Err(err).Msg("dataprovider: error recording upload")
+	}
+}
+
+func targetOfInfo(info tusd.FileInfo) *provider.Reference {
+	return &provider.Reference{Path: path.Join("/", info.MetaData["dir"], info.MetaData["filename"])}
+}
@@ -0,0 +1,112 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package dataprovider
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/activity"
+	"github.com/cs3org/reva/pkg/activity/manager/memory"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/storage/fs/local"
+	"github.com/cs3org/reva/pkg/utils"
+	tusd "github.com/tus/tusd/pkg/handler"
+)
+
+func TestActivityFS(t *testing.T) {
+	root, err := ioutil.TempDir("", "dataprovider-activity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	inner, err := local.New(map[string]interface{}{"root": root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	am, err := memory.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := newActivityFS(inner, am)
+
+	einstein := &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "einstein", Type: userpb.UserType_USER_TYPE_PRIMARY}, Username: "einstein"}
+	ctx := ctxpkg.ContextSetUser(context.Background(), einstein)
+
+	listUploads := func(ref *provider.Reference) []*activity.Activity {
+		info, err := inner.GetMD(ctx, ref, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		list, err := am.ListActivities(ctx, &activity.Filter{ResourceID: info.Id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return list
+	}
+
+	// simple uploads are recorded once they are committed
+	ids, err := fs.InitiateUpload(ctx, &provider.Reference{Path: "/simple.txt"}, 5, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Upload(ctx, &provider.Reference{Path: ids["simple"]}, ioutil.NopCloser(strings.NewReader("hello"))); err != nil {
+		t.Fatal(err)
+	}
+	list := listUploads(&provider.Reference{Path: "/simple.txt"})
+	if len(list) != 1 || list[0].Type != activity.TypeUpload || !utils.UserEqual(list[0].Executant, einstein.Id) {
+		t.Fatalf("expected the upload to be recorded, got %+v", list)
+	}
+
+	// tus uploads are recorded when they are finished, without the context of the request
+	tus, ok := fs.(tusStorage)
+	if !ok {
+		t.Fatal("expected the storage to support tus")
+	}
+	composer := tusd.NewStoreComposer()
+	tus.UseIn(composer)
+
+	ids, err = fs.InitiateUpload(ctx, &provider.Reference{Path: "/tus.txt"}, 5, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload, err := composer.Core.GetUpload(context.Background(), ids["tus"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := upload.WriteChunk(context.Background(), 0, strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := am.ListActivities(ctx, &activity.Filter{}); len(list) != 1 {
+		t.Fatalf("expected unfinished uploads not to be recorded, got %+v", list)
+	}
+	if err := upload.FinishUpload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	list = listUploads(&provider.Reference{Path: "/tus.txt"})
+	if len(list) != 1 || !utils.UserEqual(list[0].Executant, einstein.Id) {
+		t.Fatalf("expected the upload to be recorded, got %+v", list)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"net/http"
 
+	activityregistry "github.com/cs3org/reva/pkg/activity/manager/registry"
 	"github.com/cs3org/reva/pkg/appctx"
 	datatxregistry "github.com/cs3org/reva/pkg/rhttp/datatx/manager/registry"
 	"github.com/cs3org/reva/pkg/rhttp/global"
@@ -43,6 +44,9 @@ type config struct {
 	DataTXs  map[string]map[string]interface{} `mapstructure:"data_txs" docs:"url:pkg/rhttp/datatx/manager/simple/simple.go;The configuration for the data tx protocols"`
 	Timeout  int64                             `mapstructure:"timeout"`
 	Insecure bool                              `mapstructure:"insecure"`
+	// ActivityDriver records the finished uploads, it is usually configured like the one of the activity interceptor
+	ActivityDriver  string                            `mapstructure:"activity_driver" docs:";The activity driver finished uploads are recorded with, e.g. json. Uploads are not recorded if unset."`
+	ActivityDrivers map[string]map[string]interface{} `mapstructure:"activity_drivers" docs:"url:pkg/activity/manager/json/json.go;The configuration of the activity drivers."`
 }
 
 func (c *config) init() {
@@ -75,6 +79,18 @@ func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error)
 		return nil, err
 	}
 
+	if conf.ActivityDriver != "" {
+		f, ok := activityregistry.NewFuncs[conf.ActivityDriver]
+		if !ok {
+			return nil, fmt.Errorf("activity driver not found: %s", conf.ActivityDriver)
+		}
+		am, err := f(conf.ActivityDrivers[conf.ActivityDriver])
+		if err != nil {
+			return nil, err
+		}
+		fs = newActivityFS(fs, am)
+	}
+
 	dataTXs, err := getDataTXs(conf, fs)
 	if err != nil {
 		return nil, err
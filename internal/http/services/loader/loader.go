@@ -20,6 +20,7 @@ package loader
 
 import (
 	// Load core HTTP services
+	_ "github.com/cs3org/reva/internal/http/services/activity"
 	_ "github.com/cs3org/reva/internal/http/services/appprovider"
 	_ "github.com/cs3org/reva/internal/http/services/archiver"
 	_ "github.com/cs3org/reva/internal/http/services/datagateway"
@@ -42,6 +42,11 @@ type Config struct {
 	GatewaySvc       string                      `mapstructure:"gatewaysvc"`
 	MeshDirectoryURL string                      `mapstructure:"mesh_directory_url"`
 	Config           configData                  `mapstructure:"config"`
+	// NotificationManager notifies users about the shares they receive. It needs to
+	// share its storage with the notification manager of the ocs service, e.g. the same json file
+	NotificationManager  string                            `mapstructure:"notification_manager"`
+	NotificationManagers map[string]map[string]interface{} `mapstructure:"notification_managers"`
+	NotificationSMTP     *smtpclient.SMTPCredentials       `mapstructure:"notification_smtp"`
 }
 
 func (c *Config) init() {
@@ -76,7 +81,9 @@ func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error)
 	s.NotificationsHandler = new(notificationsHandler)
 	s.ConfigHandler = new(configHandler)
 	s.InvitesHandler = new(invitesHandler)
-	s.SharesHandler.init(s.Conf)
+	if err := s.SharesHandler.init(s.Conf); err != nil {
+		return nil, err
+	}
 	s.NotificationsHandler.init(s.Conf)
 	s.ConfigHandler.init(s.Conf)
 	s.InvitesHandler.init(s.Conf)
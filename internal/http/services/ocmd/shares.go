@@ -32,16 +32,33 @@ import (
 	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/notification"
+	"github.com/cs3org/reva/pkg/notification/manager/registry"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/pkg/utils"
 )
 
 type sharesHandler struct {
 	gatewayAddr string
+	notifier    *notification.Notifier
 }
 
-func (h *sharesHandler) init(c *Config) {
+func (h *sharesHandler) init(c *Config) error {
 	h.gatewayAddr = c.GatewaySvc
+
+	if c.NotificationManager != "" {
+		f, ok := registry.NewFuncs[c.NotificationManager]
+		if !ok {
+			return errtypes.NotFound("driver not found: " + c.NotificationManager)
+		}
+		m, err := f(c.NotificationManagers[c.NotificationManager])
+		if err != nil {
+			return err
+		}
+		h.notifier = notification.NewNotifier(m, c.NotificationSMTP)
+	}
+	return nil
 }
 
 func (h *sharesHandler) Handler() http.Handler {
@@ -196,6 +213,27 @@ func (h *sharesHandler) createShare(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.notifier != nil {
+		sender := r.FormValue("senderDisplayName")
+		if sender == "" {
+			sender = r.FormValue("ownerDisplayName")
+		}
+		if sender == "" {
+			sender = owner + "@" + meshProvider
+		}
+		err := h.notifier.Notify(ctx, userRes.User, &notification.Notification{
+			Key:        "remote_share:" + createShareResponse.Id,
+			App:        "files_sharing",
+			Subject:    fmt.Sprintf("%s shared %s with you", sender, resource),
+			Message:    fmt.Sprintf("%s shared %s with you from %s.", sender, resource, meshProvider),
+			ObjectType: "remote_share",
+			ObjectID:   createShareResponse.Id,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("error notifying user about ocm share")
+		}
+	}
+
 	timeCreated := createShareResponse.Created
 	jsonOut, err := json.Marshal(
 		map[string]string{
@@ -19,19 +19,62 @@
 package ocdav
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha1"
 	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif" // register the gif decoder for uploaded avatars
+	"io"
+	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
 
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/avatar"
+	"github.com/cs3org/reva/pkg/avatar/registry"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/thumbnails"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	maxAvatarSize = 1024
 )
 
 // AvatarsHandler handles avatar requests
 type AvatarsHandler struct {
+	manager    avatar.Manager
+	thumbnails *thumbnails.Manager
+	maxUpload  int64
 }
 
 func (h *AvatarsHandler) init(c *Config) error {
-	return nil
+	f, ok := registry.NewFuncs[c.AvatarStorageDriver]
+	if !ok {
+		return errtypes.NotFound("driver not found: " + c.AvatarStorageDriver)
+	}
+	m, err := f(c.AvatarStorageDrivers[c.AvatarStorageDriver])
+	if err != nil {
+		return err
+	}
+	h.manager = m
+	h.maxUpload = c.AvatarMaxUploadSize
+	h.thumbnails, err = thumbnails.New(&thumbnails.Config{
+		MaxWidth:     maxAvatarSize,
+		MaxHeight:    maxAvatarSize,
+		MaxInputSize: uint64(c.AvatarMaxUploadSize),
+	})
+	return err
 }
 
 // Handler handles requests
@@ -48,22 +91,226 @@ func (h *AvatarsHandler) Handler(s *svc) http.Handler {
 			return
 		}
 
-		_, r.URL.Path = router.ShiftPath(r.URL.Path)
-		if r.Method == http.MethodGet && r.URL.Path == "/128.png" {
-			// TODO load avatar url from user context?
-			const img = "89504E470D0A1A0A0000000D4948445200000080000000800806000000C33E61CB00000006624B474400FF00FF00FFA0BDA793000000097048597300000B1300000B1301009A9C180000000774494D4507E3061B080516D3ECF61E000008F24944415478DAED9D7D8C1D5515C07FDB76774B775BB7454AA54BBB2D5DDD765B6BD34A140B464CB07EA0113518016B4848FC438A1A448D9A18FF40316942524D544C900F49A17C2882120A28604D506C915AB160B7B2A14275B7BB606BCB76779F7FDC79F4CE79F7BD7DEFED7B3377E69E5FF2B233DBED9B7B3EEECCB977CE3D171445511445511445098B9680645D0BAC8C7EAE020A0E5D0C027B80DDC033EA1ED96521B001D80A3C1F19BB9ECF007003F0CEE83B15CFB90C781A189986D1CB7D8E007F06AE5035FBC599C0359181AA35E6716014188A3EA3D1EFAAFDFFAF025F06DEA2EA4F97EB81935318EB047037F0396035300FE8043A8039D1A723FADD3CA01FB80AB817989CE2BB4F0237AA1992E703C00B150CB313D812057DD36555D4DB7756B8DE41E0236A9664B8A982216E897A72B3980BDC5CE1CE70AB9AA779744541984BF1DF03BA136C4B77F4F871B5E519E074355763590E8C9519A62D4DB15DDDC07E47BBC681156AB6C6D0071C7328F93A60A607ED9B017CDED1BEA35140A94C83259122ED67EE316093876DDD28E61F26A3B69EAD66AC9F61D1AB463D1F7BCF075E126D1E5233D6C74EC7E4CBEA0CB47B317048B4FD6135676D5C2E14F83A705686DA3FD771F7D229E41A823E19507D2A83729CEF90A34FCD3B35F70BA5DD906159AE14B2FC5ACD5B99F384C20E016D19966726B04FC874819AB93C434259EFCD814C2B1C2319C5C14542513FCF916C5B856C17ABB94BF915F1A9D43CCDA2AD20FEDAFA5135779CD9A287FC2D8732EE12322E52B39FE28742391B722863BF90F17635BBA115386C296630C7B2DA492CFFC16423A5CA0C0F94B214938A55E4DE9CC73945E691EEAB6C6F1C605D140314F96D8E1DE009EBB82D923D78EE14CFC63C67DA9E2D64DDA1E687D7882751E49D717452E80DE692DFC99F723C26646E0F390638579C3F1280033CEE888182758035E27C57000EF09438EF0BD9017AC5F940000EB0479CF784EC004BACE362E66FDE1916E7DD213BC07CEBF8BF8104BE72B4B330640768B58E8F0734FA39661D7785EA002DE2FA2703790448676F0DD901EC123593013D02267CB90BCF48591105E110A13051A12304E500E3BEDC0A136666858E105410683B407B20778116605699BB41700E30621DCF09E80E709A757C22640778D93A9E1B501C603BFB70C80EF092753C0B3FD6FB27815DC6E65F213B80CCFFEB0DC0F8B27CCC3F43768003E27C6D000E20339E5F08D9019E9B423979E43C71BE97C0B1B3639E0A40DE3F089983E72FC4EBEAE41DBBDED1F36937C687B4703B55BA050F72E59B488F18EA3EAE0E509A07B826C70E2083DC87D5014C143C669DAFCFB103D8B28D3B82E020E9225EEA3DCF2B839EB4E41C414BCABEC19E4022635BC67D3E346886278AF99138BF3487C6DF2CCE7FA2FD3EEE8876EF78368732CA6251AD6AF6D2D180BDA54B9E6AEC2E25BE25CD633EF53C5FD86E1DCF06DE9D2307D8487C09FC1DDADF4B5981C98E29F692277224DB1F2DB926D0BD04CAF2AC784E2ECB814CB236D05E3573792E10CABA270732FD46C874A19AB9320396B286C9F664C9424C1188A23C2FFA38FCF20D3B185C80D9222EAB7C0C7893757EA7F6EFA9E9A174E3C7AC22B797D3E0AF4AEE168AFB520665F8AA90E101356BF57489DEB39F6C958D6FA77467D337AB59ABA705784828F033196AFF15A2ED8F12D6DAC786B086D22D57B2B07A688EA3DDEBD59CF5F103A1C86D1968F336D1E69FAA19EB6701A6744C5666079789B61ED367FFF4F99650EA11FC5C42D64A3CB3A9007C57CDD7189E168AFDBE876DBC91FCE734A4463F66F3485BC11FF4A87D978AB68D11C632B744B99AD2DD44CFF1A05DEB89BFC62E00D7AAB99AC30EA1E8D7800E8F82BE02709F9AA9799C46E9DE820748A7E2F65B8997BA2F06A81D6AA6E6D289C9A9B7153F98F070EB3D8E9E3F4AFCCD9FD244563B0C3044325BB17DC271ED02F02E354BF2C1D70987219AB9A6E0DA32C6FFA49A231DFACA18647B13AE7553996B6D5333A4CB324CA125DB2813C0CA065EA3D731D42B00B7A9FAFDC136CCFF68ECEE638BA2EF94A38F3655BB1FC8F705CDD87CF23E718D6FAADAFD19168E0AE3346338D625AE314C7CB58F921232FBA6995BCFDD21AEF551557FBAB4736AA38924B26F36503AF9A3A95E29F26002C33F89CC58BE4BCD900E1FA2741E3E89A8BC8D78E2C704F03E3547B2F43AC6E4572778FD2D8EEBF7A859926101F04A0AB77E89DCF5FC1029EF0016024B89EFBE5D00FE413AAF83DB319341765B4E92EF4297A97215A519C2C749E60D603916112FFD52DC14F2323557633803F3EEFD49C73377043F52C2CE1141617149DB4398323767AA19AB6739F005E09798248F51DC6FE00EE357DD80D3817F9769EBAB517CF040143C6AB018B10CB818F80EA61ED0781905CACF0EFC4CBBEAC45434A9468613983AC15F073691AF8A6815E9C1E4CF8F44069FAC5261C5D2EAABF07BE6AD0593A3F05C0D724D46BA18C2AC77E8CE93C1DB804F03B746B7F4420D9F21E07EE02BC0BA0CCADE0F5C8399391CA851F641E076E072329864DA1605463B6A10780CB38E6E2F701D8D7D97EF13E702D7037F8F460B6355EAE741E06D789E7FB004933675A04AA186819B31397C6B896FA516029D98E4D64B22BD1DA9426703C08F7DEA20B380B7535A0ACDF59C3B0CEC06BE019CA531B0933E4C8EE100A51948AE6078252916FADA8829803C51A191AF005FC32CA298AFF6ADA9632D8E628017A77874EE8E3A6162F402BFA8D0A8039852E8FD6AC786B10E938CF27205BD6F4F628EE18B94AED22D7E0E621226B40C7AF368053E4EE9CA287B7E6173332EDC8149B4745DF477C087D53689B3391A4DB86C720B0DAEA774D07191A3C0F96A87D4D952663839D8A85BCE2EC7977F9B6C54EC0A851EE0670E3BED9EEEDCC1FB1D51E73AD5B7B75CE888D12E99CE17CAE95B5D04E93F17519A2B5917B2FAC53ED56D6678840614A9FEACF8924DAAD7CCB0BEDA3B77A569C47788F3DFAB5E33C37E71BEB81E07586E1D1F45F7BACF1AF67ECC67D4E30036AFAB03648A494CB26A91B28B6567D5F0A573D07570596176E40045C3774ED7011670EA3DBFE23F2DC2E8EDF538408B389EA77ACD2C6DF5C40007556FB9E1AFD5F472175762B66D9B2D6EFF05F19332E7D4F877AE7F6FF66327EF8FB53F015BB50F288AA2288AA2288A62F83FEC37068C6750398B0000000049454E44AE426082"
-			decoded, err := hex.DecodeString(img)
-			if err != nil {
-				log.Error().Err(err).Msg("error decoding string")
-				w.WriteHeader(http.StatusInternalServerError)
-			}
-			w.Header().Set(HeaderContentType, "image/png")
-			if _, err := w.Write(decoded); err != nil {
-				log.Error().Err(err).Msg("error writing data response")
-			}
-			return
+		var userIDorName string
+		userIDorName, r.URL.Path = router.ShiftPath(r.URL.Path)
+		sublog := log.With().Str("user", userIDorName).Str("handler", "avatars").Logger()
+
+		switch r.Method {
+		case http.MethodGet:
+			h.handleGet(ctx, w, r, s, userIDorName, sublog)
+		case http.MethodPut:
+			h.handlePut(ctx, w, r, userIDorName, sublog)
+		case http.MethodDelete:
+			h.handleDelete(ctx, w, r, userIDorName, sublog)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
 		}
+	})
+}
 
+// handleGet serves /avatars/{user}/{size}.png from the uploaded avatar, the
+// picture returned by the user provider or a generated initials avatar.
+func (h *AvatarsHandler) handleGet(ctx context.Context, w http.ResponseWriter, r *http.Request, s *svc, userIDorName string, log zerolog.Logger) {
+	size, err := parseAvatarSize(r.URL.Path)
+	if err != nil {
+		log.Debug().Err(err).Msg("invalid avatar size")
 		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	u, err := s.getAvatarUser(ctx, userIDorName)
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotFound); ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		log.Error().Err(err).Msg("error looking up user")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	a, err := h.manager.GetAvatar(ctx, u.Id)
+	switch err.(type) {
+	case nil:
+	case errtypes.IsNotFound:
+		a = userAvatar(u)
+	default:
+		log.Error().Err(err).Msg("error reading avatar")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var data []byte
+	var etag, mimeType string
+	if a != nil {
+		sum := sha1.Sum(a.Data)
+		etag = fmt.Sprintf(`"%s-%d"`, hex.EncodeToString(sum[:]), size)
+		if r.Header.Get(HeaderIfNoneMatch) == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		t, err := h.thumbnails.Get(ctx, &thumbnails.Request{
+			ResourceID: "avatar:" + u.Id.Idp + "!" + u.Id.OpaqueId,
+			Etag:       etag,
+			MimeType:   a.MimeType,
+			Size:       uint64(len(a.Data)),
+			Width:      size,
+			Height:     size,
+		}, func(context.Context) (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(a.Data)), nil
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("error scaling avatar")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		data, mimeType = t.Data, t.MimeType
+	} else {
+		name := u.DisplayName
+		if name == "" {
+			name = u.Username
+		}
+		sum := sha1.Sum([]byte(u.Id.OpaqueId + "\x00" + name))
+		etag = fmt.Sprintf(`"initials-%s-%d"`, hex.EncodeToString(sum[:]), size)
+		if r.Header.Get(HeaderIfNoneMatch) == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		data, err = avatar.Generate(u.Id.OpaqueId, name, size)
+		if err != nil {
+			log.Error().Err(err).Msg("error generating avatar")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		mimeType = "image/png"
+	}
+
+	w.Header().Set(HeaderContentType, mimeType)
+	w.Header().Set(HeaderContentLength, strconv.Itoa(len(data)))
+	w.Header().Set(HeaderETag, etag)
+	w.Header().Set(HeaderCacheControl, "private, no-cache")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(data); err != nil {
+		log.Error().Err(err).Msg("error writing data response")
+	}
+}
+
+// handlePut stores the avatar uploaded by the current user.
+func (h *AvatarsHandler) handlePut(ctx context.Context, w http.ResponseWriter, r *http.Request, userIDorName string, log zerolog.Logger) {
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok || !isOwner(userIDorName, u) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(r.Body, h.maxUpload+1))
+	if err != nil {
+		log.Error().Err(err).Msg("error reading avatar")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if int64(len(data)) > h.maxUpload {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+	if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+		log.Debug().Err(err).Msg("uploaded avatar is not an image")
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	mimeType := http.DetectContentType(data)
+	if !h.thumbnails.Supports(mimeType) {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	if err := h.manager.SetAvatar(ctx, u.Id, &avatar.Avatar{MimeType: mimeType, Data: data}); err != nil {
+		log.Error().Err(err).Msg("error storing avatar")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDelete removes the avatar of the current user, falling back to the generated one.
+func (h *AvatarsHandler) handleDelete(ctx context.Context, w http.ResponseWriter, r *http.Request, userIDorName string, log zerolog.Logger) {
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok || !isOwner(userIDorName, u) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if err := h.manager.DeleteAvatar(ctx, u.Id); err != nil {
+		log.Error().Err(err).Msg("error removing avatar")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getAvatarUser looks up the user identified by its id or username in the avatar url.
+func (s *svc) getAvatarUser(ctx context.Context, userIDorName string) (*userpb.User, error) {
+	client, err := s.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var id *userpb.UserId
+	if u, ok := ctxpkg.ContextGetUser(ctx); ok && isOwner(userIDorName, u) {
+		id = u.Id
+	} else {
+		res, err := client.GetUserByClaim(ctx, &userpb.GetUserByClaimRequest{Claim: "username", Value: userIDorName})
+		if err != nil {
+			return nil, err
+		}
+		if res.Status.Code == rpc.Code_CODE_NOT_FOUND {
+			return nil, errtypes.NotFound(userIDorName)
+		} else if res.Status.Code != rpc.Code_CODE_OK {
+			return nil, errors.New(res.Status.Message)
+		}
+		id = res.User.Id
+	}
+
+	// fetch the full user, asking the user provider to include its picture, e.g. an LDAP jpegPhoto
+	res, err := client.GetUser(ctx, &userpb.GetUserRequest{
+		UserId: id,
+		Opaque: &types.Opaque{
+			Map: map[string]*types.OpaqueEntry{
+				user.AvatarOpaqueKey: {Decoder: "plain", Value: []byte("1")},
+			},
+		},
 	})
+	if err != nil {
+		return nil, err
+	}
+	if res.Status.Code == rpc.Code_CODE_NOT_FOUND {
+		return nil, errtypes.NotFound(userIDorName)
+	} else if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, errors.New(res.Status.Message)
+	}
+	return res.User, nil
+}
+
+// userAvatar returns the picture provided by the user provider, if any.
+func userAvatar(u *userpb.User) *avatar.Avatar {
+	if u.Opaque == nil || u.Opaque.Map == nil {
+		return nil
+	}
+	e, ok := u.Opaque.Map[user.AvatarOpaqueKey]
+	if !ok || len(e.Value) == 0 {
+		return nil
+	}
+	return &avatar.Avatar{MimeType: http.DetectContentType(e.Value), Data: e.Value}
+}
+
+// parseAvatarSize parses the size from paths like /128.png
+func parseAvatarSize(p string) (int, error) {
+	name := strings.TrimPrefix(p, "/")
+	if !strings.HasSuffix(name, ".png") {
+		return 0, errors.Wrap(errInvalidValue, "unexpected avatar path "+p)
+	}
+	size, err := strconv.Atoi(strings.TrimSuffix(name, ".png"))
+	if err != nil || size <= 0 || size > maxAvatarSize {
+		return 0, errors.Wrap(errInvalidValue, "invalid avatar size "+name)
+	}
+	return size, nil
 }
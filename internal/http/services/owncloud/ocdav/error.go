@@ -23,6 +23,7 @@ import (
 	"net/http"
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
@@ -119,6 +120,14 @@ func HandleErrorStatus(log *zerolog.Logger, w http.ResponseWriter, s *rpc.Status
 	case rpc.Code_CODE_INSUFFICIENT_STORAGE:
 		log.Debug().Interface("status", s).Msg("insufficient storage")
 		w.WriteHeader(http.StatusInsufficientStorage)
+	case rpc.Code_CODE_ABORTED:
+		if !status.IsLocked(s) {
+			log.Error().Interface("status", s).Msg("grpc request failed")
+			w.WriteHeader(http.StatusInternalServerError)
+			break
+		}
+		log.Debug().Interface("status", s).Msg("resource is locked")
+		w.WriteHeader(http.StatusLocked)
 	case rpc.Code_CODE_FAILED_PRECONDITION:
 		log.Debug().Interface("status", s).Msg("destination does not exist")
 		w.WriteHeader(http.StatusConflict)
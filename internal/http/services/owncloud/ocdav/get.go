@@ -28,6 +28,7 @@ import (
 	"strings"
 	"time"
 
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/internal/grpc/services/storageprovider"
@@ -76,31 +77,20 @@ func (s *svc) handleGet(ctx context.Context, w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	dReq := &provider.InitiateFileDownloadRequest{Ref: ref}
-	dRes, err := client.InitiateFileDownload(ctx, dReq)
-	if err != nil {
-		log.Error().Err(err).Msg("error initiating file download")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	} else if dRes.Status.Code != rpc.Code_CODE_OK {
-		HandleErrorStatus(&log, w, dRes.Status)
+	if s.thumbnails != nil && r.URL.Query().Get("preview") == "1" {
+		s.handlePreview(ctx, w, r, client, ref, sRes.Info, dlProtocol, log)
 		return
 	}
 
-	var ep, token string
-	for _, p := range dRes.Protocols {
-		if p.Protocol == dlProtocol {
-			ep, token = p.DownloadEndpoint, p.Token
-		}
-	}
-
-	httpReq, err := rhttp.NewRequest(ctx, http.MethodGet, ep, nil)
+	httpReq, status, err := newDownloadRequest(ctx, client, &provider.InitiateFileDownloadRequest{Ref: ref}, dlProtocol)
 	if err != nil {
-		log.Error().Err(err).Msg("error creating http request")
+		log.Error().Err(err).Msg("error initiating file download")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
+	} else if status.Code != rpc.Code_CODE_OK {
+		HandleErrorStatus(&log, w, status)
+		return
 	}
-	httpReq.Header.Set(datagateway.TokenTransportHeader, token)
 
 	if r.Header.Get(HeaderRange) != "" {
 		httpReq.Header.Set(HeaderRange, r.Header.Get(HeaderRange))
@@ -159,6 +149,31 @@ func (s *svc) handleGet(ctx context.Context, w http.ResponseWriter, r *http.Requ
 	// TODO we need to send the If-Match etag in the GET to the datagateway to prevent race conditions between stating and reading the file
 }
 
+// newDownloadRequest initiates the download of the requested file and returns
+// the request fetching its content from the data gateway.
+func newDownloadRequest(ctx context.Context, client gateway.GatewayAPIClient, req *provider.InitiateFileDownloadRequest, dlProtocol string) (*http.Request, *rpc.Status, error) {
+	dRes, err := client.InitiateFileDownload(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	} else if dRes.Status.Code != rpc.Code_CODE_OK {
+		return nil, dRes.Status, nil
+	}
+
+	var ep, token string
+	for _, p := range dRes.Protocols {
+		if p.Protocol == dlProtocol {
+			ep, token = p.DownloadEndpoint, p.Token
+		}
+	}
+
+	httpReq, err := rhttp.NewRequest(ctx, http.MethodGet, ep, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	httpReq.Header.Set(datagateway.TokenTransportHeader, token)
+	return httpReq, dRes.Status, nil
+}
+
 func (s *svc) handleSpacesGet(w http.ResponseWriter, r *http.Request, spaceID string) {
 	ctx, span := rtrace.Provider.Tracer("reva").Start(r.Context(), "spaces_get")
 	defer span.End()
@@ -41,6 +41,7 @@ import (
 	"github.com/cs3org/reva/pkg/storage/favorite"
 	"github.com/cs3org/reva/pkg/storage/favorite/registry"
 	"github.com/cs3org/reva/pkg/storage/utils/templates"
+	"github.com/cs3org/reva/pkg/thumbnails"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
@@ -102,6 +103,13 @@ type Config struct {
 	PublicURL              string                            `mapstructure:"public_url"`
 	FavoriteStorageDriver  string                            `mapstructure:"favorite_storage_driver"`
 	FavoriteStorageDrivers map[string]map[string]interface{} `mapstructure:"favorite_storage_drivers"`
+	AvatarStorageDriver    string                            `mapstructure:"avatar_storage_driver"`
+	AvatarStorageDrivers   map[string]map[string]interface{} `mapstructure:"avatar_storage_drivers"`
+	// AvatarMaxUploadSize is the maximum size in bytes of uploaded avatars.
+	AvatarMaxUploadSize int64 `mapstructure:"avatar_max_upload_size"`
+	// EnablePreviews renders thumbnails for ?preview=1 GET requests and reports them in oc:has-preview.
+	EnablePreviews bool              `mapstructure:"enable_previews"`
+	Previews       thumbnails.Config `mapstructure:"previews"`
 }
 
 func (c *Config) init() {
@@ -111,6 +119,13 @@ func (c *Config) init() {
 	if c.FavoriteStorageDriver == "" {
 		c.FavoriteStorageDriver = "memory"
 	}
+
+	if c.AvatarStorageDriver == "" {
+		c.AvatarStorageDriver = "memory"
+	}
+	if c.AvatarMaxUploadSize == 0 {
+		c.AvatarMaxUploadSize = 5 * 1024 * 1024
+	}
 }
 
 type svc struct {
@@ -118,6 +133,7 @@ type svc struct {
 	webDavHandler    *WebDavHandler
 	davHandler       *DavHandler
 	favoritesManager favorite.Manager
+	thumbnails       *thumbnails.Manager
 	client           *http.Client
 }
 
@@ -152,6 +168,11 @@ func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error)
 		),
 		favoritesManager: fm,
 	}
+	if conf.EnablePreviews {
+		if s.thumbnails, err = thumbnails.New(&conf.Previews); err != nil {
+			return nil, err
+		}
+	}
 	// initialize handlers and set default configs
 	if err := s.webDavHandler.init(conf.WebdavNamespace, true); err != nil {
 		return nil, err
@@ -167,6 +188,9 @@ func (s *svc) Prefix() string {
 }
 
 func (s *svc) Close() error {
+	if s.thumbnails != nil {
+		return s.thumbnails.Close()
+	}
 	return nil
 }
 
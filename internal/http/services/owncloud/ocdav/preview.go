@@ -0,0 +1,116 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/thumbnails"
+	"github.com/cs3org/reva/pkg/utils/resourceid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// handlePreview answers the ownCloud preview requests, e.g. GET /file.jpg?preview=1&x=32&y=32&a=1
+func (s *svc) handlePreview(ctx context.Context, w http.ResponseWriter, r *http.Request, client gateway.GatewayAPIClient, ref *provider.Reference, info *provider.ResourceInfo, dlProtocol string, log zerolog.Logger) {
+	q := r.URL.Query()
+	width, err := parsePreviewDimension(q.Get("x"))
+	if err != nil {
+		log.Debug().Err(err).Msg("invalid preview width")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	height, err := parsePreviewDimension(q.Get("y"))
+	if err != nil {
+		log.Debug().Err(err).Msg("invalid preview height")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	req := &thumbnails.Request{
+		ResourceID: resourceid.OwnCloudResourceIDWrap(info.Id),
+		Etag:       info.Etag,
+		MimeType:   info.MimeType,
+		Size:       info.Size,
+		Width:      width,
+		Height:     height,
+		KeepAspect: q.Get("a") == "1",
+	}
+	// thumbnails rendered in advance are found by the checksum of the content
+	if info.Checksum.GetType() == provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_SHA1 {
+		req.Checksum = info.Checksum.Sum
+	}
+	fetch := func(ctx context.Context) (io.ReadCloser, error) {
+		httpReq, status, err := newDownloadRequest(ctx, client, &provider.InitiateFileDownloadRequest{Ref: ref}, dlProtocol)
+		if err != nil {
+			return nil, err
+		} else if status.Code != rpc.Code_CODE_OK {
+			return nil, errors.New(status.Message)
+		}
+		httpRes, err := s.client.Do(httpReq)
+		if err != nil {
+			return nil, err
+		}
+		if httpRes.StatusCode != http.StatusOK {
+			httpRes.Body.Close()
+			return nil, fmt.Errorf("unexpected status %d from data gateway", httpRes.StatusCode)
+		}
+		return httpRes.Body, nil
+	}
+
+	t, err := s.thumbnails.Get(ctx, req, fetch)
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotSupported); ok {
+			log.Debug().Err(err).Msg("no preview available")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		log.Error().Err(err).Msg("error generating preview")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(HeaderContentType, t.MimeType)
+	w.Header().Set(HeaderContentLength, strconv.Itoa(len(t.Data)))
+	w.Header().Set(HeaderETag, info.Etag)
+	w.Header().Set(HeaderOCFileID, resourceid.OwnCloudResourceIDWrap(info.Id))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(t.Data); err != nil {
+		log.Error().Err(err).Msg("error writing preview")
+	}
+}
+
+func parsePreviewDimension(v string) (int, error) {
+	if v == "" {
+		return 0, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil || i < 0 {
+		return 0, errors.Wrap(errInvalidValue, "invalid preview dimension "+v)
+	}
+	return i, nil
+}
@@ -508,6 +508,7 @@ func (s *svc) mdToPropResponse(ctx context.Context, pf *propfindXML, md *provide
 	// -3 indicates unlimited
 	quota := _propQuotaUnknown
 	size := fmt.Sprintf("%d", md.Size)
+	var processingStatus string
 	// TODO refactor helper functions: GetOpaqueJSONEncoded(opaque, key string, *struct) err, GetOpaquePlainEncoded(opaque, key) value, err
 	// or use ok like pattern and return bool?
 	if md.Opaque != nil && md.Opaque.Map != nil {
@@ -521,6 +522,10 @@ func (s *svc) mdToPropResponse(ctx context.Context, pf *propfindXML, md *provide
 		if md.Opaque.Map["quota"] != nil && md.Opaque.Map["quota"].Decoder == "plain" {
 			quota = string(md.Opaque.Map["quota"].Value)
 		}
+		// e.g. "processing" while an upload is being post-processed
+		if md.Opaque.Map["status"] != nil && md.Opaque.Map["status"].Decoder == "plain" {
+			processingStatus = string(md.Opaque.Map["status"].Value)
+		}
 	}
 
 	role := conversions.RoleFromResourcePermissions(md.PermissionSet)
@@ -626,6 +631,10 @@ func (s *svc) mdToPropResponse(ctx context.Context, pf *propfindXML, md *provide
 			propstatOK.Prop = append(propstatOK.Prop, s.newPropRaw("oc:checksums", checksums.String()))
 		}
 
+		if processingStatus != "" {
+			propstatOK.Prop = append(propstatOK.Prop, s.newProp("oc:processing-status", processingStatus))
+		}
+
 		// ls do not report any properties as missing by default
 		if ls == nil {
 			// favorites from arbitrary metadata
@@ -794,6 +803,12 @@ func (s *svc) mdToPropResponse(ctx context.Context, pf *propfindXML, md *provide
 					} else {
 						propstatNotFound.Prop = append(propstatNotFound.Prop, s.newProp("oc:checksums", ""))
 					}
+				case "processing-status":
+					if processingStatus != "" {
+						propstatOK.Prop = append(propstatOK.Prop, s.newProp("oc:processing-status", processingStatus))
+					} else {
+						propstatNotFound.Prop = append(propstatNotFound.Prop, s.newProp("oc:processing-status", ""))
+					}
 				case "share-types": // desktop
 					var types strings.Builder
 					k := md.GetArbitraryMetadata()
@@ -866,6 +881,12 @@ func (s *svc) mdToPropResponse(ctx context.Context, pf *propfindXML, md *provide
 							propstatNotFound.Prop = append(propstatNotFound.Prop, s.newProp("oc:signature-auth", ""))
 						}
 					}
+				case "has-preview": // both
+					if md.Type == provider.ResourceType_RESOURCE_TYPE_FILE && s.thumbnails != nil && s.thumbnails.Supports(md.MimeType) {
+						propstatOK.Prop = append(propstatOK.Prop, s.newProp("oc:has-preview", "1"))
+					} else {
+						propstatOK.Prop = append(propstatOK.Prop, s.newProp("oc:has-preview", "0"))
+					}
 				case "privatelink": // phoenix only
 					// <oc:privatelink>https://phoenix.owncloud.com/f/9</oc:privatelink>
 					fallthrough
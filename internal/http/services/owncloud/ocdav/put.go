@@ -26,6 +26,7 @@ import (
 	"strings"
 	"time"
 
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
@@ -33,6 +34,7 @@ import (
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/virusscan"
 	"github.com/cs3org/reva/pkg/storage/utils/chunking"
 	rtrace "github.com/cs3org/reva/pkg/trace"
 	"github.com/cs3org/reva/pkg/utils"
@@ -184,6 +186,14 @@ func (s *svc) handlePut(ctx context.Context, w http.ResponseWriter, r *http.Requ
 		w.Header().Set(HeaderOCMtime, "accepted")
 	}
 
+	if name := r.Header.Get(HeaderUploaderName); name != "" {
+		// used to sort uploads to file drop shares by uploader
+		opaqueMap[HeaderUploaderName] = &typespb.OpaqueEntry{
+			Decoder: "plain",
+			Value:   []byte(name),
+		}
+	}
+
 	// curl -X PUT https://demo.owncloud.com/remote.php/webdav/testcs.bin -u demo:demo -d '123' -v -H 'OC-Checksum: SHA1:40bd001563085fc35165329ea1ff5c5ecbdbbeef'
 
 	var cparts []string
@@ -279,6 +289,16 @@ func (s *svc) handlePut(ctx context.Context, w http.ResponseWriter, r *http.Requ
 			HandleWebdavError(&log, w, b, err)
 			return
 		}
+		if virus := httpRes.Header.Get(virusscan.InfectedHeader); virus != "" {
+			w.Header().Set(virusscan.InfectedHeader, virus)
+			w.WriteHeader(http.StatusForbidden)
+			b, err := Marshal(exception{
+				code:    SabredavPermissionDenied,
+				message: "the upload was refused because it contains a virus: " + virus,
+			})
+			HandleWebdavError(&log, w, b, err)
+			return
+		}
 		log.Error().Err(err).Msg("PUT request to data server failed")
 		w.WriteHeader(httpRes.StatusCode)
 		return
@@ -306,6 +326,12 @@ func (s *svc) handlePut(ctx context.Context, w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if sRes.Status.Code == rpc.Code_CODE_NOT_FOUND && info == nil && isFileDropUpload(uRes) {
+		// the uploaded file is hidden in file drop shares
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
 	if sRes.Status.Code != rpc.Code_CODE_OK {
 		HandleErrorStatus(&log, w, sRes.Status)
 		return
@@ -381,3 +407,10 @@ func getContentLength(w http.ResponseWriter, r *http.Request) (int64, error) {
 	}
 	return length, nil
 }
+
+// isFileDropUpload tells if an upload goes to a file drop share, whose
+// uploads can't be stated by the uploaders.
+func isFileDropUpload(res *gateway.InitiateFileUploadResponse) bool {
+	e := res.GetOpaque().GetMap()["file-drop"]
+	return e != nil && e.Decoder == "plain" && string(e.Value) == "true"
+}
@@ -34,6 +34,7 @@ import (
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/rhttp"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/virusscan"
 	rtrace "github.com/cs3org/reva/pkg/trace"
 	"github.com/cs3org/reva/pkg/utils"
 	"github.com/cs3org/reva/pkg/utils/resourceid"
@@ -172,6 +173,14 @@ func (s *svc) handleTusPost(ctx context.Context, w http.ResponseWriter, r *http.
 		}
 	}
 
+	if name := r.Header.Get(HeaderUploaderName); name != "" {
+		// used to sort uploads to file drop shares by uploader
+		opaqueMap[HeaderUploaderName] = &typespb.OpaqueEntry{
+			Decoder: "plain",
+			Value:   []byte(name),
+		}
+	}
+
 	// initiateUpload
 	uReq := &provider.InitiateFileUploadRequest{
 		Ref: ref,
@@ -254,6 +263,9 @@ func (s *svc) handleTusPost(ctx context.Context, w http.ResponseWriter, r *http.
 		w.Header().Set(HeaderUploadOffset, httpRes.Header.Get(HeaderUploadOffset))
 		w.Header().Set(HeaderTusResumable, httpRes.Header.Get(HeaderTusResumable))
 		w.Header().Set(HeaderTusUploadExpires, httpRes.Header.Get(HeaderTusUploadExpires))
+		if virus := httpRes.Header.Get(virusscan.InfectedHeader); virus != "" {
+			w.Header().Set(virusscan.InfectedHeader, virus)
+		}
 		if httpRes.StatusCode != http.StatusNoContent {
 			w.WriteHeader(httpRes.StatusCode)
 			return
@@ -285,6 +297,11 @@ func (s *svc) handleTusPost(ctx context.Context, w http.ResponseWriter, r *http.
 			}
 
 			info := sRes.Info
+			if info == nil && sRes.Status.Code == rpc.Code_CODE_NOT_FOUND && isFileDropUpload(uRes) {
+				// the uploaded file is hidden in file drop shares
+				w.WriteHeader(http.StatusCreated)
+				return
+			}
 			if info == nil {
 				log.Error().Msg("No info found for uploaded file")
 				w.WriteHeader(http.StatusInternalServerError)
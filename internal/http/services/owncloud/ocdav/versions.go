@@ -20,8 +20,11 @@ package ocdav
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"path"
+	"strconv"
+	"time"
 
 	rtrace "github.com/cs3org/reva/pkg/trace"
 	"github.com/cs3org/reva/pkg/utils/resourceid"
@@ -44,6 +47,7 @@ func (h *VersionsHandler) init(c *Config) error {
 // Handler handles requests
 // versions can be listed with a PROPFIND to /remote.php/dav/meta/<fileid>/v
 // a version is identified by a timestamp, eg. /remote.php/dav/meta/<fileid>/v/1561410426
+// and its content can be fetched with a GET
 func (h *VersionsHandler) Handler(s *svc, rid *provider.ResourceId) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -68,8 +72,11 @@ func (h *VersionsHandler) Handler(s *svc, rid *provider.ResourceId) http.Handler
 			h.doListVersions(w, r, s, rid)
 			return
 		}
+		if key != "" && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+			h.doDownload(w, r, s, rid, key)
+			return
+		}
 		if key != "" && r.Method == MethodCopy {
-			// TODO(jfd) it seems we cannot directly GET version content with cs3 ...
 			// TODO(jfd) cs3api has no delete file version call
 			// TODO(jfd) restore version to given Destination, but cs3api has no destination
 			h.doRestore(w, r, s, rid, key)
@@ -212,3 +219,111 @@ func (h *VersionsHandler) doRestore(w http.ResponseWriter, r *http.Request, s *s
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func (h *VersionsHandler) doDownload(w http.ResponseWriter, r *http.Request, s *svc, rid *provider.ResourceId, key string) {
+	ctx, span := rtrace.Provider.Tracer("ocdav").Start(r.Context(), "downloadVersion")
+	defer span.End()
+
+	sublog := appctx.GetLogger(ctx).With().Interface("resourceid", rid).Str("key", key).Logger()
+
+	client, err := s.getClient()
+	if err != nil {
+		sublog.Error().Err(err).Msg("error getting grpc client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ref := &provider.Reference{ResourceId: rid}
+	sRes, err := client.Stat(ctx, &provider.StatRequest{Ref: ref})
+	switch {
+	case err != nil:
+		sublog.Error().Err(err).Msg("error sending grpc stat request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	case sRes.Status.Code != rpc.Code_CODE_OK:
+		HandleErrorStatus(&sublog, w, sRes.Status)
+		return
+	}
+
+	lvRes, err := client.ListFileVersions(ctx, &provider.ListFileVersionsRequest{Ref: ref})
+	switch {
+	case err != nil:
+		sublog.Error().Err(err).Msg("error sending list file versions grpc request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	case lvRes.Status.Code != rpc.Code_CODE_OK:
+		HandleErrorStatus(&sublog, w, lvRes.Status)
+		return
+	}
+	var version *provider.FileVersion
+	for _, v := range lvRes.Versions {
+		if v.Key == key {
+			version = v
+		}
+	}
+	if version == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// revisions are downloaded like the file itself, but with the version key
+	dReq := &provider.InitiateFileDownloadRequest{
+		Ref: &provider.Reference{ResourceId: rid, Path: "."},
+		Opaque: &types.Opaque{
+			Map: map[string]*types.OpaqueEntry{
+				"version": {
+					Decoder: "plain",
+					Value:   []byte(key),
+				},
+			},
+		},
+	}
+	httpReq, status, err := newDownloadRequest(ctx, client, dReq, "spaces")
+	if err != nil {
+		sublog.Error().Err(err).Msg("error initiating version download")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	} else if status.Code != rpc.Code_CODE_OK {
+		HandleErrorStatus(&sublog, w, status)
+		return
+	}
+	httpReq.Method = r.Method
+	if r.Header.Get(HeaderRange) != "" {
+		httpReq.Header.Set(HeaderRange, r.Header.Get(HeaderRange))
+	}
+
+	httpRes, err := s.client.Do(httpReq)
+	if err != nil {
+		sublog.Error().Err(err).Msg("error performing http request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer httpRes.Body.Close()
+
+	if httpRes.StatusCode != http.StatusOK && httpRes.StatusCode != http.StatusPartialContent {
+		w.WriteHeader(httpRes.StatusCode)
+		return
+	}
+
+	info := sRes.Info
+	name := path.Base(info.Path)
+	w.Header().Set(HeaderContentType, info.MimeType)
+	w.Header().Set(HeaderContentDisposistion, "attachment; filename*=UTF-8''"+name+"; filename=\""+name+"\"")
+	w.Header().Set(HeaderETag, version.Etag)
+	w.Header().Set(HeaderOCFileID, resourceid.OwnCloudResourceIDWrap(info.Id))
+	w.Header().Set(HeaderLastModified, time.Unix(int64(version.Mtime), 0).UTC().Format(time.RFC1123Z))
+
+	if httpRes.StatusCode == http.StatusPartialContent {
+		w.Header().Set(HeaderContentRange, httpRes.Header.Get(HeaderContentRange))
+		w.Header().Set(HeaderContentLength, httpRes.Header.Get(HeaderContentLength))
+		w.WriteHeader(http.StatusPartialContent)
+	} else {
+		w.Header().Set(HeaderContentLength, strconv.FormatUint(version.Size, 10))
+	}
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := io.Copy(w, httpRes.Body); err != nil {
+		sublog.Error().Err(err).Msg("error finishing copying data to response")
+	}
+}
@@ -51,6 +51,8 @@ const (
 	HeaderLocation                   = "Location"
 	HeaderRange                      = "Range"
 	HeaderIfMatch                    = "If-Match"
+	HeaderIfNoneMatch                = "If-None-Match"
+	HeaderCacheControl               = "Cache-Control"
 )
 
 // Non standard HTTP headers.
@@ -74,6 +76,7 @@ const (
 	HeaderUploadOffset         = "Upload-Offset"
 	HeaderOCMtime              = "X-OC-Mtime"
 	HeaderExpectedEntityLength = "X-Expected-Entity-Length"
+	HeaderUploaderName         = "X-Uploader-Name"
 )
 
 // WebDavHandler implements a dav endpoint
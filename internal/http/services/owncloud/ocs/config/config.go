@@ -21,6 +21,7 @@ package config
 import (
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/data"
 	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/smtpclient"
 )
 
 // Config holds the config options that need to be passed down to all ocs handlers
@@ -40,6 +41,17 @@ type Config struct {
 	ResourceInfoCacheSize   int                               `mapstructure:"resource_info_cache_size"`
 	ResourceInfoCacheTTL    int                               `mapstructure:"resource_info_cache_ttl"`
 	UserIdentifierCacheTTL  int                               `mapstructure:"user_identifier_cache_ttl"`
+	NotificationManager     string                            `mapstructure:"notification_manager"`
+	NotificationManagers    map[string]map[string]interface{} `mapstructure:"notification_managers"`
+	// NotificationSMTP sends notifications by e-mail as well if set
+	NotificationSMTP *smtpclient.SMTPCredentials `mapstructure:"notification_smtp"`
+	// ShareExpiryWarning is the number of days before their expiry users are notified about expiring public links, -1 disables the warning
+	ShareExpiryWarning int `mapstructure:"share_expiry_warning"`
+	// UserProviderSvc and GroupProviderSvc provision users and groups through the provisioning API, it is read-only if they are not set
+	UserProviderSvc  string `mapstructure:"userprovidersvc"`
+	GroupProviderSvc string `mapstructure:"groupprovidersvc"`
+	// AdminGroup is the group of the users allowed to provision users and groups, it is required for provisioning
+	AdminGroup string `mapstructure:"admin_group"`
 }
 
 // Init sets sane defaults
@@ -72,5 +84,13 @@ func (c *Config) Init() {
 		c.UserIdentifierCacheTTL = 60
 	}
 
+	if c.NotificationManager == "" {
+		c.NotificationManager = "memory"
+	}
+
+	if c.ShareExpiryWarning == 0 {
+		c.ShareExpiryWarning = 3
+	}
+
 	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
 }
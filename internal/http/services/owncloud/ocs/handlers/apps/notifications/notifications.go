@@ -0,0 +1,242 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ReneKroon/ttlcache/v2"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/config"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/notification"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// expiringSharesScanInterval is the minimum time between two scans for expiring
+// public links of a user. The notifications are only created once per share
+// and expiration, so polling clients don't need a fresh scan on every request.
+const expiringSharesScanInterval = time.Hour
+
+// Handler implements the ownCloud notifications API
+type Handler struct {
+	gatewayAddr   string
+	notifier      *notification.Notifier
+	expiryWarning time.Duration
+	// scanned tracks the users whose public links have been scanned recently
+	scanned *ttlcache.Cache
+}
+
+// Notification is the ownCloud representation of a notification
+type Notification struct {
+	NotificationID int64    `json:"notification_id" xml:"notification_id"`
+	App            string   `json:"app" xml:"app"`
+	User           string   `json:"user" xml:"user"`
+	Datetime       string   `json:"datetime" xml:"datetime"`
+	ObjectType     string   `json:"object_type" xml:"object_type"`
+	ObjectID       string   `json:"object_id" xml:"object_id"`
+	Subject        string   `json:"subject" xml:"subject"`
+	Message        string   `json:"message" xml:"message"`
+	Link           string   `json:"link" xml:"link"`
+	Actions        []string `json:"actions" xml:"actions>element"`
+	Read           bool     `json:"read" xml:"read"`
+}
+
+// Init initializes this and any contained handlers
+func (h *Handler) Init(c *config.Config, n *notification.Notifier) {
+	h.gatewayAddr = c.GatewaySvc
+	h.notifier = n
+	h.expiryWarning = time.Duration(c.ShareExpiryWarning) * 24 * time.Hour
+	h.scanned = ttlcache.NewCache()
+	_ = h.scanned.SetTTL(expiringSharesScanInterval)
+	h.scanned.SkipTTLExtensionOnHit(true)
+}
+
+// ListNotifications handles GET requests on /apps/notifications/api/v1/notifications
+func (h *Handler) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	u := ctxpkg.ContextMustGetUser(ctx)
+
+	if h.expiryWarning > 0 {
+		if _, err := h.scanned.Get(u.Id.OpaqueId); err != nil {
+			_ = h.scanned.Set(u.Id.OpaqueId, true)
+			if err := h.notifyExpiringShares(ctx); err != nil {
+				appctx.GetLogger(ctx).Error().Err(err).Msg("error checking expiring shares")
+			}
+		}
+	}
+
+	list, err := h.notifier.Manager().ListNotifications(ctx, u.Id)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error listing notifications", err)
+		return
+	}
+	data := make([]*Notification, 0, len(list))
+	for _, n := range list {
+		data = append(data, toOCS(n, u.Username))
+	}
+	response.WriteOCSSuccess(w, r, data)
+}
+
+// GetNotification handles GET requests on /apps/notifications/api/v1/notifications/{notificationid}
+func (h *Handler) GetNotification(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	u := ctxpkg.ContextMustGetUser(ctx)
+
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+	n, err := h.notifier.Manager().GetNotification(ctx, u.Id, id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	response.WriteOCSSuccess(w, r, toOCS(n, u.Username))
+}
+
+// MarkAsRead handles POST requests on /apps/notifications/api/v1/notifications/{notificationid}/read
+func (h *Handler) MarkAsRead(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	u := ctxpkg.ContextMustGetUser(ctx)
+
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.notifier.Manager().MarkAsRead(ctx, u.Id, id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	response.WriteOCSSuccess(w, r, nil)
+}
+
+// DeleteNotification handles DELETE requests on /apps/notifications/api/v1/notifications/{notificationid}
+func (h *Handler) DeleteNotification(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	u := ctxpkg.ContextMustGetUser(ctx)
+
+	id, ok := parseID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.notifier.Manager().DeleteNotification(ctx, u.Id, id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	response.WriteOCSSuccess(w, r, nil)
+}
+
+// DeleteNotifications handles DELETE requests on /apps/notifications/api/v1/notifications
+func (h *Handler) DeleteNotifications(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	u := ctxpkg.ContextMustGetUser(ctx)
+
+	if err := h.notifier.Manager().DeleteNotifications(ctx, u.Id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	response.WriteOCSSuccess(w, r, nil)
+}
+
+// notifyExpiringShares notifies the current user about the public links that expire soon.
+func (h *Handler) notifyExpiringShares(ctx context.Context) error {
+	u := ctxpkg.ContextMustGetUser(ctx)
+	client, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		return err
+	}
+	res, err := client.ListPublicShares(ctx, &link.ListPublicSharesRequest{})
+	if err != nil {
+		return err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return fmt.Errorf("error listing public shares: %s", res.Status.Message)
+	}
+
+	now := time.Now()
+	for _, s := range res.Share {
+		if s.Expiration == nil || !utils.UserEqual(s.Owner, u.Id) && !utils.UserEqual(s.Creator, u.Id) {
+			continue
+		}
+		expiration := utils.TSToTime(s.Expiration)
+		if expiration.Before(now) || expiration.After(now.Add(h.expiryWarning)) {
+			continue
+		}
+		name := s.DisplayName
+		if name == "" {
+			name = s.Token
+		}
+		err := h.notifier.Notify(ctx, u, &notification.Notification{
+			Key:        fmt.Sprintf("public_link_expiring:%s:%d", s.Id.OpaqueId, expiration.Unix()),
+			App:        "files_sharing",
+			Subject:    fmt.Sprintf("Public link %s expires soon", name),
+			Message:    fmt.Sprintf("The public link %s expires on %s.", name, expiration.UTC().Format(time.RFC1123)),
+			ObjectType: "public_link",
+			ObjectID:   s.Id.OpaqueId,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func toOCS(n *notification.Notification, username string) *Notification {
+	return &Notification{
+		NotificationID: n.ID,
+		App:            n.App,
+		User:           username,
+		Datetime:       n.Datetime.UTC().Format(time.RFC3339),
+		ObjectType:     n.ObjectType,
+		ObjectID:       n.ObjectID,
+		Subject:        n.Subject,
+		Message:        n.Message,
+		Link:           n.Link,
+		Actions:        []string{},
+		Read:           n.Read,
+	}
+}
+
+func parseID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "notificationid"), 10, 64)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "invalid notification id", nil)
+		return 0, false
+	}
+	return id, true
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	if _, ok := err.(errtypes.IsNotFound); ok {
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "notification not found", nil)
+		return
+	}
+	response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error handling notification", err)
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/share"
 )
 
 func (h *Handler) createGroupShare(w http.ResponseWriter, r *http.Request, statInfo *provider.ResourceInfo, role *conversions.Role, roleVal []byte) {
@@ -80,5 +81,14 @@ func (h *Handler) createGroupShare(w http.ResponseWriter, r *http.Request, statI
 		},
 	}
 
+	expiration, _, err := expirationFromRequest(r)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "invalid datetime format", err)
+		return
+	}
+	if expiration != nil {
+		createShareReq.Opaque = share.ExpirationToOpaque(createShareReq.Opaque, expiration)
+	}
+
 	h.createCs3Share(ctx, w, r, c, createShareReq, statInfo)
 }
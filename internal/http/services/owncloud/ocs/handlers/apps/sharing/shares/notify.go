@@ -0,0 +1,112 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package shares
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/notification"
+	"github.com/cs3org/reva/pkg/utils"
+	"google.golang.org/grpc/metadata"
+)
+
+// SetNotifier sets the notifier used to inform users about the shares they receive
+func (h *Handler) SetNotifier(n *notification.Notifier) {
+	h.notifier = n
+}
+
+// notifyShareCreated notifies the grantees of a new share. Failures are only logged,
+// the share has been created anyway. The members of groups are notified in the
+// background as groups can be large.
+func (h *Handler) notifyShareCreated(ctx context.Context, client gateway.GatewayAPIClient, share *collaboration.Share, info *provider.ResourceInfo) {
+	if h.notifier == nil {
+		return
+	}
+
+	switch share.Grantee.Type {
+	case provider.GranteeType_GRANTEE_TYPE_USER:
+		h.notifyRecipients(ctx, client, share, info, []*userpb.UserId{share.Grantee.GetUserId()})
+	case provider.GranteeType_GRANTEE_TYPE_GROUP:
+		ctx := detachContext(ctx)
+		go func() {
+			res, err := client.GetMembers(ctx, &grouppb.GetMembersRequest{GroupId: share.Grantee.GetGroupId()})
+			if err != nil || res.Status.Code != rpc.Code_CODE_OK {
+				appctx.GetLogger(ctx).Error().Err(err).Interface("group", share.Grantee.GetGroupId()).Msg("error getting group members to notify")
+				return
+			}
+			h.notifyRecipients(ctx, client, share, info, res.Members)
+		}()
+	}
+}
+
+func (h *Handler) notifyRecipients(ctx context.Context, client gateway.GatewayAPIClient, share *collaboration.Share, info *provider.ResourceInfo, recipients []*userpb.UserId) {
+	log := appctx.GetLogger(ctx)
+
+	sharer := ctxpkg.ContextMustGetUser(ctx)
+	name := sharer.DisplayName
+	if name == "" {
+		name = sharer.Username
+	}
+	resource := path.Base(info.Path)
+
+	for _, id := range recipients {
+		// don't notify the sharer about shares with a group they are a member of
+		if utils.UserEqual(id, sharer.Id) {
+			continue
+		}
+		res, err := client.GetUser(ctx, &userpb.GetUserRequest{UserId: id})
+		if err != nil || res.Status.Code != rpc.Code_CODE_OK {
+			log.Error().Err(err).Interface("user", id).Msg("error getting user to notify")
+			continue
+		}
+		err = h.notifier.Notify(ctx, res.User, &notification.Notification{
+			Key:        "local_share:" + share.Id.OpaqueId,
+			App:        "files_sharing",
+			Subject:    fmt.Sprintf("%s shared %s with you", name, resource),
+			Message:    fmt.Sprintf("%s shared %s with you.", name, resource),
+			ObjectType: "local_share",
+			ObjectID:   share.Id.OpaqueId,
+		})
+		if err != nil {
+			log.Error().Err(err).Interface("user", id).Msg("error notifying user about share")
+		}
+	}
+}
+
+// detachContext returns a context with the user, token and logger of ctx that
+// is not cancelled once the request is finished.
+func detachContext(ctx context.Context) context.Context {
+	dctx := appctx.WithLogger(context.Background(), appctx.GetLogger(ctx))
+	dctx = ctxpkg.ContextSetUser(dctx, ctxpkg.ContextMustGetUser(ctx))
+	if tkn, ok := ctxpkg.ContextGetToken(ctx); ok {
+		dctx = ctxpkg.ContextSetToken(dctx, tkn)
+		dctx = metadata.AppendToOutgoingContext(dctx, ctxpkg.TokenHeader, tkn)
+	}
+	return dctx
+}
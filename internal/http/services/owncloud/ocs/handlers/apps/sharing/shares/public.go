@@ -117,6 +117,11 @@ func (h *Handler) createPublicLinkShare(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
+	if createRes.Status.Code == rpc.Code_CODE_INVALID_ARGUMENT {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, createRes.Status.Message, nil)
+		return
+	}
+
 	if createRes.Status.Code != rpc.Code_CODE_OK {
 		log.Debug().Err(errors.New("create public share failed")).Str("shares", "createShare").Msgf("create public share failed with status code: %v", createRes.Status.Code.String())
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "grpc create public share request failed", err)
@@ -347,6 +352,10 @@ func (h *Handler) updatePublicShare(w http.ResponseWriter, r *http.Request, shar
 				response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "Error sending update request to public link provider", err)
 				return
 			}
+			if uRes.Status.Code == rpc.Code_CODE_INVALID_ARGUMENT {
+				response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, uRes.Status.Message, nil)
+				return
+			}
 		}
 		publicShare = uRes.Share
 	} else if !updatesFound {
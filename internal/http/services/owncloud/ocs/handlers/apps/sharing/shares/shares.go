@@ -38,6 +38,7 @@ import (
 	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
 	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog/log"
 
@@ -48,6 +49,7 @@ import (
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/notification"
 	"github.com/cs3org/reva/pkg/publicshare"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/pkg/share"
@@ -73,6 +75,7 @@ type Handler struct {
 	userIdentifierCache    *ttlcache.Cache
 	resourceInfoCache      gcache.Cache
 	resourceInfoCacheTTL   time.Duration
+	notifier               *notification.Notifier
 }
 
 // we only cache the minimal set of data instead of the full user metadata
@@ -365,6 +368,7 @@ func (h *Handler) GetShare(w http.ResponseWriter, r *http.Request) {
 				response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error mapping share data", err)
 				return
 			}
+			setExpiration(share, uRes.Opaque)
 		}
 	}
 
@@ -413,19 +417,13 @@ func (h *Handler) updateShare(w http.ResponseWriter, r *http.Request, shareID st
 	ctx := r.Context()
 
 	pval := r.FormValue("permissions")
-	if pval == "" {
-		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "permissions missing", nil)
-		return
-	}
-
-	pint, err := strconv.Atoi(pval)
+	expiration, updateExpiration, err := expirationFromRequest(r)
 	if err != nil {
-		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "permissions must be an integer", nil)
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "invalid datetime format", err)
 		return
 	}
-	permissions, err := conversions.NewPermissions(pint)
-	if err != nil {
-		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, err.Error(), nil)
+	if pval == "" && !updateExpiration {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "permissions missing", nil)
 		return
 	}
 
@@ -435,23 +433,59 @@ func (h *Handler) updateShare(w http.ResponseWriter, r *http.Request, shareID st
 		return
 	}
 
-	uReq := &collaboration.UpdateShareRequest{
-		Ref: &collaboration.ShareReference{
-			Spec: &collaboration.ShareReference_Id{
-				Id: &collaboration.ShareId{
-					OpaqueId: shareID,
-				},
+	shareRef := &collaboration.ShareReference{
+		Spec: &collaboration.ShareReference_Id{
+			Id: &collaboration.ShareId{
+				OpaqueId: shareID,
 			},
 		},
+	}
+
+	var sharePermissions *collaboration.SharePermissions
+	if pval != "" {
+		pint, err := strconv.Atoi(pval)
+		if err != nil {
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "permissions must be an integer", nil)
+			return
+		}
+		permissions, err := conversions.NewPermissions(pint)
+		if err != nil {
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, err.Error(), nil)
+			return
+		}
+		sharePermissions = &collaboration.SharePermissions{
+			// this completely overwrites the permissions for this user
+			Permissions: conversions.RoleFromOCSPermissions(permissions).CS3ResourcePermissions(),
+		}
+	} else {
+		// only the expiration changes, keep the current permissions
+		getShareRes, err := client.GetShare(ctx, &collaboration.GetShareRequest{Ref: shareRef})
+		if err != nil {
+			response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc get share request", err)
+			return
+		}
+		if getShareRes.Status.Code != rpc.Code_CODE_OK {
+			if getShareRes.Status.Code == rpc.Code_CODE_NOT_FOUND {
+				response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "not found", nil)
+				return
+			}
+			response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "grpc get share request failed", nil)
+			return
+		}
+		sharePermissions = getShareRes.Share.Permissions
+	}
+
+	uReq := &collaboration.UpdateShareRequest{
+		Ref: shareRef,
 		Field: &collaboration.UpdateShareRequest_UpdateField{
 			Field: &collaboration.UpdateShareRequest_UpdateField_Permissions{
-				Permissions: &collaboration.SharePermissions{
-					// this completely overwrites the permissions for this user
-					Permissions: conversions.RoleFromOCSPermissions(permissions).CS3ResourcePermissions(),
-				},
+				Permissions: sharePermissions,
 			},
 		},
 	}
+	if updateExpiration {
+		uReq.Opaque = share.ExpirationToOpaque(nil, expiration)
+	}
 	uRes, err := client.UpdateShare(ctx, uReq)
 	if err != nil {
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error sending a grpc update share request", err)
@@ -463,6 +497,10 @@ func (h *Handler) updateShare(w http.ResponseWriter, r *http.Request, shareID st
 			response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "not found", nil)
 			return
 		}
+		if uRes.Status.Code == rpc.Code_CODE_INVALID_ARGUMENT {
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, uRes.Status.Message, nil)
+			return
+		}
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "grpc update share request failed", err)
 		return
 	}
@@ -472,6 +510,7 @@ func (h *Handler) updateShare(w http.ResponseWriter, r *http.Request, shareID st
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error mapping share data", err)
 		return
 	}
+	setExpiration(share, uRes.Opaque)
 
 	statReq := provider.StatRequest{Ref: &provider.Reference{
 		ResourceId: uRes.Share.ResourceId,
@@ -677,6 +716,7 @@ func (h *Handler) listSharesWithMe(w http.ResponseWriter, r *http.Request) {
 		}
 
 		data.State = mapState(rs.GetState())
+		setExpiration(data, lrsRes.Opaque)
 
 		if err := h.addFileInfo(ctx, data, info); err != nil {
 			log.Debug().Interface("received_share", rs).Interface("info", info).Interface("shareData", data).Err(err).Msg("could not add file info, skipping")
@@ -1079,6 +1119,10 @@ func (h *Handler) createCs3Share(ctx context.Context, w http.ResponseWriter, r *
 			response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "not found", nil)
 			return
 		}
+		if createShareResponse.Status.Code == rpc.Code_CODE_INVALID_ARGUMENT {
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, createShareResponse.Status.Message, nil)
+			return
+		}
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "grpc create share request failed", err)
 		return
 	}
@@ -1087,6 +1131,7 @@ func (h *Handler) createCs3Share(ctx context.Context, w http.ResponseWriter, r *
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error mapping share data", err)
 		return
 	}
+	setExpiration(s, createShareResponse.Opaque)
 	err = h.addFileInfo(ctx, s, info)
 	if err != nil {
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error adding fileinfo to share", err)
@@ -1094,9 +1139,33 @@ func (h *Handler) createCs3Share(ctx context.Context, w http.ResponseWriter, r *
 	}
 	h.mapUserIds(ctx, client, s)
 
+	h.notifyShareCreated(ctx, client, createShareResponse.Share, info)
+
 	response.WriteOCSSuccess(w, r, s)
 }
 
+// expirationFromRequest parses the expireDate of a user or group share request.
+// The second return value tells whether the request carried an expireDate at all,
+// an empty one removes the expiration.
+func expirationFromRequest(r *http.Request) (*types.Timestamp, bool, error) {
+	v, ok := r.Form["expireDate"]
+	if !ok {
+		return nil, false, nil
+	}
+	if v[0] == "" {
+		return nil, true, nil
+	}
+	expiration, err := conversions.ParseTimestamp(v[0])
+	return expiration, true, err
+}
+
+// setExpiration adds the expiration the share provider returned in the opaque of a response to the share data.
+func setExpiration(data *conversions.ShareData, o *types.Opaque) {
+	if e, ok := share.ExpirationsFromOpaque(o)[data.ID]; ok {
+		data.Expiration = conversions.TimestampToExpiration(e)
+	}
+}
+
 func mapState(state collaboration.ShareState) int {
 	var mapped int
 	switch state {
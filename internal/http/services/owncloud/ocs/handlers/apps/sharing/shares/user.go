@@ -31,6 +31,7 @@ import (
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/share"
 )
 
 func (h *Handler) createUserShare(w http.ResponseWriter, r *http.Request, statInfo *provider.ResourceInfo, role *conversions.Role, roleVal []byte) {
@@ -82,6 +83,15 @@ func (h *Handler) createUserShare(w http.ResponseWriter, r *http.Request, statIn
 		},
 	}
 
+	expiration, _, err := expirationFromRequest(r)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "invalid datetime format", err)
+		return
+	}
+	if expiration != nil {
+		createShareReq.Opaque = share.ExpirationToOpaque(createShareReq.Opaque, expiration)
+	}
+
 	h.createCs3Share(ctx, w, r, c, createShareReq, statInfo)
 }
 
@@ -144,6 +154,7 @@ func (h *Handler) removeUserShare(w http.ResponseWriter, r *http.Request, shareI
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "deleting share failed", err)
 		return
 	}
+	setExpiration(data, getShareResp.Opaque)
 	// A deleted share should not have an ID.
 	data.ID = ""
 
@@ -197,6 +208,7 @@ func (h *Handler) listUserShares(r *http.Request, filters []*collaboration.Filte
 				log.Debug().Interface("share", s).Interface("shareData", data).Err(err).Msg("could not CS3Share2ShareData, skipping")
 				continue
 			}
+			setExpiration(data, lsUserSharesResponse.Opaque)
 
 			info, status, err := h.getResourceInfoByID(ctx, client, s.ResourceId)
 			if err != nil || status.Code != rpc.Code_CODE_OK {
@@ -0,0 +1,185 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package users
+
+import (
+	"net/http"
+	"sort"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/go-chi/chi/v5"
+)
+
+// GroupList holds the names of groups
+type GroupList struct {
+	Groups []string `json:"groups" xml:"groups>element"`
+}
+
+// ListGroups handles GET requests on /cloud/groups
+func (h *Handler) ListGroups(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAdmin(w, r) {
+		return
+	}
+	ctx := r.Context()
+	gc, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting gateway client", err)
+		return
+	}
+
+	res, err := gc.FindGroups(ctx, &grouppb.FindGroupsRequest{Filter: r.URL.Query().Get("search"), SkipFetchingMembers: true})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error searching groups", err)
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, res.Status.Message, nil)
+		return
+	}
+
+	groups := make([]string, 0, len(res.Groups))
+	for _, g := range res.Groups {
+		groups = append(groups, g.GroupName)
+	}
+	sort.Strings(groups)
+	response.WriteOCSSuccess(w, r, &GroupList{Groups: groups})
+}
+
+// CreateGroup handles POST requests on /cloud/groups
+func (h *Handler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAdmin(w, r) || !h.checkGroupProvisioning(w, r) {
+		return
+	}
+	form, err := formValues(r)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "invalid form", err)
+		return
+	}
+	name := form.Get("groupid")
+	if name == "" {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "missing groupid", nil)
+		return
+	}
+	displayName := form.Get("displayname")
+	if displayName == "" {
+		displayName = name
+	}
+
+	if _, err := h.groups.CreateGroup(r.Context(), &grouppb.Group{GroupName: name, DisplayName: displayName}); err != nil {
+		writeError(w, r, "error creating group", err)
+		return
+	}
+	response.WriteOCSSuccess(w, r, nil)
+}
+
+// GetGroupMembers handles GET requests on /cloud/groups/{groupid}
+func (h *Handler) GetGroupMembers(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAdmin(w, r) {
+		return
+	}
+	ctx := r.Context()
+	gc, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting gateway client", err)
+		return
+	}
+
+	gres, err := gc.GetGroupByClaim(ctx, &grouppb.GetGroupByClaimRequest{Claim: "group_name", Value: chi.URLParam(r, "groupid"), SkipFetchingMembers: true})
+	switch {
+	case err != nil:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error looking up group", err)
+		return
+	case gres.Status.Code == rpc.Code_CODE_NOT_FOUND:
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "group not found", nil)
+		return
+	case gres.Status.Code != rpc.Code_CODE_OK:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, gres.Status.Message, nil)
+		return
+	}
+
+	mres, err := gc.GetMembers(ctx, &grouppb.GetMembersRequest{GroupId: gres.Group.Id})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting group members", err)
+		return
+	}
+	if mres.Status.Code != rpc.Code_CODE_OK {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, mres.Status.Message, nil)
+		return
+	}
+
+	users := make([]string, 0, len(mres.Members))
+	for _, id := range mres.Members {
+		// the provisioning API identifies users by their username
+		name := id.OpaqueId
+		if res, err := gc.GetUser(ctx, &userpb.GetUserRequest{UserId: id, SkipFetchingUserGroups: true}); err == nil && res.Status.Code == rpc.Code_CODE_OK {
+			name = res.User.Username
+		}
+		users = append(users, name)
+	}
+	sort.Strings(users)
+	response.WriteOCSSuccess(w, r, &UserList{Users: users})
+}
+
+// DeleteGroup handles DELETE requests on /cloud/groups/{groupid}
+func (h *Handler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAdmin(w, r) || !h.checkGroupProvisioning(w, r) {
+		return
+	}
+	ctx := r.Context()
+	name := chi.URLParam(r, "groupid")
+	if name == h.adminGroup {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "the admin group can't be deleted", nil)
+		return
+	}
+
+	gc, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting gateway client", err)
+		return
+	}
+
+	g, err := lookupGroup(ctx, gc, name)
+	if err != nil {
+		writeError(w, r, "error looking up group", err)
+		return
+	}
+	if err := h.groups.DeleteGroup(ctx, g.Id); err != nil {
+		writeError(w, r, "error deleting group", err)
+		return
+	}
+
+	// the members don't belong to the group anymore
+	if h.users != nil {
+		for _, id := range g.Members {
+			res, err := gc.GetUser(ctx, &userpb.GetUserRequest{UserId: id})
+			if err != nil || res.Status.Code != rpc.Code_CODE_OK {
+				continue
+			}
+			if err := h.dropGroup(ctx, res.User, name); err != nil {
+				appctx.GetLogger(ctx).Warn().Err(err).Str("user", id.OpaqueId).Msg("error removing deleted group from user")
+			}
+		}
+	}
+	response.WriteOCSSuccess(w, r, nil)
+}
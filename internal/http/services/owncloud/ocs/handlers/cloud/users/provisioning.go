@@ -0,0 +1,466 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package users
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/go-chi/chi/v5"
+)
+
+// ocsAlreadyExists is the status code of the provisioning API for existing users and groups
+const ocsAlreadyExists = 102
+
+// UserList holds the ids of users
+type UserList struct {
+	Users []string `json:"users" xml:"users>element"`
+}
+
+func (h *Handler) isAdmin(u *userpb.User) bool {
+	if h.adminGroup == "" {
+		return false
+	}
+	for _, g := range u.Groups {
+		if g == h.adminGroup {
+			return true
+		}
+	}
+	return false
+}
+
+// requireAdmin writes an error and returns false if the current user is no admin.
+func (h *Handler) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	u, ok := ctxpkg.ContextGetUser(r.Context())
+	if !ok {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "missing user in context", fmt.Errorf("missing user in context"))
+		return false
+	}
+	if !h.isAdmin(u) {
+		response.WriteOCSError(w, r, response.MetaUnauthorized.StatusCode, "only admins can manage users and groups", nil)
+		return false
+	}
+	return true
+}
+
+// getUser looks up a user by username. Users can only look up themselves unless they are admins.
+func (h *Handler) getUser(w http.ResponseWriter, r *http.Request, gc gateway.GatewayAPIClient, username string) (*userpb.User, bool) {
+	ctx := r.Context()
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "missing user in context", fmt.Errorf("missing user in context"))
+		return nil, false
+	}
+	if username == u.Username {
+		return u, true
+	}
+	if !h.isAdmin(u) {
+		response.WriteOCSError(w, r, http.StatusForbidden, "user id mismatch", fmt.Errorf("%s tried to access %s user info endpoint", u.Id.OpaqueId, username))
+		return nil, false
+	}
+
+	res, err := gc.GetUserByClaim(ctx, &userpb.GetUserByClaimRequest{Claim: "username", Value: username})
+	switch {
+	case err != nil:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error looking up user", err)
+		return nil, false
+	case res.Status.Code == rpc.Code_CODE_NOT_FOUND:
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, "user not found", nil)
+		return nil, false
+	case res.Status.Code != rpc.Code_CODE_OK:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, res.Status.Message, nil)
+		return nil, false
+	}
+	return res.User, true
+}
+
+// lookupUser gets a user by username through the gateway.
+func lookupUser(ctx context.Context, gc gateway.GatewayAPIClient, username string) (*userpb.User, error) {
+	res, err := gc.GetUserByClaim(ctx, &userpb.GetUserByClaimRequest{Claim: "username", Value: username})
+	if err != nil {
+		return nil, err
+	}
+	if err := errorFromStatus(res.Status); err != nil {
+		return nil, err
+	}
+	return res.User, nil
+}
+
+// lookupGroup gets a group and its members by name through the gateway.
+func lookupGroup(ctx context.Context, gc gateway.GatewayAPIClient, name string) (*grouppb.Group, error) {
+	res, err := gc.GetGroupByClaim(ctx, &grouppb.GetGroupByClaimRequest{Claim: "group_name", Value: name})
+	if err != nil {
+		return nil, err
+	}
+	if err := errorFromStatus(res.Status); err != nil {
+		return nil, err
+	}
+	return res.Group, nil
+}
+
+func errorFromStatus(s *rpc.Status) error {
+	switch s.Code {
+	case rpc.Code_CODE_OK:
+		return nil
+	case rpc.Code_CODE_NOT_FOUND:
+		return errtypes.NotFound(s.Message)
+	}
+	return errtypes.InternalError(s.Message)
+}
+
+// writeError maps the errors of the user and group managers to ocs errors.
+func writeError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	switch err.(type) {
+	case errtypes.IsNotFound:
+		response.WriteOCSError(w, r, response.MetaNotFound.StatusCode, msg+": not found", nil)
+	case errtypes.IsAlreadyExists:
+		response.WriteOCSError(w, r, ocsAlreadyExists, msg+": already exists", nil)
+	case errtypes.IsBadRequest:
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, msg+": "+err.Error(), nil)
+	case errtypes.IsNotSupported:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, msg+": "+err.Error(), nil)
+	case errtypes.IsPermissionDenied:
+		response.WriteOCSError(w, r, response.MetaUnauthorized.StatusCode, msg+": "+err.Error(), nil)
+	default:
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, msg, err)
+	}
+}
+
+// formValues returns the form of the request, including the body of DELETE
+// requests which is ignored by http.Request.ParseForm.
+func formValues(r *http.Request) (url.Values, error) {
+	if r.Method != http.MethodDelete {
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+		return r.Form, nil
+	}
+	values := r.URL.Query()
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range form {
+		values[k] = append(values[k], v...)
+	}
+	return values, nil
+}
+
+// checkUserProvisioning writes an error and returns false if users can't be provisioned.
+func (h *Handler) checkUserProvisioning(w http.ResponseWriter, r *http.Request) bool {
+	if h.users == nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "user provisioning is not configured", nil)
+		return false
+	}
+	return true
+}
+
+// checkGroupProvisioning writes an error and returns false if groups can't be provisioned.
+func (h *Handler) checkGroupProvisioning(w http.ResponseWriter, r *http.Request) bool {
+	if h.groups == nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "group provisioning is not configured", nil)
+		return false
+	}
+	return true
+}
+
+// ListUsers handles GET requests on /cloud/users
+func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAdmin(w, r) {
+		return
+	}
+	ctx := r.Context()
+	gc, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting gateway client", err)
+		return
+	}
+
+	res, err := gc.FindUsers(ctx, &userpb.FindUsersRequest{Filter: r.URL.Query().Get("search"), SkipFetchingUserGroups: true})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error searching users", err)
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, res.Status.Message, nil)
+		return
+	}
+
+	users := make([]string, 0, len(res.Users))
+	for _, u := range res.Users {
+		users = append(users, u.Username)
+	}
+	sort.Strings(users)
+	response.WriteOCSSuccess(w, r, &UserList{Users: users})
+}
+
+// CreateUser handles POST requests on /cloud/users
+func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAdmin(w, r) || !h.checkUserProvisioning(w, r) {
+		return
+	}
+	ctx := r.Context()
+	form, err := formValues(r)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "invalid form", err)
+		return
+	}
+
+	username := form.Get("userid")
+	if username == "" {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "missing userid", nil)
+		return
+	}
+	displayName := form.Get("displayname")
+	if displayName == "" {
+		displayName = form.Get("displayName")
+	}
+	groups := form["groups[]"]
+	if len(groups) > 0 && !h.checkGroupProvisioning(w, r) {
+		return
+	}
+	gc, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting gateway client", err)
+		return
+	}
+
+	u, err := h.users.CreateUser(ctx, &userpb.User{
+		Username:    username,
+		DisplayName: displayName,
+		Mail:        form.Get("email"),
+	}, form.Get("password"))
+	if err != nil {
+		writeError(w, r, "error creating user", err)
+		return
+	}
+
+	for _, name := range groups {
+		if err := h.addToGroup(ctx, gc, u, name); err != nil {
+			writeError(w, r, "error adding user to group "+name, err)
+			return
+		}
+	}
+	response.WriteOCSSuccess(w, r, &Users{ID: u.Username})
+}
+
+// EditUser handles PUT requests on /cloud/users/{userid}, the key can be
+// email, displayname or password. Users can edit themselves.
+func (h *Handler) EditUser(w http.ResponseWriter, r *http.Request) {
+	if !h.checkUserProvisioning(w, r) {
+		return
+	}
+	ctx := r.Context()
+	current, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "missing user in context", fmt.Errorf("missing user in context"))
+		return
+	}
+	username := chi.URLParam(r, "userid")
+	if username != current.Username && !h.requireAdmin(w, r) {
+		return
+	}
+	form, err := formValues(r)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "invalid form", err)
+		return
+	}
+	gc, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting gateway client", err)
+		return
+	}
+
+	u, err := lookupUser(ctx, gc, username)
+	if err != nil {
+		writeError(w, r, "error looking up user", err)
+		return
+	}
+
+	value := form.Get("value")
+	switch form.Get("key") {
+	case "email":
+		_, err = h.users.UpdateUser(ctx, &userpb.User{Id: u.Id, Mail: value, DisplayName: u.DisplayName, Groups: u.Groups})
+	case "displayname", "display":
+		_, err = h.users.UpdateUser(ctx, &userpb.User{Id: u.Id, Mail: u.Mail, DisplayName: value, Groups: u.Groups})
+	case "password":
+		if value == "" {
+			response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "empty password", nil)
+			return
+		}
+		err = h.users.SetPassword(ctx, u.Id, value)
+	default:
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "unsupported key "+form.Get("key"), nil)
+		return
+	}
+	if err != nil {
+		writeError(w, r, "error editing user", err)
+		return
+	}
+	response.WriteOCSSuccess(w, r, nil)
+}
+
+// DeleteUser handles DELETE requests on /cloud/users/{userid}
+func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	if !h.requireAdmin(w, r) || !h.checkUserProvisioning(w, r) {
+		return
+	}
+	ctx := r.Context()
+	username := chi.URLParam(r, "userid")
+	if current, _ := ctxpkg.ContextGetUser(ctx); current.Username == username {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "users can't delete themselves", nil)
+		return
+	}
+	gc, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting gateway client", err)
+		return
+	}
+
+	u, err := lookupUser(ctx, gc, username)
+	if err != nil {
+		writeError(w, r, "error looking up user", err)
+		return
+	}
+
+	// drop the memberships first so the groups don't keep dangling members
+	if h.groups != nil {
+		for _, name := range u.Groups {
+			g, err := lookupGroup(ctx, gc, name)
+			if err != nil {
+				continue
+			}
+			if err := h.groups.RemoveMember(ctx, g.Id, u.Id); err != nil {
+				appctx.GetLogger(ctx).Warn().Err(err).Str("group", name).Msg("error removing deleted user from group")
+			}
+		}
+	}
+
+	if err := h.users.DeleteUser(ctx, u.Id); err != nil {
+		writeError(w, r, "error deleting user", err)
+		return
+	}
+	response.WriteOCSSuccess(w, r, nil)
+}
+
+// AddToGroup handles POST requests on /cloud/users/{userid}/groups
+func (h *Handler) AddToGroup(w http.ResponseWriter, r *http.Request) {
+	h.changeGroup(w, r, h.addToGroup)
+}
+
+// RemoveFromGroup handles DELETE requests on /cloud/users/{userid}/groups
+func (h *Handler) RemoveFromGroup(w http.ResponseWriter, r *http.Request) {
+	h.changeGroup(w, r, h.removeFromGroup)
+}
+
+func (h *Handler) changeGroup(w http.ResponseWriter, r *http.Request, change func(ctx context.Context, gc gateway.GatewayAPIClient, u *userpb.User, group string) error) {
+	if !h.requireAdmin(w, r) || !h.checkUserProvisioning(w, r) || !h.checkGroupProvisioning(w, r) {
+		return
+	}
+	ctx := r.Context()
+	form, err := formValues(r)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "invalid form", err)
+		return
+	}
+	name := form.Get("groupid")
+	if name == "" {
+		response.WriteOCSError(w, r, response.MetaBadRequest.StatusCode, "missing groupid", nil)
+		return
+	}
+	gc, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting gateway client", err)
+		return
+	}
+
+	u, err := lookupUser(ctx, gc, chi.URLParam(r, "userid"))
+	if err != nil {
+		writeError(w, r, "error looking up user", err)
+		return
+	}
+	if err := change(ctx, gc, u, name); err != nil {
+		writeError(w, r, "error changing group "+name, err)
+		return
+	}
+	response.WriteOCSSuccess(w, r, nil)
+}
+
+// addToGroup adds the user to the group and to the groups of the user for the
+// user managers storing them with the user.
+func (h *Handler) addToGroup(ctx context.Context, gc gateway.GatewayAPIClient, u *userpb.User, name string) error {
+	g, err := lookupGroup(ctx, gc, name)
+	if err != nil {
+		return err
+	}
+	if err := h.groups.AddMember(ctx, g.Id, u.Id); err != nil {
+		return err
+	}
+	groups := append([]string{}, u.Groups...)
+	for _, n := range groups {
+		if n == name {
+			return nil
+		}
+	}
+	_, err = h.users.UpdateUser(ctx, &userpb.User{Id: u.Id, Mail: u.Mail, DisplayName: u.DisplayName, Groups: append(groups, name)})
+	return err
+}
+
+func (h *Handler) removeFromGroup(ctx context.Context, gc gateway.GatewayAPIClient, u *userpb.User, name string) error {
+	g, err := lookupGroup(ctx, gc, name)
+	if err != nil {
+		return err
+	}
+	if err := h.groups.RemoveMember(ctx, g.Id, u.Id); err != nil {
+		return err
+	}
+	return h.dropGroup(ctx, u, name)
+}
+
+// dropGroup removes a group from the groups of the user.
+func (h *Handler) dropGroup(ctx context.Context, u *userpb.User, name string) error {
+	groups := []string{}
+	for _, n := range u.Groups {
+		if n != name {
+			groups = append(groups, n)
+		}
+	}
+	if len(groups) == len(u.Groups) {
+		return nil
+	}
+	_, err := h.users.UpdateUser(ctx, &userpb.User{Id: u.Id, Mail: u.Mail, DisplayName: u.DisplayName, Groups: groups})
+	return err
+}
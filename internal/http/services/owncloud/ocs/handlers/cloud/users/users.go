@@ -32,24 +32,71 @@ import (
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
 	"github.com/cs3org/reva/pkg/appctx"
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/provisioning"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/go-chi/chi/v5"
 )
 
-// Handler renders user data for the user id given in the url path
+// Handler renders user data for the user id given in the url path and
+// implements the provisioning API
 type Handler struct {
 	gatewayAddr string
+	adminGroup  string
+	users       *provisioning.UserClient
+	groups      *provisioning.GroupClient
 }
 
 // Init initializes this and any contained handlers
-func (h *Handler) Init(c *config.Config) {
+func (h *Handler) Init(c *config.Config) error {
 	h.gatewayAddr = c.GatewaySvc
+	h.adminGroup = c.AdminGroup
+
+	if (c.UserProviderSvc != "" || c.GroupProviderSvc != "") && c.AdminGroup == "" {
+		return fmt.Errorf("admin_group is required to provision users and groups")
+	}
+	if c.UserProviderSvc != "" {
+		conn, err := pool.NewConn(c.UserProviderSvc)
+		if err != nil {
+			return err
+		}
+		h.users = provisioning.NewUserClient(conn)
+	}
+	if c.GroupProviderSvc != "" {
+		conn, err := pool.NewConn(c.GroupProviderSvc)
+		if err != nil {
+			return err
+		}
+		h.groups = provisioning.NewGroupClient(conn)
+	}
+	return nil
 }
 
-// GetGroups handles GET requests on /cloud/users/groups
-// TODO: implement
+// GetGroups handles GET requests on /cloud/users/{userid}/groups
 func (h *Handler) GetGroups(w http.ResponseWriter, r *http.Request) {
-	response.WriteOCSSuccess(w, r, &Groups{})
+	ctx := r.Context()
+	sublog := appctx.GetLogger(ctx)
+
+	gc, err := pool.GetGatewayServiceClient(h.gatewayAddr)
+	if err != nil {
+		sublog.Error().Err(err).Msg("error getting gateway client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	u, ok := h.getUser(w, r, gc, chi.URLParam(r, "userid"))
+	if !ok {
+		return
+	}
+
+	res, err := gc.GetUserGroups(ctx, &userpb.GetUserGroupsRequest{UserId: u.Id})
+	if err != nil {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "error getting groups", err)
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, res.Status.Message, nil)
+		return
+	}
+	response.WriteOCSSuccess(w, r, &Groups{Groups: res.Groups})
 }
 
 // Quota holds quota information
@@ -63,7 +110,8 @@ type Quota struct {
 
 // Users holds users data
 type Users struct {
-	Quota       *Quota `json:"quota" xml:"quota"`
+	ID          string `json:"id" xml:"id"`
+	Quota       *Quota `json:"quota,omitempty" xml:"quota,omitempty"`
 	Email       string `json:"email" xml:"email"`
 	DisplayName string `json:"displayname" xml:"displayname"`
 	UserType    string `json:"user-type" xml:"user-type"`
@@ -77,25 +125,18 @@ type Groups struct {
 	Groups []string `json:"groups" xml:"groups>element"`
 }
 
-// GetUsers handles GET requests on /cloud/users
-// Only allow self-read currently. TODO: List Users and Get on other users (both require
-// administrative privileges)
+// GetUsers handles GET requests on /cloud/users/{userid}
+// Admins can read all users, the quota is only returned for the current user.
 func (h *Handler) GetUsers(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	sublog := appctx.GetLogger(r.Context())
 
-	user := chi.URLParam(r, "userid")
-	// FIXME use ldap to fetch user info
+	username := chi.URLParam(r, "userid")
 	u, ok := ctxpkg.ContextGetUser(ctx)
 	if !ok {
 		response.WriteOCSError(w, r, response.MetaServerError.StatusCode, "missing user in context", fmt.Errorf("missing user in context"))
 		return
 	}
-	if user != u.Username {
-		// FIXME allow fetching other users info? only for admins
-		response.WriteOCSError(w, r, http.StatusForbidden, "user id mismatch", fmt.Errorf("%s tried to access %s user info endpoint", u.Id.OpaqueId, user))
-		return
-	}
 
 	gc, err := pool.GetGatewayServiceClient(h.gatewayAddr)
 	if err != nil {
@@ -104,6 +145,20 @@ func (h *Handler) GetUsers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if username != u.Username {
+		other, ok := h.getUser(w, r, gc, username)
+		if !ok {
+			return
+		}
+		response.WriteOCSSuccess(w, r, &Users{
+			ID:          other.Username,
+			DisplayName: other.DisplayName,
+			Email:       other.Mail,
+			UserType:    conversions.UserTypeString(other.Id.Type),
+		})
+		return
+	}
+
 	getHomeRes, err := gc.GetHome(ctx, &provider.GetHomeRequest{})
 	if err != nil {
 		sublog.Error().Err(err).Msg("error calling GetHome")
@@ -135,6 +190,7 @@ func (h *Handler) GetUsers(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response.WriteOCSSuccess(w, r, &Users{
+		ID: u.Username,
 		// ocs can only return the home storage quota
 		Quota: &Quota{
 			Free: int64(total - used),
@@ -24,6 +24,7 @@ import (
 
 	"github.com/ReneKroon/ttlcache/v2"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/config"
+	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/handlers/apps/notifications"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/handlers/apps/sharing/sharees"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/handlers/apps/sharing/shares"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/handlers/cloud/capabilities"
@@ -32,6 +33,9 @@ import (
 	configHandler "github.com/cs3org/reva/internal/http/services/owncloud/ocs/handlers/config"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/response"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/notification"
+	"github.com/cs3org/reva/pkg/notification/manager/registry"
 	"github.com/cs3org/reva/pkg/rhttp/global"
 	"github.com/go-chi/chi/v5"
 	"github.com/mitchellh/mapstructure"
@@ -46,6 +50,7 @@ type svc struct {
 	c                  *config.Config
 	router             *chi.Mux
 	warmupCacheTracker *ttlcache.Cache
+	notifier           *notification.Notifier
 }
 
 func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error) {
@@ -56,10 +61,16 @@ func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error)
 
 	conf.Init()
 
+	nm, err := getNotificationManager(conf)
+	if err != nil {
+		return nil, err
+	}
+
 	r := chi.NewRouter()
 	s := &svc{
-		c:      conf,
-		router: r,
+		c:        conf,
+		router:   r,
+		notifier: notification.NewNotifier(nm, conf.NotificationSMTP),
 	}
 
 	if err := s.routerInit(); err != nil {
@@ -74,6 +85,13 @@ func New(m map[string]interface{}, log *zerolog.Logger) (global.Service, error)
 	return s, nil
 }
 
+func getNotificationManager(c *config.Config) (notification.Manager, error) {
+	if f, ok := registry.NewFuncs[c.NotificationManager]; ok {
+		return f(c.NotificationManagers[c.NotificationManager])
+	}
+	return nil, errtypes.NotFound("driver not found: " + c.NotificationManager)
+}
+
 func (s *svc) Prefix() string {
 	return s.c.Prefix
 }
@@ -93,11 +111,16 @@ func (s *svc) routerInit() error {
 	configHandler := new(configHandler.Handler)
 	sharesHandler := new(shares.Handler)
 	shareesHandler := new(sharees.Handler)
+	notificationsHandler := new(notifications.Handler)
 	capabilitiesHandler.Init(s.c)
-	usersHandler.Init(s.c)
+	if err := usersHandler.Init(s.c); err != nil {
+		return err
+	}
 	configHandler.Init(s.c)
 	sharesHandler.Init(s.c)
+	sharesHandler.SetNotifier(s.notifier)
 	shareesHandler.Init(s.c)
+	notificationsHandler.Init(s.c, s.notifier)
 
 	s.router.Route("/v{version:(1|2)}.php", func(r chi.Router) {
 		r.Use(response.VersionCtx)
@@ -123,9 +146,12 @@ func (s *svc) routerInit() error {
 			r.Get("/sharees", shareesHandler.FindSharees)
 		})
 
-		// placeholder for notifications
-		r.Get("/apps/notifications/api/v1/notifications", func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
+		r.Route("/apps/notifications/api/v1/notifications", func(r chi.Router) {
+			r.Get("/", notificationsHandler.ListNotifications)
+			r.Delete("/", notificationsHandler.DeleteNotifications)
+			r.Get("/{notificationid}", notificationsHandler.GetNotification)
+			r.Delete("/{notificationid}", notificationsHandler.DeleteNotification)
+			r.Post("/{notificationid}/read", notificationsHandler.MarkAsRead)
 		})
 
 		r.Get("/config", configHandler.GetConfig)
@@ -134,8 +160,20 @@ func (s *svc) routerInit() error {
 			r.Get("/capabilities", capabilitiesHandler.GetCapabilities)
 			r.Get("/user", userHandler.GetSelf)
 			r.Route("/users", func(r chi.Router) {
+				r.Get("/", usersHandler.ListUsers)
+				r.Post("/", usersHandler.CreateUser)
 				r.Get("/{userid}", usersHandler.GetUsers)
+				r.Put("/{userid}", usersHandler.EditUser)
+				r.Delete("/{userid}", usersHandler.DeleteUser)
 				r.Get("/{userid}/groups", usersHandler.GetGroups)
+				r.Post("/{userid}/groups", usersHandler.AddToGroup)
+				r.Delete("/{userid}/groups", usersHandler.RemoveFromGroup)
+			})
+			r.Route("/groups", func(r chi.Router) {
+				r.Get("/", usersHandler.ListGroups)
+				r.Post("/", usersHandler.CreateGroup)
+				r.Get("/{groupid}", usersHandler.GetGroupMembers)
+				r.Delete("/{groupid}", usersHandler.DeleteGroup)
 			})
 		})
 	})
@@ -47,6 +47,7 @@ const (
 
 // Close is called when this service is being stopped.
 func (s *svc) Close() error {
+	s.siteacc.Close()
 	return nil
 }
 
@@ -97,6 +98,16 @@ func applyDefaultConfig(conf *config.Configuration) {
 		conf.Mentix.SiteRegistrationEndpoint = "/sitereg"
 	}
 
+	// Verification links are valid for 2 days by default
+	if conf.Lifecycle.VerificationTimeout <= 0 {
+		conf.Lifecycle.VerificationTimeout = 48
+	}
+
+	// Warn users 2 weeks before their account expires by default
+	if conf.Lifecycle.ExpiryWarning <= 0 {
+		conf.Lifecycle.ExpiryWarning = 14
+	}
+
 	// Enforce a minimum session timeout of 1 minute (and default to 5 minutes)
 	if conf.Webserver.SessionTimeout < 60 {
 		conf.Webserver.SessionTimeout = 5 * 60
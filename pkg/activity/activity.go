@@ -0,0 +1,87 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package activity keeps track of the actions users perform on files and shares.
+package activity
+
+import (
+	"context"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/utils"
+)
+
+// The types of the recorded activities.
+const (
+	TypeUpload                = "upload"
+	TypeCreateContainer       = "create_container"
+	TypeTouch                 = "touch"
+	TypeDelete                = "delete"
+	TypeMove                  = "move"
+	TypeRestoreRecycle        = "restore_recycle_item"
+	TypeRestoreVersion        = "restore_version"
+	TypeShareCreated          = "share_created"
+	TypeShareUpdated          = "share_updated"
+	TypeShareRemoved          = "share_removed"
+	TypeLinkCreated           = "link_created"
+	TypeLinkUpdated           = "link_updated"
+	TypeLinkRemoved           = "link_removed"
+	TypeFederatedShareCreated = "federated_share_created"
+)
+
+// Activity is an action performed by a user on a resource.
+type Activity struct {
+	ID         int64                `json:"id"`
+	Type       string               `json:"type"`
+	Executant  *userpb.UserId       `json:"executant"`
+	ResourceID *provider.ResourceId `json:"resource_id,omitempty"`
+	Path       string               `json:"path,omitempty"`
+	Timestamp  time.Time            `json:"timestamp"`
+	// Details holds further information depending on the type, e.g. the
+	// destination of a move or the grantee of a share.
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// Filter selects the activities to list. Empty fields match all activities.
+type Filter struct {
+	Executant  *userpb.UserId
+	ResourceID *provider.ResourceId
+	// Limit is the maximum number of activities returned, 0 means no limit.
+	Limit int
+}
+
+// Match returns whether the activity is selected by the filter.
+func (f *Filter) Match(a *Activity) bool {
+	if f.Executant != nil && !utils.UserEqual(f.Executant, a.Executant) {
+		return false
+	}
+	if f.ResourceID != nil && !utils.ResourceIDEqual(f.ResourceID, a.ResourceID) {
+		return false
+	}
+	return true
+}
+
+// Manager stores activities.
+type Manager interface {
+	// Record stores a new activity and assigns its ID.
+	Record(ctx context.Context, a *Activity) error
+	// ListActivities returns the activities selected by the filter, newest first.
+	ListActivities(ctx context.Context, f *Filter) ([]*Activity, error)
+}
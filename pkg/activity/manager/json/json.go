@@ -0,0 +1,180 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package json
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/cs3org/reva/pkg/activity"
+	"github.com/cs3org/reva/pkg/activity/manager/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("json", New)
+}
+
+// the services recording into the same file in one process, e.g. the
+// interceptor of the gateway and the data provider, have to share its state
+var (
+	filesMu sync.Mutex
+	files   = map[string]*activityFile{}
+)
+
+// activityFile serializes the access to an activities file and keeps the last
+// assigned id, so that the file doesn't have to be read for every record.
+type activityFile struct {
+	sync.Mutex
+	lastID int64
+	// size is the size of the file after the last write. If it differs the
+	// file has been appended to by another process and the last id is read
+	// again.
+	size int64
+}
+
+func getFile(file string) *activityFile {
+	filesMu.Lock()
+	defer filesMu.Unlock()
+	if _, ok := files[file]; !ok {
+		files[file] = &activityFile{size: -1}
+	}
+	return files[file]
+}
+
+type config struct {
+	File string `mapstructure:"file" docs:"/var/tmp/reva/activities.json;The file the activities are appended to, one json object per line."`
+}
+
+func (c *config) init() {
+	if c.File == "" {
+		c.File = "/var/tmp/reva/activities.json"
+	}
+}
+
+type mgr struct {
+	c    *config
+	file *activityFile
+}
+
+// New returns an activity manager appending the activities to a file.
+func New(m map[string]interface{}) (activity.Manager, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+	c.init()
+
+	if err := os.MkdirAll(filepath.Dir(c.File), 0700); err != nil {
+		return nil, errors.Wrap(err, "error creating the activities directory")
+	}
+	return &mgr{c: c, file: getFile(c.File)}, nil
+}
+
+// read calls f for every activity stored in the file, oldest first.
+func (m *mgr) read(f func(a *activity.Activity)) error {
+	fd, err := os.Open(m.c.File)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrap(err, "error opening the activities file")
+	}
+	defer fd.Close()
+
+	scanner := bufio.NewScanner(fd)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		a := &activity.Activity{}
+		if err := json.Unmarshal(scanner.Bytes(), a); err != nil {
+			return errors.Wrap(err, "error decoding the activities file")
+		}
+		f(a)
+	}
+	return errors.Wrap(scanner.Err(), "error reading the activities file")
+}
+
+func (m *mgr) Record(ctx context.Context, a *activity.Activity) error {
+	m.file.Lock()
+	defer m.file.Unlock()
+
+	fd, err := os.OpenFile(m.c.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return errors.Wrap(err, "error opening the activities file")
+	}
+	defer fd.Close()
+
+	info, err := fd.Stat()
+	if err != nil {
+		return errors.Wrap(err, "error opening the activities file")
+	}
+	if info.Size() != m.file.size {
+		var lastID int64
+		if err := m.read(func(a *activity.Activity) { lastID = a.ID }); err != nil {
+			return err
+		}
+		m.file.lastID = lastID
+	}
+	a.ID = m.file.lastID + 1
+
+	data, err := json.Marshal(a)
+	if err != nil {
+		return errors.Wrap(err, "error encoding the activity")
+	}
+	data = append(data, '\n')
+	if _, err := fd.Write(data); err != nil {
+		// the file may have been partially written
+		m.file.size = -1
+		return errors.Wrap(err, "error writing the activities file")
+	}
+	m.file.lastID = a.ID
+	m.file.size = info.Size() + int64(len(data))
+	return nil
+}
+
+func (m *mgr) ListActivities(ctx context.Context, f *activity.Filter) ([]*activity.Activity, error) {
+	m.file.Lock()
+	defer m.file.Unlock()
+
+	matches := []*activity.Activity{}
+	if err := m.read(func(a *activity.Activity) {
+		if f.Match(a) {
+			matches = append(matches, a)
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	list := make([]*activity.Activity, 0, len(matches))
+	for i := len(matches) - 1; i >= 0; i-- {
+		if f.Limit > 0 && len(list) == f.Limit {
+			break
+		}
+		list = append(list, matches[i])
+	}
+	return list, nil
+}
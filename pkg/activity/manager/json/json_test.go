@@ -0,0 +1,115 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package json
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/activity"
+)
+
+func TestRecordAndList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "activities")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "activities.json")
+	m, err := New(map[string]interface{}{"file": file})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	einstein := &userpb.UserId{Idp: "idp", OpaqueId: "einstein"}
+	marie := &userpb.UserId{Idp: "idp", OpaqueId: "marie"}
+	res := &provider.ResourceId{StorageId: "storage", OpaqueId: "file"}
+	records := []*activity.Activity{
+		{Type: activity.TypeUpload, Executant: einstein, ResourceID: res, Path: "/file", Timestamp: time.Now()},
+		{Type: activity.TypeCreateContainer, Executant: einstein, ResourceID: &provider.ResourceId{StorageId: "storage", OpaqueId: "dir"}, Path: "/dir", Timestamp: time.Now()},
+		{Type: activity.TypeShareCreated, Executant: einstein, ResourceID: res, Path: "/file", Timestamp: time.Now(), Details: map[string]string{"grantee": "marie"}},
+		{Type: activity.TypeMove, Executant: marie, ResourceID: res, Path: "/file", Timestamp: time.Now(), Details: map[string]string{"destination": "/renamed"}},
+	}
+	for _, a := range records {
+		if err := m.Record(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if records[3].ID != 4 {
+		t.Fatalf("expected id 4 got %d", records[3].ID)
+	}
+
+	// a new manager on the same file continues the ids
+	m, err = New(map[string]interface{}{"file": file})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := m.ListActivities(ctx, &activity.Filter{ResourceID: res})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 || list[0].Type != activity.TypeMove || list[2].Type != activity.TypeUpload {
+		t.Fatalf("unexpected resource activities %+v", list)
+	}
+	if list[1].Details["grantee"] != "marie" {
+		t.Fatalf("expected share details, got %v", list[1].Details)
+	}
+
+	list, err = m.ListActivities(ctx, &activity.Filter{Executant: einstein, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].ID != 3 || list[1].ID != 2 {
+		t.Fatalf("unexpected user activities %+v", list)
+	}
+
+	a := &activity.Activity{Type: activity.TypeDelete, Executant: marie, ResourceID: res, Timestamp: time.Now()}
+	if err := m.Record(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if a.ID != 5 {
+		t.Fatalf("expected id 5 got %d", a.ID)
+	}
+
+	// activities appended by another process are taken into account
+	fd, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.WriteString(`{"id":6,"type":"delete"}` + "\n"); err != nil {
+		t.Fatal(err)
+	}
+	fd.Close()
+	a = &activity.Activity{Type: activity.TypeDelete, Executant: marie, ResourceID: res, Timestamp: time.Now()}
+	if err := m.Record(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if a.ID != 7 {
+		t.Fatalf("expected id 7 got %d", a.ID)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cs3org/reva/pkg/activity"
+	"github.com/cs3org/reva/pkg/activity/manager/registry"
+)
+
+func init() {
+	registry.Register("memory", New)
+}
+
+type mgr struct {
+	sync.Mutex
+	activities []*activity.Activity
+}
+
+// New returns an in-memory activity manager.
+func New(m map[string]interface{}) (activity.Manager, error) {
+	return &mgr{}, nil
+}
+
+func (m *mgr) Record(ctx context.Context, a *activity.Activity) error {
+	m.Lock()
+	defer m.Unlock()
+
+	a.ID = int64(len(m.activities) + 1)
+	c := *a
+	m.activities = append(m.activities, &c)
+	return nil
+}
+
+func (m *mgr) ListActivities(ctx context.Context, f *activity.Filter) ([]*activity.Activity, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	list := []*activity.Activity{}
+	for i := len(m.activities) - 1; i >= 0; i-- {
+		if f.Limit > 0 && len(list) == f.Limit {
+			break
+		}
+		if f.Match(m.activities[i]) {
+			c := *m.activities[i]
+			list = append(list, &c)
+		}
+	}
+	return list, nil
+}
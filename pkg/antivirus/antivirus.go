@@ -0,0 +1,42 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package antivirus defines the interface of virus scanners which check the
+// content of uploaded files.
+package antivirus
+
+import (
+	"context"
+	"io"
+)
+
+// Result is the outcome of a scan.
+type Result struct {
+	// Infected is set if the scanned content contains a virus.
+	Infected bool
+	// Description names the virus found, if any.
+	Description string
+}
+
+// Scanner scans content for viruses.
+type Scanner interface {
+	// Scan reads r until EOF and reports whether it is infected.
+	// The name is used for logging and by scanners which take the
+	// file name into account.
+	Scan(ctx context.Context, name string, r io.Reader) (*Result, error)
+}
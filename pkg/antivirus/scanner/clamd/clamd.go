@@ -0,0 +1,151 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package clamd scans content with the ClamAV daemon, see
+// https://docs.clamav.net/manual/Usage/Scanning.html#clamd
+package clamd
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/antivirus"
+	"github.com/cs3org/reva/pkg/antivirus/scanner/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("clamd", New)
+}
+
+const chunkSize = 32 * 1024
+
+type config struct {
+	Network string `mapstructure:"network" docs:"unix;The network of the clamd socket, unix or tcp."`
+	Address string `mapstructure:"address" docs:"/run/clamav/clamd.ctl;The address of the clamd socket."`
+	Timeout int    `mapstructure:"timeout" docs:"300;The timeout of a scan in seconds."`
+}
+
+func (c *config) init() {
+	if c.Network == "" {
+		c.Network = "unix"
+	}
+	if c.Address == "" {
+		c.Address = "/run/clamav/clamd.ctl"
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 300
+	}
+}
+
+type scanner struct {
+	c *config
+}
+
+func parseConfig(m map[string]interface{}) (*config, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		err = errors.Wrap(err, "error decoding conf")
+		return nil, err
+	}
+	return c, nil
+}
+
+// New returns a scanner which streams the content to clamd.
+func New(m map[string]interface{}) (antivirus.Scanner, error) {
+	c, err := parseConfig(m)
+	if err != nil {
+		return nil, err
+	}
+	c.init()
+	return &scanner{c: c}, nil
+}
+
+// Scan sends the content with the INSTREAM command, which expects chunks
+// prefixed with their length and terminated by an empty chunk.
+func (s *scanner) Scan(ctx context.Context, name string, r io.Reader) (*antivirus.Result, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, s.c.Network, s.c.Address)
+	if err != nil {
+		return nil, errors.Wrap(err, "clamd: error connecting to clamd")
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(time.Duration(s.c.Timeout) * time.Second)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, errors.Wrap(err, "clamd: error setting deadline")
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, errors.Wrap(err, "clamd: error sending command")
+	}
+
+	buf := make([]byte, 4+chunkSize)
+	for {
+		n, err := r.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, werr := conn.Write(buf[:4+n]); werr != nil {
+				return nil, errors.Wrap(werr, "clamd: error sending content")
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "clamd: error reading content")
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return nil, errors.Wrap(err, "clamd: error terminating content")
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "clamd: error reading reply")
+	}
+	return parseReply(reply)
+}
+
+// parseReply parses replies like "stream: OK" or
+// "stream: Eicar-Signature FOUND".
+func parseReply(reply string) (*antivirus.Result, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	reply = strings.TrimPrefix(reply, "stream:")
+	reply = strings.TrimSpace(reply)
+	switch {
+	case reply == "OK":
+		return &antivirus.Result{}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return &antivirus.Result{
+			Infected:    true,
+			Description: strings.TrimSuffix(reply, " FOUND"),
+		}, nil
+	default:
+		return nil, errors.Errorf("clamd: scan failed: %s", reply)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package clamd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// serve accepts a single INSTREAM command and replies with "FOUND"
+// if the streamed content contains "virus".
+func serve(t *testing.T, l net.Listener) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	cmd, err := r.ReadString(0)
+	if err != nil || cmd != "zINSTREAM\x00" {
+		t.Errorf("unexpected command %q: %v", cmd, err)
+		return
+	}
+	var content bytes.Buffer
+	for {
+		var size uint32
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			t.Errorf("error reading chunk size: %v", err)
+			return
+		}
+		if size == 0 {
+			break
+		}
+		if _, err := io.CopyN(&content, r, int64(size)); err != nil {
+			t.Errorf("error reading chunk: %v", err)
+			return
+		}
+	}
+	if strings.Contains(content.String(), "virus") {
+		_, _ = conn.Write([]byte("stream: Test-Virus FOUND\x00"))
+	} else {
+		_, _ = conn.Write([]byte("stream: OK\x00"))
+	}
+}
+
+func TestScan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clamd-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "clamd.sock")
+
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	s, err := New(map[string]interface{}{"network": "unix", "address": socket})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		content  string
+		infected bool
+	}{
+		"clean":    {strings.Repeat("clean content ", 10000), false},
+		"infected": {strings.Repeat("x", 2*chunkSize) + "virus", true},
+		"empty":    {"", false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			go serve(t, l)
+			res, err := s.Scan(context.Background(), "file.txt", strings.NewReader(tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Infected != tt.infected {
+				t.Errorf("expected infected to be %v, got %v", tt.infected, res.Infected)
+			}
+			if tt.infected && res.Description != "Test-Virus" {
+				t.Errorf("unexpected description %q", res.Description)
+			}
+		})
+	}
+}
+
+func TestParseReply(t *testing.T) {
+	if _, err := parseReply("INSTREAM size limit exceeded. ERROR\x00"); err == nil {
+		t.Error("expected an error")
+	}
+	res, err := parseReply("stream: Win.Test.EICAR_HDB-1 FOUND\x00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Infected || res.Description != "Win.Test.EICAR_HDB-1" {
+		t.Errorf("unexpected result %+v", res)
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package icap scans content with an ICAP server, see RFC 3507.
+package icap
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/antivirus"
+	"github.com/cs3org/reva/pkg/antivirus/scanner/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("icap", New)
+}
+
+const (
+	defaultPort = "1344"
+	chunkSize   = 32 * 1024
+)
+
+type config struct {
+	URL     string `mapstructure:"url" docs:"icap://localhost:1344/avscan;The URL of the ICAP service."`
+	Timeout int    `mapstructure:"timeout" docs:"300;The timeout of a scan in seconds."`
+}
+
+func (c *config) init() {
+	if c.URL == "" {
+		c.URL = "icap://localhost:1344/avscan"
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 300
+	}
+}
+
+type scanner struct {
+	c   *config
+	url *url.URL
+}
+
+func parseConfig(m map[string]interface{}) (*config, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		err = errors.Wrap(err, "error decoding conf")
+		return nil, err
+	}
+	return c, nil
+}
+
+// New returns a scanner which sends the content to an ICAP server
+// in a RESPMOD request.
+func New(m map[string]interface{}) (antivirus.Scanner, error) {
+	c, err := parseConfig(m)
+	if err != nil {
+		return nil, err
+	}
+	c.init()
+
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, errors.Wrap(err, "icap: error parsing url")
+	}
+	if u.Scheme != "icap" {
+		return nil, errors.Errorf("icap: unsupported scheme %q", u.Scheme)
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), defaultPort)
+	}
+	return &scanner{c: c, url: u}, nil
+}
+
+func (s *scanner) Scan(ctx context.Context, name string, r io.Reader) (*antivirus.Result, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.url.Host)
+	if err != nil {
+		return nil, errors.Wrap(err, "icap: error connecting to server")
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(time.Duration(s.c.Timeout) * time.Second)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, errors.Wrap(err, "icap: error setting deadline")
+	}
+
+	w := bufio.NewWriter(conn)
+	if err := s.writeRequest(w, name, r); err != nil {
+		return nil, err
+	}
+	if err := w.Flush(); err != nil {
+		return nil, errors.Wrap(err, "icap: error sending request")
+	}
+
+	return readResponse(bufio.NewReader(conn))
+}
+
+// writeRequest writes a RESPMOD request encapsulating a GET request for the
+// file and a response carrying the content in chunked encoding.
+func (s *scanner) writeRequest(w *bufio.Writer, name string, r io.Reader) error {
+	reqHdr := fmt.Sprintf("GET /%s HTTP/1.1\r\nHost: reva\r\n\r\n", strings.TrimPrefix(path.Clean("/"+name), "/"))
+	resHdr := "HTTP/1.1 200 OK\r\nContent-Type: application/octet-stream\r\nTransfer-Encoding: chunked\r\n\r\n"
+
+	fmt.Fprintf(w, "RESPMOD %s ICAP/1.0\r\n", s.url.String())
+	fmt.Fprintf(w, "Host: %s\r\n", s.url.Host)
+	fmt.Fprintf(w, "Allow: 204\r\n")
+	fmt.Fprintf(w, "Encapsulated: req-hdr=0, res-hdr=%d, res-body=%d\r\n", len(reqHdr), len(reqHdr)+len(resHdr))
+	fmt.Fprintf(w, "\r\n")
+	w.WriteString(reqHdr)
+	w.WriteString(resHdr)
+
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			fmt.Fprintf(w, "%x\r\n", n)
+			w.Write(buf[:n])
+			if _, werr := w.WriteString("\r\n"); werr != nil {
+				return errors.Wrap(werr, "icap: error sending content")
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "icap: error reading content")
+		}
+	}
+	_, err := w.WriteString("0\r\n\r\n")
+	return err
+}
+
+func readResponse(r *bufio.Reader) (*antivirus.Result, error) {
+	tp := textproto.NewReader(r)
+	line, err := tp.ReadLine()
+	if err != nil {
+		return nil, errors.Wrap(err, "icap: error reading response")
+	}
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) < 2 || !strings.HasPrefix(parts[0], "ICAP/") {
+		return nil, errors.Errorf("icap: malformed status line %q", line)
+	}
+	code, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, errors.Errorf("icap: malformed status line %q", line)
+	}
+
+	header, err := tp.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "icap: error reading response headers")
+	}
+
+	switch code {
+	case 204:
+		// the content is unmodified, i.e. clean
+		return &antivirus.Result{}, nil
+	case 200:
+		return &antivirus.Result{Infected: true, Description: threat(header)}, nil
+	default:
+		return nil, errors.Errorf("icap: scan failed: %s", line)
+	}
+}
+
+// threat extracts the name of the virus from the headers commonly used by
+// ICAP servers, e.g. "X-Infection-Found: Type=0; Resolution=2; Threat=Eicar;".
+func threat(h textproto.MIMEHeader) string {
+	if v := h.Get("X-Infection-Found"); v != "" {
+		for _, field := range strings.Split(v, ";") {
+			field = strings.TrimSpace(field)
+			if strings.HasPrefix(field, "Threat=") {
+				return strings.TrimPrefix(field, "Threat=")
+			}
+		}
+	}
+	if v := h.Get("X-Virus-ID"); v != "" {
+		return strings.TrimSpace(v)
+	}
+	if v := h.Get("X-Violations-Found"); v != "" {
+		return strings.TrimSpace(v)
+	}
+	// the server modified the content without telling why
+	return "blocked by the ICAP server"
+}
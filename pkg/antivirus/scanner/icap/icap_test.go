@@ -0,0 +1,122 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package icap
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// serve accepts a single RESPMOD request and replies with 200 and the
+// X-Infection-Found header if the encapsulated body contains "virus".
+func serve(t *testing.T, l net.Listener) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	tp := textproto.NewReader(r)
+	line, err := tp.ReadLine()
+	if err != nil || !strings.HasPrefix(line, "RESPMOD icap://") {
+		t.Errorf("unexpected request line %q: %v", line, err)
+		return
+	}
+	if _, err := tp.ReadMIMEHeader(); err != nil {
+		t.Errorf("error reading icap headers: %v", err)
+		return
+	}
+	req, err := http.ReadRequest(r)
+	if err != nil {
+		t.Errorf("error reading encapsulated request: %v", err)
+		return
+	}
+	res, err := http.ReadResponse(r, req)
+	if err != nil {
+		t.Errorf("error reading encapsulated response: %v", err)
+		return
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Errorf("error reading encapsulated body: %v", err)
+		return
+	}
+
+	if strings.Contains(string(body), "virus") {
+		_, _ = conn.Write([]byte("ICAP/1.0 200 OK\r\nX-Infection-Found: Type=0; Resolution=2; Threat=Test-Virus;\r\nEncapsulated: null-body=0\r\n\r\n"))
+	} else {
+		_, _ = conn.Write([]byte("ICAP/1.0 204 No Content\r\nEncapsulated: null-body=0\r\n\r\n"))
+	}
+}
+
+func TestScan(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	s, err := New(map[string]interface{}{"url": "icap://" + l.Addr().String() + "/avscan"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		content  string
+		infected bool
+	}{
+		"clean":    {strings.Repeat("clean content ", 10000), false},
+		"infected": {strings.Repeat("x", 2*chunkSize) + "virus", true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			go serve(t, l)
+			res, err := s.Scan(context.Background(), "/some/file.txt", strings.NewReader(tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Infected != tt.infected {
+				t.Errorf("expected infected to be %v, got %v", tt.infected, res.Infected)
+			}
+			if tt.infected && res.Description != "Test-Virus" {
+				t.Errorf("unexpected description %q", res.Description)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(map[string]interface{}{"url": "http://localhost/avscan"}); err == nil {
+		t.Error("expected an error for a non icap url")
+	}
+	s, err := New(map[string]interface{}{"url": "icap://localhost/avscan"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host := s.(*scanner).url.Host; host != "localhost:1344" {
+		t.Errorf("expected the default port, got %q", host)
+	}
+}
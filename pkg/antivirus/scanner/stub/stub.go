@@ -0,0 +1,88 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package stub provides a virus scanner for tests which reports content
+// containing a configurable signature as infected.
+package stub
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+
+	"github.com/cs3org/reva/pkg/antivirus"
+	"github.com/cs3org/reva/pkg/antivirus/scanner/registry"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("stub", New)
+}
+
+// EICAR is the signature of the EICAR anti malware test file.
+const EICAR = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+type config struct {
+	Signature   string `mapstructure:"signature" docs:"The EICAR test signature;Content containing this signature is reported as infected."`
+	Description string `mapstructure:"description" docs:"Eicar-Signature;The name of the virus reported."`
+}
+
+func (c *config) init() {
+	if c.Signature == "" {
+		c.Signature = EICAR
+	}
+	if c.Description == "" {
+		c.Description = "Eicar-Signature"
+	}
+}
+
+type scanner struct {
+	c *config
+}
+
+func parseConfig(m map[string]interface{}) (*config, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		err = errors.Wrap(err, "error decoding conf")
+		return nil, err
+	}
+	return c, nil
+}
+
+// New returns a scanner which looks for the configured signature.
+func New(m map[string]interface{}) (antivirus.Scanner, error) {
+	c, err := parseConfig(m)
+	if err != nil {
+		return nil, err
+	}
+	c.init()
+	return &scanner{c: c}, nil
+}
+
+func (s *scanner) Scan(ctx context.Context, name string, r io.Reader) (*antivirus.Result, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "stub: error reading content")
+	}
+	if bytes.Contains(content, []byte(s.c.Signature)) {
+		return &antivirus.Result{Infected: true, Description: s.c.Description}, nil
+	}
+	return &antivirus.Result{}, nil
+}
@@ -34,6 +34,7 @@ import (
 	"github.com/cs3org/reva/pkg/appauth/manager/registry"
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/passwordpolicy"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 	"github.com/sethvargo/go-password/password"
@@ -48,11 +49,18 @@ type config struct {
 	File             string `mapstructure:"file"`
 	TokenStrength    int    `mapstructure:"token_strength"`
 	PasswordHashCost int    `mapstructure:"password_hash_cost"`
+	// PasswordPolicy is applied to the generated application passwords.
+	PasswordPolicy map[string]interface{} `mapstructure:"password_policy"`
 }
 
+// maxGenerateAttempts bounds the number of tokens generated until one
+// fulfills the password policy.
+const maxGenerateAttempts = 100
+
 type jsonManager struct {
 	sync.Mutex
 	config *config
+	policy *passwordpolicy.Policy
 	// map[userid][password]AppPassword
 	passwords map[string]map[string]*apppb.AppPassword
 }
@@ -66,6 +74,13 @@ func New(m map[string]interface{}) (appauth.Manager, error) {
 
 	c.init()
 
+	var policy *passwordpolicy.Policy
+	if len(c.PasswordPolicy) > 0 {
+		if policy, err = passwordpolicy.New(c.PasswordPolicy); err != nil {
+			return nil, errors.Wrap(err, "error creating a new manager")
+		}
+	}
+
 	// load or create file
 	manager, err := loadOrCreate(c.File)
 	if err != nil {
@@ -73,6 +88,7 @@ func New(m map[string]interface{}) (appauth.Manager, error) {
 	}
 
 	manager.config = c
+	manager.policy = policy
 
 	return manager, nil
 }
@@ -128,8 +144,44 @@ func loadOrCreate(file string) (*jsonManager, error) {
 	return m, nil
 }
 
+// generateToken returns a random token which fulfills the password policy.
+func (mgr *jsonManager) generateToken() (string, error) {
+	policy := mgr.policy
+	if policy == nil {
+		policy = &passwordpolicy.Policy{}
+	}
+
+	length, digits, symbols := mgr.config.TokenStrength, mgr.config.TokenStrength/2, 0
+	if policy.MinLength > length {
+		length = policy.MinLength
+	}
+	if policy.MinDigits > digits {
+		digits = policy.MinDigits
+	}
+	if policy.MinSpecialCharacters > symbols {
+		symbols = policy.MinSpecialCharacters
+	}
+	letters := policy.MinLowerCaseCharacters + policy.MinUpperCaseCharacters
+	if digits+symbols+letters > length {
+		length = digits + symbols + letters
+	}
+	allowRepeat := digits > len(password.Digits) || symbols > len(password.Symbols) ||
+		length-digits-symbols > len(password.LowerLetters)+len(password.UpperLetters)
+
+	for i := 0; i < maxGenerateAttempts; i++ {
+		token, err := password.Generate(length, digits, symbols, false, allowRepeat)
+		if err != nil {
+			return "", err
+		}
+		if policy.Validate(token) == nil {
+			return token, nil
+		}
+	}
+	return "", errors.New("could not generate a token fulfilling the password policy")
+}
+
 func (mgr *jsonManager) GenerateAppPassword(ctx context.Context, scope map[string]*authpb.Scope, label string, expiration *typespb.Timestamp) (*apppb.AppPassword, error) {
-	token, err := password.Generate(mgr.config.TokenStrength, mgr.config.TokenStrength/2, 0, false, false)
+	token, err := mgr.generateToken()
 	if err != nil {
 		return nil, errors.Wrap(err, "error creating new token")
 	}
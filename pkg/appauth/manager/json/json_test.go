@@ -732,3 +732,40 @@ func concatMaps(maps ...map[string]map[string]*apppb.AppPassword) map[string]map
 	}
 	return res
 }
+
+func TestGenerateAppPasswordPolicy(t *testing.T) {
+	userTest := &userpb.User{Id: &userpb.UserId{Idp: "0"}, Username: "Test User"}
+	ctx := ctxpkg.ContextSetUser(context.Background(), userTest)
+	tempDir := createTempDir(t, "jsonappauth_test")
+	defer os.RemoveAll(tempDir)
+
+	tmpFile := createTempFile(t, tempDir, "test.json")
+	defer tmpFile.Close()
+	fill(t, tmpFile, "")
+
+	manager, err := New(map[string]interface{}{
+		"file":               tmpFile.Name(),
+		"token_strength":     8,
+		"password_hash_cost": 4,
+		"password_policy": map[string]interface{}{
+			"min_length":               20,
+			"min_uppercase_characters": 2,
+			"min_special_characters":   3,
+		},
+	})
+	if err != nil {
+		t.Fatal("error creating manager:", err)
+	}
+
+	token, err := manager.(*jsonManager).generateToken()
+	if err != nil {
+		t.Fatal("error generating token:", err)
+	}
+	if err := manager.(*jsonManager).policy.Validate(token); err != nil {
+		t.Fatalf("generated token %q does not fulfill the policy: %v", token, err)
+	}
+
+	if _, err := manager.GenerateAppPassword(ctx, nil, "label", nil); err != nil {
+		t.Fatal("error generating password:", err)
+	}
+}
@@ -39,6 +39,8 @@ type Credentials struct {
 	Type         string
 	ClientID     string
 	ClientSecret string
+	// OTP is a one-time password sent separately from the secret, if any
+	OTP string
 }
 
 // CredentialStrategy obtains Credentials from the request.
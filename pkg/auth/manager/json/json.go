@@ -22,6 +22,9 @@ import (
 	"context"
 	"encoding/json"
 	"io/ioutil"
+	"os"
+	"sync"
+	"time"
 
 	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
 	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
@@ -53,7 +56,10 @@ type Credentials struct {
 }
 
 type manager struct {
+	c           *config
+	mu          sync.Mutex
 	credentials map[string]*Credentials
+	mtime       time.Time
 }
 
 type config struct {
@@ -93,8 +99,30 @@ func (m *manager) Configure(ml map[string]interface{}) error {
 		return err
 	}
 
-	m.credentials = map[string]*Credentials{}
-	f, err := ioutil.ReadFile(c.Users)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.c = c
+	m.mtime = time.Time{}
+	return m.load()
+}
+
+// load reads the users file if it changed since it was read last, e.g.
+// because users have been provisioned through the json user manager.
+func (m *manager) load() error {
+	fd, err := os.Open(m.c.Users)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	info, err := fd.Stat()
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(m.mtime) {
+		return nil
+	}
+
+	f, err := ioutil.ReadAll(fd)
 	if err != nil {
 		return err
 	}
@@ -106,14 +134,25 @@ func (m *manager) Configure(ml map[string]interface{}) error {
 		return err
 	}
 
+	m.credentials = map[string]*Credentials{}
 	for _, c := range credentials {
 		m.credentials[c.Username] = c
 	}
+	m.mtime = info.ModTime()
 	return nil
 }
 
+func (m *manager) getCredentials(username string) (*Credentials, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// keep using the credentials read before if the file can't be read
+	_ = m.load()
+	c, ok := m.credentials[username]
+	return c, ok
+}
+
 func (m *manager) Authenticate(ctx context.Context, username string, secret string) (*user.User, map[string]*authpb.Scope, error) {
-	if c, ok := m.credentials[username]; ok {
+	if c, ok := m.getCredentials(username); ok {
 		if c.Secret == secret {
 			var scopes map[string]*authpb.Scope
 			var err error
@@ -31,5 +31,6 @@ import (
 	_ "github.com/cs3org/reva/pkg/auth/manager/oidcmapping"
 	_ "github.com/cs3org/reva/pkg/auth/manager/owncloudsql"
 	_ "github.com/cs3org/reva/pkg/auth/manager/publicshares"
+	_ "github.com/cs3org/reva/pkg/auth/manager/totp"
 	// Add your own here
 )
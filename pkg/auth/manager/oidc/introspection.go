@@ -0,0 +1,97 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/oauth2"
+)
+
+// getIntrospectionEndpoint returns the configured introspection endpoint or, if a client ID
+// has been configured, the one advertised in the discovery document of the main issuer.
+func (am *mgr) getIntrospectionEndpoint(ctx context.Context) string {
+	if am.c.IntrospectionEndpoint != "" {
+		return am.c.IntrospectionEndpoint
+	}
+	if am.c.IntrospectionClientID == "" || am.c.Issuer == "" {
+		return ""
+	}
+
+	provider, err := am.getOIDCProvider(ctx, am.c.Issuer)
+	if err != nil {
+		log.Debug().Err(err).Msg("oidc: error creating oidc provider for introspection discovery")
+		return ""
+	}
+	var discovery struct {
+		IntrospectionEndpoint string `json:"introspection_endpoint"`
+	}
+	if err := provider.Claims(&discovery); err != nil {
+		return ""
+	}
+	return discovery.IntrospectionEndpoint
+}
+
+// introspect validates an opaque token against an RFC 7662 introspection endpoint
+// and returns the claims of the introspection response.
+func (am *mgr) introspect(ctx context.Context, endpoint, token string) (map[string]interface{}, error) {
+	form := url.Values{}
+	form.Set("token", token)
+	form.Set("token_type_hint", "access_token")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, errors.Wrap(err, "oidc: error creating introspection request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if am.c.IntrospectionClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(am.c.IntrospectionClientID), url.QueryEscape(am.c.IntrospectionClientSecret))
+	}
+
+	client := http.DefaultClient
+	if c, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok {
+		client = c
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "oidc: error introspecting token")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc: introspection endpoint returned status %d", res.StatusCode)
+	}
+
+	var claims map[string]interface{}
+	if err := json.NewDecoder(res.Body).Decode(&claims); err != nil {
+		return nil, errors.Wrap(err, "oidc: error decoding introspection response")
+	}
+	if active, _ := claims["active"].(bool); !active {
+		return nil, errors.New("oidc: token is not active")
+	}
+	return claims, nil
+}
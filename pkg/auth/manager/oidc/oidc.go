@@ -22,8 +22,11 @@ package oidc
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	oidc "github.com/coreos/go-oidc"
@@ -47,17 +50,24 @@ func init() {
 }
 
 type mgr struct {
-	provider *oidc.Provider // cached on first request
-	c        *config
+	providers map[string]*oidc.Provider // cached on first request, keyed by issuer
+	mu        sync.Mutex
+	c         *config
 }
 
 type config struct {
-	Insecure   bool   `mapstructure:"insecure" docs:"false;Whether to skip certificate checks when sending requests."`
-	Issuer     string `mapstructure:"issuer" docs:";The issuer of the OIDC token."`
-	IDClaim    string `mapstructure:"id_claim" docs:"sub;The claim containing the ID of the user."`
-	UIDClaim   string `mapstructure:"uid_claim" docs:";The claim containing the UID of the user."`
-	GIDClaim   string `mapstructure:"gid_claim" docs:";The claim containing the GID of the user."`
-	GatewaySvc string `mapstructure:"gatewaysvc" docs:";The endpoint at which the GRPC gateway is exposed."`
+	Insecure                  bool     `mapstructure:"insecure" docs:"false;Whether to skip certificate checks when sending requests."`
+	Issuer                    string   `mapstructure:"issuer" docs:";The issuer of the OIDC token."`
+	Issuers                   []string `mapstructure:"issuers" docs:";Additional trusted issuers of OIDC tokens."`
+	Audiences                 []string `mapstructure:"audiences" docs:";If set, only JWT and introspected tokens issued for one of these audiences are accepted; tokens that can only be checked through the userinfo endpoint are rejected."`
+	IDClaim                   string   `mapstructure:"id_claim" docs:"sub;The claim containing the ID of the user."`
+	UIDClaim                  string   `mapstructure:"uid_claim" docs:";The claim containing the UID of the user."`
+	GIDClaim                  string   `mapstructure:"gid_claim" docs:";The claim containing the GID of the user."`
+	GroupsClaim               string   `mapstructure:"groups_claim" docs:";The claim containing the groups of the user. If empty, the groups are obtained from the user provider."`
+	IntrospectionEndpoint     string   `mapstructure:"introspection_endpoint" docs:";The RFC 7662 endpoint used to validate opaque access tokens. If empty, it is discovered from the issuer when a client ID is set."`
+	IntrospectionClientID     string   `mapstructure:"introspection_client_id" docs:";The client ID used to authenticate against the introspection endpoint."`
+	IntrospectionClientSecret string   `mapstructure:"introspection_client_secret" docs:";The client secret used to authenticate against the introspection endpoint."`
+	GatewaySvc                string   `mapstructure:"gatewaysvc" docs:";The endpoint at which the GRPC gateway is exposed."`
 }
 
 func (c *config) init() {
@@ -69,6 +79,29 @@ func (c *config) init() {
 	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
 }
 
+// trustedIssuers returns the configured issuers, the main one first.
+func (c *config) trustedIssuers() []string {
+	issuers := []string{}
+	if c.Issuer != "" {
+		issuers = append(issuers, c.Issuer)
+	}
+	for _, i := range c.Issuers {
+		if i != "" && i != c.Issuer {
+			issuers = append(issuers, i)
+		}
+	}
+	return issuers
+}
+
+func (c *config) isTrustedIssuer(issuer string) bool {
+	for _, i := range c.trustedIssuers() {
+		if i == issuer {
+			return true
+		}
+	}
+	return false
+}
+
 func parseConfig(m map[string]interface{}) (*config, error) {
 	c := &config{}
 	if err := mapstructure.Decode(m, c); err != nil {
@@ -95,6 +128,7 @@ func (am *mgr) Configure(m map[string]interface{}) error {
 	}
 	c.init()
 	am.c = c
+	am.providers = map[string]*oidc.Provider{}
 	return nil
 }
 
@@ -104,26 +138,12 @@ func (am *mgr) Configure(m map[string]interface{}) error {
 func (am *mgr) Authenticate(ctx context.Context, clientID, clientSecret string) (*user.User, map[string]*authpb.Scope, error) {
 	ctx = am.getOAuthCtx(ctx)
 
-	oidcProvider, err := am.getOIDCProvider(ctx)
-	if err != nil {
-		return nil, nil, fmt.Errorf("error creating oidc provider: +%v", err)
-	}
-
-	oauth2Token := &oauth2.Token{
-		AccessToken: clientSecret,
-	}
-	userInfo, err := oidcProvider.UserInfo(ctx, oauth2.StaticTokenSource(oauth2Token))
-	if err != nil {
-		return nil, nil, fmt.Errorf("oidc: error getting userinfo: +%v", err)
-	}
-
 	// claims contains the standard OIDC claims like issuer, iat, aud, ... and any other non-standard one.
 	// TODO(labkode): make claims configuration dynamic from the config file so we can add arbitrary mappings from claims to user struct.
-	var claims map[string]interface{}
-	if err := userInfo.Claims(&claims); err != nil {
-		return nil, nil, fmt.Errorf("oidc: error unmarshaling userinfo claims: %v", err)
+	claims, err := am.getClaims(ctx, clientSecret)
+	if err != nil {
+		return nil, nil, err
 	}
-	log.Debug().Interface("claims", claims).Interface("userInfo", userInfo).Msg("unmarshalled userinfo")
 
 	if claims["issuer"] == nil { // This is not set in simplesamlphp
 		claims["issuer"] = am.c.Issuer
@@ -153,6 +173,9 @@ func (am *mgr) Authenticate(ctx context.Context, clientID, clientSecret string)
 	if claims["name"] == nil {
 		return nil, nil, fmt.Errorf("no \"name\" attribute found in userinfo: maybe the client did not request the oidc \"profile\"-scope")
 	}
+	if _, ok := claims[am.c.IDClaim].(string); !ok {
+		return nil, nil, fmt.Errorf("no %q attribute found in userinfo", am.c.IDClaim)
+	}
 
 	var uid, gid float64
 	if am.c.UIDClaim != "" {
@@ -167,28 +190,18 @@ func (am *mgr) Authenticate(ctx context.Context, clientID, clientSecret string)
 		Idp:      claims["issuer"].(string),     // in the scope of this issuer
 		Type:     getUserType(claims[am.c.IDClaim].(string)),
 	}
-	gwc, err := pool.GetGatewayServiceClient(am.c.GatewaySvc)
-	if err != nil {
-		return nil, nil, errors.Wrap(err, "oidc: error getting gateway grpc client")
-	}
-	getGroupsResp, err := gwc.GetUserGroups(ctx, &user.GetUserGroupsRequest{
-		UserId: userID,
-	})
+
+	groups, err := am.getGroups(ctx, userID, claims)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "oidc: error getting user groups")
-	}
-	if getGroupsResp.Status.Code != rpc.Code_CODE_OK {
-		return nil, nil, errors.Wrap(err, "oidc: grpc getting user groups failed")
+		return nil, nil, err
 	}
 
 	u := &user.User{
 		Id:       userID,
 		Username: claims[userClaim].(string),
-		// TODO(labkode) if we can get groups from the claim we need to give the possibility
-		// to the admin to choose what claim provides the groups.
 		// TODO(labkode) ... use all claims from oidc?
 		// TODO(labkode): do like K8s does it: https://github.com/kubernetes/kubernetes/blob/master/staging/src/k8s.io/apiserver/plugin/pkg/authenticator/token/oidc/oidc.go
-		Groups:       getGroupsResp.Groups,
+		Groups:       groups,
 		Mail:         claims["email"].(string),
 		MailVerified: claims["email_verified"].(bool),
 		DisplayName:  claims["name"].(string),
@@ -212,6 +225,201 @@ func (am *mgr) Authenticate(ctx context.Context, clientID, clientSecret string)
 	return u, scopes, nil
 }
 
+// getClaims validates the token and returns its claims.
+// JWTs are verified against the keys of their trusted issuer, opaque tokens are
+// validated through introspection if configured. The userinfo endpoint of the issuer
+// is then queried, with the claims obtained from the token as a fallback.
+func (am *mgr) getClaims(ctx context.Context, token string) (map[string]interface{}, error) {
+	claims := map[string]interface{}{}
+	issuers := am.c.trustedIssuers()
+	validated := false
+
+	if iss, ok := getUnverifiedIssuer(token); ok {
+		if !am.c.isTrustedIssuer(iss) {
+			return nil, fmt.Errorf("oidc: token issued by untrusted issuer %q", iss)
+		}
+		issuers = []string{iss}
+
+		provider, err := am.getOIDCProvider(ctx, iss)
+		if err != nil {
+			return nil, fmt.Errorf("error creating oidc provider: +%v", err)
+		}
+		verifier := provider.Verifier(&oidc.Config{SkipClientIDCheck: true})
+		if idToken, err := verifier.Verify(ctx, token); err == nil {
+			if err := idToken.Claims(&claims); err != nil {
+				return nil, fmt.Errorf("oidc: error unmarshaling token claims: %v", err)
+			}
+			validated = true
+		} else {
+			log.Debug().Err(err).Str("issuer", iss).Msg("oidc: error verifying jwt, falling back to userinfo")
+		}
+	} else if endpoint := am.getIntrospectionEndpoint(ctx); endpoint != "" {
+		ic, err := am.introspect(ctx, endpoint, token)
+		if err != nil {
+			return nil, err
+		}
+		if iss, ok := ic["iss"].(string); ok {
+			if !am.c.isTrustedIssuer(iss) {
+				return nil, fmt.Errorf("oidc: token issued by untrusted issuer %q", iss)
+			}
+			issuers = []string{iss}
+		}
+		claims = ic
+		validated = true
+	}
+
+	// Userinfo responses carry no audience, so only validated tokens can pass an audience restriction
+	if len(am.c.Audiences) > 0 {
+		if !validated {
+			return nil, errors.New("oidc: token audience cannot be verified")
+		}
+		if !am.isAudienceAllowed(claims["aud"]) {
+			return nil, fmt.Errorf("oidc: token audience %v not allowed", claims["aud"])
+		}
+	}
+
+	var userInfoErr error
+	for _, iss := range issuers {
+		uc, err := am.getUserInfoClaims(ctx, iss, token)
+		if err != nil {
+			userInfoErr = err
+			continue
+		}
+		if uc["issuer"] == nil {
+			uc["issuer"] = iss
+		}
+		for k, v := range uc {
+			claims[k] = v
+		}
+		log.Debug().Interface("claims", claims).Msg("unmarshalled userinfo")
+		return claims, nil
+	}
+
+	if !validated {
+		if userInfoErr == nil {
+			userInfoErr = errors.New("no trusted issuer configured")
+		}
+		return nil, fmt.Errorf("oidc: error getting userinfo: +%v", userInfoErr)
+	}
+
+	log.Debug().Err(userInfoErr).Msg("oidc: error getting userinfo, using token claims")
+	if claims["issuer"] == nil {
+		claims["issuer"] = claims["iss"]
+	}
+	return claims, nil
+}
+
+func (am *mgr) getUserInfoClaims(ctx context.Context, issuer, token string) (map[string]interface{}, error) {
+	provider, err := am.getOIDCProvider(ctx, issuer)
+	if err != nil {
+		return nil, fmt.Errorf("error creating oidc provider: +%v", err)
+	}
+
+	oauth2Token := &oauth2.Token{
+		AccessToken: token,
+	}
+	userInfo, err := provider.UserInfo(ctx, oauth2.StaticTokenSource(oauth2Token))
+	if err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := userInfo.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("oidc: error unmarshaling userinfo claims: %v", err)
+	}
+	return claims, nil
+}
+
+// isAudienceAllowed checks the aud claim, either a string or a list of strings,
+// against the configured audiences.
+func (am *mgr) isAudienceAllowed(aud interface{}) bool {
+	if len(am.c.Audiences) == 0 {
+		return true
+	}
+
+	var auds []string
+	switch t := aud.(type) {
+	case string:
+		auds = []string{t}
+	case []interface{}:
+		for _, a := range t {
+			if s, ok := a.(string); ok {
+				auds = append(auds, s)
+			}
+		}
+	}
+
+	for _, a := range auds {
+		for _, allowed := range am.c.Audiences {
+			if a == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (am *mgr) getGroups(ctx context.Context, userID *user.UserId, claims map[string]interface{}) ([]string, error) {
+	if am.c.GroupsClaim != "" {
+		return getGroupsFromClaim(claims[am.c.GroupsClaim]), nil
+	}
+
+	gwc, err := pool.GetGatewayServiceClient(am.c.GatewaySvc)
+	if err != nil {
+		return nil, errors.Wrap(err, "oidc: error getting gateway grpc client")
+	}
+	getGroupsResp, err := gwc.GetUserGroups(ctx, &user.GetUserGroupsRequest{
+		UserId: userID,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "oidc: error getting user groups")
+	}
+	if getGroupsResp.Status.Code != rpc.Code_CODE_OK {
+		return nil, errors.New("oidc: grpc getting user groups failed: " + getGroupsResp.Status.Message)
+	}
+	return getGroupsResp.Groups, nil
+}
+
+// getGroupsFromClaim accepts either a list of strings or a comma separated string.
+func getGroupsFromClaim(v interface{}) []string {
+	groups := []string{}
+	switch t := v.(type) {
+	case []interface{}:
+		for _, g := range t {
+			if s, ok := g.(string); ok && s != "" {
+				groups = append(groups, s)
+			}
+		}
+	case string:
+		for _, g := range strings.Split(t, ",") {
+			if g = strings.TrimSpace(g); g != "" {
+				groups = append(groups, g)
+			}
+		}
+	}
+	return groups
+}
+
+// getUnverifiedIssuer returns the iss claim of the token if it is a JWT.
+// The signature is verified later on against the keys of the issuer.
+func getUnverifiedIssuer(token string) (string, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", false
+	}
+	var claims struct {
+		Issuer string `json:"iss"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Issuer == "" {
+		return "", false
+	}
+	return claims.Issuer, true
+}
+
 func (am *mgr) getOAuthCtx(ctx context.Context) context.Context {
 	// Sometimes for testing we need to skip the TLS check, that's why we need a
 	// custom HTTP client.
@@ -226,22 +434,25 @@ func (am *mgr) getOAuthCtx(ctx context.Context) context.Context {
 	return ctx
 }
 
-func (am *mgr) getOIDCProvider(ctx context.Context) (*oidc.Provider, error) {
-	if am.provider != nil {
-		return am.provider, nil
+func (am *mgr) getOIDCProvider(ctx context.Context, issuer string) (*oidc.Provider, error) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if provider, ok := am.providers[issuer]; ok {
+		return provider, nil
 	}
 
 	// Initialize a provider by specifying the issuer URL.
 	// Once initialized is a singleton that is reused if further requests.
 	// The provider is responsible to verify the token sent by the client
 	// against the security keys oftentimes available in the .well-known endpoint.
-	provider, err := oidc.NewProvider(ctx, am.c.Issuer)
+	provider, err := oidc.NewProvider(ctx, issuer)
 	if err != nil {
 		return nil, fmt.Errorf("error creating a new oidc provider: %+v", err)
 	}
 
-	am.provider = provider
-	return am.provider, nil
+	am.providers[issuer] = provider
+	return provider, nil
 }
 
 func getUserType(upn string) user.UserType {
@@ -0,0 +1,256 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+const opaqueToken = "opaque-access-token"
+
+// mockProvider is a minimal OIDC provider serving discovery, keys, userinfo and introspection.
+type mockProvider struct {
+	*httptest.Server
+	key          *rsa.PrivateKey
+	userInfo     bool
+	introspected map[string]interface{}
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &mockProvider{key: key, userInfo: true}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"issuer":                 p.URL,
+			"jwks_uri":               p.URL + "/keys",
+			"userinfo_endpoint":      p.URL + "/userinfo",
+			"introspection_endpoint": p.URL + "/introspect",
+			"authorization_endpoint": p.URL + "/auth",
+			"token_endpoint":         p.URL + "/token",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"keys": []map[string]interface{}{{
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"kid": "test",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if !p.userInfo {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"sub":                "einstein-id",
+			"preferred_username": "einstein",
+			"email":              "einstein@example.org",
+			"name":               "Albert Einstein",
+			"groups":             []string{"physics", "sailing"},
+		})
+	})
+	mux.HandleFunc("/introspect", func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, ok := r.BasicAuth(); !ok || id != "reva" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.FormValue("token") != opaqueToken {
+			writeJSON(w, map[string]interface{}{"active": false})
+			return
+		}
+		writeJSON(w, p.introspected)
+	})
+	p.Server = httptest.NewServer(mux)
+	p.introspected = map[string]interface{}{
+		"active": true,
+		"iss":    p.URL,
+		"sub":    "einstein-id",
+		"aud":    "reva",
+	}
+	return p
+}
+
+func (p *mockProvider) sign(t *testing.T, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test"
+	s, err := token.SignedString(p.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestAuthenticateWithJWT(t *testing.T) {
+	p := newMockProvider(t)
+	defer p.Close()
+
+	manager, err := New(map[string]interface{}{
+		"issuer":       p.URL,
+		"audiences":    []string{"reva"},
+		"groups_claim": "groups",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := p.sign(t, jwt.MapClaims{
+		"iss":                p.URL,
+		"sub":                "einstein-id",
+		"aud":                "reva",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"email":              "einstein@example.org",
+		"preferred_username": "einstein",
+		"name":               "Albert Einstein",
+		"groups":             "physics",
+	})
+
+	u, _, err := manager.Authenticate(context.Background(), "", token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "einstein-id", u.Id.OpaqueId)
+	assert.Equal(t, p.URL, u.Id.Idp)
+	assert.Equal(t, []string{"physics", "sailing"}, u.Groups)
+
+	// the claims of the token are used if the userinfo endpoint is not available
+	p.userInfo = false
+	u, _, err = manager.Authenticate(context.Background(), "", token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "einstein", u.Username)
+	assert.Equal(t, []string{"physics"}, u.Groups)
+
+	wrongAudience := p.sign(t, jwt.MapClaims{
+		"iss": p.URL,
+		"sub": "einstein-id",
+		"aud": "other",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	_, _, err = manager.Authenticate(context.Background(), "", wrongAudience)
+	assert.Error(t, err)
+
+	untrusted := p.sign(t, jwt.MapClaims{
+		"iss": "https://untrusted.example.org",
+		"sub": "einstein-id",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	_, _, err = manager.Authenticate(context.Background(), "", untrusted)
+	assert.Error(t, err)
+}
+
+func TestAuthenticateWithIntrospection(t *testing.T) {
+	p := newMockProvider(t)
+	defer p.Close()
+
+	manager, err := New(map[string]interface{}{
+		"issuers":                     []string{"https://other.example.org", p.URL},
+		"audiences":                   []string{"reva"},
+		"groups_claim":                "groups",
+		"introspection_endpoint":      p.URL + "/introspect",
+		"introspection_client_id":     "reva",
+		"introspection_client_secret": "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, _, err := manager.Authenticate(context.Background(), "", opaqueToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "einstein", u.Username)
+	assert.Equal(t, p.URL, u.Id.Idp)
+	assert.Equal(t, "einstein@example.org", u.Mail)
+
+	_, _, err = manager.Authenticate(context.Background(), "", "inactive-token")
+	assert.Error(t, err)
+
+	p.introspected["aud"] = []string{"other"}
+	_, _, err = manager.Authenticate(context.Background(), "", opaqueToken)
+	assert.Error(t, err)
+}
+
+func TestAuthenticateRequiresVerifiableAudience(t *testing.T) {
+	p := newMockProvider(t)
+	defer p.Close()
+
+	manager, err := New(map[string]interface{}{
+		"issuer":    p.URL,
+		"audiences": []string{"reva"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// opaque tokens can only be checked through the userinfo endpoint without introspection
+	_, _, err = manager.Authenticate(context.Background(), "", opaqueToken)
+	assert.Error(t, err)
+
+	// tokens failing verification must not fall back to the userinfo endpoint
+	expired := p.sign(t, jwt.MapClaims{
+		"iss": p.URL,
+		"sub": "einstein-id",
+		"aud": "reva",
+		"exp": time.Now().Add(-time.Hour).Unix(),
+	})
+	_, _, err = manager.Authenticate(context.Background(), "", expired)
+	assert.Error(t, err)
+
+	// without an audience restriction, the userinfo endpoint is still used
+	manager, err = New(map[string]interface{}{
+		"issuer":       p.URL,
+		"groups_claim": "groups",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _, err := manager.Authenticate(context.Background(), "", opaqueToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "einstein", u.Username)
+}
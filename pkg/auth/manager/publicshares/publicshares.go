@@ -32,6 +32,7 @@ import (
 	"github.com/cs3org/reva/pkg/auth"
 	"github.com/cs3org/reva/pkg/auth/manager/registry"
 	"github.com/cs3org/reva/pkg/auth/scope"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/mitchellh/mapstructure"
@@ -43,11 +44,24 @@ func init() {
 }
 
 type manager struct {
-	c *config
+	c         *config
+	throttler *throttler
 }
 
 type config struct {
-	GatewayAddr string `mapstructure:"gateway_addr"`
+	GatewayAddr          string `mapstructure:"gateway_addr"`
+	MaxFailedAttempts    int    `mapstructure:"max_failed_attempts" docs:"0;The number of failed password attempts of a client IP, on a share and overall, after which further attempts of the client are rejected. Requests without a client IP, see trusted_proxies of the http auth middleware and the grpc server, are not throttled. 0 disables the protection."`
+	FailedAttemptsWindow int    `mapstructure:"failed_attempts_window" docs:"600;The time window in seconds in which failed attempts are counted."`
+	LockoutDuration      int    `mapstructure:"lockout_duration" docs:"900;The time in seconds for which further attempts are rejected."`
+}
+
+func (c *config) init() {
+	if c.FailedAttemptsWindow == 0 {
+		c.FailedAttemptsWindow = 600
+	}
+	if c.LockoutDuration == 0 {
+		c.LockoutDuration = 900
+	}
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -74,7 +88,11 @@ func (m *manager) Configure(ml map[string]interface{}) error {
 	if err != nil {
 		return err
 	}
+	conf.init()
 	m.c = conf
+	m.throttler = newThrottler(conf.MaxFailedAttempts,
+		time.Duration(conf.FailedAttemptsWindow)*time.Second,
+		time.Duration(conf.LockoutDuration)*time.Second)
 	return nil
 }
 
@@ -85,7 +103,13 @@ func (m *manager) Authenticate(ctx context.Context, token, secret string) (*user
 	}
 
 	var auth *link.PublicShareAuthentication
+	var throttleKeys []string
 	if strings.HasPrefix(secret, "password|") {
+		throttleKeys = clientThrottleKeys(ctx, token)
+		if m.throttler.locked(throttleKeys...) {
+			return nil, nil, errtypes.PermissionDenied("too many failed attempts")
+		}
+
 		secret = strings.TrimPrefix(secret, "password|")
 		auth = &link.PublicShareAuthentication{
 			Spec: &link.PublicShareAuthentication_Password{
@@ -122,10 +146,16 @@ func (m *manager) Authenticate(ctx context.Context, token, secret string) (*user
 	case publicShareResponse.Status.Code == rpcv1beta1.Code_CODE_NOT_FOUND:
 		return nil, nil, errtypes.NotFound(publicShareResponse.Status.Message)
 	case publicShareResponse.Status.Code == rpcv1beta1.Code_CODE_PERMISSION_DENIED:
+		m.throttler.fail(throttleKeys...)
 		return nil, nil, errtypes.InvalidCredentials(publicShareResponse.Status.Message)
 	case publicShareResponse.Status.Code != rpcv1beta1.Code_CODE_OK:
 		return nil, nil, errtypes.InternalError(publicShareResponse.Status.Message)
 	}
+	// only the attempts of the client on the share are forgotten, otherwise a
+	// client could alternate between guessing and a share it knows the password of
+	if len(throttleKeys) > 0 {
+		m.throttler.reset(throttleKeys[0])
+	}
 
 	getUserResponse, err := gwConn.GetUser(ctx, &userprovider.GetUserRequest{
 		UserId: publicShareResponse.GetShare().GetCreator(),
@@ -161,3 +191,15 @@ func (m *manager) Authenticate(ctx context.Context, token, secret string) (*user
 
 // ErrPasswordNotProvided is returned when the public share is password protected, but there was no password on the request
 var ErrPasswordNotProvided = errors.New("public share is password protected, but password was not provided")
+
+// clientThrottleKeys returns the keys the failed password attempts on a share
+// are counted under: the client on the share and the client overall. Keying
+// them on the share alone would let anyone lock the visitors of a share out,
+// so requests without a client IP aren't throttled.
+func clientThrottleKeys(ctx context.Context, token string) []string {
+	ip, ok := ctxpkg.ContextGetClientIP(ctx)
+	if !ok {
+		return nil
+	}
+	return []string{"share:" + ip + "|" + token, "ip:" + ip}
+}
@@ -0,0 +1,116 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package publicshares
+
+import (
+	"sync"
+	"time"
+)
+
+// throttler keeps track of failed authentication attempts and locks keys,
+// e.g. a client IP on a share or a client IP, out once too many attempts
+// failed within a time window.
+type throttler struct {
+	mu          sync.Mutex
+	maxAttempts int
+	window      time.Duration
+	lockout     time.Duration
+	attempts    map[string]*attempts
+	now         func() time.Time
+}
+
+type attempts struct {
+	first       time.Time
+	count       int
+	lockedUntil time.Time
+}
+
+func newThrottler(maxAttempts int, window, lockout time.Duration) *throttler {
+	return &throttler{
+		maxAttempts: maxAttempts,
+		window:      window,
+		lockout:     lockout,
+		attempts:    map[string]*attempts{},
+		now:         time.Now,
+	}
+}
+
+// locked reports whether any of the given keys is currently locked out.
+func (t *throttler) locked(keys ...string) bool {
+	if t.maxAttempts <= 0 {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	for _, k := range keys {
+		if a, ok := t.attempts[k]; ok && now.Before(a.lockedUntil) {
+			return true
+		}
+	}
+	return false
+}
+
+// fail records a failed attempt for each of the given keys.
+func (t *throttler) fail(keys ...string) {
+	if t.maxAttempts <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.gc(now)
+	for _, k := range keys {
+		a, ok := t.attempts[k]
+		if !ok || now.Sub(a.first) > t.window {
+			a = &attempts{first: now}
+			t.attempts[k] = a
+		}
+		a.count++
+		if a.count >= t.maxAttempts {
+			a.lockedUntil = now.Add(t.lockout)
+			a.first = now
+			a.count = 0
+		}
+	}
+}
+
+// reset forgets the failed attempts of the given keys.
+func (t *throttler) reset(keys ...string) {
+	if t.maxAttempts <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, k := range keys {
+		delete(t.attempts, k)
+	}
+}
+
+// gc drops the entries which are neither locked nor within the window anymore.
+func (t *throttler) gc(now time.Time) {
+	for k, a := range t.attempts {
+		if now.Sub(a.first) > t.window && !now.Before(a.lockedUntil) {
+			delete(t.attempts, k)
+		}
+	}
+}
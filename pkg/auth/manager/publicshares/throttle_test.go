@@ -0,0 +1,101 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package publicshares
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+)
+
+func TestThrottler(t *testing.T) {
+	now := time.Date(2021, time.October, 1, 12, 0, 0, 0, time.UTC)
+	th := newThrottler(3, 10*time.Minute, 15*time.Minute)
+	th.now = func() time.Time { return now }
+
+	th.fail("token:a", "ip:1")
+	th.fail("token:a", "ip:1")
+	if th.locked("token:a") || th.locked("ip:1") {
+		t.Fatal("locked out before reaching the maximum number of attempts")
+	}
+
+	th.fail("token:b", "ip:1")
+	if !th.locked("ip:1") {
+		t.Fatal("ip not locked out after the maximum number of attempts")
+	}
+	if th.locked("token:a") || th.locked("token:b") {
+		t.Fatal("tokens locked out before reaching the maximum number of attempts")
+	}
+	if !th.locked("token:a", "ip:1") {
+		t.Fatal("expected the request to be rejected because of the locked ip")
+	}
+
+	now = now.Add(16 * time.Minute)
+	if th.locked("ip:1") {
+		t.Fatal("ip still locked out after the lockout duration")
+	}
+
+	// attempts outside of the window are not counted
+	th.fail("token:c")
+	th.fail("token:c")
+	now = now.Add(11 * time.Minute)
+	th.fail("token:c")
+	if th.locked("token:c") {
+		t.Fatal("attempts outside of the window were counted")
+	}
+
+	th.fail("token:c")
+	th.reset("token:c")
+	th.fail("token:c")
+	if th.locked("token:c") {
+		t.Fatal("attempts were not reset")
+	}
+}
+
+func TestThrottlerDisabled(t *testing.T) {
+	th := newThrottler(0, time.Minute, time.Minute)
+	for i := 0; i < 10; i++ {
+		th.fail("token:a")
+	}
+	if th.locked("token:a") {
+		t.Fatal("disabled throttler locked out")
+	}
+}
+
+func TestClientThrottleKeys(t *testing.T) {
+	th := newThrottler(3, 10*time.Minute, 15*time.Minute)
+	attacker := clientThrottleKeys(ctxpkg.ContextSetClientIP(context.Background(), "1.2.3.4"), "token")
+	visitor := clientThrottleKeys(ctxpkg.ContextSetClientIP(context.Background(), "5.6.7.8"), "token")
+
+	for i := 0; i < 3; i++ {
+		th.fail(attacker...)
+	}
+	if !th.locked(attacker...) {
+		t.Fatal("attacker not locked out")
+	}
+	if th.locked(visitor...) {
+		t.Fatal("other clients of the share locked out")
+	}
+
+	if keys := clientThrottleKeys(context.Background(), "token"); len(keys) != 0 {
+		t.Fatalf("expected no keys without a client ip, got %v", keys)
+	}
+}
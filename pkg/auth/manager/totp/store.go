@@ -0,0 +1,64 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package totp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// Store keeps the TOTP secrets of the enrolled users.
+type Store interface {
+	GetSecret(username string) (string, bool, error)
+}
+
+type jsonStore struct {
+	secrets map[string]string
+}
+
+// NewJSONStore returns a Store reading the secrets from a JSON file mapping
+// usernames to base32 encoded secrets. The file is provisioned by the
+// administrators, e.g. with the secret from `head -c 20 /dev/urandom | base32`.
+func NewJSONStore(file string) (Store, error) {
+	s := &jsonStore{
+		secrets: map[string]string{},
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, errors.Wrap(err, "totp: error reading secrets file")
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &s.secrets); err != nil {
+			return nil, errors.Wrap(err, "totp: error decoding secrets file")
+		}
+	}
+	return s, nil
+}
+
+func (s *jsonStore) GetSecret(username string) (string, bool, error) {
+	secret, ok := s.secrets[username]
+	return secret, ok, nil
+}
@@ -0,0 +1,220 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package totp wraps an auth manager requiring a time-based one-time password
+// (RFC 6238) as second factor for the users that enrolled one.
+package totp
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/auth"
+	"github.com/cs3org/reva/pkg/auth/manager/registry"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("totp", New)
+}
+
+type config struct {
+	AuthManager  string                            `mapstructure:"auth_manager" docs:"json;The auth manager verifying the password of the users."`
+	AuthManagers map[string]map[string]interface{} `mapstructure:"auth_managers" docs:"url:pkg/auth/manager/json/json.go;The configuration of the auth managers."`
+	Secrets      string                            `mapstructure:"secrets" docs:"/etc/revad/totp.json;The JSON file mapping usernames to their base32 encoded TOTP secret, provisioned by the administrators."`
+	Digits       int                               `mapstructure:"digits" docs:"6;The number of digits of the one-time passwords."`
+	Period       int                               `mapstructure:"period" docs:"30;The validity in seconds of a one-time password."`
+	Skew         int                               `mapstructure:"skew" docs:"1;The number of periods before and after the current one that are accepted to compensate clock drifts, 0 only accepts the current one."`
+	Enforce      bool                              `mapstructure:"enforce" docs:"false;Whether users without an enrolled secret are rejected."`
+	AppPasswords bool                              `mapstructure:"app_passwords" docs:"false;Whether app passwords are accepted without second factor."`
+	GatewaySvc   string                            `mapstructure:"gatewaysvc" docs:";The endpoint at which the GRPC gateway is exposed, used to verify app passwords."`
+}
+
+func (c *config) init() {
+	if c.AuthManager == "" {
+		c.AuthManager = "json"
+	}
+	if c.Secrets == "" {
+		c.Secrets = "/etc/revad/totp.json"
+	}
+	if c.Digits == 0 {
+		c.Digits = 6
+	}
+	if c.Period == 0 {
+		c.Period = 30
+	}
+	if c.Skew < 0 {
+		c.Skew = 1
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+}
+
+func parseConfig(m map[string]interface{}) (*config, error) {
+	// a negative skew marks it as unset, so that 0 can be configured
+	c := &config{Skew: -1}
+	if err := mapstructure.Decode(m, c); err != nil {
+		err = errors.Wrap(err, "error decoding conf")
+		return nil, err
+	}
+	return c, nil
+}
+
+type manager struct {
+	c       *config
+	store   Store
+	auth    auth.Manager
+	appAuth auth.Manager
+
+	// lastSteps holds the last time step used by each user to prevent replays
+	lastSteps map[string]uint64
+	mu        sync.Mutex
+}
+
+// New returns an auth manager requiring a TOTP code appended to the password
+// of the users that enrolled a secret.
+func New(m map[string]interface{}) (auth.Manager, error) {
+	mgr := &manager{}
+	err := mgr.Configure(m)
+	if err != nil {
+		return nil, err
+	}
+	return mgr, nil
+}
+
+func (m *manager) Configure(ml map[string]interface{}) error {
+	c, err := parseConfig(ml)
+	if err != nil {
+		return err
+	}
+	c.init()
+
+	if c.AuthManager == "totp" {
+		return errors.New("totp: cannot wrap itself")
+	}
+	f, ok := registry.NewFuncs[c.AuthManager]
+	if !ok {
+		return errtypes.NotFound("totp: auth manager not found: " + c.AuthManager)
+	}
+	authManager, err := f(c.AuthManagers[c.AuthManager])
+	if err != nil {
+		return err
+	}
+
+	var appAuth auth.Manager
+	if c.AppPasswords {
+		f, ok := registry.NewFuncs["appauth"]
+		if !ok {
+			return errtypes.NotFound("totp: auth manager not found: appauth")
+		}
+		appAuth, err = f(map[string]interface{}{"gateway_addr": c.GatewaySvc})
+		if err != nil {
+			return err
+		}
+	}
+
+	store, err := NewJSONStore(c.Secrets)
+	if err != nil {
+		return err
+	}
+
+	m.c = c
+	m.store = store
+	m.auth = authManager
+	m.appAuth = appAuth
+	m.lastSteps = map[string]uint64{}
+	return nil
+}
+
+// Authenticate expects the one-time password to be appended to the password
+// of the users that enrolled a secret, unless it was sent separately.
+func (m *manager) Authenticate(ctx context.Context, username, secret string) (*user.User, map[string]*authpb.Scope, error) {
+	otpSecret, enrolled, err := m.store.GetSecret(username)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !enrolled {
+		if !m.c.Enforce {
+			return m.auth.Authenticate(ctx, username, secret)
+		}
+		return m.authenticateAppPassword(ctx, username, secret, errtypes.PermissionDenied("totp: user not enrolled"))
+	}
+
+	key, err := decodeSecret(otpSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	password, code, ok := m.splitSecret(ctx, secret)
+	if !ok {
+		return m.authenticateAppPassword(ctx, username, secret, errtypes.InvalidCredentials("totp: one-time password missing"))
+	}
+
+	step, ok := verifyCode(key, code, time.Now(), m.c.Digits, m.c.Period, m.c.Skew)
+	if !ok {
+		return m.authenticateAppPassword(ctx, username, secret, errtypes.InvalidCredentials("totp: invalid one-time password"))
+	}
+
+	u, scopes, err := m.auth.Authenticate(ctx, username, password)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !m.useStep(username, step) {
+		return nil, nil, errtypes.InvalidCredentials("totp: one-time password already used")
+	}
+	return u, scopes, nil
+}
+
+// splitSecret returns the password and the one-time password of the secret.
+// App passwords are always checked against the secret as it was sent.
+func (m *manager) splitSecret(ctx context.Context, secret string) (string, string, bool) {
+	if otp, ok := ctxpkg.ContextGetOTP(ctx); ok {
+		return secret, otp, true
+	}
+	if len(secret) <= m.c.Digits {
+		return "", "", false
+	}
+	return secret[:len(secret)-m.c.Digits], secret[len(secret)-m.c.Digits:], true
+}
+
+// authenticateAppPassword falls back to app passwords, which are exempted from the second factor.
+func (m *manager) authenticateAppPassword(ctx context.Context, username, secret string, err error) (*user.User, map[string]*authpb.Scope, error) {
+	if m.appAuth == nil {
+		return nil, nil, err
+	}
+	return m.appAuth.Authenticate(ctx, username, secret)
+}
+
+// useStep records the time step used by the user, failing if it has already been used.
+func (m *manager) useStep(username string, step uint64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if last, ok := m.lastSteps[username]; ok && step <= last {
+		return false
+	}
+	m.lastSteps[username] = step
+	return true
+}
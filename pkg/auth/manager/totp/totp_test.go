@@ -0,0 +1,167 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package totp
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/cs3org/reva/pkg/auth/manager/json"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateCode(t *testing.T) {
+	// test vectors from RFC 6238, appendix B
+	key := []byte("12345678901234567890")
+	tests := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+	for ts, code := range tests {
+		assert.Equal(t, code, generateCode(key, uint64(ts/30), 8))
+
+		step, ok := verifyCode(key, code, time.Unix(ts, 0), 8, 30, 1)
+		assert.True(t, ok)
+		assert.Equal(t, uint64(ts/30), step)
+	}
+
+	_, ok := verifyCode(key, "94287082", time.Unix(59+90, 0), 8, 30, 1)
+	assert.False(t, ok)
+}
+
+func TestAuthenticate(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "totp_test")
+	if err != nil {
+		t.Fatalf("Error while creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	users := filepath.Join(tmpDir, "users.json")
+	err = ioutil.WriteFile(users, []byte(`[{"username":"einstein","secret":"relativity"},{"username":"marie","secret":"radioactivity"}]`), 0600)
+	if err != nil {
+		t.Fatalf("Error while writing users file: %v", err)
+	}
+
+	secret := "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+	secrets := filepath.Join(tmpDir, "totp.json")
+	err = ioutil.WriteFile(secrets, []byte(`{"einstein":"`+secret+`"}`), 0600)
+	if err != nil {
+		t.Fatalf("Error while writing secrets file: %v", err)
+	}
+
+	manager, err := New(map[string]interface{}{
+		"secrets":       secrets,
+		"auth_managers": map[string]interface{}{"json": map[string]interface{}{"users": users}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := decodeSecret(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := generateCode(key, uint64(time.Now().Unix())/30, 6)
+
+	// users without enrolled secret only need their password
+	_, _, err = manager.Authenticate(context.Background(), "marie", "radioactivity")
+	assert.NoError(t, err)
+
+	_, _, err = manager.Authenticate(context.Background(), "einstein", "relativity")
+	assert.Error(t, err)
+
+	_, _, err = manager.Authenticate(context.Background(), "einstein", "wrong"+code)
+	assert.Error(t, err)
+
+	u, _, err := manager.Authenticate(context.Background(), "einstein", "relativity"+code)
+	assert.NoError(t, err)
+	assert.Equal(t, "einstein", u.Username)
+
+	// one-time passwords cannot be replayed
+	_, _, err = manager.Authenticate(context.Background(), "einstein", "relativity"+code)
+	assert.Error(t, err)
+}
+
+func TestAuthenticateSeparateOTP(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "totp_test")
+	if err != nil {
+		t.Fatalf("Error while creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	users := filepath.Join(tmpDir, "users.json")
+	err = ioutil.WriteFile(users, []byte(`[{"username":"einstein","secret":"relativity"}]`), 0600)
+	if err != nil {
+		t.Fatalf("Error while writing users file: %v", err)
+	}
+
+	secret := "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+	secrets := filepath.Join(tmpDir, "totp.json")
+	err = ioutil.WriteFile(secrets, []byte(`{"einstein":"`+secret+`"}`), 0600)
+	if err != nil {
+		t.Fatalf("Error while writing secrets file: %v", err)
+	}
+
+	manager, err := New(map[string]interface{}{
+		"secrets":       secrets,
+		"auth_managers": map[string]interface{}{"json": map[string]interface{}{"users": users}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := decodeSecret(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := generateCode(key, uint64(time.Now().Unix())/30, 6)
+
+	// the password is not combined with a one-time password sent separately
+	_, _, err = manager.Authenticate(ctxpkg.ContextSetOTP(context.Background(), code), "einstein", "relativity"+code)
+	assert.Error(t, err)
+
+	u, _, err := manager.Authenticate(ctxpkg.ContextSetOTP(context.Background(), code), "einstein", "relativity")
+	assert.NoError(t, err)
+	assert.Equal(t, "einstein", u.Username)
+}
+
+func TestSkew(t *testing.T) {
+	c, err := parseConfig(map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.init()
+	assert.Equal(t, 1, c.Skew)
+
+	c, err = parseConfig(map[string]interface{}{"skew": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.init()
+	assert.Equal(t, 0, c.Skew)
+}
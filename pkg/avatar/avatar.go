@@ -0,0 +1,41 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package avatar
+
+import (
+	"context"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+)
+
+// Avatar is the picture of a user.
+type Avatar struct {
+	MimeType string
+	Data     []byte
+}
+
+// Manager defines an interface for storing the avatars uploaded by users.
+type Manager interface {
+	// GetAvatar returns the avatar of a user or a NotFound error if the user has none.
+	GetAvatar(ctx context.Context, userID *user.UserId) (*Avatar, error)
+	// SetAvatar stores the avatar of a user.
+	SetAvatar(ctx context.Context, userID *user.UserId, avatar *Avatar) error
+	// DeleteAvatar removes the avatar of a user.
+	DeleteAvatar(ctx context.Context, userID *user.UserId) error
+}
@@ -0,0 +1,55 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package avatar
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+)
+
+func TestInitials(t *testing.T) {
+	tests := map[string]string{
+		"Albert Einstein":         "AE",
+		"marie":                   "M",
+		"Johann Carl Fried Gauss": "JG",
+		"richard.feynman":         "RF",
+		"":                        "?",
+		"Ørsted":                  "?",
+	}
+	for name, expected := range tests {
+		if got := Initials(name); got != expected {
+			t.Errorf("Initials(%q) = %q, want %q", name, got, expected)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	data, err := Generate("einstein", "Albert Einstein", 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 64 || b.Dy() != 64 {
+		t.Fatalf("expected 64x64 got %dx%d", b.Dx(), b.Dy())
+	}
+}
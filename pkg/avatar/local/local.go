@@ -0,0 +1,111 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package local
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/avatar"
+	"github.com/cs3org/reva/pkg/avatar/registry"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("local", New)
+}
+
+type config struct {
+	Root string `mapstructure:"root" docs:"/var/tmp/reva/avatars;The directory the avatars are stored in."`
+}
+
+func (c *config) init() {
+	if c.Root == "" {
+		c.Root = "/var/tmp/reva/avatars"
+	}
+}
+
+type mgr struct {
+	c *config
+}
+
+// New returns an avatar manager storing the avatars as files in a local directory.
+func New(m map[string]interface{}) (avatar.Manager, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+	c.init()
+
+	if err := os.MkdirAll(c.Root, 0700); err != nil {
+		return nil, errors.Wrap(err, "error creating avatar directory")
+	}
+	return &mgr{c: c}, nil
+}
+
+// path returns the file of the avatar of a user. The user id is hashed to
+// keep arbitrary ids from escaping the root directory.
+func (m *mgr) path(userID *user.UserId) string {
+	sum := sha256.Sum256([]byte(userID.Idp + "!" + userID.OpaqueId))
+	return filepath.Join(m.c.Root, hex.EncodeToString(sum[:]))
+}
+
+func (m *mgr) GetAvatar(_ context.Context, userID *user.UserId) (*avatar.Avatar, error) {
+	data, err := ioutil.ReadFile(m.path(userID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errtypes.NotFound(userID.OpaqueId)
+		}
+		return nil, errors.Wrap(err, "error reading avatar")
+	}
+	return &avatar.Avatar{MimeType: http.DetectContentType(data), Data: data}, nil
+}
+
+func (m *mgr) SetAvatar(_ context.Context, userID *user.UserId, a *avatar.Avatar) error {
+	// write to a temporary file first so readers never see a partial avatar
+	tmp, err := ioutil.TempFile(m.c.Root, ".upload-")
+	if err != nil {
+		return errors.Wrap(err, "error creating avatar file")
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(a.Data); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "error writing avatar")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "error writing avatar")
+	}
+	return errors.Wrap(os.Rename(tmp.Name(), m.path(userID)), "error storing avatar")
+}
+
+func (m *mgr) DeleteAvatar(_ context.Context, userID *user.UserId) error {
+	if err := os.Remove(m.path(userID)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "error removing avatar")
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package local
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/avatar"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+func TestLocal(t *testing.T) {
+	root, err := ioutil.TempDir("", "reva-avatars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	m, err := New(map[string]interface{}{"root": root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	uid := &user.UserId{Idp: "localhost", OpaqueId: "../einstein"}
+
+	if _, err := m.GetAvatar(ctx, uid); err == nil {
+		t.Fatal("expected not found error")
+	} else if _, ok := err.(errtypes.IsNotFound); !ok {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+
+	png, err := avatar.Generate("einstein", "Albert Einstein", 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetAvatar(ctx, uid, &avatar.Avatar{MimeType: "image/png", Data: png}); err != nil {
+		t.Fatal(err)
+	}
+	a, err := m.GetAvatar(ctx, uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.MimeType != "image/png" || !bytes.Equal(a.Data, png) {
+		t.Fatalf("unexpected avatar %s with %d bytes", a.MimeType, len(a.Data))
+	}
+
+	if err := m.DeleteAvatar(ctx, uid); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.GetAvatar(ctx, uid); err == nil {
+		t.Fatal("expected avatar to be deleted")
+	}
+}
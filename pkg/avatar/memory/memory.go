@@ -0,0 +1,67 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package memory
+
+import (
+	"context"
+	"sync"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/avatar"
+	"github.com/cs3org/reva/pkg/avatar/registry"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+func init() {
+	registry.Register("memory", New)
+}
+
+type mgr struct {
+	sync.RWMutex
+	avatars map[string]*avatar.Avatar
+}
+
+// New returns an instance of the in-memory avatar manager.
+func New(m map[string]interface{}) (avatar.Manager, error) {
+	return &mgr{avatars: make(map[string]*avatar.Avatar)}, nil
+}
+
+func (m *mgr) GetAvatar(_ context.Context, userID *user.UserId) (*avatar.Avatar, error) {
+	m.RLock()
+	defer m.RUnlock()
+	a, ok := m.avatars[userID.OpaqueId]
+	if !ok {
+		return nil, errtypes.NotFound(userID.OpaqueId)
+	}
+	return a, nil
+}
+
+func (m *mgr) SetAvatar(_ context.Context, userID *user.UserId, a *avatar.Avatar) error {
+	m.Lock()
+	defer m.Unlock()
+	m.avatars[userID.OpaqueId] = a
+	return nil
+}
+
+func (m *mgr) DeleteAvatar(_ context.Context, userID *user.UserId) error {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.avatars, userID.OpaqueId)
+	return nil
+}
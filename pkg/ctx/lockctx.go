@@ -0,0 +1,39 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ctx
+
+import (
+	"context"
+)
+
+// LockAppHeader is the header apps send the name they hold locks
+// under with. Locks held by an app can only be changed by requests
+// carrying the same name.
+const LockAppHeader = "x-lock-app"
+
+// ContextGetLockApp returns the name of the app holding locks if set in the given context.
+func ContextGetLockApp(ctx context.Context) (string, bool) {
+	app, ok := ctx.Value(lockAppKey).(string)
+	return app, ok
+}
+
+// ContextSetLockApp stores the name of the app holding locks in the context.
+func ContextSetLockApp(ctx context.Context, app string) context.Context {
+	return context.WithValue(ctx, lockAppKey, app)
+}
@@ -30,6 +30,9 @@ const (
 	userKey key = iota
 	tokenKey
 	idKey
+	clientIPKey
+	lockAppKey
+	otpKey
 )
 
 // ContextGetUser returns the user if set in the given context.
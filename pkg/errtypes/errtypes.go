@@ -122,6 +122,22 @@ func (e InsufficientStorage) IsInsufficientStorage() {}
 // https://developer.mozilla.org/en-US/docs/Web/HTTP/Status/507
 const StatusInssufficientStorage = 507
 
+// TooEarly is the error to use when a resource is not ready yet, e.g. because it is still being processed.
+type TooEarly string
+
+func (e TooEarly) Error() string { return "error: too early: " + string(e) }
+
+// IsTooEarly implements the IsTooEarly interface.
+func (e TooEarly) IsTooEarly() {}
+
+// Locked is the error to use when a resource is locked by someone else.
+type Locked string
+
+func (e Locked) Error() string { return "error: locked by " + string(e) }
+
+// IsLocked implements the IsLocked interface.
+func (e Locked) IsLocked() {}
+
 // IsNotFound is the interface to implement
 // to specify that an a resource is not found.
 type IsNotFound interface {
@@ -187,3 +203,15 @@ type IsChecksumMismatch interface {
 type IsInsufficientStorage interface {
 	IsInsufficientStorage()
 }
+
+// IsTooEarly is the interface to implement
+// to specify that a resource is not ready yet.
+type IsTooEarly interface {
+	IsTooEarly()
+}
+
+// IsLocked is the interface to implement
+// to specify that a resource is locked.
+type IsLocked interface {
+	IsLocked()
+}
@@ -33,3 +33,17 @@ type Manager interface {
 	GetMembers(ctx context.Context, gid *grouppb.GroupId) ([]*userpb.UserId, error)
 	HasMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) (bool, error)
 }
+
+// ManageableManager is implemented by group managers that can provision
+// groups and their members.
+type ManageableManager interface {
+	Manager
+	// CreateGroup creates a new group. A new id is generated if the group has none.
+	CreateGroup(ctx context.Context, g *grouppb.Group) (*grouppb.Group, error)
+	// DeleteGroup removes a group.
+	DeleteGroup(ctx context.Context, gid *grouppb.GroupId) error
+	// AddMember adds a user to a group.
+	AddMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) error
+	// RemoveMember removes a user from a group.
+	RemoveMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) error
+}
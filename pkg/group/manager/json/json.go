@@ -22,14 +22,19 @@ import (
 	"context"
 	"encoding/json"
 	"io/ioutil"
+	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/group"
 	"github.com/cs3org/reva/pkg/group/manager/registry"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/google/uuid"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
 )
@@ -39,12 +44,17 @@ func init() {
 }
 
 type manager struct {
+	c      *config
+	mu     sync.Mutex
 	groups []*grouppb.Group
+	mtime  time.Time
 }
 
 type config struct {
 	// Groups holds a path to a file containing json conforming to the Groups struct
 	Groups string `mapstructure:"groups"`
+	// Idp is the identity provider of the groups created by the manager
+	Idp string `mapstructure:"idp" docs:";The identity provider of the groups created through the manager."`
 }
 
 func (c *config) init() {
@@ -70,25 +80,56 @@ func New(m map[string]interface{}) (group.Manager, error) {
 		return nil, err
 	}
 
-	f, err := ioutil.ReadFile(c.Groups)
-	if err != nil {
+	mgr := &manager{c: c}
+	if err := mgr.load(); err != nil {
 		return nil, err
 	}
+	return mgr, nil
+}
+
+// load reads the groups file if it changed since it was read last. It has to
+// be called with the lock held.
+func (m *manager) load() error {
+	fd, err := os.Open(m.c.Groups)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	info, err := fd.Stat()
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(m.mtime) {
+		return nil
+	}
+
+	f, err := ioutil.ReadAll(fd)
+	if err != nil {
+		return err
+	}
 
 	groups := []*grouppb.Group{}
 
 	err = json.Unmarshal(f, &groups)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	m.groups = groups
+	m.mtime = info.ModTime()
+	return nil
+}
 
-	return &manager{
-		groups: groups,
-	}, nil
+// list returns the current groups, reloading the groups file if it changed.
+func (m *manager) list() []*grouppb.Group {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// keep serving the groups read before if the file can't be read
+	_ = m.load()
+	return m.groups
 }
 
 func (m *manager) GetGroup(ctx context.Context, gid *grouppb.GroupId) (*grouppb.Group, error) {
-	for _, g := range m.groups {
+	for _, g := range m.list() {
 		if g.Id.GetOpaqueId() == gid.OpaqueId || g.GroupName == gid.OpaqueId {
 			return g, nil
 		}
@@ -97,7 +138,7 @@ func (m *manager) GetGroup(ctx context.Context, gid *grouppb.GroupId) (*grouppb.
 }
 
 func (m *manager) GetGroupByClaim(ctx context.Context, claim, value string) (*grouppb.Group, error) {
-	for _, g := range m.groups {
+	for _, g := range m.list() {
 		if groupClaim, err := extractClaim(g, claim); err == nil && value == groupClaim {
 			return g, nil
 		}
@@ -121,7 +162,7 @@ func extractClaim(g *grouppb.Group, claim string) (string, error) {
 
 func (m *manager) FindGroups(ctx context.Context, query string) ([]*grouppb.Group, error) {
 	groups := []*grouppb.Group{}
-	for _, g := range m.groups {
+	for _, g := range m.list() {
 		if groupContains(g, query) {
 			groups = append(groups, g)
 		}
@@ -136,7 +177,7 @@ func groupContains(g *grouppb.Group, query string) bool {
 }
 
 func (m *manager) GetMembers(ctx context.Context, gid *grouppb.GroupId) ([]*userpb.UserId, error) {
-	for _, g := range m.groups {
+	for _, g := range m.list() {
 		if g.Id.GetOpaqueId() == gid.OpaqueId || g.GroupName == gid.OpaqueId {
 			return g.Members, nil
 		}
@@ -157,3 +198,126 @@ func (m *manager) HasMember(ctx context.Context, gid *grouppb.GroupId, uid *user
 	}
 	return false, nil
 }
+
+func find(groups []*grouppb.Group, gid *grouppb.GroupId) int {
+	for i, g := range groups {
+		if g.Id.GetOpaqueId() == gid.OpaqueId || g.GroupName == gid.OpaqueId {
+			return i
+		}
+	}
+	return -1
+}
+
+// update runs f on a copy of the stored groups and saves them if f succeeds.
+// The stored groups are never modified in place as they are handed out to the callers.
+func (m *manager) update(f func(groups []*grouppb.Group) ([]*grouppb.Group, error)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.load(); err != nil {
+		return err
+	}
+	groups, err := f(append([]*grouppb.Group{}, m.groups...))
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(groups, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "json: error encoding groups")
+	}
+	tmp := m.c.Groups + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return errors.Wrap(err, "json: error writing groups file")
+	}
+	if err := os.Rename(tmp, m.c.Groups); err != nil {
+		return errors.Wrap(err, "json: error writing groups file")
+	}
+	if info, err := os.Stat(m.c.Groups); err == nil {
+		m.mtime = info.ModTime()
+	}
+	m.groups = groups
+	return nil
+}
+
+// withMembers returns a copy of g with the given members.
+func withMembers(g *grouppb.Group, members []*userpb.UserId) *grouppb.Group {
+	return &grouppb.Group{
+		Id:          g.Id,
+		GroupName:   g.GroupName,
+		Mail:        g.Mail,
+		DisplayName: g.DisplayName,
+		GidNumber:   g.GidNumber,
+		Members:     members,
+		Opaque:      g.Opaque,
+	}
+}
+
+func (m *manager) CreateGroup(ctx context.Context, g *grouppb.Group) (*grouppb.Group, error) {
+	if g.GroupName == "" {
+		return nil, errtypes.BadRequest("json: missing group name")
+	}
+	n := withMembers(g, g.Members)
+	if n.Id == nil || n.Id.OpaqueId == "" {
+		n.Id = &grouppb.GroupId{Idp: m.c.Idp, OpaqueId: uuid.New().String()}
+	}
+	err := m.update(func(groups []*grouppb.Group) ([]*grouppb.Group, error) {
+		for _, e := range groups {
+			if e.GroupName == n.GroupName || e.Id.GetOpaqueId() == n.Id.OpaqueId {
+				return nil, errtypes.AlreadyExists(n.GroupName)
+			}
+		}
+		return append(groups, n), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (m *manager) DeleteGroup(ctx context.Context, gid *grouppb.GroupId) error {
+	return m.update(func(groups []*grouppb.Group) ([]*grouppb.Group, error) {
+		i := find(groups, gid)
+		if i < 0 {
+			return nil, errtypes.NotFound(gid.OpaqueId)
+		}
+		return append(groups[:i], groups[i+1:]...), nil
+	})
+}
+
+func (m *manager) AddMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) error {
+	return m.update(func(groups []*grouppb.Group) ([]*grouppb.Group, error) {
+		i := find(groups, gid)
+		if i < 0 {
+			return nil, errtypes.NotFound(gid.OpaqueId)
+		}
+		for _, u := range groups[i].Members {
+			if utils.UserEqual(u, uid) {
+				return nil, errtypes.AlreadyExists(uid.OpaqueId)
+			}
+		}
+		members := append(append([]*userpb.UserId{}, groups[i].Members...), uid)
+		groups[i] = withMembers(groups[i], members)
+		return groups, nil
+	})
+}
+
+func (m *manager) RemoveMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) error {
+	return m.update(func(groups []*grouppb.Group) ([]*grouppb.Group, error) {
+		i := find(groups, gid)
+		if i < 0 {
+			return nil, errtypes.NotFound(gid.OpaqueId)
+		}
+		members := []*userpb.UserId{}
+		for _, u := range groups[i].Members {
+			if !utils.UserEqual(u, uid) {
+				members = append(members, u)
+			}
+		}
+		if len(members) == len(groups[i].Members) {
+			return nil, errtypes.NotFound(uid.OpaqueId)
+		}
+		groups[i] = withMembers(groups[i], members)
+		return groups, nil
+	})
+}
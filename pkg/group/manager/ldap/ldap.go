@@ -25,8 +25,10 @@ import (
 	"strconv"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/Masterminds/sprig"
+	"github.com/ReneKroon/ttlcache/v2"
 	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
@@ -45,8 +47,12 @@ func init() {
 
 type manager struct {
 	c            *config
+	ldap         utils.LDAPSearcher
 	groupfilter  *template.Template
 	memberfilter *template.Template
+	userfilter   *template.Template
+	groupCache   *ttlcache.Cache
+	membersCache *ttlcache.Cache
 }
 
 type config struct {
@@ -59,6 +65,16 @@ type config struct {
 	Idp             string     `mapstructure:"idp"`
 	Schema          attributes `mapstructure:"schema"`
 	Nobody          int64      `mapstructure:"nobody"`
+	// CacheTTL is the number of seconds groups and their members are cached, 0 disables caching
+	CacheTTL int `mapstructure:"cache_ttl"`
+	// WriteBaseDN is the DN new groups are created under, groups can't be provisioned if it is empty
+	WriteBaseDN string `mapstructure:"write_base_dn"`
+	// GroupObjectClasses are the object classes of new groups
+	GroupObjectClasses []string `mapstructure:"group_object_classes"`
+	// UserFilter finds the entry of a user to add to or remove from a group, e.g. `(&(objectclass=posixAccount)(cn={{.OpaqueId}}))`
+	UserFilter string `mapstructure:"userfilter"`
+	// EmptyMember is stored as the member of groups without members if the object class of the groups requires one, e.g. `groupOfNames`
+	EmptyMember string `mapstructure:"empty_member"`
 }
 
 type attributes struct {
@@ -74,6 +90,8 @@ type attributes struct {
 	DisplayName string `mapstructure:"displayName"`
 	// GIDNumber is a numeric id that maps to a filesystem gid, eg. 654321
 	GIDNumber string `mapstructure:"gidNumber"`
+	// Member holds the DNs of the members of a group, e.g. `member` or `uniqueMember`
+	Member string `mapstructure:"member"`
 }
 
 // Default attributes (Active Directory)
@@ -84,6 +102,7 @@ var ldapDefaults = attributes{
 	Mail:        "mail",
 	DisplayName: "displayName",
 	GIDNumber:   "gidNumber",
+	Member:      "member",
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -110,9 +129,17 @@ func New(m map[string]interface{}) (group.Manager, error) {
 		c.FindFilter = c.GroupFilter
 	}
 	c.MemberFilter = strings.ReplaceAll(c.MemberFilter, "%s", "{{.OpaqueId}}")
+	if len(c.GroupObjectClasses) == 0 {
+		c.GroupObjectClasses = []string{"top", "groupOfNames"}
+	}
 
 	mgr := &manager{
-		c: c,
+		c:    c,
+		ldap: utils.NewLDAPClient(&c.LDAPConn),
+	}
+	if c.CacheTTL > 0 {
+		mgr.groupCache = newCache(c.CacheTTL)
+		mgr.membersCache = newCache(c.CacheTTL)
 	}
 
 	mgr.groupfilter, err = template.New("gf").Funcs(sprig.TxtFuncMap()).Parse(c.GroupFilter)
@@ -125,17 +152,29 @@ func New(m map[string]interface{}) (group.Manager, error) {
 		err := errors.Wrap(err, fmt.Sprintf("error parsing memberfilter tpl:%s", c.MemberFilter))
 		panic(err)
 	}
+	mgr.userfilter, err = template.New("userf").Funcs(sprig.TxtFuncMap()).Parse(c.UserFilter)
+	if err != nil {
+		err := errors.Wrap(err, fmt.Sprintf("error parsing userfilter tpl:%s", c.UserFilter))
+		panic(err)
+	}
 
 	return mgr, nil
 }
 
+func newCache(ttl int) *ttlcache.Cache {
+	cache := ttlcache.NewCache()
+	_ = cache.SetTTL(time.Duration(ttl) * time.Second)
+	cache.SkipTTLExtensionOnHit(true)
+	return cache
+}
+
 func (m *manager) GetGroup(ctx context.Context, gid *grouppb.GroupId) (*grouppb.Group, error) {
-	log := appctx.GetLogger(ctx)
-	l, err := utils.GetLDAPConnection(&m.c.LDAPConn)
-	if err != nil {
-		return nil, err
+	key := "gid:" + gid.OpaqueId
+	if g, ok := m.getCachedGroup(key); ok {
+		return g, nil
 	}
-	defer l.Close()
+
+	log := appctx.GetLogger(ctx)
 
 	// Search for the given clientID
 	searchRequest := ldap.NewSearchRequest(
@@ -146,7 +185,7 @@ func (m *manager) GetGroup(ctx context.Context, gid *grouppb.GroupId) (*grouppb.
 		nil,
 	)
 
-	sr, err := l.Search(searchRequest)
+	sr, err := m.ldap.Search(searchRequest)
 	if err != nil {
 		return nil, err
 	}
@@ -183,6 +222,7 @@ func (m *manager) GetGroup(ctx context.Context, gid *grouppb.GroupId) (*grouppb.
 		GidNumber:   gidNumber,
 	}
 
+	m.setCachedGroup(key, g)
 	return g, nil
 }
 
@@ -201,12 +241,12 @@ func (m *manager) GetGroupByClaim(ctx context.Context, claim, value string) (*gr
 		return nil, errors.New("ldap: invalid field " + claim)
 	}
 
-	log := appctx.GetLogger(ctx)
-	l, err := utils.GetLDAPConnection(&m.c.LDAPConn)
-	if err != nil {
-		return nil, err
+	key := "claim:" + claim + ":" + value
+	if g, ok := m.getCachedGroup(key); ok {
+		return g, nil
 	}
-	defer l.Close()
+
+	log := appctx.GetLogger(ctx)
 
 	// Search for the given clientID
 	searchRequest := ldap.NewSearchRequest(
@@ -217,7 +257,7 @@ func (m *manager) GetGroupByClaim(ctx context.Context, claim, value string) (*gr
 		nil,
 	)
 
-	sr, err := l.Search(searchRequest)
+	sr, err := m.ldap.Search(searchRequest)
 	if err != nil {
 		return nil, err
 	}
@@ -250,16 +290,11 @@ func (m *manager) GetGroupByClaim(ctx context.Context, claim, value string) (*gr
 		GidNumber:   gidNumber,
 	}
 
+	m.setCachedGroup(key, g)
 	return g, nil
 }
 
 func (m *manager) FindGroups(ctx context.Context, query string) ([]*grouppb.Group, error) {
-	l, err := utils.GetLDAPConnection(&m.c.LDAPConn)
-	if err != nil {
-		return nil, err
-	}
-	defer l.Close()
-
 	// Search for the given clientID
 	searchRequest := ldap.NewSearchRequest(
 		m.c.BaseDN,
@@ -269,7 +304,7 @@ func (m *manager) FindGroups(ctx context.Context, query string) ([]*grouppb.Grou
 		nil,
 	)
 
-	sr, err := l.Search(searchRequest)
+	sr, err := m.ldap.Search(searchRequest)
 	if err != nil {
 		return nil, err
 	}
@@ -300,11 +335,11 @@ func (m *manager) FindGroups(ctx context.Context, query string) ([]*grouppb.Grou
 }
 
 func (m *manager) GetMembers(ctx context.Context, gid *grouppb.GroupId) ([]*userpb.UserId, error) {
-	l, err := utils.GetLDAPConnection(&m.c.LDAPConn)
-	if err != nil {
-		return nil, err
+	if m.membersCache != nil {
+		if v, err := m.membersCache.Get(gid.OpaqueId); err == nil {
+			return v.([]*userpb.UserId), nil
+		}
 	}
-	defer l.Close()
 
 	// Search for the given clientID
 	searchRequest := ldap.NewSearchRequest(
@@ -315,7 +350,7 @@ func (m *manager) GetMembers(ctx context.Context, gid *grouppb.GroupId) ([]*user
 		nil,
 	)
 
-	sr, err := l.Search(searchRequest)
+	sr, err := m.ldap.Search(searchRequest)
 	if err != nil {
 		return nil, err
 	}
@@ -330,6 +365,9 @@ func (m *manager) GetMembers(ctx context.Context, gid *grouppb.GroupId) ([]*user
 		})
 	}
 
+	if m.membersCache != nil {
+		_ = m.membersCache.Set(gid.OpaqueId, users)
+	}
 	return users, nil
 }
 
@@ -347,6 +385,23 @@ func (m *manager) HasMember(ctx context.Context, gid *grouppb.GroupId, uid *user
 	return false, nil
 }
 
+func (m *manager) getCachedGroup(key string) (*grouppb.Group, bool) {
+	if m.groupCache == nil {
+		return nil, false
+	}
+	v, err := m.groupCache.Get(key)
+	if err != nil {
+		return nil, false
+	}
+	return v.(*grouppb.Group), true
+}
+
+func (m *manager) setCachedGroup(key string, g *grouppb.Group) {
+	if m.groupCache != nil {
+		_ = m.groupCache.Set(key, g)
+	}
+}
+
 func (m *manager) getGroupFilter(gid *grouppb.GroupId) string {
 	b := bytes.Buffer{}
 	if err := m.groupfilter.Execute(&b, gid); err != nil {
@@ -0,0 +1,240 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ldap
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+)
+
+func (m *manager) getModifier() (utils.LDAPModifier, error) {
+	w, ok := m.ldap.(utils.LDAPModifier)
+	if !ok || m.c.WriteBaseDN == "" {
+		return nil, errtypes.NotSupported("ldap: provisioning groups is not configured")
+	}
+	return w, nil
+}
+
+// getGroupEntry returns the entry of a group with its members.
+func (m *manager) getGroupEntry(gid *grouppb.GroupId) (*ldap.Entry, error) {
+	searchRequest := ldap.NewSearchRequest(
+		m.c.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		m.getGroupFilter(gid),
+		[]string{m.c.Schema.DN, m.c.Schema.Member},
+		nil,
+	)
+
+	sr, err := m.ldap.Search(searchRequest)
+	if err != nil {
+		return nil, err
+	}
+	if len(sr.Entries) != 1 {
+		return nil, errtypes.NotFound(gid.OpaqueId)
+	}
+	return sr.Entries[0], nil
+}
+
+func (m *manager) getUserDN(uid *userpb.UserId) (string, error) {
+	if m.c.UserFilter == "" {
+		return "", errtypes.NotSupported("ldap: no userfilter configured to look up members")
+	}
+	b := bytes.Buffer{}
+	if err := m.userfilter.Execute(&b, uid); err != nil {
+		return "", errors.Wrap(err, fmt.Sprintf("error executing user template: userid:%+v", uid))
+	}
+
+	searchRequest := ldap.NewSearchRequest(
+		m.c.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		b.String(),
+		[]string{m.c.Schema.DN},
+		nil,
+	)
+
+	sr, err := m.ldap.Search(searchRequest)
+	if err != nil {
+		return "", err
+	}
+	if len(sr.Entries) != 1 {
+		return "", errtypes.NotFound(uid.OpaqueId)
+	}
+	return sr.Entries[0].DN, nil
+}
+
+// invalidate drops all cached groups, a changed group may be cached under several keys.
+func (m *manager) invalidate() {
+	if m.groupCache != nil {
+		_ = m.groupCache.Purge()
+	}
+	if m.membersCache != nil {
+		_ = m.membersCache.Purge()
+	}
+}
+
+func (m *manager) CreateGroup(ctx context.Context, g *grouppb.Group) (*grouppb.Group, error) {
+	w, err := m.getModifier()
+	if err != nil {
+		return nil, err
+	}
+	if g.GroupName == "" {
+		return nil, errtypes.BadRequest("ldap: missing group name")
+	}
+	if _, err := m.getGroupEntry(&grouppb.GroupId{OpaqueId: g.GroupName}); err == nil {
+		return nil, errtypes.AlreadyExists(g.GroupName)
+	}
+
+	members := []string{}
+	for _, uid := range g.Members {
+		dn, err := m.getUserDN(uid)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, dn)
+	}
+	if len(members) == 0 && m.c.EmptyMember != "" {
+		members = append(members, m.c.EmptyMember)
+	}
+
+	dn := m.c.Schema.CN + "=" + utils.EscapeLDAPDNValue(g.GroupName) + "," + m.c.WriteBaseDN
+	req := ldap.NewAddRequest(dn, nil)
+	req.Attribute("objectClass", m.c.GroupObjectClasses)
+	req.Attribute(m.c.Schema.CN, []string{g.GroupName})
+	if len(members) > 0 {
+		req.Attribute(m.c.Schema.Member, members)
+	}
+	if g.Mail != "" {
+		req.Attribute(m.c.Schema.Mail, []string{g.Mail})
+	}
+	if g.DisplayName != "" {
+		req.Attribute(m.c.Schema.DisplayName, []string{g.DisplayName})
+	}
+	if g.GidNumber != 0 {
+		req.Attribute(m.c.Schema.GIDNumber, []string{strconv.FormatInt(g.GidNumber, 10)})
+	}
+
+	if err := w.Add(req); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultEntryAlreadyExists) {
+			return nil, errtypes.AlreadyExists(g.GroupName)
+		}
+		return nil, errors.Wrap(err, "ldap: error creating group "+g.GroupName)
+	}
+	m.invalidate()
+
+	// read the group back to get the ids assigned by the server
+	return m.GetGroupByClaim(ctx, "group_name", g.GroupName)
+}
+
+func (m *manager) DeleteGroup(ctx context.Context, gid *grouppb.GroupId) error {
+	w, err := m.getModifier()
+	if err != nil {
+		return err
+	}
+	entry, err := m.getGroupEntry(gid)
+	if err != nil {
+		return err
+	}
+
+	if err := w.Del(ldap.NewDelRequest(entry.DN, nil)); err != nil {
+		return errors.Wrap(err, "ldap: error deleting group "+gid.OpaqueId)
+	}
+	m.invalidate()
+	return nil
+}
+
+func (m *manager) AddMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) error {
+	w, err := m.getModifier()
+	if err != nil {
+		return err
+	}
+	entry, err := m.getGroupEntry(gid)
+	if err != nil {
+		return err
+	}
+	dn, err := m.getUserDN(uid)
+	if err != nil {
+		return err
+	}
+
+	req := ldap.NewModifyRequest(entry.DN, nil)
+	for _, member := range entry.GetEqualFoldAttributeValues(m.c.Schema.Member) {
+		if strings.EqualFold(member, dn) {
+			return errtypes.AlreadyExists(uid.OpaqueId)
+		}
+		if m.c.EmptyMember != "" && strings.EqualFold(member, m.c.EmptyMember) {
+			req.Delete(m.c.Schema.Member, []string{member})
+		}
+	}
+	req.Add(m.c.Schema.Member, []string{dn})
+
+	if err := w.Modify(req); err != nil {
+		return errors.Wrap(err, "ldap: error adding member to group "+gid.OpaqueId)
+	}
+	m.invalidate()
+	return nil
+}
+
+func (m *manager) RemoveMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) error {
+	w, err := m.getModifier()
+	if err != nil {
+		return err
+	}
+	entry, err := m.getGroupEntry(gid)
+	if err != nil {
+		return err
+	}
+	dn, err := m.getUserDN(uid)
+	if err != nil {
+		return err
+	}
+
+	members := entry.GetEqualFoldAttributeValues(m.c.Schema.Member)
+	var found string
+	for _, member := range members {
+		if strings.EqualFold(member, dn) {
+			found = member
+		}
+	}
+	if found == "" {
+		return errtypes.NotFound(uid.OpaqueId)
+	}
+
+	req := ldap.NewModifyRequest(entry.DN, nil)
+	// a group may have to keep a member
+	if len(members) == 1 && m.c.EmptyMember != "" {
+		req.Add(m.c.Schema.Member, []string{m.c.EmptyMember})
+	}
+	req.Delete(m.c.Schema.Member, []string{found})
+
+	if err := w.Modify(req); err != nil {
+		return errors.Wrap(err, "ldap: error removing member from group "+gid.OpaqueId)
+	}
+	m.invalidate()
+	return nil
+}
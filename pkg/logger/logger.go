@@ -21,6 +21,7 @@ package logger
 import (
 	"io"
 	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -62,6 +63,36 @@ func WithLevel(lvl string) Option {
 	}
 }
 
+// Level is a logging level that can be changed while the logger is in use.
+type Level struct {
+	lvl int32
+}
+
+// NewLevel returns a Level initialized to the given level.
+func NewLevel(lvl string) *Level {
+	l := &Level{}
+	l.Set(lvl)
+	return l
+}
+
+// Set changes the logging level.
+func (l *Level) Set(lvl string) {
+	atomic.StoreInt32(&l.lvl, int32(parseLevel(lvl)))
+}
+
+// Sample implements the zerolog.Sampler interface rejecting the events below the current level.
+// Samplers are consulted before an event is built, so disabled events cost next to nothing.
+func (l *Level) Sample(lvl zerolog.Level) bool {
+	return lvl >= zerolog.Level(atomic.LoadInt32(&l.lvl))
+}
+
+// WithDynamicLevel is an option to configure a logging level that can be changed at runtime.
+func WithDynamicLevel(lvl *Level) Option {
+	return func(l *zerolog.Logger) {
+		*l = l.Level(zerolog.TraceLevel).Sample(lvl)
+	}
+}
+
 // WithWriter is an option to configure the logging output.
 func WithWriter(w io.Writer, m Mode) Option {
 	return func(l *zerolog.Logger) {
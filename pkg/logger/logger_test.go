@@ -0,0 +1,49 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package logger
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDynamicLevel(t *testing.T) {
+	var buf bytes.Buffer
+	lvl := NewLevel("info")
+	log := New(WithWriter(&buf, JSONMode), WithDynamicLevel(lvl))
+
+	log.Debug().Msg("hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("debug event written at info level: %s", buf.String())
+	}
+
+	lvl.Set("debug")
+	sub := log.With().Str("k", "v").Logger()
+	sub.Debug().Msg("shown")
+	if !bytes.Contains(buf.Bytes(), []byte("shown")) {
+		t.Fatal("debug event not written after lowering the level")
+	}
+
+	buf.Reset()
+	lvl.Set("error")
+	sub.Warn().Msg("hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("warn event written at error level: %s", buf.String())
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package json
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/notification"
+	"github.com/cs3org/reva/pkg/notification/manager/registry"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("json", New)
+}
+
+// the services sharing a file in the same process, e.g. ocs and ocmd,
+// have to share its lock as well
+var (
+	locksMu sync.Mutex
+	locks   = map[string]*sync.Mutex{}
+)
+
+func fileLock(file string) *sync.Mutex {
+	locksMu.Lock()
+	defer locksMu.Unlock()
+	if _, ok := locks[file]; !ok {
+		locks[file] = &sync.Mutex{}
+	}
+	return locks[file]
+}
+
+type config struct {
+	File string `mapstructure:"file" docs:"/var/tmp/reva/notifications.json;The file the notifications are stored in."`
+	// KeyRetention is the number of days the keys of deleted notifications are remembered.
+	KeyRetention int `mapstructure:"key_retention" docs:"30;The number of days the keys of deleted notifications are remembered to avoid generating them again."`
+}
+
+func (c *config) init() {
+	if c.File == "" {
+		c.File = "/var/tmp/reva/notifications.json"
+	}
+	if c.KeyRetention == 0 {
+		c.KeyRetention = 30
+	}
+}
+
+type mgr struct {
+	c     *config
+	mutex *sync.Mutex
+}
+
+type model struct {
+	LastID        int64                                 `json:"last_id"`
+	Notifications map[string]*notification.Notification `json:"notifications"`
+	// Keys maps the keys of all notifications sent to a user to the time they were sent
+	Keys map[string]time.Time `json:"keys"`
+}
+
+// New returns a notification manager persisting the notifications in a json file.
+func New(m map[string]interface{}) (notification.Manager, error) {
+	c := &config{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "error decoding conf")
+	}
+	c.init()
+
+	if err := os.MkdirAll(filepath.Dir(c.File), 0700); err != nil {
+		return nil, errors.Wrap(err, "error creating the notifications directory")
+	}
+	return &mgr{c: c, mutex: fileLock(c.File)}, nil
+}
+
+func (m *mgr) read() (*model, error) {
+	db := &model{}
+	data, err := ioutil.ReadFile(m.c.File)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, errors.Wrap(err, "error reading the notifications file")
+	case len(data) > 0:
+		if err := json.Unmarshal(data, db); err != nil {
+			return nil, errors.Wrap(err, "error decoding the notifications file")
+		}
+	}
+	if db.Notifications == nil {
+		db.Notifications = map[string]*notification.Notification{}
+	}
+	if db.Keys == nil {
+		db.Keys = map[string]time.Time{}
+	}
+	return db, nil
+}
+
+func (m *mgr) write(db *model) error {
+	// forget old keys, their notifications can't be generated again
+	limit := time.Now().AddDate(0, 0, -m.c.KeyRetention)
+	for k, t := range db.Keys {
+		if t.Before(limit) {
+			delete(db.Keys, k)
+		}
+	}
+
+	data, err := json.Marshal(db)
+	if err != nil {
+		return errors.Wrap(err, "error encoding the notifications")
+	}
+	tmp := m.c.File + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return errors.Wrap(err, "error writing the notifications file")
+	}
+	return errors.Wrap(os.Rename(tmp, m.c.File), "error writing the notifications file")
+}
+
+// update runs f on the stored notifications and saves them if f succeeds.
+func (m *mgr) update(f func(db *model) error) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	db, err := m.read()
+	if err != nil {
+		return err
+	}
+	if err := f(db); err != nil {
+		return err
+	}
+	return m.write(db)
+}
+
+func userKey(u *userpb.UserId, key string) string {
+	return u.Idp + "!" + u.OpaqueId + "!" + key
+}
+
+func (m *mgr) Notify(ctx context.Context, n *notification.Notification) error {
+	return m.update(func(db *model) error {
+		if n.Key != "" {
+			k := userKey(n.User, n.Key)
+			if _, ok := db.Keys[k]; ok {
+				return errtypes.AlreadyExists(n.Key)
+			}
+			db.Keys[k] = n.Datetime
+		}
+		db.LastID++
+		n.ID = db.LastID
+		db.Notifications[strconv.FormatInt(n.ID, 10)] = n
+		return nil
+	})
+}
+
+func (m *mgr) ListNotifications(ctx context.Context, user *userpb.UserId) ([]*notification.Notification, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	db, err := m.read()
+	if err != nil {
+		return nil, err
+	}
+	list := []*notification.Notification{}
+	for _, n := range db.Notifications {
+		if utils.UserEqual(n.User, user) {
+			list = append(list, n)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID > list[j].ID })
+	return list, nil
+}
+
+func get(db *model, user *userpb.UserId, id int64) (*notification.Notification, error) {
+	n, ok := db.Notifications[strconv.FormatInt(id, 10)]
+	if !ok || !utils.UserEqual(n.User, user) {
+		return nil, errtypes.NotFound(strconv.FormatInt(id, 10))
+	}
+	return n, nil
+}
+
+func (m *mgr) GetNotification(ctx context.Context, user *userpb.UserId, id int64) (*notification.Notification, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	db, err := m.read()
+	if err != nil {
+		return nil, err
+	}
+	return get(db, user, id)
+}
+
+func (m *mgr) MarkAsRead(ctx context.Context, user *userpb.UserId, id int64) error {
+	return m.update(func(db *model) error {
+		n, err := get(db, user, id)
+		if err != nil {
+			return err
+		}
+		n.Read = true
+		return nil
+	})
+}
+
+func (m *mgr) DeleteNotification(ctx context.Context, user *userpb.UserId, id int64) error {
+	return m.update(func(db *model) error {
+		if _, err := get(db, user, id); err != nil {
+			return err
+		}
+		delete(db.Notifications, strconv.FormatInt(id, 10))
+		return nil
+	})
+}
+
+func (m *mgr) DeleteNotifications(ctx context.Context, user *userpb.UserId) error {
+	return m.update(func(db *model) error {
+		for id, n := range db.Notifications {
+			if utils.UserEqual(n.User, user) {
+				delete(db.Notifications, id)
+			}
+		}
+		return nil
+	})
+}
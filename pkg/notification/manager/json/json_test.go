@@ -0,0 +1,109 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package json
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/notification"
+)
+
+func TestManager(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reva-notifications")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	file := filepath.Join(dir, "notifications.json")
+	m, err := New(map[string]interface{}{"file": file})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	einstein := &userpb.UserId{Idp: "localhost", OpaqueId: "einstein"}
+	marie := &userpb.UserId{Idp: "localhost", OpaqueId: "marie"}
+	newNotification := func(u *userpb.UserId, key string) *notification.Notification {
+		return &notification.Notification{User: u, Key: key, App: "files_sharing", Subject: "subject", Datetime: time.Now()}
+	}
+
+	for _, n := range []*notification.Notification{
+		newNotification(einstein, "share:1"),
+		newNotification(einstein, "share:2"),
+		newNotification(marie, "share:1"),
+	} {
+		if err := m.Notify(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Notify(ctx, newNotification(einstein, "share:1")); err == nil {
+		t.Fatal("expected an error for a duplicate key")
+	} else if _, ok := err.(errtypes.IsAlreadyExists); !ok {
+		t.Fatalf("expected already exists error, got %v", err)
+	}
+
+	// a second manager on the same file sees the same notifications
+	m2, err := New(map[string]interface{}{"file": file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := m2.ListNotifications(ctx, einstein)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].ID != 2 || list[1].ID != 1 {
+		t.Fatalf("expected notifications 2 and 1, got %v", list)
+	}
+
+	if err := m.MarkAsRead(ctx, einstein, 1); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := m.GetNotification(ctx, einstein, 1); err != nil || !n.Read {
+		t.Fatalf("expected notification to be read, got %v %v", n, err)
+	}
+	if _, err := m.GetNotification(ctx, marie, 1); err == nil {
+		t.Fatal("expected notifications of other users to be hidden")
+	}
+
+	if err := m.DeleteNotification(ctx, einstein, 1); err != nil {
+		t.Fatal(err)
+	}
+	// deleted notifications are not generated again
+	if err := m.Notify(ctx, newNotification(einstein, "share:1")); err == nil {
+		t.Fatal("expected an error for the key of a deleted notification")
+	}
+
+	if err := m.DeleteNotifications(ctx, einstein); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := m.ListNotifications(ctx, einstein); len(list) != 0 {
+		t.Fatalf("expected no notifications, got %v", list)
+	}
+	if list, _ := m.ListNotifications(ctx, marie); len(list) != 1 {
+		t.Fatalf("expected one notification for marie, got %v", list)
+	}
+}
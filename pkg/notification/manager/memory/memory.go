@@ -0,0 +1,145 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package memory
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/notification"
+	"github.com/cs3org/reva/pkg/notification/manager/registry"
+	"github.com/cs3org/reva/pkg/utils"
+)
+
+func init() {
+	registry.Register("memory", New)
+}
+
+type mgr struct {
+	sync.Mutex
+	lastID        int64
+	notifications map[int64]*notification.Notification
+	// keys remembers the keys of all notifications ever sent to a user
+	keys map[string]struct{}
+}
+
+// New returns an in-memory notification manager.
+func New(m map[string]interface{}) (notification.Manager, error) {
+	return &mgr{
+		notifications: map[int64]*notification.Notification{},
+		keys:          map[string]struct{}{},
+	}, nil
+}
+
+func userKey(u *userpb.UserId, key string) string {
+	return u.Idp + "!" + u.OpaqueId + "!" + key
+}
+
+func (m *mgr) Notify(ctx context.Context, n *notification.Notification) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if n.Key != "" {
+		k := userKey(n.User, n.Key)
+		if _, ok := m.keys[k]; ok {
+			return errtypes.AlreadyExists(n.Key)
+		}
+		m.keys[k] = struct{}{}
+	}
+
+	m.lastID++
+	n.ID = m.lastID
+	c := *n
+	m.notifications[n.ID] = &c
+	return nil
+}
+
+func (m *mgr) ListNotifications(ctx context.Context, user *userpb.UserId) ([]*notification.Notification, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	list := []*notification.Notification{}
+	for _, n := range m.notifications {
+		if utils.UserEqual(n.User, user) {
+			c := *n
+			list = append(list, &c)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID > list[j].ID })
+	return list, nil
+}
+
+func (m *mgr) get(user *userpb.UserId, id int64) (*notification.Notification, error) {
+	n, ok := m.notifications[id]
+	if !ok || !utils.UserEqual(n.User, user) {
+		return nil, errtypes.NotFound(strconv.FormatInt(id, 10))
+	}
+	return n, nil
+}
+
+func (m *mgr) GetNotification(ctx context.Context, user *userpb.UserId, id int64) (*notification.Notification, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	n, err := m.get(user, id)
+	if err != nil {
+		return nil, err
+	}
+	c := *n
+	return &c, nil
+}
+
+func (m *mgr) MarkAsRead(ctx context.Context, user *userpb.UserId, id int64) error {
+	m.Lock()
+	defer m.Unlock()
+
+	n, err := m.get(user, id)
+	if err != nil {
+		return err
+	}
+	n.Read = true
+	return nil
+}
+
+func (m *mgr) DeleteNotification(ctx context.Context, user *userpb.UserId, id int64) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, err := m.get(user, id); err != nil {
+		return err
+	}
+	delete(m.notifications, id)
+	return nil
+}
+
+func (m *mgr) DeleteNotifications(ctx context.Context, user *userpb.UserId) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for id, n := range m.notifications {
+		if utils.UserEqual(n.User, user) {
+			delete(m.notifications, id)
+		}
+	}
+	return nil
+}
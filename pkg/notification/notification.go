@@ -0,0 +1,62 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package notification provides in-app notifications for users.
+package notification
+
+import (
+	"context"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+)
+
+// Notification is a message addressed to a single user.
+type Notification struct {
+	ID   int64          `json:"id"`
+	User *userpb.UserId `json:"user"`
+	// Key identifies the event the notification was generated for, e.g. the
+	// expiry of a share. A manager stores at most one notification per user and key.
+	Key        string    `json:"key,omitempty"`
+	App        string    `json:"app"`
+	Subject    string    `json:"subject"`
+	Message    string    `json:"message"`
+	Link       string    `json:"link,omitempty"`
+	ObjectType string    `json:"object_type"`
+	ObjectID   string    `json:"object_id"`
+	Datetime   time.Time `json:"datetime"`
+	Read       bool      `json:"read"`
+}
+
+// Manager stores notifications.
+type Manager interface {
+	// Notify stores a new notification and assigns its ID. If a notification
+	// with the same key has been stored for the user before, even if it has been
+	// deleted since, an AlreadyExists error is returned.
+	Notify(ctx context.Context, n *Notification) error
+	// ListNotifications returns the notifications of a user, newest first.
+	ListNotifications(ctx context.Context, user *userpb.UserId) ([]*Notification, error)
+	// GetNotification returns a notification of a user.
+	GetNotification(ctx context.Context, user *userpb.UserId, id int64) (*Notification, error)
+	// MarkAsRead marks a notification of a user as read.
+	MarkAsRead(ctx context.Context, user *userpb.UserId, id int64) error
+	// DeleteNotification removes a notification of a user.
+	DeleteNotification(ctx context.Context, user *userpb.UserId, id int64) error
+	// DeleteNotifications removes all notifications of a user.
+	DeleteNotifications(ctx context.Context, user *userpb.UserId) error
+}
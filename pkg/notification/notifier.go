@@ -0,0 +1,79 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package notification
+
+import (
+	"context"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/smtpclient"
+)
+
+// Notifier stores notifications and delivers them through the configured channels.
+type Notifier struct {
+	manager Manager
+	smtp    *smtpclient.SMTPCredentials
+}
+
+// NewNotifier returns a notifier storing notifications with the given manager.
+// If smtp is not nil new notifications are also sent by e-mail.
+func NewNotifier(m Manager, smtp *smtpclient.SMTPCredentials) *Notifier {
+	if smtp != nil {
+		smtp = smtpclient.NewSMTPCredentials(smtp)
+	}
+	return &Notifier{manager: m, smtp: smtp}
+}
+
+// Manager returns the manager storing the notifications.
+func (n *Notifier) Manager() Manager {
+	return n.manager
+}
+
+// Notify sends a notification to a user. Notifications that have already been
+// sent for the same key are silently dropped.
+func (n *Notifier) Notify(ctx context.Context, recipient *userpb.User, notification *Notification) error {
+	notification.User = recipient.Id
+	if notification.Datetime.IsZero() {
+		notification.Datetime = time.Now()
+	}
+
+	if err := n.manager.Notify(ctx, notification); err != nil {
+		if _, ok := err.(errtypes.IsAlreadyExists); ok {
+			return nil
+		}
+		return err
+	}
+
+	if n.smtp != nil && recipient.Mail != "" {
+		body := notification.Message
+		if notification.Link != "" {
+			body += "\n\n" + notification.Link
+		}
+		// Send the mail w/o blocking the request
+		go func() {
+			if err := n.smtp.SendMail(recipient.Mail, notification.Subject, body); err != nil {
+				appctx.GetLogger(ctx).Error().Err(err).Str("recipient", recipient.Mail).Msg("error sending notification mail")
+			}
+		}()
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package passwordpolicy checks passwords chosen by users, e.g. for public links,
+// against configurable requirements.
+package passwordpolicy
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+// Policy holds the requirements a password has to fulfill.
+// The zero value accepts any password.
+type Policy struct {
+	MinLength              int      `mapstructure:"min_length" docs:"0;The minimum number of characters."`
+	MinLowerCaseCharacters int      `mapstructure:"min_lowercase_characters" docs:"0;The minimum number of lower case characters."`
+	MinUpperCaseCharacters int      `mapstructure:"min_uppercase_characters" docs:"0;The minimum number of upper case characters."`
+	MinDigits              int      `mapstructure:"min_digits" docs:"0;The minimum number of digits."`
+	MinSpecialCharacters   int      `mapstructure:"min_special_characters" docs:"0;The minimum number of characters which are neither letters nor digits."`
+	BannedPasswords        []string `mapstructure:"banned_passwords" docs:";Passwords which are not allowed, compared case insensitively."`
+	BannedPasswordsFile    string   `mapstructure:"banned_passwords_file" docs:";A file with one banned password per line."`
+
+	banned map[string]struct{}
+}
+
+// New returns the policy described by the given configuration.
+func New(m map[string]interface{}) (*Policy, error) {
+	p := &Policy{}
+	if err := mapstructure.Decode(m, p); err != nil {
+		return nil, errors.Wrap(err, "passwordpolicy: error decoding config")
+	}
+
+	p.banned = map[string]struct{}{}
+	for _, b := range p.BannedPasswords {
+		p.banned[strings.ToLower(b)] = struct{}{}
+	}
+	if p.BannedPasswordsFile != "" {
+		f, err := os.Open(p.BannedPasswordsFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "passwordpolicy: error opening banned passwords file")
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if b := strings.TrimSpace(scanner.Text()); b != "" {
+				p.banned[strings.ToLower(b)] = struct{}{}
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, errors.Wrap(err, "passwordpolicy: error reading banned passwords file")
+		}
+	}
+	return p, nil
+}
+
+// Validate checks the password against the policy. The returned error
+// explains the first requirement which is not met and can be shown to users.
+func (p *Policy) Validate(password string) error {
+	if p == nil {
+		return nil
+	}
+
+	var lower, upper, digits, special int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower++
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsDigit(r):
+			digits++
+		case !unicode.IsLetter(r):
+			special++
+		}
+	}
+
+	switch {
+	case utf8.RuneCountInString(password) < p.MinLength:
+		return errors.Errorf("the password must contain at least %d characters", p.MinLength)
+	case lower < p.MinLowerCaseCharacters:
+		return errors.Errorf("the password must contain at least %d lower case characters", p.MinLowerCaseCharacters)
+	case upper < p.MinUpperCaseCharacters:
+		return errors.Errorf("the password must contain at least %d upper case characters", p.MinUpperCaseCharacters)
+	case digits < p.MinDigits:
+		return errors.Errorf("the password must contain at least %d digits", p.MinDigits)
+	case special < p.MinSpecialCharacters:
+		return errors.Errorf("the password must contain at least %d special characters", p.MinSpecialCharacters)
+	}
+	if _, ok := p.banned[strings.ToLower(password)]; ok {
+		return errors.New("the password is too common")
+	}
+	return nil
+}
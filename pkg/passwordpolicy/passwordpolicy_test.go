@@ -0,0 +1,82 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package passwordpolicy
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	file, err := ioutil.TempFile("", "banned")
+	if err != nil {
+		t.Fatalf("error creating temp file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString("Passw0rd!\n\nletmein\n"); err != nil {
+		t.Fatalf("error writing temp file: %v", err)
+	}
+	file.Close()
+
+	p, err := New(map[string]interface{}{
+		"min_length":               8,
+		"min_lowercase_characters": 2,
+		"min_uppercase_characters": 1,
+		"min_digits":               1,
+		"min_special_characters":   1,
+		"banned_passwords":         []string{"Summer2021!"},
+		"banned_passwords_file":    file.Name(),
+	})
+	if err != nil {
+		t.Fatalf("error creating policy: %v", err)
+	}
+
+	tests := map[string]bool{
+		"":                  false,
+		"Ab1!":              false,
+		"abcdefg1!":         false,
+		"ABCDEFG1!":         false,
+		"Abcdefgh!":         false,
+		"Abcdefgh1":         false,
+		"summer2021!":       false,
+		"passw0rd!":         false,
+		"Correct1Horse!":    true,
+		"Ünïcödé-Pässwört1": true,
+	}
+	for password, valid := range tests {
+		if err := p.Validate(password); (err == nil) != valid {
+			t.Errorf("Validate(%q) = %v, expected valid: %v", password, err, valid)
+		}
+	}
+}
+
+func TestEmptyPolicy(t *testing.T) {
+	var p *Policy
+	if err := p.Validate(""); err != nil {
+		t.Errorf("a nil policy must accept any password, got %v", err)
+	}
+	p, err := New(nil)
+	if err != nil {
+		t.Fatalf("error creating policy: %v", err)
+	}
+	if err := p.Validate("a"); err != nil {
+		t.Errorf("an empty policy must accept any password, got %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package provisioning
+
+import (
+	"context"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/group"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"google.golang.org/grpc"
+)
+
+const groupServiceName = "revad.provisioning.v1beta1.GroupProvisioningAPI"
+
+// GroupServer provisions groups and their members. Only admins may use it.
+type GroupServer struct {
+	m          group.ManageableManager
+	adminGroup string
+}
+
+// RegisterGroupServer serves the group provisioning API on ss. Members of
+// adminGroup may provision groups.
+func RegisterGroupServer(ss *grpc.Server, m group.ManageableManager, adminGroup string) {
+	s := &GroupServer{m: m, adminGroup: adminGroup}
+	ss.RegisterService(&grpc.ServiceDesc{
+		ServiceName: groupServiceName,
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{
+			{
+				MethodName: "CreateGroup",
+				Handler: unaryHandler("/"+groupServiceName+"/CreateGroup", func() interface{} { return &grouppb.Group{} }, func(_ interface{}, ctx context.Context, req interface{}) (interface{}, error) {
+					return s.CreateGroup(ctx, req.(*grouppb.Group))
+				}),
+			},
+			{
+				MethodName: "DeleteGroup",
+				Handler: unaryHandler("/"+groupServiceName+"/DeleteGroup", func() interface{} { return &grouppb.GetGroupRequest{} }, func(_ interface{}, ctx context.Context, req interface{}) (interface{}, error) {
+					return s.DeleteGroup(ctx, req.(*grouppb.GetGroupRequest))
+				}),
+			},
+			{
+				MethodName: "AddMember",
+				Handler: unaryHandler("/"+groupServiceName+"/AddMember", func() interface{} { return &grouppb.HasMemberRequest{} }, func(_ interface{}, ctx context.Context, req interface{}) (interface{}, error) {
+					return s.AddMember(ctx, req.(*grouppb.HasMemberRequest))
+				}),
+			},
+			{
+				MethodName: "RemoveMember",
+				Handler: unaryHandler("/"+groupServiceName+"/RemoveMember", func() interface{} { return &grouppb.HasMemberRequest{} }, func(_ interface{}, ctx context.Context, req interface{}) (interface{}, error) {
+					return s.RemoveMember(ctx, req.(*grouppb.HasMemberRequest))
+				}),
+			},
+		},
+	}, s)
+}
+
+// CreateGroup creates a new group.
+func (s *GroupServer) CreateGroup(ctx context.Context, g *grouppb.Group) (*grouppb.GetGroupResponse, error) {
+	if _, admin := currentUser(ctx, s.adminGroup); !admin {
+		return &grouppb.GetGroupResponse{Status: status.NewPermissionDenied(ctx, nil, "only admins can create groups")}, nil
+	}
+	created, err := s.m.CreateGroup(ctx, g)
+	if err != nil {
+		return &grouppb.GetGroupResponse{Status: statusFromError(ctx, "error creating group", err)}, nil
+	}
+	return &grouppb.GetGroupResponse{Status: status.NewOK(ctx), Group: created}, nil
+}
+
+// DeleteGroup removes a group.
+func (s *GroupServer) DeleteGroup(ctx context.Context, req *grouppb.GetGroupRequest) (*grouppb.GetGroupResponse, error) {
+	if _, admin := currentUser(ctx, s.adminGroup); !admin {
+		return &grouppb.GetGroupResponse{Status: status.NewPermissionDenied(ctx, nil, "only admins can delete groups")}, nil
+	}
+	if err := s.m.DeleteGroup(ctx, req.GroupId); err != nil {
+		return &grouppb.GetGroupResponse{Status: statusFromError(ctx, "error deleting group", err)}, nil
+	}
+	return &grouppb.GetGroupResponse{Status: status.NewOK(ctx)}, nil
+}
+
+// AddMember adds a user to a group.
+func (s *GroupServer) AddMember(ctx context.Context, req *grouppb.HasMemberRequest) (*grouppb.HasMemberResponse, error) {
+	if _, admin := currentUser(ctx, s.adminGroup); !admin {
+		return &grouppb.HasMemberResponse{Status: status.NewPermissionDenied(ctx, nil, "only admins can change group members")}, nil
+	}
+	if err := s.m.AddMember(ctx, req.GroupId, req.UserId); err != nil {
+		return &grouppb.HasMemberResponse{Status: statusFromError(ctx, "error adding member", err)}, nil
+	}
+	return &grouppb.HasMemberResponse{Status: status.NewOK(ctx), Ok: true}, nil
+}
+
+// RemoveMember removes a user from a group.
+func (s *GroupServer) RemoveMember(ctx context.Context, req *grouppb.HasMemberRequest) (*grouppb.HasMemberResponse, error) {
+	if _, admin := currentUser(ctx, s.adminGroup); !admin {
+		return &grouppb.HasMemberResponse{Status: status.NewPermissionDenied(ctx, nil, "only admins can change group members")}, nil
+	}
+	if err := s.m.RemoveMember(ctx, req.GroupId, req.UserId); err != nil {
+		return &grouppb.HasMemberResponse{Status: statusFromError(ctx, "error removing member", err)}, nil
+	}
+	return &grouppb.HasMemberResponse{Status: status.NewOK(ctx)}, nil
+}
+
+// GroupClient provisions groups through the group provider. It returns the
+// errors of the group managers.
+type GroupClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewGroupClient returns a client of the group provisioning API.
+func NewGroupClient(cc *grpc.ClientConn) *GroupClient {
+	return &GroupClient{cc: cc}
+}
+
+// CreateGroup creates a new group.
+func (c *GroupClient) CreateGroup(ctx context.Context, g *grouppb.Group) (*grouppb.Group, error) {
+	res := &grouppb.GetGroupResponse{}
+	err := c.cc.Invoke(ctx, "/"+groupServiceName+"/CreateGroup", g, res)
+	if err = errorFromStatus(res.Status, err); err != nil {
+		return nil, err
+	}
+	return res.Group, nil
+}
+
+// DeleteGroup removes a group.
+func (c *GroupClient) DeleteGroup(ctx context.Context, gid *grouppb.GroupId) error {
+	res := &grouppb.GetGroupResponse{}
+	err := c.cc.Invoke(ctx, "/"+groupServiceName+"/DeleteGroup", &grouppb.GetGroupRequest{GroupId: gid}, res)
+	return errorFromStatus(res.Status, err)
+}
+
+// AddMember adds a user to a group.
+func (c *GroupClient) AddMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) error {
+	res := &grouppb.HasMemberResponse{}
+	err := c.cc.Invoke(ctx, "/"+groupServiceName+"/AddMember", &grouppb.HasMemberRequest{GroupId: gid, UserId: uid}, res)
+	return errorFromStatus(res.Status, err)
+}
+
+// RemoveMember removes a user from a group.
+func (c *GroupClient) RemoveMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) error {
+	res := &grouppb.HasMemberResponse{}
+	err := c.cc.Invoke(ctx, "/"+groupServiceName+"/RemoveMember", &grouppb.HasMemberRequest{GroupId: gid, UserId: uid}, res)
+	return errorFromStatus(res.Status, err)
+}
@@ -0,0 +1,130 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package provisioning serves the user and group managers that can provision
+// users and groups over grpc. The CS3 identity APIs are read-only, so the user
+// and group providers register these services next to them.
+package provisioning
+
+import (
+	"context"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
+)
+
+// passwordKey is the opaque key holding the password of a user.
+const passwordKey = "password"
+
+// unaryHandler returns the grpc handler of a method of a service.
+func unaryHandler(fullMethod string, newReq func() interface{}, call func(srv interface{}, ctx context.Context, req interface{}) (interface{}, error)) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := newReq()
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return call(srv, ctx, in)
+		}
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod}
+		return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return call(srv, ctx, req)
+		})
+	}
+}
+
+// currentUser returns the user of the request and whether it belongs to the admin group.
+func currentUser(ctx context.Context, adminGroup string) (*userpb.User, bool) {
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok {
+		return nil, false
+	}
+	if adminGroup == "" {
+		return u, false
+	}
+	for _, g := range u.Groups {
+		if g == adminGroup {
+			return u, true
+		}
+	}
+	return u, false
+}
+
+// statusFromError maps the errors of the user and group managers to statuses.
+func statusFromError(ctx context.Context, msg string, err error) *rpc.Status {
+	switch err.(type) {
+	case errtypes.IsNotFound:
+		return status.NewNotFound(ctx, msg+": "+err.Error())
+	case errtypes.IsAlreadyExists:
+		return status.NewAlreadyExists(ctx, err, msg+": "+err.Error())
+	case errtypes.IsBadRequest:
+		return status.NewInvalidArg(ctx, msg+": "+err.Error())
+	case errtypes.IsNotSupported:
+		return status.NewUnimplemented(ctx, err, msg+": "+err.Error())
+	case errtypes.IsPermissionDenied:
+		return status.NewPermissionDenied(ctx, err, msg+": "+err.Error())
+	}
+	return status.NewInternal(ctx, err, msg)
+}
+
+// errorFromStatus maps the status and error of a call back to the errors of
+// the user and group managers.
+func errorFromStatus(st *rpc.Status, err error) error {
+	if err != nil {
+		if gstatus.Code(errors.Cause(err)) == codes.Unimplemented {
+			return errtypes.NotSupported("provisioning is not enabled on the provider")
+		}
+		return err
+	}
+	switch st.GetCode() {
+	case rpc.Code_CODE_OK:
+		return nil
+	case rpc.Code_CODE_NOT_FOUND:
+		return errtypes.NotFound(st.Message)
+	case rpc.Code_CODE_ALREADY_EXISTS:
+		return errtypes.AlreadyExists(st.Message)
+	case rpc.Code_CODE_INVALID_ARGUMENT:
+		return errtypes.BadRequest(st.Message)
+	case rpc.Code_CODE_UNIMPLEMENTED:
+		return errtypes.NotSupported(st.Message)
+	case rpc.Code_CODE_PERMISSION_DENIED:
+		return errtypes.PermissionDenied(st.Message)
+	}
+	return errtypes.InternalError(st.GetMessage())
+}
+
+func passwordOpaque(password string) *types.Opaque {
+	return &types.Opaque{Map: map[string]*types.OpaqueEntry{
+		passwordKey: {Decoder: "plain", Value: []byte(password)},
+	}}
+}
+
+func passwordFromOpaque(o *types.Opaque) string {
+	if e := o.GetMap()[passwordKey]; e != nil {
+		return string(e.Value)
+	}
+	return ""
+}
@@ -0,0 +1,192 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package provisioning
+
+import (
+	"context"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/cs3org/reva/pkg/utils"
+	"google.golang.org/grpc"
+)
+
+const userServiceName = "revad.provisioning.v1beta1.UserProvisioningAPI"
+
+// UserServer provisions users. Only admins may create and delete users, the
+// users themselves may change their mail, display name and password.
+type UserServer struct {
+	m          user.ManageableManager
+	adminGroup string
+}
+
+// RegisterUserServer serves the user provisioning API on ss. Members of
+// adminGroup may provision users.
+func RegisterUserServer(ss *grpc.Server, m user.ManageableManager, adminGroup string) {
+	s := &UserServer{m: m, adminGroup: adminGroup}
+	ss.RegisterService(&grpc.ServiceDesc{
+		ServiceName: userServiceName,
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{
+			{
+				MethodName: "CreateUser",
+				Handler: unaryHandler("/"+userServiceName+"/CreateUser", func() interface{} { return &userpb.User{} }, func(_ interface{}, ctx context.Context, req interface{}) (interface{}, error) {
+					return s.CreateUser(ctx, req.(*userpb.User))
+				}),
+			},
+			{
+				MethodName: "UpdateUser",
+				Handler: unaryHandler("/"+userServiceName+"/UpdateUser", func() interface{} { return &userpb.User{} }, func(_ interface{}, ctx context.Context, req interface{}) (interface{}, error) {
+					return s.UpdateUser(ctx, req.(*userpb.User))
+				}),
+			},
+			{
+				MethodName: "SetPassword",
+				Handler: unaryHandler("/"+userServiceName+"/SetPassword", func() interface{} { return &userpb.User{} }, func(_ interface{}, ctx context.Context, req interface{}) (interface{}, error) {
+					return s.SetPassword(ctx, req.(*userpb.User))
+				}),
+			},
+			{
+				MethodName: "DeleteUser",
+				Handler: unaryHandler("/"+userServiceName+"/DeleteUser", func() interface{} { return &userpb.GetUserRequest{} }, func(_ interface{}, ctx context.Context, req interface{}) (interface{}, error) {
+					return s.DeleteUser(ctx, req.(*userpb.GetUserRequest))
+				}),
+			},
+		},
+	}, s)
+}
+
+// CreateUser creates the user with the password held in its opaque.
+func (s *UserServer) CreateUser(ctx context.Context, u *userpb.User) (*userpb.GetUserResponse, error) {
+	if _, admin := currentUser(ctx, s.adminGroup); !admin {
+		return &userpb.GetUserResponse{Status: status.NewPermissionDenied(ctx, nil, "only admins can create users")}, nil
+	}
+	password := passwordFromOpaque(u.Opaque)
+	u.Opaque = nil
+	created, err := s.m.CreateUser(ctx, u, password)
+	if err != nil {
+		return &userpb.GetUserResponse{Status: statusFromError(ctx, "error creating user", err)}, nil
+	}
+	return &userpb.GetUserResponse{Status: status.NewOK(ctx), User: created}, nil
+}
+
+// UpdateUser changes the display name, mail and groups of a user. Users
+// editing themselves can't change their groups.
+func (s *UserServer) UpdateUser(ctx context.Context, u *userpb.User) (*userpb.GetUserResponse, error) {
+	current, admin := currentUser(ctx, s.adminGroup)
+	if !admin {
+		if current == nil || !utils.UserEqual(current.Id, u.Id) {
+			return &userpb.GetUserResponse{Status: status.NewPermissionDenied(ctx, nil, "users can only edit themselves")}, nil
+		}
+		stored, err := s.m.GetUser(ctx, u.Id)
+		if err != nil {
+			return &userpb.GetUserResponse{Status: statusFromError(ctx, "error getting user", err)}, nil
+		}
+		u.Groups = stored.Groups
+	}
+	updated, err := s.m.UpdateUser(ctx, u)
+	if err != nil {
+		return &userpb.GetUserResponse{Status: statusFromError(ctx, "error updating user", err)}, nil
+	}
+	return &userpb.GetUserResponse{Status: status.NewOK(ctx), User: updated}, nil
+}
+
+// SetPassword sets the password held in the opaque of the user.
+func (s *UserServer) SetPassword(ctx context.Context, u *userpb.User) (*userpb.GetUserResponse, error) {
+	current, admin := currentUser(ctx, s.adminGroup)
+	if !admin && (current == nil || !utils.UserEqual(current.Id, u.Id)) {
+		return &userpb.GetUserResponse{Status: status.NewPermissionDenied(ctx, nil, "users can only change their own password")}, nil
+	}
+	password := passwordFromOpaque(u.Opaque)
+	if password == "" {
+		return &userpb.GetUserResponse{Status: status.NewInvalidArg(ctx, "empty password")}, nil
+	}
+	if err := s.m.SetPassword(ctx, u.Id, password); err != nil {
+		return &userpb.GetUserResponse{Status: statusFromError(ctx, "error setting password", err)}, nil
+	}
+	return &userpb.GetUserResponse{Status: status.NewOK(ctx)}, nil
+}
+
+// DeleteUser removes a user.
+func (s *UserServer) DeleteUser(ctx context.Context, req *userpb.GetUserRequest) (*userpb.GetUserResponse, error) {
+	if _, admin := currentUser(ctx, s.adminGroup); !admin {
+		return &userpb.GetUserResponse{Status: status.NewPermissionDenied(ctx, nil, "only admins can delete users")}, nil
+	}
+	if err := s.m.DeleteUser(ctx, req.UserId); err != nil {
+		return &userpb.GetUserResponse{Status: statusFromError(ctx, "error deleting user", err)}, nil
+	}
+	return &userpb.GetUserResponse{Status: status.NewOK(ctx)}, nil
+}
+
+// UserClient provisions users through the user provider. It returns the
+// errors of the user managers.
+type UserClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewUserClient returns a client of the user provisioning API.
+func NewUserClient(cc *grpc.ClientConn) *UserClient {
+	return &UserClient{cc: cc}
+}
+
+func (c *UserClient) invoke(ctx context.Context, method string, in interface{}) (*userpb.GetUserResponse, error) {
+	res := &userpb.GetUserResponse{}
+	err := c.cc.Invoke(ctx, "/"+userServiceName+"/"+method, in, res)
+	if err = errorFromStatus(res.Status, err); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// CreateUser creates a new user with the given password.
+func (c *UserClient) CreateUser(ctx context.Context, u *userpb.User, password string) (*userpb.User, error) {
+	res, err := c.invoke(ctx, "CreateUser", &userpb.User{
+		Username:    u.Username,
+		DisplayName: u.DisplayName,
+		Mail:        u.Mail,
+		Groups:      u.Groups,
+		Opaque:      passwordOpaque(password),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.User, nil
+}
+
+// UpdateUser changes the display name, mail and groups of the user with the id of u.
+func (c *UserClient) UpdateUser(ctx context.Context, u *userpb.User) (*userpb.User, error) {
+	res, err := c.invoke(ctx, "UpdateUser", u)
+	if err != nil {
+		return nil, err
+	}
+	return res.User, nil
+}
+
+// SetPassword changes the password of a user.
+func (c *UserClient) SetPassword(ctx context.Context, uid *userpb.UserId, password string) error {
+	_, err := c.invoke(ctx, "SetPassword", &userpb.User{Id: uid, Opaque: passwordOpaque(password)})
+	return err
+}
+
+// DeleteUser removes a user.
+func (c *UserClient) DeleteUser(ctx context.Context, uid *userpb.UserId) error {
+	_, err := c.invoke(ctx, "DeleteUser", &userpb.GetUserRequest{UserId: uid})
+	return err
+}
@@ -0,0 +1,167 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package provisioning
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/cs3org/reva/pkg/user/manager/json"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var (
+	admin    = &userpb.User{Id: &userpb.UserId{Idp: "localhost", OpaqueId: "admin"}, Username: "admin", Groups: []string{"admins"}}
+	einstein = &userpb.User{Id: &userpb.UserId{Idp: "localhost", OpaqueId: "einstein"}, Username: "einstein", Groups: []string{"physics-lovers"}}
+)
+
+// startUserServer serves the user provisioning API of a json user manager.
+// The user of a call is taken from the "user" metadata.
+func startUserServer(t *testing.T) (*UserClient, user.ManageableManager) {
+	dir, err := ioutil.TempDir("", "provisioning_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	file := filepath.Join(dir, "users.json")
+	users := `[{"id":{"idp":"localhost","opaque_id":"admin"},"username":"admin","groups":["admins"]},` +
+		`{"id":{"idp":"localhost","opaque_id":"einstein"},"username":"einstein","groups":["physics-lovers"]}]`
+	if err := ioutil.WriteFile(file, []byte(users), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := json.New(map[string]interface{}{"users": file})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	ss := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		for _, u := range []*userpb.User{admin, einstein} {
+			if len(md.Get("user")) > 0 && md.Get("user")[0] == u.Username {
+				ctx = ctxpkg.ContextSetUser(ctx, u)
+			}
+		}
+		return handler(ctx, req)
+	}))
+	RegisterUserServer(ss, m.(user.ManageableManager), "admins")
+	go func() { _ = ss.Serve(lis) }()
+	t.Cleanup(ss.Stop)
+
+	cc, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithDialer(func(string, time.Duration) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cc.Close() })
+	return NewUserClient(cc), m.(user.ManageableManager)
+}
+
+func as(u *userpb.User) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "user", u.Username)
+}
+
+func TestUserProvisioning(t *testing.T) {
+	c, m := startUserServer(t)
+
+	if _, err := c.CreateUser(as(einstein), &userpb.User{Username: "marie"}, "radium"); !isPermissionDenied(err) {
+		t.Fatalf("expected permission denied for a non admin, got %v", err)
+	}
+	u, err := c.CreateUser(as(admin), &userpb.User{Username: "marie", Mail: "marie@example.org"}, "radium")
+	if err != nil {
+		t.Fatalf("error creating user: %v", err)
+	}
+	if u.Username != "marie" || u.Id.GetOpaqueId() == "" {
+		t.Fatalf("unexpected user %v", u)
+	}
+	if _, err := c.CreateUser(as(admin), &userpb.User{Username: "marie"}, "radium"); err == nil {
+		t.Fatal("expected an error creating an existing user")
+	} else if _, ok := err.(errtypes.IsAlreadyExists); !ok {
+		t.Fatalf("expected already exists, got %v", err)
+	}
+
+	// users can edit themselves but not their groups
+	if _, err := c.UpdateUser(as(einstein), &userpb.User{Id: einstein.Id, Mail: "albert@example.org", Groups: []string{"admins"}}); err != nil {
+		t.Fatalf("error updating own user: %v", err)
+	}
+	stored, err := m.GetUser(context.Background(), einstein.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Mail != "albert@example.org" || len(stored.Groups) != 1 || stored.Groups[0] != "physics-lovers" {
+		t.Fatalf("unexpected user after update %v", stored)
+	}
+	if _, err := c.UpdateUser(as(einstein), &userpb.User{Id: u.Id, Mail: "einstein@example.org"}); !isPermissionDenied(err) {
+		t.Fatalf("expected permission denied updating another user, got %v", err)
+	}
+	if err := c.SetPassword(as(einstein), u.Id, "relativity"); !isPermissionDenied(err) {
+		t.Fatalf("expected permission denied setting the password of another user, got %v", err)
+	}
+	if err := c.SetPassword(as(einstein), einstein.Id, "relativity"); err != nil {
+		t.Fatalf("error setting own password: %v", err)
+	}
+
+	if err := c.DeleteUser(as(einstein), u.Id); !isPermissionDenied(err) {
+		t.Fatalf("expected permission denied for a non admin, got %v", err)
+	}
+	if err := c.DeleteUser(as(admin), u.Id); err != nil {
+		t.Fatalf("error deleting user: %v", err)
+	}
+	if err := c.DeleteUser(as(admin), u.Id); err == nil {
+		t.Fatal("expected an error deleting a missing user")
+	} else if _, ok := err.(errtypes.IsNotFound); !ok {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestUserProvisioningDisabled(t *testing.T) {
+	lis := bufconn.Listen(1 << 20)
+	ss := grpc.NewServer()
+	go func() { _ = ss.Serve(lis) }()
+	defer ss.Stop()
+	cc, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithDialer(func(string, time.Duration) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+
+	err = NewUserClient(cc).DeleteUser(context.Background(), einstein.Id)
+	if _, ok := err.(errtypes.IsNotSupported); !ok {
+		t.Fatalf("expected not supported, got %v", err)
+	}
+}
+
+func isPermissionDenied(err error) bool {
+	_, ok := err.(errtypes.IsPermissionDenied)
+	return ok
+}
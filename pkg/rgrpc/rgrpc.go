@@ -22,16 +22,22 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"reflect"
 	"sort"
+	"strings"
+	"sync"
 
 	"github.com/cs3org/reva/internal/grpc/interceptors/appctx"
 	"github.com/cs3org/reva/internal/grpc/interceptors/auth"
+	"github.com/cs3org/reva/internal/grpc/interceptors/clientip"
+	"github.com/cs3org/reva/internal/grpc/interceptors/lockapp"
 	"github.com/cs3org/reva/internal/grpc/interceptors/log"
 	"github.com/cs3org/reva/internal/grpc/interceptors/recovery"
 	"github.com/cs3org/reva/internal/grpc/interceptors/token"
 	"github.com/cs3org/reva/internal/grpc/interceptors/useragent"
 	"github.com/cs3org/reva/pkg/sharedconf"
 	rtrace "github.com/cs3org/reva/pkg/trace"
+	"github.com/cs3org/reva/pkg/utils"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
@@ -82,6 +88,12 @@ type Service interface {
 	UnprotectedEndpoints() []string
 }
 
+// Reloader is the interface that services can optionally implement
+// to apply an updated configuration without restarting the server.
+type Reloader interface {
+	Reload(conf map[string]interface{}) error
+}
+
 type unaryInterceptorTriple struct {
 	Name        string
 	Priority    int
@@ -101,6 +113,9 @@ type config struct {
 	Services         map[string]map[string]interface{} `mapstructure:"services"`
 	Interceptors     map[string]map[string]interface{} `mapstructure:"interceptors"`
 	EnableReflection bool                              `mapstructure:"enable_reflection"`
+	// TrustedProxies are the addresses and networks of the peers allowed to pass on the IP of their client
+	// and the name of the app holding locks, e.g. the app providers
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 func (c *config) init() {
@@ -111,6 +126,10 @@ func (c *config) init() {
 	if c.Address == "" {
 		c.Address = sharedconf.GetGatewaySVC("0.0.0.0:19000")
 	}
+
+	if c.TrustedProxies == nil {
+		c.TrustedProxies = []string{"127.0.0.1", "::1"}
+	}
 }
 
 // Server is a gRPC server.
@@ -120,6 +139,11 @@ type Server struct {
 	listener net.Listener
 	log      zerolog.Logger
 	services map[string]Service
+
+	trustedProxies []*net.IPNet
+
+	// mu serializes configuration reloads
+	mu sync.Mutex
 }
 
 // NewServer returns a new Server.
@@ -131,7 +155,12 @@ func NewServer(m interface{}, log zerolog.Logger) (*Server, error) {
 
 	conf.init()
 
-	server := &Server{conf: conf, log: log, services: map[string]Service{}}
+	trustedProxies, err := utils.ParseTrustedProxies(conf.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &Server{conf: conf, log: log, services: map[string]Service{}, trustedProxies: trustedProxies}
 
 	return server, nil
 }
@@ -238,6 +267,66 @@ func (s *Server) GracefulStop() error {
 	return nil
 }
 
+// Reload applies the given configuration to the running services.
+// Services implementing Reloader are reloaded when their configuration changed,
+// unchanged services are left running. Changes that cannot be applied in-process,
+// like adding or removing services, are logged and require a restart.
+func (s *Server) Reload(m interface{}) error {
+	conf := &config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return err
+	}
+	conf.init()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if conf.Network != s.conf.Network || conf.Address != s.conf.Address {
+		s.log.Warn().Msg("rgrpc: listener configuration changed, a restart is required to apply it")
+	}
+	if !reflect.DeepEqual(conf.Interceptors, s.conf.Interceptors) {
+		s.log.Warn().Msg("rgrpc: interceptors configuration changed, a restart is required to apply it")
+	}
+	if !reflect.DeepEqual(conf.TrustedProxies, s.conf.TrustedProxies) {
+		s.log.Warn().Msg("rgrpc: trusted proxies changed, a restart is required to apply them")
+	}
+
+	for name := range s.services {
+		if _, ok := conf.Services[name]; !ok {
+			s.log.Warn().Msgf("rgrpc: grpc service %s removed, a restart is required to apply it", name)
+		}
+	}
+
+	var failed []string
+	for name, svcConf := range conf.Services {
+		svc, ok := s.services[name]
+		if !ok {
+			s.log.Warn().Msgf("rgrpc: grpc service %s added, a restart is required to apply it", name)
+			continue
+		}
+		if reflect.DeepEqual(svcConf, s.conf.Services[name]) {
+			continue
+		}
+		r, ok := svc.(Reloader)
+		if !ok {
+			s.log.Warn().Msgf("rgrpc: grpc service %s does not support reloading, a restart is required to apply it", name)
+			continue
+		}
+		if err := r.Reload(svcConf); err != nil {
+			s.log.Error().Err(err).Msgf("rgrpc: error reloading grpc service %s", name)
+			failed = append(failed, name)
+			continue
+		}
+		s.conf.Services[name] = svcConf
+		s.log.Info().Msgf("rgrpc: grpc service reloaded: %s", name)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("rgrpc: error reloading grpc services: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
 // Network returns the network type.
 func (s *Server) Network() string {
 	return s.conf.Network
@@ -292,6 +381,8 @@ func (s *Server) getInterceptors(unprotected []string) ([]grpc.ServerOption, err
 		appctx.NewUnary(s.log),
 		token.NewUnary(),
 		useragent.NewUnary(),
+		lockapp.NewUnary(s.trustedProxies),
+		clientip.NewUnary(s.trustedProxies),
 		log.NewUnary(),
 		recovery.NewUnary(),
 	}, unaryInterceptors...)
@@ -334,6 +425,8 @@ func (s *Server) getInterceptors(unprotected []string) ([]grpc.ServerOption, err
 		appctx.NewStream(s.log),
 		token.NewStream(),
 		useragent.NewStream(),
+		lockapp.NewStream(s.trustedProxies),
+		clientip.NewStream(s.trustedProxies),
 		log.NewStream(),
 		recovery.NewStream(),
 	}, streamInterceptors...)
@@ -154,6 +154,26 @@ func NewConflict(ctx context.Context, err error, msg string) *rpc.Status {
 	}
 }
 
+// lockedMessage is the message of statuses reporting a lock conflict.
+// CS3 has no code of its own for it, CODE_ABORTED is used for other
+// conflicts as well.
+const lockedMessage = "resource is locked"
+
+// NewLocked returns a Status with Code_CODE_ABORTED for a resource which
+// is locked by someone else.
+func NewLocked(ctx context.Context, err error) *rpc.Status {
+	return &rpc.Status{
+		Code:    rpc.Code_CODE_ABORTED,
+		Message: lockedMessage,
+		Trace:   getTrace(ctx),
+	}
+}
+
+// IsLocked returns whether the status reports a lock conflict.
+func IsLocked(s *rpc.Status) bool {
+	return s.GetCode() == rpc.Code_CODE_ABORTED && s.GetMessage() == lockedMessage
+}
+
 // NewStatusFromErrType returns a status that corresponds to the given errtype
 func NewStatusFromErrType(ctx context.Context, msg string, err error) *rpc.Status {
 	switch e := err.(type) {
@@ -19,7 +19,10 @@
 package simple
 
 import (
+	"context"
+	"io"
 	"net/http"
+	"strings"
 
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
@@ -27,19 +30,24 @@ import (
 	"github.com/cs3org/reva/pkg/rhttp/datatx"
 	"github.com/cs3org/reva/pkg/rhttp/datatx/manager/registry"
 	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/download"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/virusscan"
 	"github.com/cs3org/reva/pkg/storage"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
+	tusd "github.com/tus/tusd/pkg/handler"
 )
 
 func init() {
 	registry.Register("simple", New)
 }
 
-type config struct{}
+type config struct {
+	virusscan.Config `mapstructure:",squash"`
+}
 
 type manager struct {
 	conf *config
+	hook *virusscan.Hook
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -58,7 +66,12 @@ func New(m map[string]interface{}) (datatx.DataTX, error) {
 		return nil, err
 	}
 
-	return &manager{conf: c}, nil
+	hook, err := virusscan.New(&c.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &manager{conf: c, hook: hook}, nil
 }
 
 func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
@@ -75,7 +88,12 @@ func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
 
 			ref := &provider.Reference{Path: fn}
 
-			err := fs.Upload(ctx, ref, r.Body)
+			body := newLimitedBody(ctx, fs, fn, r.Body)
+			err := m.hook.Upload(ctx, fs, ref, body)
+			if body.exceeded {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
 			switch v := err.(type) {
 			case nil:
 				w.WriteHeader(http.StatusOK)
@@ -87,6 +105,8 @@ func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
 				w.WriteHeader(http.StatusNotFound)
 			case errtypes.PermissionDenied:
 				w.WriteHeader(http.StatusForbidden)
+			case virusscan.Infected:
+				v.WriteError(w)
 			case errtypes.InvalidCredentials:
 				w.WriteHeader(http.StatusUnauthorized)
 			case errtypes.InsufficientStorage:
@@ -102,3 +122,59 @@ func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
 	})
 	return h, nil
 }
+
+// limitedBody fails reading more than the length an upload was initiated
+// with, which limits the size of uploads checked when they were initiated.
+type limitedBody struct {
+	r        io.ReadCloser
+	left     int64
+	exceeded bool
+}
+
+// newLimitedBody returns the body of an upload, limited to its length if the
+// storage tracks it. A length of 0 is not enforced as clients may initiate
+// uploads without a length. The data gateway limits uploads to the length
+// signed into their transfer token for every storage, this only covers
+// storages tracking the length when the data server is exposed.
+func newLimitedBody(ctx context.Context, fs storage.FS, id string, r io.ReadCloser) *limitedBody {
+	b := &limitedBody{r: r, left: -1}
+	ds, ok := fs.(tusd.DataStore)
+	if !ok {
+		return b
+	}
+	upload, err := ds.GetUpload(ctx, strings.TrimPrefix(id, "/"))
+	if err != nil {
+		return b
+	}
+	info, err := upload.GetInfo(ctx)
+	if err != nil || info.SizeIsDeferred || info.Size <= 0 {
+		return b
+	}
+	b.left = info.Size
+	return b
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.left < 0 {
+		return b.r.Read(p)
+	}
+	if b.left == 0 {
+		// only fail if there is more data
+		n, err := b.r.Read(make([]byte, 1))
+		if n > 0 {
+			b.exceeded = true
+			return 0, errtypes.BadRequest("the upload exceeds its length")
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.left {
+		p = p[:b.left]
+	}
+	n, err := b.r.Read(p)
+	b.left -= int64(n)
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.r.Close()
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/cs3org/reva/pkg/rhttp/datatx"
 	"github.com/cs3org/reva/pkg/rhttp/datatx/manager/registry"
 	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/download"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/virusscan"
 	"github.com/cs3org/reva/pkg/rhttp/router"
 	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/utils"
@@ -40,10 +41,13 @@ func init() {
 	registry.Register("spaces", New)
 }
 
-type config struct{}
+type config struct {
+	virusscan.Config `mapstructure:",squash"`
+}
 
 type manager struct {
 	conf *config
+	hook *virusscan.Hook
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -62,7 +66,12 @@ func New(m map[string]interface{}) (datatx.DataTX, error) {
 		return nil, err
 	}
 
-	return &manager{conf: c}, nil
+	hook, err := virusscan.New(&c.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &manager{conf: c, hook: hook}, nil
 }
 
 func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
@@ -95,7 +104,7 @@ func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
 				ResourceId: &provider.ResourceId{StorageId: storageid, OpaqueId: opaqeid},
 				Path:       fn,
 			}
-			err = fs.Upload(ctx, ref, r.Body)
+			err = m.hook.Upload(ctx, fs, ref, r.Body)
 			switch v := err.(type) {
 			case nil:
 				w.WriteHeader(http.StatusOK)
@@ -107,6 +116,8 @@ func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
 				w.WriteHeader(http.StatusNotFound)
 			case errtypes.PermissionDenied:
 				w.WriteHeader(http.StatusForbidden)
+			case virusscan.Infected:
+				v.WriteError(w)
 			case errtypes.InvalidCredentials:
 				w.WriteHeader(http.StatusUnauthorized)
 			case errtypes.InsufficientStorage:
@@ -27,6 +27,7 @@ import (
 	"github.com/cs3org/reva/pkg/rhttp/datatx"
 	"github.com/cs3org/reva/pkg/rhttp/datatx/manager/registry"
 	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/download"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/virusscan"
 	"github.com/cs3org/reva/pkg/storage"
 	"github.com/mitchellh/mapstructure"
 	tusd "github.com/tus/tusd/pkg/handler"
@@ -36,10 +37,13 @@ func init() {
 	registry.Register("tus", New)
 }
 
-type config struct{}
+type config struct {
+	virusscan.Config `mapstructure:",squash"`
+}
 
 type manager struct {
 	conf *config
+	hook *virusscan.Hook
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -58,7 +62,12 @@ func New(m map[string]interface{}) (datatx.DataTX, error) {
 		return nil, err
 	}
 
-	return &manager{conf: c}, nil
+	hook, err := virusscan.New(&c.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &manager{conf: c, hook: hook}, nil
 }
 
 func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
@@ -76,6 +85,9 @@ func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
 	// let the composable storage tell tus which extensions it supports
 	composable.UseIn(composer)
 
+	// scan finished uploads for viruses, if configured
+	m.hook.UseIn(composer, fs)
+
 	config := tusd.Config{
 		StoreComposer: composer,
 	}
@@ -85,7 +97,7 @@ func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
 		return nil, err
 	}
 
-	h := handler.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	h := handler.Middleware(m.hook.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		method := r.Method
 		// https://github.com/tus/tus-resumable-upload-protocol/blob/master/protocol.md#x-http-method-override
@@ -107,7 +119,7 @@ func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
 		default:
 			w.WriteHeader(http.StatusNotImplemented)
 		}
-	}))
+	})))
 
 	return h, nil
 }
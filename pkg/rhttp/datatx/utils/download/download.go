@@ -20,6 +20,7 @@
 package download
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -28,6 +29,7 @@ import (
 	"strconv"
 
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/storage"
@@ -78,6 +80,15 @@ func GetOrHeadFile(w http.ResponseWriter, r *http.Request, fs storage.FS, spaceI
 		return
 	}
 
+	// a version parameter selects a revision of the file
+	revisionKey := r.URL.Query().Get("version")
+	if revisionKey != "" {
+		if md, err = revisionMD(ctx, fs, ref, md, revisionKey); err != nil {
+			handleError(w, &sublog, err, "list revisions")
+			return
+		}
+	}
+
 	var ranges []HTTPRange
 
 	if r.Header.Get("Range") != "" {
@@ -100,7 +111,12 @@ func GetOrHeadFile(w http.ResponseWriter, r *http.Request, fs storage.FS, spaceI
 		}
 	}
 
-	content, err := fs.Download(ctx, ref)
+	var content io.ReadCloser
+	if revisionKey != "" {
+		content, err = fs.DownloadRevision(ctx, ref, revisionKey)
+	} else {
+		content, err = fs.Download(ctx, ref)
+	}
 	if err != nil {
 		handleError(w, &sublog, err, "download")
 		return
@@ -198,6 +214,24 @@ func GetOrHeadFile(w http.ResponseWriter, r *http.Request, fs storage.FS, spaceI
 
 }
 
+// revisionMD returns the metadata of the file with the size, etag and mtime of
+// the given revision
+func revisionMD(ctx context.Context, fs storage.FS, ref *provider.Reference, md *provider.ResourceInfo, key string) (*provider.ResourceInfo, error) {
+	revisions, err := fs.ListRevisions(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	for _, rev := range revisions {
+		if rev.Key == key {
+			md.Size = rev.Size
+			md.Etag = rev.Etag
+			md.Mtime = &types.Timestamp{Seconds: rev.Mtime}
+			return md, nil
+		}
+	}
+	return nil, errtypes.NotFound(key)
+}
+
 func handleError(w http.ResponseWriter, log *zerolog.Logger, err error, action string) {
 	switch err.(type) {
 	case errtypes.IsNotFound:
@@ -206,6 +240,9 @@ func handleError(w http.ResponseWriter, log *zerolog.Logger, err error, action s
 	case errtypes.IsPermissionDenied:
 		log.Debug().Err(err).Str("action", action).Msg("permission denied")
 		w.WriteHeader(http.StatusForbidden)
+	case errtypes.IsTooEarly:
+		log.Debug().Err(err).Str("action", action).Msg("resource not ready yet")
+		w.WriteHeader(http.StatusTooEarly)
 	default:
 		log.Error().Err(err).Str("action", action).Msg("unexpected error")
 		w.WriteHeader(http.StatusInternalServerError)
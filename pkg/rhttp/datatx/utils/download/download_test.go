@@ -0,0 +1,94 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package download
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+// revFS serves a single file with its revisions. Calls to other methods of
+// storage.FS panic.
+type revFS struct {
+	storage.FS
+	content   []byte
+	revisions map[string][]byte
+}
+
+func (fs *revFS) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	return &provider.ResourceInfo{Path: ref.Path, Size: uint64(len(fs.content)), MimeType: "text/plain"}, nil
+}
+
+func (fs *revFS) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(fs.content)), nil
+}
+
+func (fs *revFS) ListRevisions(ctx context.Context, ref *provider.Reference) ([]*provider.FileVersion, error) {
+	versions := []*provider.FileVersion{}
+	for k, b := range fs.revisions {
+		versions = append(versions, &provider.FileVersion{Key: k, Size: uint64(len(b))})
+	}
+	return versions, nil
+}
+
+func (fs *revFS) DownloadRevision(ctx context.Context, ref *provider.Reference, key string) (io.ReadCloser, error) {
+	b, ok := fs.revisions[key]
+	if !ok {
+		return nil, errtypes.NotFound(key)
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+func TestGetOrHeadFileRevision(t *testing.T) {
+	fs := &revFS{
+		content:   []byte("current content"),
+		revisions: map[string][]byte{"1561410426": []byte("old")},
+	}
+
+	tests := []struct {
+		url  string
+		code int
+		body string
+	}{
+		{"/file.txt", http.StatusOK, "current content"},
+		{"/file.txt?version=1561410426", http.StatusOK, "old"},
+		{"/file.txt?version=1", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		GetOrHeadFile(w, r, fs, "")
+
+		if w.Code != tt.code {
+			t.Errorf("%s: expected status %d, got %d", tt.url, tt.code, w.Code)
+		}
+		if w.Body.String() != tt.body {
+			t.Errorf("%s: expected body %q, got %q", tt.url, tt.body, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package tuswrap lets the data transfer managers run code when tus uploads
+// are finished, e.g. to scan them or to record them.
+package tuswrap
+
+import (
+	"context"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/utils"
+	tusd "github.com/tus/tusd/pkg/handler"
+)
+
+// FinishFunc finishes an upload instead of its FinishUpload method. It has
+// to call next to commit the upload to the storage.
+type FinishFunc func(ctx context.Context, upload tusd.Upload, next func(ctx context.Context) error) error
+
+// UseIn wraps the data store of the composer so that finish is called when
+// uploads are finished. The composer can be wrapped multiple times, the
+// FinishFunc passed last is called first.
+func UseIn(composer *tusd.StoreComposer, finish FinishFunc) {
+	if composer.UsesTerminater {
+		composer.UseTerminater(terminater{composer.Terminater})
+	}
+	if composer.UsesConcater {
+		composer.UseConcater(concater{composer.Concater})
+	}
+	if composer.UsesLengthDeferrer {
+		composer.UseLengthDeferrer(lengthDeferrer{composer.LengthDeferrer})
+	}
+	composer.UseCore(&dataStore{DataStore: composer.Core, finish: finish})
+}
+
+// Uploader returns a context holding the user who initiated the upload, as
+// recorded in the upload info by the storages. tusd finishes uploads without
+// the context of the request.
+func Uploader(ctx context.Context, info tusd.FileInfo) context.Context {
+	u := &userpb.User{
+		Id: &userpb.UserId{
+			Idp:      info.Storage["Idp"],
+			OpaqueId: info.Storage["UserId"],
+			Type:     utils.UserTypeMap(info.Storage["UserType"]),
+		},
+		Username: info.Storage["UserName"],
+	}
+	return ctxpkg.ContextSetUser(ctx, u)
+}
+
+type dataStore struct {
+	tusd.DataStore
+	finish FinishFunc
+}
+
+func (s *dataStore) NewUpload(ctx context.Context, info tusd.FileInfo) (tusd.Upload, error) {
+	u, err := s.DataStore.NewUpload(ctx, info)
+	if err != nil {
+		return nil, err
+	}
+	return &upload{Upload: u, finish: s.finish}, nil
+}
+
+func (s *dataStore) GetUpload(ctx context.Context, id string) (tusd.Upload, error) {
+	u, err := s.DataStore.GetUpload(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &upload{Upload: u, finish: s.finish}, nil
+}
+
+type upload struct {
+	tusd.Upload
+	finish FinishFunc
+}
+
+func (u *upload) FinishUpload(ctx context.Context) error {
+	return u.finish(ctx, u.Upload, u.Upload.FinishUpload)
+}
+
+// Unwrap returns the upload of the storage.
+func Unwrap(u tusd.Upload) tusd.Upload {
+	for {
+		w, ok := u.(*upload)
+		if !ok {
+			return u
+		}
+		u = w.Upload
+	}
+}
+
+// The storages expect their own uploads in the extensions.
+
+type terminater struct {
+	tusd.TerminaterDataStore
+}
+
+func (t terminater) AsTerminatableUpload(upload tusd.Upload) tusd.TerminatableUpload {
+	return t.TerminaterDataStore.AsTerminatableUpload(Unwrap(upload))
+}
+
+type concater struct {
+	tusd.ConcaterDataStore
+}
+
+func (c concater) AsConcatableUpload(upload tusd.Upload) tusd.ConcatableUpload {
+	return concatableUpload{c.ConcaterDataStore.AsConcatableUpload(Unwrap(upload))}
+}
+
+type concatableUpload struct {
+	tusd.ConcatableUpload
+}
+
+func (c concatableUpload) ConcatUploads(ctx context.Context, partialUploads []tusd.Upload) error {
+	unwrapped := make([]tusd.Upload, 0, len(partialUploads))
+	for _, u := range partialUploads {
+		unwrapped = append(unwrapped, Unwrap(u))
+	}
+	return c.ConcatableUpload.ConcatUploads(ctx, unwrapped)
+}
+
+type lengthDeferrer struct {
+	tusd.LengthDeferrerDataStore
+}
+
+func (l lengthDeferrer) AsLengthDeclarableUpload(upload tusd.Upload) tusd.LengthDeclarableUpload {
+	return l.LengthDeferrerDataStore.AsLengthDeclarableUpload(Unwrap(upload))
+}
@@ -0,0 +1,146 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package virusscan
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path"
+
+	"github.com/cs3org/reva/pkg/antivirus"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/utils/tuswrap"
+	"github.com/cs3org/reva/pkg/storage"
+	tusd "github.com/tus/tusd/pkg/handler"
+)
+
+// UseIn makes the data store of the composer scan uploads when they are
+// finished. tusd finishes uploads without the request, requests finishing
+// uploads have to be passed through Handler for the scans to use their
+// context and to report refused uploads in their response.
+func (h *Hook) UseIn(composer *tusd.StoreComposer, fs storage.FS) {
+	if h == nil {
+		return
+	}
+
+	s := &scanner{hook: h, fs: fs}
+	if composer.UsesTerminater {
+		s.terminater = composer.Terminater
+	}
+	tuswrap.UseIn(composer, s.finish)
+}
+
+// request is a request uploading to a tus upload.
+type request struct {
+	ctx context.Context
+	w   http.ResponseWriter
+}
+
+// Handler passes the requests to next, which is a tus handler using a data
+// store set up with UseIn.
+func (h *Hook) Handler(next http.Handler) http.Handler {
+	if h == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// tus identifies uploads by the last segment of the path
+		id := path.Base(r.URL.Path)
+		if _, loaded := h.requests.LoadOrStore(id, &request{ctx: r.Context(), w: w}); !loaded {
+			defer h.requests.Delete(id)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type scanner struct {
+	hook       *Hook
+	fs         storage.FS
+	terminater tusd.TerminaterDataStore
+}
+
+// finish scans the upload before or after finishing it.
+func (s *scanner) finish(ctx context.Context, upload tusd.Upload, next func(context.Context) error) error {
+	h, fs := s.hook, s.fs
+
+	info, err := upload.GetInfo(ctx)
+	if err != nil {
+		return err
+	}
+	target := targetOfInfo(info)
+
+	var w http.ResponseWriter
+	if v, ok := h.requests.Load(info.ID); ok {
+		req := v.(*request)
+		ctx, w = req.ctx, req.w
+	} else {
+		ctx = tuswrap.Uploader(ctx, info)
+	}
+
+	if h.conf.VirusScanMode == ModeAfter {
+		if err := next(ctx); err != nil {
+			return err
+		}
+		h.scanCommitted(ctx, fs, target)
+		return nil
+	}
+
+	var res *antivirus.Result
+	if err := readUpload(ctx, upload, func(r io.Reader) (err error) {
+		res, err = h.scan(ctx, target, r)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if res.Infected && h.conf.VirusInfectedAction != ActionTag {
+		err := readUpload(ctx, upload, func(r io.Reader) error {
+			return h.refuse(ctx, target, res, r)
+		})
+		v, ok := err.(Infected)
+		if !ok {
+			// keep the upload if it couldn't be quarantined
+			return err
+		}
+		if s.terminater != nil {
+			_ = s.terminater.AsTerminatableUpload(tuswrap.Unwrap(upload)).Terminate(ctx)
+		}
+		if w != nil {
+			w.Header().Set(InfectedHeader, string(v))
+		}
+		return tusd.NewHTTPError(err, http.StatusForbidden)
+	}
+
+	if err := next(ctx); err != nil {
+		return err
+	}
+	h.tag(ctx, fs, target, res)
+	return nil
+}
+
+// readUpload calls fn with the content of the upload.
+func readUpload(ctx context.Context, upload tusd.Upload, fn func(r io.Reader) error) error {
+	r, err := upload.GetReader(ctx)
+	if err != nil {
+		return err
+	}
+	if c, ok := r.(io.Closer); ok {
+		defer c.Close()
+	}
+	return fn(r)
+}
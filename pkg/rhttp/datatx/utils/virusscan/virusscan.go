@@ -0,0 +1,323 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package virusscan scans uploads received by the data transfer managers
+// for viruses and handles infected files.
+package virusscan
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/antivirus"
+	"github.com/cs3org/reva/pkg/antivirus/scanner/registry"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/pkg/errors"
+	tusd "github.com/tus/tusd/pkg/handler"
+)
+
+// The modes define when uploads are scanned.
+const (
+	// ModeBefore scans uploads before they are committed to the storage.
+	ModeBefore = "before"
+	// ModeAfter scans uploads right after they were committed to the storage.
+	// Infected uploads can only be tagged then, removing them would also
+	// remove the previous versions of the file.
+	ModeAfter = "after"
+)
+
+// The actions define how infected uploads are handled. Except for
+// ActionTag, infected uploads are never committed to the storage.
+const (
+	// ActionReject refuses the upload.
+	ActionReject = "reject"
+	// ActionQuarantine refuses the upload and keeps its content in the
+	// quarantine folder.
+	ActionQuarantine = "quarantine"
+	// ActionTag accepts the upload and only records the result.
+	ActionTag = "tag"
+)
+
+// The arbitrary metadata keys the results of scans are recorded in.
+const (
+	StatusKey      = "virusscan.status"
+	DescriptionKey = "virusscan.description"
+	ScanDateKey    = "virusscan.scandate"
+)
+
+// The values of StatusKey.
+const (
+	StatusClean    = "clean"
+	StatusInfected = "infected"
+	// StatusError is recorded when a file committed before the scan
+	// couldn't be scanned.
+	StatusError = "error"
+)
+
+// Config configures the scanning of uploads. It is meant to be embedded
+// into the configuration of the data transfer managers.
+type Config struct {
+	VirusScanner        string                            `mapstructure:"virus_scanner" docs:";The virus scanner checking the uploads, e.g. clamd, icap or stub. Uploads are not scanned if unset."`
+	VirusScanners       map[string]map[string]interface{} `mapstructure:"virus_scanners" docs:"url:pkg/antivirus/scanner/clamd/clamd.go;The configuration of the virus scanners."`
+	VirusScanMode       string                            `mapstructure:"virus_scan_mode" docs:"before;Whether uploads are scanned before or after they are committed to the storage. Scanning after the commit requires the tag action."`
+	VirusInfectedAction string                            `mapstructure:"virus_infected_action" docs:"reject;What happens to infected uploads: reject, quarantine or tag."`
+	VirusQuarantinePath string                            `mapstructure:"virus_quarantine_path" docs:"/var/tmp/reva/quarantine;The local folder infected uploads are kept in. It must not be reachable through the storages."`
+}
+
+func (c *Config) init() {
+	if c.VirusScanMode == "" {
+		c.VirusScanMode = ModeBefore
+	}
+	if c.VirusInfectedAction == "" {
+		c.VirusInfectedAction = ActionReject
+	}
+	if c.VirusQuarantinePath == "" {
+		c.VirusQuarantinePath = "/var/tmp/reva/quarantine"
+	}
+}
+
+// InfectedHeader is the response header naming the virus an upload
+// was refused for.
+const InfectedHeader = "X-Virus-Found"
+
+// Infected is returned when an upload is refused because it contains a virus.
+type Infected string
+
+func (e Infected) Error() string { return "virus found: " + string(e) }
+
+// WriteError responds to a refused upload.
+func (e Infected) WriteError(w http.ResponseWriter) {
+	w.Header().Set(InfectedHeader, string(e))
+	http.Error(w, e.Error(), http.StatusForbidden)
+}
+
+// Hook scans uploads. A nil Hook doesn't scan at all.
+type Hook struct {
+	conf    *Config
+	scanner antivirus.Scanner
+
+	// the requests tus uploads are currently finished by, see Handler
+	requests sync.Map
+}
+
+// New returns the hook configured by c, or nil if no scanner is configured.
+func New(c *Config) (*Hook, error) {
+	if c.VirusScanner == "" {
+		return nil, nil
+	}
+	c.init()
+
+	switch c.VirusScanMode {
+	case ModeBefore, ModeAfter:
+	default:
+		return nil, errors.Errorf("virusscan: unknown scan mode %q", c.VirusScanMode)
+	}
+	switch c.VirusInfectedAction {
+	case ActionReject, ActionQuarantine, ActionTag:
+	default:
+		return nil, errors.Errorf("virusscan: unknown action %q", c.VirusInfectedAction)
+	}
+	if c.VirusScanMode == ModeAfter && c.VirusInfectedAction != ActionTag {
+		return nil, errors.Errorf("virusscan: infected uploads can only be tagged when scanning after the commit")
+	}
+
+	f, ok := registry.NewFuncs[c.VirusScanner]
+	if !ok {
+		return nil, errors.Errorf("virusscan: virus scanner %q not found", c.VirusScanner)
+	}
+	scanner, err := f(c.VirusScanners[c.VirusScanner])
+	if err != nil {
+		return nil, errors.Wrap(err, "virusscan: error creating virus scanner")
+	}
+	return &Hook{conf: c, scanner: scanner}, nil
+}
+
+// Upload uploads the content of r to the storage like fs.Upload and scans
+// it on the way. It returns Infected if the upload was refused.
+func (h *Hook) Upload(ctx context.Context, fs storage.FS, ref *provider.Reference, r io.ReadCloser) error {
+	if h == nil {
+		return fs.Upload(ctx, ref, r)
+	}
+
+	// the reference points to the upload, find the file it will be stored at
+	target := targetOf(ctx, fs, ref)
+
+	if h.conf.VirusScanMode == ModeAfter {
+		if err := fs.Upload(ctx, ref, r); err != nil {
+			return err
+		}
+		h.scanCommitted(ctx, fs, target)
+		return nil
+	}
+
+	// the content has to be scanned before uploading it, so keep it around
+	tmp, err := ioutil.TempFile("", "reva-virusscan-")
+	if err != nil {
+		return errors.Wrap(err, "virusscan: error creating temporary file")
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		return errors.Wrap(err, "virusscan: error buffering upload")
+	}
+	r.Close()
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return errors.Wrap(err, "virusscan: error rewinding upload")
+	}
+	res, err := h.scan(ctx, target, tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return errors.Wrap(err, "virusscan: error rewinding upload")
+	}
+	if res.Infected && h.conf.VirusInfectedAction != ActionTag {
+		return h.refuse(ctx, target, res, tmp)
+	}
+
+	if err := fs.Upload(ctx, ref, ioutil.NopCloser(tmp)); err != nil {
+		return err
+	}
+	h.tag(ctx, fs, target, res)
+	return nil
+}
+
+func (h *Hook) scan(ctx context.Context, ref *provider.Reference, r io.Reader) (*antivirus.Result, error) {
+	res, err := h.scanner.Scan(ctx, path.Base(ref.GetPath()), r)
+	if err != nil {
+		return nil, errors.Wrap(err, "virusscan: error scanning upload")
+	}
+	return res, nil
+}
+
+// scanFile scans a file which was already committed to the storage.
+func (h *Hook) scanFile(ctx context.Context, fs storage.FS, ref *provider.Reference) (*antivirus.Result, error) {
+	rc, err := fs.Download(ctx, ref)
+	if err != nil {
+		return nil, errors.Wrap(err, "virusscan: error reading uploaded file")
+	}
+	defer rc.Close()
+	return h.scan(ctx, ref, rc)
+}
+
+// scanCommitted scans a file which was committed to the storage and tags it.
+// The upload succeeded, so a failing scan is only logged and recorded.
+func (h *Hook) scanCommitted(ctx context.Context, fs storage.FS, ref *provider.Reference) {
+	res, err := h.scanFile(ctx, fs, ref)
+	if err != nil {
+		log := appctx.GetLogger(ctx)
+		log.Error().Err(err).Str("file", ref.GetPath()).Msg("virusscan: error scanning committed upload")
+		if err := record(ctx, fs, ref, StatusError, ""); err != nil {
+			log.Error().Err(err).Str("file", ref.GetPath()).Msg("virusscan: error recording scan result")
+		}
+		return
+	}
+	h.tag(ctx, fs, ref, res)
+}
+
+// tag records the result of the scan on the committed file. The upload
+// succeeded, so errors are only logged.
+func (h *Hook) tag(ctx context.Context, fs storage.FS, ref *provider.Reference, res *antivirus.Result) {
+	log := appctx.GetLogger(ctx)
+	status := StatusClean
+	if res.Infected {
+		status = StatusInfected
+	}
+	if err := record(ctx, fs, ref, status, res.Description); err != nil {
+		log.Error().Err(err).Str("file", ref.GetPath()).Msg("virusscan: error recording scan result")
+	}
+	if res.Infected {
+		log.Warn().Str("file", ref.GetPath()).Str("virus", res.Description).Msg("virusscan: tagged infected upload")
+	}
+}
+
+// refuse handles an infected upload which was not committed to the storage
+// according to the configured action. The content of the upload is read
+// from r. It returns Infected unless the content couldn't be quarantined.
+func (h *Hook) refuse(ctx context.Context, ref *provider.Reference, res *antivirus.Result, r io.Reader) error {
+	log := appctx.GetLogger(ctx)
+
+	if h.conf.VirusInfectedAction != ActionQuarantine {
+		log.Warn().Str("file", ref.GetPath()).Str("virus", res.Description).Msg("virusscan: rejected infected upload")
+		return Infected(res.Description)
+	}
+
+	if err := os.MkdirAll(h.conf.VirusQuarantinePath, 0700); err != nil {
+		return errors.Wrap(err, "virusscan: error creating quarantine folder")
+	}
+	dst := filepath.Join(h.conf.VirusQuarantinePath, strconv.FormatInt(time.Now().UnixNano(), 10)+"-"+path.Base(ref.GetPath()))
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrap(err, "virusscan: error creating quarantined file")
+	}
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return errors.Wrap(err, "virusscan: error writing quarantined file")
+	}
+	log.Warn().Str("file", ref.GetPath()).Str("quarantine", dst).Str("virus", res.Description).Msg("virusscan: quarantined infected upload")
+	return Infected(res.Description)
+}
+
+func record(ctx context.Context, fs storage.FS, ref *provider.Reference, status, description string) error {
+	return fs.SetArbitraryMetadata(ctx, ref, &provider.ArbitraryMetadata{
+		Metadata: map[string]string{
+			StatusKey:      status,
+			DescriptionKey: description,
+			ScanDateKey:    time.Now().UTC().Format(time.RFC3339),
+		},
+	})
+}
+
+// targetOf returns a reference to the file an upload will be stored at.
+// Storages supporting tus identify uploads by an id instead of the path.
+func targetOf(ctx context.Context, fs storage.FS, ref *provider.Reference) *provider.Reference {
+	uploads, ok := fs.(interface {
+		GetUpload(ctx context.Context, id string) (tusd.Upload, error)
+	})
+	if !ok {
+		return ref
+	}
+	upload, err := uploads.GetUpload(ctx, ref.GetPath())
+	if err != nil {
+		return ref
+	}
+	info, err := upload.GetInfo(ctx)
+	if err != nil {
+		return ref
+	}
+	return targetOfInfo(info)
+}
+
+func targetOfInfo(info tusd.FileInfo) *provider.Reference {
+	return &provider.Reference{Path: path.Join("/", info.MetaData["dir"], info.MetaData["filename"])}
+}
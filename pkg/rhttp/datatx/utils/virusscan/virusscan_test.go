@@ -0,0 +1,196 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package virusscan
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/antivirus"
+	_ "github.com/cs3org/reva/pkg/antivirus/scanner/stub"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// memFS keeps files and their metadata in memory. Calls to other methods
+// of storage.FS panic.
+type memFS struct {
+	storage.FS
+	files    map[string][]byte
+	metadata map[string]map[string]string
+	trash    []string
+}
+
+func newMemFS() *memFS {
+	return &memFS{files: map[string][]byte{}, metadata: map[string]map[string]string{}}
+}
+
+func (fs *memFS) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	fs.files[ref.Path] = b
+	return nil
+}
+
+func (fs *memFS) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
+	b, ok := fs.files[ref.Path]
+	if !ok {
+		return nil, errtypes.NotFound(ref.Path)
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (fs *memFS) Delete(ctx context.Context, ref *provider.Reference) error {
+	delete(fs.files, ref.Path)
+	fs.trash = append(fs.trash, ref.Path)
+	return nil
+}
+
+func (fs *memFS) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	fs.metadata[ref.Path] = md.Metadata
+	return nil
+}
+
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// failingScanner fails every scan.
+type failingScanner struct{}
+
+func (failingScanner) Scan(ctx context.Context, name string, r io.Reader) (*antivirus.Result, error) {
+	return nil, errors.New("scanner unavailable")
+}
+
+func TestNewWithoutScanner(t *testing.T) {
+	h, err := New(&Config{})
+	if err != nil || h != nil {
+		t.Fatalf("expected no hook, got %v, %v", h, err)
+	}
+
+	// a nil hook just uploads
+	fs := newMemFS()
+	if err := h.Upload(context.Background(), fs, &provider.Reference{Path: "/file"}, ioutil.NopCloser(strings.NewReader(eicar))); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fs.files["/file"]; !ok {
+		t.Error("file was not uploaded")
+	}
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	for _, c := range []*Config{
+		{VirusScanner: "unknown"},
+		{VirusScanner: "stub", VirusScanMode: "sometimes"},
+		{VirusScanner: "stub", VirusInfectedAction: "ignore"},
+		{VirusScanner: "stub", VirusInfectedAction: "delete"},
+		{VirusScanner: "stub", VirusScanMode: ModeAfter, VirusInfectedAction: ActionReject},
+		{VirusScanner: "stub", VirusScanMode: ModeAfter, VirusInfectedAction: ActionQuarantine},
+	} {
+		if _, err := New(c); err == nil {
+			t.Errorf("expected an error for %+v", c)
+		}
+	}
+}
+
+func TestUpload(t *testing.T) {
+	tests := []struct {
+		mode, action string
+		content      string
+		refused      bool
+		status       string
+		quarantined  bool
+	}{
+		{mode: ModeBefore, action: ActionReject, content: "clean", status: "clean"},
+		{mode: ModeAfter, action: ActionTag, content: "clean", status: "clean"},
+		{mode: ModeBefore, action: ActionReject, content: eicar, refused: true},
+		{mode: ModeBefore, action: ActionQuarantine, content: eicar, refused: true, quarantined: true},
+		{mode: ModeBefore, action: ActionTag, content: eicar, status: "infected"},
+		{mode: ModeAfter, action: ActionTag, content: eicar, status: "infected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode+"/"+tt.action+"/"+tt.status, func(t *testing.T) {
+			quarantine := t.TempDir()
+			h, err := New(&Config{VirusScanner: "stub", VirusScanMode: tt.mode, VirusInfectedAction: tt.action, VirusQuarantinePath: quarantine})
+			if err != nil {
+				t.Fatal(err)
+			}
+			fs := newMemFS()
+			fs.files["/file"] = []byte("previous")
+			err = h.Upload(context.Background(), fs, &provider.Reference{Path: "/file"}, ioutil.NopCloser(strings.NewReader(tt.content)))
+
+			if _, ok := err.(Infected); ok != tt.refused {
+				t.Fatalf("expected refused to be %v, got error %v", tt.refused, err)
+			}
+			// refused uploads must not touch the existing file
+			want := tt.content
+			if tt.refused {
+				want = "previous"
+			}
+			if got := string(fs.files["/file"]); got != want {
+				t.Errorf("expected content %q, got %q", want, got)
+			}
+			if got := fs.metadata["/file"][StatusKey]; got != tt.status {
+				t.Errorf("expected status %q, got %q", tt.status, got)
+			}
+			if len(fs.trash) > 0 {
+				t.Error("the file was deleted")
+			}
+
+			files, err := ioutil.ReadDir(quarantine)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if quarantined := len(files) > 0; quarantined != tt.quarantined {
+				t.Fatalf("expected quarantined to be %v", tt.quarantined)
+			}
+			for _, f := range files {
+				b, err := ioutil.ReadFile(filepath.Join(quarantine, f.Name()))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(b) != tt.content {
+					t.Errorf("unexpected quarantined content %q", b)
+				}
+			}
+		})
+	}
+}
+
+func TestUploadAfterScanError(t *testing.T) {
+	h := &Hook{conf: &Config{VirusScanMode: ModeAfter, VirusInfectedAction: ActionTag}, scanner: failingScanner{}}
+	fs := newMemFS()
+	if err := h.Upload(context.Background(), fs, &provider.Reference{Path: "/file"}, ioutil.NopCloser(strings.NewReader("content"))); err != nil {
+		t.Fatalf("the committed upload must not fail, got %v", err)
+	}
+	if got := string(fs.files["/file"]); got != "content" {
+		t.Errorf("expected the upload to be kept, got %q", got)
+	}
+	if got := fs.metadata["/file"][StatusKey]; got != StatusError {
+		t.Errorf("expected status %q, got %q", StatusError, got)
+	}
+}
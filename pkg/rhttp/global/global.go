@@ -59,3 +59,9 @@ type Service interface {
 	// GET is public and POST is not.
 	Unprotected() []string
 }
+
+// Reloader is the interface that services can optionally implement
+// to apply an updated configuration without restarting the server.
+type Reloader interface {
+	Reload(conf map[string]interface{}) error
+}
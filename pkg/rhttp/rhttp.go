@@ -24,7 +24,10 @@ import (
 	"net"
 	"net/http"
 	"path"
+	"reflect"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/cs3org/reva/internal/http/interceptors/appctx"
@@ -54,6 +57,7 @@ func New(m interface{}, l zerolog.Logger) (*Server, error) {
 		httpServer:  httpServer,
 		conf:        conf,
 		svcs:        map[string]global.Service{},
+		names:       map[string]global.Service{},
 		unprotected: []string{},
 		handlers:    map[string]http.Handler{},
 		log:         l,
@@ -67,10 +71,14 @@ type Server struct {
 	conf        *config
 	listener    net.Listener
 	svcs        map[string]global.Service // map key is svc Prefix
+	names       map[string]global.Service // map key is svc name
 	unprotected []string
 	handlers    map[string]http.Handler
 	middlewares []*middlewareTriple
 	log         zerolog.Logger
+
+	// mu serializes configuration reloads
+	mu sync.Mutex
 }
 
 type config struct {
@@ -139,6 +147,68 @@ func (s *Server) closeServices() {
 	}
 }
 
+// Reload applies the given configuration to the running services.
+// Services implementing global.Reloader are reloaded when their configuration changed,
+// unchanged services are left running. Changes that cannot be applied in-process,
+// like adding or removing services or changing their prefix, are logged and require a restart.
+func (s *Server) Reload(m interface{}) error {
+	conf := &config{}
+	if err := mapstructure.Decode(m, conf); err != nil {
+		return err
+	}
+	conf.init()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if conf.Network != s.conf.Network || conf.Address != s.conf.Address {
+		s.log.Warn().Msg("rhttp: listener configuration changed, a restart is required to apply it")
+	}
+	if !reflect.DeepEqual(conf.Middlewares, s.conf.Middlewares) {
+		s.log.Warn().Msg("rhttp: middlewares configuration changed, a restart is required to apply it")
+	}
+
+	for name := range s.names {
+		if _, ok := conf.Services[name]; !ok {
+			s.log.Warn().Msgf("rhttp: http service %s removed, a restart is required to apply it", name)
+		}
+	}
+
+	var failed []string
+	for name, svcConf := range conf.Services {
+		svc, ok := s.names[name]
+		if !ok {
+			s.log.Warn().Msgf("rhttp: http service %s added, a restart is required to apply it", name)
+			continue
+		}
+		oldConf := s.conf.Services[name]
+		if reflect.DeepEqual(svcConf, oldConf) {
+			continue
+		}
+		if svcConf["prefix"] != oldConf["prefix"] {
+			s.log.Warn().Msgf("rhttp: prefix of http service %s changed, a restart is required to apply it", name)
+			continue
+		}
+		r, ok := svc.(global.Reloader)
+		if !ok {
+			s.log.Warn().Msgf("rhttp: http service %s does not support reloading, a restart is required to apply it", name)
+			continue
+		}
+		if err := r.Reload(svcConf); err != nil {
+			s.log.Error().Err(err).Msgf("rhttp: error reloading http service %s", name)
+			failed = append(failed, name)
+			continue
+		}
+		s.conf.Services[name] = svcConf
+		s.log.Info().Msgf("rhttp: http service reloaded: %s", name)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("rhttp: error reloading http services: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
 // Network return the network type.
 func (s *Server) Network() string {
 	return s.conf.Network
@@ -203,6 +273,7 @@ func (s *Server) registerServices() error {
 			h := traceHandler(svcName, svc.Handler())
 			s.handlers[svc.Prefix()] = h
 			s.svcs[svc.Prefix()] = svc
+			s.names[svcName] = svc
 			s.unprotected = append(s.unprotected, getUnprotected(svc.Prefix(), svc.Unprotected())...)
 			s.log.Info().Msgf("http service enabled: %s@/%s", svcName, svc.Prefix())
 		} else {
@@ -96,7 +96,7 @@ func loadOrCreate(file string) (*shareModel, error) {
 		return nil, err
 	}
 
-	m := &shareModel{State: j.State}
+	m := &shareModel{State: j.State, Expirations: j.Expirations}
 	for _, s := range j.Shares {
 		var decShare collaboration.Share
 		if err = utils.UnmarshalJSONToProtoV1([]byte(s), &decShare); err != nil {
@@ -108,24 +108,29 @@ func loadOrCreate(file string) (*shareModel, error) {
 	if m.State == nil {
 		m.State = map[string]map[string]collaboration.ShareState{}
 	}
+	if m.Expirations == nil {
+		m.Expirations = map[string]*typespb.Timestamp{}
+	}
 
 	m.file = file
 	return m, nil
 }
 
 type shareModel struct {
-	file   string
-	State  map[string]map[string]collaboration.ShareState `json:"state"` // map[username]map[share_id]ShareState
-	Shares []*collaboration.Share                         `json:"shares"`
+	file        string
+	State       map[string]map[string]collaboration.ShareState `json:"state"`       // map[username]map[share_id]ShareState
+	Expirations map[string]*typespb.Timestamp                  `json:"expirations"` // map[share_id]Expiration
+	Shares      []*collaboration.Share                         `json:"shares"`
 }
 
 type jsonEncoding struct {
-	State  map[string]map[string]collaboration.ShareState `json:"state"` // map[username]map[share_id]ShareState
-	Shares []string                                       `json:"shares"`
+	State       map[string]map[string]collaboration.ShareState `json:"state"`       // map[username]map[share_id]ShareState
+	Expirations map[string]*typespb.Timestamp                  `json:"expirations"` // map[share_id]Expiration
+	Shares      []string                                       `json:"shares"`
 }
 
 func (m *shareModel) Save() error {
-	j := &jsonEncoding{State: m.State}
+	j := &jsonEncoding{State: m.State, Expirations: m.Expirations}
 	for _, s := range m.Shares {
 		encShare, err := utils.MarshalProtoV1ToJSON(s)
 		if err != nil {
@@ -177,6 +182,14 @@ func genID() string {
 }
 
 func (m *mgr) Share(ctx context.Context, md *provider.ResourceInfo, g *collaboration.ShareGrant) (*collaboration.Share, error) {
+	return m.share(ctx, md, g, nil)
+}
+
+func (m *mgr) ShareWithExpiration(ctx context.Context, md *provider.ResourceInfo, g *collaboration.ShareGrant, expiration *typespb.Timestamp) (*collaboration.Share, error) {
+	return m.share(ctx, md, g, expiration)
+}
+
+func (m *mgr) share(ctx context.Context, md *provider.ResourceInfo, g *collaboration.ShareGrant, expiration *typespb.Timestamp) (*collaboration.Share, error) {
 	id := genID()
 	user := ctxpkg.ContextMustGetUser(ctx)
 	now := time.Now().UnixNano()
@@ -222,6 +235,9 @@ func (m *mgr) Share(ctx context.Context, md *provider.ResourceInfo, g *collabora
 	defer m.Unlock()
 
 	m.model.Shares = append(m.model.Shares, s)
+	if expiration != nil {
+		m.model.Expirations[id] = expiration
+	}
 	if err := m.model.Save(); err != nil {
 		err = errors.Wrap(err, "error saving model")
 		return nil, err
@@ -300,6 +316,7 @@ func (m *mgr) Unshare(ctx context.Context, ref *collaboration.ShareReference) er
 			if share.IsCreatedByUser(s, user) {
 				m.model.Shares[len(m.model.Shares)-1], m.model.Shares[i] = m.model.Shares[i], m.model.Shares[len(m.model.Shares)-1]
 				m.model.Shares = m.model.Shares[:len(m.model.Shares)-1]
+				delete(m.model.Expirations, s.Id.OpaqueId)
 				if err := m.model.Save(); err != nil {
 					err = errors.Wrap(err, "error saving model")
 					return err
@@ -381,6 +398,9 @@ func (m *mgr) ListReceivedShares(ctx context.Context, filters []*collaboration.F
 			// omit shares created by the user or shares the user can't access
 			continue
 		}
+		if share.IsExpired(m.model.Expirations[s.Id.OpaqueId]) {
+			continue
+		}
 
 		if len(filters) == 0 {
 			rs := m.convert(ctx, s)
@@ -421,7 +441,7 @@ func (m *mgr) getReceived(ctx context.Context, ref *collaboration.ShareReference
 	user := ctxpkg.ContextMustGetUser(ctx)
 	for _, s := range m.model.Shares {
 		if sharesEqual(ref, s) {
-			if share.IsGrantedToUser(s, user) {
+			if share.IsGrantedToUser(s, user) && !share.IsExpired(m.model.Expirations[s.Id.OpaqueId]) {
 				rs := m.convert(ctx, s)
 				return rs, nil
 			}
@@ -467,3 +487,47 @@ func (m *mgr) UpdateReceivedShare(ctx context.Context, receivedShare *collaborat
 
 	return rs, nil
 }
+
+func (m *mgr) SetExpiration(ctx context.Context, ref *collaboration.ShareReference, expiration *typespb.Timestamp) error {
+	m.Lock()
+	defer m.Unlock()
+	user := ctxpkg.ContextMustGetUser(ctx)
+	for _, s := range m.model.Shares {
+		if sharesEqual(ref, s) && share.IsCreatedByUser(s, user) {
+			if expiration == nil {
+				delete(m.model.Expirations, s.Id.OpaqueId)
+			} else {
+				m.model.Expirations[s.Id.OpaqueId] = expiration
+			}
+			if err := m.model.Save(); err != nil {
+				return errors.Wrap(err, "error saving model")
+			}
+			return nil
+		}
+	}
+	return errtypes.NotFound(ref.String())
+}
+
+func (m *mgr) GetExpirations(ctx context.Context, ids []*collaboration.ShareId) (map[string]*typespb.Timestamp, error) {
+	m.Lock()
+	defer m.Unlock()
+	expirations := map[string]*typespb.Timestamp{}
+	for _, id := range ids {
+		if e, ok := m.model.Expirations[id.OpaqueId]; ok {
+			expirations[id.OpaqueId] = e
+		}
+	}
+	return expirations, nil
+}
+
+func (m *mgr) ListExpiredShares(ctx context.Context, before time.Time) ([]*collaboration.Share, error) {
+	m.Lock()
+	defer m.Unlock()
+	var ss []*collaboration.Share
+	for _, s := range m.model.Shares {
+		if e, ok := m.model.Expirations[s.Id.OpaqueId]; ok && time.Unix(int64(e.Seconds), int64(e.Nanos)).Before(before) {
+			ss = append(ss, s)
+		}
+	}
+	return ss, nil
+}
@@ -48,8 +48,9 @@ func init() {
 func New(c map[string]interface{}) (share.Manager, error) {
 	state := map[string]map[*collaboration.ShareId]collaboration.ShareState{}
 	return &manager{
-		shareState: state,
-		lock:       &sync.Mutex{},
+		shareState:  state,
+		expirations: map[string]*typespb.Timestamp{},
+		lock:        &sync.Mutex{},
 	}, nil
 }
 
@@ -59,15 +60,29 @@ type manager struct {
 	// shareState contains the share state for a user.
 	// map["alice"]["share-id"]state.
 	shareState map[string]map[*collaboration.ShareId]collaboration.ShareState
+	// expirations contains the expiration dates of the shares.
+	// map["share-id"]expiration.
+	expirations map[string]*typespb.Timestamp
 }
 
-func (m *manager) add(ctx context.Context, s *collaboration.Share) {
+func (m *manager) add(ctx context.Context, s *collaboration.Share, expiration *typespb.Timestamp) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 	m.shares = append(m.shares, s)
+	if expiration != nil {
+		m.expirations[s.Id.OpaqueId] = expiration
+	}
 }
 
 func (m *manager) Share(ctx context.Context, md *provider.ResourceInfo, g *collaboration.ShareGrant) (*collaboration.Share, error) {
+	return m.share(ctx, md, g, nil)
+}
+
+func (m *manager) ShareWithExpiration(ctx context.Context, md *provider.ResourceInfo, g *collaboration.ShareGrant, expiration *typespb.Timestamp) (*collaboration.Share, error) {
+	return m.share(ctx, md, g, expiration)
+}
+
+func (m *manager) share(ctx context.Context, md *provider.ResourceInfo, g *collaboration.ShareGrant, expiration *typespb.Timestamp) (*collaboration.Share, error) {
 	id := atomic.AddUint64(&counter, 1)
 	user := ctxpkg.ContextMustGetUser(ctx)
 	now := time.Now().UnixNano()
@@ -106,7 +121,7 @@ func (m *manager) Share(ctx context.Context, md *provider.ResourceInfo, g *colla
 		Mtime:       ts,
 	}
 
-	m.add(ctx, s)
+	m.add(ctx, s, expiration)
 	return s, nil
 }
 
@@ -175,6 +190,7 @@ func (m *manager) Unshare(ctx context.Context, ref *collaboration.ShareReference
 			if share.IsCreatedByUser(s, user) {
 				m.shares[len(m.shares)-1], m.shares[i] = m.shares[i], m.shares[len(m.shares)-1]
 				m.shares = m.shares[:len(m.shares)-1]
+				delete(m.expirations, s.Id.OpaqueId)
 				return nil
 			}
 		}
@@ -248,6 +264,9 @@ func (m *manager) ListReceivedShares(ctx context.Context, filters []*collaborati
 			// omit shares created by the user or shares the user can't access
 			continue
 		}
+		if share.IsExpired(m.expirations[s.Id.OpaqueId]) {
+			continue
+		}
 
 		if len(filters) == 0 {
 			rs := m.convert(ctx, s)
@@ -288,7 +307,7 @@ func (m *manager) getReceived(ctx context.Context, ref *collaboration.ShareRefer
 	user := ctxpkg.ContextMustGetUser(ctx)
 	for _, s := range m.shares {
 		if sharesEqual(ref, s) {
-			if share.IsGrantedToUser(s, user) {
+			if share.IsGrantedToUser(s, user) && !share.IsExpired(m.expirations[s.Id.OpaqueId]) {
 				rs := m.convert(ctx, s)
 				return rs, nil
 			}
@@ -329,3 +348,44 @@ func (m *manager) UpdateReceivedShare(ctx context.Context, receivedShare *collab
 
 	return rs, nil
 }
+
+func (m *manager) SetExpiration(ctx context.Context, ref *collaboration.ShareReference, expiration *typespb.Timestamp) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	user := ctxpkg.ContextMustGetUser(ctx)
+	for _, s := range m.shares {
+		if sharesEqual(ref, s) && share.IsCreatedByUser(s, user) {
+			if expiration == nil {
+				delete(m.expirations, s.Id.OpaqueId)
+			} else {
+				m.expirations[s.Id.OpaqueId] = expiration
+			}
+			return nil
+		}
+	}
+	return errtypes.NotFound(ref.String())
+}
+
+func (m *manager) GetExpirations(ctx context.Context, ids []*collaboration.ShareId) (map[string]*typespb.Timestamp, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	expirations := map[string]*typespb.Timestamp{}
+	for _, id := range ids {
+		if e, ok := m.expirations[id.OpaqueId]; ok {
+			expirations[id.OpaqueId] = e
+		}
+	}
+	return expirations, nil
+}
+
+func (m *manager) ListExpiredShares(ctx context.Context, before time.Time) ([]*collaboration.Share, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	var ss []*collaboration.Share
+	for _, s := range m.shares {
+		if e, ok := m.expirations[s.Id.OpaqueId]; ok && time.Unix(int64(e.Seconds), int64(e.Nanos)).Before(before) {
+			ss = append(ss, s)
+		}
+	}
+	return ss, nil
+}
@@ -46,6 +46,9 @@ import (
 const (
 	shareTypeUser  = 0
 	shareTypeGroup = 1
+
+	// expirationFormat is the format of the expiration column, in UTC.
+	expirationFormat = "2006-01-02 15:04:05"
 )
 
 func init() {
@@ -106,6 +109,14 @@ func parseConfig(m map[string]interface{}) (*config, error) {
 }
 
 func (m *mgr) Share(ctx context.Context, md *provider.ResourceInfo, g *collaboration.ShareGrant) (*collaboration.Share, error) {
+	return m.share(ctx, md, g, nil)
+}
+
+func (m *mgr) ShareWithExpiration(ctx context.Context, md *provider.ResourceInfo, g *collaboration.ShareGrant, expiration *typespb.Timestamp) (*collaboration.Share, error) {
+	return m.share(ctx, md, g, expiration)
+}
+
+func (m *mgr) share(ctx context.Context, md *provider.ResourceInfo, g *collaboration.ShareGrant, expiration *typespb.Timestamp) (*collaboration.Share, error) {
 	user := ctxpkg.ContextMustGetUser(ctx)
 
 	// do not allow share to myself or the owner if share is for a user
@@ -152,8 +163,13 @@ func (m *mgr) Share(ctx context.Context, md *provider.ResourceInfo, g *collabora
 		fileSource = 0
 	}
 
-	stmtString := "INSERT INTO oc_share (share_type,uid_owner,uid_initiator,item_type,item_source,file_source,permissions,stime,share_with,file_target) VALUES (?,?,?,?,?,?,?,?,?,?)"
-	stmtValues := []interface{}{shareType, owner, user.Username, itemType, itemSource, fileSource, permissions, now, shareWith, targetPath}
+	var expirationValue interface{}
+	if expiration != nil {
+		expirationValue = time.Unix(int64(expiration.Seconds), 0).UTC().Format(expirationFormat)
+	}
+
+	stmtString := "INSERT INTO oc_share (share_type,uid_owner,uid_initiator,item_type,item_source,file_source,permissions,stime,share_with,file_target,expiration) VALUES (?,?,?,?,?,?,?,?,?,?,?)"
+	stmtValues := []interface{}{shareType, owner, user.Username, itemType, itemSource, fileSource, permissions, now, shareWith, targetPath, expirationValue}
 
 	stmt, err := m.db.Prepare(stmtString)
 	if err != nil {
@@ -348,6 +364,8 @@ func (m *mgr) ListReceivedShares(ctx context.Context, filters []*collaboration.F
 	} else {
 		query += "AND (share_with=?)"
 	}
+	query += " AND (expiration IS NULL OR expiration > ?)"
+	params = append(params, time.Now().UTC().Format(expirationFormat))
 
 	filterQuery, filterParams, err := translateFilters(filters)
 	if err != nil {
@@ -493,6 +511,8 @@ func (m *mgr) getReceivedByID(ctx context.Context, id *collaboration.ShareId) (*
 	} else {
 		query += "AND (share_with=?)"
 	}
+	query += " AND (expiration IS NULL OR expiration > ?)"
+	params = append(params, time.Now().UTC().Format(expirationFormat))
 	if err := m.db.QueryRow(query, params...).Scan(&s.UIDOwner, &s.UIDInitiator, &s.ShareWith, &s.ItemSource, &s.STime, &s.Permissions, &s.ShareType, &s.State); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errtypes.NotFound(id.OpaqueId)
@@ -522,6 +542,8 @@ func (m *mgr) getReceivedByKey(ctx context.Context, key *collaboration.ShareKey)
 	} else {
 		query += "AND (share_with=?)"
 	}
+	query += " AND (expiration IS NULL OR expiration > ?)"
+	params = append(params, time.Now().UTC().Format(expirationFormat))
 
 	if err := m.db.QueryRow(query, params...).Scan(&s.UIDOwner, &s.UIDInitiator, &s.ShareWith, &s.ItemSource, &s.ID, &s.STime, &s.Permissions, &s.ShareType, &s.State); err != nil {
 		if err == sql.ErrNoRows {
@@ -532,6 +554,91 @@ func (m *mgr) getReceivedByKey(ctx context.Context, key *collaboration.ShareKey)
 	return m.convertToCS3ReceivedShare(ctx, s, m.storageMountID)
 }
 
+func (m *mgr) SetExpiration(ctx context.Context, ref *collaboration.ShareReference, expiration *typespb.Timestamp) error {
+	s, err := m.GetShare(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	var value interface{}
+	if expiration != nil {
+		value = time.Unix(int64(expiration.Seconds), 0).UTC().Format(expirationFormat)
+	}
+	stmt, err := m.db.Prepare("update oc_share set expiration=? where id=?")
+	if err != nil {
+		return err
+	}
+	_, err = stmt.Exec(value, s.Id.OpaqueId)
+	return err
+}
+
+func (m *mgr) GetExpirations(ctx context.Context, ids []*collaboration.ShareId) (map[string]*typespb.Timestamp, error) {
+	expirations := map[string]*typespb.Timestamp{}
+	if len(ids) == 0 {
+		return expirations, nil
+	}
+
+	params := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		params = append(params, id.OpaqueId)
+	}
+	query := "select id, coalesce(expiration, '') as expiration FROM oc_share WHERE expiration IS NOT NULL AND id in (?" + strings.Repeat(",?", len(ids)-1) + ")"
+	rows, err := m.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, expiration string
+		if err := rows.Scan(&id, &expiration); err != nil {
+			continue
+		}
+		if ts, err := parseExpiration(expiration); err == nil {
+			expirations[id] = ts
+		}
+	}
+	return expirations, rows.Err()
+}
+
+func (m *mgr) ListExpiredShares(ctx context.Context, before time.Time) ([]*collaboration.Share, error) {
+	query := "select coalesce(uid_owner, '') as uid_owner, coalesce(uid_initiator, '') as uid_initiator, coalesce(share_with, '') as share_with, coalesce(item_source, '') as item_source, id, stime, permissions, share_type FROM oc_share WHERE (share_type=? OR share_type=?) AND expiration IS NOT NULL AND expiration < ?"
+	rows, err := m.db.Query(query, shareTypeUser, shareTypeGroup, before.UTC().Format(expirationFormat))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var s DBShare
+	shares := []*collaboration.Share{}
+	for rows.Next() {
+		if err := rows.Scan(&s.UIDOwner, &s.UIDInitiator, &s.ShareWith, &s.ItemSource, &s.ID, &s.STime, &s.Permissions, &s.ShareType); err != nil {
+			continue
+		}
+		share, err := m.convertToCS3Share(ctx, s, m.storageMountID)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return shares, nil
+}
+
+func parseExpiration(expiration string) (*typespb.Timestamp, error) {
+	t, err := time.Parse(expirationFormat, expiration)
+	if err != nil {
+		// some drivers return the datetime in RFC 3339 format
+		if t, err = time.Parse(time.RFC3339, expiration); err != nil {
+			return nil, err
+		}
+	}
+	return &typespb.Timestamp{Seconds: uint64(t.Unix())}, nil
+}
+
 func granteeTypeToShareType(granteeType provider.GranteeType) int {
 	switch granteeType {
 	case provider.GranteeType_GRANTEE_TYPE_USER:
@@ -23,11 +23,13 @@ import (
 	"database/sql"
 	"io/ioutil"
 	"os"
+	"time"
 
 	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	ruser "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/share"
 	sqlmanager "github.com/cs3org/reva/pkg/share/manager/sql"
@@ -275,4 +277,78 @@ var _ = Describe("SQL manager", func() {
 			Expect(share.Permissions.Permissions.Delete).To(BeFalse())
 		})
 	})
+
+	Describe("Expiration", func() {
+		var em share.ExpirationManager
+
+		BeforeEach(func() {
+			em = mgr.(share.ExpirationManager)
+		})
+
+		It("stores the expiration date", func() {
+			expiration := &typespb.Timestamp{Seconds: uint64(time.Now().Add(time.Hour).Unix())}
+			err := em.SetExpiration(ctx, shareRef, expiration)
+			Expect(err).ToNot(HaveOccurred())
+
+			expirations, err := em.GetExpirations(ctx, []*collaboration.ShareId{shareRef.GetId()})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(expirations["1"].Seconds).To(Equal(expiration.Seconds))
+
+			err = em.SetExpiration(ctx, shareRef, nil)
+			Expect(err).ToNot(HaveOccurred())
+			expirations, err = em.GetExpirations(ctx, []*collaboration.ShareId{shareRef.GetId()})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(expirations).To(BeEmpty())
+		})
+
+		It("hides and lists expired shares", func() {
+			err := em.SetExpiration(ctx, shareRef, &typespb.Timestamp{Seconds: uint64(time.Now().Add(-time.Hour).Unix())})
+			Expect(err).ToNot(HaveOccurred())
+
+			expired, err := em.ListExpiredShares(ctx, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(expired)).To(Equal(1))
+			Expect(expired[0].Id.OpaqueId).To(Equal("1"))
+
+			loginAs(otherUser)
+			shares, err := mgr.ListReceivedShares(ctx, []*collaboration.Filter{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(shares)).To(Equal(0))
+			_, err = mgr.GetReceivedShare(ctx, shareRef)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("creates shares with an expiration date", func() {
+			grant := &collaboration.ShareGrant{
+				Grantee: &provider.Grantee{
+					Type: provider.GranteeType_GRANTEE_TYPE_USER,
+					Id: &provider.Grantee_UserId{UserId: &user.UserId{
+						OpaqueId: "someone",
+					}},
+				},
+				Permissions: &collaboration.SharePermissions{
+					Permissions: &provider.ResourcePermissions{Stat: true},
+				},
+			}
+			info := &provider.ResourceInfo{
+				Id: &provider.ResourceId{
+					StorageId: "/",
+					OpaqueId:  "something",
+				},
+			}
+			expiration := &typespb.Timestamp{Seconds: uint64(time.Now().Add(time.Hour).Unix())}
+			share, err := em.ShareWithExpiration(ctx, info, grant, expiration)
+			Expect(err).ToNot(HaveOccurred())
+
+			expirations, err := em.GetExpirations(ctx, []*collaboration.ShareId{share.Id})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(expirations[share.Id.OpaqueId].Seconds).To(Equal(expiration.Seconds))
+		})
+
+		It("only lets the owner set the expiration date", func() {
+			loginAs(&userpb.User{Id: &userpb.UserId{OpaqueId: "marie"}, Username: "marie"})
+			err := em.SetExpiration(ctx, shareRef, &typespb.Timestamp{Seconds: 1})
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
@@ -20,10 +20,14 @@ package share
 
 import (
 	"context"
+	"encoding/json"
+	"strconv"
+	"time"
 
 	userv1beta1 "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
 	"github.com/cs3org/reva/pkg/utils"
 	"google.golang.org/genproto/protobuf/field_mask"
@@ -59,6 +63,102 @@ type Manager interface {
 	UpdateReceivedShare(ctx context.Context, share *collaboration.ReceivedShare, fieldMask *field_mask.FieldMask) (*collaboration.ReceivedShare, error)
 }
 
+// ExpirationManager is implemented by share managers which can store an expiration date for shares.
+// Expired shares are no longer listed as received shares and are removed by the share provider.
+type ExpirationManager interface {
+	// ShareWithExpiration creates a share like Manager.Share which expires at the given date.
+	ShareWithExpiration(ctx context.Context, md *provider.ResourceInfo, g *collaboration.ShareGrant, expiration *typespb.Timestamp) (*collaboration.Share, error)
+
+	// SetExpiration sets the expiration date of a share created by the user in the context.
+	// A nil expiration removes it.
+	SetExpiration(ctx context.Context, ref *collaboration.ShareReference, expiration *typespb.Timestamp) error
+
+	// GetExpirations returns the expiration dates of the given shares, indexed by share id.
+	// Shares without an expiration date are omitted.
+	GetExpirations(ctx context.Context, ids []*collaboration.ShareId) (map[string]*typespb.Timestamp, error)
+
+	// ListExpiredShares returns the shares of all users which expired before the given time.
+	ListExpiredShares(ctx context.Context, before time.Time) ([]*collaboration.Share, error)
+}
+
+const (
+	// ExpirationOpaqueKey is the key of the opaque entry carrying the expiration date
+	// of a share in create and update requests, as unix timestamp in seconds.
+	// An empty value removes the expiration date on update.
+	ExpirationOpaqueKey = "expiration"
+
+	// ExpirationsOpaqueKey is the key of the opaque entry carrying the expiration dates
+	// of the shares in a response, indexed by share id.
+	ExpirationsOpaqueKey = "expirations"
+)
+
+// IsExpired checks if the given expiration date lies in the past.
+func IsExpired(expiration *typespb.Timestamp) bool {
+	return expiration != nil && time.Unix(int64(expiration.Seconds), int64(expiration.Nanos)).Before(time.Now())
+}
+
+// ExpirationFromOpaque returns the expiration date set in the opaque of a request.
+// The second return value tells whether the opaque carried an expiration at all.
+func ExpirationFromOpaque(o *typespb.Opaque) (*typespb.Timestamp, bool, error) {
+	entry, ok := o.GetMap()[ExpirationOpaqueKey]
+	if !ok {
+		return nil, false, nil
+	}
+	if len(entry.Value) == 0 {
+		return nil, true, nil
+	}
+	seconds, err := strconv.ParseUint(string(entry.Value), 10, 64)
+	if err != nil {
+		return nil, true, err
+	}
+	if seconds == 0 {
+		return nil, true, nil
+	}
+	return &typespb.Timestamp{Seconds: seconds}, true, nil
+}
+
+// ExpirationToOpaque adds the expiration date to the opaque of a request.
+// A nil expiration asks to remove an existing one.
+func ExpirationToOpaque(o *typespb.Opaque, expiration *typespb.Timestamp) *typespb.Opaque {
+	if o == nil {
+		o = &typespb.Opaque{}
+	}
+	if o.Map == nil {
+		o.Map = map[string]*typespb.OpaqueEntry{}
+	}
+	var value string
+	if expiration != nil {
+		value = strconv.FormatUint(expiration.Seconds, 10)
+	}
+	o.Map[ExpirationOpaqueKey] = &typespb.OpaqueEntry{Decoder: "plain", Value: []byte(value)}
+	return o
+}
+
+// ExpirationsFromOpaque returns the expiration dates of the shares in a response, indexed by share id.
+func ExpirationsFromOpaque(o *typespb.Opaque) map[string]*typespb.Timestamp {
+	expirations := map[string]*typespb.Timestamp{}
+	if entry, ok := o.GetMap()[ExpirationsOpaqueKey]; ok {
+		_ = json.Unmarshal(entry.Value, &expirations)
+	}
+	return expirations
+}
+
+// ExpirationsToOpaque returns an opaque carrying the expiration dates of the shares in a response.
+func ExpirationsToOpaque(expirations map[string]*typespb.Timestamp) (*typespb.Opaque, error) {
+	if len(expirations) == 0 {
+		return nil, nil
+	}
+	value, err := json.Marshal(expirations)
+	if err != nil {
+		return nil, err
+	}
+	return &typespb.Opaque{
+		Map: map[string]*typespb.OpaqueEntry{
+			ExpirationsOpaqueKey: {Decoder: "json", Value: value},
+		},
+	}, nil
+}
+
 // GroupGranteeFilter is an abstraction for creating filter by grantee type group.
 func GroupGranteeFilter() *collaboration.Filter {
 	return &collaboration.Filter{
@@ -20,11 +20,13 @@ package share
 
 import (
 	"testing"
+	"time"
 
 	groupv1beta1 "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
 	userv1beta1 "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 )
 
 func TestIsCreatedByUser(t *testing.T) {
@@ -211,3 +213,41 @@ func TestGroupFiltersByType(t *testing.T) {
 		}
 	}
 }
+
+func TestExpirationOpaque(t *testing.T) {
+	if _, ok, err := ExpirationFromOpaque(nil); ok || err != nil {
+		t.Errorf("Expected no expiration in empty opaque, got %v %v", ok, err)
+	}
+
+	o := ExpirationToOpaque(nil, &types.Timestamp{Seconds: 1234})
+	e, ok, err := ExpirationFromOpaque(o)
+	if !ok || err != nil || e.GetSeconds() != 1234 {
+		t.Errorf("Expected expiration 1234, got %v %v %v", e, ok, err)
+	}
+
+	o = ExpirationToOpaque(o, nil)
+	e, ok, err = ExpirationFromOpaque(o)
+	if !ok || err != nil || e != nil {
+		t.Errorf("Expected expiration to be removed, got %v %v %v", e, ok, err)
+	}
+
+	o, err = ExpirationsToOpaque(map[string]*types.Timestamp{"share": {Seconds: 42}})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if expirations := ExpirationsFromOpaque(o); expirations["share"].GetSeconds() != 42 {
+		t.Errorf("Expected expiration 42 for share, got %v", expirations)
+	}
+}
+
+func TestIsExpired(t *testing.T) {
+	if IsExpired(nil) {
+		t.Error("A missing expiration should not be expired")
+	}
+	if !IsExpired(&types.Timestamp{Seconds: uint64(time.Now().Add(-time.Minute).Unix())}) {
+		t.Error("A past expiration should be expired")
+	}
+	if IsExpired(&types.Timestamp{Seconds: uint64(time.Now().Add(time.Minute).Unix())}) {
+		t.Error("A future expiration should not be expired")
+	}
+}
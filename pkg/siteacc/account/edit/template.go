@@ -50,8 +50,37 @@ function verifyForm(formData) {
 	return true;
 }
 
+function handleChangeEmail() {
+	const formData = new FormData(document.getElementById("email-form"));
+	if (formData.getTrimmed("email") == "") {
+		setState(STATE_ERROR, "Please specify your new email address.", "email-form", "email", true);
+		return;
+	}
+
+	setState(STATE_STATUS, "Requesting email change... this should only take a moment.", "email-form", null, false);
+
+	var xhr = new XMLHttpRequest();
+    xhr.open("POST", "{{getServerAddress}}/change-email?invoker=user");
+    xhr.setRequestHeader('Content-Type', 'application/json; charset=UTF-8');
+
+	xhr.onload = function() {
+		if (this.status == 200) {
+			setState(STATE_SUCCESS, "A verification link has been sent to your new email address. Your email address will be changed once it has been verified.", "email-form", null, true);
+		} else {
+			var resp = JSON.parse(this.responseText);
+			setState(STATE_ERROR, "An error occurred while trying to change your email address:<br><em>" + resp.error + "</em>", "email-form", null, true);
+		}
+	}
+
+	var postData = {
+		"email": formData.getTrimmed("email")
+    };
+
+    xhr.send(JSON.stringify(postData));
+}
+
 function handleAction(action) {
-	const formData = new FormData(document.querySelector("form"));
+	const formData = new FormData(document.getElementById("form"));
 	if (!verifyForm(formData)) {
 		return;
 	}
@@ -100,7 +129,7 @@ html * {
 const tplBody = `
 <div>
 	<p>Edit your ScienceMesh account information below.</p>
-	<p>Please note that you cannot modify your email address using this form.</p>
+	<p>Please note that you cannot modify your email address using this form; use the form below the account information instead.</p>
 </div>
 <div>&nbsp;</div>
 <div>
@@ -152,6 +181,24 @@ const tplBody = `
 		</div>
 	</form>
 </div>
+<div>&nbsp;</div>
+<div>
+	<p>To change your email address, enter the new address below. A verification link will be sent to it; your address will only be changed once it has been verified.</p>
+</div>
+<div>
+	<form id="email-form" method="POST" class="box container-inline" style="width: 100%;" onSubmit="handleChangeEmail(); return false;">
+		<div style="grid-row: 1;"><label for="email">New email address:</label></div>
+		<div style="grid-row: 2;"><input type="text" id="email" name="email" placeholder="me@example.com" {{if .Account.Data.APIKey}}disabled{{end}}/></div>
+		<div style="grid-row: 2; grid-column: 2; text-align: right;">
+			<button type="submit" style="font-weight: bold;" {{if .Account.Data.APIKey}}disabled{{end}}>Change email</button>
+		</div>
+		{{if .Account.Data.APIKey}}
+		<div style="grid-row: 3; grid-column: 1 / span 2; font-style: italic; font-size: 0.8em;">
+			Your email address cannot be changed anymore, as an API key has already been assigned to your account.
+		</div>
+		{{end}}
+	</form>
+</div>
 <div>
 	<p>Go <a href="{{getServerAddress}}/account/?path=manage">back</a> to the main account page.</p>
 </div>
@@ -39,6 +39,25 @@ function handleRequestKey() {
 	window.location.replace("{{getServerAddress}}/account/?path=contact&subject=" + encodeURIComponent("Request API key"));
 }
 
+function handleRequestVerification() {
+	var xhr = new XMLHttpRequest();
+    xhr.open("POST", "{{getServerAddress}}/request-verification?invoker=user");
+    xhr.setRequestHeader('Content-Type', 'application/json; charset=UTF-8');
+
+	setState(STATE_STATUS, "Sending verification link...");
+
+	xhr.onload = function() {
+		if (this.status == 200) {
+			setState(STATE_SUCCESS, "A new verification link has been sent. Please check your inbox.");
+		} else {
+			var resp = JSON.parse(this.responseText);
+			setState(STATE_ERROR, "An error occurred while sending the verification link:<br><em>" + resp.error + "</em>");
+		}
+	}
+
+    xhr.send();
+}
+
 function handleLogout() {
 	var xhr = new XMLHttpRequest();
     xhr.open("GET", "{{getServerAddress}}/logout");
@@ -77,6 +96,16 @@ const tplBody = `
 	<p><strong>Hello {{.Account.FirstName}} {{.Account.LastName}},</strong></p>
 	<p>On this page, you can manage your ScienceMesh user account. This includes editing your personal information, requesting an API key or access to the GOCDB and more.</p>
 </div>
+{{if or (not .Account.Data.EmailVerified) .Account.IsEmailChangePending}}
+<div class="box status">
+	{{if .Account.IsEmailChangePending}}
+	The change of your email address to <em>{{.Account.Verification.Email}}</em> has not been verified yet.
+	{{else}}
+	Your email address has not been verified yet.
+	{{end}}
+	Please click the link in the verification email we have sent you, or <a href="#" onClick="handleRequestVerification();">request a new one</a>.
+</div>
+{{end}}
 <div>&nbsp;</div>
 <div>
 	<strong>Personal information:</strong>
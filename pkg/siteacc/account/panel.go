@@ -29,6 +29,7 @@ import (
 	"github.com/cs3org/reva/pkg/siteacc/account/manage"
 	"github.com/cs3org/reva/pkg/siteacc/account/registration"
 	"github.com/cs3org/reva/pkg/siteacc/account/settings"
+	"github.com/cs3org/reva/pkg/siteacc/account/verify"
 	"github.com/cs3org/reva/pkg/siteacc/config"
 	"github.com/cs3org/reva/pkg/siteacc/data"
 	"github.com/cs3org/reva/pkg/siteacc/html"
@@ -52,6 +53,7 @@ const (
 	templateEdit         = "edit"
 	templateContact      = "contact"
 	templateRegistration = "register"
+	templateVerify       = "verify"
 )
 
 func (panel *Panel) initialize(conf *config.Configuration, log *zerolog.Logger) error {
@@ -92,12 +94,16 @@ func (panel *Panel) initialize(conf *config.Configuration, log *zerolog.Logger)
 		return errors.Wrap(err, "unable to create the registration template")
 	}
 
+	if err := panel.htmlPanel.AddTemplate(templateVerify, &verify.PanelTemplate{}); err != nil {
+		return errors.Wrap(err, "unable to create the email verification template")
+	}
+
 	return nil
 }
 
 // GetActiveTemplate returns the name of the active template.
 func (panel *Panel) GetActiveTemplate(session *html.Session, path string) string {
-	validPaths := []string{templateLogin, templateManage, templateSettings, templateEdit, templateContact, templateRegistration}
+	validPaths := []string{templateLogin, templateManage, templateSettings, templateEdit, templateContact, templateRegistration, templateVerify}
 	template := templateLogin
 
 	// Only allow valid template paths; redirect to the login page otherwise
@@ -0,0 +1,58 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package verify
+
+const tplJavaScript = `
+function handleAction(action) {
+	setState(STATE_STATUS, "Verifying your email address... this should only take a moment.", "form", null, false);
+
+	var xhr = new XMLHttpRequest();
+    xhr.open("POST", "{{getServerAddress}}/" + action + "?token=" + encodeURIComponent("{{.Params.Token}}"));
+    xhr.setRequestHeader('Content-Type', 'application/json; charset=UTF-8');
+
+	xhr.onload = function() {
+		if (this.status == 200) {
+			setState(STATE_SUCCESS, "Your email address was successfully verified! You can now <a href=\"{{getServerAddress}}/account/?path=login\">log in</a> to your account.", "form", null, false);
+		} else {
+			var resp = JSON.parse(this.responseText);
+			setState(STATE_ERROR, "An error occurred while trying to verify your email address:<br><em>" + resp.error + "</em>", "form", null, true);
+		}
+	}
+
+    xhr.send();
+}
+`
+
+const tplStyleSheet = `
+html * {
+	font-family: arial !important;
+}
+`
+
+const tplBody = `
+<div>
+	<p>Click the button below to verify the email address of your ScienceMesh account.</p>
+</div>
+<div>&nbsp;</div>
+<div>
+	<form id="form" method="POST" class="box" style="width: 100%;" onSubmit="handleAction('verify-email'); return false;">
+		<button type="submit" style="font-weight: bold;" {{if not .Params.Token}}disabled{{end}}>Verify email address</button>
+	</form>
+</div>
+`
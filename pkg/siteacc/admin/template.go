@@ -75,7 +75,23 @@ const tplBody = `
 				<strong>Authorized:</strong> <em>{{if .Data.Authorized}}Yes{{else}}No{{end}}</em>
 				<br>
 				<strong>GOCDB access:</strong> <em>{{if .Data.GOCDBAccess}}Granted{{else}}Not granted{{end}}</em>
+				<br>
+				<strong>Email verified:</strong> <em>{{if .Data.EmailVerified}}Yes{{else}}No{{end}}{{if .IsEmailChangePending}} (change to {{.Verification.Email}} pending){{end}}</em>
+				<br>
+				<strong>Last activity:</strong> <em>{{.LastActivity.Format "Jan 02, 2006 15:04"}}</em>
+			</p>
+			{{if .AuditTrail}}
+			<p>
+				<details>
+					<summary><strong>Audit trail</strong> ({{.AuditTrail | len}} entries)</summary>
+					<ul style="padding-left: 1em;">
+					{{range .AuditTrail}}
+						<li>{{.Date.Format "Jan 02, 2006 15:04"}}: <strong>{{.Action}}</strong>{{if .Parameters}} ({{.Parameters}}){{end}} by {{.Actor}}{{if not .Succeeded}} <em>- failed: {{.Error}}</em>{{end}}</li>
+					{{end}}
+					</ul>
+				</details>
 			</p>
+			{{end}}
 			<p>
 				<form method="POST" style="width: 100%;">
 					<button type="button" onClick="handleAction('assign-api-key', '{{.Email}}');" {{if .Data.APIKey}}disabled{{end}}>Default API Key</button>
@@ -91,7 +107,7 @@ const tplBody = `
 				{{if .Data.Authorized}}
 					<button type="button" onClick="handleAction('authorize?status=false', '{{.Email}}');" {{if not .Data.APIKey}}disabled{{end}}>Unauthorize</button>
 				{{else}}
-					<button type="button" onClick="handleAction('authorize?status=true', '{{.Email}}');" {{if not .Data.APIKey}}disabled{{end}}>Authorize</button>
+					<button type="button" onClick="handleAction('authorize?status=true', '{{.Email}}');" {{if or (not .Data.APIKey) (not .Data.EmailVerified)}}disabled{{end}}>Authorize</button>
 				{{end}}
 
 					<span style="width: 25px;">&nbsp;</span>
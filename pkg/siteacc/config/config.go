@@ -34,6 +34,11 @@ type Configuration struct {
 		File struct {
 			File string `mapstructure:"file"`
 		} `mapstructure:"file"`
+
+		SQL struct {
+			Driver string `mapstructure:"driver"`
+			DSN    string `mapstructure:"dsn"`
+		} `mapstructure:"sql"`
 	} `mapstructure:"storage"`
 
 	Email struct {
@@ -41,6 +46,12 @@ type Configuration struct {
 		NotificationsMail string                      `mapstructure:"notifications_mail"`
 	} `mapstructure:"email"`
 
+	Lifecycle struct {
+		VerificationTimeout int `mapstructure:"verification_timeout"`
+		InactivityExpiry    int `mapstructure:"inactivity_expiry"`
+		ExpiryWarning       int `mapstructure:"expiry_warning"`
+	} `mapstructure:"lifecycle"`
+
 	Mentix struct {
 		URL                      string `mapstructure:"url"`
 		DataEndpoint             string `mapstructure:"data_endpoint"`
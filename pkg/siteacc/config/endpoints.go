@@ -54,6 +54,13 @@ const (
 	// EndpointContact is the endpoint path for sending contact emails
 	EndpointContact = "/contact"
 
+	// EndpointVerifyEmail is the endpoint path for verifying email addresses.
+	EndpointVerifyEmail = "/verify-email"
+	// EndpointRequestVerification is the endpoint path for (re-)sending email verification links.
+	EndpointRequestVerification = "/request-verification"
+	// EndpointChangeEmail is the endpoint path for changing the email address of an account.
+	EndpointChangeEmail = "/change-email"
+
 	// EndpointVerifyUserToken is the endpoint path for user token validation.
 	EndpointVerifyUserToken = "/verify-user-token"
 
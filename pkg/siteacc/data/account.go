@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/cs3org/reva/pkg/siteacc/password"
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 
 	"github.com/cs3org/reva/pkg/mentix/key"
@@ -41,11 +42,16 @@ type Account struct {
 
 	Password password.Password `json:"password"`
 
-	DateCreated  time.Time `json:"dateCreated"`
-	DateModified time.Time `json:"dateModified"`
+	DateCreated       time.Time `json:"dateCreated"`
+	DateModified      time.Time `json:"dateModified"`
+	DateLastActive    time.Time `json:"dateLastActive"`
+	DateExpiryWarning time.Time `json:"dateExpiryWarning"`
 
 	Data     AccountData     `json:"data"`
 	Settings AccountSettings `json:"settings"`
+
+	Verification *AccountVerification `json:"verification,omitempty"`
+	AuditTrail   []AuditEntry         `json:"auditTrail,omitempty"`
 }
 
 // AccountData holds additional data for a site account.
@@ -53,6 +59,15 @@ type AccountData struct {
 	APIKey      key.APIKey `json:"apiKey"`
 	GOCDBAccess bool       `json:"gocdbAccess"`
 	Authorized  bool       `json:"authorized"`
+
+	EmailVerified bool `json:"emailVerified"`
+}
+
+// AccountVerification holds a pending email address verification.
+type AccountVerification struct {
+	Email   string    `json:"email"`
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
 }
 
 // AccountSettings holds additional settings for a site account.
@@ -123,9 +138,43 @@ func (acc *Account) Clone(erasePassword bool) *Account {
 		clone.Password.Clear()
 	}
 
+	if acc.Verification != nil {
+		verification := *acc.Verification
+		if erasePassword {
+			// The verification token is a secret as well
+			verification.Token = ""
+		}
+		clone.Verification = &verification
+	}
+
+	clone.AuditTrail = append([]AuditEntry(nil), acc.AuditTrail...)
+
 	return &clone
 }
 
+// StartVerification creates a new pending verification for the given email address, replacing any previous one.
+func (acc *Account) StartVerification(email string, timeout time.Duration) *AccountVerification {
+	acc.Verification = &AccountVerification{
+		Email:   email,
+		Token:   uuid.NewString(),
+		Expires: time.Now().Add(timeout),
+	}
+	return acc.Verification
+}
+
+// IsEmailChangePending checks whether the account has a pending verification of a new email address.
+func (acc *Account) IsEmailChangePending() bool {
+	return acc.Verification != nil && !strings.EqualFold(acc.Verification.Email, acc.Email)
+}
+
+// LastActivity returns the time of the last known activity of the account.
+func (acc *Account) LastActivity() time.Time {
+	if acc.DateLastActive.After(acc.DateModified) {
+		return acc.DateLastActive
+	}
+	return acc.DateModified
+}
+
 // CheckScopeAccess checks whether the user can access the specified scope.
 func (acc *Account) CheckScopeAccess(scope string) bool {
 	hasAccess := false
@@ -199,19 +248,21 @@ func NewAccount(email string, title, firstName, lastName string, site, role stri
 	t := time.Now()
 
 	acc := &Account{
-		Email:        email,
-		Title:        title,
-		FirstName:    firstName,
-		LastName:     lastName,
-		Site:         site,
-		Role:         role,
-		PhoneNumber:  phoneNumber,
-		DateCreated:  t,
-		DateModified: t,
+		Email:          email,
+		Title:          title,
+		FirstName:      firstName,
+		LastName:       lastName,
+		Site:           site,
+		Role:           role,
+		PhoneNumber:    phoneNumber,
+		DateCreated:    t,
+		DateModified:   t,
+		DateLastActive: t,
 		Data: AccountData{
-			APIKey:      "",
-			GOCDBAccess: false,
-			Authorized:  false,
+			APIKey:        "",
+			GOCDBAccess:   false,
+			Authorized:    false,
+			EmailVerified: false,
 		},
 		Settings: AccountSettings{
 			ReceiveAlerts: false,
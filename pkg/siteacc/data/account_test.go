@@ -0,0 +1,86 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAccountVerification(t *testing.T) {
+	account := &Account{Email: "alice@example.com"}
+
+	verification := account.StartVerification("ALICE@example.com", time.Hour)
+	if verification.Token == "" || !verification.Expires.After(time.Now()) {
+		t.Fatalf("invalid verification: %+v", verification)
+	}
+	if account.IsEmailChangePending() {
+		t.Fatal("verifying the current address is no email change")
+	}
+
+	account.StartVerification("bob@example.com", time.Hour)
+	if account.Verification.Token == verification.Token {
+		t.Fatal("a new verification must use a new token")
+	}
+	if !account.IsEmailChangePending() {
+		t.Fatal("expected a pending email change")
+	}
+
+	clone := account.Clone(true)
+	if clone.Verification.Token != "" || clone.Verification.Email != "bob@example.com" {
+		t.Fatalf("verification token not erased in clone: %+v", clone.Verification)
+	}
+	if account.Verification.Token == "" {
+		t.Fatal("cloning must not modify the original account")
+	}
+}
+
+func TestAccountAuditTrail(t *testing.T) {
+	account := &Account{Email: "alice@example.com"}
+	for i := 0; i < MaxAuditEntries+10; i++ {
+		account.AddAuditEntry(AuditEntry{Date: time.Unix(int64(i), 0), Action: "authorize"})
+	}
+
+	if len(account.AuditTrail) != MaxAuditEntries {
+		t.Fatalf("expected %d audit entries, got %d", MaxAuditEntries, len(account.AuditTrail))
+	}
+	if first := account.AuditTrail[0].Date.Unix(); first != 10 {
+		t.Fatalf("expected the oldest entries to be dropped, first entry is %d", first)
+	}
+
+	clone := account.Clone(false)
+	clone.AuditTrail[0].Action = "remove"
+	if account.AuditTrail[0].Action != "authorize" {
+		t.Fatal("the audit trail must not be shared with clones")
+	}
+}
+
+func TestAccountLastActivity(t *testing.T) {
+	now := time.Now()
+	account := &Account{DateModified: now.Add(-time.Hour), DateLastActive: now}
+	if !account.LastActivity().Equal(now) {
+		t.Fatalf("expected the last activity to be %v, got %v", now, account.LastActivity())
+	}
+
+	// Accounts without any recorded activity fall back to their modification date
+	account.DateLastActive = time.Time{}
+	if !account.LastActivity().Equal(now.Add(-time.Hour)) {
+		t.Fatalf("expected the modification date, got %v", account.LastActivity())
+	}
+}
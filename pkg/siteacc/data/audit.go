@@ -0,0 +1,50 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package data
+
+import (
+	"time"
+)
+
+// AuditEntry records a single administrative action performed on an account.
+type AuditEntry struct {
+	Date       time.Time `json:"date"`
+	Actor      string    `json:"actor"`
+	Action     string    `json:"action"`
+	Parameters string    `json:"parameters,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+const (
+	// MaxAuditEntries is the maximum number of audit entries kept per account.
+	MaxAuditEntries = 100
+)
+
+// Succeeded checks whether the audited action was successful.
+func (entry AuditEntry) Succeeded() bool {
+	return entry.Error == ""
+}
+
+// AddAuditEntry appends the given entry to the audit trail of the account; only the most recent entries are kept.
+func (acc *Account) AddAuditEntry(entry AuditEntry) {
+	acc.AuditTrail = append(acc.AuditTrail, entry)
+	if n := len(acc.AuditTrail); n > MaxAuditEntries {
+		acc.AuditTrail = append([]AuditEntry(nil), acc.AuditTrail[n-MaxAuditEntries:]...)
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package data
+
+import (
+	"github.com/pkg/errors"
+)
+
+// MigrateAccounts copies all accounts from one storage to another, replacing all accounts in the target storage.
+// The number of migrated accounts is returned.
+func MigrateAccounts(from Storage, to Storage) (int, error) {
+	accounts, err := from.ReadAll()
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to read the source accounts")
+	}
+
+	// Reading the target first lets storages that only write what has changed replace the accounts they already hold;
+	// a target that cannot be read yet has nothing to replace
+	_, _ = to.ReadAll()
+	if err := to.WriteAll(accounts); err != nil {
+		return 0, errors.Wrap(err, "unable to write the target accounts")
+	}
+
+	return len(*accounts), nil
+}
@@ -0,0 +1,259 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package data
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+
+	// Provide the supported SQL drivers
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const sqlAccountsTable = "siteacc_accounts"
+
+// SQLStorage implements an SQL-based storage; SQLite and MySQL are supported.
+type SQLStorage struct {
+	Storage
+
+	conf *config.Configuration
+	log  *zerolog.Logger
+
+	db *sql.DB
+
+	// The serialized accounts as last written to the database, keyed by their (lowercase) email
+	written map[string]string
+	mutex   sync.Mutex
+}
+
+func (storage *SQLStorage) initialize(conf *config.Configuration, log *zerolog.Logger) error {
+	if conf == nil {
+		return errors.Errorf("no configuration provided")
+	}
+	storage.conf = conf
+
+	if log == nil {
+		return errors.Errorf("no logger provided")
+	}
+	storage.log = log
+
+	switch conf.Storage.SQL.Driver {
+	case "sqlite3", "mysql":
+	case "":
+		return errors.Errorf("no SQL driver set in the configuration")
+	default:
+		return errors.Errorf("unsupported SQL driver %v", conf.Storage.SQL.Driver)
+	}
+
+	if conf.Storage.SQL.DSN == "" {
+		return errors.Errorf("no DSN set in the configuration")
+	}
+
+	db, err := sql.Open(conf.Storage.SQL.Driver, conf.Storage.SQL.DSN)
+	if err != nil {
+		return errors.Wrap(err, "unable to open the database")
+	}
+	storage.db = db
+
+	// Create the accounts table if necessary
+	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS " + sqlAccountsTable + " (email VARCHAR(255) NOT NULL PRIMARY KEY, data TEXT NOT NULL)"); err != nil {
+		_ = db.Close()
+		return errors.Wrap(err, "unable to create the accounts table")
+	}
+
+	storage.written = make(map[string]string)
+
+	return nil
+}
+
+// ReadAll reads all stored accounts into the given data object.
+func (storage *SQLStorage) ReadAll() (*Accounts, error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	rows, err := storage.db.Query("SELECT email, data FROM " + sqlAccountsTable)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to query the accounts")
+	}
+	defer rows.Close()
+
+	accounts := &Accounts{}
+	written := make(map[string]string)
+	for rows.Next() {
+		var email, accountData string
+		if err := rows.Scan(&email, &accountData); err != nil {
+			return nil, errors.Wrap(err, "unable to read an account")
+		}
+
+		account := &Account{}
+		if err := json.Unmarshal([]byte(accountData), account); err != nil {
+			return nil, errors.Wrapf(err, "invalid account data for %v", email)
+		}
+		*accounts = append(*accounts, account)
+		written[email] = accountData
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "unable to read the accounts")
+	}
+
+	storage.written = written
+	return accounts, nil
+}
+
+// WriteAll writes all stored accounts from the given data object.
+// Only accounts that differ from what has already been written are touched; accounts that have been written before
+// but no longer exist are removed.
+func (storage *SQLStorage) WriteAll(accounts *Accounts) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	tx, err := storage.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "unable to begin a transaction")
+	}
+
+	written := make(map[string]string, len(*accounts))
+	for _, account := range *accounts {
+		key, accountData, err := serializeAccount(account)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+
+		if storage.written[key] != accountData {
+			if err := writeAccount(tx, key, accountData); err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+		}
+		written[key] = accountData
+	}
+
+	for key := range storage.written {
+		if _, ok := written[key]; !ok {
+			if err := deleteAccount(tx, key); err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "unable to commit the accounts")
+	}
+
+	storage.written = written
+	return nil
+}
+
+// WritesIncrementally tells that all changes are already stored by the incremental hooks.
+func (storage *SQLStorage) WritesIncrementally() bool {
+	return true
+}
+
+// AccountAdded is called when an account has been added.
+func (storage *SQLStorage) AccountAdded(account *Account) {
+	storage.storeAccount(account)
+}
+
+// AccountUpdated is called when an account has been updated.
+func (storage *SQLStorage) AccountUpdated(account *Account) {
+	storage.storeAccount(account)
+}
+
+// AccountRemoved is called when an account has been removed.
+func (storage *SQLStorage) AccountRemoved(account *Account) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	key := accountKey(account)
+	if err := deleteAccount(storage.db, key); err != nil {
+		storage.log.Warn().Err(err).Str("email", account.Email).Msg("error while removing account")
+		return
+	}
+	delete(storage.written, key)
+}
+
+// Close closes the underlying database.
+func (storage *SQLStorage) Close() error {
+	return storage.db.Close()
+}
+
+func (storage *SQLStorage) storeAccount(account *Account) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	key, accountData, err := serializeAccount(account)
+	if err == nil {
+		err = writeAccount(storage.db, key, accountData)
+	}
+	if err != nil {
+		storage.log.Warn().Err(err).Str("email", account.Email).Msg("error while storing account")
+		return
+	}
+	storage.written[key] = accountData
+}
+
+type sqlExecer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func writeAccount(db sqlExecer, key string, accountData string) error {
+	// REPLACE is understood by both SQLite and MySQL
+	if _, err := db.Exec("REPLACE INTO "+sqlAccountsTable+" (email, data) VALUES (?, ?)", key, accountData); err != nil {
+		return errors.Wrapf(err, "unable to write account %v", key)
+	}
+	return nil
+}
+
+func deleteAccount(db sqlExecer, key string) error {
+	if _, err := db.Exec("DELETE FROM "+sqlAccountsTable+" WHERE email=?", key); err != nil {
+		return errors.Wrapf(err, "unable to delete account %v", key)
+	}
+	return nil
+}
+
+func serializeAccount(account *Account) (string, string, error) {
+	accountData, err := json.Marshal(account)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "unable to serialize account %v", account.Email)
+	}
+	return accountKey(account), string(accountData), nil
+}
+
+func accountKey(account *Account) string {
+	// Accounts are identified by their email address, regardless of its case
+	return strings.ToLower(account.Email)
+}
+
+// NewSQLStorage creates a new SQL storage.
+func NewSQLStorage(conf *config.Configuration, log *zerolog.Logger) (*SQLStorage, error) {
+	storage := &SQLStorage{}
+	if err := storage.initialize(conf, log); err != nil {
+		return nil, errors.Wrapf(err, "unable to initialize the SQL storage")
+	}
+	return storage, nil
+}
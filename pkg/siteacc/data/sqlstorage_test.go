@@ -0,0 +1,176 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package data
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/rs/zerolog"
+)
+
+func newTestStorages(t *testing.T) (*FileStorage, *SQLStorage) {
+	dir := t.TempDir()
+
+	conf := &config.Configuration{}
+	conf.Storage.File.File = filepath.Join(dir, "accounts.json")
+	conf.Storage.SQL.Driver = "sqlite3"
+	conf.Storage.SQL.DSN = filepath.Join(dir, "accounts.db")
+	log := zerolog.Nop()
+
+	fileStorage, err := NewFileStorage(conf, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlStorage, err := NewSQLStorage(conf, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = sqlStorage.Close() })
+
+	return fileStorage, sqlStorage
+}
+
+func readEmails(t *testing.T, storage Storage) map[string]*Account {
+	accounts, err := storage.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	emails := make(map[string]*Account)
+	for _, account := range *accounts {
+		emails[account.Email] = account
+	}
+	return emails
+}
+
+func TestSQLStorageHooks(t *testing.T) {
+	_, storage := newTestStorages(t)
+
+	alice := &Account{Email: "alice@example.com", FirstName: "Alice"}
+	bob := &Account{Email: "bob@example.com", FirstName: "Bob"}
+	storage.AccountAdded(alice)
+	storage.AccountAdded(bob)
+
+	alice.Site = "site-a"
+	storage.AccountUpdated(alice)
+	storage.AccountRemoved(&Account{Email: "BOB@example.com"})
+
+	accounts := readEmails(t, storage)
+	if len(accounts) != 1 {
+		t.Fatalf("expected 1 account, got %d", len(accounts))
+	}
+	if accounts["alice@example.com"] == nil || accounts["alice@example.com"].Site != "site-a" {
+		t.Fatalf("account not updated: %+v", accounts["alice@example.com"])
+	}
+}
+
+func TestSQLStorageWriteAll(t *testing.T) {
+	_, storage := newTestStorages(t)
+
+	accounts := Accounts{
+		{Email: "alice@example.com"},
+		{Email: "bob@example.com"},
+	}
+	if err := storage.WriteAll(&accounts); err != nil {
+		t.Fatal(err)
+	}
+
+	accounts = Accounts{
+		{Email: "alice@example.com", Role: "admin"},
+		{Email: "carol@example.com"},
+	}
+	if err := storage.WriteAll(&accounts); err != nil {
+		t.Fatal(err)
+	}
+
+	stored := readEmails(t, storage)
+	if len(stored) != 2 || stored["bob@example.com"] != nil || stored["carol@example.com"] == nil {
+		t.Fatalf("unexpected accounts: %v", stored)
+	}
+	if stored["alice@example.com"].Role != "admin" {
+		t.Fatalf("account not updated: %+v", stored["alice@example.com"])
+	}
+}
+
+func TestSQLStorageWriteAllOnlyTouchesWrittenAccounts(t *testing.T) {
+	_, storage := newTestStorages(t)
+
+	// Only migrations replace accounts the storage has not written itself
+	if err := writeAccount(storage.db, "other@example.com", `{"email":"other@example.com"}`); err != nil {
+		t.Fatal(err)
+	}
+	accounts := Accounts{{Email: "alice@example.com"}}
+	if err := storage.WriteAll(&accounts); err != nil {
+		t.Fatal(err)
+	}
+
+	if stored := readEmails(t, storage); len(stored) != 2 || stored["other@example.com"] == nil {
+		t.Fatalf("unexpected accounts: %v", stored)
+	}
+}
+
+func TestMigrateAccounts(t *testing.T) {
+	fileStorage, sqlStorage := newTestStorages(t)
+
+	accounts := Accounts{
+		{Email: "alice@example.com", Data: AccountData{Authorized: true}},
+		{Email: "bob@example.com", Settings: AccountSettings{ReceiveAlerts: true}},
+	}
+	if err := fileStorage.WriteAll(&accounts); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := MigrateAccounts(fileStorage, sqlStorage); err != nil || n != 2 {
+		t.Fatalf("import failed: %d, %v", n, err)
+	}
+	stored := readEmails(t, sqlStorage)
+	if !stored["alice@example.com"].Data.Authorized || !stored["bob@example.com"].Settings.ReceiveAlerts {
+		t.Fatalf("account data not imported: %v", stored)
+	}
+
+	sqlStorage.AccountRemoved(accounts[0])
+	if n, err := MigrateAccounts(sqlStorage, fileStorage); err != nil || n != 1 {
+		t.Fatalf("export failed: %d, %v", n, err)
+	}
+	if stored := readEmails(t, fileStorage); len(stored) != 1 || stored["bob@example.com"] == nil {
+		t.Fatalf("unexpected exported accounts: %v", stored)
+	}
+}
+
+func TestMigrateAccountsIntoExistingDatabase(t *testing.T) {
+	fileStorage, sqlStorage := newTestStorages(t)
+
+	// Rows that the storage has not written itself, e.g. left over from an earlier import
+	if err := writeAccount(sqlStorage.db, "stale@example.com", `{"email":"stale@example.com"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	accounts := Accounts{{Email: "alice@example.com"}}
+	if err := fileStorage.WriteAll(&accounts); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := MigrateAccounts(fileStorage, sqlStorage); err != nil || n != 1 {
+		t.Fatalf("import failed: %d, %v", n, err)
+	}
+
+	if stored := readEmails(t, sqlStorage); len(stored) != 1 || stored["alice@example.com"] == nil {
+		t.Fatalf("unexpected imported accounts: %v", stored)
+	}
+}
@@ -32,3 +32,12 @@ type Storage interface {
 	// AccountRemoved is called when an account has been removed.
 	AccountRemoved(account *Account)
 }
+
+// IncrementalStorage is implemented by storages that store all changes in the incremental hooks already.
+// Such storages do not need to write all accounts after each change.
+type IncrementalStorage interface {
+	Storage
+
+	// WritesIncrementally tells whether all changes are stored by the incremental hooks.
+	WritesIncrementally() bool
+}
@@ -55,6 +55,21 @@ func SendAccountCreated(account *data.Account, recipients []string, params map[s
 	return send(recipients, "ScienceMesh: Site account created", accountCreatedTemplate, getEmailData(account, conf, params), conf.Email.SMTP)
 }
 
+// SendEmailVerification sends an email containing a link to verify the email address.
+func SendEmailVerification(account *data.Account, recipients []string, params map[string]string, conf config.Configuration) error {
+	return send(recipients, "ScienceMesh: Verify your email address", emailVerificationTemplate, getEmailData(account, conf, params), conf.Email.SMTP)
+}
+
+// SendAccountExpiryWarning sends an email about the upcoming expiry of an inactive account.
+func SendAccountExpiryWarning(account *data.Account, recipients []string, params map[string]string, conf config.Configuration) error {
+	return send(recipients, "ScienceMesh: Your account is about to expire", accountExpiryWarningTemplate, getEmailData(account, conf, params), conf.Email.SMTP)
+}
+
+// SendAccountExpired sends an email about the removal of an expired account.
+func SendAccountExpired(account *data.Account, recipients []string, params map[string]string, conf config.Configuration) error {
+	return send(recipients, "ScienceMesh: Your account has expired", accountExpiredTemplate, getEmailData(account, conf, params), conf.Email.SMTP)
+}
+
 // SendAPIKeyAssigned sends an email about API key assignment.
 func SendAPIKeyAssigned(account *data.Account, recipients []string, params map[string]string, conf config.Configuration) error {
 	return send(recipients, "ScienceMesh: Your API key", apiKeyAssignedTemplate, getEmailData(account, conf, params), conf.Email.SMTP)
@@ -32,6 +32,42 @@ Kind regards,
 The ScienceMesh Team
 `
 
+const emailVerificationTemplate = `
+Dear {{.Account.FirstName}} {{.Account.LastName}},
+
+Please verify your email address {{.Params.Email}} by visiting the following link:
+{{.AccountsAddress}}account/?path=verify&token={{.Params.Token}}
+
+This link is valid until {{.Params.Expires}}. If you did not request this, simply ignore this email.
+
+Kind regards,
+The ScienceMesh Team
+`
+
+const accountExpiryWarningTemplate = `
+Dear {{.Account.FirstName}} {{.Account.LastName}},
+
+Your ScienceMesh account has not been used for a long time and will expire on {{.Params.ExpiryDate}}.
+
+To keep your account, simply log in to your user account panel before then:
+{{.AccountsAddress}}
+
+Kind regards,
+The ScienceMesh Team
+`
+
+const accountExpiredTemplate = `
+Dear {{.Account.FirstName}} {{.Account.LastName}},
+
+Your ScienceMesh account has not been used for a long time and has therefore been removed.
+
+You can register a new account at any time by visiting the user account panel:
+{{.AccountsAddress}}
+
+Kind regards,
+The ScienceMesh Team
+`
+
 const apiKeyAssignedTemplate = `
 Dear {{.Account.FirstName}} {{.Account.LastName}},
 
@@ -25,7 +25,9 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/mentix/key"
 	"github.com/cs3org/reva/pkg/siteacc/config"
 	"github.com/cs3org/reva/pkg/siteacc/data"
@@ -47,6 +49,7 @@ type endpoint struct {
 	Handler         func(*SiteAccounts, endpoint, http.ResponseWriter, *http.Request, *html.Session)
 	MethodCallbacks map[string]methodCallback
 	IsPublic        bool
+	IsAudited       bool
 }
 
 func createMethodCallbacks(cbGet methodCallback, cbPost methodCallback) map[string]methodCallback {
@@ -66,35 +69,39 @@ func createMethodCallbacks(cbGet methodCallback, cbPost methodCallback) map[stri
 func getEndpoints() []endpoint {
 	endpoints := []endpoint{
 		// Form/panel endpoints
-		{config.EndpointAdministration, callAdministrationEndpoint, nil, false},
-		{config.EndpointAccount, callAccountEndpoint, nil, true},
+		{config.EndpointAdministration, callAdministrationEndpoint, nil, false, false},
+		{config.EndpointAccount, callAccountEndpoint, nil, true, false},
 		// API key endpoints
-		{config.EndpointGenerateAPIKey, callMethodEndpoint, createMethodCallbacks(handleGenerateAPIKey, nil), false},
-		{config.EndpointVerifyAPIKey, callMethodEndpoint, createMethodCallbacks(handleVerifyAPIKey, nil), false},
-		{config.EndpointAssignAPIKey, callMethodEndpoint, createMethodCallbacks(nil, handleAssignAPIKey), false},
+		{config.EndpointGenerateAPIKey, callMethodEndpoint, createMethodCallbacks(handleGenerateAPIKey, nil), false, false},
+		{config.EndpointVerifyAPIKey, callMethodEndpoint, createMethodCallbacks(handleVerifyAPIKey, nil), false, false},
+		{config.EndpointAssignAPIKey, callMethodEndpoint, createMethodCallbacks(nil, handleAssignAPIKey), false, true},
 		// General account endpoints
-		{config.EndpointList, callMethodEndpoint, createMethodCallbacks(handleList, nil), false},
-		{config.EndpointFind, callMethodEndpoint, createMethodCallbacks(handleFind, nil), false},
-		{config.EndpointCreate, callMethodEndpoint, createMethodCallbacks(nil, handleCreate), true},
-		{config.EndpointUpdate, callMethodEndpoint, createMethodCallbacks(nil, handleUpdate), false},
-		{config.EndpointConfigure, callMethodEndpoint, createMethodCallbacks(nil, handleConfigure), false},
-		{config.EndpointRemove, callMethodEndpoint, createMethodCallbacks(nil, handleRemove), false},
+		{config.EndpointList, callMethodEndpoint, createMethodCallbacks(handleList, nil), false, false},
+		{config.EndpointFind, callMethodEndpoint, createMethodCallbacks(handleFind, nil), false, false},
+		{config.EndpointCreate, callMethodEndpoint, createMethodCallbacks(nil, handleCreate), true, false},
+		{config.EndpointUpdate, callMethodEndpoint, createMethodCallbacks(nil, handleUpdate), false, false},
+		{config.EndpointConfigure, callMethodEndpoint, createMethodCallbacks(nil, handleConfigure), false, false},
+		{config.EndpointRemove, callMethodEndpoint, createMethodCallbacks(nil, handleRemove), false, true},
 		// Login endpoints
-		{config.EndpointLogin, callMethodEndpoint, createMethodCallbacks(nil, handleLogin), true},
-		{config.EndpointLogout, callMethodEndpoint, createMethodCallbacks(handleLogout, nil), true},
-		{config.EndpointResetPassword, callMethodEndpoint, createMethodCallbacks(nil, handleResetPassword), false},
-		{config.EndpointContact, callMethodEndpoint, createMethodCallbacks(nil, handleContact), false},
+		{config.EndpointLogin, callMethodEndpoint, createMethodCallbacks(nil, handleLogin), true, false},
+		{config.EndpointLogout, callMethodEndpoint, createMethodCallbacks(handleLogout, nil), true, false},
+		{config.EndpointResetPassword, callMethodEndpoint, createMethodCallbacks(nil, handleResetPassword), false, false},
+		{config.EndpointContact, callMethodEndpoint, createMethodCallbacks(nil, handleContact), false, false},
+		// Email verification endpoints
+		{config.EndpointVerifyEmail, callMethodEndpoint, createMethodCallbacks(nil, handleVerifyEmail), true, false},
+		{config.EndpointRequestVerification, callMethodEndpoint, createMethodCallbacks(nil, handleRequestVerification), false, false},
+		{config.EndpointChangeEmail, callMethodEndpoint, createMethodCallbacks(nil, handleChangeEmail), false, false},
 		// Authentication endpoints
-		{config.EndpointVerifyUserToken, callMethodEndpoint, createMethodCallbacks(handleVerifyUserToken, nil), true},
+		{config.EndpointVerifyUserToken, callMethodEndpoint, createMethodCallbacks(handleVerifyUserToken, nil), true, false},
 		// Authorization endpoints
-		{config.EndpointAuthorize, callMethodEndpoint, createMethodCallbacks(nil, handleAuthorize), false},
-		{config.EndpointIsAuthorized, callMethodEndpoint, createMethodCallbacks(handleIsAuthorized, nil), false},
+		{config.EndpointAuthorize, callMethodEndpoint, createMethodCallbacks(nil, handleAuthorize), false, true},
+		{config.EndpointIsAuthorized, callMethodEndpoint, createMethodCallbacks(handleIsAuthorized, nil), false, false},
 		// Access management endpoints
-		{config.EndpointGrantGOCDBAccess, callMethodEndpoint, createMethodCallbacks(nil, handleGrantGOCDBAccess), false},
+		{config.EndpointGrantGOCDBAccess, callMethodEndpoint, createMethodCallbacks(nil, handleGrantGOCDBAccess), false, true},
 		// Alerting endpoints
-		{config.EndpointDispatchAlert, callMethodEndpoint, createMethodCallbacks(nil, handleDispatchAlert), false},
+		{config.EndpointDispatchAlert, callMethodEndpoint, createMethodCallbacks(nil, handleDispatchAlert), false, false},
 		// Account site endpoints
-		{config.EndpointUnregisterSite, callMethodEndpoint, createMethodCallbacks(nil, handleUnregisterSite), false},
+		{config.EndpointUnregisterSite, callMethodEndpoint, createMethodCallbacks(nil, handleUnregisterSite), false, true},
 	}
 
 	return endpoints
@@ -135,7 +142,8 @@ func callMethodEndpoint(siteacc *SiteAccounts, ep endpoint, w http.ResponseWrite
 			if method == r.Method {
 				body, _ := ioutil.ReadAll(r.Body)
 
-				if respData, err := cb(siteacc, r.URL.Query(), body, session); err == nil {
+				respData, err := cb(siteacc, r.URL.Query(), body, session)
+				if err == nil {
 					resp.Success = true
 					resp.Error = ""
 					resp.Data = respData
@@ -144,6 +152,11 @@ func callMethodEndpoint(siteacc *SiteAccounts, ep endpoint, w http.ResponseWrite
 					resp.Error = fmt.Sprintf("%v", err)
 					resp.Data = nil
 				}
+
+				// Keep track of all administrative actions, successful or not
+				if ep.IsAudited {
+					recordAdminAction(siteacc, ep, r, body, err)
+				}
 			}
 		}
 	}
@@ -195,6 +208,9 @@ func handleVerifyAPIKey(siteacc *SiteAccounts, values url.Values, body []byte, s
 	if err != nil {
 		return nil, errors.Wrap(err, "invalid API key")
 	}
+
+	// Using the API key counts as account activity
+	siteacc.AccountsManager().RecordActivity(email)
 	return nil, nil
 }
 
@@ -373,6 +389,51 @@ func handleContact(siteacc *SiteAccounts, values url.Values, body []byte, sessio
 	return nil, nil
 }
 
+func handleVerifyEmail(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	// Verify the email address through the accounts manager
+	if err := siteacc.AccountsManager().VerifyEmail(values.Get("token")); err != nil {
+		return nil, errors.Wrap(err, "unable to verify the email address")
+	}
+
+	return nil, nil
+}
+
+func handleRequestVerification(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	email, _, err := processInvoker(siteacc, values, session)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send a new verification link through the accounts manager
+	if err := siteacc.AccountsManager().RequestEmailVerification(&data.Account{Email: email}); err != nil {
+		return nil, errors.Wrap(err, "unable to request an email verification")
+	}
+
+	return nil, nil
+}
+
+func handleChangeEmail(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
+	type jsonData struct {
+		Email string `json:"email"`
+	}
+	emailData := &jsonData{}
+	if err := json.Unmarshal(body, emailData); err != nil {
+		return nil, errors.Wrap(err, "invalid form data")
+	}
+
+	email, _, err := processInvoker(siteacc, values, session)
+	if err != nil {
+		return nil, err
+	}
+
+	// Request the email change through the accounts manager
+	if err := siteacc.AccountsManager().ChangeEmail(&data.Account{Email: email}, emailData.Email); err != nil {
+		return nil, errors.Wrap(err, "unable to change the email address")
+	}
+
+	return nil, nil
+}
+
 func handleVerifyUserToken(siteacc *SiteAccounts, values url.Values, body []byte, session *html.Session) (interface{}, error) {
 	token := values.Get("token")
 	if token == "" {
@@ -476,6 +537,44 @@ func unmarshalRequestData(body []byte) (*data.Account, error) {
 	return account, nil
 }
 
+func recordAdminAction(siteacc *SiteAccounts, ep endpoint, r *http.Request, body []byte, actionErr error) {
+	account, err := unmarshalRequestData(body)
+	if err != nil {
+		return
+	}
+
+	entry := data.AuditEntry{
+		Date:       time.Now(),
+		Actor:      getRequestActor(r),
+		Action:     strings.TrimPrefix(ep.Path, "/"),
+		Parameters: r.URL.RawQuery,
+	}
+	if actionErr != nil {
+		entry.Error = actionErr.Error()
+	}
+
+	siteacc.log.Info().Str("actor", entry.Actor).Str("action", entry.Action).Str("parameters", entry.Parameters).Str("email", account.Email).AnErr("error", actionErr).Msg("administrative action performed")
+
+	// Removed accounts have no audit trail anymore, so the action is only logged in this case
+	if err := siteacc.AccountsManager().RecordAdminAction(account, entry); err != nil {
+		siteacc.log.Debug().Err(err).Str("email", account.Email).Msg("unable to add the administrative action to the audit trail")
+	}
+}
+
+func getRequestActor(r *http.Request) string {
+	// Administrative endpoints are protected, so the acting user is usually known
+	if user, ok := ctxpkg.ContextGetUser(r.Context()); ok {
+		if user.Username != "" {
+			return user.Username
+		}
+		if user.Id != nil {
+			return user.Id.OpaqueId
+		}
+	}
+
+	return fmt.Sprintf("unknown (%v)", r.RemoteAddr)
+}
+
 func findAccount(siteacc *SiteAccounts, by string, value string) (*data.Account, error) {
 	if len(by) == 0 && len(value) == 0 {
 		return nil, errors.Errorf("missing search criteria")
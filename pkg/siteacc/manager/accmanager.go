@@ -56,6 +56,8 @@ type AccountsManager struct {
 
 	smtp *smtpclient.SMTPCredentials
 
+	quit chan struct{}
+
 	mutex sync.RWMutex
 }
 
@@ -92,12 +94,21 @@ func (mngr *AccountsManager) initialize(conf *config.Configuration, log *zerolog
 		mngr.smtp = smtpclient.NewSMTPCredentials(conf.Email.SMTP)
 	}
 
+	// Periodically remove inactive accounts if enabled
+	mngr.quit = make(chan struct{})
+	if conf.Lifecycle.InactivityExpiry > 0 {
+		go mngr.runExpiryChecks()
+	}
+
 	return nil
 }
 
 func (mngr *AccountsManager) createStorage(driver string) (data.Storage, error) {
-	if driver == "file" {
+	switch driver {
+	case "file":
 		return data.NewFileStorage(mngr.conf, mngr.log)
+	case "sql":
+		return data.NewSQLStorage(mngr.conf, mngr.log)
 	}
 
 	return nil, errors.Errorf("unknown storage driver %v", driver)
@@ -106,6 +117,26 @@ func (mngr *AccountsManager) createStorage(driver string) (data.Storage, error)
 func (mngr *AccountsManager) readAllAccounts() {
 	if accounts, err := mngr.storage.ReadAll(); err == nil {
 		mngr.accounts = *accounts
+
+		// Accounts created before email verification was introduced are considered to be verified
+		for _, account := range mngr.accounts {
+			if account.Verification == nil && !account.Data.EmailVerified {
+				account.Data.EmailVerified = true
+			}
+		}
+
+		// Accounts created before activity tracking was introduced are considered to be active now, so that they do not expire right away
+		activityAdded := false
+		for _, account := range mngr.accounts {
+			if account.DateLastActive.IsZero() {
+				account.DateLastActive = time.Now()
+				mngr.storage.AccountUpdated(account)
+				activityAdded = true
+			}
+		}
+		if activityAdded {
+			mngr.writeAllAccounts()
+		}
 	} else {
 		// Just warn when not being able to read accounts
 		mngr.log.Warn().Err(err).Msg("error while reading accounts")
@@ -113,6 +144,11 @@ func (mngr *AccountsManager) readAllAccounts() {
 }
 
 func (mngr *AccountsManager) writeAllAccounts() {
+	// All changes have already been stored through the incremental hooks
+	if storage, ok := mngr.storage.(data.IncrementalStorage); ok && storage.WritesIncrementally() {
+		return
+	}
+
 	if err := mngr.storage.WriteAll(&mngr.accounts); err != nil {
 		// Just warn when not being able to write accounts
 		mngr.log.Warn().Err(err).Msg("error while writing accounts")
@@ -166,11 +202,14 @@ func (mngr *AccountsManager) CreateAccount(accountData *data.Account) error {
 	}
 
 	if account, err := data.NewAccount(accountData.Email, accountData.Title, accountData.FirstName, accountData.LastName, accountData.Site, accountData.Role, accountData.PhoneNumber, accountData.Password.Value); err == nil {
+		mngr.startVerification(account, account.Email)
+
 		mngr.accounts = append(mngr.accounts, account)
 		mngr.storage.AccountAdded(account)
 		mngr.writeAllAccounts()
 
 		mngr.sendEmail(account, nil, email.SendAccountCreated)
+		mngr.sendVerificationEmail(account)
 		mngr.callListeners(account, AccountsListener.AccountCreated)
 	} else {
 		return errors.Wrap(err, "error while creating account")
@@ -276,6 +315,10 @@ func (mngr *AccountsManager) AuthorizeAccount(accountData *data.Account, authori
 		return errors.Wrap(err, "no account with the specified email exists")
 	}
 
+	if authorized && !account.Data.EmailVerified {
+		return errors.Errorf("the email address of the account has not been verified yet")
+	}
+
 	authorizedOld := account.Data.Authorized
 	account.Data.Authorized = authorized
 
@@ -421,7 +464,16 @@ func (mngr *AccountsManager) callListeners(account *data.Account, cb AccountsLis
 }
 
 func (mngr *AccountsManager) sendEmail(account *data.Account, params map[string]string, sendFunc email.SendFunction) {
-	_ = sendFunc(account, []string{account.Email, mngr.conf.Email.NotificationsMail}, params, *mngr.conf)
+	mngr.sendEmailTo(account, []string{account.Email, mngr.conf.Email.NotificationsMail}, params, sendFunc)
+}
+
+func (mngr *AccountsManager) sendEmailTo(account *data.Account, recipients []string, params map[string]string, sendFunc email.SendFunction) {
+	_ = sendFunc(account, recipients, params, *mngr.conf)
+}
+
+// Close stops all background tasks of the accounts manager.
+func (mngr *AccountsManager) Close() {
+	close(mngr.quit)
 }
 
 // NewAccountsManager creates a new accounts manager instance.
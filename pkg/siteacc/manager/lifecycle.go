@@ -0,0 +1,284 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package manager
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/siteacc/data"
+	"github.com/cs3org/reva/pkg/siteacc/email"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+const (
+	expiryCheckInterval    = time.Hour
+	activityUpdateInterval = time.Hour
+
+	emailDateFormat = "Jan 02, 2006 15:04 MST"
+)
+
+// RequestEmailVerification (re-)sends the verification link for the (pending) email address of the account identified by the account email.
+func (mngr *AccountsManager) RequestEmailVerification(accountData *data.Account) error {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	account, err := mngr.findAccount(FindByEmail, accountData.Email)
+	if err != nil {
+		return errors.Wrap(err, "no account with the specified email exists")
+	}
+
+	verifyEmail := account.Email
+	if account.IsEmailChangePending() {
+		verifyEmail = account.Verification.Email
+	} else if account.Data.EmailVerified {
+		return errors.Errorf("the email address has already been verified")
+	}
+
+	mngr.startVerification(account, verifyEmail)
+
+	mngr.storage.AccountUpdated(account)
+	mngr.writeAllAccounts()
+
+	mngr.sendVerificationEmail(account)
+
+	return nil
+}
+
+// ChangeEmail requests to change the email address of the account identified by the account email; the change only takes effect once the new address has been verified.
+func (mngr *AccountsManager) ChangeEmail(accountData *data.Account, newEmail string) error {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	account, err := mngr.findAccount(FindByEmail, accountData.Email)
+	if err != nil {
+		return errors.Wrap(err, "no account with the specified email exists")
+	}
+
+	newEmail = strings.TrimSpace(newEmail)
+	if !utils.IsEmailValid(newEmail) {
+		return errors.Errorf("invalid email address: %v", newEmail)
+	}
+	if strings.EqualFold(newEmail, account.Email) {
+		return errors.Errorf("the new email address equals the current one")
+	}
+	if err := mngr.verifyEmailChange(account, newEmail); err != nil {
+		return err
+	}
+
+	mngr.startVerification(account, newEmail)
+
+	mngr.storage.AccountUpdated(account)
+	mngr.writeAllAccounts()
+
+	mngr.sendVerificationEmail(account)
+
+	return nil
+}
+
+// VerifyEmail verifies an email address using the token sent to it; if the verification belongs to an address change, the email address of the account is changed.
+func (mngr *AccountsManager) VerifyEmail(token string) error {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	if token == "" {
+		return errors.Errorf("no verification token provided")
+	}
+
+	account := mngr.findAccountByPredicate(func(account *data.Account) bool {
+		return account.Verification != nil && account.Verification.Token == token
+	})
+	if account == nil {
+		return errors.Errorf("invalid verification token")
+	}
+
+	if time.Now().After(account.Verification.Expires) {
+		return errors.Errorf("the verification link has expired")
+	}
+
+	if account.IsEmailChangePending() {
+		newEmail := account.Verification.Email
+		if err := mngr.verifyEmailChange(account, newEmail); err != nil {
+			return err
+		}
+
+		// The email address identifies the account, so the old account is removed and a new one added
+		oldAccount := account.Clone(false)
+		account.Email = newEmail
+		mngr.finishVerification(account)
+
+		mngr.storage.AccountRemoved(oldAccount)
+		mngr.storage.AccountAdded(account)
+		mngr.writeAllAccounts()
+
+		mngr.callListeners(oldAccount, AccountsListener.AccountRemoved)
+		mngr.callListeners(account, AccountsListener.AccountCreated)
+	} else {
+		mngr.finishVerification(account)
+
+		mngr.storage.AccountUpdated(account)
+		mngr.writeAllAccounts()
+
+		mngr.callListeners(account, AccountsListener.AccountUpdated)
+	}
+
+	return nil
+}
+
+// RecordAdminAction adds an entry to the audit trail of the account identified by the account email.
+func (mngr *AccountsManager) RecordAdminAction(accountData *data.Account, entry data.AuditEntry) error {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	account, err := mngr.findAccount(FindByEmail, accountData.Email)
+	if err != nil {
+		return errors.Wrap(err, "no account with the specified email exists")
+	}
+
+	if entry.Date.IsZero() {
+		entry.Date = time.Now()
+	}
+	account.AddAuditEntry(entry)
+
+	mngr.storage.AccountUpdated(account)
+	mngr.writeAllAccounts()
+
+	return nil
+}
+
+func (mngr *AccountsManager) verifyEmailChange(account *data.Account, newEmail string) error {
+	// The site ID is derived from the email address, so it cannot be changed once a key has been assigned
+	if account.Data.APIKey != "" {
+		return errors.Errorf("the email address cannot be changed once an API key has been assigned")
+	}
+
+	if other, _ := mngr.findAccount(FindByEmail, newEmail); other != nil && other != account {
+		return errors.Errorf("an account with the specified email address already exists")
+	}
+
+	return nil
+}
+
+func (mngr *AccountsManager) startVerification(account *data.Account, verifyEmail string) {
+	account.StartVerification(verifyEmail, time.Duration(mngr.conf.Lifecycle.VerificationTimeout)*time.Hour)
+}
+
+func (mngr *AccountsManager) finishVerification(account *data.Account) {
+	account.Verification = nil
+	account.Data.EmailVerified = true
+	account.DateModified = time.Now()
+}
+
+func (mngr *AccountsManager) sendVerificationEmail(account *data.Account) {
+	// The verification link must only be sent to the address being verified
+	params := map[string]string{
+		"Email":   account.Verification.Email,
+		"Token":   account.Verification.Token,
+		"Expires": account.Verification.Expires.Format(emailDateFormat),
+	}
+	mngr.sendEmailTo(account, []string{account.Verification.Email}, params, email.SendEmailVerification)
+}
+
+// RecordActivity records that the account has just been used, which postpones its expiry.
+func (mngr *AccountsManager) RecordActivity(accountEmail string) {
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	account, err := mngr.findAccount(FindByEmail, accountEmail)
+	if err != nil {
+		return
+	}
+
+	// Only store the activity from time to time to avoid writing the accounts on every request
+	if time.Since(account.DateLastActive) < activityUpdateInterval {
+		return
+	}
+	account.DateLastActive = time.Now()
+
+	mngr.storage.AccountUpdated(account)
+	mngr.writeAllAccounts()
+}
+
+func (mngr *AccountsManager) expireAccounts() {
+	if mngr.conf.Lifecycle.InactivityExpiry <= 0 {
+		return
+	}
+
+	mngr.mutex.Lock()
+	defer mngr.mutex.Unlock()
+
+	expiry := time.Duration(mngr.conf.Lifecycle.InactivityExpiry) * 24 * time.Hour
+	warning := time.Duration(mngr.conf.Lifecycle.ExpiryWarning) * 24 * time.Hour
+	now := time.Now()
+
+	accounts := make(data.Accounts, 0, len(mngr.accounts))
+	modified := false
+	for _, account := range mngr.accounts {
+		expiryDate := account.LastActivity().Add(expiry)
+		warned := account.DateExpiryWarning.After(account.LastActivity())
+
+		// Accounts are only removed once their owner has been warned early enough
+		if !now.Before(expiryDate) && warned && !now.Before(account.DateExpiryWarning.Add(warning)) {
+			mngr.log.Info().Str("email", account.Email).Time("last-activity", account.LastActivity()).Msg("removing expired account")
+
+			mngr.storage.AccountRemoved(account)
+			mngr.sendEmail(account, nil, email.SendAccountExpired)
+			mngr.callListeners(account, AccountsListener.AccountRemoved)
+			modified = true
+			continue
+		}
+
+		// Only warn once per period of inactivity
+		if now.Add(warning).After(expiryDate) && !warned {
+			account.DateExpiryWarning = now
+
+			// Accounts that have not been warned in time are kept until the full warning period has passed
+			if removalDate := now.Add(warning); removalDate.After(expiryDate) {
+				expiryDate = removalDate
+			}
+
+			mngr.storage.AccountUpdated(account)
+			mngr.sendEmailTo(account, []string{account.Email}, map[string]string{"ExpiryDate": expiryDate.Format(emailDateFormat)}, email.SendAccountExpiryWarning)
+			modified = true
+		}
+
+		accounts = append(accounts, account)
+	}
+
+	if modified {
+		mngr.accounts = accounts
+		mngr.writeAllAccounts()
+	}
+}
+
+func (mngr *AccountsManager) runExpiryChecks() {
+	ticker := time.NewTicker(expiryCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		mngr.expireAccounts()
+
+		select {
+		case <-ticker.C:
+		case <-mngr.quit:
+			return
+		}
+	}
+}
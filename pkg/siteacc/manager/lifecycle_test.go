@@ -0,0 +1,249 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package manager
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cs3org/reva/pkg/siteacc/config"
+	"github.com/cs3org/reva/pkg/siteacc/data"
+	"github.com/cs3org/reva/pkg/siteacc/password"
+	"github.com/rs/zerolog"
+)
+
+func newTestAccountsManager(t *testing.T) *AccountsManager {
+	conf := &config.Configuration{}
+	conf.Storage.Driver = "file"
+	conf.Storage.File.File = filepath.Join(t.TempDir(), "accounts.json")
+	conf.Lifecycle.VerificationTimeout = 1
+	log := zerolog.Nop()
+
+	mngr, err := NewAccountsManager(conf, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mngr.Close)
+	return mngr
+}
+
+func createTestAccount(t *testing.T, mngr *AccountsManager, email string) *data.Account {
+	accountData := &data.Account{
+		Email:     email,
+		Title:     "Mr",
+		FirstName: "John",
+		LastName:  "Doe",
+		Site:      "site",
+		Role:      "Admin",
+		Password:  password.Password{Value: "Secret123"},
+	}
+	if err := mngr.CreateAccount(accountData); err != nil {
+		t.Fatal(err)
+	}
+
+	account, err := mngr.FindAccountEx(FindByEmail, email, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return account
+}
+
+func TestVerifyEmail(t *testing.T) {
+	mngr := newTestAccountsManager(t)
+	account := createTestAccount(t, mngr, "john@example.com")
+
+	if account.Data.EmailVerified || account.Verification == nil {
+		t.Fatal("new accounts must have a pending verification")
+	}
+	if err := mngr.AuthorizeAccount(account, true); err == nil {
+		t.Fatal("unverified accounts must not be authorized")
+	}
+
+	if err := mngr.VerifyEmail("invalid"); err == nil {
+		t.Fatal("expected an invalid token to be rejected")
+	}
+
+	account.Verification.Expires = time.Now().Add(-time.Minute)
+	if err := mngr.VerifyEmail(account.Verification.Token); err == nil {
+		t.Fatal("expected an expired token to be rejected")
+	}
+
+	if err := mngr.RequestEmailVerification(account); err != nil {
+		t.Fatal(err)
+	}
+	if err := mngr.VerifyEmail(account.Verification.Token); err != nil {
+		t.Fatal(err)
+	}
+	if !account.Data.EmailVerified || account.Verification != nil {
+		t.Fatalf("email not verified: %+v", account)
+	}
+
+	if err := mngr.RequestEmailVerification(account); err == nil {
+		t.Fatal("verified addresses must not be verified again")
+	}
+	if err := mngr.AuthorizeAccount(account, true); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChangeEmail(t *testing.T) {
+	mngr := newTestAccountsManager(t)
+	account := createTestAccount(t, mngr, "john@example.com")
+	_ = createTestAccount(t, mngr, "jane@example.com")
+
+	if err := mngr.ChangeEmail(account, "jane@example.com"); err == nil {
+		t.Fatal("expected a change to an existing address to fail")
+	}
+	if err := mngr.ChangeEmail(account, "invalid"); err == nil {
+		t.Fatal("expected a change to an invalid address to fail")
+	}
+
+	if err := mngr.ChangeEmail(account, "johnny@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if account.Email != "john@example.com" || !account.IsEmailChangePending() {
+		t.Fatal("the address must only change once it has been verified")
+	}
+
+	if err := mngr.VerifyEmail(account.Verification.Token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mngr.FindAccount(FindByEmail, "john@example.com"); err == nil {
+		t.Fatal("the old address must not be found anymore")
+	}
+	if acc, err := mngr.FindAccount(FindByEmail, "johnny@example.com"); err != nil || !acc.Data.EmailVerified {
+		t.Fatalf("the new address must be verified: %v", err)
+	}
+
+	// The stored accounts must reflect the change as well
+	accounts, err := mngr.storage.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, acc := range *accounts {
+		if acc.Email == "john@example.com" {
+			t.Fatal("the old address is still stored")
+		}
+	}
+
+	account.Data.APIKey = "key"
+	if err := mngr.ChangeEmail(account, "john@example.com"); err == nil {
+		t.Fatal("the address must not be changed once an API key has been assigned")
+	}
+}
+
+func TestExpireAccounts(t *testing.T) {
+	mngr := newTestAccountsManager(t)
+	mngr.conf.Lifecycle.InactivityExpiry = 30
+	mngr.conf.Lifecycle.ExpiryWarning = 7
+
+	active := createTestAccount(t, mngr, "active@example.com")
+	idle := createTestAccount(t, mngr, "idle@example.com")
+
+	setLastActivity := func(account *data.Account, inactive time.Duration) {
+		account.DateModified = time.Now().Add(-inactive)
+		account.DateLastActive = account.DateModified
+	}
+
+	setLastActivity(idle, 25*24*time.Hour)
+	mngr.expireAccounts()
+	if idle.DateExpiryWarning.IsZero() || !active.DateExpiryWarning.IsZero() {
+		t.Fatal("only the idle account must have been warned")
+	}
+
+	warned := idle.DateExpiryWarning
+	mngr.expireAccounts()
+	if !idle.DateExpiryWarning.Equal(warned) {
+		t.Fatal("accounts must only be warned once")
+	}
+
+	// The warning was sent less than a week ago, so the account is kept for now
+	setLastActivity(idle, 31*24*time.Hour)
+	mngr.expireAccounts()
+	if _, err := mngr.FindAccount(FindByEmail, "idle@example.com"); err != nil {
+		t.Fatal("the account must not be removed before the warning period has passed")
+	}
+
+	idle.DateExpiryWarning = time.Now().Add(-7 * 24 * time.Hour)
+	mngr.expireAccounts()
+	if _, err := mngr.FindAccount(FindByEmail, "idle@example.com"); err == nil {
+		t.Fatal("the expired account must have been removed")
+	}
+	if _, err := mngr.FindAccount(FindByEmail, "active@example.com"); err != nil {
+		t.Fatal("the active account must not have been removed")
+	}
+}
+
+func TestExpireAccountsWarnsFirst(t *testing.T) {
+	mngr := newTestAccountsManager(t)
+	mngr.conf.Lifecycle.InactivityExpiry = 30
+	mngr.conf.Lifecycle.ExpiryWarning = 7
+
+	// An account that has long expired without ever being warned
+	account := createTestAccount(t, mngr, "idle@example.com")
+	account.DateModified = time.Now().Add(-60 * 24 * time.Hour)
+	account.DateLastActive = account.DateModified
+
+	mngr.expireAccounts()
+	if _, err := mngr.FindAccount(FindByEmail, "idle@example.com"); err != nil {
+		t.Fatal("the account must be warned before being removed")
+	}
+	if account.DateExpiryWarning.IsZero() {
+		t.Fatal("the account must have been warned")
+	}
+}
+
+func TestUntrackedAccountsAreActive(t *testing.T) {
+	mngr := newTestAccountsManager(t)
+	mngr.conf.Lifecycle.InactivityExpiry = 30
+	mngr.conf.Lifecycle.ExpiryWarning = 7
+
+	// Accounts stored before activity tracking have no last activity
+	accounts := data.Accounts{{Email: "old@example.com", DateModified: time.Now().Add(-60 * 24 * time.Hour)}}
+	if err := mngr.storage.WriteAll(&accounts); err != nil {
+		t.Fatal(err)
+	}
+	mngr.readAllAccounts()
+
+	mngr.expireAccounts()
+	account, err := mngr.FindAccountEx(FindByEmail, "old@example.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(account.DateLastActive) > time.Minute || !account.DateExpiryWarning.IsZero() {
+		t.Fatalf("the account must be considered active: %+v", account)
+	}
+}
+
+func TestRecordAdminAction(t *testing.T) {
+	mngr := newTestAccountsManager(t)
+	account := createTestAccount(t, mngr, "john@example.com")
+
+	if err := mngr.RecordAdminAction(account, data.AuditEntry{Actor: "admin", Action: "authorize"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(account.AuditTrail) != 1 || account.AuditTrail[0].Date.IsZero() {
+		t.Fatalf("unexpected audit trail: %+v", account.AuditTrail)
+	}
+
+	if err := mngr.RecordAdminAction(&data.Account{Email: "nobody@example.com"}, data.AuditEntry{}); err == nil {
+		t.Fatal("expected an error for unknown accounts")
+	}
+}
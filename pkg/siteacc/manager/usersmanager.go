@@ -77,6 +77,7 @@ func (mngr *UsersManager) LoginUser(name, password string, scope string, session
 
 	// Store the user account in the session
 	session.LoggedInUser = account
+	mngr.accountsManager.RecordActivity(account.Email)
 
 	// Generate a token that can be used as a "ticket"
 	token, err := generateUserToken(session.LoggedInUser.Email, scope, mngr.conf.Webserver.SessionTimeout)
@@ -119,6 +120,8 @@ func (mngr *UsersManager) VerifyUserToken(token string, user string, scope strin
 		return "", errors.Errorf("invalid scope")
 	}
 
+	mngr.accountsManager.RecordActivity(utoken.User)
+
 	// Refresh the user token (as a form of keep-alive, since tokens expire quickly)
 	newToken, err := generateUserToken(utoken.User, utoken.Scope, mngr.conf.Webserver.SessionTimeout)
 	if err != nil {
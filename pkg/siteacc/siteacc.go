@@ -144,6 +144,11 @@ func (siteacc *SiteAccounts) ShowAccountPanel(w http.ResponseWriter, r *http.Req
 	return siteacc.accountPanel.Execute(w, r, session)
 }
 
+// Close stops all background tasks of the service.
+func (siteacc *SiteAccounts) Close() {
+	siteacc.accountsManager.Close()
+}
+
 // AccountsManager returns the central accounts manager instance.
 func (siteacc *SiteAccounts) AccountsManager() *manager.AccountsManager {
 	return siteacc.accountsManager
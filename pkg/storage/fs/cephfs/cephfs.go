@@ -43,7 +43,6 @@ import (
 
 const (
 	xattrTrustedNs = "trusted."
-	xattrEID       = xattrTrustedNs + "eid"
 	xattrMd5       = xattrTrustedNs + "checksum"
 	xattrMd5ts     = xattrTrustedNs + "checksumTS"
 	xattrRef       = xattrTrustedNs + "ref"
@@ -56,6 +55,7 @@ type cephfs struct {
 	conn         *connections
 	adminConn    *adminConn
 	chunkHandler *ChunkHandler
+	ids          *entryIDs
 }
 
 func init() {
@@ -93,6 +93,11 @@ func New(m map[string]interface{}) (fs storage.FS, err error) {
 		conf:      c,
 		conn:      cache,
 		adminConn: adminConn,
+		ids: &entryIDs{
+			mount: cephIDMount{adminConn.adminMount},
+			index: radosIndex{adminConn.radosIO},
+			root:  c.Root,
+		},
 	}, nil
 }
 
@@ -135,6 +140,10 @@ func (fs *cephfs) CreateHome(ctx context.Context) (err error) {
 		return getRevaError(err)
 	}
 
+	if _, err = fs.ids.id(user.home); err != nil {
+		return getRevaError(err)
+	}
+
 	user.op(func(cv *cacheVal) {
 		err = cv.mount.MakeDir(removeLeadingSlash(fs.conf.ShareFolder), dirPermDefault)
 		if err != nil && err.Error() == errFileExists {
@@ -157,7 +166,10 @@ func (fs *cephfs) CreateDir(ctx context.Context, ref *provider.Reference) error
 			return
 		}
 
-		//TODO(tmourati): Add entry id logic
+		// ids are assigned lazily, but the inode of a new directory is known to be its own
+		if _, e := fs.ids.id(path); e != nil {
+			appctx.GetLogger(ctx).Error().Err(e).Str("path", path).Msg("cephfs: could not assign id")
+		}
 	})
 
 	return getRevaError(err)
@@ -171,13 +183,21 @@ func (fs *cephfs) Delete(ctx context.Context, ref *provider.Reference) (err erro
 		return err
 	}
 
+	// the ids of the entries below a directory go with it
+	ids, e := fs.ids.below(path)
+	if e != nil {
+		appctx.GetLogger(ctx).Error().Err(e).Str("path", path).Msg("cephfs: could not collect ids")
+	}
 	user.op(func(cv *cacheVal) {
 		if err = cv.mount.Unlink(path); err != nil && err.Error() == errIsADirectory {
 			err = cv.mount.RemoveDir(path)
 		}
-
-		//TODO(tmourati): Add entry id logic
 	})
+	if err == nil {
+		if e := fs.ids.forget(ids); e != nil {
+			appctx.GetLogger(ctx).Error().Err(e).Str("path", path).Msg("cephfs: could not remove ids from index")
+		}
+	}
 
 	//has already been deleted by direct mount
 	if err != nil && err.Error() == errNotFound {
@@ -202,7 +222,10 @@ func (fs *cephfs) Move(ctx context.Context, oldRef, newRef *provider.Reference)
 			return
 		}
 
-		//TODO(tmourati): Add entry id logic, handle already moved file error
+		// the id moves with the entry
+		if e := fs.ids.move(newPath); e != nil {
+			appctx.GetLogger(ctx).Error().Err(e).Str("path", newPath).Msg("cephfs: could not move id")
+		}
 	})
 
 	// has already been moved by direct mount
@@ -289,7 +312,6 @@ func (fs *cephfs) Download(ctx context.Context, ref *provider.Reference) (rc io.
 }
 
 func (fs *cephfs) ListRevisions(ctx context.Context, ref *provider.Reference) (fvs []*provider.FileVersion, err error) {
-	//TODO(tmourati): Fix entry id logic
 	var path string
 	user := fs.makeUser(ctx)
 	if path, err = user.resolveRef(ref); err != nil {
@@ -316,7 +338,7 @@ func (fs *cephfs) ListRevisions(ctx context.Context, ref *provider.Reference) (f
 				continue
 			}
 
-			revPath, e = resolveRevRef(cv.mount, ref, d.Name())
+			revPath, e = resolveRevRef(user, ref, d.Name())
 			if e != nil {
 				continue
 			}
@@ -336,12 +358,11 @@ func (fs *cephfs) ListRevisions(ctx context.Context, ref *provider.Reference) (f
 }
 
 func (fs *cephfs) DownloadRevision(ctx context.Context, ref *provider.Reference, key string) (file io.ReadCloser, err error) {
-	//TODO(tmourati): Fix entry id logic
 	user := fs.makeUser(ctx)
 
 	user.op(func(cv *cacheVal) {
 		var revPath string
-		revPath, err = resolveRevRef(cv.mount, ref, key)
+		revPath, err = resolveRevRef(user, ref, key)
 		if err != nil {
 			return
 		}
@@ -353,7 +374,6 @@ func (fs *cephfs) DownloadRevision(ctx context.Context, ref *provider.Reference,
 }
 
 func (fs *cephfs) RestoreRevision(ctx context.Context, ref *provider.Reference, key string) (err error) {
-	//TODO(tmourati): Fix entry id logic
 	var path string
 	user := fs.makeUser(ctx)
 	if path, err = user.resolveRef(ref); err != nil {
@@ -362,7 +382,7 @@ func (fs *cephfs) RestoreRevision(ctx context.Context, ref *provider.Reference,
 
 	user.op(func(cv *cacheVal) {
 		var revPath string
-		if revPath, err = resolveRevRef(cv.mount, ref, key); err != nil {
+		if revPath, err = resolveRevRef(user, ref, key); err != nil {
 			err = errors.Wrap(err, "cephfs: error resolving revision ref "+ref.String())
 			return
 		}
@@ -385,8 +405,20 @@ func (fs *cephfs) RestoreRevision(ctx context.Context, ref *provider.Reference,
 }
 
 func (fs *cephfs) GetPathByID(ctx context.Context, id *provider.ResourceId) (str string, err error) {
-	//TODO(tmourati): Add entry id logic
-	return "", errtypes.NotSupported("cephfs: entry IDs currently not supported")
+	user := fs.makeUser(ctx)
+	if str, err = fs.ids.path(id.OpaqueId); err != nil {
+		return "", err
+	}
+
+	// only reveal the path of entries the user can access
+	user.op(func(cv *cacheVal) {
+		_, err = cv.mount.Statx(str, cephfs2.StatxMode, 0)
+	})
+	if err != nil {
+		return "", getRevaError(err)
+	}
+
+	return addLeadingSlash(str), nil
 }
 
 func (fs *cephfs) AddGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) (err error) {
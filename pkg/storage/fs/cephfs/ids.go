@@ -0,0 +1,270 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package cephfs
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+// Entries are identified by the inode number they had when they were first
+// seen. The id is persisted in the xattrEID attribute, so that it survives
+// operations replacing the inode, like finishing an upload over an existing
+// file. The index maps ids to "<parent id>/<name>", so that renaming a
+// directory does not touch the index entries of its children. Ids are
+// assigned when entries are created or moved, reads never write ids.
+
+const (
+	// xattrEID holds the id of an entry
+	xattrEID = "trusted.eid"
+	// rootLocation is the location of the root in the index
+	rootLocation = "/"
+	// maxIDDepth guards the resolution of ids against loops in the index
+	maxIDDepth = 4096
+)
+
+// idMount is the part of a ceph mount needed to assign ids to entries
+type idMount interface {
+	Inode(path string) (uint64, error)
+	GetXattr(path, name string) ([]byte, error)
+	SetXattr(path, name string, value []byte) error
+	// Children returns the names of the entries in the directory at the
+	// given path, files have none
+	Children(path string) ([]string, error)
+}
+
+// idIndex persists the location of entries by id
+type idIndex interface {
+	Get(id string) (string, error)
+	Set(id, location string) error
+	Delete(id string) error
+}
+
+// entryIDs assigns ids to the entries below root and resolves them to paths
+// relative to root. Relative paths are taken relative to root, like on the
+// mounts of the users.
+type entryIDs struct {
+	mount idMount
+	index idIndex
+	root  string
+}
+
+func (e *entryIDs) rel(p string) (string, error) {
+	if !filepath.IsAbs(p) {
+		return filepath.Clean(p), nil
+	}
+	r, err := filepath.Rel(e.root, p)
+	if err != nil || r == ".." || strings.HasPrefix(r, "../") {
+		return "", errtypes.BadRequest("cephfs: path is outside of the root: " + p)
+	}
+	return r, nil
+}
+
+// id returns the id of the entry at the given path, assigning one if the
+// entry has none yet
+func (e *entryIDs) id(p string) (string, error) {
+	rel, err := e.rel(p)
+	if err != nil {
+		return "", err
+	}
+	ino, err := e.mount.Inode(filepath.Join(e.root, rel))
+	if err != nil {
+		return "", err
+	}
+	return e.assign(rel, ino)
+}
+
+// stored returns the id of the entry at the given path without assigning
+// one. Entries without a valid id, like the ones created directly on the
+// mount, are identified by their inode until they get an id.
+func (e *entryIDs) stored(p string, ino uint64) string {
+	inode := strconv.FormatUint(ino, 10)
+	rel, err := e.rel(p)
+	if err != nil {
+		return inode
+	}
+	b, err := e.mount.GetXattr(filepath.Join(e.root, rel), xattrEID)
+	if err != nil || len(b) == 0 {
+		return inode
+	}
+	id := string(b)
+	if id != inode {
+		// a copy carrying the xattr of its source has no id of its own yet
+		if p, err := e.path(id); err != nil || p != rel {
+			return inode
+		}
+	}
+	return id
+}
+
+func (e *entryIDs) assign(rel string, ino uint64) (string, error) {
+	abs := filepath.Join(e.root, rel)
+	inode := strconv.FormatUint(ino, 10)
+
+	if b, err := e.mount.GetXattr(abs, xattrEID); err == nil && len(b) > 0 {
+		id := string(b)
+		if id == inode {
+			return id, nil
+		}
+		// the inode changed: keep the id if the entry replaced the one it
+		// was carried over from, a copy carrying the xattr of its source
+		// gets an id of its own
+		if p, err := e.path(id); err == nil && p == rel {
+			return id, nil
+		}
+	}
+
+	location := rootLocation
+	if rel != "." {
+		parent, err := e.id(filepath.Dir(rel))
+		if err != nil {
+			return "", err
+		}
+		location = parent + "/" + filepath.Base(rel)
+	}
+	// index first, an entry with an id can always be resolved
+	if err := e.index.Set(inode, location); err != nil {
+		return "", err
+	}
+	if err := e.mount.SetXattr(abs, xattrEID, []byte(inode)); err != nil {
+		return "", err
+	}
+	return inode, nil
+}
+
+// path returns the path of the entry with the given id relative to root
+func (e *entryIDs) path(id string) (string, error) {
+	names := []string{}
+	for i := 0; i < maxIDDepth; i++ {
+		location, err := e.index.Get(id)
+		if err != nil {
+			return "", err
+		}
+		if location == rootLocation {
+			p := "."
+			for j := len(names) - 1; j >= 0; j-- {
+				p = filepath.Join(p, names[j])
+			}
+			return p, nil
+		}
+		parts := strings.SplitN(location, "/", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return "", fmt.Errorf("cephfs: invalid index entry %q for id %s", location, id)
+		}
+		names = append(names, parts[1])
+		id = parts[0]
+	}
+	return "", fmt.Errorf("cephfs: entry %s is nested too deep", id)
+}
+
+// move updates the index after an entry has been renamed to the given path
+func (e *entryIDs) move(p string) error {
+	rel, err := e.rel(p)
+	if err != nil {
+		return err
+	}
+	b, err := e.mount.GetXattr(filepath.Join(e.root, rel), xattrEID)
+	if err != nil || len(b) == 0 {
+		// the entry has not been seen before
+		_, err = e.id(rel)
+		return err
+	}
+	parent, err := e.id(filepath.Dir(rel))
+	if err != nil {
+		return err
+	}
+	return e.index.Set(string(b), parent+"/"+filepath.Base(rel))
+}
+
+// lookup returns the id stored on the entry at the given path without
+// assigning one, it returns an empty id for entries that have none
+func (e *entryIDs) lookup(p string) string {
+	rel, err := e.rel(p)
+	if err != nil {
+		return ""
+	}
+	b, err := e.mount.GetXattr(filepath.Join(e.root, rel), xattrEID)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// remove drops a deleted entry from the index
+func (e *entryIDs) remove(id string) error {
+	if id == "" {
+		return nil
+	}
+	return e.index.Delete(id)
+}
+
+// below returns the ids of the entry at the given path and of all entries
+// below it by their path relative to root
+func (e *entryIDs) below(p string) (map[string]string, error) {
+	rel, err := e.rel(p)
+	if err != nil {
+		return nil, err
+	}
+	ids := map[string]string{}
+	return ids, e.collect(rel, ids)
+}
+
+func (e *entryIDs) collect(rel string, ids map[string]string) error {
+	if id := e.lookup(rel); id != "" {
+		ids[rel] = id
+	}
+	names, err := e.mount.Children(filepath.Join(e.root, rel))
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := e.collect(filepath.Join(rel, name), ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forget drops the ids returned by below from the index once their entries
+// are gone, so that a partially deleted tree keeps the ids of what is left
+func (e *entryIDs) forget(ids map[string]string) error {
+	for rel, id := range ids {
+		if e.lookup(rel) == id {
+			continue
+		}
+		if err := e.remove(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// carry hands the id of the entry at the given path over to the file at src,
+// which is about to replace it
+func (e *entryIDs) carry(src, p string) error {
+	id := e.lookup(p)
+	if id == "" {
+		return nil
+	}
+	return e.mount.SetXattr(src, xattrEID, []byte(id))
+}
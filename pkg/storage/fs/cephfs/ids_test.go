@@ -0,0 +1,334 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package cephfs
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+type fakeEntry struct {
+	inode  uint64
+	xattrs map[string][]byte
+}
+
+// fakeMount keeps the inodes and xattrs of entries by absolute path
+type fakeMount struct {
+	entries   map[string]*fakeEntry
+	nextInode uint64
+}
+
+func newFakeMount(paths ...string) *fakeMount {
+	m := &fakeMount{entries: map[string]*fakeEntry{}, nextInode: 1}
+	for _, p := range paths {
+		m.create(p)
+	}
+	return m
+}
+
+func (m *fakeMount) create(p string) {
+	m.entries[p] = &fakeEntry{inode: m.nextInode, xattrs: map[string][]byte{}}
+	m.nextInode++
+}
+
+func (m *fakeMount) rename(oldPath, newPath string) {
+	for p, e := range m.entries {
+		if p == oldPath || strings.HasPrefix(p, oldPath+"/") {
+			delete(m.entries, p)
+			m.entries[newPath+strings.TrimPrefix(p, oldPath)] = e
+		}
+	}
+}
+
+func (m *fakeMount) entry(p string) (*fakeEntry, error) {
+	e, ok := m.entries[p]
+	if !ok {
+		return nil, errtypes.NotFound(p)
+	}
+	return e, nil
+}
+
+func (m *fakeMount) Inode(p string) (uint64, error) {
+	e, err := m.entry(p)
+	if err != nil {
+		return 0, err
+	}
+	return e.inode, nil
+}
+
+func (m *fakeMount) GetXattr(p, name string) ([]byte, error) {
+	e, err := m.entry(p)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := e.xattrs[name]
+	if !ok {
+		return nil, errtypes.NotFound(name)
+	}
+	return v, nil
+}
+
+func (m *fakeMount) SetXattr(p, name string, value []byte) error {
+	e, err := m.entry(p)
+	if err != nil {
+		return err
+	}
+	e.xattrs[name] = value
+	return nil
+}
+
+func (m *fakeMount) Children(p string) ([]string, error) {
+	if _, err := m.entry(p); err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for c := range m.entries {
+		if filepath.Dir(c) == p && c != p {
+			names = append(names, filepath.Base(c))
+		}
+	}
+	return names, nil
+}
+
+func (m *fakeMount) remove(p string) {
+	for c := range m.entries {
+		if c == p || strings.HasPrefix(c, p+"/") {
+			delete(m.entries, c)
+		}
+	}
+}
+
+type fakeIndex map[string]string
+
+func (i fakeIndex) Get(id string) (string, error) {
+	location, ok := i[id]
+	if !ok {
+		return "", errtypes.NotFound(id)
+	}
+	return location, nil
+}
+
+func (i fakeIndex) Set(id, location string) error {
+	i[id] = location
+	return nil
+}
+
+func (i fakeIndex) Delete(id string) error {
+	delete(i, id)
+	return nil
+}
+
+func newEntryIDs(m *fakeMount) *entryIDs {
+	return &entryIDs{mount: m, index: fakeIndex{}, root: "/home"}
+}
+
+func mustID(t *testing.T, ids *entryIDs, p string) string {
+	id, err := ids.id(p)
+	if err != nil {
+		t.Fatalf("error getting id of %s: %v", p, err)
+	}
+	return id
+}
+
+func expectPath(t *testing.T, ids *entryIDs, id, expected string) {
+	p, err := ids.path(id)
+	if err != nil {
+		t.Fatalf("error resolving id %s: %v", id, err)
+	}
+	if p != expected {
+		t.Errorf("expected id %s to resolve to %s, got %s", id, expected, p)
+	}
+}
+
+func TestIDsAreInodes(t *testing.T) {
+	m := newFakeMount("/home", "/home/alice", "/home/alice/docs", "/home/alice/docs/file.txt")
+	ids := newEntryIDs(m)
+
+	id := mustID(t, ids, "alice/docs/file.txt")
+	if id != "4" {
+		t.Errorf("expected the inode as id, got %s", id)
+	}
+	expectPath(t, ids, id, "alice/docs/file.txt")
+	expectPath(t, ids, mustID(t, ids, "alice"), "alice")
+	expectPath(t, ids, mustID(t, ids, "/home"), ".")
+
+	// absolute paths below the root are accepted too
+	if abs := mustID(t, ids, "/home/alice/docs/file.txt"); abs != id {
+		t.Errorf("expected the same id for the absolute path, got %s", abs)
+	}
+	if _, err := ids.id("/etc"); err == nil {
+		t.Error("expected an error for a path outside of the root")
+	}
+	if _, err := ids.path("42"); err == nil {
+		t.Error("expected an error for an unknown id")
+	}
+}
+
+func TestIDsSurviveMoves(t *testing.T) {
+	m := newFakeMount("/home", "/home/alice", "/home/alice/docs", "/home/alice/docs/file.txt")
+	ids := newEntryIDs(m)
+	dir := mustID(t, ids, "alice/docs")
+	file := mustID(t, ids, "alice/docs/file.txt")
+
+	m.create("/home/alice/archive")
+	m.rename("/home/alice/docs", "/home/alice/archive/docs")
+	if err := ids.move("alice/archive/docs"); err != nil {
+		t.Fatal(err)
+	}
+
+	if moved := mustID(t, ids, "alice/archive/docs"); moved != dir {
+		t.Errorf("expected the moved directory to keep id %s, got %s", dir, moved)
+	}
+	expectPath(t, ids, dir, "alice/archive/docs")
+	// the children follow their parent without touching the index
+	expectPath(t, ids, file, "alice/archive/docs/file.txt")
+}
+
+func TestIDsSurviveReplacedFiles(t *testing.T) {
+	m := newFakeMount("/home", "/home/alice", "/home/alice/file.txt", "/uploads/123")
+	ids := newEntryIDs(m)
+	id := mustID(t, ids, "alice/file.txt")
+
+	// finishing an upload renames the uploaded file over the existing one
+	if err := ids.carry("/uploads/123", "alice/file.txt"); err != nil {
+		t.Fatal(err)
+	}
+	m.rename("/uploads/123", "/home/alice/file.txt")
+
+	if replaced := mustID(t, ids, "alice/file.txt"); replaced != id {
+		t.Errorf("expected the new file to keep id %s, got %s", id, replaced)
+	}
+	expectPath(t, ids, id, "alice/file.txt")
+}
+
+func TestCopiesGetTheirOwnID(t *testing.T) {
+	m := newFakeMount("/home", "/home/alice", "/home/alice/file.txt")
+	ids := newEntryIDs(m)
+	id := mustID(t, ids, "alice/file.txt")
+
+	// a copy preserving the xattrs of its source
+	m.create("/home/alice/copy.txt")
+	m.entries["/home/alice/copy.txt"].xattrs[xattrEID] = []byte(id)
+
+	copied := mustID(t, ids, "alice/copy.txt")
+	if copied == id {
+		t.Errorf("expected the copy to get an id of its own")
+	}
+	expectPath(t, ids, copied, "alice/copy.txt")
+	expectPath(t, ids, id, "alice/file.txt")
+}
+
+func TestRemovedIDs(t *testing.T) {
+	m := newFakeMount("/home", "/home/alice", "/home/alice/file.txt")
+	ids := newEntryIDs(m)
+	id := mustID(t, ids, "alice/file.txt")
+
+	if lookup := ids.lookup("alice/file.txt"); lookup != id {
+		t.Errorf("expected lookup to return %s, got %s", id, lookup)
+	}
+	if err := ids.remove(id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ids.path(id); err == nil {
+		t.Error("expected an error resolving a removed id")
+	}
+	if lookup := ids.lookup(filepath.Join("alice", "unknown")); lookup != "" {
+		t.Errorf("expected no id for an unknown entry, got %s", lookup)
+	}
+}
+
+func TestIndexLoops(t *testing.T) {
+	ids := &entryIDs{mount: newFakeMount(), index: fakeIndex{"1": "2/a", "2": "1/b"}, root: "/home"}
+	if _, err := ids.path("1"); err == nil {
+		t.Error("expected an error for a loop in the index")
+	}
+}
+
+func TestReadsDoNotAssignIDs(t *testing.T) {
+	m := newFakeMount("/home", "/home/alice", "/home/alice/file.txt")
+	ids := newEntryIDs(m)
+	index := ids.index.(fakeIndex)
+
+	// entries created outside of reva are identified by their inode
+	if id := ids.stored("alice/file.txt", 3); id != "3" {
+		t.Errorf("expected the inode as id, got %s", id)
+	}
+	if len(index) != 0 {
+		t.Errorf("expected reads to leave the index alone, got %v", index)
+	}
+	if _, ok := m.entries["/home/alice/file.txt"].xattrs[xattrEID]; ok {
+		t.Error("expected reads to leave the entry alone")
+	}
+
+	id := mustID(t, ids, "alice/file.txt")
+	if stored := ids.stored("alice/file.txt", 3); stored != id {
+		t.Errorf("expected the assigned id %s, got %s", id, stored)
+	}
+
+	// a copy preserving the xattrs of its source does not share its id
+	m.create("/home/alice/copy.txt")
+	m.entries["/home/alice/copy.txt"].xattrs[xattrEID] = []byte(id)
+	if copied := ids.stored("alice/copy.txt", 4); copied != "4" {
+		t.Errorf("expected the copy to be identified by its inode, got %s", copied)
+	}
+}
+
+func TestDeletedTreesLeaveNoIDs(t *testing.T) {
+	m := newFakeMount("/home", "/home/alice", "/home/alice/docs", "/home/alice/docs/sub", "/home/alice/docs/sub/file.txt", "/home/alice/keep.txt")
+	ids := newEntryIDs(m)
+	docs := mustID(t, ids, "alice/docs")
+	file := mustID(t, ids, "alice/docs/sub/file.txt")
+	keep := mustID(t, ids, "alice/keep.txt")
+
+	below, err := ids.below("alice/docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(below) != 3 || below["alice/docs"] != docs || below["alice/docs/sub/file.txt"] != file {
+		t.Errorf("unexpected ids below the directory: %v", below)
+	}
+
+	// a failed delete keeps the ids of what is left
+	m.remove("/home/alice/docs/sub/file.txt")
+	if err := ids.forget(below); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ids.path(file); err == nil {
+		t.Error("expected the id of the removed file to be gone")
+	}
+	expectPath(t, ids, docs, "alice/docs")
+
+	m.remove("/home/alice/docs")
+	if err := ids.forget(below); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ids.path(docs); err == nil {
+		t.Error("expected the id of the removed directory to be gone")
+	}
+	index := ids.index.(fakeIndex)
+	for id, location := range index {
+		if id != keep && strings.HasPrefix(location, docs+"/") {
+			t.Errorf("expected no entries below the removed directory, got %s: %s", id, location)
+		}
+	}
+	expectPath(t, ids, keep, "alice/keep.txt")
+}
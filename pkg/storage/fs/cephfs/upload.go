@@ -367,6 +367,11 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) (err error) {
 	user := upload.fs.makeUser(upload.ctx)
 	log := appctx.GetLogger(ctx)
 
+	// keep the id of an overwritten file
+	if err = upload.fs.ids.carry(upload.binPath, np); err != nil {
+		log.Err(err).Interface("info", upload.info).Msg("cephfs: could not keep the id of the overwritten file")
+	}
+
 	user.op(func(cv *cacheVal) {
 		err = cv.mount.Rename(upload.binPath, np)
 	})
@@ -374,6 +379,11 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) (err error) {
 		return errors.Wrap(err, upload.binPath)
 	}
 
+	// new files get their id on creation
+	if _, err = upload.fs.ids.id(np); err != nil {
+		log.Err(err).Interface("info", upload.info).Msg("cephfs: could not assign id")
+	}
+
 	// only delete the upload if it was successfully written to the fs
 	user.op(func(cv *cacheVal) {
 		err = cv.mount.Unlink(upload.infoPath)
@@ -29,8 +29,6 @@ import (
 	"strings"
 	"syscall"
 
-	"github.com/cs3org/reva/pkg/errtypes"
-
 	cephfs2 "github.com/ceph/go-ceph/cephfs"
 	userv1beta1 "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
@@ -136,8 +134,6 @@ func (user *User) fileAsResourceInfo(cv *cacheVal, path string, stat *cephfs2.Ce
 		}
 	}
 
-	//TODO(tmourati): Add entry id logic here
-
 	var etag string
 	if isDir(_type) {
 		rctime, _ := cv.mount.GetXattr(path, "ceph.dir.rctime")
@@ -211,7 +207,7 @@ func (user *User) fileAsResourceInfo(cv *cacheVal, path string, stat *cephfs2.Ce
 
 	ri = &provider.ResourceInfo{
 		Type:              _type,
-		Id:                &provider.ResourceId{OpaqueId: fmt.Sprint(stat.Inode)},
+		Id:                &provider.ResourceId{OpaqueId: user.fs.ids.stored(path, uint64(stat.Inode))},
 		Checksum:          &checksum,
 		Etag:              etag,
 		MimeType:          mime.Detect(isDir(_type), path),
@@ -232,8 +228,16 @@ func (user *User) resolveRef(ref *provider.Reference) (str string, err error) {
 		return "", fmt.Errorf("cephfs: nil reference")
 	}
 
+	if ref.GetResourceId() != nil {
+		if str, err = user.fs.ids.path(ref.ResourceId.OpaqueId); err != nil {
+			return
+		}
+		// anchor the relative path so that it cannot leave the resource
+		return filepath.Join(str, filepath.Join("/", ref.GetPath())), nil
+	}
+
 	if str = ref.GetPath(); str == "" {
-		return "", errtypes.NotSupported("cephfs: entry IDs not currently supported")
+		return "", fmt.Errorf("cephfs: empty reference")
 	}
 
 	str = removeLeadingSlash(str) //path must be relative
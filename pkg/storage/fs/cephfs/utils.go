@@ -24,15 +24,16 @@ package cephfs
 import (
 	"crypto/md5"
 	"encoding/hex"
-	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strconv"
-	"strings"
+	"syscall"
 
 	cephfs2 "github.com/ceph/go-ceph/cephfs"
+	rados2 "github.com/ceph/go-ceph/rados"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
 )
 
 // Mount type
@@ -75,29 +76,81 @@ func isDir(t provider.ResourceType) bool {
 	return t == provider.ResourceType_RESOURCE_TYPE_CONTAINER
 }
 
-func (fs *cephfs) makeFIDPath(fid string) string {
-	return "" //filepath.Join(fs.conf.EIDFolder, fid)
+// cephIDMount looks up the inodes and xattrs of entries on a ceph mount
+type cephIDMount struct {
+	mount Mount
 }
 
-func (fs *cephfs) makeFID(absolutePath string, inode string) (rid *provider.ResourceId, err error) {
-	sum := md5.New()
-	sum.Write([]byte(absolutePath))
-	fid := fmt.Sprintf("%s-%s", hex.EncodeToString(sum.Sum(nil)), inode)
-	rid = &provider.ResourceId{OpaqueId: fid}
+func (m cephIDMount) Inode(path string) (uint64, error) {
+	stat, err := m.mount.Statx(path, cephfs2.StatxIno, 0)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(stat.Inode), nil
+}
 
-	_ = fs.adminConn.adminMount.Link(absolutePath, fs.makeFIDPath(fid))
-	_ = fs.adminConn.adminMount.SetXattr(absolutePath, xattrEID, []byte(fid), 0)
+func (m cephIDMount) GetXattr(path, name string) ([]byte, error) {
+	return m.mount.GetXattr(path, name)
+}
 
-	return
+func (m cephIDMount) SetXattr(path, name string, value []byte) error {
+	return m.mount.SetXattr(path, name, value, 0)
 }
 
-func (fs *cephfs) getFIDPath(cv *cacheVal, path string) (fid string, err error) {
-	var buffer []byte
-	if buffer, err = cv.mount.GetXattr(path, xattrEID); err != nil {
-		return
+func (m cephIDMount) Children(path string) (names []string, err error) {
+	stat, err := m.mount.Statx(path, cephfs2.StatxMode, 0)
+	if err != nil {
+		return nil, err
+	}
+	if int(stat.Mode)&syscall.S_IFMT != syscall.S_IFDIR {
+		return nil, nil
+	}
+
+	dir, err := m.mount.OpenDir(path)
+	if err != nil {
+		return nil, err
 	}
+	defer closeDir(dir)
 
-	return fs.makeFIDPath(string(buffer)), err
+	var entry *cephfs2.DirEntry
+	for entry, err = dir.ReadDir(); entry != nil && err == nil; entry, err = dir.ReadDir() {
+		if name := entry.Name(); name != "." && name != ".." {
+			names = append(names, name)
+		}
+	}
+	return names, err
+}
+
+// radosIndex keeps the id index in objects of the index pool
+type radosIndex struct {
+	io *rados2.IOContext
+}
+
+func (r radosIndex) Get(id string) (string, error) {
+	stat, err := r.io.Stat(id)
+	if err == rados2.ErrNotFound {
+		return "", errtypes.NotFound("cephfs: entry id " + id)
+	}
+	if err != nil {
+		return "", err
+	}
+	buffer := make([]byte, stat.Size)
+	n, err := r.io.Read(id, buffer, 0)
+	if err != nil {
+		return "", err
+	}
+	return string(buffer[:n]), nil
+}
+
+func (r radosIndex) Set(id, location string) error {
+	return r.io.WriteFull(id, []byte(location))
+}
+
+func (r radosIndex) Delete(id string) error {
+	if err := r.io.Delete(id); err != nil && err != rados2.ErrNotFound {
+		return err
+	}
+	return nil
 }
 
 func calcChecksum(filepath string, mt Mount, stat Statx) (checksum string, err error) {
@@ -118,27 +171,12 @@ func calcChecksum(filepath string, mt Mount, stat Statx) (checksum string, err e
 	return
 }
 
-func resolveRevRef(mt Mount, ref *provider.Reference, revKey string) (str string, err error) {
-	var buf []byte
-	if ref.GetResourceId() != nil {
-		str, err = mt.Readlink(filepath.Join(snap, revKey, ref.ResourceId.OpaqueId))
-		if err != nil {
-			return "", fmt.Errorf("cephfs: invalid reference %+v", ref)
-		}
-	} else if str = ref.GetPath(); str != "" {
-		buf, err = mt.GetXattr(str, xattrEID)
-		if err != nil {
-			return
-		}
-		str, err = mt.Readlink(filepath.Join(snap, revKey, string(buf)))
-		if err != nil {
-			return
-		}
-	} else {
-		return "", fmt.Errorf("cephfs: empty reference %+v", ref)
+func resolveRevRef(user *User, ref *provider.Reference, revKey string) (str string, err error) {
+	if str, err = user.resolveRef(ref); err != nil {
+		return
 	}
 
-	return filepath.Join(snap, revKey, str), err
+	return filepath.Join(snap, revKey, str), nil
 }
 
 func removeLeadingSlash(path string) string {
@@ -194,52 +232,3 @@ func walkPath(path string, f func(string) error, reverse bool) (err error) {
 
 	return
 }
-
-func (fs *cephfs) writeIndex(oid string, value string) (err error) {
-	return fs.adminConn.radosIO.WriteFull(oid, []byte(value))
-}
-
-func (fs *cephfs) removeIndex(oid string) error {
-	return fs.adminConn.radosIO.Delete(oid)
-}
-
-func (fs *cephfs) resolveIndex(oid string) (fullPath string, err error) {
-	var i int
-	var currPath strings.Builder
-	root := string(filepath.Separator)
-	offset := uint64(0)
-	io := fs.adminConn.radosIO
-	bsize := 4096
-	buffer := make([]byte, bsize)
-	for {
-		for { //read object
-			i, err = io.Read(oid, buffer, offset)
-			offset += uint64(bsize)
-			currPath.Write(buffer)
-			if err == nil && i >= bsize {
-				buffer = buffer[:0]
-				continue
-			} else {
-				offset = 0
-				break
-			}
-		}
-		if err != nil {
-			return
-		}
-
-		ss := strings.SplitN(currPath.String(), string(filepath.Separator), 2)
-		if len(ss) != 2 {
-			if currPath.String() == root {
-				return
-			}
-
-			return "", fmt.Errorf("cephfs: entry id is not in the form of \"parentID/entryname\"")
-		}
-		parentOID := ss[0]
-		entryName := ss[1]
-		fullPath = filepath.Join(entryName, fullPath)
-		oid = parentOID
-		currPath.Reset()
-	}
-}
@@ -31,8 +31,10 @@ func init() {
 }
 
 type config struct {
-	Root        string `mapstructure:"root" docs:"/var/tmp/reva/;Path of root directory for user storage."`
-	ShareFolder string `mapstructure:"share_folder" docs:"/MyShares;Path for storing share references."`
+	Root          string   `mapstructure:"root" docs:"/var/tmp/reva/;Path of root directory for user storage."`
+	ShareFolder   string   `mapstructure:"share_folder" docs:"/MyShares;Path for storing share references."`
+	ProjectSpaces []string `mapstructure:"project_spaces" docs:"[];Paths of the directories exposed as project spaces."`
+	GatewaySVC    string   `mapstructure:"gatewaysvc" docs:";The gateway used to check the permission to change the quota of spaces. Defaults to the shared gateway."`
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -53,9 +55,11 @@ func New(m map[string]interface{}) (storage.FS, error) {
 	}
 
 	conf := localfs.Config{
-		Root:        c.Root,
-		ShareFolder: c.ShareFolder,
-		DisableHome: true,
+		Root:          c.Root,
+		ShareFolder:   c.ShareFolder,
+		DisableHome:   true,
+		ProjectSpaces: c.ProjectSpaces,
+		GatewaySVC:    c.GatewaySVC,
 	}
 	return localfs.NewLocalFS(&conf)
 }
@@ -34,6 +34,7 @@ type config struct {
 	Root        string `mapstructure:"root" docs:"/var/tmp/reva/;Path of root directory for user storage."`
 	ShareFolder string `mapstructure:"share_folder" docs:"/MyShares;Path for storing share references."`
 	UserLayout  string `mapstructure:"user_layout" docs:"{{.Username}};Template for user home directories"`
+	GatewaySVC  string `mapstructure:"gatewaysvc" docs:";The gateway used to check the permission to change the quota of spaces. Defaults to the shared gateway."`
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -57,6 +58,7 @@ func New(m map[string]interface{}) (storage.FS, error) {
 		Root:        c.Root,
 		ShareFolder: c.ShareFolder,
 		UserLayout:  c.UserLayout,
+		GatewaySVC:  c.GatewaySVC,
 	}
 	return localfs.NewLocalFS(&conf)
 }
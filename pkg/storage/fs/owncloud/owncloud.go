@@ -48,7 +48,9 @@ import (
 	"github.com/cs3org/reva/pkg/storage/fs/registry"
 	"github.com/cs3org/reva/pkg/storage/utils/ace"
 	"github.com/cs3org/reva/pkg/storage/utils/chunking"
+	"github.com/cs3org/reva/pkg/storage/utils/spaces"
 	"github.com/cs3org/reva/pkg/storage/utils/templates"
+	"github.com/golang/protobuf/proto"
 	"github.com/gomodule/redigo/redis"
 	"github.com/google/uuid"
 	"github.com/mitchellh/mapstructure"
@@ -70,6 +72,7 @@ const (
 
 	// SharePrefix is the prefix for sharing related extended attributes
 	sharePrefix       string = ocPrefix + "grant." // grants are similar to acls, but they are not propagated down the tree when being changed
+	denyPrefix        string = ocPrefix + "deny."  // denials are kept apart from the grants of the same principal
 	trashOriginPrefix string = ocPrefix + "o"
 	mdPrefix          string = ocPrefix + "md."   // arbitrary metadata
 	favPrefix         string = ocPrefix + "fav."  // favorite flag, per user
@@ -87,6 +90,7 @@ var ownerPermissions *provider.ResourcePermissions = &provider.ResourcePermissio
 	AddGrant:             true,
 	CreateContainer:      true,
 	Delete:               true,
+	DenyGrant:            true,
 	GetPath:              true,
 	GetQuota:             true,
 	InitiateFileDownload: true,
@@ -109,15 +113,17 @@ func init() {
 }
 
 type config struct {
-	DataDirectory            string `mapstructure:"datadirectory"`
-	UploadInfoDir            string `mapstructure:"upload_info_dir"`
-	DeprecatedShareDirectory string `mapstructure:"sharedirectory"`
-	ShareFolder              string `mapstructure:"share_folder"`
-	UserLayout               string `mapstructure:"user_layout"`
-	Redis                    string `mapstructure:"redis"`
-	EnableHome               bool   `mapstructure:"enable_home"`
-	Scan                     bool   `mapstructure:"scan"`
-	UserProviderEndpoint     string `mapstructure:"userprovidersvc"`
+	DataDirectory            string   `mapstructure:"datadirectory"`
+	UploadInfoDir            string   `mapstructure:"upload_info_dir"`
+	DeprecatedShareDirectory string   `mapstructure:"sharedirectory"`
+	ShareFolder              string   `mapstructure:"share_folder"`
+	UserLayout               string   `mapstructure:"user_layout"`
+	Redis                    string   `mapstructure:"redis"`
+	EnableHome               bool     `mapstructure:"enable_home"`
+	Scan                     bool     `mapstructure:"scan"`
+	UserProviderEndpoint     string   `mapstructure:"userprovidersvc"`
+	ProjectSpaces            []string `mapstructure:"project_spaces"`
+	GatewaySVC               string   `mapstructure:"gatewaysvc"`
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -154,6 +160,7 @@ func (c *config) init(m map[string]interface{}) {
 		c.Scan = true
 	}
 	c.UserProviderEndpoint = sharedconf.GetGatewaySVC(c.UserProviderEndpoint)
+	c.GatewaySVC = sharedconf.GetGatewaySVC(c.GatewaySVC)
 }
 
 // New returns an implementation to of the storage.FS interface that talk to
@@ -206,6 +213,7 @@ func New(m map[string]interface{}) (storage.FS, error) {
 		c:            c,
 		pool:         pool,
 		chunkHandler: chunking.NewChunkHandler(c.UploadInfoDir),
+		spaceUsage:   spaces.NewUsageCache(spaces.Usage),
 	}, nil
 }
 
@@ -213,6 +221,8 @@ type ocfs struct {
 	c            *config
 	pool         *redis.Pool
 	chunkHandler *chunking.ChunkHandler
+	// spaceUsage caches the usage of the spaces by their internal root path
+	spaceUsage *spaces.UsageCache
 }
 
 func (fs *ocfs) Shutdown(ctx context.Context) error {
@@ -814,8 +824,32 @@ func (fs *ocfs) resolve(ctx context.Context, ref *provider.Reference) (string, e
 
 }
 
+// DenyGrant denies access to the resource for the grantee. The denial is stored as an ACE
+// next to any grant the grantee has on the resource and takes precedence over grants on the parents.
 func (fs *ocfs) DenyGrant(ctx context.Context, ref *provider.Reference, g *provider.Grantee) error {
-	return errtypes.NotSupported("ocfs: deny grant not supported")
+	ip, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return errors.Wrap(err, "ocfs: error resolving reference")
+	}
+
+	// check permissions
+	if perm, err := fs.readPermissions(ctx, ip); err == nil {
+		if !perm.DenyGrant {
+			return errtypes.PermissionDenied("")
+		}
+	} else {
+		if isNotFound(err) {
+			return errtypes.NotFound(fs.toStoragePath(ctx, ip))
+		}
+		return errors.Wrap(err, "ocfs: error reading permissions")
+	}
+
+	e := ace.FromDenial(g)
+	principal, value := e.Marshal()
+	if err := xattr.Set(ip, denyPrefix+principal, value); err != nil {
+		return err
+	}
+	return fs.propagate(ctx, ip)
 }
 
 func (fs *ocfs) AddGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
@@ -849,7 +883,11 @@ func extractACEsFromAttrs(ctx context.Context, ip string, attrs []string) (entri
 	log := appctx.GetLogger(ctx)
 	entries = []*ace.ACE{}
 	for i := range attrs {
-		if strings.HasPrefix(attrs[i], sharePrefix) {
+		prefix := sharePrefix
+		if strings.HasPrefix(attrs[i], denyPrefix) {
+			prefix = denyPrefix
+		}
+		if strings.HasPrefix(attrs[i], prefix) {
 			var value []byte
 			var err error
 			if value, err = xattr.Get(ip, attrs[i]); err != nil {
@@ -857,7 +895,7 @@ func extractACEsFromAttrs(ctx context.Context, ip string, attrs []string) (entri
 				continue
 			}
 			var e *ace.ACE
-			principal := attrs[i][len(sharePrefix):]
+			principal := attrs[i][len(prefix):]
 			if e, err = ace.Unmarshal(principal, value); err != nil {
 				log.Error().Err(err).Str("principal", principal).Str("attr", attrs[i]).Msg("could not unmarshal ace")
 				continue
@@ -915,6 +953,15 @@ func (fs *ocfs) readPermissions(ctx context.Context, ip string) (p *provider.Res
 			return nil, err
 		}
 
+		// denials take precedence over any grant on the node or its parents
+		for i := range attrs {
+			if attrs[i] == denyPrefix+"u:"+u.Id.OpaqueId ||
+				(strings.HasPrefix(attrs[i], denyPrefix+"g:") && groupsMap[strings.TrimPrefix(attrs[i], denyPrefix+"g:")]) {
+				appctx.GetLogger(ctx).Debug().Str("ipath", np).Str("principal", strings.TrimPrefix(attrs[i], denyPrefix)).Msg("access denied")
+				return &provider.ResourcePermissions{}, nil
+			}
+		}
+
 		userace := sharePrefix + "u:" + u.Id.OpaqueId
 		userFound := false
 		for i := range attrs {
@@ -967,13 +1014,39 @@ func (fs *ocfs) readPermissions(ctx context.Context, ip string) (p *provider.Res
 	//   what if, when checking /a/b/c/d, /a/b has write permission for group g, but /a/b/c has an ace for another group h the user is also a member of?
 	//     it would allow restricting a users permissions by resharing something with him with lower permission?
 	//     so if you have reshare permissions you could accidentially restrict users access to a subfolder of a rw share to ro by sharing it to another group as ro when they are part of both groups
-	//       it makes more sense to have explicit negative permissions
+	//       it makes more sense to have explicit negative permissions, which is what denials are for
 
 	// TODO we need to read all parents ... until we find a matching ace?
 	appctx.GetLogger(ctx).Debug().Interface("permissions", aggregatedPermissions).Str("ipath", ip).Msg("returning aggregated permissions")
 	return aggregatedPermissions, nil
 }
 
+// isDenied checks if the node itself carries a denial for the current user or one of its groups.
+// Denials on the parents are already taken into account by readPermissions, so this is enough
+// to filter the children of a folder the user is allowed to list.
+func (fs *ocfs) isDenied(ctx context.Context, ip string) bool {
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok || fs.getOwner(ip) == u.Id.OpaqueId {
+		return false
+	}
+	attrs, err := xattr.List(ip)
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Str("ipath", ip).Msg("error listing attributes")
+		return false
+	}
+	principals := make(map[string]bool, len(u.Groups)+1)
+	principals["u:"+u.Id.OpaqueId] = true
+	for i := range u.Groups {
+		principals["g:"+u.Groups[i]] = true
+	}
+	for _, e := range extractACEsFromAttrs(ctx, ip, attrs) {
+		if e.IsDenial() && principals[e.Principal()] {
+			return true
+		}
+	}
+	return false
+}
+
 func isNoData(err error) bool {
 	if xerr, ok := err.(*xattr.Error); ok {
 		if serr, ok2 := xerr.Err.(syscall.Errno); ok2 {
@@ -1084,11 +1157,16 @@ func (fs *ocfs) RemoveGrant(ctx context.Context, ref *provider.Reference, g *pro
 		return errors.Wrap(err, "ocfs: error reading permissions")
 	}
 
+	// denials are listed as grants without permissions
+	prefix := sharePrefix
+	if g.Permissions == nil || proto.Equal(g.Permissions, &provider.ResourcePermissions{}) {
+		prefix = denyPrefix
+	}
 	var attr string
 	if g.Grantee.Type == provider.GranteeType_GRANTEE_TYPE_GROUP {
-		attr = sharePrefix + "g:" + g.Grantee.GetGroupId().OpaqueId
+		attr = prefix + "g:" + g.Grantee.GetGroupId().OpaqueId
 	} else {
-		attr = sharePrefix + "u:" + g.Grantee.GetUserId().OpaqueId
+		attr = prefix + "u:" + g.Grantee.GetUserId().OpaqueId
 	}
 
 	if err = xattr.Remove(ip, attr); err != nil {
@@ -1244,7 +1322,22 @@ func (fs *ocfs) setMtime(ctx context.Context, ip string, mtime string) error {
 	}
 	return nil
 }
-func (fs *ocfs) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) (err error) {
+
+// SetArbitraryMetadata sets the given metadata, keys reserved for spaces
+// cannot be set
+func (fs *ocfs) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	if err := spaces.CheckMetadata(md.GetMetadata()); err != nil {
+		return err
+	}
+	return fs.setArbitraryMetadata(ctx, ref, md)
+}
+
+// SetSpaceMetadata sets the metadata of the space rooted at the given reference
+func (fs *ocfs) SetSpaceMetadata(ctx context.Context, ref *provider.Reference, md map[string]string) error {
+	return fs.setArbitraryMetadata(ctx, ref, &provider.ArbitraryMetadata{Metadata: md})
+}
+
+func (fs *ocfs) setArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) (err error) {
 	log := appctx.GetLogger(ctx)
 
 	var ip string
@@ -1388,6 +1481,9 @@ func parseMTime(v string) (t time.Time, err error) {
 }
 
 func (fs *ocfs) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) (err error) {
+	if err := spaces.CheckKeys(keys); err != nil {
+		return err
+	}
 	log := appctx.GetLogger(ctx)
 
 	var ip string
@@ -1798,6 +1894,9 @@ func (fs *ocfs) listWithNominalHome(ctx context.Context, ip string, mdKeys []str
 	finfos := []*provider.ResourceInfo{}
 	for _, md := range mds {
 		cp := filepath.Join(ip, md.Name())
+		if fs.isDenied(ctx, cp) {
+			continue
+		}
 		m := fs.convertToResourceInfo(ctx, md, cp, fs.toStoragePath(ctx, cp), c, mdKeys)
 		finfos = append(finfos, m)
 	}
@@ -2031,8 +2130,47 @@ func (fs *ocfs) filterAsRevision(ctx context.Context, bn string, md os.FileInfo)
 	return nil
 }
 
+// DownloadRevision returns the content of a file version from the files_versions folder
 func (fs *ocfs) DownloadRevision(ctx context.Context, ref *provider.Reference, revisionKey string) (io.ReadCloser, error) {
-	return nil, errtypes.NotSupported("download revision")
+	ip, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return nil, errors.Wrap(err, "ocfs: error resolving reference")
+	}
+
+	// check permissions
+	if perm, err := fs.readPermissions(ctx, ip); err == nil {
+		if !perm.ListFileVersions || !perm.InitiateFileDownload {
+			return nil, errtypes.PermissionDenied("")
+		}
+	} else {
+		if isNotFound(err) {
+			return nil, errtypes.NotFound(fs.toStoragePath(ctx, filepath.Dir(ip)))
+		}
+		return nil, errors.Wrap(err, "ocfs: error reading permissions")
+	}
+
+	// versions are named after their mtime, reject anything else
+	if _, err := strconv.ParseUint(revisionKey, 10, 64); err != nil {
+		return nil, errtypes.NotFound(revisionKey)
+	}
+
+	rp := fs.getVersionsPath(ctx, ip) + ".v" + revisionKey
+	rs, err := os.Stat(rp)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errtypes.NotFound(revisionKey)
+		}
+		return nil, errors.Wrap(err, "ocfs: error reading revision "+revisionKey)
+	}
+	if !rs.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", rp)
+	}
+
+	r, err := os.Open(rp)
+	if err != nil {
+		return nil, errors.Wrap(err, "ocfs: error opening revision "+revisionKey)
+	}
+	return r, nil
 }
 
 func (fs *ocfs) RestoreRevision(ctx context.Context, ref *provider.Reference, revisionKey string) error {
@@ -2255,13 +2393,42 @@ func (fs *ocfs) RestoreRecycleItem(ctx context.Context, basePath, key, relativeP
 	return fs.propagate(ctx, tgt)
 }
 
+// ListStorageSpaces lists the home of the user as personal space and the
+// configured project directories as project spaces
 func (fs *ocfs) ListStorageSpaces(ctx context.Context, filter []*provider.ListStorageSpacesRequest_Filter) ([]*provider.StorageSpace, error) {
-	return nil, errtypes.NotSupported("list storage spaces")
+	return spaces.List(ctx, fs, fs.spacesLayout(ctx), filter)
 }
 
 // UpdateStorageSpace updates a storage space
 func (fs *ocfs) UpdateStorageSpace(ctx context.Context, req *provider.UpdateStorageSpaceRequest) (*provider.UpdateStorageSpaceResponse, error) {
-	return nil, errtypes.NotSupported("update storage space")
+	return spaces.Update(ctx, fs, fs.spacesLayout(ctx), spaces.GatewayPermissionChecker(fs.c.GatewaySVC), req)
+}
+
+func (fs *ocfs) spacesLayout(ctx context.Context) spaces.Layout {
+	l := spaces.Layout{Projects: fs.c.ProjectSpaces}
+	if fs.c.EnableHome {
+		l.Personal = "/"
+	} else if u, ok := ctxpkg.ContextGetUser(ctx); ok && u.Username != "" {
+		// without a home the storage path starts with the username
+		l.Personal = "/" + u.Username
+	}
+	return l
+}
+
+// checkQuota checks that writing size bytes to the given path fits into the
+// quota of its space
+func (fs *ocfs) checkQuota(ctx context.Context, p string, size uint64) error {
+	return spaces.CheckQuota(ctx, fs, fs.spacesLayout(ctx), p, size, func(root string) (uint64, error) {
+		return fs.spaceUsage.Usage(fs.toInternalPath(ctx, root))
+	})
+}
+
+// addSpaceUsage adds the bytes written to the given path to the usage of its
+// space
+func (fs *ocfs) addSpaceUsage(ctx context.Context, p string, delta int64) {
+	if root, ok := fs.spacesLayout(ctx).SpaceOf(p); ok {
+		fs.spaceUsage.Add(fs.toInternalPath(ctx, root), delta)
+	}
 }
 
 func (fs *ocfs) propagate(ctx context.Context, leafPath string) error {
@@ -121,6 +121,12 @@ func (fs *ocfs) InitiateUpload(ctx context.Context, ref *provider.Reference, upl
 		}
 	}
 
+	if !info.SizeIsDeferred {
+		if err := fs.checkQuota(ctx, p, uint64(uploadLength)); err != nil {
+			return nil, err
+		}
+	}
+
 	upload, err := fs.NewUpload(ctx, info)
 	if err != nil {
 		return nil, err
@@ -404,9 +410,19 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) error {
 
 	ip := upload.info.Storage["InternalDestination"]
 
+	fi, err := os.Stat(upload.binPath)
+	if err != nil {
+		return err
+	}
+	if err := upload.fs.checkQuota(upload.ctx, upload.fs.toStoragePath(upload.ctx, ip), uint64(fi.Size())); err != nil {
+		return err
+	}
+
 	// if destination exists
 	// TODO check etag with If-Match header
-	if _, err := os.Stat(ip); err == nil {
+	delta := fi.Size()
+	if old, err := os.Stat(ip); err == nil {
+		delta -= old.Size()
 		// copy attributes of existing file to tmp file
 		if err := upload.fs.copyMD(ip, upload.binPath); err != nil {
 			return errors.Wrap(err, "ocfs: error copying metadata from "+ip+" to "+upload.binPath)
@@ -417,7 +433,7 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) error {
 		}
 	}
 
-	err := os.Rename(upload.binPath, ip)
+	err = os.Rename(upload.binPath, ip)
 	if err != nil {
 		log.Err(err).Interface("info", upload.info).
 			Str("binPath", upload.binPath).
@@ -425,6 +441,7 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) error {
 			Msg("ocfs: could not rename")
 		return err
 	}
+	upload.fs.addSpaceUsage(upload.ctx, upload.fs.toStoragePath(upload.ctx, ip), delta)
 
 	// only delete the upload if it was successfully written to the storage
 	if err := os.Remove(upload.infoPath); err != nil {
@@ -287,6 +287,31 @@ func (c *Cache) List(storage interface{}, p string) ([]*File, error) {
 	return entries, nil
 }
 
+// Size returns the total size of the files below the specified storage/path
+func (c *Cache) Size(storage interface{}, p string) (uint64, error) {
+	storageID, err := toIntID(storage)
+	if err != nil {
+		return 0, err
+	}
+
+	// escape the LIKE wildcards of the path, '!' works as escape character in mysql and sqlite
+	prefix := "%"
+	if p = strings.Trim(p, "/"); p != "" {
+		prefix = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(p) + "/%"
+	}
+	// unknown sizes are stored as negative values
+	row := c.db.QueryRow(`
+		SELECT COALESCE(SUM(fc.size), 0)
+		FROM oc_filecache fc
+		LEFT JOIN oc_mimetypes mt ON fc.mimetype = mt.id
+		WHERE fc.storage = ? AND fc.path LIKE ? ESCAPE '!' AND fc.size > 0 AND mt.mimetype <> 'httpd/unix-directory'`, storageID, prefix)
+	var size uint64
+	if err := row.Scan(&size); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
 // Permissions returns the permissions for the specified storage/path
 func (c *Cache) Permissions(storage interface{}, p string) (*provider.ResourcePermissions, error) {
 	entry, err := c.Get(storage, p)
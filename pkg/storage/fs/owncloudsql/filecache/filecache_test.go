@@ -146,6 +146,30 @@ var _ = Describe("Filecache", func() {
 		})
 	})
 
+	Describe("Size", func() {
+		It("sums the sizes of the files below the path", func() {
+			size, err := cache.Size(1, "files")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(size).To(Equal(uint64(1047691)))
+
+			size, err = cache.Size(1, "files/Photos/")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(size).To(Equal(uint64(1011464)))
+		})
+
+		It("does not match siblings with the same prefix", func() {
+			size, err := cache.Size(1, "files/Photo")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(size).To(Equal(uint64(0)))
+		})
+
+		It("treats LIKE wildcards in the path literally", func() {
+			size, err := cache.Size(1, "files/Photo_")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(size).To(Equal(uint64(0)))
+		})
+	})
+
 	Describe("Path", func() {
 		It("returns the path", func() {
 			path, err := cache.Path(10)
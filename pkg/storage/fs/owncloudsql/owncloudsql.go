@@ -52,7 +52,9 @@ import (
 	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/fs/owncloudsql/filecache"
 	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	"github.com/cs3org/reva/pkg/storage/utils/ace"
 	"github.com/cs3org/reva/pkg/storage/utils/chunking"
+	"github.com/cs3org/reva/pkg/storage/utils/spaces"
 	"github.com/cs3org/reva/pkg/storage/utils/templates"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
@@ -72,6 +74,7 @@ const (
 	mdPrefix     string = ocPrefix + "md."   // arbitrary metadata
 	favPrefix    string = ocPrefix + "fav."  // favorite flag, per user
 	etagPrefix   string = ocPrefix + "etag." // allow overriding a calculated etag with one from the extended attributes
+	denyPrefix   string = ocPrefix + "deny." // denials, grants are managed by the share manager
 	checksumsKey string = "http://owncloud.org/ns/checksums"
 )
 
@@ -106,18 +109,20 @@ func init() {
 }
 
 type config struct {
-	DataDirectory            string `mapstructure:"datadirectory"`
-	UploadInfoDir            string `mapstructure:"upload_info_dir"`
-	DeprecatedShareDirectory string `mapstructure:"sharedirectory"`
-	ShareFolder              string `mapstructure:"share_folder"`
-	UserLayout               string `mapstructure:"user_layout"`
-	EnableHome               bool   `mapstructure:"enable_home"`
-	UserProviderEndpoint     string `mapstructure:"userprovidersvc"`
-	DbUsername               string `mapstructure:"dbusername"`
-	DbPassword               string `mapstructure:"dbpassword"`
-	DbHost                   string `mapstructure:"dbhost"`
-	DbPort                   int    `mapstructure:"dbport"`
-	DbName                   string `mapstructure:"dbname"`
+	DataDirectory            string   `mapstructure:"datadirectory"`
+	UploadInfoDir            string   `mapstructure:"upload_info_dir"`
+	DeprecatedShareDirectory string   `mapstructure:"sharedirectory"`
+	ShareFolder              string   `mapstructure:"share_folder"`
+	UserLayout               string   `mapstructure:"user_layout"`
+	EnableHome               bool     `mapstructure:"enable_home"`
+	UserProviderEndpoint     string   `mapstructure:"userprovidersvc"`
+	DbUsername               string   `mapstructure:"dbusername"`
+	DbPassword               string   `mapstructure:"dbpassword"`
+	DbHost                   string   `mapstructure:"dbhost"`
+	DbPort                   int      `mapstructure:"dbport"`
+	DbName                   string   `mapstructure:"dbname"`
+	ProjectSpaces            []string `mapstructure:"project_spaces"`
+	GatewaySVC               string   `mapstructure:"gatewaysvc"`
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -147,6 +152,7 @@ func (c *config) init(m map[string]interface{}) {
 	c.ShareFolder = filepath.Join("/", c.ShareFolder)
 
 	c.UserProviderEndpoint = sharedconf.GetGatewaySVC(c.UserProviderEndpoint)
+	c.GatewaySVC = sharedconf.GetGatewaySVC(c.GatewaySVC)
 }
 
 // New returns an implementation to of the storage.FS interface that talk to
@@ -437,6 +443,7 @@ func (fs *owncloudsqlfs) permissionSet(ctx context.Context, owner *userpb.UserId
 			AddGrant:             true,
 			CreateContainer:      true,
 			Delete:               true,
+			DenyGrant:            true,
 			GetPath:              true,
 			GetQuota:             true,
 			InitiateFileDownload: true,
@@ -521,6 +528,28 @@ func (fs *owncloudsqlfs) convertToResourceInfo(ctx context.Context, entry *filec
 		},
 	}
 
+	list, err := xattr.List(ip)
+	if err == nil {
+		for _, entry := range list {
+			// filter out non-custom properties
+			if !strings.HasPrefix(entry, mdPrefix) {
+				continue
+			}
+			if val, err := xattr.Get(ip, entry); err == nil {
+				k := entry[len(mdPrefix):]
+				if _, ok := mdKeysMap[k]; returnAllKeys || ok {
+					ri.ArbitraryMetadata.Metadata[k] = string(val)
+				}
+			} else {
+				appctx.GetLogger(ctx).Error().Err(err).
+					Str("entry", entry).
+					Msg("error retrieving xattr metadata")
+			}
+		}
+	} else {
+		appctx.GetLogger(ctx).Error().Err(err).Msg("error getting list of extended attributes")
+	}
+
 	if owner, err := fs.getUser(ctx, fs.getOwner(ip)); err == nil {
 		ri.Owner = owner.Id
 	} else {
@@ -580,7 +609,8 @@ func (fs *owncloudsqlfs) resolve(ctx context.Context, ref *provider.Reference) (
 			}
 			p = filepath.Join(owner, p)
 		}
-		return fs.toInternalPath(ctx, p), nil
+		// anchor the relative path so that it cannot leave the resource
+		return fs.toInternalPath(ctx, filepath.Join("/", p, filepath.Join("/", ref.GetPath()))), nil
 	}
 
 	if ref.GetPath() != "" {
@@ -591,8 +621,32 @@ func (fs *owncloudsqlfs) resolve(ctx context.Context, ref *provider.Reference) (
 	return "", fmt.Errorf("invalid reference %+v", ref)
 }
 
+// DenyGrant denies access to the resource for the grantee. Grants are managed by the share manager,
+// so the storage only persists denials, as ACEs in the extended attributes of the resource.
 func (fs *owncloudsqlfs) DenyGrant(ctx context.Context, ref *provider.Reference, g *provider.Grantee) error {
-	return errtypes.NotSupported("owncloudsqlfs: deny grant not supported")
+	ip, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return errors.Wrap(err, "owncloudsql: error resolving reference")
+	}
+
+	// check permissions
+	if perm, err := fs.readPermissions(ctx, ip); err == nil {
+		if !perm.DenyGrant {
+			return errtypes.PermissionDenied("")
+		}
+	} else {
+		if isNotFound(err) {
+			return errtypes.NotFound(fs.toStoragePath(ctx, ip))
+		}
+		return errors.Wrap(err, "owncloudsql: error reading permissions")
+	}
+
+	e := ace.FromDenial(g)
+	principal, value := e.Marshal()
+	if err := xattr.Set(ip, denyPrefix+principal, value); err != nil {
+		return err
+	}
+	return fs.propagate(ctx, ip)
 }
 
 func (fs *owncloudsqlfs) AddGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
@@ -612,6 +666,15 @@ func (fs *owncloudsqlfs) readPermissions(ctx context.Context, ip string) (p *pro
 		return ownerPermissions, nil
 	}
 
+	// denials on the node or its parents take precedence over the share permissions
+	home := filepath.Join(fs.c.DataDirectory, owner)
+	for np := ip; strings.HasPrefix(np, home+"/"); np = filepath.Dir(np) {
+		if fs.isDenied(ctx, np) {
+			appctx.GetLogger(ctx).Debug().Str("ipath", np).Msg("access denied")
+			return &provider.ResourcePermissions{}, nil
+		}
+	}
+
 	// otherwise this is a share
 	ownerStorageID, err := fs.filecache.GetNumericStorageID("home::" + owner)
 	if err != nil {
@@ -628,6 +691,68 @@ func (fs *owncloudsqlfs) readPermissions(ctx context.Context, ip string) (p *pro
 	return conversions.RoleFromOCSPermissions(perms).CS3ResourcePermissions(), nil
 }
 
+// isDenied checks if the node itself carries a denial for the current user or one of its groups.
+func (fs *owncloudsqlfs) isDenied(ctx context.Context, ip string) bool {
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok || fs.getOwner(ip) == u.Username {
+		return false
+	}
+	principals := make(map[string]bool, len(u.Groups)+1)
+	principals["u:"+u.Id.GetOpaqueId()] = true
+	for i := range u.Groups {
+		principals["g:"+u.Groups[i]] = true
+	}
+	denials, err := fs.readDenials(ctx, ip)
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Str("ipath", ip).Msg("error reading denials")
+		return false
+	}
+	for _, e := range denials {
+		if principals[e.Principal()] {
+			return true
+		}
+	}
+	return false
+}
+
+// readDenials reads the deny ACEs persisted on the node
+func (fs *owncloudsqlfs) readDenials(ctx context.Context, ip string) ([]*ace.ACE, error) {
+	attrs, err := xattr.List(ip)
+	if err != nil {
+		return nil, err
+	}
+	denials := []*ace.ACE{}
+	for i := range attrs {
+		if !strings.HasPrefix(attrs[i], denyPrefix) {
+			continue
+		}
+		principal := attrs[i][len(denyPrefix):]
+		value, err := xattr.Get(ip, attrs[i])
+		if err != nil {
+			appctx.GetLogger(ctx).Error().Err(err).Str("attr", attrs[i]).Msg("could not read attribute")
+			continue
+		}
+		e, err := ace.Unmarshal(principal, value)
+		if err != nil {
+			appctx.GetLogger(ctx).Error().Err(err).Str("principal", principal).Str("attr", attrs[i]).Msg("could not unmarshal ace")
+			continue
+		}
+		if e.IsDenial() {
+			denials = append(denials, e)
+		}
+	}
+	return denials, nil
+}
+
+func isNoData(err error) bool {
+	if xerr, ok := err.(*xattr.Error); ok {
+		if serr, ok2 := xerr.Err.(syscall.Errno); ok2 {
+			return serr == syscall.ENODATA
+		}
+	}
+	return false
+}
+
 // The os not exists error is buried inside the xattr error,
 // so we cannot just use os.IsNotExists().
 func isNotFound(err error) bool {
@@ -639,12 +764,67 @@ func isNotFound(err error) bool {
 	return false
 }
 
+// ListGrants lists the denials on the resource, grants are managed by the share manager
 func (fs *owncloudsqlfs) ListGrants(ctx context.Context, ref *provider.Reference) (grants []*provider.Grant, err error) {
-	return []*provider.Grant{}, nil // nop
+	ip, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return nil, errors.Wrap(err, "owncloudsql: error resolving reference")
+	}
+
+	// check permissions
+	if perm, err := fs.readPermissions(ctx, ip); err == nil {
+		if !perm.ListGrants {
+			return nil, errtypes.PermissionDenied("")
+		}
+	} else {
+		if isNotFound(err) {
+			return nil, errtypes.NotFound(fs.toStoragePath(ctx, ip))
+		}
+		return nil, errors.Wrap(err, "owncloudsql: error reading permissions")
+	}
+
+	denials, err := fs.readDenials(ctx, ip)
+	if err != nil {
+		return nil, errors.Wrap(err, "owncloudsql: error reading denials")
+	}
+	grants = make([]*provider.Grant, 0, len(denials))
+	for _, e := range denials {
+		grants = append(grants, e.Grant())
+	}
+	return grants, nil
 }
 
+// RemoveGrant removes a denial from the resource, grants are managed by the share manager
 func (fs *owncloudsqlfs) RemoveGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) (err error) {
-	return nil // nop
+	ip, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return errors.Wrap(err, "owncloudsql: error resolving reference")
+	}
+
+	attr := denyPrefix + ace.FromGrant(g).Principal()
+	if _, err := xattr.Get(ip, attr); err != nil {
+		if isNoData(err) {
+			return nil // nop
+		}
+		if isNotFound(err) {
+			return errtypes.NotFound(fs.toStoragePath(ctx, ip))
+		}
+		return err
+	}
+
+	// check permissions
+	if perm, err := fs.readPermissions(ctx, ip); err == nil {
+		if !perm.RemoveGrant {
+			return errtypes.PermissionDenied("")
+		}
+	} else {
+		return errors.Wrap(err, "owncloudsql: error reading permissions")
+	}
+
+	if err := xattr.Remove(ip, attr); err != nil {
+		return err
+	}
+	return fs.propagate(ctx, ip)
 }
 
 func (fs *owncloudsqlfs) UpdateGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
@@ -795,7 +975,22 @@ func (fs *owncloudsqlfs) setMtime(ctx context.Context, ip string, mtime string)
 	}
 	return nil
 }
-func (fs *owncloudsqlfs) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) (err error) {
+
+// SetArbitraryMetadata sets the given metadata, keys reserved for spaces
+// cannot be set
+func (fs *owncloudsqlfs) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	if err := spaces.CheckMetadata(md.GetMetadata()); err != nil {
+		return err
+	}
+	return fs.setArbitraryMetadata(ctx, ref, md)
+}
+
+// SetSpaceMetadata sets the metadata of the space rooted at the given reference
+func (fs *owncloudsqlfs) SetSpaceMetadata(ctx context.Context, ref *provider.Reference, md map[string]string) error {
+	return fs.setArbitraryMetadata(ctx, ref, &provider.ArbitraryMetadata{Metadata: md})
+}
+
+func (fs *owncloudsqlfs) setArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) (err error) {
 	log := appctx.GetLogger(ctx)
 
 	var ip string
@@ -939,6 +1134,9 @@ func parseMTime(v string) (t time.Time, err error) {
 }
 
 func (fs *owncloudsqlfs) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) (err error) {
+	if err := spaces.CheckKeys(keys); err != nil {
+		return err
+	}
 	log := appctx.GetLogger(ctx)
 
 	var ip string
@@ -1331,6 +1529,9 @@ func (fs *owncloudsqlfs) listWithNominalHome(ctx context.Context, ip string, mdK
 		if err != nil {
 			return nil, err
 		}
+		if fs.isDenied(ctx, cp) {
+			continue
+		}
 		m, err := fs.convertToResourceInfo(ctx, entry, cp, mdKeys)
 		if err != nil {
 			appctx.GetLogger(ctx).Error().Err(err).Str("path", cp).Msg("error converting to a resource info")
@@ -1515,8 +1716,47 @@ func (fs *owncloudsqlfs) ListRevisions(ctx context.Context, ref *provider.Refere
 	return revisions, nil
 }
 
+// DownloadRevision returns the content of a file version from the files_versions folder
 func (fs *owncloudsqlfs) DownloadRevision(ctx context.Context, ref *provider.Reference, revisionKey string) (io.ReadCloser, error) {
-	return nil, errtypes.NotSupported("download revision")
+	ip, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return nil, errors.Wrap(err, "owncloudsql: error resolving reference")
+	}
+
+	// check permissions
+	if perm, err := fs.readPermissions(ctx, ip); err == nil {
+		if !perm.ListFileVersions || !perm.InitiateFileDownload {
+			return nil, errtypes.PermissionDenied("")
+		}
+	} else {
+		if isNotFound(err) {
+			return nil, errtypes.NotFound(fs.toStoragePath(ctx, filepath.Dir(ip)))
+		}
+		return nil, errors.Wrap(err, "owncloudsql: error reading permissions")
+	}
+
+	// versions are named after their mtime, reject anything else
+	if _, err := strconv.ParseUint(revisionKey, 10, 64); err != nil {
+		return nil, errtypes.NotFound(revisionKey)
+	}
+
+	rp := fs.getVersionsPath(ctx, ip) + ".v" + revisionKey
+	rs, err := os.Stat(rp)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errtypes.NotFound(revisionKey)
+		}
+		return nil, errors.Wrap(err, "owncloudsql: error reading revision "+revisionKey)
+	}
+	if !rs.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", rp)
+	}
+
+	r, err := os.Open(rp)
+	if err != nil {
+		return nil, errors.Wrap(err, "owncloudsql: error opening revision "+revisionKey)
+	}
+	return r, nil
 }
 
 func (fs *owncloudsqlfs) RestoreRevision(ctx context.Context, ref *provider.Reference, revisionKey string) error {
@@ -1950,14 +2190,40 @@ func (fs *owncloudsqlfs) HashFile(path string) (string, string, string, error) {
 	}
 }
 
+// ListStorageSpaces lists the home of the user as personal space and the
+// configured project directories as project spaces
 func (fs *owncloudsqlfs) ListStorageSpaces(ctx context.Context, filter []*provider.ListStorageSpacesRequest_Filter) ([]*provider.StorageSpace, error) {
-	// TODO(corby): Implement
-	return nil, errtypes.NotSupported("list storage spaces")
+	return spaces.List(ctx, fs, fs.spacesLayout(ctx), filter)
 }
 
 // UpdateStorageSpace updates a storage space
 func (fs *owncloudsqlfs) UpdateStorageSpace(ctx context.Context, req *provider.UpdateStorageSpaceRequest) (*provider.UpdateStorageSpaceResponse, error) {
-	return nil, errtypes.NotSupported("update storage space")
+	return spaces.Update(ctx, fs, fs.spacesLayout(ctx), spaces.GatewayPermissionChecker(fs.c.GatewaySVC), req)
+}
+
+func (fs *owncloudsqlfs) spacesLayout(ctx context.Context) spaces.Layout {
+	l := spaces.Layout{Projects: fs.c.ProjectSpaces}
+	if fs.c.EnableHome {
+		l.Personal = "/"
+	} else if u, ok := ctxpkg.ContextGetUser(ctx); ok && u.Username != "" {
+		// without a home the storage path starts with the username
+		l.Personal = "/" + u.Username
+	}
+	return l
+}
+
+// checkQuota checks that writing size bytes to the given path fits into the
+// quota of its space
+func (fs *owncloudsqlfs) checkQuota(ctx context.Context, p string, size uint64) error {
+	return spaces.CheckQuota(ctx, fs, fs.spacesLayout(ctx), p, size, func(root string) (uint64, error) {
+		// the file cache knows the size of every file of the space
+		ip := fs.toInternalPath(ctx, root)
+		storage, err := fs.getStorage(ip)
+		if err != nil {
+			return 0, err
+		}
+		return fs.filecache.Size(storage, fs.toDatabasePath(ip))
+	})
 }
 
 func readChecksumIntoResourceChecksum(ctx context.Context, checksums, algo string, ri *provider.ResourceInfo) {
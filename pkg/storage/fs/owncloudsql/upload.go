@@ -117,6 +117,12 @@ func (fs *owncloudsqlfs) InitiateUpload(ctx context.Context, ref *provider.Refer
 		}
 	}
 
+	if !info.SizeIsDeferred {
+		if err := fs.checkQuota(ctx, p, uint64(uploadLength)); err != nil {
+			return nil, err
+		}
+	}
+
 	upload, err := fs.NewUpload(ctx, info)
 	if err != nil {
 		return nil, err
@@ -362,6 +368,14 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) error {
 
 	ip := upload.info.Storage["InternalDestination"]
 
+	fi, err := os.Stat(upload.binPath)
+	if err != nil {
+		return err
+	}
+	if err := upload.fs.checkQuota(upload.ctx, upload.fs.toStoragePath(upload.ctx, ip), uint64(fi.Size())); err != nil {
+		return err
+	}
+
 	// if destination exists
 	// TODO check etag with If-Match header
 	if _, err := os.Stat(ip); err == nil {
@@ -385,7 +399,6 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) error {
 		return err
 	}
 
-	var fi os.FileInfo
 	fi, err = os.Stat(ip)
 	if err != nil {
 		return err
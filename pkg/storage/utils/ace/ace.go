@@ -39,14 +39,15 @@ import (
 // see https://linux.die.net/man/5/nfs4_acl:
 // the extended attributes will look like this
 // "user.oc.grant.<type>:<flags>:<principal>:<permissions>"
-// - *type* will be limited to A for now
+// - *type* will be limited to A and D for now
 //     A: Allow - allow *principal* to perform actions requiring *permissions*
+//     D: Deny - deny *principal* any access to the node and its children,
+//               takes precedence over allow ACEs on the node or its parents
 //   In the future we can use:
 //     U: aUdit - log any attempted access by principal which requires
 //                permissions.
 //     L: aLarm - generate a system alarm at any attempted access by
 //                principal which requires permissions
-//     D: for Deny is not recommended
 // - *flags* for now empty or g for group, no inheritance yet
 //   - d directory-inherit - newly-created subdirectories will inherit the
 //                           ACE.
@@ -131,13 +132,31 @@ func FromGrant(g *provider.Grant) *ACE {
 		permissions: getACEPerm(g.Permissions),
 		// TODO creator ...
 	}
-	if g.Grantee.Type == provider.GranteeType_GRANTEE_TYPE_GROUP {
+	e.setPrincipal(g.Grantee)
+	return e
+}
+
+// FromDenial creates a deny ACE for a CS3 grantee
+func FromDenial(g *provider.Grantee) *ACE {
+	e := &ACE{
+		_type: "D",
+	}
+	e.setPrincipal(g)
+	return e
+}
+
+func (e *ACE) setPrincipal(g *provider.Grantee) {
+	if g.Type == provider.GranteeType_GRANTEE_TYPE_GROUP {
 		e.flags = "g"
-		e.principal = "g:" + g.Grantee.GetGroupId().OpaqueId
+		e.principal = "g:" + g.GetGroupId().OpaqueId
 	} else {
-		e.principal = "u:" + g.Grantee.GetUserId().OpaqueId
+		e.principal = "u:" + g.GetUserId().OpaqueId
 	}
-	return e
+}
+
+// IsDenial returns true if the ACE denies access to the principal
+func (e *ACE) IsDenial() bool {
+	return e._type == "D"
 }
 
 // Principal returns the principal of the ACE, eg. `u:<userid>` or `g:<groupid>`
@@ -179,7 +198,7 @@ func Unmarshal(principal string, v []byte) (e *ACE, err error) {
 	return
 }
 
-// Grant returns a CS3 grant
+// Grant returns a CS3 grant. Denials are represented by a grant without permissions.
 func (e *ACE) Grant() *provider.Grant {
 	g := &provider.Grant{
 		Grantee: &provider.Grantee{
@@ -207,6 +226,9 @@ func (e *ACE) granteeType() provider.GranteeType {
 // grantPermissionSet returns the set of CS3 resource permissions representing the ACE
 func (e *ACE) grantPermissionSet() *provider.ResourcePermissions {
 	p := &provider.ResourcePermissions{}
+	if e.IsDenial() {
+		return p
+	}
 	// r
 	if strings.Contains(e.permissions, "r") {
 		p.Stat = true
@@ -240,6 +262,7 @@ func (e *ACE) grantPermissionSet() *provider.ResourcePermissions {
 	// sharing
 	if strings.Contains(e.permissions, "C") {
 		p.AddGrant = true
+		p.DenyGrant = true
 		p.RemoveGrant = true
 		p.UpdateGrant = true
 	}
@@ -339,7 +362,7 @@ func getACEPerm(set *provider.ResourcePermissions) string {
 	}
 
 	// sharing
-	if set.AddGrant || set.RemoveGrant || set.UpdateGrant {
+	if set.AddGrant || set.DenyGrant || set.RemoveGrant || set.UpdateGrant {
 		b.WriteString("C")
 	}
 	if set.ListGrants {
@@ -70,12 +70,38 @@ var _ = Describe("ACE", func() {
 		})
 	})
 
+	Describe("FromDenial", func() {
+		It("creates a deny ACE for a user", func() {
+			ace := ace.FromDenial(userGrant.Grantee)
+			Expect(ace.Principal()).To(Equal("u:foo"))
+			Expect(ace.IsDenial()).To(BeTrue())
+		})
+
+		It("creates a deny ACE for a group", func() {
+			ace := ace.FromDenial(groupGrant.Grantee)
+			Expect(ace.Principal()).To(Equal("g:foo"))
+			Expect(ace.IsDenial()).To(BeTrue())
+		})
+
+		It("does not mark grants as denials", func() {
+			Expect(ace.FromGrant(userGrant).IsDenial()).To(BeFalse())
+		})
+	})
+
 	Describe("Grant", func() {
 		It("returns a proper Grant", func() {
 			ace := ace.FromGrant(userGrant)
 			grant := ace.Grant()
 			Expect(grant).To(Equal(userGrant))
 		})
+
+		It("returns a grant without permissions for denials", func() {
+			userGrant.Permissions.Stat = true
+			grant := ace.FromDenial(userGrant.Grantee).Grant()
+			userGrant.Permissions.Stat = false
+			Expect(grant.Grantee).To(Equal(userGrant.Grantee))
+			Expect(grant.Permissions).To(Equal(&provider.ResourcePermissions{}))
+		})
 	})
 
 	Describe("marshalling", func() {
@@ -88,6 +114,17 @@ var _ = Describe("ACE", func() {
 
 			Expect(unmarshalled).To(Equal(a))
 		})
+
+		It("works for denials", func() {
+			a := ace.FromDenial(groupGrant.Grantee)
+
+			marshalled, principal := a.Marshal()
+			unmarshalled, err := ace.Unmarshal(marshalled, principal)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(unmarshalled).To(Equal(a))
+			Expect(unmarshalled.IsDenial()).To(BeTrue())
+		})
 	})
 
 	Describe("converting permissions", func() {
@@ -169,6 +206,12 @@ var _ = Describe("ACE", func() {
 			userGrant.Permissions.UpdateGrant = false
 			Expect(newGrant.Permissions.UpdateGrant).To(BeTrue())
 			Expect(newGrant.Permissions.Delete).To(BeFalse())
+
+			userGrant.Permissions.DenyGrant = true
+			newGrant = ace.FromGrant(userGrant).Grant()
+			userGrant.Permissions.DenyGrant = false
+			Expect(newGrant.Permissions.DenyGrant).To(BeTrue())
+			Expect(newGrant.Permissions.Delete).To(BeFalse())
 		})
 
 		It("converts c", func() {
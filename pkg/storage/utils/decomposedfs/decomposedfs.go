@@ -32,6 +32,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
@@ -44,6 +45,7 @@ import (
 	"github.com/cs3org/reva/pkg/storage/utils/chunking"
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/node"
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/options"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/postprocessing"
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/tree"
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/xattrs"
 	"github.com/cs3org/reva/pkg/storage/utils/templates"
@@ -87,6 +89,7 @@ type Decomposedfs struct {
 	o            *options.Options
 	p            PermissionsChecker
 	chunkHandler *chunking.ChunkHandler
+	pp           *postprocessing.Pipeline
 }
 
 // NewDefault returns an instance with default components
@@ -126,13 +129,23 @@ func New(o *options.Options, lu *Lookup, p PermissionsChecker, tp Tree) (storage
 		return nil, errors.Wrap(err, "could not setup tree")
 	}
 
-	return &Decomposedfs{
+	pp, err := postprocessing.New(o.PostProcessing.Steps, o.PostProcessing.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	fs := &Decomposedfs{
 		tp:           tp,
 		lu:           lu,
 		o:            o,
 		p:            p,
 		chunkHandler: chunking.NewChunkHandler(filepath.Join(o.Root, "uploads")),
-	}, nil
+		pp:           pp,
+	}
+	if pp != nil {
+		fs.resumeProcessing()
+	}
+	return fs, nil
 }
 
 // Shutdown shuts down the storage
@@ -399,6 +412,10 @@ func (fs *Decomposedfs) Move(ctx context.Context, oldRef, newRef *provider.Refer
 		return errtypes.PermissionDenied(oldNode.ID)
 	}
 
+	if err := oldNode.CheckLockTree(ctx); err != nil {
+		return err
+	}
+
 	if newNode, err = fs.lu.NodeFromResource(ctx, newRef); err != nil {
 		return
 	}
@@ -495,6 +512,10 @@ func (fs *Decomposedfs) Delete(ctx context.Context, ref *provider.Reference) (er
 		return errtypes.PermissionDenied(filepath.Join(node.ParentID, node.Name))
 	}
 
+	if err := node.CheckLockTree(ctx); err != nil {
+		return err
+	}
+
 	return fs.tp.Delete(ctx, node)
 }
 
@@ -520,6 +541,11 @@ func (fs *Decomposedfs) Download(ctx context.Context, ref *provider.Reference) (
 		return nil, errtypes.PermissionDenied(filepath.Join(node.ParentID, node.Name))
 	}
 
+	// new files only have content once post-processing succeeded
+	if node.BlobID == "" && node.IsProcessing() {
+		return nil, errtypes.TooEarly(filepath.Join(node.ParentID, node.Name))
+	}
+
 	reader, err := fs.tp.ReadBlob(node.BlobID)
 	if err != nil {
 		return nil, errors.Wrap(err, "Decomposedfs: error download blob '"+node.ID+"'")
@@ -529,20 +555,64 @@ func (fs *Decomposedfs) Download(ctx context.Context, ref *provider.Reference) (
 
 // GetLock returns an existing lock on the given reference
 func (fs *Decomposedfs) GetLock(ctx context.Context, ref *provider.Reference) (*provider.Lock, error) {
-	return nil, errtypes.NotSupported("unimplemented")
+	node, err := fs.lockableNode(ctx, ref, func(rp *provider.ResourcePermissions) bool {
+		return rp.Stat
+	})
+	if err != nil {
+		return nil, err
+	}
+	return node.ReadLock(ctx)
 }
 
 // SetLock puts a lock on the given reference
 func (fs *Decomposedfs) SetLock(ctx context.Context, ref *provider.Reference, lock *provider.Lock) error {
-	return errtypes.NotSupported("unimplemented")
+	node, err := fs.lockableNode(ctx, ref, func(rp *provider.ResourcePermissions) bool {
+		return rp.InitiateFileUpload
+	})
+	if err != nil {
+		return err
+	}
+	return node.SetLock(ctx, lock, time.Duration(fs.o.LockExpiration)*time.Second)
 }
 
 // RefreshLock refreshes an existing lock on the given reference
 func (fs *Decomposedfs) RefreshLock(ctx context.Context, ref *provider.Reference, lock *provider.Lock) error {
-	return errtypes.NotSupported("unimplemented")
+	node, err := fs.lockableNode(ctx, ref, func(rp *provider.ResourcePermissions) bool {
+		return rp.InitiateFileUpload
+	})
+	if err != nil {
+		return err
+	}
+	return node.RefreshLock(ctx, lock, time.Duration(fs.o.LockExpiration)*time.Second)
 }
 
 // Unlock removes an existing lock from the given reference
 func (fs *Decomposedfs) Unlock(ctx context.Context, ref *provider.Reference) error {
-	return errtypes.NotSupported("unimplemented")
+	node, err := fs.lockableNode(ctx, ref, func(rp *provider.ResourcePermissions) bool {
+		return rp.InitiateFileUpload
+	})
+	if err != nil {
+		return err
+	}
+	return node.Unlock(ctx)
+}
+
+// lockableNode resolves the node for a lock operation and checks the permissions
+func (fs *Decomposedfs) lockableNode(ctx context.Context, ref *provider.Reference, check func(*provider.ResourcePermissions) bool) (*node.Node, error) {
+	node, err := fs.lu.NodeFromResource(ctx, ref)
+	if err != nil {
+		return nil, errors.Wrap(err, "Decomposedfs: error resolving ref")
+	}
+	if !node.Exists {
+		return nil, errtypes.NotFound(filepath.Join(node.ParentID, node.Name))
+	}
+
+	ok, err := fs.p.HasPermission(ctx, node, check)
+	switch {
+	case err != nil:
+		return nil, errtypes.InternalError(err.Error())
+	case !ok:
+		return nil, errtypes.PermissionDenied(filepath.Join(node.ParentID, node.Name))
+	}
+	return node, nil
 }
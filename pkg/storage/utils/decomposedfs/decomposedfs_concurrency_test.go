@@ -20,6 +20,7 @@ package decomposedfs_test
 
 import (
 	"context"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
@@ -28,6 +29,7 @@ import (
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs"
 	treemocks "github.com/cs3org/reva/pkg/storage/utils/decomposedfs/tree/mocks"
@@ -121,6 +123,43 @@ var _ = Describe("Decomposed", func() {
 			})
 		})
 
+		Describe("SetLock", func() {
+			It("grants the lock to exactly one caller", func() {
+				ref := &provider.Reference{Path: "/locked"}
+				Expect(fs.CreateDir(ctx, ref)).To(Succeed())
+
+				var (
+					wg     sync.WaitGroup
+					mu     sync.Mutex
+					locked []string
+				)
+				for i := 0; i < 10; i++ {
+					wg.Add(1)
+					go func(app string) {
+						defer GinkgoRecover()
+						defer wg.Done()
+						err := fs.SetLock(ctxpkg.ContextSetLockApp(ctx, app), ref, &provider.Lock{
+							Type:   provider.LockType_LOCK_TYPE_EXCL,
+							Holder: &provider.Lock_AppName{AppName: app},
+						})
+						if err != nil {
+							Expect(err).To(BeAssignableToTypeOf(errtypes.Locked("")))
+							return
+						}
+						mu.Lock()
+						locked = append(locked, app)
+						mu.Unlock()
+					}(fmt.Sprintf("app%d", i))
+				}
+				wg.Wait()
+
+				Expect(len(locked)).To(Equal(1))
+				lock, err := fs.GetLock(ctx, ref)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lock.GetAppName()).To(Equal(locked[0]))
+			})
+		})
+
 		Describe("CreateDir", func() {
 			It("handle already existing directories", func() {
 				for i := 0; i < 10; i++ {
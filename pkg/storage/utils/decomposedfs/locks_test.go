@@ -0,0 +1,220 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package decomposedfs_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/node"
+	helpers "github.com/cs3org/reva/pkg/storage/utils/decomposedfs/testhelpers"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/xattrs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/xattr"
+	"github.com/stretchr/testify/mock"
+)
+
+var _ = Describe("Locks", func() {
+	var (
+		env      *helpers.TestEnv
+		ref      *provider.Reference
+		file     *node.Node
+		lock     *provider.Lock
+		otherCtx context.Context
+	)
+
+	BeforeEach(func() {
+		otherCtx = ctxpkg.ContextSetUser(context.Background(), &userpb.User{
+			Id: &userpb.UserId{
+				Idp:      "idp",
+				OpaqueId: "otheruserid",
+				Type:     userpb.UserType_USER_TYPE_PRIMARY,
+			},
+			Username: "otheruser",
+		})
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		env, err = helpers.NewTestEnv()
+		Expect(err).ToNot(HaveOccurred())
+		env.Permissions.On("HasPermission", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+
+		file, err = env.Lookup.NodeFromPath(env.Ctx, "/dir1/file1", false)
+		Expect(err).ToNot(HaveOccurred())
+		ref = &provider.Reference{ResourceId: &provider.ResourceId{OpaqueId: file.ID}}
+		lock = &provider.Lock{
+			Type:     provider.LockType_LOCK_TYPE_EXCL,
+			Holder:   &provider.Lock_User{User: env.Owner.Id},
+			Metadata: "lockid",
+		}
+	})
+
+	AfterEach(func() {
+		if env != nil {
+			env.Cleanup()
+		}
+	})
+
+	It("returns no lock for unlocked nodes", func() {
+		l, err := env.Fs.GetLock(env.Ctx, ref)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(l).To(BeNil())
+	})
+
+	It("persists the lock", func() {
+		Expect(env.Fs.SetLock(env.Ctx, ref, lock)).To(Succeed())
+
+		l, err := env.Fs.GetLock(otherCtx, ref)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(l.Type).To(Equal(provider.LockType_LOCK_TYPE_EXCL))
+		Expect(l.GetUser().OpaqueId).To(Equal("userid"))
+		Expect(l.Metadata).To(Equal("lockid"))
+		Expect(l.Mtime).ToNot(BeNil())
+	})
+
+	It("rejects locks without a holder or type", func() {
+		lock.Holder = nil
+		Expect(env.Fs.SetLock(env.Ctx, ref, lock)).To(MatchError(errtypes.BadRequest("lock holder missing")))
+		lock.Holder = &provider.Lock_AppName{AppName: "app"}
+		lock.Type = provider.LockType_LOCK_TYPE_INVALID
+		Expect(env.Fs.SetLock(env.Ctx, ref, lock)).To(MatchError(errtypes.BadRequest("invalid lock type")))
+	})
+
+	It("only lets apps lock under their own name", func() {
+		lock.Holder = &provider.Lock_AppName{AppName: "app"}
+		Expect(env.Fs.SetLock(env.Ctx, ref, lock)).To(MatchError(errtypes.BadRequest("lock app name does not match the requesting app")))
+		Expect(env.Fs.SetLock(ctxpkg.ContextSetLockApp(env.Ctx, "otherapp"), ref, lock)).To(MatchError(errtypes.BadRequest("lock app name does not match the requesting app")))
+	})
+
+	It("denies deleting and moving folders containing locked nodes", func() {
+		Expect(env.Fs.SetLock(otherCtx, ref, lock)).To(Succeed())
+
+		dir := &provider.Reference{Path: "/dir1"}
+		Expect(env.Fs.Delete(env.Ctx, dir)).To(MatchError(errtypes.Locked("userid")))
+		Expect(env.Fs.Move(env.Ctx, dir, &provider.Reference{Path: "/moved"})).To(MatchError(errtypes.Locked("userid")))
+
+		Expect(env.Fs.Unlock(otherCtx, ref)).To(Succeed())
+		Expect(env.Fs.Move(env.Ctx, dir, &provider.Reference{Path: "/moved"})).To(Succeed())
+	})
+
+	It("does not lock nodes twice", func() {
+		Expect(env.Fs.SetLock(env.Ctx, ref, lock)).To(Succeed())
+		Expect(env.Fs.SetLock(otherCtx, ref, lock)).To(MatchError(errtypes.Locked("userid")))
+	})
+
+	It("ignores expired locks", func() {
+		err := xattr.Set(file.InternalPath(), xattrs.LockAttr, []byte(`{"type":3,"app_name":"app","expiration":"2000-01-01T00:00:00Z"}`))
+		Expect(err).ToNot(HaveOccurred())
+
+		l, err := env.Fs.GetLock(env.Ctx, ref)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(l).To(BeNil())
+		err = env.Fs.SetArbitraryMetadata(otherCtx, ref, &provider.ArbitraryMetadata{Metadata: map[string]string{"foo": "bar"}})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("denies uploads to users that did not set the lock", func() {
+		Expect(env.Fs.SetLock(otherCtx, ref, lock)).To(Succeed())
+
+		_, err := env.Fs.InitiateUpload(env.Ctx, ref, 10, map[string]string{})
+		Expect(err).To(MatchError(errtypes.Locked("userid")))
+	})
+
+	Context("with a lock held by an app", func() {
+		var appCtx, otherAppCtx context.Context
+
+		JustBeforeEach(func() {
+			appCtx = ctxpkg.ContextSetLockApp(env.Ctx, "app")
+			otherAppCtx = ctxpkg.ContextSetLockApp(otherCtx, "app")
+			lock.Holder = &provider.Lock_AppName{AppName: "app"}
+			Expect(env.Fs.SetLock(appCtx, ref, lock)).To(Succeed())
+		})
+
+		It("denies changes of other users outside of the app", func() {
+			for _, ctx := range []context.Context{otherCtx, ctxpkg.ContextSetLockApp(otherCtx, "otherapp")} {
+				Expect(env.Fs.Delete(ctx, ref)).To(MatchError(errtypes.Locked("app")))
+				Expect(env.Fs.Move(ctx, ref, &provider.Reference{Path: "/dir1/moved"})).To(MatchError(errtypes.Locked("app")))
+				err := env.Fs.SetArbitraryMetadata(ctx, ref, &provider.ArbitraryMetadata{Metadata: map[string]string{"foo": "bar"}})
+				Expect(err).To(MatchError(errtypes.Locked("app")))
+				err = env.Fs.UnsetArbitraryMetadata(ctx, ref, []string{"foo"})
+				Expect(err).To(MatchError(errtypes.Locked("app")))
+			}
+		})
+
+		It("allows other users to mark the node as favorite", func() {
+			err := env.Fs.SetArbitraryMetadata(otherCtx, ref, &provider.ArbitraryMetadata{Metadata: map[string]string{node.FavoriteKey: "1"}})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("allows changes to the user that set the lock", func() {
+			err := env.Fs.SetArbitraryMetadata(env.Ctx, ref, &provider.ArbitraryMetadata{Metadata: map[string]string{"foo": "bar"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(env.Fs.Move(env.Ctx, ref, &provider.Reference{Path: "/dir1/moved"})).To(Succeed())
+		})
+
+		It("allows changes to all users of the app", func() {
+			err := env.Fs.SetArbitraryMetadata(otherAppCtx, ref, &provider.ArbitraryMetadata{Metadata: map[string]string{"foo": "bar"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(env.Fs.Delete(appCtx, ref)).To(Succeed())
+		})
+
+		It("only allows the holder to refresh the lock", func() {
+			lock.Metadata = "newlockid"
+			Expect(env.Fs.RefreshLock(env.Ctx, ref, lock)).To(MatchError(errtypes.BadRequest("lock app name does not match the requesting app")))
+
+			lock.Holder = &provider.Lock_AppName{AppName: "otherapp"}
+			Expect(env.Fs.RefreshLock(ctxpkg.ContextSetLockApp(env.Ctx, "otherapp"), ref, lock)).To(MatchError(errtypes.Locked("app")))
+
+			lock.Holder = &provider.Lock_AppName{AppName: "app"}
+			Expect(env.Fs.RefreshLock(otherAppCtx, ref, lock)).To(Succeed())
+			l, err := env.Fs.GetLock(env.Ctx, ref)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(l.Metadata).To(Equal("newlockid"))
+		})
+
+		It("only allows the holder to unlock", func() {
+			Expect(env.Fs.Unlock(otherCtx, ref)).To(MatchError(errtypes.Locked("app")))
+			Expect(env.Fs.Unlock(appCtx, ref)).To(Succeed())
+			Expect(env.Fs.Unlock(appCtx, ref)).To(MatchError(errtypes.BadRequest("node is not locked")))
+			err := env.Fs.SetArbitraryMetadata(otherCtx, ref, &provider.ArbitraryMetadata{Metadata: map[string]string{"foo": "bar"}})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("keeps the lock when the app uploads a new revision", func() {
+			env.Blobstore.On("Upload", mock.Anything, mock.Anything).Return(nil)
+			uploadIds, err := env.Fs.InitiateUpload(appCtx, ref, 3, map[string]string{})
+			Expect(err).ToNot(HaveOccurred())
+			// the data transfer does not carry the app
+			err = env.Fs.Upload(env.Ctx, &provider.Reference{Path: "/" + uploadIds["simple"]}, ioutil.NopCloser(bytes.NewReader([]byte("foo"))))
+			Expect(err).ToNot(HaveOccurred())
+
+			l, err := env.Fs.GetLock(env.Ctx, ref)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(l).ToNot(BeNil())
+			Expect(l.GetAppName()).To(Equal("app"))
+		})
+	})
+})
@@ -58,6 +58,13 @@ func (fs *Decomposedfs) SetArbitraryMetadata(ctx context.Context, ref *provider.
 		return errtypes.PermissionDenied(filepath.Join(n.ParentID, n.Name))
 	}
 
+	// favorites are per user and can be set on locked nodes
+	if _, ok := md.GetMetadata()[node.FavoriteKey]; !ok || len(md.GetMetadata()) > 1 {
+		if err := n.CheckLock(ctx); err != nil {
+			return err
+		}
+	}
+
 	nodePath := n.InternalPath()
 
 	errs := []error{}
@@ -146,6 +153,13 @@ func (fs *Decomposedfs) UnsetArbitraryMetadata(ctx context.Context, ref *provide
 		return errtypes.PermissionDenied(filepath.Join(n.ParentID, n.Name))
 	}
 
+	// favorites are per user and can be unset on locked nodes
+	if len(keys) != 1 || keys[0] != node.FavoriteKey {
+		if err := n.CheckLock(ctx); err != nil {
+			return err
+		}
+	}
+
 	nodePath := n.InternalPath()
 	errs := []error{}
 	for _, k := range keys {
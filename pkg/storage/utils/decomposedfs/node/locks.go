@@ -0,0 +1,193 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/xattrs"
+	"github.com/cs3org/reva/pkg/storage/utils/locks"
+	"github.com/pkg/errors"
+	"github.com/pkg/xattr"
+)
+
+// lockMutexes serializes lock changes per node within this process
+var lockMutexes locks.Mutexes
+
+// readLock returns the lock of the node, or nil if it is not locked or the lock expired
+func (n *Node) readLock() (*locks.Lock, error) {
+	b, err := xattr.Get(n.InternalPath(), xattrs.LockAttr)
+	switch {
+	case err == nil:
+	case isAttrUnset(err):
+		return nil, nil
+	case isNotFound(err):
+		return nil, errtypes.NotFound(n.ID)
+	default:
+		return nil, errors.Wrap(err, "Decomposedfs: could not read lock")
+	}
+	l := &locks.Lock{}
+	if err := json.Unmarshal(b, l); err != nil {
+		return nil, errors.Wrap(err, "Decomposedfs: could not unmarshal lock")
+	}
+	if l.Expired() {
+		return nil, nil
+	}
+	return l, nil
+}
+
+func (n *Node) writeLock(l *locks.Lock, flags int) error {
+	b, err := json.Marshal(l)
+	if err != nil {
+		return errors.Wrap(err, "Decomposedfs: could not marshal lock")
+	}
+	if err := xattr.SetWithFlags(n.InternalPath(), xattrs.LockAttr, b, flags); err != nil {
+		return errors.Wrap(err, "Decomposedfs: could not write lock")
+	}
+	return nil
+}
+
+// ReadLock returns the lock of the node, or nil if it is not locked
+func (n *Node) ReadLock(ctx context.Context) (*provider.Lock, error) {
+	l, err := n.readLock()
+	if err != nil || l == nil {
+		return nil, err
+	}
+	return l.CS3(), nil
+}
+
+// SetLock locks the node until the lock expires after ttl, unless it is already locked
+func (n *Node) SetLock(ctx context.Context, lock *provider.Lock, ttl time.Duration) error {
+	l, err := locks.New(ctx, lock, ttl)
+	if err != nil {
+		return err
+	}
+
+	defer lockMutexes.Lock(n.ID)()
+
+	existing, err := n.readLock()
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return errtypes.Locked(existing.Holder())
+	}
+	// only create the attribute if there is none, so other processes cannot
+	// take the lock at the same time. Expired locks are replaced.
+	flags := xattr.XATTR_CREATE
+	if _, err := xattr.Get(n.InternalPath(), xattrs.LockAttr); err == nil {
+		flags = 0
+	}
+	if err := n.writeLock(l, flags); err != nil {
+		return errtypes.Locked(err.Error())
+	}
+	return nil
+}
+
+// RefreshLock updates the lock of the node and extends it by ttl. Only the
+// holder of the lock can refresh it.
+func (n *Node) RefreshLock(ctx context.Context, lock *provider.Lock, ttl time.Duration) error {
+	l, err := locks.New(ctx, lock, ttl)
+	if err != nil {
+		return err
+	}
+
+	defer lockMutexes.Lock(n.ID)()
+
+	existing, err := n.readLock()
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errtypes.BadRequest("node is not locked")
+	}
+	if !existing.SameHolder(lock) || !existing.HeldBy(ctx) {
+		return errtypes.Locked(existing.Holder())
+	}
+	return n.writeLock(l, xattr.XATTR_REPLACE)
+}
+
+// Unlock removes the lock of the node. Only the holder of the lock can remove it.
+func (n *Node) Unlock(ctx context.Context) error {
+	defer lockMutexes.Lock(n.ID)()
+
+	existing, err := n.readLock()
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errtypes.BadRequest("node is not locked")
+	}
+	if !existing.HeldBy(ctx) {
+		return errtypes.Locked(existing.Holder())
+	}
+	if err := xattr.Remove(n.InternalPath(), xattrs.LockAttr); err != nil && !isAttrUnset(err) {
+		return errors.Wrap(err, "Decomposedfs: could not remove lock")
+	}
+	return nil
+}
+
+// CheckLock returns an error if the node is locked by someone else than the
+// user in the context
+func (n *Node) CheckLock(ctx context.Context) error {
+	l, err := n.readLock()
+	if err != nil || l == nil || l.HeldBy(ctx) {
+		return err
+	}
+	return errtypes.Locked(l.Holder())
+}
+
+// CheckLockTree is like CheckLock, but also checks the descendants of
+// folders, which are moved or deleted together with them.
+func (n *Node) CheckLockTree(ctx context.Context) error {
+	if err := n.CheckLock(ctx); err != nil {
+		return err
+	}
+
+	dir := n.InternalPath()
+	f, err := os.Open(dir)
+	if err != nil {
+		return errors.Wrap(err, "Decomposedfs: could not open node")
+	}
+	defer f.Close()
+	if fi, err := f.Stat(); err != nil || !fi.IsDir() {
+		return err
+	}
+	names, err := f.Readdirnames(0)
+	if err != nil {
+		return errors.Wrap(err, "Decomposedfs: could not list children")
+	}
+	for _, name := range names {
+		link, err := os.Readlink(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		child := &Node{ID: filepath.Base(link), ParentID: n.ID, Name: name, lu: n.lu}
+		if err := child.CheckLockTree(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -57,6 +57,10 @@ const (
 	ChecksumsKey  = "http://owncloud.org/ns/checksums"
 	UserShareType = "0"
 	QuotaKey      = "quota"
+	StatusKey     = "status"
+
+	// ProcessingStatus is the status of nodes with an upload being post-processed
+	ProcessingStatus = "processing"
 
 	QuotaUncalculated = "-1"
 	QuotaUnknown      = "-2"
@@ -632,6 +636,19 @@ func (n *Node) AsResourceInfo(ctx context.Context, rp *provider.ResourcePermissi
 		}
 	}
 
+	// processing status
+	if n.IsProcessing() {
+		if ri.Opaque == nil {
+			ri.Opaque = &types.Opaque{
+				Map: map[string]*types.OpaqueEntry{},
+			}
+		}
+		ri.Opaque.Map[StatusKey] = &types.OpaqueEntry{
+			Decoder: "plain",
+			Value:   []byte(ProcessingStatus),
+		}
+	}
+
 	// only read the requested metadata attributes
 	attrs, err := xattr.List(nodePath)
 	if err != nil {
@@ -738,6 +755,12 @@ func readQuotaIntoOpaque(ctx context.Context, nodePath string, ri *provider.Reso
 	}
 }
 
+// IsProcessing returns whether an upload for the node is being post-processed
+func (n *Node) IsProcessing() bool {
+	_, err := xattr.Get(n.InternalPath(), xattrs.ProcessingAttr)
+	return err == nil
+}
+
 // HasPropagation checks if the propagation attribute exists and is set to "1"
 func (n *Node) HasPropagation() (propagation bool) {
 	if b, err := xattr.Get(n.lu.InternalPath(n.ID), xattrs.PropagationAttr); err == nil {
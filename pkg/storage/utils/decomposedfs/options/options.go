@@ -55,6 +55,20 @@ type Options struct {
 	OwnerType string `mapstructure:"owner_type"`
 
 	GatewayAddr string `mapstructure:"gateway_addr"`
+
+	// LockExpiration is the time in seconds after which locks expire unless they are refreshed
+	LockExpiration int `mapstructure:"lock_expiration"`
+
+	// PostProcessing configures the steps finished uploads pass through before they become the current revision
+	PostProcessing PostProcessing `mapstructure:"postprocessing"`
+}
+
+// PostProcessing defines the post-processing pipeline for uploads.
+type PostProcessing struct {
+	// Steps lists the steps to run in order, e.g. checksum, virusscan, metadata or thumbnails
+	Steps []string `mapstructure:"steps"`
+	// Config holds the configuration of the steps by name
+	Config map[string]map[string]interface{} `mapstructure:"config"`
 }
 
 // New returns a new Options instance for the given configuration
@@ -77,6 +91,10 @@ func New(m map[string]interface{}) (*Options, error) {
 	// ensure share folder always starts with slash
 	o.ShareFolder = filepath.Join("/", o.ShareFolder)
 
+	if o.LockExpiration == 0 {
+		o.LockExpiration = 30 * 60
+	}
+
 	// c.DataDirectory should never end in / unless it is the root
 	o.Root = filepath.Clean(o.Root)
 
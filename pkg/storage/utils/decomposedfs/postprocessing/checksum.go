@@ -0,0 +1,73 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package postprocessing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	Register("checksum", newChecksum)
+}
+
+type checksumConfig struct {
+	// Require rejects uploads without a checksum
+	Require bool `mapstructure:"require"`
+}
+
+type checksum struct {
+	c *checksumConfig
+}
+
+func newChecksum(m map[string]interface{}) (Step, error) {
+	c := &checksumConfig{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "checksum: error decoding conf")
+	}
+	return &checksum{c: c}, nil
+}
+
+// Process compares the checksum sent by the client with the one computed
+// for the uploaded bytes.
+func (s *checksum) Process(ctx context.Context, u *Upload) error {
+	expected := u.MetaData["checksum"]
+	if expected == "" {
+		if s.c.Require {
+			return errtypes.BadRequest("checksum required")
+		}
+		return nil
+	}
+	parts := strings.SplitN(expected, " ", 2)
+	if len(parts) != 2 {
+		return errtypes.BadRequest("invalid checksum format. must be '[algorithm] [checksum]'")
+	}
+	actual, ok := u.Checksums[parts[0]]
+	if !ok {
+		return errtypes.BadRequest("unsupported checksum algorithm: " + parts[0])
+	}
+	if !strings.EqualFold(parts[1], actual) {
+		return errtypes.ChecksumMismatch("invalid checksum: expected " + expected + " got " + actual)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package postprocessing
+
+import (
+	"context"
+	"image"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	// register the image formats we extract dimensions from
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// Keys of the metadata extracted by the metadata step
+const (
+	ContentTypeKey = "content-type"
+	ImageWidthKey  = "image.width"
+	ImageHeightKey = "image.height"
+)
+
+func init() {
+	Register("metadata", newMetadata)
+}
+
+type metadata struct{}
+
+func newMetadata(m map[string]interface{}) (Step, error) {
+	return &metadata{}, nil
+}
+
+// Process sniffs the content type of the uploaded bytes and the dimensions
+// of images.
+func (s *metadata) Process(ctx context.Context, u *Upload) error {
+	f, err := u.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// http.DetectContentType considers at most 512 bytes
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	contentType := http.DetectContentType(buf[:n])
+	u.Results[ContentTypeKey] = contentType
+
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	// unknown or broken images are not an error, we just cannot tell their size
+	if cfg, _, err := image.DecodeConfig(f); err == nil {
+		u.Results[ImageWidthKey] = strconv.Itoa(cfg.Width)
+		u.Results[ImageHeightKey] = strconv.Itoa(cfg.Height)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package postprocessing
+
+import (
+	"context"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// Step is a single step of the post-processing pipeline. A step that returns
+// an error rejects the upload.
+type Step interface {
+	Process(ctx context.Context, u *Upload) error
+}
+
+// NewFunc is the function that post-processing steps
+// should register at init time.
+type NewFunc func(map[string]interface{}) (Step, error)
+
+// NewFuncs is a map containing all the registered post-processing steps.
+var NewFuncs = map[string]NewFunc{}
+
+// Register registers a new post-processing step new function.
+// Not safe for concurrent use. Safe for use from package init.
+func Register(name string, f NewFunc) {
+	NewFuncs[name] = f
+}
+
+// Upload describes a finished upload passing through the pipeline.
+type Upload struct {
+	// ID is the id of the upload, which is also used as the blob id
+	ID string
+	// Filename is the base name of the uploaded file
+	Filename string
+	// Path is the path of the uploaded bytes on local disk
+	Path string
+	Size int64
+	// MetaData holds the metadata the client sent with the upload, e.g. the expected checksum
+	MetaData map[string]string
+	// Checksums holds the hex encoded checksums of the uploaded bytes by algorithm
+	Checksums map[string]string
+	// Results collects the arbitrary metadata steps want to set on the node
+	// once all steps succeeded
+	Results map[string]string
+}
+
+// Open opens the uploaded bytes for reading.
+func (u *Upload) Open() (*os.File, error) {
+	return os.Open(u.Path)
+}
+
+type namedStep struct {
+	name string
+	step Step
+}
+
+// Pipeline runs uploads through a list of steps.
+type Pipeline struct {
+	steps []namedStep
+}
+
+// New returns a pipeline running the given steps in order. Each step is
+// configured with its entry in conf. It returns nil if no steps are given.
+func New(steps []string, conf map[string]map[string]interface{}) (*Pipeline, error) {
+	if len(steps) == 0 {
+		return nil, nil
+	}
+	p := &Pipeline{}
+	for _, name := range steps {
+		f, ok := NewFuncs[name]
+		if !ok {
+			return nil, errors.Errorf("postprocessing: step %q not found", name)
+		}
+		s, err := f(conf[name])
+		if err != nil {
+			return nil, errors.Wrapf(err, "postprocessing: error creating step %q", name)
+		}
+		p.steps = append(p.steps, namedStep{name: name, step: s})
+	}
+	return p, nil
+}
+
+// Run passes the upload through all steps and stops at the first failing one.
+func (p *Pipeline) Run(ctx context.Context, u *Upload) error {
+	if u.Results == nil {
+		u.Results = map[string]string{}
+	}
+	for _, s := range p.steps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.step.Process(ctx, u); err != nil {
+			return errors.Wrapf(err, "postprocessing: step %q failed", s.name)
+		}
+	}
+	return nil
+}
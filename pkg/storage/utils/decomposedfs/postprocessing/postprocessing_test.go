@@ -0,0 +1,188 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package postprocessing_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/postprocessing"
+	"github.com/cs3org/reva/pkg/thumbnails"
+	"github.com/pkg/errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Postprocessing", func() {
+	var (
+		dir string
+		u   *postprocessing.Upload
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "postprocessing-test-*")
+		Expect(err).ToNot(HaveOccurred())
+
+		u = &postprocessing.Upload{
+			ID:       "upload",
+			Filename: "file.txt",
+			Path:     filepath.Join(dir, "upload"),
+			MetaData: map[string]string{},
+			Checksums: map[string]string{
+				"sha1": "87acec17cd9dcd20a716cc2cf67417b71c8a7016",
+			},
+		}
+		Expect(ioutil.WriteFile(u.Path, []byte("0123456789"), 0600)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	Describe("New", func() {
+		It("returns no pipeline without steps", func() {
+			p, err := postprocessing.New(nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p).To(BeNil())
+		})
+
+		It("fails for unknown steps", func() {
+			_, err := postprocessing.New([]string{"unknown"}, nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("checksum", func() {
+		var p *postprocessing.Pipeline
+
+		BeforeEach(func() {
+			var err error
+			p, err = postprocessing.New([]string{"checksum"}, nil)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("accepts uploads without a checksum", func() {
+			Expect(p.Run(context.Background(), u)).To(Succeed())
+		})
+
+		It("accepts matching checksums", func() {
+			u.MetaData["checksum"] = "sha1 87ACEC17CD9DCD20A716CC2CF67417B71C8A7016"
+			Expect(p.Run(context.Background(), u)).To(Succeed())
+		})
+
+		It("rejects mismatching checksums", func() {
+			u.MetaData["checksum"] = "sha1 0000000000000000000000000000000000000000"
+			err := p.Run(context.Background(), u)
+			Expect(err).To(HaveOccurred())
+			_, ok := errors.Cause(err).(errtypes.IsChecksumMismatch)
+			Expect(ok).To(BeTrue())
+		})
+
+		It("rejects uploads without a checksum when required", func() {
+			p, err := postprocessing.New([]string{"checksum"}, map[string]map[string]interface{}{
+				"checksum": {"require": true},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.Run(context.Background(), u)).ToNot(Succeed())
+		})
+	})
+
+	Describe("metadata", func() {
+		var p *postprocessing.Pipeline
+
+		BeforeEach(func() {
+			var err error
+			p, err = postprocessing.New([]string{"metadata"}, nil)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("detects the content type", func() {
+			Expect(p.Run(context.Background(), u)).To(Succeed())
+			Expect(u.Results[postprocessing.ContentTypeKey]).To(Equal("text/plain; charset=utf-8"))
+			Expect(u.Results).ToNot(HaveKey(postprocessing.ImageWidthKey))
+		})
+
+		It("extracts the dimensions of images", func() {
+			var buf bytes.Buffer
+			Expect(png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 16, 9)))).To(Succeed())
+			Expect(ioutil.WriteFile(u.Path, buf.Bytes(), 0600)).To(Succeed())
+
+			Expect(p.Run(context.Background(), u)).To(Succeed())
+			Expect(u.Results[postprocessing.ContentTypeKey]).To(Equal("image/png"))
+			Expect(u.Results[postprocessing.ImageWidthKey]).To(Equal("16"))
+			Expect(u.Results[postprocessing.ImageHeightKey]).To(Equal("9"))
+		})
+	})
+
+	Describe("thumbnails", func() {
+		It("requires a thumbnail dir", func() {
+			_, err := postprocessing.New([]string{"thumbnails"}, nil)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("renders thumbnails of images for the previews", func() {
+			conf := map[string]interface{}{"dir": filepath.Join(dir, "thumbnails"), "sizes": []string{"8x8"}}
+			p, err := postprocessing.New([]string{"thumbnails"}, map[string]map[string]interface{}{"thumbnails": conf})
+			Expect(err).ToNot(HaveOccurred())
+
+			var buf bytes.Buffer
+			Expect(png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 16, 16)))).To(Succeed())
+			Expect(ioutil.WriteFile(u.Path, buf.Bytes(), 0600)).To(Succeed())
+			u.Filename = "image.png"
+			u.Size = int64(buf.Len())
+
+			Expect(p.Run(context.Background(), u)).To(Succeed())
+
+			// the previews find the thumbnail without fetching the content
+			m, err := thumbnails.New(&thumbnails.Config{Dir: filepath.Join(dir, "thumbnails")})
+			Expect(err).ToNot(HaveOccurred())
+			defer m.Close()
+			t, err := m.Get(context.Background(), &thumbnails.Request{
+				ResourceID: "id",
+				Etag:       "etag",
+				Checksum:   u.Checksums["sha1"],
+				MimeType:   "image/png",
+				Width:      8,
+				Height:     8,
+			}, func(context.Context) (io.ReadCloser, error) {
+				return nil, errors.New("the content must not be fetched")
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(t.MimeType).To(Equal("image/png"))
+		})
+
+		It("accepts files without thumbnails", func() {
+			p, err := postprocessing.New([]string{"thumbnails"}, map[string]map[string]interface{}{
+				"thumbnails": {"dir": filepath.Join(dir, "thumbnails")},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.Run(context.Background(), u)).To(Succeed())
+		})
+	})
+
+})
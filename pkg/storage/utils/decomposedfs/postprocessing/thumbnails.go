@@ -0,0 +1,135 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package postprocessing
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/mime"
+	"github.com/cs3org/reva/pkg/thumbnails"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	Register("thumbnails", newThumbnails)
+}
+
+type thumbnailsConfig struct {
+	// Config configures the rendering, its dir has to be shared with the
+	// previews of ocdav, which serve the rendered thumbnails
+	thumbnails.Config `mapstructure:",squash"`
+	// Sizes lists the thumbnails to render as WIDTHxHEIGHT
+	Sizes []string `mapstructure:"sizes"`
+	// KeepAspect renders thumbnails scaled to fit into the sizes instead of
+	// cropped, like previews requested with a=1
+	KeepAspect bool `mapstructure:"keep_aspect"`
+}
+
+func (c *thumbnailsConfig) init() {
+	if len(c.Sizes) == 0 {
+		c.Sizes = []string{"36x36", "1280x1024"}
+	}
+}
+
+type size struct {
+	width, height int
+}
+
+type thumbnailsStep struct {
+	c     *thumbnailsConfig
+	m     *thumbnails.Manager
+	sizes []size
+}
+
+func newThumbnails(m map[string]interface{}) (Step, error) {
+	c := &thumbnailsConfig{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "thumbnails: error decoding conf")
+	}
+	c.init()
+	if c.Dir == "" {
+		return nil, errors.New("thumbnails: dir must be configured")
+	}
+
+	s := &thumbnailsStep{c: c}
+	for _, v := range c.Sizes {
+		parts := strings.SplitN(v, "x", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("thumbnails: invalid size %q", v)
+		}
+		w, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, errors.Wrapf(err, "thumbnails: invalid size %q", v)
+		}
+		h, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "thumbnails: invalid size %q", v)
+		}
+		s.sizes = append(s.sizes, size{width: w, height: h})
+	}
+
+	// the thumbnails are kept on disk, keep the in memory cache small
+	c.CacheSize = len(s.sizes)
+	mgr, err := thumbnails.New(&c.Config)
+	if err != nil {
+		return nil, err
+	}
+	s.m = mgr
+	return s, nil
+}
+
+// Process renders the configured thumbnails into the thumbnail directory,
+// where ocdav finds them by the SHA1 checksum of the content. Files
+// thumbnails cannot be rendered for are still valid uploads, so failures
+// are only logged.
+func (s *thumbnailsStep) Process(ctx context.Context, u *Upload) error {
+	log := appctx.GetLogger(ctx)
+	mimeType := mime.Detect(false, u.Filename)
+	if !s.m.Supports(mimeType) {
+		return nil
+	}
+	checksum := u.Checksums["sha1"]
+	if checksum == "" {
+		log.Debug().Str("upload", u.ID).Msg("thumbnails: no checksum to keep the thumbnails by")
+		return nil
+	}
+
+	for _, sz := range s.sizes {
+		_, err := s.m.Get(ctx, &thumbnails.Request{
+			Checksum:   checksum,
+			MimeType:   mimeType,
+			Size:       uint64(u.Size),
+			Width:      sz.width,
+			Height:     sz.height,
+			KeepAspect: s.c.KeepAspect,
+		}, func(context.Context) (io.ReadCloser, error) {
+			return u.Open()
+		})
+		if err != nil {
+			log.Debug().Err(err).Str("upload", u.ID).Msg("thumbnails: could not render thumbnail")
+			return nil
+		}
+	}
+	return nil
+}
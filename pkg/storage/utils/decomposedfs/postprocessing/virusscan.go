@@ -0,0 +1,76 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package postprocessing
+
+import (
+	"context"
+
+	"github.com/cs3org/reva/pkg/antivirus"
+	"github.com/cs3org/reva/pkg/antivirus/scanner/registry"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	Register("virusscan", newVirusScan)
+}
+
+type virusScanConfig struct {
+	Scanner  string                            `mapstructure:"scanner"`
+	Scanners map[string]map[string]interface{} `mapstructure:"scanners"`
+}
+
+type virusScan struct {
+	scanner antivirus.Scanner
+}
+
+func newVirusScan(m map[string]interface{}) (Step, error) {
+	c := &virusScanConfig{}
+	if err := mapstructure.Decode(m, c); err != nil {
+		return nil, errors.Wrap(err, "virusscan: error decoding conf")
+	}
+	f, ok := registry.NewFuncs[c.Scanner]
+	if !ok {
+		return nil, errors.Errorf("virusscan: virus scanner %q not found", c.Scanner)
+	}
+	scanner, err := f(c.Scanners[c.Scanner])
+	if err != nil {
+		return nil, errors.Wrap(err, "virusscan: error creating virus scanner")
+	}
+	return &virusScan{scanner: scanner}, nil
+}
+
+// Process scans the uploaded bytes and rejects infected uploads.
+func (s *virusScan) Process(ctx context.Context, u *Upload) error {
+	f, err := u.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	res, err := s.scanner.Scan(ctx, u.Filename, f)
+	if err != nil {
+		return errors.Wrap(err, "virusscan: error scanning upload")
+	}
+	if res.Infected {
+		return errtypes.PermissionDenied("virus found: " + res.Description)
+	}
+	return nil
+}
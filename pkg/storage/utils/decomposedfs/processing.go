@@ -0,0 +1,262 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package decomposedfs
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/node"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/postprocessing"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/xattrs"
+	"github.com/pkg/errors"
+	"github.com/pkg/xattr"
+	tusd "github.com/tus/tusd/pkg/handler"
+)
+
+// startProcessing marks the node as processing and runs the post-processing
+// pipeline in the background. New files get a placeholder node without a blob
+// so they show up in listings while being processed. The state is kept in the
+// upload info, so that the processing can be resumed after a restart.
+func (upload *fileUpload) startProcessing(n *node.Node, hashes map[string]hash.Hash) error {
+	placeholder := false
+	if _, err := os.Stat(n.InternalPath()); os.IsNotExist(err) {
+		placeholder = true
+	}
+
+	upload.info.Storage["NodeId"] = n.ID
+	upload.info.Storage["Processing"] = "true"
+	if placeholder {
+		upload.info.Storage["ProcessingPlaceholder"] = "true"
+	}
+	if err := upload.writeInfo(); err != nil {
+		return errors.Wrap(err, "Decomposedfs: could not persist processing state")
+	}
+
+	return upload.runProcessing(n, placeholder, hashes)
+}
+
+// resumeProcessing restarts the post-processing of the uploads that were
+// being processed when the storage was stopped.
+func (fs *Decomposedfs) resumeProcessing() {
+	infos, err := filepath.Glob(filepath.Join(fs.o.Root, "uploads", "*.info"))
+	if err != nil {
+		return
+	}
+	for _, infoPath := range infos {
+		info := tusd.FileInfo{}
+		data, err := ioutil.ReadFile(infoPath)
+		if err != nil || json.Unmarshal(data, &info) != nil || info.Storage["Processing"] == "" {
+			continue
+		}
+
+		var upload *fileUpload
+		if u, err := fs.GetUpload(context.Background(), info.ID); err == nil {
+			upload = u.(*fileUpload)
+		} else {
+			// the uploaded bytes are gone, resumeProcessing discards the upload
+			upload = &fileUpload{
+				info:     info,
+				binPath:  info.Storage["BinPath"],
+				infoPath: infoPath,
+				fs:       fs,
+				ctx:      context.Background(),
+			}
+		}
+		upload.resumeProcessing()
+	}
+}
+
+// resumeProcessing runs the upload through the pipeline again. The upload
+// is discarded if that is not possible.
+func (upload *fileUpload) resumeProcessing() {
+	log := appctx.GetLogger(upload.ctx)
+	placeholder := upload.info.Storage["ProcessingPlaceholder"] != ""
+
+	n := node.New(
+		upload.info.Storage["NodeId"],
+		upload.info.Storage["NodeParentId"],
+		upload.info.Storage["NodeName"],
+		0,
+		"",
+		nil,
+		upload.fs.lu,
+	)
+	n.SpaceRoot = node.New(upload.info.Storage["SpaceRoot"], "", "", 0, "", nil, upload.fs.lu)
+
+	fi, err := os.Stat(upload.binPath)
+	if err != nil {
+		log.Err(err).Interface("info", upload.info).Msg("Decomposedfs: could not resume processing")
+		upload.failProcessing(upload.ctx, n, placeholder)
+		return
+	}
+	n.Blobsize = fi.Size()
+
+	sublog := log.With().Interface("info", upload.info).Logger()
+	if err := upload.runProcessing(n, placeholder, upload.checksums(&sublog)); err != nil {
+		sublog.Err(err).Msg("Decomposedfs: could not resume processing")
+		upload.failProcessing(upload.ctx, n, placeholder)
+		return
+	}
+	sublog.Info().Msg("Decomposedfs: resumed processing")
+}
+
+// runProcessing marks the node as processing and runs the pipeline in the background.
+func (upload *fileUpload) runProcessing(n *node.Node, placeholder bool, hashes map[string]hash.Hash) error {
+	// the request context is gone once the upload returns
+	ctx := appctx.WithLogger(context.Background(), appctx.GetLogger(upload.ctx))
+	if u, ok := ctxpkg.ContextGetUser(upload.ctx); ok {
+		ctx = ctxpkg.ContextSetUser(ctx, u)
+	}
+	sublog := appctx.GetLogger(ctx).With().Interface("info", upload.info).Str("targetPath", n.InternalPath()).Logger()
+
+	if _, err := os.Stat(n.InternalPath()); placeholder && os.IsNotExist(err) {
+		if err := upload.createPlaceholder(n); err != nil {
+			return err
+		}
+	}
+	if err := xattr.Set(n.InternalPath(), xattrs.ProcessingAttr, []byte(upload.info.ID)); err != nil {
+		return errors.Wrap(err, "Decomposedfs: could not set processing attribute")
+	}
+	if err := upload.fs.tp.Propagate(ctx, n); err != nil {
+		sublog.Err(err).Msg("Decomposedfs: could not propagate processing node")
+	}
+
+	u := &postprocessing.Upload{
+		ID:        upload.info.ID,
+		Filename:  n.Name,
+		Path:      upload.binPath,
+		Size:      n.Blobsize,
+		MetaData:  upload.info.MetaData,
+		Checksums: map[string]string{},
+	}
+	for algo, h := range hashes {
+		u.Checksums[algo] = hex.EncodeToString(h.Sum(nil))
+	}
+
+	go upload.process(ctx, n, placeholder, u, hashes)
+	return nil
+}
+
+// process runs the upload through the pipeline and makes it the current
+// revision if all steps succeed. Failed uploads are discarded together with
+// the placeholder node, if one was created.
+func (upload *fileUpload) process(ctx context.Context, n *node.Node, placeholder bool, u *postprocessing.Upload, hashes map[string]hash.Hash) {
+	sublog := appctx.GetLogger(ctx).With().Interface("info", upload.info).Str("targetPath", n.InternalPath()).Logger()
+
+	if err := upload.fs.pp.Run(ctx, u); err != nil {
+		sublog.Info().Err(err).Msg("Decomposedfs: upload rejected by post-processing")
+		upload.failProcessing(ctx, n, placeholder)
+		return
+	}
+
+	// unmark the node before it becomes a version
+	upload.endProcessing(n)
+	if err := upload.finalize(ctx, n, hashes); err != nil {
+		sublog.Err(err).Msg("Decomposedfs: could not finalize processed upload")
+		upload.failProcessing(ctx, n, placeholder)
+		return
+	}
+	upload.discardChunk()
+
+	for k, v := range u.Results {
+		if err := n.SetMetadata(xattrs.MetadataPrefix+k, v); err != nil {
+			sublog.Err(err).Str("key", k).Msg("Decomposedfs: could not set post-processing metadata")
+		}
+	}
+}
+
+// failProcessing discards the upload together with the placeholder node, if one was created.
+func (upload *fileUpload) failProcessing(ctx context.Context, n *node.Node, placeholder bool) {
+	upload.endProcessing(n)
+	upload.discardChunk()
+	if placeholder {
+		upload.removePlaceholder(ctx, n)
+	}
+}
+
+// createPlaceholder creates an empty node for a new file that is being processed
+func (upload *fileUpload) createPlaceholder(n *node.Node) error {
+	f, err := os.OpenFile(n.InternalPath(), os.O_CREATE|os.O_EXCL, defaultFilePerm)
+	if err != nil {
+		return errors.Wrap(err, "Decomposedfs: could not create placeholder node")
+	}
+	f.Close()
+
+	placeholder := node.New(n.ID, n.ParentID, n.Name, 0, "", nil, upload.fs.lu)
+	// node.New generates a blob id, placeholders are recognized by not having one
+	placeholder.BlobID = ""
+	err = placeholder.WriteMetadata(&userpb.UserId{
+		Idp:      upload.info.Storage["OwnerIdp"],
+		OpaqueId: upload.info.Storage["OwnerId"],
+	})
+	if err != nil {
+		return errors.Wrap(err, "Decomposedfs: could not write placeholder metadata")
+	}
+	log := appctx.GetLogger(upload.ctx)
+	return upload.linkChild(n, log)
+}
+
+// removePlaceholder removes the placeholder node of a new file after processing failed,
+// unless another upload is being processed for it or already replaced it
+func (upload *fileUpload) removePlaceholder(ctx context.Context, n *node.Node) {
+	nodePath := n.InternalPath()
+	if !isPlaceholder(nodePath) || n.IsProcessing() {
+		return
+	}
+	sublog := appctx.GetLogger(ctx).With().Str("nodePath", nodePath).Logger()
+
+	childNameLink := filepath.Join(upload.fs.lu.InternalPath(n.ParentID), n.Name)
+	if link, err := os.Readlink(childNameLink); err == nil && link == "../"+n.ID {
+		if err := os.Remove(childNameLink); err != nil {
+			sublog.Err(err).Msg("Decomposedfs: could not remove placeholder child entry")
+		}
+	}
+	if err := os.Remove(nodePath); err != nil && !os.IsNotExist(err) {
+		sublog.Err(err).Msg("Decomposedfs: could not remove placeholder node")
+		return
+	}
+	if err := upload.fs.tp.Propagate(ctx, n); err != nil {
+		sublog.Err(err).Msg("Decomposedfs: could not propagate placeholder removal")
+	}
+}
+
+// endProcessing removes the processing mark, unless another upload took over the node in the meantime
+func (upload *fileUpload) endProcessing(n *node.Node) {
+	nodePath := n.InternalPath()
+	if v, err := xattr.Get(nodePath, xattrs.ProcessingAttr); err == nil && string(v) == upload.info.ID {
+		if err := xattr.Remove(nodePath, xattrs.ProcessingAttr); err != nil {
+			appctx.GetLogger(upload.ctx).Err(err).Str("nodePath", nodePath).Msg("Decomposedfs: could not remove processing attribute")
+		}
+	}
+}
+
+// isPlaceholder returns whether the node at the given path has no blob yet
+func isPlaceholder(nodePath string) bool {
+	v, err := xattr.Get(nodePath, xattrs.BlobIDAttr)
+	return err == nil && len(v) == 0
+}
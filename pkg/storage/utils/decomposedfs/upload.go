@@ -42,9 +42,11 @@ import (
 	"github.com/cs3org/reva/pkg/logger"
 	"github.com/cs3org/reva/pkg/storage/utils/chunking"
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/node"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/xattrs"
 	"github.com/cs3org/reva/pkg/utils"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	"github.com/pkg/xattr"
 	"github.com/rs/zerolog"
 	tusd "github.com/tus/tusd/pkg/handler"
 )
@@ -230,6 +232,12 @@ func (fs *Decomposedfs) NewUpload(ctx context.Context, info tusd.FileInfo) (uplo
 		return nil, errtypes.PermissionDenied(filepath.Join(n.ParentID, n.Name))
 	}
 
+	if n.Exists {
+		if err := n.CheckLock(ctx); err != nil {
+			return nil, err
+		}
+	}
+
 	info.ID = uuid.New().String()
 
 	binPath, err := fs.getUploadPath(ctx, info.ID)
@@ -270,6 +278,10 @@ func (fs *Decomposedfs) NewUpload(ctx context.Context, info tusd.FileInfo) (uplo
 
 		"LogLevel": log.GetLevel().String(),
 	}
+	// the data transfer finishing the upload does not know the app holding the lock
+	if app, ok := ctxpkg.ContextGetLockApp(ctx); ok {
+		info.Storage["LockApp"] = app
+	}
 	// Create binary file in the upload folder with no content
 	log.Debug().Interface("info", info).Msg("Decomposedfs: built storage info")
 	file, err := os.OpenFile(binPath, os.O_CREATE|os.O_WRONLY, defaultFilePerm)
@@ -333,6 +345,9 @@ func (fs *Decomposedfs) GetUpload(ctx context.Context, id string) (tusd.Upload,
 	}
 
 	ctx = ctxpkg.ContextSetUser(ctx, u)
+	if app := info.Storage["LockApp"]; app != "" {
+		ctx = ctxpkg.ContextSetLockApp(ctx, app)
+	}
 	// TODO configure the logger the same way ... store and add traceid in file info
 
 	var opts []logger.Option
@@ -443,11 +458,18 @@ func (upload *fileUpload) writeInfo() error {
 	return ioutil.WriteFile(upload.infoPath, data, defaultFilePerm)
 }
 
-// FinishUpload finishes an upload and moves the file to the internal destination
+// FinishUpload finishes an upload and moves the file to the internal destination.
+// When post-processing is configured the upload is handed over to the pipeline
+// and only becomes the current revision once all steps succeeded.
 func (upload *fileUpload) FinishUpload(ctx context.Context) (err error) {
 
-	// ensure cleanup
-	defer upload.discardChunk()
+	// ensure cleanup, unless the upload is being post-processed
+	processing := false
+	defer func() {
+		if !processing {
+			upload.discardChunk()
+		}
+	}()
 
 	fi, err := os.Stat(upload.binPath)
 	if err != nil {
@@ -473,15 +495,50 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) (err error) {
 
 	if n.ID == "" {
 		n.ID = uuid.New().String()
+	} else if err = n.CheckLock(upload.ctx); err != nil {
+		// the node might have been locked since the upload was initiated
+		return err
 	}
-	targetPath := n.InternalPath()
 	sublog := appctx.GetLogger(upload.ctx).
 		With().
 		Interface("info", upload.info).
 		Str("binPath", upload.binPath).
-		Str("targetPath", targetPath).
+		Str("targetPath", n.InternalPath()).
 		Logger()
 
+	hashes := upload.checksums(&sublog)
+
+	if upload.fs.pp != nil {
+		if err = upload.startProcessing(n, hashes); err != nil {
+			return err
+		}
+		processing = true
+		return nil
+	}
+
+	// compare if they match the sent checksum
+	// TODO the tus checksum extension would do this on every chunk, but I currently don't see an easy way to pass in the requested checksum. for now we do it in FinishUpload which is also called for chunked uploads
+	if upload.info.MetaData["checksum"] != "" {
+		parts := strings.SplitN(upload.info.MetaData["checksum"], " ", 2)
+		if len(parts) != 2 {
+			return errtypes.BadRequest("invalid checksum format. must be '[algorithm] [checksum]'")
+		}
+		switch parts[0] {
+		case "sha1", "md5", "adler32":
+			err = upload.checkHash(parts[1], hashes[parts[0]])
+		default:
+			err = errtypes.BadRequest("unsupported checksum algorithm: " + parts[0])
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return upload.finalize(upload.ctx, n, hashes)
+}
+
+// checksums calculates the checksums of the uploaded bytes
+func (upload *fileUpload) checksums(sublog *zerolog.Logger) map[string]hash.Hash {
 	// calculate the checksum of the written bytes
 	// they will all be written to the metadata later, so we cannot omit any of them
 	// TODO only calculate the checksum in sync that was requested to match, the rest could be async ... but the tests currently expect all to be present
@@ -504,33 +561,33 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) (err error) {
 			sublog.Err(err).Msg("Decomposedfs: could not copy bytes for checksumming")
 		}
 	}
-	// compare if they match the sent checksum
-	// TODO the tus checksum extension would do this on every chunk, but I currently don't see an easy way to pass in the requested checksum. for now we do it in FinishUpload which is also called for chunked uploads
-	if upload.info.MetaData["checksum"] != "" {
-		parts := strings.SplitN(upload.info.MetaData["checksum"], " ", 2)
-		if len(parts) != 2 {
-			return errtypes.BadRequest("invalid checksum format. must be '[algorithm] [checksum]'")
-		}
-		switch parts[0] {
-		case "sha1":
-			err = upload.checkHash(parts[1], sha1h)
-		case "md5":
-			err = upload.checkHash(parts[1], md5h)
-		case "adler32":
-			err = upload.checkHash(parts[1], adler32h)
-		default:
-			err = errtypes.BadRequest("unsupported checksum algorithm: " + parts[0])
-		}
-		if err != nil {
-			return err
-		}
+	return map[string]hash.Hash{
+		"sha1":    sha1h,
+		"md5":     md5h,
+		"adler32": adler32h,
 	}
+}
+
+// finalize moves the uploaded bytes to the blobstore and makes them the current revision of the node
+func (upload *fileUpload) finalize(ctx context.Context, n *node.Node, hashes map[string]hash.Hash) (err error) {
+	targetPath := n.InternalPath()
+	sublog := appctx.GetLogger(ctx).
+		With().
+		Interface("info", upload.info).
+		Str("binPath", upload.binPath).
+		Str("targetPath", targetPath).
+		Logger()
+
 	n.BlobID = upload.info.ID // This can be changed to a content hash in the future when reference counting for the blobs was added
 
 	// defer writing the checksums until the node is in place
 
-	// if target exists create new version
-	if fi, err = os.Stat(targetPath); err == nil {
+	// the lock belongs to the node, not to the revision
+	lock, _ := xattr.Get(targetPath, xattrs.LockAttr)
+
+	// if target exists create new version, unless it is the placeholder of a new file that was being processed
+	var fi os.FileInfo
+	if fi, err = os.Stat(targetPath); err == nil && !isPlaceholder(targetPath) {
 		// versions are stored alongside the actual file, so a rename can be efficient and does not cross storage / partition boundaries
 		versionsPath := upload.fs.lu.InternalPath(n.ID + ".REV." + fi.ModTime().UTC().Format(time.RFC3339Nano))
 
@@ -556,7 +613,6 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) (err error) {
 
 	// now truncate the upload (the payload stays in the blobstore) and move it to the target path
 	// TODO put uploads on the same underlying storage as the destination dir?
-	// TODO trigger a workflow as the final rename might eg involve antivirus scanning
 	if err = os.Truncate(upload.binPath, 0); err != nil {
 		sublog.Err(err).
 			Msg("Decomposedfs: could not truncate")
@@ -569,9 +625,9 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) (err error) {
 	}
 
 	// now try write all checksums
-	tryWritingChecksum(&sublog, n, "sha1", sha1h)
-	tryWritingChecksum(&sublog, n, "md5", md5h)
-	tryWritingChecksum(&sublog, n, "adler32", adler32h)
+	for algo, h := range hashes {
+		tryWritingChecksum(&sublog, n, algo, h)
+	}
 
 	// who will become the owner?  the owner of the parent actually ... not the currently logged in user
 	err = n.WriteMetadata(&userpb.UserId{
@@ -581,26 +637,14 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) (err error) {
 	if err != nil {
 		return errors.Wrap(err, "Decomposedfs: could not write metadata")
 	}
-
-	// link child name to parent if it is new
-	childNameLink := filepath.Join(upload.fs.lu.InternalPath(n.ParentID), n.Name)
-	var link string
-	link, err = os.Readlink(childNameLink)
-	if err == nil && link != "../"+n.ID {
-		sublog.Err(err).
-			Interface("node", n).
-			Str("childNameLink", childNameLink).
-			Str("link", link).
-			Msg("Decomposedfs: child name link has wrong target id, repairing")
-
-		if err = os.Remove(childNameLink); err != nil {
-			return errors.Wrap(err, "Decomposedfs: could not remove symlink child entry")
+	if len(lock) > 0 {
+		if err = xattr.Set(targetPath, xattrs.LockAttr, lock); err != nil {
+			return errors.Wrap(err, "Decomposedfs: could not restore lock")
 		}
 	}
-	if os.IsNotExist(err) || link != "../"+n.ID {
-		if err = os.Symlink("../"+n.ID, childNameLink); err != nil {
-			return errors.Wrap(err, "Decomposedfs: could not symlink child entry")
-		}
+
+	if err = upload.linkChild(n, &sublog); err != nil {
+		return err
 	}
 
 	// only delete the upload if it was successfully written to the storage
@@ -621,7 +665,31 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) (err error) {
 
 	n.Exists = true
 
-	return upload.fs.tp.Propagate(upload.ctx, n)
+	return upload.fs.tp.Propagate(ctx, n)
+}
+
+// linkChild links the child name to the parent if it is new
+func (upload *fileUpload) linkChild(n *node.Node, sublog *zerolog.Logger) (err error) {
+	childNameLink := filepath.Join(upload.fs.lu.InternalPath(n.ParentID), n.Name)
+	var link string
+	link, err = os.Readlink(childNameLink)
+	if err == nil && link != "../"+n.ID {
+		sublog.Err(err).
+			Interface("node", n).
+			Str("childNameLink", childNameLink).
+			Str("link", link).
+			Msg("Decomposedfs: child name link has wrong target id, repairing")
+
+		if err = os.Remove(childNameLink); err != nil {
+			return errors.Wrap(err, "Decomposedfs: could not remove symlink child entry")
+		}
+	}
+	if os.IsNotExist(err) || link != "../"+n.ID {
+		if err = os.Symlink("../"+n.ID, childNameLink); err != nil {
+			return errors.Wrap(err, "Decomposedfs: could not symlink child entry")
+		}
+	}
+	return nil
 }
 
 func (upload *fileUpload) checkHash(expected string, h hash.Hash) error {
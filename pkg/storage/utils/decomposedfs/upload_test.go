@@ -35,6 +35,7 @@ import (
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/mocks"
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/node"
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/options"
+	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/postprocessing"
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/tree"
 	treemocks "github.com/cs3org/reva/pkg/storage/utils/decomposedfs/tree/mocks"
 	"github.com/cs3org/reva/pkg/storage/utils/decomposedfs/xattrs"
@@ -46,6 +47,15 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+// waitStep blocks until the test sends the result of the step
+type waitStep struct {
+	result chan error
+}
+
+func (s *waitStep) Process(ctx context.Context, u *postprocessing.Upload) error {
+	return <-s.result
+}
+
 var _ = Describe("File uploads", func() {
 	var (
 		ref  *provider.Reference
@@ -274,6 +284,96 @@ var _ = Describe("File uploads", func() {
 			})
 		})
 
+		Context("with post-processing", func() {
+			var (
+				result      chan error
+				fileContent = []byte("0123456789")
+			)
+
+			BeforeEach(func() {
+				result = make(chan error)
+				postprocessing.Register("wait", func(map[string]interface{}) (postprocessing.Step, error) {
+					return &waitStep{result: result}, nil
+				})
+				o.PostProcessing.Steps = []string{"wait", "metadata"}
+			})
+
+			upload := func() {
+				uploadIds, err := fs.InitiateUpload(ctx, ref, 10, map[string]string{})
+				Expect(err).ToNot(HaveOccurred())
+
+				uploadRef := &provider.Reference{Path: "/" + uploadIds["simple"]}
+				err = fs.Upload(ctx, uploadRef, ioutil.NopCloser(bytes.NewReader(fileContent)))
+				Expect(err).ToNot(HaveOccurred())
+			}
+
+			listRoot := func() []*provider.ResourceInfo {
+				resources, err := fs.ListFolder(ctx, &provider.Reference{Path: "/"}, []string{})
+				Expect(err).ToNot(HaveOccurred())
+				return resources
+			}
+
+			It("marks new files as processing until all steps succeeded", func() {
+				bs.On("Upload", mock.AnythingOfType("string"), mock.AnythingOfType("*os.File")).Return(nil)
+
+				upload()
+
+				resources := listRoot()
+				Expect(len(resources)).To(Equal(1))
+				Expect(string(resources[0].Opaque.Map[node.StatusKey].Value)).To(Equal(node.ProcessingStatus))
+				bs.AssertNotCalled(GinkgoT(), "Upload", mock.Anything, mock.Anything)
+
+				_, err := fs.Download(ctx, ref)
+				Expect(err).To(MatchError(errtypes.TooEarly("root/foo")))
+
+				result <- nil
+
+				// the results of the steps are set once the upload is the current revision
+				Eventually(func() bool {
+					resources := listRoot()
+					return len(resources) == 1 && resources[0].Opaque.GetMap()[node.StatusKey] == nil &&
+						resources[0].ArbitraryMetadata.GetMetadata()[postprocessing.ContentTypeKey] != ""
+				}).Should(BeTrue())
+				resources = listRoot()
+				Expect(resources[0].Size).To(Equal(uint64(len(fileContent))))
+				Expect(resources[0].ArbitraryMetadata.Metadata[postprocessing.ContentTypeKey]).To(Equal("text/plain; charset=utf-8"))
+				bs.AssertCalled(GinkgoT(), "Upload", mock.Anything, mock.Anything)
+			})
+
+			It("discards uploads rejected by a step", func() {
+				upload()
+				Expect(len(listRoot())).To(Equal(1))
+
+				result <- errtypes.PermissionDenied("rejected")
+
+				Eventually(func() int {
+					return len(listRoot())
+				}).Should(Equal(0))
+				bs.AssertNotCalled(GinkgoT(), "Upload", mock.Anything, mock.Anything)
+			})
+
+			It("resumes the processing after a restart", func() {
+				bs.On("Upload", mock.AnythingOfType("string"), mock.AnythingOfType("*os.File")).Return(nil)
+
+				upload()
+				Expect(len(listRoot())).To(Equal(1))
+
+				// the steps of the first instance never finish
+				result = make(chan error)
+				fs, err := decomposedfs.New(o, lookup, permissions, tree.New(o.Root, true, true, lookup, bs))
+				Expect(err).ToNot(HaveOccurred())
+
+				result <- nil
+
+				Eventually(func() bool {
+					resources, err := fs.ListFolder(ctx, &provider.Reference{Path: "/"}, []string{})
+					Expect(err).ToNot(HaveOccurred())
+					return len(resources) == 1 && resources[0].Opaque.GetMap()[node.StatusKey] == nil
+				}).Should(BeTrue())
+				bs.AssertCalled(GinkgoT(), "Upload", mock.Anything, mock.Anything)
+			})
+		})
+
 		When("the user tries to upload a file without intialising the upload", func() {
 			It("fails", func() {
 				var (
@@ -58,6 +58,13 @@ const (
 	ChecksumPrefix  string = OcisPrefix + "cs."          // followed by the algorithm, eg. ocis.cs.sha1
 	TrashOriginAttr string = OcisPrefix + "trash.origin" // trash origin
 
+	// the id of the upload that is currently post-processed for this node
+	// only set while the upload is being processed
+	ProcessingAttr string = OcisPrefix + "processing"
+
+	// the lock of a node, stored as json
+	LockAttr string = OcisPrefix + "lock"
+
 	// we use a single attribute to enable or disable propagation of both: synctime and treesize
 	// The propagation attribute is set to '1' at the top of the (sub)tree. Propagation will stop at
 	// that node.
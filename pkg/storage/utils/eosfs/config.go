@@ -164,4 +164,8 @@ type Config struct {
 	// TokenExpiry stores in seconds the time after which generated tokens will expire
 	// Default is 3600
 	TokenExpiry int
+
+	// LockExpiration stores in seconds the time after which locks expire unless they are refreshed
+	// Default is 1800
+	LockExpiration int `mapstructure:"lock_expiration"`
 }
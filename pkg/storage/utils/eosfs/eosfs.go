@@ -52,6 +52,7 @@ import (
 	"github.com/cs3org/reva/pkg/storage/utils/acl"
 	"github.com/cs3org/reva/pkg/storage/utils/chunking"
 	"github.com/cs3org/reva/pkg/storage/utils/grants"
+	"github.com/cs3org/reva/pkg/storage/utils/locks"
 	"github.com/cs3org/reva/pkg/storage/utils/templates"
 	"github.com/pkg/errors"
 )
@@ -133,6 +134,10 @@ func (c *Config) init() {
 		c.TokenExpiry = 3600
 	}
 
+	if c.LockExpiration == 0 {
+		c.LockExpiration = 1800
+	}
+
 	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
 }
 
@@ -143,6 +148,9 @@ type eosfs struct {
 	singleUserAuth eosclient.Authorization
 	userIDCache    *ttlcache.Cache
 	tokenCache     gcache.Cache
+	// EOS offers no exclusive creation of attributes, so concurrent lock
+	// requests for the same file must go through the same storage provider
+	lockMutexes locks.Mutexes
 }
 
 // NewEOSFS returns a storage.FS interface implementation that connects to an EOS instance
@@ -474,6 +482,10 @@ func (fs *eosfs) SetArbitraryMetadata(ctx context.Context, ref *provider.Referen
 		return errors.Wrap(err, "eosfs: error getting uid and gid for user")
 	}
 
+	if err := fs.checkLock(ctx, auth, fn); err != nil {
+		return err
+	}
+
 	for k, v := range md.Metadata {
 		if k == "" || v == "" {
 			return errtypes.BadRequest(fmt.Sprintf("eosfs: key or value is empty: key:%s, value:%s", k, v))
@@ -516,6 +528,10 @@ func (fs *eosfs) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Refer
 		return errors.Wrap(err, "eosfs: error getting uid and gid for user")
 	}
 
+	if err := fs.checkLock(ctx, auth, fn); err != nil {
+		return err
+	}
+
 	for _, k := range keys {
 		if k == "" {
 			return errtypes.BadRequest("eosfs: key is empty")
@@ -535,26 +551,6 @@ func (fs *eosfs) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Refer
 	return nil
 }
 
-// GetLock returns an existing lock on the given reference
-func (fs *eosfs) GetLock(ctx context.Context, ref *provider.Reference) (*provider.Lock, error) {
-	return nil, errtypes.NotSupported("unimplemented")
-}
-
-// SetLock puts a lock on the given reference
-func (fs *eosfs) SetLock(ctx context.Context, ref *provider.Reference, lock *provider.Lock) error {
-	return errtypes.NotSupported("unimplemented")
-}
-
-// RefreshLock refreshes an existing lock on the given reference
-func (fs *eosfs) RefreshLock(ctx context.Context, ref *provider.Reference, lock *provider.Lock) error {
-	return errtypes.NotSupported("unimplemented")
-}
-
-// Unlock removes an existing lock from the given reference
-func (fs *eosfs) Unlock(ctx context.Context, ref *provider.Reference) error {
-	return errtypes.NotSupported("unimplemented")
-}
-
 func (fs *eosfs) AddGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
 	u, err := getUser(ctx)
 	if err != nil {
@@ -1337,6 +1333,10 @@ func (fs *eosfs) Delete(ctx context.Context, ref *provider.Reference) error {
 		return err
 	}
 
+	if err := fs.checkLock(ctx, auth, fn); err != nil {
+		return err
+	}
+
 	return fs.c.Remove(ctx, auth, fn, false)
 }
 
@@ -1389,6 +1389,10 @@ func (fs *eosfs) Move(ctx context.Context, oldRef, newRef *provider.Reference) e
 		return err
 	}
 
+	if err := fs.checkLock(ctx, auth, oldFn); err != nil {
+		return err
+	}
+
 	return fs.c.Rename(ctx, auth, oldFn, newFn)
 }
 
@@ -1523,6 +1527,10 @@ func (fs *eosfs) RestoreRevision(ctx context.Context, ref *provider.Reference, r
 		return err
 	}
 
+	if err := fs.checkLock(ctx, auth, fn); err != nil {
+		return err
+	}
+
 	return fs.c.RollbackToVersion(ctx, auth, fn, revisionKey)
 }
 
@@ -0,0 +1,240 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package eosfs
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/eosclient"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage/utils/locks"
+	"github.com/pkg/errors"
+)
+
+// lockPayloadKey is the system attribute holding the lock of a resource.
+// Users cannot modify system attributes, so it is always written as root.
+const lockPayloadKey = "reva.lockpayload"
+
+// lockTTL returns how long locks are valid.
+func (fs *eosfs) lockTTL() time.Duration {
+	return time.Duration(fs.conf.LockExpiration) * time.Second
+}
+
+// readLock returns the lock of the file, or nil if it is not locked or the lock expired.
+// Users can read system attributes, so the file is looked up with their auth.
+func (fs *eosfs) readLock(ctx context.Context, auth eosclient.Authorization, fn string) (*locks.Lock, error) {
+	eosFileInfo, err := fs.c.GetFileInfoByPath(ctx, auth, fn)
+	if err != nil {
+		return nil, err
+	}
+	val, ok := eosFileInfo.Attrs["sys."+lockPayloadKey]
+	if !ok || val == "" {
+		return nil, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(val)
+	if err != nil {
+		return nil, errors.Wrap(err, "eosfs: error decoding lock")
+	}
+	l := &locks.Lock{}
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, errors.Wrap(err, "eosfs: error unmarshalling lock")
+	}
+	if l.Expired() {
+		return nil, nil
+	}
+	return l, nil
+}
+
+func (fs *eosfs) writeLock(ctx context.Context, fn string, l *locks.Lock) error {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return errors.Wrap(err, "eosfs: error marshalling lock")
+	}
+	rootAuth, err := fs.getRootAuth(ctx)
+	if err != nil {
+		return err
+	}
+	attr := &eosclient.Attribute{
+		Type: SystemAttr,
+		Key:  lockPayloadKey,
+		Val:  base64.StdEncoding.EncodeToString(data),
+	}
+	if err := fs.c.SetAttr(ctx, rootAuth, attr, false, fn); err != nil {
+		return errors.Wrap(err, "eosfs: error setting lock")
+	}
+	return nil
+}
+
+// checkLock returns an error if the file is locked by someone else than the
+// requester, see locks.Lock.HeldBy. Files that do not exist yet are not locked.
+func (fs *eosfs) checkLock(ctx context.Context, auth eosclient.Authorization, fn string) error {
+	l, err := fs.readLock(ctx, auth, fn)
+	switch {
+	case err != nil:
+		if _, ok := err.(errtypes.IsNotFound); ok {
+			return nil
+		}
+		return err
+	case l == nil || l.HeldBy(ctx):
+		return nil
+	}
+	return errtypes.Locked(l.Holder())
+}
+
+// resolveLockable returns the internal path of the referenced file and the
+// auth of the user in the context after checking that the user has the
+// given permission on it
+func (fs *eosfs) resolveLockable(ctx context.Context, ref *provider.Reference, check func(*provider.ResourcePermissions) bool) (string, eosclient.Authorization, error) {
+	p, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return "", eosclient.Authorization{}, errors.Wrap(err, "eosfs: error resolving reference")
+	}
+	if fs.isShareFolder(ctx, p) {
+		return "", eosclient.Authorization{}, errtypes.PermissionDenied("eosfs: cannot lock under the virtual share folder")
+	}
+	info, err := fs.GetMD(ctx, ref, nil)
+	if err != nil {
+		return "", eosclient.Authorization{}, err
+	}
+	if !check(info.PermissionSet) {
+		return "", eosclient.Authorization{}, errtypes.PermissionDenied(p)
+	}
+	fn := fs.wrap(ctx, p)
+
+	u, err := getUser(ctx)
+	if err != nil {
+		return "", eosclient.Authorization{}, err
+	}
+	auth, err := fs.getUserAuth(ctx, u, fn)
+	if err != nil {
+		return "", eosclient.Authorization{}, err
+	}
+	return fn, auth, nil
+}
+
+// GetLock returns an existing lock on the given reference
+func (fs *eosfs) GetLock(ctx context.Context, ref *provider.Reference) (*provider.Lock, error) {
+	fn, auth, err := fs.resolveLockable(ctx, ref, func(rp *provider.ResourcePermissions) bool {
+		return rp.Stat
+	})
+	if err != nil {
+		return nil, err
+	}
+	l, err := fs.readLock(ctx, auth, fn)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil {
+		return nil, errtypes.NotFound("eosfs: no lock found for " + ref.String())
+	}
+	return l.CS3(), nil
+}
+
+// SetLock puts a lock on the given reference
+func (fs *eosfs) SetLock(ctx context.Context, ref *provider.Reference, lock *provider.Lock) error {
+	fn, auth, err := fs.resolveLockable(ctx, ref, func(rp *provider.ResourcePermissions) bool {
+		return rp.InitiateFileUpload
+	})
+	if err != nil {
+		return err
+	}
+	l, err := locks.New(ctx, lock, fs.lockTTL())
+	if err != nil {
+		return err
+	}
+
+	defer fs.lockMutexes.Lock(fn)()
+
+	existing, err := fs.readLock(ctx, auth, fn)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return errtypes.Locked(existing.Holder())
+	}
+	return fs.writeLock(ctx, fn, l)
+}
+
+// RefreshLock refreshes an existing lock on the given reference
+func (fs *eosfs) RefreshLock(ctx context.Context, ref *provider.Reference, lock *provider.Lock) error {
+	fn, auth, err := fs.resolveLockable(ctx, ref, func(rp *provider.ResourcePermissions) bool {
+		return rp.InitiateFileUpload
+	})
+	if err != nil {
+		return err
+	}
+	l, err := locks.New(ctx, lock, fs.lockTTL())
+	if err != nil {
+		return err
+	}
+
+	defer fs.lockMutexes.Lock(fn)()
+
+	existing, err := fs.readLock(ctx, auth, fn)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errtypes.BadRequest("eosfs: file is not locked")
+	}
+	if !existing.SameHolder(lock) || !existing.HeldBy(ctx) {
+		return errtypes.Locked(existing.Holder())
+	}
+	return fs.writeLock(ctx, fn, l)
+}
+
+// Unlock removes an existing lock from the given reference
+func (fs *eosfs) Unlock(ctx context.Context, ref *provider.Reference) error {
+	fn, auth, err := fs.resolveLockable(ctx, ref, func(rp *provider.ResourcePermissions) bool {
+		return rp.InitiateFileUpload
+	})
+	if err != nil {
+		return err
+	}
+
+	defer fs.lockMutexes.Lock(fn)()
+
+	existing, err := fs.readLock(ctx, auth, fn)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errtypes.BadRequest("eosfs: file is not locked")
+	}
+	if !existing.HeldBy(ctx) {
+		return errtypes.Locked(existing.Holder())
+	}
+
+	rootAuth, err := fs.getRootAuth(ctx)
+	if err != nil {
+		return err
+	}
+	attr := &eosclient.Attribute{
+		Type: SystemAttr,
+		Key:  lockPayloadKey,
+	}
+	if err := fs.c.UnsetAttr(ctx, rootAuth, attr, false, fn); err != nil {
+		return errors.Wrap(err, "eosfs: error removing lock")
+	}
+	return nil
+}
@@ -0,0 +1,401 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package eosfs
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ReneKroon/ttlcache/v2"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/eosclient"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage/utils/acl"
+)
+
+// fakeClient keeps the files of an EOS namespace in memory
+type fakeClient struct {
+	eosclient.EOSClient
+
+	mu    sync.Mutex
+	files map[string]*eosclient.FileInfo
+}
+
+func attrKey(attr *eosclient.Attribute) string {
+	if attr.Type == SystemAttr {
+		return "sys." + attr.Key
+	}
+	return "user." + attr.Key
+}
+
+func (c *fakeClient) GetFileInfoByPath(ctx context.Context, auth eosclient.Authorization, path string) (*eosclient.FileInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fi, ok := c.files[path]
+	if !ok {
+		return nil, errtypes.NotFound(path)
+	}
+	cp := *fi
+	cp.Attrs = map[string]string{}
+	for k, v := range fi.Attrs {
+		cp.Attrs[k] = v
+	}
+	return &cp, nil
+}
+
+func (c *fakeClient) GetFileInfoByInode(ctx context.Context, auth eosclient.Authorization, inode uint64) (*eosclient.FileInfo, error) {
+	c.mu.Lock()
+	var path string
+	for p, fi := range c.files {
+		if fi.Inode == inode {
+			path = p
+		}
+	}
+	c.mu.Unlock()
+	return c.GetFileInfoByPath(ctx, auth, path)
+}
+
+func (c *fakeClient) SetAttr(ctx context.Context, auth eosclient.Authorization, attr *eosclient.Attribute, recursive bool, path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fi, ok := c.files[path]
+	if !ok {
+		return errtypes.NotFound(path)
+	}
+	fi.Attrs[attrKey(attr)] = attr.Val
+	return nil
+}
+
+func (c *fakeClient) UnsetAttr(ctx context.Context, auth eosclient.Authorization, attr *eosclient.Attribute, recursive bool, path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fi, ok := c.files[path]
+	if !ok {
+		return errtypes.NotFound(path)
+	}
+	delete(fi.Attrs, attrKey(attr))
+	return nil
+}
+
+func (c *fakeClient) Remove(ctx context.Context, auth eosclient.Authorization, path string, noRecycle bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.files, path)
+	return nil
+}
+
+func (c *fakeClient) Rename(ctx context.Context, auth eosclient.Authorization, oldPath, newPath string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files[newPath] = c.files[oldPath]
+	c.files[newPath].File = newPath
+	delete(c.files, oldPath)
+	return nil
+}
+
+func (c *fakeClient) Write(ctx context.Context, auth eosclient.Authorization, path string, stream io.ReadCloser) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, err := ioutil.ReadAll(stream)
+	if err != nil {
+		return err
+	}
+	if _, ok := c.files[path]; !ok {
+		c.files[path] = &eosclient.FileInfo{File: path, Inode: uint64(len(c.files) + 100), Attrs: map[string]string{}}
+	}
+	c.files[path].Size = uint64(len(b))
+	return nil
+}
+
+var (
+	alice = &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "alice"}, UidNumber: 1000, GidNumber: 1000}
+	bob   = &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "bob"}, UidNumber: 1001, GidNumber: 1000}
+	carol = &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "carol"}, UidNumber: 1002, GidNumber: 1000}
+)
+
+func newLockTestFS(t *testing.T) (*eosfs, *fakeClient) {
+	client := &fakeClient{files: map[string]*eosclient.FileInfo{
+		"/eos/user/file.txt": {
+			File:  "/eos/user/file.txt",
+			Inode: 10,
+			UID:   1000,
+			GID:   1000,
+			SysACL: &acl.ACLs{Entries: []*acl.Entry{
+				{Type: acl.TypeUser, Qualifier: "1001", Permissions: "rwx"},
+				{Type: acl.TypeUser, Qualifier: "1002", Permissions: "rx"},
+			}},
+			Attrs: map[string]string{},
+		},
+	}}
+	conf := &Config{Namespace: "/eos/user"}
+	conf.init()
+	fs := &eosfs{c: client, conf: conf, userIDCache: ttlcache.NewCache()}
+	t.Cleanup(func() { _ = fs.userIDCache.Close() })
+	if err := fs.userIDCache.Set("1000", alice.Id); err != nil {
+		t.Fatal(err)
+	}
+	return fs, client
+}
+
+func ctxFor(u *userpb.User) context.Context {
+	return ctxpkg.ContextSetUser(context.Background(), u)
+}
+
+// appCtxFor returns the context of requests the app sends on behalf of the user
+func appCtxFor(u *userpb.User, app string) context.Context {
+	return ctxpkg.ContextSetLockApp(ctxFor(u), app)
+}
+
+var fileRef = &provider.Reference{Path: "/file.txt"}
+
+func appLock(app string) *provider.Lock {
+	return &provider.Lock{
+		Type:     provider.LockType_LOCK_TYPE_WRITE,
+		Holder:   &provider.Lock_AppName{AppName: app},
+		Metadata: "wopi-lock-id",
+	}
+}
+
+func isLocked(err error) bool {
+	_, ok := err.(errtypes.IsLocked)
+	return ok
+}
+
+func TestSetAndGetLock(t *testing.T) {
+	fs, client := newLockTestFS(t)
+
+	if _, err := fs.GetLock(ctxFor(alice), fileRef); err == nil {
+		t.Fatal("expected no lock on an unlocked file")
+	}
+
+	if err := fs.SetLock(ctxFor(alice), fileRef, appLock("collabora")); err == nil {
+		t.Fatal("expected app locks to be rejected outside of the app")
+	}
+	if err := fs.SetLock(appCtxFor(alice, "collabora"), fileRef, appLock("collabora")); err != nil {
+		t.Fatalf("error setting lock: %v", err)
+	}
+	if client.files["/eos/user/file.txt"].Attrs["sys."+lockPayloadKey] == "" {
+		t.Fatal("expected the lock to be stored as a system attribute")
+	}
+
+	// users with read access see the lock
+	lock, err := fs.GetLock(ctxFor(carol), &provider.Reference{ResourceId: &provider.ResourceId{OpaqueId: "10"}})
+	if err != nil {
+		t.Fatalf("error getting lock: %v", err)
+	}
+	if lock.GetAppName() != "collabora" || lock.Metadata != "wopi-lock-id" || lock.Type != provider.LockType_LOCK_TYPE_WRITE {
+		t.Fatalf("unexpected lock %+v", lock)
+	}
+
+	// the lock is not exposed as arbitrary metadata
+	info, err := fs.convert(ctxFor(alice), client.files["/eos/user/file.txt"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k := range info.ArbitraryMetadata.Metadata {
+		if strings.Contains(k, lockPayloadKey) {
+			t.Fatalf("lock exposed in metadata key %s", k)
+		}
+	}
+
+	if err := fs.SetLock(appCtxFor(bob, "onlyoffice"), fileRef, appLock("onlyoffice")); !isLocked(err) {
+		t.Fatalf("expected a locked error, got %v", err)
+	}
+}
+
+func TestSetLockValidation(t *testing.T) {
+	fs, _ := newLockTestFS(t)
+
+	if err := fs.SetLock(ctxFor(carol), fileRef, appLock("collabora")); err == nil {
+		t.Fatal("expected users without write access to be denied")
+	}
+	if err := fs.SetLock(ctxFor(alice), fileRef, &provider.Lock{Type: provider.LockType_LOCK_TYPE_EXCL}); err == nil {
+		t.Fatal("expected locks without holder to be rejected")
+	}
+	if err := fs.SetLock(ctxFor(alice), fileRef, &provider.Lock{Holder: &provider.Lock_AppName{AppName: "app"}}); err == nil {
+		t.Fatal("expected locks without type to be rejected")
+	}
+	if err := fs.SetLock(ctxFor(alice), &provider.Reference{Path: "/MyShares/foo"}, appLock("collabora")); err == nil {
+		t.Fatal("expected locks in the share folder to be rejected")
+	}
+}
+
+func TestExpiredLock(t *testing.T) {
+	fs, _ := newLockTestFS(t)
+	fs.conf.LockExpiration = -1
+
+	if err := fs.SetLock(appCtxFor(alice, "collabora"), fileRef, appLock("collabora")); err != nil {
+		t.Fatalf("error setting lock: %v", err)
+	}
+	if _, err := fs.GetLock(ctxFor(alice), fileRef); err == nil {
+		t.Fatal("expected expired locks to be ignored")
+	}
+	if err := fs.Delete(ctxFor(bob), fileRef); err != nil {
+		t.Fatalf("expected expired locks not to block writes: %v", err)
+	}
+}
+
+func TestAppLockBlocksOtherWriters(t *testing.T) {
+	fs, _ := newLockTestFS(t)
+
+	if err := fs.SetLock(appCtxFor(alice, "collabora"), fileRef, appLock("collabora")); err != nil {
+		t.Fatalf("error setting lock: %v", err)
+	}
+
+	// other users are locked out, also through other apps
+	md := &provider.ArbitraryMetadata{Metadata: map[string]string{"foo": "bar"}}
+	for _, ctx := range []context.Context{ctxFor(bob), appCtxFor(bob, "onlyoffice")} {
+		if err := fs.SetArbitraryMetadata(ctx, fileRef, md); !isLocked(err) {
+			t.Fatalf("expected set metadata to be locked, got %v", err)
+		}
+		if err := fs.UnsetArbitraryMetadata(ctx, fileRef, []string{"foo"}); !isLocked(err) {
+			t.Fatalf("expected unset metadata to be locked, got %v", err)
+		}
+		if err := fs.Upload(ctx, fileRef, ioutil.NopCloser(strings.NewReader("bob"))); !isLocked(err) {
+			t.Fatalf("expected upload to be locked, got %v", err)
+		}
+		if err := fs.Move(ctx, fileRef, &provider.Reference{Path: "/moved.txt"}); !isLocked(err) {
+			t.Fatalf("expected move to be locked, got %v", err)
+		}
+		if err := fs.Delete(ctx, fileRef); !isLocked(err) {
+			t.Fatalf("expected delete to be locked, got %v", err)
+		}
+		if err := fs.Unlock(ctx, fileRef); !isLocked(err) {
+			t.Fatalf("expected unlock outside of the app to be locked, got %v", err)
+		}
+	}
+	if err := fs.RefreshLock(ctxFor(bob), fileRef, appLock("collabora")); err == nil {
+		t.Fatal("expected refresh outside of the app to fail")
+	}
+
+	// the app writes on behalf of all its users
+	if err := fs.Upload(appCtxFor(bob, "collabora"), fileRef, ioutil.NopCloser(strings.NewReader("bob"))); err != nil {
+		t.Fatalf("expected the app to upload: %v", err)
+	}
+	if err := fs.SetArbitraryMetadata(appCtxFor(alice, "collabora"), fileRef, md); err != nil {
+		t.Fatalf("expected the app to set metadata: %v", err)
+	}
+	if _, err := fs.GetLock(ctxFor(alice), fileRef); err != nil {
+		t.Fatalf("expected the lock to survive the upload: %v", err)
+	}
+
+	if err := fs.RefreshLock(appCtxFor(bob, "onlyoffice"), fileRef, appLock("onlyoffice")); !isLocked(err) {
+		t.Fatalf("expected refresh by another app to be locked, got %v", err)
+	}
+	if err := fs.RefreshLock(appCtxFor(bob, "collabora"), fileRef, appLock("collabora")); err != nil {
+		t.Fatalf("error refreshing lock: %v", err)
+	}
+	if err := fs.Unlock(appCtxFor(bob, "collabora"), fileRef); err != nil {
+		t.Fatalf("error unlocking: %v", err)
+	}
+	if err := fs.Unlock(appCtxFor(alice, "collabora"), fileRef); err == nil {
+		t.Fatal("expected unlocking an unlocked file to fail")
+	}
+	if err := fs.Delete(ctxFor(bob), fileRef); err != nil {
+		t.Fatalf("expected writes after unlock to succeed: %v", err)
+	}
+}
+
+func TestAppLockAllowsHoldingUser(t *testing.T) {
+	fs, _ := newLockTestFS(t)
+
+	if err := fs.SetLock(appCtxFor(alice, "collabora"), fileRef, appLock("collabora")); err != nil {
+		t.Fatalf("error setting lock: %v", err)
+	}
+
+	// the user that set the lock keeps writing outside of the app
+	md := &provider.ArbitraryMetadata{Metadata: map[string]string{"foo": "bar"}}
+	if err := fs.SetArbitraryMetadata(ctxFor(alice), fileRef, md); err != nil {
+		t.Fatalf("expected the holding user to set metadata: %v", err)
+	}
+	if err := fs.UnsetArbitraryMetadata(ctxFor(alice), fileRef, []string{"foo"}); err != nil {
+		t.Fatalf("expected the holding user to unset metadata: %v", err)
+	}
+	if err := fs.Upload(ctxFor(alice), fileRef, ioutil.NopCloser(strings.NewReader("alice"))); err != nil {
+		t.Fatalf("expected the holding user to upload: %v", err)
+	}
+	if _, err := fs.GetLock(ctxFor(alice), fileRef); err != nil {
+		t.Fatalf("expected the lock to survive the upload: %v", err)
+	}
+	if err := fs.Unlock(ctxFor(alice), fileRef); err != nil {
+		t.Fatalf("expected the holding user to unlock: %v", err)
+	}
+}
+
+func TestUserLock(t *testing.T) {
+	fs, _ := newLockTestFS(t)
+
+	lock := &provider.Lock{
+		Type:   provider.LockType_LOCK_TYPE_EXCL,
+		Holder: &provider.Lock_User{User: bob.Id},
+	}
+	if err := fs.SetLock(ctxFor(bob), fileRef, lock); err != nil {
+		t.Fatalf("error setting lock: %v", err)
+	}
+	l, err := fs.GetLock(ctxFor(alice), fileRef)
+	if err != nil {
+		t.Fatalf("error getting lock: %v", err)
+	}
+	if l.GetUser().GetOpaqueId() != "bob" || l.Mtime.Seconds > uint64(time.Now().Unix()) {
+		t.Fatalf("unexpected lock %+v", l)
+	}
+
+	if err := fs.Delete(ctxFor(alice), fileRef); !isLocked(err) {
+		t.Fatalf("expected the owner to be locked out, got %v", err)
+	}
+	if err := fs.Move(ctxFor(bob), fileRef, &provider.Reference{Path: "/moved.txt"}); err != nil {
+		t.Fatalf("expected the lock holder to move the file: %v", err)
+	}
+}
+
+func TestConcurrentSetLock(t *testing.T) {
+	fs, _ := newLockTestFS(t)
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		locked int
+	)
+	for _, app := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		wg.Add(1)
+		go func(app string) {
+			defer wg.Done()
+			err := fs.SetLock(appCtxFor(alice, app), fileRef, appLock(app))
+			switch {
+			case err == nil:
+				mu.Lock()
+				locked++
+				mu.Unlock()
+			case !isLocked(err):
+				t.Errorf("unexpected error %v", err)
+			}
+		}(app)
+	}
+	wg.Wait()
+	if locked != 1 {
+		t.Fatalf("expected exactly one lock, got %d", locked)
+	}
+}
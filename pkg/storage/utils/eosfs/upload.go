@@ -71,6 +71,11 @@ func (fs *eosfs) Upload(ctx context.Context, ref *provider.Reference, r io.ReadC
 	if err != nil {
 		return err
 	}
+
+	if err := fs.checkLock(ctx, auth, fn); err != nil {
+		return err
+	}
+
 	return fs.c.Write(ctx, auth, fn, r)
 }
 
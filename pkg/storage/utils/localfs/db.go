@@ -121,7 +121,7 @@ func (fs *localfs) addToACLDB(ctx context.Context, resource, grantee, role strin
 }
 
 func (fs *localfs) getACLs(ctx context.Context, resource string) (*sql.Rows, error) {
-	grants, err := fs.db.Query("SELECT grantee, role FROM user_interaction WHERE resource=?", resource)
+	grants, err := fs.db.Query("SELECT grantee, role FROM user_interaction WHERE resource=? AND role != ''", resource)
 	if err != nil {
 		return nil, err
 	}
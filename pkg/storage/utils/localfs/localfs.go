@@ -29,6 +29,7 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
@@ -38,28 +39,35 @@ import (
 	ctxpkg "github.com/cs3org/reva/pkg/ctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/mime"
+	"github.com/cs3org/reva/pkg/sharedconf"
 	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/utils/acl"
 	"github.com/cs3org/reva/pkg/storage/utils/chunking"
 	"github.com/cs3org/reva/pkg/storage/utils/grants"
+	"github.com/cs3org/reva/pkg/storage/utils/spaces"
 	"github.com/cs3org/reva/pkg/storage/utils/templates"
 	"github.com/cs3org/reva/pkg/utils"
 	"github.com/pkg/errors"
 )
 
+// deniedRole is the role grants.GetACLPerm assigns to an empty permission set, it marks denials in the DB
+const deniedRole = "!r!w!x!m!u!d"
+
 // Config holds the configuration details for the local fs.
 type Config struct {
-	Root                string `mapstructure:"root"`
-	DisableHome         bool   `mapstructure:"disable_home"`
-	UserLayout          string `mapstructure:"user_layout"`
-	ShareFolder         string `mapstructure:"share_folder"`
-	DataTransfersFolder string `mapstructure:"data_transfers_folder"`
-	Uploads             string `mapstructure:"uploads"`
-	DataDirectory       string `mapstructure:"data_directory"`
-	RecycleBin          string `mapstructure:"recycle_bin"`
-	Versions            string `mapstructure:"versions"`
-	Shadow              string `mapstructure:"shadow"`
-	References          string `mapstructure:"references"`
+	Root                string   `mapstructure:"root"`
+	DisableHome         bool     `mapstructure:"disable_home"`
+	UserLayout          string   `mapstructure:"user_layout"`
+	ShareFolder         string   `mapstructure:"share_folder"`
+	DataTransfersFolder string   `mapstructure:"data_transfers_folder"`
+	Uploads             string   `mapstructure:"uploads"`
+	DataDirectory       string   `mapstructure:"data_directory"`
+	RecycleBin          string   `mapstructure:"recycle_bin"`
+	Versions            string   `mapstructure:"versions"`
+	Shadow              string   `mapstructure:"shadow"`
+	References          string   `mapstructure:"references"`
+	ProjectSpaces       []string `mapstructure:"project_spaces"`
+	GatewaySVC          string   `mapstructure:"gatewaysvc"`
 }
 
 func (c *Config) init() {
@@ -90,12 +98,17 @@ func (c *Config) init() {
 	c.RecycleBin = path.Join(c.Shadow, "recycle_bin")
 	c.Versions = path.Join(c.Shadow, "versions")
 
+	c.GatewaySVC = sharedconf.GetGatewaySVC(c.GatewaySVC)
 }
 
 type localfs struct {
 	conf         *Config
 	db           *sql.DB
 	chunkHandler *chunking.ChunkHandler
+	// denials caches the denial lookups of the running requests
+	denials sync.Map
+	// spaceUsage caches the usage of the spaces by their wrapped root path
+	spaceUsage *spaces.UsageCache
 }
 
 // NewLocalFS returns a storage.FS interface implementation that controls then
@@ -125,6 +138,7 @@ func NewLocalFS(c *Config) (storage.FS, error) {
 		conf:         c,
 		db:           db,
 		chunkHandler: chunking.NewChunkHandler(c.Uploads),
+		spaceUsage:   spaces.NewUsageCache(spaces.Usage),
 	}, nil
 }
 
@@ -290,6 +304,7 @@ func (fs *localfs) permissionSet(ctx context.Context, owner *userpb.UserId) *pro
 			AddGrant:             true,
 			CreateContainer:      true,
 			Delete:               true,
+			DenyGrant:            true,
 			GetPath:              true,
 			GetQuota:             true,
 			InitiateFileDownload: true,
@@ -312,6 +327,7 @@ func (fs *localfs) permissionSet(ctx context.Context, owner *userpb.UserId) *pro
 		AddGrant:             true,
 		CreateContainer:      true,
 		Delete:               true,
+		DenyGrant:            true,
 		GetPath:              true,
 		GetQuota:             true,
 		InitiateFileDownload: true,
@@ -435,8 +451,28 @@ func (fs *localfs) GetPathByID(ctx context.Context, ref *provider.ResourceId) (s
 	return url.QueryUnescape(strings.TrimPrefix(ref.OpaqueId, "fileid-"+layout))
 }
 
+// DenyGrant denies access to the resource and its children for the grantee.
+// Denials are stored like grants, with the role representing an empty permission set.
 func (fs *localfs) DenyGrant(ctx context.Context, ref *provider.Reference, g *provider.Grantee) error {
-	return errtypes.NotSupported("localfs: deny grant not supported")
+	fn, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return errors.Wrap(err, "localfs: error resolving ref")
+	}
+	fn = fs.wrap(ctx, fn)
+
+	grantee, err := granteeKey(g)
+	if err != nil {
+		return err
+	}
+
+	err = fs.addToACLDB(ctx, fn, grantee, deniedRole)
+	if err != nil {
+		return errors.Wrap(err, "localfs: error adding entry to DB")
+	}
+	// the grants of the resource changed
+	fs.denials.Delete(ctx)
+
+	return fs.propagate(ctx, fn)
 }
 
 func (fs *localfs) AddGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
@@ -451,23 +487,140 @@ func (fs *localfs) AddGrant(ctx context.Context, ref *provider.Reference, g *pro
 		return errors.Wrap(err, "localfs: unknown set permissions")
 	}
 
-	granteeType, err := grants.GetACLType(g.Grantee.Type)
+	grantee, err := granteeKey(g.Grantee)
 	if err != nil {
-		return errors.Wrap(err, "localfs: error getting grantee type")
+		return err
+	}
+
+	err = fs.addToACLDB(ctx, fn, grantee, role)
+	if err != nil {
+		return errors.Wrap(err, "localfs: error adding entry to DB")
+	}
+	// the grants of the resource changed
+	fs.denials.Delete(ctx)
+
+	return fs.propagate(ctx, fn)
+}
+
+// granteeKey returns the key the grants of a grantee are stored under in the DB
+func granteeKey(g *provider.Grantee) (string, error) {
+	granteeType, err := grants.GetACLType(g.Type)
+	if err != nil {
+		return "", errors.Wrap(err, "localfs: error getting grantee type")
 	}
 	var grantee string
 	if granteeType == acl.TypeUser {
-		grantee = fmt.Sprintf("%s:%s:%s@%s", granteeType, g.Grantee.GetUserId().OpaqueId, utils.UserTypeToString(g.Grantee.GetUserId().Type), g.Grantee.GetUserId().Idp)
+		grantee = userGranteeKey(g.GetUserId())
 	} else if granteeType == acl.TypeGroup {
-		grantee = fmt.Sprintf("%s::%s@%s", granteeType, g.Grantee.GetGroupId().OpaqueId, g.Grantee.GetGroupId().Idp)
+		grantee = fmt.Sprintf("%s::%s@%s", granteeType, g.GetGroupId().OpaqueId, g.GetGroupId().Idp)
 	}
+	return grantee, nil
+}
 
-	err = fs.addToACLDB(ctx, fn, grantee, role)
+func userGranteeKey(u *userpb.UserId) string {
+	return fmt.Sprintf("%s:%s:%s@%s", acl.TypeUser, u.OpaqueId, utils.UserTypeToString(u.Type), u.Idp)
+}
+
+// checkDenied returns a PermissionDenied error if access to the resource or
+// one of its parents was denied to the current user
+func (fs *localfs) checkDenied(ctx context.Context, fn string) error {
+	if denied, err := fs.isDenied(ctx, fn); err != nil {
+		return err
+	} else if denied {
+		return errtypes.PermissionDenied(fn)
+	}
+	return nil
+}
+
+// isDenied checks if access to the resource or one of its parents was denied to the current user
+func (fs *localfs) isDenied(ctx context.Context, fn string) (bool, error) {
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok || u.Id == nil {
+		return false, nil
+	}
+	root := path.Clean(fs.conf.DataDirectory)
+	for p := fn; strings.HasPrefix(p, root); p = path.Dir(p) {
+		denied, err := fs.hasDenial(ctx, u, p)
+		if err != nil || denied {
+			return denied, err
+		}
+		if p == root {
+			break
+		}
+	}
+	return false, nil
+}
+
+// denialCache holds the denials looked up during a request by resource
+type denialCache struct {
+	sync.Mutex
+	denied map[string]bool
+}
+
+// requestDenials returns the denial cache of the request behind ctx. The cache
+// is dropped when the request is done. Contexts that are never done, like the
+// background context, get no cache.
+func (fs *localfs) requestDenials(ctx context.Context) *denialCache {
+	if ctx.Done() == nil {
+		return nil
+	}
+	c, loaded := fs.denials.LoadOrStore(ctx, &denialCache{denied: map[string]bool{}})
+	if !loaded {
+		go func() {
+			<-ctx.Done()
+			fs.denials.Delete(ctx)
+		}()
+	}
+	return c.(*denialCache)
+}
+
+// hasDenial checks if the resource itself carries a denial for the user or one of its groups
+func (fs *localfs) hasDenial(ctx context.Context, u *userpb.User, fn string) (bool, error) {
+	c := fs.requestDenials(ctx)
+	if c != nil {
+		c.Lock()
+		denied, ok := c.denied[fn]
+		c.Unlock()
+		if ok {
+			return denied, nil
+		}
+	}
+
+	denied, err := fs.lookupDenial(ctx, u, fn)
+	if err == nil && c != nil {
+		c.Lock()
+		c.denied[fn] = denied
+		c.Unlock()
+	}
+	return denied, err
+}
+
+func (fs *localfs) lookupDenial(ctx context.Context, u *userpb.User, fn string) (bool, error) {
+	rows, err := fs.getACLs(ctx, fn)
 	if err != nil {
-		return errors.Wrap(err, "localfs: error adding entry to DB")
+		return false, errors.Wrap(err, "localfs: error listing grants")
 	}
+	defer rows.Close()
 
-	return fs.propagate(ctx, fn)
+	userKey := userGranteeKey(u.Id)
+	var grantee, role string
+	for rows.Next() {
+		if err := rows.Scan(&grantee, &role); err != nil {
+			return false, errors.Wrap(err, "localfs: error scanning db rows")
+		}
+		if role != deniedRole {
+			continue
+		}
+		if grantee == userKey {
+			return true, nil
+		}
+		for _, g := range u.Groups {
+			if strings.HasPrefix(grantee, fmt.Sprintf("%s::%s@", acl.TypeGroup, g)) {
+				return true, nil
+			}
+		}
+	}
+	return false, rows.Err()
 }
 
 func (fs *localfs) ListGrants(ctx context.Context, ref *provider.Reference) ([]*provider.Grant, error) {
@@ -481,6 +634,7 @@ func (fs *localfs) ListGrants(ctx context.Context, ref *provider.Reference) ([]*
 	if err != nil {
 		return nil, errors.Wrap(err, "localfs: error listing grants")
 	}
+	defer g.Close()
 	var granteeID, role string
 	var grantList []*provider.Grant
 
@@ -489,7 +643,7 @@ func (fs *localfs) ListGrants(ctx context.Context, ref *provider.Reference) ([]*
 		if err != nil {
 			return nil, errors.Wrap(err, "localfs: error scanning db rows")
 		}
-		grantSplit := strings.Split(granteeID, ":")
+		grantSplit := strings.SplitN(granteeID, ":", 3)
 		grantee := &provider.Grantee{Type: grants.GetGranteeType(grantSplit[0])}
 		parts := strings.Split(grantSplit[2], "@")
 		if grantSplit[0] == acl.TypeUser {
@@ -515,21 +669,17 @@ func (fs *localfs) RemoveGrant(ctx context.Context, ref *provider.Reference, g *
 	}
 	fn = fs.wrap(ctx, fn)
 
-	granteeType, err := grants.GetACLType(g.Grantee.Type)
+	grantee, err := granteeKey(g.Grantee)
 	if err != nil {
-		return errors.Wrap(err, "localfs: error getting grantee type")
-	}
-	var grantee string
-	if granteeType == acl.TypeUser {
-		grantee = fmt.Sprintf("%s:%s@%s", granteeType, g.Grantee.GetUserId().OpaqueId, g.Grantee.GetUserId().Idp)
-	} else if granteeType == acl.TypeGroup {
-		grantee = fmt.Sprintf("%s:%s@%s", granteeType, g.Grantee.GetGroupId().OpaqueId, g.Grantee.GetGroupId().Idp)
+		return err
 	}
 
 	err = fs.removeFromACLDB(ctx, fn, grantee)
 	if err != nil {
 		return errors.Wrap(err, "localfs: error removing from DB")
 	}
+	// the grants of the resource changed
+	fs.denials.Delete(ctx)
 
 	return fs.propagate(ctx, fn)
 }
@@ -569,7 +719,21 @@ func (fs *localfs) CreateStorageSpace(ctx context.Context, req *provider.CreateS
 	return nil, fmt.Errorf("unimplemented: CreateStorageSpace")
 }
 
+// SetArbitraryMetadata sets the given metadata, keys reserved for spaces
+// cannot be set
 func (fs *localfs) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	if err := spaces.CheckMetadata(md.GetMetadata()); err != nil {
+		return err
+	}
+	return fs.setArbitraryMetadata(ctx, ref, md)
+}
+
+// SetSpaceMetadata sets the metadata of the space rooted at the given reference
+func (fs *localfs) SetSpaceMetadata(ctx context.Context, ref *provider.Reference, md map[string]string) error {
+	return fs.setArbitraryMetadata(ctx, ref, &provider.ArbitraryMetadata{Metadata: md})
+}
+
+func (fs *localfs) setArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
 
 	np, err := fs.resolve(ctx, ref)
 	if err != nil {
@@ -584,6 +748,9 @@ func (fs *localfs) SetArbitraryMetadata(ctx context.Context, ref *provider.Refer
 		np = fs.wrapReferences(ctx, np)
 	} else {
 		np = fs.wrap(ctx, np)
+		if err := fs.checkDenied(ctx, np); err != nil {
+			return err
+		}
 	}
 
 	fi, err := os.Stat(np)
@@ -657,7 +824,9 @@ func parseMTime(v string) (t time.Time, err error) {
 }
 
 func (fs *localfs) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) error {
-
+	if err := spaces.CheckKeys(keys); err != nil {
+		return err
+	}
 	np, err := fs.resolve(ctx, ref)
 	if err != nil {
 		return errors.Wrap(err, "localfs: error resolving ref")
@@ -671,6 +840,9 @@ func (fs *localfs) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Ref
 		np = fs.wrapReferences(ctx, np)
 	} else {
 		np = fs.wrap(ctx, np)
+		if err := fs.checkDenied(ctx, np); err != nil {
+			return err
+		}
 	}
 
 	_, err = os.Stat(np)
@@ -788,6 +960,9 @@ func (fs *localfs) CreateDir(ctx context.Context, ref *provider.Reference) error
 	}
 
 	fn = fs.wrap(ctx, fn)
+	if err := fs.checkDenied(ctx, fn); err != nil {
+		return err
+	}
 	if _, err := os.Stat(fn); err == nil {
 		return errtypes.AlreadyExists(fn)
 	}
@@ -804,7 +979,34 @@ func (fs *localfs) CreateDir(ctx context.Context, ref *provider.Reference) error
 
 // TouchFile as defined in the storage.FS interface
 func (fs *localfs) TouchFile(ctx context.Context, ref *provider.Reference) error {
-	return fmt.Errorf("unimplemented: TouchFile")
+	fn, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return errors.Wrap(err, "localfs: error resolving ref")
+	}
+
+	if fs.isShareFolder(ctx, fn) {
+		return errtypes.PermissionDenied("localfs: cannot create file under the share folder")
+	}
+
+	fn = fs.wrap(ctx, fn)
+	if err := fs.checkDenied(ctx, fn); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(fn, os.O_CREATE|os.O_EXCL|os.O_WRONLY, defaultFilePerm)
+	if err != nil {
+		if os.IsExist(err) {
+			return errtypes.AlreadyExists(fn)
+		}
+		if os.IsNotExist(err) {
+			return errtypes.NotFound(fn)
+		}
+		return errors.Wrap(err, "localfs: error creating file "+fn)
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "localfs: error closing file "+fn)
+	}
+
+	return fs.propagate(ctx, fn)
 }
 
 func (fs *localfs) Delete(ctx context.Context, ref *provider.Reference) error {
@@ -822,6 +1024,9 @@ func (fs *localfs) Delete(ctx context.Context, ref *provider.Reference) error {
 		fp = fs.wrapReferences(ctx, fn)
 	} else {
 		fp = fs.wrap(ctx, fn)
+		if err := fs.checkDenied(ctx, fp); err != nil {
+			return err
+		}
 	}
 
 	_, err = os.Stat(fp)
@@ -863,6 +1068,13 @@ func (fs *localfs) Move(ctx context.Context, oldRef, newRef *provider.Reference)
 	oldName = fs.wrap(ctx, oldName)
 	newName = fs.wrap(ctx, newName)
 
+	if err := fs.checkDenied(ctx, oldName); err != nil {
+		return err
+	}
+	if err := fs.checkDenied(ctx, newName); err != nil {
+		return err
+	}
+
 	if err := os.Rename(oldName, newName); err != nil {
 		return errors.Wrap(err, "localfs: error moving "+oldName+" to "+newName)
 	}
@@ -937,6 +1149,10 @@ func (fs *localfs) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []
 		return nil, errors.Wrap(err, "localfs: error stating "+fn)
 	}
 
+	if err := fs.checkDenied(ctx, fn); err != nil {
+		return nil, err
+	}
+
 	return fs.normalize(ctx, md, fn, mdKeys)
 }
 
@@ -1001,9 +1217,21 @@ func (fs *localfs) listFolder(ctx context.Context, fn string, mdKeys []string) (
 		return nil, errors.Wrap(err, "localfs: error listing "+fn)
 	}
 
+	if err := fs.checkDenied(ctx, fn); err != nil {
+		return nil, err
+	}
+
+	u, _ := ctxpkg.ContextGetUser(ctx)
 	finfos := []*provider.ResourceInfo{}
 	for _, md := range mds {
-		info, err := fs.normalize(ctx, md, path.Join(fn, md.Name()), mdKeys)
+		cp := path.Join(fn, md.Name())
+		// denials on the parents were checked above
+		if u != nil && u.Id != nil {
+			if denied, err := fs.hasDenial(ctx, u, cp); err != nil || denied {
+				continue
+			}
+		}
+		info, err := fs.normalize(ctx, md, cp, mdKeys)
 		if err == nil {
 			finfos = append(finfos, info)
 		}
@@ -1050,6 +1278,10 @@ func (fs *localfs) Download(ctx context.Context, ref *provider.Reference) (io.Re
 	}
 
 	fn = fs.wrap(ctx, fn)
+	if err := fs.checkDenied(ctx, fn); err != nil {
+		return nil, err
+	}
+
 	r, err := os.Open(fn)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -1286,13 +1518,39 @@ func (fs *localfs) RestoreRecycleItem(ctx context.Context, basePath, key, relati
 	return fs.propagate(ctx, localRestorePath)
 }
 
+// ListStorageSpaces lists the home of the user as personal space and the
+// configured project directories as project spaces
 func (fs *localfs) ListStorageSpaces(ctx context.Context, filter []*provider.ListStorageSpacesRequest_Filter) ([]*provider.StorageSpace, error) {
-	return nil, errtypes.NotSupported("list storage spaces")
+	return spaces.List(ctx, fs, fs.spacesLayout(), filter)
 }
 
 // UpdateStorageSpace updates a storage space
 func (fs *localfs) UpdateStorageSpace(ctx context.Context, req *provider.UpdateStorageSpaceRequest) (*provider.UpdateStorageSpaceResponse, error) {
-	return nil, errtypes.NotSupported("update storage space")
+	return spaces.Update(ctx, fs, fs.spacesLayout(), spaces.GatewayPermissionChecker(fs.conf.GatewaySVC), req)
+}
+
+func (fs *localfs) spacesLayout() spaces.Layout {
+	l := spaces.Layout{Projects: fs.conf.ProjectSpaces}
+	if !fs.conf.DisableHome {
+		l.Personal = "/"
+	}
+	return l
+}
+
+// checkQuota checks that writing size bytes to the given path fits into the
+// quota of its space
+func (fs *localfs) checkQuota(ctx context.Context, p string, size uint64) error {
+	return spaces.CheckQuota(ctx, fs, fs.spacesLayout(), p, size, func(root string) (uint64, error) {
+		return fs.spaceUsage.Usage(fs.wrap(ctx, root))
+	})
+}
+
+// addSpaceUsage adds the bytes written to the given path to the usage of its
+// space
+func (fs *localfs) addSpaceUsage(ctx context.Context, p string, delta int64) {
+	if root, ok := fs.spacesLayout().SpaceOf(p); ok {
+		fs.spaceUsage.Add(fs.wrap(ctx, root), delta)
+	}
 }
 
 func (fs *localfs) propagate(ctx context.Context, leafPath string) error {
@@ -0,0 +1,182 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package localfs
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/utils/spaces"
+)
+
+var (
+	alice = &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "alice", Type: userpb.UserType_USER_TYPE_PRIMARY}, Username: "alice"}
+	bob   = &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "bob", Type: userpb.UserType_USER_TYPE_PRIMARY}, Username: "bob"}
+)
+
+// requestCtx returns the context of a request by the user, which is done
+// when the test ends
+func requestCtx(t *testing.T, u *userpb.User) context.Context {
+	ctx, cancel := context.WithCancel(ctxpkg.ContextSetUser(context.Background(), u))
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func ref(p string) *provider.Reference {
+	return &provider.Reference{Path: p}
+}
+
+// newDeniedFS returns a localfs with a /secret folder alice was denied access to
+func newDeniedFS(t *testing.T) storage.FS {
+	fs, err := NewLocalFS(&Config{Root: t.TempDir(), DisableHome: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = fs.Shutdown(context.Background()) })
+
+	ctx := requestCtx(t, bob)
+	for _, dir := range []string{"/secret", "/secret/folder", "/public"} {
+		if err := fs.CreateDir(ctx, ref(dir)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"/secret/file.txt", "/public/file.txt"} {
+		if err := fs.TouchFile(ctx, ref(file)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	grantee := &provider.Grantee{
+		Type: provider.GranteeType_GRANTEE_TYPE_USER,
+		Id:   &provider.Grantee_UserId{UserId: alice.Id},
+	}
+	if err := fs.DenyGrant(ctx, ref("/secret"), grantee); err != nil {
+		t.Fatal(err)
+	}
+	return fs
+}
+
+func expectDenied(t *testing.T, op string, err error) {
+	t.Helper()
+	if _, ok := err.(errtypes.IsPermissionDenied); !ok {
+		t.Errorf("expected %s to be denied, got %v", op, err)
+	}
+}
+
+func TestDeniedWrites(t *testing.T) {
+	fs := newDeniedFS(t)
+	ctx := requestCtx(t, alice)
+	md := &provider.ArbitraryMetadata{Metadata: map[string]string{"foo": "bar"}}
+
+	expectDenied(t, "stat", func() error { _, err := fs.GetMD(ctx, ref("/secret/file.txt"), nil); return err }())
+	expectDenied(t, "create dir", fs.CreateDir(ctx, ref("/secret/new")))
+	expectDenied(t, "touch file", fs.TouchFile(ctx, ref("/secret/folder/new.txt")))
+	expectDenied(t, "delete", fs.Delete(ctx, ref("/secret/file.txt")))
+	expectDenied(t, "move out", fs.Move(ctx, ref("/secret/file.txt"), ref("/public/moved.txt")))
+	expectDenied(t, "move in", fs.Move(ctx, ref("/public/file.txt"), ref("/secret/moved.txt")))
+	expectDenied(t, "set metadata", fs.SetArbitraryMetadata(ctx, ref("/secret/file.txt"), md))
+	expectDenied(t, "unset metadata", fs.UnsetArbitraryMetadata(ctx, ref("/secret/file.txt"), []string{"foo"}))
+	_, err := fs.InitiateUpload(ctx, ref("/secret/upload.txt"), 4, nil)
+	expectDenied(t, "initiate upload", err)
+
+	// the files are left alone
+	bobCtx := requestCtx(t, bob)
+	for _, p := range []string{"/secret/file.txt", "/public/file.txt"} {
+		if _, err := fs.GetMD(bobCtx, ref(p), nil); err != nil {
+			t.Errorf("expected %s to exist: %v", p, err)
+		}
+	}
+
+	// other users and other folders are not affected
+	if err := fs.Move(bobCtx, ref("/secret/file.txt"), ref("/secret/folder/file.txt")); err != nil {
+		t.Errorf("expected bob to move the file: %v", err)
+	}
+	if err := fs.SetArbitraryMetadata(ctx, ref("/public/file.txt"), md); err != nil {
+		t.Errorf("expected alice to set metadata outside of the denied folder: %v", err)
+	}
+	if err := fs.Delete(ctx, ref("/public/file.txt")); err != nil {
+		t.Errorf("expected alice to delete outside of the denied folder: %v", err)
+	}
+}
+
+func TestSpaceMetadataIsReserved(t *testing.T) {
+	fs := newDeniedFS(t)
+	ctx := requestCtx(t, bob)
+	md := &provider.ArbitraryMetadata{Metadata: map[string]string{spaces.QuotaKey: "1"}}
+
+	expectDenied(t, "set space metadata", fs.SetArbitraryMetadata(ctx, ref("/public"), md))
+	expectDenied(t, "unset space metadata", fs.UnsetArbitraryMetadata(ctx, ref("/public"), []string{spaces.QuotaKey}))
+
+	ri, err := fs.GetMD(ctx, ref("/public"), []string{spaces.QuotaKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q, ok := ri.GetArbitraryMetadata().GetMetadata()[spaces.QuotaKey]; ok {
+		t.Errorf("expected no quota to be set, got %q", q)
+	}
+}
+
+func TestDeniedUpload(t *testing.T) {
+	fs := newDeniedFS(t)
+
+	// an upload bob initiated cannot be used by alice
+	ids, err := fs.InitiateUpload(requestCtx(t, bob), ref("/secret/upload.txt"), 4, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := requestCtx(t, alice)
+	err = fs.Upload(ctx, ref(ids["simple"]), ioutil.NopCloser(strings.NewReader("data")))
+	expectDenied(t, "upload", err)
+
+	if err := fs.Upload(requestCtx(t, bob), ref(ids["simple"]), ioutil.NopCloser(strings.NewReader("data"))); err != nil {
+		t.Fatalf("expected bob to upload: %v", err)
+	}
+}
+
+func TestDenialsAreCachedPerRequest(t *testing.T) {
+	fs := newDeniedFS(t)
+	ctx := requestCtx(t, alice)
+	if _, err := fs.GetMD(ctx, ref("/secret/file.txt"), nil); err == nil {
+		t.Fatal("expected stat to be denied")
+	}
+
+	grant := &provider.Grant{
+		Grantee: &provider.Grantee{
+			Type: provider.GranteeType_GRANTEE_TYPE_USER,
+			Id:   &provider.Grantee_UserId{UserId: alice.Id},
+		},
+	}
+	if err := fs.RemoveGrant(requestCtx(t, bob), ref("/secret"), grant); err != nil {
+		t.Fatal(err)
+	}
+
+	// the running request keeps what it looked up, the next one sees the change
+	if _, err := fs.GetMD(ctx, ref("/secret/file.txt"), nil); err == nil {
+		t.Error("expected the running request to use its cached denial")
+	}
+	if _, err := fs.GetMD(requestCtx(t, alice), ref("/secret/file.txt"), nil); err != nil {
+		t.Errorf("expected the next request to be allowed: %v", err)
+	}
+}
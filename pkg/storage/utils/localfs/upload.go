@@ -49,6 +49,9 @@ func (fs *localfs) Upload(ctx context.Context, ref *provider.Reference, r io.Rea
 	uploadInfo := upload.(*fileUpload)
 
 	p := uploadInfo.info.Storage["InternalDestination"]
+	if err := fs.checkDenied(ctx, p); err != nil {
+		return err
+	}
 	ok, err := chunking.IsChunked(p)
 	if err != nil {
 		return errors.Wrap(err, "localfs: error checking path")
@@ -111,6 +114,16 @@ func (fs *localfs) InitiateUpload(ctx context.Context, ref *provider.Reference,
 		}
 	}
 
+	if err := fs.checkDenied(ctx, fs.wrap(ctx, np)); err != nil {
+		return nil, err
+	}
+
+	if !info.SizeIsDeferred {
+		if err := fs.checkQuota(ctx, np, uint64(uploadLength)); err != nil {
+			return nil, err
+		}
+	}
+
 	upload, err := fs.NewUpload(ctx, info)
 	if err != nil {
 		return nil, err
@@ -313,6 +326,19 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) error {
 
 	np := upload.info.Storage["InternalDestination"]
 
+	// access might have been denied since the upload was initiated
+	if err := upload.fs.checkDenied(upload.ctx, np); err != nil {
+		return err
+	}
+
+	fi, err := os.Stat(upload.binPath)
+	if err != nil {
+		return err
+	}
+	if err := upload.fs.checkQuota(upload.ctx, upload.fs.unwrap(upload.ctx, np), uint64(fi.Size())); err != nil {
+		return err
+	}
+
 	// TODO check etag with If-Match header
 	// if destination exists
 	// if _, err := os.Stat(np); err == nil {
@@ -322,17 +348,20 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) error {
 	//}
 
 	// if destination exists
-	if _, err := os.Stat(np); err == nil {
+	delta := fi.Size()
+	if old, err := os.Stat(np); err == nil {
+		delta -= old.Size()
 		// create revision
 		if err := upload.fs.archiveRevision(upload.ctx, np); err != nil {
 			return err
 		}
 	}
 
-	err := os.Rename(upload.binPath, np)
+	err = os.Rename(upload.binPath, np)
 	if err != nil {
 		return err
 	}
+	upload.fs.addSpaceUsage(upload.ctx, upload.fs.unwrap(upload.ctx, np), delta)
 
 	// only delete the upload if it was successfully written to the fs
 	if err := os.Remove(upload.infoPath); err != nil {
@@ -0,0 +1,167 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package locks implements the parts of the CS3 file locking which are
+// shared by the storage drivers. The drivers persist the locks themselves.
+package locks
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/utils"
+)
+
+// Lock is the lock of a resource as persisted by the storage drivers.
+// The lock id of WOPI or WebDAV clients is carried in the metadata.
+type Lock struct {
+	Type     provider.LockType `json:"type"`
+	User     *userpb.UserId    `json:"user,omitempty"`
+	AppName  string            `json:"app_name,omitempty"`
+	Metadata string            `json:"metadata,omitempty"`
+	// Locker is the user that set the lock, also for locks held by apps
+	Locker     *userpb.UserId `json:"locker"`
+	Mtime      time.Time      `json:"mtime"`
+	Expiration time.Time      `json:"expiration"`
+}
+
+// New returns the lock the user in the context requests, expiring after ttl.
+// Apps can only request locks under the name they send along, see
+// ctxpkg.LockAppHeader.
+func New(ctx context.Context, lock *provider.Lock, ttl time.Duration) (*Lock, error) {
+	if lock.GetType() == provider.LockType_LOCK_TYPE_INVALID {
+		return nil, errtypes.BadRequest("invalid lock type")
+	}
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok {
+		return nil, errtypes.UserRequired("userrequired")
+	}
+	l := &Lock{
+		Type:     lock.Type,
+		Metadata: lock.Metadata,
+		Locker:   u.Id,
+		Mtime:    time.Now(),
+	}
+	l.Expiration = l.Mtime.Add(ttl)
+	switch h := lock.Holder.(type) {
+	case *provider.Lock_User:
+		l.User = h.User
+	case *provider.Lock_AppName:
+		if app, _ := ctxpkg.ContextGetLockApp(ctx); app != h.AppName {
+			return nil, errtypes.BadRequest("lock app name does not match the requesting app")
+		}
+		l.AppName = h.AppName
+	}
+	if l.User == nil && l.AppName == "" {
+		return nil, errtypes.BadRequest("lock holder missing")
+	}
+	return l, nil
+}
+
+// Expired returns whether the lock expired.
+func (l *Lock) Expired() bool {
+	return time.Now().After(l.Expiration)
+}
+
+// Holder returns the name of the holder of the lock.
+func (l *Lock) Holder() string {
+	if l.AppName != "" {
+		return l.AppName
+	}
+	return l.User.GetOpaqueId()
+}
+
+// HeldBy returns whether the lock is held by the requester: the user that
+// set the lock and, for locks held by apps, the app named in the context.
+func (l *Lock) HeldBy(ctx context.Context) bool {
+	if l.AppName != "" {
+		if app, ok := ctxpkg.ContextGetLockApp(ctx); ok && app == l.AppName {
+			return true
+		}
+	}
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	return ok && utils.UserEqual(u.Id, l.Locker)
+}
+
+// SameHolder returns whether lock names the holder of the lock.
+func (l *Lock) SameHolder(lock *provider.Lock) bool {
+	if l.AppName != "" {
+		return lock.GetAppName() == l.AppName
+	}
+	return utils.UserEqual(lock.GetUser(), l.User)
+}
+
+// CS3 returns the lock as a CS3 lock.
+func (l *Lock) CS3() *provider.Lock {
+	lock := &provider.Lock{
+		Type:     l.Type,
+		Metadata: l.Metadata,
+		Mtime:    &types.Timestamp{Seconds: uint64(l.Mtime.Unix())},
+	}
+	if l.AppName != "" {
+		lock.Holder = &provider.Lock_AppName{AppName: l.AppName}
+	} else {
+		lock.Holder = &provider.Lock_User{User: l.User}
+	}
+	return lock
+}
+
+// Mutexes serializes the lock changes per resource within this process.
+// The mutexes are dropped once no one is waiting for them. The zero value
+// is ready to use.
+type Mutexes struct {
+	mu sync.Mutex
+	m  map[string]*mutex
+}
+
+type mutex struct {
+	sync.Mutex
+	refs int
+}
+
+// Lock waits until no one else changes the lock of the resource identified
+// by key. The returned function has to be called when done.
+func (ms *Mutexes) Lock(key string) func() {
+	ms.mu.Lock()
+	if ms.m == nil {
+		ms.m = map[string]*mutex{}
+	}
+	m, ok := ms.m[key]
+	if !ok {
+		m = &mutex{}
+		ms.m[key] = m
+	}
+	m.refs++
+	ms.mu.Unlock()
+
+	m.Lock()
+	return func() {
+		m.Unlock()
+		ms.mu.Lock()
+		if m.refs--; m.refs == 0 {
+			delete(ms.m, key)
+		}
+		ms.mu.Unlock()
+	}
+}
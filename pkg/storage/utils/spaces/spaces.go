@@ -0,0 +1,486 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package spaces exposes directories of storage drivers without native
+// support for storage spaces as personal and project spaces.
+package spaces
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	permissionsv1beta1 "github.com/cs3org/go-cs3apis/cs3/permissions/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/utils"
+)
+
+const (
+	// TypePersonal is the type of the space on the home of a user
+	TypePersonal = "personal"
+	// TypeProject is the type of the spaces on configured project directories
+	TypeProject = "project"
+
+	// KeyPrefix is the prefix of the metadata keys holding the properties of
+	// a space. Drivers must not let users set them as arbitrary metadata.
+	KeyPrefix = "space."
+	// NameKey is the metadata key holding the name of a space on its root
+	NameKey = KeyPrefix + "name"
+	// QuotaKey is the metadata key holding the quota of a space in bytes on its root
+	QuotaKey = KeyPrefix + "quota"
+
+	// SetQuotaPermission is the permission needed to change the quota of a space
+	SetQuotaPermission = "set-space-quota"
+)
+
+// FS is the part of a storage driver needed to expose its directories as spaces
+type FS interface {
+	GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error)
+	GetQuota(ctx context.Context, ref *provider.Reference) (uint64, uint64, error)
+	// SetSpaceMetadata sets metadata reserved for spaces on the given space root
+	SetSpaceMetadata(ctx context.Context, ref *provider.Reference, md map[string]string) error
+}
+
+// CheckKeys returns a PermissionDenied error if one of the given arbitrary
+// metadata keys is reserved for spaces
+func CheckKeys(keys []string) error {
+	for _, k := range keys {
+		if strings.HasPrefix(k, KeyPrefix) {
+			return errtypes.PermissionDenied("spaces: the metadata key " + k + " is reserved for spaces")
+		}
+	}
+	return nil
+}
+
+// CheckMetadata returns a PermissionDenied error if the given arbitrary
+// metadata contains keys reserved for spaces
+func CheckMetadata(md map[string]string) error {
+	for k := range md {
+		if err := CheckKeys([]string{k}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Layout tells where the spaces of a driver are located. Paths are relative
+// to the namespace of the driver.
+type Layout struct {
+	// Personal is the path of the home of the current user, empty if the
+	// driver has no user homes
+	Personal string
+	// Projects are the paths of the project directories
+	Projects []string
+}
+
+// SpaceOf returns the path of the space containing the given path. Project
+// spaces nested in the personal space take precedence.
+func (l Layout) SpaceOf(p string) (string, bool) {
+	p = path.Join("/", p)
+	root := ""
+	for _, s := range append([]string{l.Personal}, l.Projects...) {
+		if s == "" {
+			continue
+		}
+		s = path.Join("/", s)
+		if (p == s || strings.HasPrefix(p, strings.TrimSuffix(s, "/")+"/")) && len(s) > len(root) {
+			root = s
+		}
+	}
+	return root, root != ""
+}
+
+// PermissionChecker tells whether the current user has the given permission
+type PermissionChecker func(ctx context.Context, permission string) (bool, error)
+
+// GatewayPermissionChecker returns a PermissionChecker asking the
+// permissions service through the gateway at the given address
+func GatewayPermissionChecker(gatewayAddr string) PermissionChecker {
+	return func(ctx context.Context, permission string) (bool, error) {
+		u, ok := ctxpkg.ContextGetUser(ctx)
+		if !ok {
+			return false, errtypes.UserRequired("spaces: no user in ctx")
+		}
+		client, err := pool.GetGatewayServiceClient(gatewayAddr)
+		if err != nil {
+			return false, err
+		}
+		res, err := client.CheckPermission(ctx, &permissionsv1beta1.CheckPermissionRequest{
+			Permission: permission,
+			SubjectRef: &permissionsv1beta1.SubjectReference{
+				Spec: &permissionsv1beta1.SubjectReference_UserId{
+					UserId: u.Id,
+				},
+			},
+		})
+		if err != nil {
+			return false, err
+		}
+		return res.Status.Code == rpc.Code_CODE_OK, nil
+	}
+}
+
+// Filter matches spaces against the filters of a ListStorageSpaces request.
+// Filters of the same type match if any of them matches, filters of
+// different types must all match.
+type Filter struct {
+	types  map[string]struct{}
+	ids    map[string]struct{}
+	owners []*userpb.UserId
+}
+
+// NewFilter returns a filter for the given request filters
+func NewFilter(filters []*provider.ListStorageSpacesRequest_Filter) *Filter {
+	f := &Filter{
+		types: map[string]struct{}{},
+		ids:   map[string]struct{}{},
+	}
+	for _, filter := range filters {
+		switch filter.GetType() {
+		case provider.ListStorageSpacesRequest_Filter_TYPE_SPACE_TYPE:
+			f.types[filter.GetSpaceType()] = struct{}{}
+		case provider.ListStorageSpacesRequest_Filter_TYPE_ID:
+			// space ids are made of the storage id and the id of the space root
+			id := filter.GetId().GetOpaqueId()
+			if _, rootID, err := utils.SplitStorageSpaceID(id); err == nil {
+				id = rootID
+			}
+			f.ids[id] = struct{}{}
+		case provider.ListStorageSpacesRequest_Filter_TYPE_OWNER:
+			f.owners = append(f.owners, filter.GetOwner())
+		}
+	}
+	return f
+}
+
+// MatchesType returns whether spaces of the given type can match the filter
+func (f *Filter) MatchesType(spaceType string) bool {
+	if len(f.types) == 0 {
+		return true
+	}
+	_, ok := f.types[spaceType]
+	return ok
+}
+
+// Matches returns whether the space matches the filter
+func (f *Filter) Matches(space *provider.StorageSpace) bool {
+	if !f.MatchesType(space.SpaceType) {
+		return false
+	}
+	if len(f.ids) > 0 {
+		if _, ok := f.ids[space.GetRoot().GetOpaqueId()]; !ok {
+			return false
+		}
+	}
+	if len(f.owners) > 0 {
+		for _, o := range f.owners {
+			if utils.UserEqual(o, space.GetOwner().GetId()) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// New returns the space rooted at the given resource. A name or quota stored
+// on the root overrides the given name.
+func New(root *provider.ResourceInfo, spaceType, name string) *provider.StorageSpace {
+	space := &provider.StorageSpace{
+		Root: &provider.ResourceId{
+			StorageId: root.GetId().GetStorageId(),
+			OpaqueId:  root.GetId().GetOpaqueId(),
+		},
+		Name:      name,
+		SpaceType: spaceType,
+		Mtime:     root.Mtime,
+	}
+	if root.Owner != nil {
+		space.Owner = &userpb.User{Id: root.Owner}
+	}
+	md := root.GetArbitraryMetadata().GetMetadata()
+	if n := md[NameKey]; n != "" {
+		space.Name = n
+	}
+	if q, err := strconv.ParseUint(md[QuotaKey], 10, 64); err == nil {
+		space.Quota = &provider.Quota{
+			QuotaMaxBytes: q,
+			QuotaMaxFiles: math.MaxUint64,
+		}
+	}
+	return space
+}
+
+// List returns the spaces of the given layout that match the filters and that
+// the current user can access. The personal space falls back to the quota of
+// the driver if no quota was set on it.
+func List(ctx context.Context, fs FS, layout Layout, filters []*provider.ListStorageSpacesRequest_Filter) ([]*provider.StorageSpace, error) {
+	log := appctx.GetLogger(ctx)
+	u, ok := ctxpkg.ContextGetUser(ctx)
+	if !ok {
+		return nil, errtypes.UserRequired("spaces: no user in ctx")
+	}
+	f := NewFilter(filters)
+	mdKeys := []string{NameKey, QuotaKey}
+	spaces := []*provider.StorageSpace{}
+
+	if layout.Personal != "" && f.MatchesType(TypePersonal) {
+		ref := &provider.Reference{Path: layout.Personal}
+		ri, err := fs.GetMD(ctx, ref, mdKeys)
+		switch err.(type) {
+		case nil:
+			space := New(ri, TypePersonal, u.Username)
+			space.Owner = u
+			if space.Quota == nil {
+				if total, _, err := fs.GetQuota(ctx, ref); err == nil {
+					space.Quota = &provider.Quota{QuotaMaxBytes: total, QuotaMaxFiles: math.MaxUint64}
+				}
+			}
+			if f.Matches(space) {
+				spaces = append(spaces, space)
+			}
+		case errtypes.IsNotFound:
+			// the home has not been created yet
+		default:
+			return nil, err
+		}
+	}
+
+	if f.MatchesType(TypeProject) {
+		for _, p := range layout.Projects {
+			ri, err := fs.GetMD(ctx, &provider.Reference{Path: p}, mdKeys)
+			if err != nil {
+				log.Debug().Err(err).Str("path", p).Msg("spaces: skipping inaccessible project space")
+				continue
+			}
+			if !ri.GetPermissionSet().GetStat() {
+				continue
+			}
+			space := New(ri, TypeProject, path.Base(p))
+			if f.Matches(space) {
+				spaces = append(spaces, space)
+			}
+		}
+	}
+	return spaces, nil
+}
+
+// Update changes the name and quota of a space of the given layout. Only
+// users that can manage grants on the space root can update it, changing the
+// quota also needs the SetQuotaPermission.
+func Update(ctx context.Context, fs FS, layout Layout, checkPermission PermissionChecker, req *provider.UpdateStorageSpaceRequest) (*provider.UpdateStorageSpaceResponse, error) {
+	update := req.GetStorageSpace()
+	found, err := List(ctx, fs, layout, []*provider.ListStorageSpacesRequest_Filter{
+		{
+			Type: provider.ListStorageSpacesRequest_Filter_TYPE_ID,
+			Term: &provider.ListStorageSpacesRequest_Filter_Id{Id: update.GetId()},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(found) != 1 {
+		return &provider.UpdateStorageSpaceResponse{
+			Status: &rpc.Status{
+				Code:    rpc.Code_CODE_NOT_FOUND,
+				Message: fmt.Sprintf("update space failed: found %d matching spaces", len(found)),
+			},
+		}, nil
+	}
+	space := found[0]
+	ref := &provider.Reference{ResourceId: space.Root}
+
+	ri, err := fs.GetMD(ctx, ref, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !ri.GetPermissionSet().GetAddGrant() {
+		return &provider.UpdateStorageSpaceResponse{
+			Status: &rpc.Status{
+				Code:    rpc.Code_CODE_PERMISSION_DENIED,
+				Message: "update space failed: not a manager of the space",
+			},
+		}, nil
+	}
+
+	if update.Quota != nil {
+		ok, err := checkPermission(ctx, SetQuotaPermission)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return &provider.UpdateStorageSpaceResponse{
+				Status: &rpc.Status{
+					Code:    rpc.Code_CODE_PERMISSION_DENIED,
+					Message: "update space failed: not allowed to change the quota",
+				},
+			}, nil
+		}
+	}
+
+	md := map[string]string{}
+	if update.Name != "" {
+		md[NameKey] = update.Name
+		space.Name = update.Name
+	}
+	if update.Quota != nil {
+		md[QuotaKey] = strconv.FormatUint(update.Quota.QuotaMaxBytes, 10)
+		space.Quota = &provider.Quota{
+			QuotaMaxBytes: update.Quota.QuotaMaxBytes,
+			QuotaMaxFiles: math.MaxUint64,
+		}
+	}
+	if len(md) > 0 {
+		if err := fs.SetSpaceMetadata(ctx, ref, md); err != nil {
+			return nil, err
+		}
+	}
+
+	return &provider.UpdateStorageSpaceResponse{
+		Status:       &rpc.Status{Code: rpc.Code_CODE_OK},
+		StorageSpace: space,
+	}, nil
+}
+
+// CheckQuota returns an InsufficientStorage error if writing size bytes to
+// the given path exceeds the quota stored on the root of its space. The
+// written file replaces the current one. usage returns the bytes used by the
+// space rooted at the given path.
+func CheckQuota(ctx context.Context, fs FS, layout Layout, p string, size uint64, usage func(root string) (uint64, error)) error {
+	root, ok := layout.SpaceOf(p)
+	if !ok {
+		return nil
+	}
+	ri, err := fs.GetMD(ctx, &provider.Reference{Path: root}, []string{QuotaKey})
+	if err != nil {
+		return err
+	}
+	quota, err := strconv.ParseUint(ri.GetArbitraryMetadata().GetMetadata()[QuotaKey], 10, 64)
+	if err != nil {
+		// no quota set on the space
+		return nil
+	}
+	used, err := usage(root)
+	if err != nil {
+		return err
+	}
+	if ri, err := fs.GetMD(ctx, &provider.Reference{Path: p}, nil); err == nil && ri.Type == provider.ResourceType_RESOURCE_TYPE_FILE && ri.Size <= used {
+		used -= ri.Size
+	}
+	// if quota is smaller than used, quota-used would overflow
+	if used > quota || size > quota-used {
+		return errtypes.InsufficientStorage("quota exceeded")
+	}
+	return nil
+}
+
+// UsageTTL is how long a UsageCache keeps the usage of a space before
+// computing it again
+const UsageTTL = 5 * time.Minute
+
+// UsageCache keeps the usage of the spaces of drivers that cannot tell it
+// cheaply. The usage of a space is computed when it is first needed, then only
+// adjusted by the files written to the space until it expires. Deleted files
+// are taken into account once it expires.
+type UsageCache struct {
+	compute func(root string) (uint64, error)
+
+	mu     sync.Mutex
+	spaces map[string]usage
+}
+
+type usage struct {
+	bytes   uint64
+	expires time.Time
+}
+
+// NewUsageCache returns a UsageCache computing the usage of a space with the
+// given function. Spaces are keyed by their root.
+func NewUsageCache(compute func(root string) (uint64, error)) *UsageCache {
+	return &UsageCache{
+		compute: compute,
+		spaces:  map[string]usage{},
+	}
+}
+
+// Usage returns the bytes used by the space rooted at the given path
+func (c *UsageCache) Usage(root string) (uint64, error) {
+	c.mu.Lock()
+	u, ok := c.spaces[root]
+	c.mu.Unlock()
+	if ok && time.Now().Before(u.expires) {
+		return u.bytes, nil
+	}
+
+	bytes, err := c.compute(root)
+	if err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	c.spaces[root] = usage{bytes: bytes, expires: time.Now().Add(UsageTTL)}
+	c.mu.Unlock()
+	return bytes, nil
+}
+
+// Add adds the given bytes to the usage of the space rooted at the given path
+// if it is known
+func (c *UsageCache) Add(root string, delta int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	u, ok := c.spaces[root]
+	if !ok {
+		return
+	}
+	switch {
+	case delta >= 0:
+		u.bytes += uint64(delta)
+	case uint64(-delta) < u.bytes:
+		u.bytes -= uint64(-delta)
+	default:
+		u.bytes = 0
+	}
+	c.spaces[root] = u
+}
+
+// Usage returns the bytes used by the files below the given directory
+func Usage(dir string) (uint64, error) {
+	var used uint64
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			used += uint64(info.Size())
+		}
+		return nil
+	})
+	return used, err
+}
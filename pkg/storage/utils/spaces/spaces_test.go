@@ -0,0 +1,416 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package spaces
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	ctxpkg "github.com/cs3org/reva/pkg/ctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+var (
+	alice = &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "alice"}, Username: "alice"}
+	bob   = &userpb.User{Id: &userpb.UserId{Idp: "idp", OpaqueId: "bob"}, Username: "bob"}
+)
+
+// fakeFS serves resources by path and by id
+type fakeFS struct {
+	resources map[string]*provider.ResourceInfo
+	quota     uint64
+}
+
+func newFakeFS() *fakeFS {
+	fs := &fakeFS{resources: map[string]*provider.ResourceInfo{}, quota: 100}
+	fs.add("/", "home", alice.Id, &provider.ResourcePermissions{Stat: true, AddGrant: true})
+	fs.add("/projects/physics", "physics", alice.Id, &provider.ResourcePermissions{Stat: true, AddGrant: true})
+	fs.add("/projects/hidden", "hidden", bob.Id, &provider.ResourcePermissions{})
+	return fs
+}
+
+func (fs *fakeFS) add(p, id string, owner *userpb.UserId, perms *provider.ResourcePermissions) {
+	fs.resources[p] = &provider.ResourceInfo{
+		Id:                &provider.ResourceId{StorageId: "storage", OpaqueId: id},
+		Path:              p,
+		Owner:             owner,
+		PermissionSet:     perms,
+		ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: map[string]string{}},
+	}
+}
+
+func (fs *fakeFS) lookup(ref *provider.Reference) (*provider.ResourceInfo, error) {
+	if ref.ResourceId != nil {
+		for _, ri := range fs.resources {
+			if ri.Id.OpaqueId == ref.ResourceId.OpaqueId {
+				return ri, nil
+			}
+		}
+		return nil, errtypes.NotFound(ref.ResourceId.OpaqueId)
+	}
+	if ri, ok := fs.resources[ref.Path]; ok {
+		return ri, nil
+	}
+	return nil, errtypes.NotFound(ref.Path)
+}
+
+func (fs *fakeFS) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	return fs.lookup(ref)
+}
+
+func (fs *fakeFS) GetQuota(ctx context.Context, ref *provider.Reference) (uint64, uint64, error) {
+	return fs.quota, 0, nil
+}
+
+func (fs *fakeFS) SetSpaceMetadata(ctx context.Context, ref *provider.Reference, md map[string]string) error {
+	ri, err := fs.lookup(ref)
+	if err != nil {
+		return err
+	}
+	for k, v := range md {
+		ri.ArbitraryMetadata.Metadata[k] = v
+	}
+	return nil
+}
+
+var layout = Layout{
+	Personal: "/",
+	Projects: []string{"/projects/physics", "/projects/hidden", "/projects/missing"},
+}
+
+// grants returns a PermissionChecker granting the given permissions
+func grants(permissions ...string) PermissionChecker {
+	return func(ctx context.Context, permission string) (bool, error) {
+		for _, p := range permissions {
+			if p == permission {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+func typeFilter(t string) *provider.ListStorageSpacesRequest_Filter {
+	return &provider.ListStorageSpacesRequest_Filter{
+		Type: provider.ListStorageSpacesRequest_Filter_TYPE_SPACE_TYPE,
+		Term: &provider.ListStorageSpacesRequest_Filter_SpaceType{SpaceType: t},
+	}
+}
+
+func idFilter(id string) *provider.ListStorageSpacesRequest_Filter {
+	return &provider.ListStorageSpacesRequest_Filter{
+		Type: provider.ListStorageSpacesRequest_Filter_TYPE_ID,
+		Term: &provider.ListStorageSpacesRequest_Filter_Id{Id: &provider.StorageSpaceId{OpaqueId: id}},
+	}
+}
+
+func ownerFilter(u *userpb.UserId) *provider.ListStorageSpacesRequest_Filter {
+	return &provider.ListStorageSpacesRequest_Filter{
+		Type: provider.ListStorageSpacesRequest_Filter_TYPE_OWNER,
+		Term: &provider.ListStorageSpacesRequest_Filter_Owner{Owner: u},
+	}
+}
+
+func TestFilter(t *testing.T) {
+	personal := &provider.StorageSpace{
+		SpaceType: TypePersonal,
+		Root:      &provider.ResourceId{StorageId: "storage", OpaqueId: "home"},
+		Owner:     alice,
+	}
+	tests := []struct {
+		name    string
+		filters []*provider.ListStorageSpacesRequest_Filter
+		matches bool
+	}{
+		{"no filters", nil, true},
+		{"matching type", []*provider.ListStorageSpacesRequest_Filter{typeFilter(TypePersonal)}, true},
+		{"other type", []*provider.ListStorageSpacesRequest_Filter{typeFilter(TypeProject)}, false},
+		{"any of the types", []*provider.ListStorageSpacesRequest_Filter{typeFilter(TypeProject), typeFilter(TypePersonal)}, true},
+		{"root id", []*provider.ListStorageSpacesRequest_Filter{idFilter("home")}, true},
+		{"space id", []*provider.ListStorageSpacesRequest_Filter{idFilter("storage!home")}, true},
+		{"other id", []*provider.ListStorageSpacesRequest_Filter{idFilter("storage!physics")}, false},
+		{"owner", []*provider.ListStorageSpacesRequest_Filter{ownerFilter(alice.Id)}, true},
+		{"other owner", []*provider.ListStorageSpacesRequest_Filter{ownerFilter(bob.Id)}, false},
+		{"all types must match", []*provider.ListStorageSpacesRequest_Filter{idFilter("home"), ownerFilter(bob.Id)}, false},
+	}
+	for _, tt := range tests {
+		if got := NewFilter(tt.filters).Matches(personal); got != tt.matches {
+			t.Errorf("%s: expected %t, got %t", tt.name, tt.matches, got)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	ctx := ctxpkg.ContextSetUser(context.Background(), alice)
+	fs := newFakeFS()
+	fs.resources["/projects/physics"].ArbitraryMetadata.Metadata[NameKey] = "Physics"
+	fs.resources["/projects/physics"].ArbitraryMetadata.Metadata[QuotaKey] = "42"
+
+	spaces, err := List(ctx, fs, layout, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spaces) != 2 {
+		t.Fatalf("expected the personal and one project space, got %d spaces", len(spaces))
+	}
+
+	personal, project := spaces[0], spaces[1]
+	if personal.SpaceType != TypePersonal || personal.Name != "alice" || personal.Root.OpaqueId != "home" {
+		t.Errorf("unexpected personal space %+v", personal)
+	}
+	if personal.Quota.GetQuotaMaxBytes() != 100 {
+		t.Errorf("expected the personal space to fall back to the driver quota, got %d", personal.Quota.GetQuotaMaxBytes())
+	}
+	if project.SpaceType != TypeProject || project.Name != "Physics" || project.Root.OpaqueId != "physics" {
+		t.Errorf("unexpected project space %+v", project)
+	}
+	if project.Quota.GetQuotaMaxBytes() != 42 {
+		t.Errorf("expected the quota stored on the project space, got %d", project.Quota.GetQuotaMaxBytes())
+	}
+
+	spaces, err = List(ctx, fs, layout, []*provider.ListStorageSpacesRequest_Filter{typeFilter(TypeProject)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spaces) != 1 || spaces[0].Root.OpaqueId != "physics" {
+		t.Errorf("expected only the project space, got %+v", spaces)
+	}
+
+	// a missing home is not an error
+	delete(fs.resources, "/")
+	spaces, err = List(ctx, fs, layout, []*provider.ListStorageSpacesRequest_Filter{typeFilter(TypePersonal)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spaces) != 0 {
+		t.Errorf("expected no spaces, got %+v", spaces)
+	}
+
+	if _, err := List(context.Background(), fs, layout, nil); err == nil {
+		t.Error("expected an error without a user in the context")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := ctxpkg.ContextSetUser(context.Background(), alice)
+	fs := newFakeFS()
+
+	res, err := Update(ctx, fs, layout, grants(SetQuotaPermission), &provider.UpdateStorageSpaceRequest{
+		StorageSpace: &provider.StorageSpace{
+			Id:    &provider.StorageSpaceId{OpaqueId: "storage!physics"},
+			Name:  "Particle Physics",
+			Quota: &provider.Quota{QuotaMaxBytes: 1000},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		t.Fatalf("expected OK, got %s", res.Status.Code)
+	}
+	if res.StorageSpace.Name != "Particle Physics" || res.StorageSpace.Quota.QuotaMaxBytes != 1000 {
+		t.Errorf("unexpected updated space %+v", res.StorageSpace)
+	}
+	md := fs.resources["/projects/physics"].ArbitraryMetadata.Metadata
+	if md[NameKey] != "Particle Physics" || md[QuotaKey] != "1000" {
+		t.Errorf("expected the name and quota to be stored on the root, got %+v", md)
+	}
+
+	res, err = Update(ctx, fs, layout, grants(SetQuotaPermission), &provider.UpdateStorageSpaceRequest{
+		StorageSpace: &provider.StorageSpace{Id: &provider.StorageSpaceId{OpaqueId: "storage!unknown"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status.Code != rpc.Code_CODE_NOT_FOUND {
+		t.Errorf("expected NOT_FOUND, got %s", res.Status.Code)
+	}
+
+	// bob can see the space but cannot manage it
+	fs.resources["/projects/physics"].PermissionSet = &provider.ResourcePermissions{Stat: true}
+	res, err = Update(ctxpkg.ContextSetUser(context.Background(), bob), fs, layout, grants(), &provider.UpdateStorageSpaceRequest{
+		StorageSpace: &provider.StorageSpace{
+			Id:   &provider.StorageSpaceId{OpaqueId: "storage!physics"},
+			Name: "Mine",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status.Code != rpc.Code_CODE_PERMISSION_DENIED {
+		t.Errorf("expected PERMISSION_DENIED, got %s", res.Status.Code)
+	}
+}
+
+func TestUpdateQuotaNeedsPermission(t *testing.T) {
+	ctx := ctxpkg.ContextSetUser(context.Background(), alice)
+	fs := newFakeFS()
+
+	// alice manages her home but may not raise her own quota
+	res, err := Update(ctx, fs, layout, grants(), &provider.UpdateStorageSpaceRequest{
+		StorageSpace: &provider.StorageSpace{
+			Id:    &provider.StorageSpaceId{OpaqueId: "storage!home"},
+			Quota: &provider.Quota{QuotaMaxBytes: 1 << 40},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status.Code != rpc.Code_CODE_PERMISSION_DENIED {
+		t.Errorf("expected PERMISSION_DENIED, got %s", res.Status.Code)
+	}
+	if _, ok := fs.resources["/"].ArbitraryMetadata.Metadata[QuotaKey]; ok {
+		t.Error("expected the quota to be left unchanged")
+	}
+
+	// renaming needs no quota permission
+	res, err = Update(ctx, fs, layout, grants(), &provider.UpdateStorageSpaceRequest{
+		StorageSpace: &provider.StorageSpace{
+			Id:   &provider.StorageSpaceId{OpaqueId: "storage!home"},
+			Name: "Home",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		t.Errorf("expected OK, got %s", res.Status.Code)
+	}
+}
+
+func TestSpaceOf(t *testing.T) {
+	tests := []struct {
+		path string
+		root string
+		ok   bool
+	}{
+		{"/", "/", true},
+		{"/file.txt", "/", true},
+		{"/projects/physics", "/projects/physics", true},
+		{"/projects/physics/data/run.csv", "/projects/physics", true},
+		{"/projects/physicsx/file.txt", "/", true},
+	}
+	for _, tt := range tests {
+		root, ok := layout.SpaceOf(tt.path)
+		if root != tt.root || ok != tt.ok {
+			t.Errorf("SpaceOf(%q) = %q, %v, expected %q, %v", tt.path, root, ok, tt.root, tt.ok)
+		}
+	}
+
+	projects := Layout{Projects: []string{"/projects/physics"}}
+	if root, ok := projects.SpaceOf("/other/file.txt"); ok {
+		t.Errorf("expected no space, got %q", root)
+	}
+}
+
+func TestCheckQuota(t *testing.T) {
+	ctx := ctxpkg.ContextSetUser(context.Background(), alice)
+	fs := newFakeFS()
+	fs.resources["/projects/physics"].ArbitraryMetadata.Metadata[QuotaKey] = "100"
+	fs.add("/projects/physics/run.csv", "run", alice.Id, &provider.ResourcePermissions{Stat: true})
+	fs.resources["/projects/physics/run.csv"].Type = provider.ResourceType_RESOURCE_TYPE_FILE
+	fs.resources["/projects/physics/run.csv"].Size = 30
+
+	usage := func(used uint64) func(string) (uint64, error) {
+		return func(root string) (uint64, error) {
+			if root != "/projects/physics" {
+				t.Errorf("unexpected space root %q", root)
+			}
+			return used, nil
+		}
+	}
+
+	if err := CheckQuota(ctx, fs, layout, "/projects/physics/new.csv", 20, usage(80)); err != nil {
+		t.Errorf("expected the upload to fit, got %v", err)
+	}
+	if err := CheckQuota(ctx, fs, layout, "/projects/physics/new.csv", 21, usage(80)); !isInsufficientStorage(err) {
+		t.Errorf("expected insufficient storage, got %v", err)
+	}
+	// the uploaded file replaces the current one
+	if err := CheckQuota(ctx, fs, layout, "/projects/physics/run.csv", 50, usage(80)); err != nil {
+		t.Errorf("expected the replacement to fit, got %v", err)
+	}
+	if err := CheckQuota(ctx, fs, layout, "/projects/physics/new.csv", 0, usage(120)); !isInsufficientStorage(err) {
+		t.Errorf("expected insufficient storage when over quota, got %v", err)
+	}
+	// no quota set on the home
+	if err := CheckQuota(ctx, fs, layout, "/file.txt", 1<<40, usage(0)); err != nil {
+		t.Errorf("expected no quota on the home, got %v", err)
+	}
+}
+
+func TestUsageCache(t *testing.T) {
+	computed := 0
+	c := NewUsageCache(func(root string) (uint64, error) {
+		computed++
+		return 100, nil
+	})
+
+	// unknown spaces are left alone
+	c.Add("/projects/physics", 10)
+	for i := 0; i < 2; i++ {
+		used, err := c.Usage("/projects/physics")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if used != 100 {
+			t.Errorf("expected 100 bytes used, got %d", used)
+		}
+	}
+	if computed != 1 {
+		t.Errorf("expected the usage to be computed once, got %d", computed)
+	}
+
+	c.Add("/projects/physics", 20)
+	c.Add("/projects/physics", -5)
+	if used, _ := c.Usage("/projects/physics"); used != 115 {
+		t.Errorf("expected 115 bytes used, got %d", used)
+	}
+	c.Add("/projects/physics", -1000)
+	if used, _ := c.Usage("/projects/physics"); used != 0 {
+		t.Errorf("expected no bytes used, got %d", used)
+	}
+
+	// expired usages are computed again
+	c.spaces["/projects/physics"] = usage{bytes: 0, expires: time.Now()}
+	if used, _ := c.Usage("/projects/physics"); used != 100 || computed != 2 {
+		t.Errorf("expected the usage to be computed again, got %d bytes after %d computations", used, computed)
+	}
+}
+
+func TestCheckKeys(t *testing.T) {
+	if err := CheckKeys([]string{"user.color", "spaces"}); err != nil {
+		t.Errorf("expected the keys to be allowed, got %v", err)
+	}
+	if _, ok := CheckKeys([]string{"user.color", QuotaKey}).(errtypes.IsPermissionDenied); !ok {
+		t.Error("expected the quota key to be denied")
+	}
+	if _, ok := CheckMetadata(map[string]string{NameKey: "name"}).(errtypes.IsPermissionDenied); !ok {
+		t.Error("expected the name key to be denied")
+	}
+}
+
+func isInsufficientStorage(err error) bool {
+	_, ok := err.(errtypes.IsInsufficientStorage)
+	return ok
+}
@@ -0,0 +1,170 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package uploader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/datagateway"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rhttp"
+)
+
+// Uploader is the interface implemented by the objects that are able to
+// upload the content of a Reader into a path and create the folders around it
+type Uploader interface {
+	// Upload uploads length bytes read from src into the file at path
+	Upload(ctx context.Context, path string, length int64, src io.Reader) error
+	// CreateDir creates the folder at path, its parent has to exist
+	CreateDir(ctx context.Context, path string) error
+	// Stat returns the resource info of path, or an errtypes.NotFound if it does not exist
+	Stat(ctx context.Context, path string) (*provider.ResourceInfo, error)
+}
+
+type revaUploader struct {
+	gtw        gateway.GatewayAPIClient
+	httpClient *http.Client
+}
+
+// NewUploader creates an Uploader from the reva gateway
+func NewUploader(gtw gateway.GatewayAPIClient, options ...rhttp.Option) Uploader {
+	return &revaUploader{
+		gtw:        gtw,
+		httpClient: rhttp.GetHTTPClient(options...),
+	}
+}
+
+func getUploadProtocol(protocols []*gateway.FileUploadProtocol, prot string) (*gateway.FileUploadProtocol, error) {
+	for _, p := range protocols {
+		if p.Protocol == prot {
+			return p, nil
+		}
+	}
+	return nil, errtypes.InternalError(fmt.Sprintf("protocol %s not supported for uploading", prot))
+}
+
+func statusToError(path string, status *rpc.Status) error {
+	switch status.Code {
+	case rpc.Code_CODE_OK:
+		return nil
+	case rpc.Code_CODE_NOT_FOUND:
+		return errtypes.NotFound(path)
+	case rpc.Code_CODE_PERMISSION_DENIED:
+		return errtypes.PermissionDenied(path)
+	case rpc.Code_CODE_ALREADY_EXISTS:
+		return errtypes.AlreadyExists(path)
+	case rpc.Code_CODE_INSUFFICIENT_STORAGE:
+		return errtypes.InsufficientStorage(path)
+	default:
+		return errtypes.InternalError(status.Message)
+	}
+}
+
+// Upload uploads the content of src to the given path
+func (r *revaUploader) Upload(ctx context.Context, path string, length int64, src io.Reader) error {
+	upResp, err := r.gtw.InitiateFileUpload(ctx, &provider.InitiateFileUploadRequest{
+		Ref: &provider.Reference{
+			Path: path,
+		},
+		Opaque: &typespb.Opaque{
+			Map: map[string]*typespb.OpaqueEntry{
+				"Upload-Length": {
+					Decoder: "plain",
+					Value:   []byte(strconv.FormatInt(length, 10)),
+				},
+			},
+		},
+	})
+
+	switch {
+	case err != nil:
+		return err
+	case upResp.Status.Code != rpc.Code_CODE_OK:
+		return statusToError(path, upResp.Status)
+	}
+
+	p, err := getUploadProtocol(upResp.Protocols, "simple")
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := rhttp.NewRequest(ctx, http.MethodPut, p.UploadEndpoint, io.LimitReader(src, length))
+	if err != nil {
+		return err
+	}
+	httpReq.ContentLength = length
+	httpReq.Header.Set(datagateway.TokenTransportHeader, p.Token)
+
+	httpRes, err := r.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpRes.Body.Close()
+
+	switch {
+	case httpRes.StatusCode == http.StatusOK:
+		return nil
+	case httpRes.StatusCode == http.StatusForbidden && length == 0:
+		// some storages already finish the upload of a zero byte file when initiating it
+		return nil
+	case httpRes.StatusCode == http.StatusNotFound:
+		return errtypes.NotFound(path)
+	case httpRes.StatusCode == http.StatusInsufficientStorage:
+		return errtypes.InsufficientStorage(path)
+	default:
+		return errtypes.InternalError(httpRes.Status)
+	}
+}
+
+// CreateDir creates the folder at the given path
+func (r *revaUploader) CreateDir(ctx context.Context, path string) error {
+	res, err := r.gtw.CreateContainer(ctx, &provider.CreateContainerRequest{
+		Ref: &provider.Reference{
+			Path: path,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return statusToError(path, res.Status)
+}
+
+// Stat returns the resource info of the given path
+func (r *revaUploader) Stat(ctx context.Context, path string) (*provider.ResourceInfo, error) {
+	res, err := r.gtw.Stat(ctx, &provider.StatRequest{
+		Ref: &provider.Reference{
+			Path: path,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := statusToError(path, res.Status); err != nil {
+		return nil, err
+	}
+	return res.Info, nil
+}
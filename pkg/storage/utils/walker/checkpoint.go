@@ -0,0 +1,85 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package walker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+// Checkpoint persists the folders a walk still has to list, so that an interrupted walk can be resumed.
+// After resuming, the entries of the folders being visited when the checkpoint was saved are visited again.
+// The root identifies the walk, a checkpoint saved for a different root cannot be resumed.
+type Checkpoint interface {
+	// Load returns the folders still to be listed, ok is false if no walk was saved yet
+	Load(ctx context.Context, root string) (pending []*Pending, ok bool, err error)
+	// Save persists the folders still to be listed, an empty list marks the walk as finished
+	Save(ctx context.Context, root string, pending []*Pending) error
+}
+
+type checkpointState struct {
+	Root    string     `json:"root"`
+	Pending []*Pending `json:"pending"`
+}
+
+type fileCheckpoint struct {
+	path string
+}
+
+// NewFileCheckpoint creates a Checkpoint persisted as json in the local file at path
+func NewFileCheckpoint(path string) Checkpoint {
+	return &fileCheckpoint{path: path}
+}
+
+// Load reads the pending folders from the file, failing if they were saved for another root
+func (c *fileCheckpoint) Load(ctx context.Context, root string) ([]*Pending, bool, error) {
+	b, err := ioutil.ReadFile(c.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	state := checkpointState{}
+	if err := json.Unmarshal(b, &state); err != nil {
+		return nil, false, err
+	}
+	if state.Root != root {
+		return nil, false, errtypes.BadRequest(fmt.Sprintf("checkpoint %s was saved for a walk of %s, not %s", c.path, state.Root, root))
+	}
+	return state.Pending, true, nil
+}
+
+// Save writes the pending folders to the file, replacing it atomically
+func (c *fileCheckpoint) Save(ctx context.Context, root string, pending []*Pending) error {
+	b, err := json.Marshal(checkpointState{Root: root, Pending: pending})
+	if err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
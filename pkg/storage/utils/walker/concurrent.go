@@ -0,0 +1,292 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package walker
+
+import (
+	"context"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+const (
+	defaultWorkers            = 10
+	defaultCheckpointInterval = 10 * time.Second
+)
+
+// Options configure a ConcurrentWalker
+type Options struct {
+	// Workers is the number of folders listed at the same time, defaults to 10
+	Workers int
+	// MaxDepth limits how deep the walk goes below the root, 0 means no limit.
+	// With a MaxDepth of 1 only the root and its direct children are visited.
+	MaxDepth int
+	// Include restricts the visited resources to the ones whose name matches one of the patterns.
+	// Folders that do not match are not visited but still walked into.
+	Include []string
+	// Exclude skips the resources whose name matches one of the patterns, excluded folders are not walked into
+	Exclude []string
+	// Checkpoint persists the progress of the walk, so that it can be resumed after an interruption
+	Checkpoint Checkpoint
+	// CheckpointInterval is the minimum time between two saves of the checkpoint, defaults to 10s.
+	// A negative interval saves it after every folder. The checkpoint is always saved when the walk ends.
+	CheckpointInterval time.Duration
+}
+
+// Pending is a folder a walk still has to list
+type Pending struct {
+	Path  string                 `json:"path"`
+	Depth int                    `json:"depth"`
+	Info  *provider.ResourceInfo `json:"info"`
+}
+
+// ConcurrentWalker is a Walker that lists several folders at the same time.
+// The WalkFunc is still called by a single goroutine, and a folder is always visited before its children,
+// but the order of the children of different folders is not deterministic.
+type ConcurrentWalker struct {
+	gtw  gateway.GatewayAPIClient
+	opts Options
+}
+
+type listing struct {
+	job   *Pending
+	infos []*provider.ResourceInfo
+	err   error
+}
+
+// NewConcurrentWalker creates a ConcurrentWalker that uses the reva gateway
+func NewConcurrentWalker(gtw gateway.GatewayAPIClient, opts Options) *ConcurrentWalker {
+	if opts.Workers <= 0 {
+		opts.Workers = defaultWorkers
+	}
+	if opts.CheckpointInterval == 0 {
+		opts.CheckpointInterval = defaultCheckpointInterval
+	}
+	return &ConcurrentWalker{gtw: gtw, opts: opts}
+}
+
+// Walk walks the file tree rooted at root, calling fn for each file or folder in the tree, including the root.
+func (w *ConcurrentWalker) Walk(ctx context.Context, root string, fn WalkFunc) error {
+	return w.walk(ctx, &provider.Reference{Path: root}, root, false, fn)
+}
+
+// WalkByID walks the file tree rooted at the resource with the given id, calling fn for each file or folder
+// in the tree, including the root. Folders are listed by id, so the walk is not affected by renames
+// happening while it runs. The paths passed to fn are the ones the resources had when the walk started.
+func (w *ConcurrentWalker) WalkByID(ctx context.Context, root *provider.ResourceId, fn WalkFunc) error {
+	return w.walk(ctx, &provider.Reference{ResourceId: root}, "", true, fn)
+}
+
+func (w *ConcurrentWalker) walk(ctx context.Context, ref *provider.Reference, root string, byID bool, fn WalkFunc) error {
+	// the checkpoint is bound to the root of the walk
+	key := root
+	if byID {
+		key = ref.ResourceId.StorageId + "!" + ref.ResourceId.OpaqueId
+	}
+
+	var queue []*Pending
+	resumed := false
+	if w.opts.Checkpoint != nil {
+		pending, ok, err := w.opts.Checkpoint.Load(ctx, key)
+		if err != nil {
+			return err
+		}
+		queue, resumed = pending, ok
+	}
+
+	if !resumed {
+		info, err := stat(ctx, w.gtw, ref, root)
+		if err != nil {
+			return fn(root, nil, err)
+		}
+		if byID {
+			root = info.Path
+		}
+		if info.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+			return fn(root, info, nil)
+		}
+		queue = []*Pending{{Path: root, Info: info}}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan *Pending)
+	results := make(chan listing)
+	wg := sync.WaitGroup{}
+	for i := 0; i < w.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				ref := &provider.Reference{Path: job.Path}
+				if byID {
+					ref = &provider.Reference{ResourceId: job.Info.Id}
+				}
+				infos, err := readDir(ctx, w.gtw, ref, job.Path)
+				results <- listing{job: job, infos: infos, err: err}
+			}
+		}()
+	}
+	defer func() {
+		// stop the workers, discarding the listings still running
+		cancel()
+		close(jobs)
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
+		for range results {
+		}
+	}()
+
+	pending := make(map[string]*Pending, len(queue))
+	for _, p := range queue {
+		pending[p.Path] = p
+	}
+	lastSave := time.Now()
+
+	inflight := 0
+	for len(queue) > 0 || inflight > 0 {
+		// folders are listed depth first, which keeps the number of pending folders low
+		var send chan *Pending
+		var next *Pending
+		if len(queue) > 0 {
+			send, next = jobs, queue[len(queue)-1]
+		}
+
+		select {
+		case send <- next:
+			queue = queue[:len(queue)-1]
+			inflight++
+		case res := <-results:
+			inflight--
+			children, err := w.visit(res, byID, fn)
+			if err != nil {
+				return w.save(key, pending, err)
+			}
+			delete(pending, res.job.Path)
+			for _, c := range children {
+				pending[c.Path] = c
+			}
+			queue = append(queue, children...)
+
+			if w.opts.Checkpoint != nil && time.Since(lastSave) >= w.opts.CheckpointInterval {
+				if err := w.save(key, pending, nil); err != nil {
+					return err
+				}
+				lastSave = time.Now()
+			}
+		case <-ctx.Done():
+			return w.save(key, pending, ctx.Err())
+		}
+	}
+
+	return w.save(key, pending, nil)
+}
+
+// visit calls fn for a listed folder and its children, and returns the sub folders to list
+func (w *ConcurrentWalker) visit(res listing, byID bool, fn WalkFunc) ([]*Pending, error) {
+	job := res.job
+	if job.Depth == 0 || w.included(path.Base(job.Path)) || res.err != nil {
+		// listing errors are reported even for folders that are not included
+		if err := fn(job.Path, job.Info, res.err); err != nil {
+			if err == filepath.SkipDir {
+				return nil, nil
+			}
+			return nil, err
+		}
+	}
+	if res.err != nil {
+		return nil, nil
+	}
+
+	var folders []*Pending
+	for _, info := range res.infos {
+		name := path.Base(info.Path)
+		if w.excluded(name) {
+			continue
+		}
+
+		p := info.Path
+		if byID {
+			p = path.Join(job.Path, name)
+		}
+		isDir := info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER
+		if isDir && (w.opts.MaxDepth == 0 || job.Depth+1 < w.opts.MaxDepth) {
+			folders = append(folders, &Pending{Path: p, Depth: job.Depth + 1, Info: info})
+			continue
+		}
+
+		if !w.included(name) {
+			continue
+		}
+		if err := fn(p, info, nil); err != nil {
+			if err != filepath.SkipDir {
+				return nil, err
+			}
+			if !isDir {
+				// skip the remaining entries of the folder, including its sub folders
+				return nil, nil
+			}
+		}
+	}
+	return folders, nil
+}
+
+// save persists the pending folders if a checkpoint is configured and returns err
+func (w *ConcurrentWalker) save(root string, pending map[string]*Pending, err error) error {
+	if w.opts.Checkpoint == nil {
+		return err
+	}
+
+	list := make([]*Pending, 0, len(pending))
+	for _, p := range pending {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+
+	// the context might already be canceled when the walk was interrupted
+	if serr := w.opts.Checkpoint.Save(context.Background(), root, list); serr != nil && err == nil {
+		return serr
+	}
+	return err
+}
+
+func (w *ConcurrentWalker) included(name string) bool {
+	return len(w.opts.Include) == 0 || matchAny(w.opts.Include, name)
+}
+
+func (w *ConcurrentWalker) excluded(name string) bool {
+	return matchAny(w.opts.Exclude, name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
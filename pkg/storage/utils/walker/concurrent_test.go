@@ -0,0 +1,386 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package walker
+
+import (
+	"context"
+	"errors"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"google.golang.org/grpc"
+)
+
+// fakeGateway serves an in memory tree, where every resource is identified by the path it was created with
+type fakeGateway struct {
+	gateway.GatewayAPIClient
+
+	mu       sync.Mutex
+	parent   map[string]string
+	name     map[string]string
+	dirs     map[string]bool
+	running  int
+	maxRun   int
+	onList   func(id string)
+	listings int
+}
+
+// newFakeGateway creates a tree from a list of paths, the ones ending with a / are folders
+func newFakeGateway(paths ...string) *fakeGateway {
+	g := &fakeGateway{
+		parent: map[string]string{},
+		name:   map[string]string{},
+		dirs:   map[string]bool{"/": true},
+	}
+	for _, p := range paths {
+		id := path.Clean(p)
+		g.parent[id] = path.Dir(id)
+		g.name[id] = path.Base(id)
+		if strings.HasSuffix(p, "/") {
+			g.dirs[id] = true
+		}
+	}
+	return g
+}
+
+func (g *fakeGateway) path(id string) string {
+	if id == "/" {
+		return "/"
+	}
+	return path.Join(g.path(g.parent[id]), g.name[id])
+}
+
+func (g *fakeGateway) info(id string) *provider.ResourceInfo {
+	info := &provider.ResourceInfo{
+		Id:   &provider.ResourceId{OpaqueId: id},
+		Path: g.path(id),
+		Type: provider.ResourceType_RESOURCE_TYPE_FILE,
+	}
+	if g.dirs[id] {
+		info.Type = provider.ResourceType_RESOURCE_TYPE_CONTAINER
+	}
+	return info
+}
+
+func (g *fakeGateway) resolve(ref *provider.Reference) (string, bool) {
+	if ref.ResourceId != nil {
+		id := ref.ResourceId.OpaqueId
+		_, ok := g.name[id]
+		return id, ok || id == "/"
+	}
+	for id := range g.dirs {
+		if g.path(id) == ref.Path {
+			return id, true
+		}
+	}
+	for id := range g.name {
+		if g.path(id) == ref.Path {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+func (g *fakeGateway) rename(id, name string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.name[id] = name
+}
+
+func (g *fakeGateway) Stat(ctx context.Context, req *provider.StatRequest, opts ...grpc.CallOption) (*provider.StatResponse, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	id, ok := g.resolve(req.Ref)
+	if !ok {
+		return &provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_NOT_FOUND}}, nil
+	}
+	return &provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}, Info: g.info(id)}, nil
+}
+
+func (g *fakeGateway) ListContainer(ctx context.Context, req *provider.ListContainerRequest, opts ...grpc.CallOption) (*provider.ListContainerResponse, error) {
+	g.mu.Lock()
+	id, ok := g.resolve(req.Ref)
+	g.running++
+	g.listings++
+	if g.running > g.maxRun {
+		g.maxRun = g.running
+	}
+	g.mu.Unlock()
+
+	if g.onList != nil {
+		g.onList(id)
+	}
+	time.Sleep(time.Millisecond)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.running--
+	if !ok {
+		return &provider.ListContainerResponse{Status: &rpc.Status{Code: rpc.Code_CODE_NOT_FOUND}}, nil
+	}
+	infos := []*provider.ResourceInfo{}
+	for child, parent := range g.parent {
+		if parent == id {
+			infos = append(infos, g.info(child))
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Path < infos[j].Path })
+	return &provider.ListContainerResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}, Infos: infos}, nil
+}
+
+var tree = []string{
+	"/a/",
+	"/a/f1",
+	"/a/b/",
+	"/a/b/f2.txt",
+	"/a/b/c/",
+	"/a/b/c/f3.txt",
+	"/a/d/",
+	"/a/d/f4",
+	"/a/e/",
+}
+
+type collector struct {
+	paths []string
+}
+
+func (c *collector) walkFunc(p string, info *provider.ResourceInfo, err error) error {
+	if err != nil {
+		return err
+	}
+	c.paths = append(c.paths, p)
+	return nil
+}
+
+func (c *collector) sorted() []string {
+	sort.Strings(c.paths)
+	return c.paths
+}
+
+func TestConcurrentWalk(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		root     string
+		expected []string
+	}{
+		{
+			name:     "whole tree",
+			root:     "/a",
+			expected: []string{"/a", "/a/b", "/a/b/c", "/a/b/c/f3.txt", "/a/b/f2.txt", "/a/d", "/a/d/f4", "/a/e", "/a/f1"},
+		},
+		{
+			name:     "file as root",
+			root:     "/a/f1",
+			expected: []string{"/a/f1"},
+		},
+		{
+			name:     "max depth",
+			root:     "/a",
+			opts:     Options{MaxDepth: 1},
+			expected: []string{"/a", "/a/b", "/a/d", "/a/e", "/a/f1"},
+		},
+		{
+			name:     "include",
+			root:     "/a",
+			opts:     Options{Include: []string{"*.txt"}},
+			expected: []string{"/a", "/a/b/c/f3.txt", "/a/b/f2.txt"},
+		},
+		{
+			name:     "exclude",
+			root:     "/a",
+			opts:     Options{Exclude: []string{"b", "f4"}},
+			expected: []string{"/a", "/a/d", "/a/e", "/a/f1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &collector{}
+			w := NewConcurrentWalker(newFakeGateway(tree...), tt.opts)
+			if err := w.Walk(context.Background(), tt.root, c.walkFunc); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(c.sorted(), tt.expected) {
+				t.Fatalf("walk visited %v, expected %v", c.paths, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConcurrentWalkParentsFirst(t *testing.T) {
+	visited := map[string]bool{}
+	w := NewConcurrentWalker(newFakeGateway(tree...), Options{Workers: 4})
+	err := w.Walk(context.Background(), "/a", func(p string, info *provider.ResourceInfo, err error) error {
+		if p != "/a" && !visited[path.Dir(p)] {
+			t.Fatalf("%s visited before its parent", p)
+		}
+		visited[p] = true
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConcurrentWalkWorkers(t *testing.T) {
+	paths := []string{"/root/"}
+	for _, d := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		paths = append(paths, "/root/"+d+"/", "/root/"+d+"/sub/")
+	}
+	g := newFakeGateway(paths...)
+	c := &collector{}
+	if err := NewConcurrentWalker(g, Options{Workers: 3}).Walk(context.Background(), "/root", c.walkFunc); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.paths) != 17 {
+		t.Fatalf("walk visited %d resources, expected 17", len(c.paths))
+	}
+	if g.maxRun > 3 {
+		t.Fatalf("%d folders listed at the same time, expected at most 3", g.maxRun)
+	}
+}
+
+func TestConcurrentWalkSkipDir(t *testing.T) {
+	c := &collector{}
+	w := NewConcurrentWalker(newFakeGateway(tree...), Options{})
+	err := w.Walk(context.Background(), "/a", func(p string, info *provider.ResourceInfo, err error) error {
+		if p == "/a/b" {
+			return filepath.SkipDir
+		}
+		return c.walkFunc(p, info, err)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"/a", "/a/d", "/a/d/f4", "/a/e", "/a/f1"}
+	if !reflect.DeepEqual(c.sorted(), expected) {
+		t.Fatalf("walk visited %v, expected %v", c.paths, expected)
+	}
+}
+
+func TestConcurrentWalkSkipDirFromFile(t *testing.T) {
+	c := &collector{}
+	w := NewConcurrentWalker(newFakeGateway(tree...), Options{})
+	err := w.Walk(context.Background(), "/a", func(p string, info *provider.ResourceInfo, err error) error {
+		if p == "/a/f1" {
+			return filepath.SkipDir
+		}
+		return c.walkFunc(p, info, err)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the sub folders of /a are skipped as well
+	expected := []string{"/a"}
+	if !reflect.DeepEqual(c.sorted(), expected) {
+		t.Fatalf("walk visited %v, expected %v", c.paths, expected)
+	}
+}
+
+func TestConcurrentWalkByID(t *testing.T) {
+	g := newFakeGateway(tree...)
+	// rename a folder while its children are listed
+	g.onList = func(id string) {
+		if id == "/a/b" {
+			g.rename("/a", "renamed")
+		}
+	}
+
+	c := &collector{}
+	w := NewConcurrentWalker(g, Options{Workers: 1})
+	if err := w.WalkByID(context.Background(), &provider.ResourceId{OpaqueId: "/a"}, c.walkFunc); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"/a", "/a/b", "/a/b/c", "/a/b/c/f3.txt", "/a/b/f2.txt", "/a/d", "/a/d/f4", "/a/e", "/a/f1"}
+	if !reflect.DeepEqual(c.sorted(), expected) {
+		t.Fatalf("walk visited %v, expected %v", c.paths, expected)
+	}
+}
+
+func TestConcurrentWalkCheckpoint(t *testing.T) {
+	checkpoint := NewFileCheckpoint(path.Join(t.TempDir(), "checkpoint.json"))
+	g := newFakeGateway(tree...)
+	w := NewConcurrentWalker(g, Options{Workers: 1, Checkpoint: checkpoint, CheckpointInterval: -1})
+
+	interrupted := errors.New("interrupted")
+	visited := map[string]bool{}
+	err := w.Walk(context.Background(), "/a", func(p string, info *provider.ResourceInfo, err error) error {
+		if p == "/a/b/c" {
+			return interrupted
+		}
+		visited[p] = true
+		return err
+	})
+	if err != interrupted {
+		t.Fatalf("expected the walk to be interrupted, got %v", err)
+	}
+
+	pending, ok, err := checkpoint.Load(context.Background(), "/a")
+	if err != nil || !ok || len(pending) == 0 {
+		t.Fatalf("expected pending folders in the checkpoint, got %v %v %v", pending, ok, err)
+	}
+
+	// the checkpoint cannot resume a walk of another root
+	if err := w.Walk(context.Background(), "/a/b", (&collector{}).walkFunc); err == nil {
+		t.Fatalf("expected resuming the walk of another root to fail")
+	}
+
+	// resuming visits the rest of the tree
+	err = w.Walk(context.Background(), "/a", func(p string, info *provider.ResourceInfo, err error) error {
+		visited[p] = true
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"/a", "/a/b", "/a/b/c", "/a/b/c/f3.txt", "/a/b/f2.txt", "/a/d", "/a/d/f4", "/a/e", "/a/f1"} {
+		if !visited[p] {
+			t.Fatalf("%s was not visited", p)
+		}
+	}
+
+	// a finished walk is not repeated
+	listings := g.listings
+	if err := w.Walk(context.Background(), "/a", (&collector{}).walkFunc); err != nil {
+		t.Fatal(err)
+	}
+	if g.listings != listings {
+		t.Fatalf("finished walk listed folders again")
+	}
+}
+
+func TestConcurrentWalkerDefaults(t *testing.T) {
+	w := NewConcurrentWalker(newFakeGateway(tree...), Options{})
+	if w.opts.Workers != defaultWorkers {
+		t.Fatalf("expected %d workers, got %d", defaultWorkers, w.opts.Workers)
+	}
+	if w.opts.CheckpointInterval != defaultCheckpointInterval {
+		t.Fatalf("expected a checkpoint interval of %s, got %s", defaultCheckpointInterval, w.opts.CheckpointInterval)
+	}
+}
@@ -57,7 +57,7 @@ func NewWalker(gtw gateway.GatewayAPIClient) Walker {
 
 // Walk walks the file tree rooted at root, calling fn for each file or folder in the tree, including the root.
 func (r *revaWalker) Walk(ctx context.Context, root string, fn WalkFunc) error {
-	info, err := r.stat(ctx, root)
+	info, err := stat(ctx, r.gtw, &provider.Reference{Path: root}, root)
 
 	if err != nil {
 		return fn(root, nil, err)
@@ -78,7 +78,7 @@ func (r *revaWalker) walkRecursively(ctx context.Context, path string, info *pro
 		return fn(path, info, nil)
 	}
 
-	list, err := r.readDir(ctx, path)
+	list, err := readDir(ctx, r.gtw, &provider.Reference{Path: path}, path)
 	errFn := fn(path, info, err)
 
 	if err != nil || errFn != nil {
@@ -95,11 +95,9 @@ func (r *revaWalker) walkRecursively(ctx context.Context, path string, info *pro
 	return nil
 }
 
-func (r *revaWalker) readDir(ctx context.Context, path string) ([]*provider.ResourceInfo, error) {
-	resp, err := r.gtw.ListContainer(ctx, &provider.ListContainerRequest{
-		Ref: &provider.Reference{
-			Path: path,
-		},
+func readDir(ctx context.Context, gtw gateway.GatewayAPIClient, ref *provider.Reference, path string) ([]*provider.ResourceInfo, error) {
+	resp, err := gtw.ListContainer(ctx, &provider.ListContainerRequest{
+		Ref: ref,
 	})
 
 	switch {
@@ -114,11 +112,9 @@ func (r *revaWalker) readDir(ctx context.Context, path string) ([]*provider.Reso
 	return resp.Infos, nil
 }
 
-func (r *revaWalker) stat(ctx context.Context, path string) (*provider.ResourceInfo, error) {
-	resp, err := r.gtw.Stat(ctx, &provider.StatRequest{
-		Ref: &provider.Reference{
-			Path: path,
-		},
+func stat(ctx context.Context, gtw gateway.GatewayAPIClient, ref *provider.Reference, path string) (*provider.ResourceInfo, error) {
+	resp, err := gtw.Stat(ctx, &provider.StatRequest{
+		Ref: ref,
 	})
 
 	switch {
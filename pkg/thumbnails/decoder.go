@@ -0,0 +1,114 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package thumbnails
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Decoder turns the content of a file into an image that can be scaled down.
+type Decoder interface {
+	Decode(ctx context.Context, r io.Reader) (image.Image, error)
+}
+
+// DecoderFunc is an adapter to use an ordinary function as a Decoder.
+type DecoderFunc func(ctx context.Context, r io.Reader) (image.Image, error)
+
+// Decode calls f(ctx, r).
+func (f DecoderFunc) Decode(ctx context.Context, r io.Reader) (image.Image, error) {
+	return f(ctx, r)
+}
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[string]Decoder{
+		"image/png":  DecoderFunc(decodeWith(png.Decode)),
+		"image/jpeg": DecoderFunc(decodeWith(jpeg.Decode)),
+		"image/gif":  DecoderFunc(decodeWith(gif.Decode)),
+	}
+)
+
+// RegisterDecoder registers a decoder for the given mime type, replacing any
+// decoder registered before. It allows to plug in renderers for documents
+// like PDFs or office files.
+func RegisterDecoder(mimeType string, d Decoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[mimeType] = d
+}
+
+func getDecoder(mimeType string) (Decoder, bool) {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	d, ok := decoders[mimeType]
+	return d, ok
+}
+
+func decodeWith(decode func(io.Reader) (image.Image, error)) func(context.Context, io.Reader) (image.Image, error) {
+	return func(_ context.Context, r io.Reader) (image.Image, error) {
+		return decode(r)
+	}
+}
+
+// commandDecoder renders a file by piping its content through an external
+// command, e.g. "pdftoppm -png -singlefile -r 72 -", which has to write a
+// PNG or JPEG image to stdout.
+type commandDecoder struct {
+	args      []string
+	maxPixels int
+}
+
+func newCommandDecoder(cmd string, maxPixels int) (*commandDecoder, error) {
+	args := strings.Fields(cmd)
+	if len(args) == 0 {
+		return nil, errors.New("thumbnails: empty converter command")
+	}
+	return &commandDecoder{args: args, maxPixels: maxPixels}, nil
+}
+
+func (d *commandDecoder) Decode(ctx context.Context, r io.Reader) (image.Image, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, d.args[0], d.args[1:]...)
+	cmd.Stdin = r
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Wrapf(err, "thumbnails: error running converter %s: %s", d.args[0], strings.TrimSpace(stderr.String()))
+	}
+	out, err := checkPixels(&stdout, d.maxPixels)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(out)
+	if err != nil {
+		return nil, errors.Wrapf(err, "thumbnails: error decoding output of converter %s", d.args[0])
+	}
+	return img, nil
+}
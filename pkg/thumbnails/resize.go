@@ -0,0 +1,111 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package thumbnails
+
+import (
+	"image"
+	"image/draw"
+)
+
+// targetSize computes the dimensions of the thumbnail for a source of w x h
+// pixels. Images are never scaled up. If keepAspect is set the thumbnail fits
+// into the requested box, otherwise it is cropped to fill it.
+func targetSize(w, h, maxW, maxH int, keepAspect bool) (int, int) {
+	if maxW <= 0 {
+		maxW = w
+	}
+	if maxH <= 0 {
+		maxH = h
+	}
+	if maxW > w {
+		maxW = w
+	}
+	if maxH > h {
+		maxH = h
+	}
+	if !keepAspect {
+		return maxW, maxH
+	}
+	// scale by the smaller factor so both sides fit
+	if w*maxH > h*maxW {
+		return maxW, max(1, h*maxW/w)
+	}
+	return max(1, w*maxH/h), maxH
+}
+
+// cropRect returns the centered part of b with the aspect ratio of w x h.
+func cropRect(b image.Rectangle, w, h int) image.Rectangle {
+	bw, bh := b.Dx(), b.Dy()
+	if bw*h > bh*w {
+		cw := bh * w / h
+		x := b.Min.X + (bw-cw)/2
+		return image.Rect(x, b.Min.Y, x+cw, b.Max.Y)
+	}
+	ch := bw * h / w
+	y := b.Min.Y + (bh-ch)/2
+	return image.Rect(b.Min.X, y, b.Max.X, y+ch)
+}
+
+// scale resizes the src rectangle of img to w x h pixels by averaging the
+// source pixels covered by each destination pixel.
+func scale(img image.Image, src image.Rectangle, w, h int) *image.NRGBA {
+	in := image.NewNRGBA(image.Rect(0, 0, src.Dx(), src.Dy()))
+	draw.Draw(in, in.Bounds(), img, src.Min, draw.Src)
+
+	out := image.NewNRGBA(image.Rect(0, 0, w, h))
+	sw, sh := src.Dx(), src.Dy()
+	for y := 0; y < h; y++ {
+		y0, y1 := y*sh/h, max((y+1)*sh/h, y*sh/h+1)
+		for x := 0; x < w; x++ {
+			x0, x1 := x*sw/w, max((x+1)*sw/w, x*sw/w+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				off := in.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					p := in.Pix[off : off+4 : off+4]
+					// weight the color channels by alpha to avoid dark fringes
+					pa := uint64(p[3])
+					r += uint64(p[0]) * pa
+					g += uint64(p[1]) * pa
+					b += uint64(p[2]) * pa
+					a += pa
+					n++
+					off += 4
+				}
+			}
+
+			o := out.PixOffset(x, y)
+			if a > 0 {
+				out.Pix[o] = uint8(r / a)
+				out.Pix[o+1] = uint8(g / a)
+				out.Pix[o+2] = uint8(b / a)
+			}
+			out.Pix[o+3] = uint8(a / n)
+		}
+	}
+	return out
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,377 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package thumbnails renders scaled down previews of files.
+package thumbnails
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/ReneKroon/ttlcache/v2"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/pkg/errors"
+)
+
+// Config holds the configuration of the thumbnail manager.
+type Config struct {
+	// MaxWidth and MaxHeight limit the size of the generated thumbnails.
+	MaxWidth  int `mapstructure:"max_width" docs:"1920;The maximum width of a thumbnail."`
+	MaxHeight int `mapstructure:"max_height" docs:"1080;The maximum height of a thumbnail."`
+	// MaxInputSize is the maximum size in bytes of a file to generate a thumbnail for.
+	MaxInputSize uint64 `mapstructure:"max_input_size" docs:"52428800;The maximum size in bytes of a file to generate a thumbnail for."`
+	// MaxInputPixels is the maximum number of pixels of an image to generate a
+	// thumbnail for, it bounds the memory needed to decode it.
+	MaxInputPixels int `mapstructure:"max_input_pixels" docs:"50000000;The maximum number of pixels of an image to generate a thumbnail for."`
+	MaxConcurrency int `mapstructure:"max_concurrency" docs:"4;The maximum number of thumbnails generated concurrently."`
+	JPEGQuality    int `mapstructure:"jpeg_quality" docs:"85;The quality of generated JPEG thumbnails."`
+	CacheTTL       int `mapstructure:"cache_ttl" docs:"3600;The time in seconds thumbnails are kept in memory."`
+	CacheSize      int `mapstructure:"cache_size" docs:"1000;The maximum number of thumbnails kept in memory."`
+	// Converters maps mime types to external commands reading the file from
+	// stdin and writing a PNG or JPEG to stdout.
+	Converters map[string]string `mapstructure:"converters" docs:"nil;Commands rendering files of a mime type, e.g. application/pdf = \"pdftoppm -png -singlefile -r 72 -\"."`
+	// Dir keeps the thumbnails on disk by the checksum of the content, so
+	// they can be rendered in advance, e.g. by the decomposedfs
+	// post-processing, and shared between services.
+	Dir    string `mapstructure:"dir" docs:";A directory the thumbnails are kept in by the SHA1 checksum of the file content, shared by the services rendering and serving them. Thumbnails are only kept in memory if unset."`
+	DirTTL int    `mapstructure:"dir_ttl" docs:"604800;The time in seconds thumbnails are kept in the directory after they were last used."`
+}
+
+func (c *Config) init() {
+	if c.MaxWidth == 0 {
+		c.MaxWidth = 1920
+	}
+	if c.MaxHeight == 0 {
+		c.MaxHeight = 1080
+	}
+	if c.MaxInputSize == 0 {
+		c.MaxInputSize = 50 * 1024 * 1024
+	}
+	if c.MaxInputPixels == 0 {
+		c.MaxInputPixels = 50 * 1000 * 1000
+	}
+	if c.MaxConcurrency == 0 {
+		c.MaxConcurrency = 4
+	}
+	if c.JPEGQuality == 0 {
+		c.JPEGQuality = 85
+	}
+	if c.CacheTTL == 0 {
+		c.CacheTTL = 3600
+	}
+	if c.CacheSize == 0 {
+		c.CacheSize = 1000
+	}
+	if c.DirTTL == 0 {
+		c.DirTTL = 7 * 24 * 3600
+	}
+}
+
+// Request describes the thumbnail to generate.
+type Request struct {
+	// ResourceID and Etag identify the version of the file the thumbnail is generated for.
+	ResourceID string
+	Etag       string
+	MimeType   string
+	Size       uint64
+	Width      int
+	Height     int
+	// KeepAspect scales the image to fit into Width x Height instead of cropping it.
+	KeepAspect bool
+	// Checksum is the hex encoded SHA1 checksum of the content. Thumbnails
+	// are kept in the configured directory if it is set.
+	Checksum string
+}
+
+// Thumbnail is a rendered thumbnail.
+type Thumbnail struct {
+	MimeType string
+	Data     []byte
+}
+
+// FetchFunc opens the content of the file a thumbnail is generated for.
+type FetchFunc func(ctx context.Context) (io.ReadCloser, error)
+
+// Manager generates and caches thumbnails.
+type Manager struct {
+	c          *Config
+	cache      *ttlcache.Cache
+	converters map[string]Decoder
+	// sem bounds the number of thumbnails generated concurrently
+	sem chan struct{}
+	// done stops pruning the thumbnail directory
+	done chan struct{}
+}
+
+// New returns a new thumbnail manager.
+func New(c *Config) (*Manager, error) {
+	c.init()
+
+	m := &Manager{
+		c:          c,
+		cache:      ttlcache.NewCache(),
+		converters: map[string]Decoder{},
+		sem:        make(chan struct{}, c.MaxConcurrency),
+		done:       make(chan struct{}),
+	}
+	_ = m.cache.SetTTL(time.Duration(c.CacheTTL) * time.Second)
+	m.cache.SkipTTLExtensionOnHit(true)
+	m.cache.SetCacheSizeLimit(c.CacheSize)
+
+	for mimeType, cmd := range c.Converters {
+		d, err := newCommandDecoder(cmd, c.MaxInputPixels)
+		if err != nil {
+			return nil, errors.Wrap(err, "thumbnails: invalid converter for "+mimeType)
+		}
+		m.converters[mimeType] = d
+	}
+	if c.Dir != "" {
+		go m.pruneDir()
+	}
+	return m, nil
+}
+
+// Supports returns whether thumbnails can be generated for files of the given mime type.
+func (m *Manager) Supports(mimeType string) bool {
+	_, ok := m.getDecoder(mimeType)
+	return ok
+}
+
+func (m *Manager) getDecoder(mimeType string) (Decoder, bool) {
+	if d, ok := m.converters[mimeType]; ok {
+		return d, true
+	}
+	return getDecoder(mimeType)
+}
+
+// Get returns the thumbnail for the request, rendering it from the content
+// returned by fetch if it is not cached yet.
+func (m *Manager) Get(ctx context.Context, req *Request, fetch FetchFunc) (*Thumbnail, error) {
+	d, ok := m.getDecoder(req.MimeType)
+	if !ok {
+		return nil, errtypes.NotSupported("thumbnails: no thumbnails for mime type " + req.MimeType)
+	}
+	if req.Size > m.c.MaxInputSize {
+		return nil, errtypes.NotSupported("thumbnails: file too large")
+	}
+
+	width, height := req.Width, req.Height
+	if width <= 0 || width > m.c.MaxWidth {
+		width = m.c.MaxWidth
+	}
+	if height <= 0 || height > m.c.MaxHeight {
+		height = m.c.MaxHeight
+	}
+
+	key := fmt.Sprintf("%s:%s:%s:%dx%d:%t", req.ResourceID, req.Etag, req.Checksum, width, height, req.KeepAspect)
+	if v, err := m.cache.Get(key); err == nil {
+		return v.(*Thumbnail), nil
+	}
+	fn := m.dirPath(req, width, height)
+	if t := m.load(fn); t != nil {
+		_ = m.cache.Set(key, t)
+		return t, nil
+	}
+
+	select {
+	case m.sem <- struct{}{}:
+		defer func() { <-m.sem }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	rc, err := fetch(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "thumbnails: error fetching content")
+	}
+	defer rc.Close()
+
+	r, err := checkPixels(io.LimitReader(rc, int64(m.c.MaxInputSize)), m.c.MaxInputPixels)
+	if err != nil {
+		return nil, err
+	}
+	img, err := d.Decode(ctx, r)
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotSupported); ok {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "thumbnails: error decoding content")
+	}
+
+	b := img.Bounds()
+	w, h := targetSize(b.Dx(), b.Dy(), width, height, req.KeepAspect)
+	src := b
+	if !req.KeepAspect {
+		src = cropRect(b, w, h)
+	}
+	out := scale(img, src, w, h)
+
+	t := &Thumbnail{}
+	var buf bytes.Buffer
+	// keep jpegs as jpegs, everything else may have transparency
+	if req.MimeType == "image/jpeg" {
+		t.MimeType = "image/jpeg"
+		err = jpeg.Encode(&buf, out, &jpeg.Options{Quality: m.c.JPEGQuality})
+	} else {
+		t.MimeType = "image/png"
+		err = png.Encode(&buf, out)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "thumbnails: error encoding thumbnail")
+	}
+	t.Data = buf.Bytes()
+
+	_ = m.cache.Set(key, t)
+	if err := m.store(fn, t); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Str("file", fn).Msg("thumbnails: error storing thumbnail")
+	}
+	return t, nil
+}
+
+var sha1Checksum = regexp.MustCompile("^[0-9a-f]{40}$")
+
+// dirPath returns the file the thumbnail is kept in, or "" if it isn't kept
+// on disk. The output format only depends on the mime type of the content,
+// see Get.
+func (m *Manager) dirPath(req *Request, width, height int) string {
+	if m.c.Dir == "" || !sha1Checksum.MatchString(req.Checksum) {
+		return ""
+	}
+	ext := ".png"
+	if req.MimeType == "image/jpeg" {
+		ext = ".jpg"
+	}
+	name := fmt.Sprintf("%dx%d", width, height)
+	if req.KeepAspect {
+		name += "-a"
+	}
+	return filepath.Join(m.c.Dir, req.Checksum[:2], req.Checksum, name+ext)
+}
+
+// load reads a thumbnail kept on disk, it returns nil if there is none.
+func (m *Manager) load(fn string) *Thumbnail {
+	if fn == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil
+	}
+	// mark the thumbnail as used, see pruneDir
+	now := time.Now()
+	_ = os.Chtimes(fn, now, now)
+	t := &Thumbnail{MimeType: "image/png", Data: data}
+	if filepath.Ext(fn) == ".jpg" {
+		t.MimeType = "image/jpeg"
+	}
+	return t
+}
+
+// store writes a thumbnail to disk. The thumbnail is written to a temporary
+// file first so concurrent readers never see partial thumbnails.
+func (m *Manager) store(fn string, t *Thumbnail) error {
+	if fn == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(fn), 0700); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(fn), ".tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(t.Data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), fn)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// pruneDir periodically removes the thumbnails which were not used for
+// longer than the configured TTL.
+func (m *Manager) pruneDir() {
+	ttl := time.Duration(m.c.DirTTL) * time.Second
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		m.prune(time.Now().Add(-ttl))
+		select {
+		case <-ticker.C:
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// prune removes the thumbnails last used before t and the emptied folders.
+func (m *Manager) prune(t time.Time) {
+	var dirs []string
+	_ = filepath.Walk(m.c.Dir, func(p string, info os.FileInfo, err error) error {
+		switch {
+		case err != nil:
+			return nil
+		case info.IsDir():
+			if p != m.c.Dir {
+				dirs = append(dirs, p)
+			}
+		case info.ModTime().Before(t):
+			_ = os.Remove(p)
+		}
+		return nil
+	})
+	// remove the deepest folders first, folders which are not empty are kept
+	for i := len(dirs) - 1; i >= 0; i-- {
+		_ = os.Remove(dirs[i])
+	}
+}
+
+// checkPixels reads the header of the image in r and rejects it if it has more
+// than max pixels, before the whole image is decoded into memory. Content that
+// is not in a registered image format, e.g. documents rendered by converters,
+// is passed on unchecked. The returned reader yields the full content of r.
+func checkPixels(r io.Reader, max int) (io.Reader, error) {
+	var head bytes.Buffer
+	cfg, _, err := image.DecodeConfig(io.TeeReader(r, &head))
+	if err == nil && cfg.Width*cfg.Height > max {
+		return nil, errtypes.NotSupported(fmt.Sprintf("thumbnails: image of %dx%d pixels too large", cfg.Width, cfg.Height))
+	}
+	return io.MultiReader(&head, r), nil
+}
+
+// Close releases the resources held by the manager.
+func (m *Manager) Close() error {
+	close(m.done)
+	return m.cache.Close()
+}
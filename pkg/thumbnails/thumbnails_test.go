@@ -0,0 +1,219 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package thumbnails
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestTargetSize(t *testing.T) {
+	tests := []struct {
+		w, h, maxW, maxH int
+		keepAspect       bool
+		ew, eh           int
+	}{
+		{400, 200, 100, 100, true, 100, 50},
+		{200, 400, 100, 100, true, 50, 100},
+		{400, 200, 100, 100, false, 100, 100},
+		{50, 50, 100, 100, true, 50, 50},
+		{400, 200, 0, 100, true, 200, 100},
+	}
+	for _, tt := range tests {
+		w, h := targetSize(tt.w, tt.h, tt.maxW, tt.maxH, tt.keepAspect)
+		if w != tt.ew || h != tt.eh {
+			t.Errorf("targetSize(%d, %d, %d, %d, %t) = %dx%d, want %dx%d", tt.w, tt.h, tt.maxW, tt.maxH, tt.keepAspect, w, h, tt.ew, tt.eh)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	m, err := New(&Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	content := encodePNG(t, 64, 32)
+	fetches := 0
+	fetch := func(ctx context.Context) (io.ReadCloser, error) {
+		fetches++
+		return ioutil.NopCloser(bytes.NewReader(content)), nil
+	}
+
+	req := &Request{ResourceID: "id", Etag: "etag", MimeType: "image/png", Size: uint64(len(content)), Width: 16, Height: 16, KeepAspect: true}
+	th, err := m.Get(context.Background(), req, fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if th.MimeType != "image/png" {
+		t.Fatalf("expected image/png got %s", th.MimeType)
+	}
+	img, err := png.Decode(bytes.NewReader(th.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 16 || b.Dy() != 8 {
+		t.Fatalf("expected 16x8 got %dx%d", b.Dx(), b.Dy())
+	}
+	if r, _, _, a := img.At(4, 4).RGBA(); r != 0xffff || a != 0xffff {
+		t.Fatalf("unexpected color %v", img.At(4, 4))
+	}
+
+	// the second request is served from the cache
+	if _, err := m.Get(context.Background(), req, fetch); err != nil {
+		t.Fatal(err)
+	}
+	if fetches != 1 {
+		t.Fatalf("expected content to be fetched once, got %d", fetches)
+	}
+
+	// a new etag invalidates the cached thumbnail
+	req.Etag = "etag2"
+	if _, err := m.Get(context.Background(), req, fetch); err != nil {
+		t.Fatal(err)
+	}
+	if fetches != 2 {
+		t.Fatalf("expected content to be fetched twice, got %d", fetches)
+	}
+
+	_, err = m.Get(context.Background(), &Request{MimeType: "text/plain"}, fetch)
+	if _, ok := err.(errtypes.IsNotSupported); !ok {
+		t.Fatalf("expected not supported error, got %v", err)
+	}
+}
+
+func TestGetDir(t *testing.T) {
+	dir := t.TempDir()
+	renderer, err := New(&Config{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer renderer.Close()
+	server, err := New(&Config{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	content := encodePNG(t, 64, 32)
+	checksum := "87acec17cd9dcd20a716cc2cf67417b71c8a7016"
+	req := &Request{Checksum: checksum, MimeType: "image/png", Size: uint64(len(content)), Width: 16, Height: 16}
+	rendered, err := renderer.Get(context.Background(), req, func(ctx context.Context) (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(content)), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// another manager serves the thumbnail rendered in advance
+	req = &Request{ResourceID: "id", Etag: "etag", Checksum: checksum, MimeType: "image/png", Size: uint64(len(content)), Width: 16, Height: 16}
+	served, err := server.Get(context.Background(), req, func(ctx context.Context) (io.ReadCloser, error) {
+		t.Fatal("the content must not be fetched")
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if served.MimeType != rendered.MimeType || !bytes.Equal(served.Data, rendered.Data) {
+		t.Fatal("expected the rendered thumbnail to be served")
+	}
+
+	// unused thumbnails are pruned
+	server.prune(time.Now().Add(time.Minute))
+	if _, err := os.Stat(server.dirPath(req, 16, 16)); !os.IsNotExist(err) {
+		t.Fatalf("expected the thumbnail to be pruned, got %v", err)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Fatalf("expected the empty folders to be pruned, got %d", len(files))
+	}
+}
+
+func TestGetLimits(t *testing.T) {
+	m, err := New(&Config{MaxInputPixels: 1000, MaxConcurrency: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	content := encodePNG(t, 64, 32)
+	fetch := func(ctx context.Context) (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(content)), nil
+	}
+	req := &Request{ResourceID: "id", Etag: "etag", MimeType: "image/png", Size: uint64(len(content))}
+	_, err = m.Get(context.Background(), req, fetch)
+	if _, ok := err.(errtypes.IsNotSupported); !ok {
+		t.Fatalf("expected not supported error for too many pixels, got %v", err)
+	}
+
+	// generation waits for a free slot
+	m.sem <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := m.Get(ctx, &Request{ResourceID: "id2", MimeType: "image/png"}, fetch); err != context.DeadlineExceeded {
+		t.Fatalf("expected the generation to wait for a free slot, got %v", err)
+	}
+	<-m.sem
+}
+
+func TestRegisterDecoder(t *testing.T) {
+	m, err := New(&Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	if m.Supports("application/x-test") {
+		t.Fatal("unexpected support for application/x-test")
+	}
+	RegisterDecoder("application/x-test", DecoderFunc(func(ctx context.Context, r io.Reader) (image.Image, error) {
+		return image.NewNRGBA(image.Rect(0, 0, 10, 10)), nil
+	}))
+	if !m.Supports("application/x-test") {
+		t.Fatal("expected support for application/x-test")
+	}
+}
@@ -22,8 +22,11 @@ import (
 	"context"
 	"encoding/json"
 	"io/ioutil"
+	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/cs3org/reva/pkg/user"
 	"github.com/cs3org/reva/pkg/user/manager/registry"
@@ -32,19 +35,33 @@ import (
 
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/google/uuid"
 )
 
 func init() {
 	registry.Register("json", New)
 }
 
+// the users file is shared with the json auth manager, which holds the
+// secrets of the users, so they have to be preserved when writing it
+type entry struct {
+	*userpb.User
+	Secret string `json:"secret,omitempty"`
+}
+
 type manager struct {
-	users []*userpb.User
+	c       *config
+	mu      sync.Mutex
+	entries []*entry
+	users   []*userpb.User
+	mtime   time.Time
 }
 
 type config struct {
 	// Users holds a path to a file containing json conforming to the Users struct
 	Users string `mapstructure:"users"`
+	// Idp is the identity provider of the users created by the manager
+	Idp string `mapstructure:"idp" docs:";The identity provider of the users created through the manager."`
 }
 
 func (c *config) init() {
@@ -79,23 +96,86 @@ func (m *manager) Configure(ml map[string]interface{}) error {
 		return err
 	}
 
-	f, err := ioutil.ReadFile(c.Users)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.c = c
+	m.mtime = time.Time{}
+	return m.load()
+}
+
+// load reads the users file if it changed since it was read last. It has to
+// be called with the write lock held.
+func (m *manager) load() error {
+	fd, err := os.Open(m.c.Users)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	info, err := fd.Stat()
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(m.mtime) {
+		return nil
+	}
+
+	f, err := ioutil.ReadAll(fd)
 	if err != nil {
 		return err
 	}
 
-	users := []*userpb.User{}
+	entries := []*entry{}
 
-	err = json.Unmarshal(f, &users)
+	err = json.Unmarshal(f, &entries)
 	if err != nil {
 		return err
 	}
-	m.users = users
+	m.setEntries(entries)
+	m.mtime = info.ModTime()
 	return nil
 }
 
+func (m *manager) setEntries(entries []*entry) {
+	m.entries = entries
+	m.users = make([]*userpb.User, 0, len(entries))
+	for _, e := range entries {
+		if e.User == nil {
+			e.User = &userpb.User{}
+		}
+		m.users = append(m.users, e.User)
+	}
+}
+
+func (m *manager) save(entries []*entry) error {
+	data, err := json.MarshalIndent(entries, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "json: error encoding users")
+	}
+	tmp := m.c.Users + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return errors.Wrap(err, "json: error writing users file")
+	}
+	if err := os.Rename(tmp, m.c.Users); err != nil {
+		return errors.Wrap(err, "json: error writing users file")
+	}
+	if info, err := os.Stat(m.c.Users); err == nil {
+		m.mtime = info.ModTime()
+	}
+	m.setEntries(entries)
+	return nil
+}
+
+// list returns the current users, reloading the users file if it changed.
+func (m *manager) list() []*userpb.User {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// keep serving the users read before if the file can't be read
+	_ = m.load()
+	return m.users
+}
+
 func (m *manager) GetUser(ctx context.Context, uid *userpb.UserId) (*userpb.User, error) {
-	for _, u := range m.users {
+	for _, u := range m.list() {
 		if (u.Id.GetOpaqueId() == uid.OpaqueId || u.Username == uid.OpaqueId) && (uid.Idp == "" || uid.Idp == u.Id.GetIdp()) {
 			return u, nil
 		}
@@ -104,7 +184,7 @@ func (m *manager) GetUser(ctx context.Context, uid *userpb.UserId) (*userpb.User
 }
 
 func (m *manager) GetUserByClaim(ctx context.Context, claim, value string) (*userpb.User, error) {
-	for _, u := range m.users {
+	for _, u := range m.list() {
 		if userClaim, err := extractClaim(u, claim); err == nil && value == userClaim {
 			return u, nil
 		}
@@ -118,6 +198,8 @@ func extractClaim(u *userpb.User, claim string) (string, error) {
 		return u.Mail, nil
 	case "username":
 		return u.Username, nil
+	case "userid":
+		return u.Id.OpaqueId, nil
 	case "uid":
 		if u.UidNumber != 0 {
 			return strconv.FormatInt(u.UidNumber, 10), nil
@@ -135,7 +217,7 @@ func userContains(u *userpb.User, query string) bool {
 
 func (m *manager) FindUsers(ctx context.Context, query string) ([]*userpb.User, error) {
 	users := []*userpb.User{}
-	for _, u := range m.users {
+	for _, u := range m.list() {
 		if userContains(u, query) {
 			users = append(users, u)
 		}
@@ -150,3 +232,107 @@ func (m *manager) GetUserGroups(ctx context.Context, uid *userpb.UserId) ([]stri
 	}
 	return user.Groups, nil
 }
+
+func find(entries []*entry, uid *userpb.UserId) int {
+	for i, e := range entries {
+		if (e.Id.GetOpaqueId() == uid.OpaqueId || e.Username == uid.OpaqueId) && (uid.Idp == "" || uid.Idp == e.Id.GetIdp()) {
+			return i
+		}
+	}
+	return -1
+}
+
+// update runs f on a copy of the stored users and saves them if f succeeds.
+// The stored users are never modified in place as they are handed out to the callers.
+func (m *manager) update(f func(entries []*entry) ([]*entry, error)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.load(); err != nil {
+		return err
+	}
+	entries, err := f(append([]*entry{}, m.entries...))
+	if err != nil {
+		return err
+	}
+	return m.save(entries)
+}
+
+func (m *manager) CreateUser(ctx context.Context, u *userpb.User, password string) (*userpb.User, error) {
+	if u.Username == "" {
+		return nil, errtypes.BadRequest("json: missing username")
+	}
+	n := &userpb.User{
+		Id:          u.Id,
+		Username:    u.Username,
+		Mail:        u.Mail,
+		DisplayName: u.DisplayName,
+		Groups:      u.Groups,
+		UidNumber:   u.UidNumber,
+		GidNumber:   u.GidNumber,
+	}
+	if n.Id == nil || n.Id.OpaqueId == "" {
+		n.Id = &userpb.UserId{Idp: m.c.Idp, OpaqueId: uuid.New().String(), Type: userpb.UserType_USER_TYPE_PRIMARY}
+	}
+	err := m.update(func(entries []*entry) ([]*entry, error) {
+		for _, e := range entries {
+			if e.Username == n.Username || e.Id.GetOpaqueId() == n.Id.OpaqueId {
+				return nil, errtypes.AlreadyExists(n.Username)
+			}
+		}
+		return append(entries, &entry{User: n, Secret: password}), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (m *manager) UpdateUser(ctx context.Context, u *userpb.User) (*userpb.User, error) {
+	var n *userpb.User
+	err := m.update(func(entries []*entry) ([]*entry, error) {
+		i := find(entries, u.Id)
+		if i < 0 {
+			return nil, errtypes.NotFound(u.Id.OpaqueId)
+		}
+		old := entries[i].User
+		n = &userpb.User{
+			Id:           old.Id,
+			Username:     old.Username,
+			Mail:         u.Mail,
+			MailVerified: old.MailVerified && old.Mail == u.Mail,
+			DisplayName:  u.DisplayName,
+			Groups:       u.Groups,
+			UidNumber:    old.UidNumber,
+			GidNumber:    old.GidNumber,
+			Opaque:       old.Opaque,
+		}
+		entries[i] = &entry{User: n, Secret: entries[i].Secret}
+		return entries, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (m *manager) SetPassword(ctx context.Context, uid *userpb.UserId, password string) error {
+	return m.update(func(entries []*entry) ([]*entry, error) {
+		i := find(entries, uid)
+		if i < 0 {
+			return nil, errtypes.NotFound(uid.OpaqueId)
+		}
+		entries[i] = &entry{User: entries[i].User, Secret: password}
+		return entries, nil
+	})
+}
+
+func (m *manager) DeleteUser(ctx context.Context, uid *userpb.UserId) error {
+	return m.update(func(entries []*entry) ([]*entry, error) {
+		i := find(entries, uid)
+		if i < 0 {
+			return nil, errtypes.NotFound(uid.OpaqueId)
+		}
+		return append(entries[:i], entries[i+1:]...), nil
+	})
+}
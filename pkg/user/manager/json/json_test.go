@@ -23,6 +23,7 @@ import (
 	"io/ioutil"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
@@ -135,3 +136,60 @@ func TestUserManager(t *testing.T) {
 		t.Fatalf("user differ: expected=%v got=%v", "einstein", resUser[0].Username)
 	}
 }
+
+func TestProvisioning(t *testing.T) {
+	tempdir, err := ioutil.TempDir("", "json_test")
+	if err != nil {
+		t.Fatalf("error while create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempdir)
+
+	usersFile := tempdir + "/users.json"
+	userJSON := `[{"id":{"idp":"localhost","opaque_id":"einstein","type":1},"username":"einstein","secret":"relativity","display_name":"Albert Einstein"}]`
+	if err := ioutil.WriteFile(usersFile, []byte(userJSON), 0644); err != nil {
+		t.Fatalf("error while writing temp file: %v", err)
+	}
+
+	m, err := New(map[string]interface{}{"users": usersFile, "idp": "localhost"})
+	if err != nil {
+		t.Fatalf("error while building manager: %v", err)
+	}
+	pm := m.(*manager)
+
+	created, err := pm.CreateUser(ctx, &userpb.User{Username: "marie", DisplayName: "Marie Curie", Mail: "marie@example.org"}, "radioactivity")
+	if err != nil {
+		t.Fatalf("error while creating user: %v", err)
+	}
+	if created.Id.GetOpaqueId() == "" || created.Id.Idp != "localhost" {
+		t.Fatalf("created user has invalid id: %v", created.Id)
+	}
+	_, err = pm.CreateUser(ctx, &userpb.User{Username: "marie"}, "x")
+	if _, ok := err.(errtypes.IsAlreadyExists); !ok {
+		t.Fatalf("expected already exists error, got: %v", err)
+	}
+
+	// a fresh manager sees the new user, and the secrets of other users are kept
+	m2, err := New(map[string]interface{}{"users": usersFile})
+	if err != nil {
+		t.Fatalf("error while building manager: %v", err)
+	}
+	u, err := m2.GetUserByClaim(ctx, "username", "marie")
+	if err != nil || u.Mail != "marie@example.org" {
+		t.Fatalf("created user not found: %v %v", u, err)
+	}
+	b, err := ioutil.ReadFile(usersFile)
+	if err != nil {
+		t.Fatalf("error while reading users file: %v", err)
+	}
+	if !strings.Contains(string(b), `"relativity"`) || !strings.Contains(string(b), `"radioactivity"`) {
+		t.Fatalf("secrets not stored in users file: %s", b)
+	}
+
+	if err := pm.DeleteUser(ctx, created.Id); err != nil {
+		t.Fatalf("error while deleting user: %v", err)
+	}
+	_, err = pm.GetUserByClaim(ctx, "username", "marie")
+	if _, ok := err.(errtypes.IsNotFound); !ok {
+		t.Fatalf("expected not found error, got: %v", err)
+	}
+}
@@ -25,8 +25,10 @@ import (
 	"strconv"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/Masterminds/sprig"
+	"github.com/ReneKroon/ttlcache/v2"
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
@@ -44,8 +46,12 @@ func init() {
 
 type manager struct {
 	c           *config
+	ldap        utils.LDAPSearcher
 	userfilter  *template.Template
 	groupfilter *template.Template
+	userCache   *ttlcache.Cache
+	groupsCache *ttlcache.Cache
+	findCache   *ttlcache.Cache
 }
 
 type config struct {
@@ -58,6 +64,16 @@ type config struct {
 	Idp             string     `mapstructure:"idp"`
 	Schema          attributes `mapstructure:"schema"`
 	Nobody          int64      `mapstructure:"nobody"`
+	// GroupMemberFilter finds the groups a group is a member of, {{dn}} is replaced with the DN of the group
+	GroupMemberFilter string `mapstructure:"groupmemberfilter"`
+	// NestedGroups resolves the groups the groups of a user are members of
+	NestedGroups bool `mapstructure:"nested_groups"`
+	// CacheTTL is the number of seconds users, their groups and search results are cached, 0 disables caching
+	CacheTTL int `mapstructure:"cache_ttl"`
+	// WriteBaseDN is the DN new users are created under, users can't be provisioned if it is empty
+	WriteBaseDN string `mapstructure:"write_base_dn"`
+	// UserObjectClasses are the object classes of new users
+	UserObjectClasses []string `mapstructure:"user_object_classes"`
 }
 
 type attributes struct {
@@ -75,6 +91,12 @@ type attributes struct {
 	UIDNumber string `mapstructure:"uidNumber"`
 	// GIDNumber is a numeric id that maps to a filesystem gid, eg. 654321
 	GIDNumber string `mapstructure:"gidNumber"`
+	// MemberOf lists the DNs of the groups of a user, e.g. `memberOf`. If empty the groupfilter is used
+	MemberOf string `mapstructure:"memberOf"`
+	// Avatar holds the picture of a user, e.g. `jpegPhoto` or `thumbnailPhoto`. It is only returned by GetUserAvatar
+	Avatar string `mapstructure:"avatar"`
+	// Password holds the password of a user when provisioning users, e.g. `userPassword`
+	Password string `mapstructure:"password"`
 }
 
 // Default attributes (Active Directory)
@@ -86,6 +108,7 @@ var ldapDefaults = attributes{
 	DisplayName: "displayName",
 	UIDNumber:   "uidNumber",
 	GIDNumber:   "gidNumber",
+	Password:    "userPassword",
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -126,8 +149,31 @@ func (m *manager) Configure(ml map[string]interface{}) error {
 	if c.Nobody == 0 {
 		c.Nobody = 99
 	}
+	if c.GroupMemberFilter == "" {
+		c.GroupMemberFilter = "(member={{dn}})"
+	}
+	if len(c.UserObjectClasses) == 0 {
+		c.UserObjectClasses = []string{"top", "person", "organizationalPerson", "inetOrgPerson"}
+	}
 
 	m.c = c
+	if client, ok := m.ldap.(*utils.LDAPClient); ok {
+		client.Close()
+	}
+	m.ldap = utils.NewLDAPClient(&c.LDAPConn)
+
+	for _, cache := range []*ttlcache.Cache{m.userCache, m.groupsCache, m.findCache} {
+		if cache != nil {
+			_ = cache.Close()
+		}
+	}
+	m.userCache, m.groupsCache, m.findCache = nil, nil, nil
+	if c.CacheTTL > 0 {
+		m.userCache = newCache(c.CacheTTL)
+		m.groupsCache = newCache(c.CacheTTL)
+		m.findCache = newCache(c.CacheTTL)
+	}
+
 	m.userfilter, err = template.New("uf").Funcs(sprig.TxtFuncMap()).Parse(c.UserFilter)
 	if err != nil {
 		err := errors.Wrap(err, fmt.Sprintf("error parsing userfilter tpl:%s", c.UserFilter))
@@ -141,24 +187,31 @@ func (m *manager) Configure(ml map[string]interface{}) error {
 	return nil
 }
 
+func newCache(ttl int) *ttlcache.Cache {
+	cache := ttlcache.NewCache()
+	_ = cache.SetTTL(time.Duration(ttl) * time.Second)
+	cache.SkipTTLExtensionOnHit(true)
+	return cache
+}
+
 func (m *manager) GetUser(ctx context.Context, uid *userpb.UserId) (*userpb.User, error) {
-	log := appctx.GetLogger(ctx)
-	l, err := utils.GetLDAPConnection(&m.c.LDAPConn)
-	if err != nil {
-		return nil, err
+	key := "uid:" + uid.OpaqueId
+	if u, ok := m.getCachedUser(key); ok {
+		return u, nil
 	}
-	defer l.Close()
+
+	log := appctx.GetLogger(ctx)
 
 	// Search for the given clientID
 	searchRequest := ldap.NewSearchRequest(
 		m.c.BaseDN,
 		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
 		m.getUserFilter(uid),
-		[]string{m.c.Schema.DN, m.c.Schema.UID, m.c.Schema.CN, m.c.Schema.Mail, m.c.Schema.DisplayName, m.c.Schema.UIDNumber, m.c.Schema.GIDNumber},
+		m.getUserAttributes(),
 		nil,
 	)
 
-	sr, err := l.Search(searchRequest)
+	sr, err := m.ldap.Search(searchRequest)
 	if err != nil {
 		return nil, err
 	}
@@ -169,42 +222,42 @@ func (m *manager) GetUser(ctx context.Context, uid *userpb.UserId) (*userpb.User
 
 	log.Debug().Interface("entries", sr.Entries).Msg("entries")
 
-	id := &userpb.UserId{
-		Idp:      m.c.Idp,
-		OpaqueId: sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.UID),
-		Type:     userpb.UserType_USER_TYPE_PRIMARY,
-	}
-	groups, err := m.GetUserGroups(ctx, id)
+	u, err := m.getUserFromEntry(ctx, sr.Entries[0])
 	if err != nil {
 		return nil, err
 	}
-	gidNumber := m.c.Nobody
-	gidValue := sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.GIDNumber)
-	if gidValue != "" {
-		gidNumber, err = strconv.ParseInt(gidValue, 10, 64)
-		if err != nil {
-			return nil, err
-		}
+	m.setCachedUser(key, u)
+	return u, nil
+}
+
+// GetUserAvatar returns the picture stored in the avatar attribute of the user.
+// It is not cached as it is only needed when the avatar is rendered.
+func (m *manager) GetUserAvatar(ctx context.Context, uid *userpb.UserId) ([]byte, error) {
+	if m.c.Schema.Avatar == "" {
+		return nil, errtypes.NotFound("avatar of " + uid.OpaqueId)
 	}
-	uidNumber := m.c.Nobody
-	uidValue := sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.UIDNumber)
-	if uidValue != "" {
-		uidNumber, err = strconv.ParseInt(uidValue, 10, 64)
-		if err != nil {
-			return nil, err
-		}
+
+	searchRequest := ldap.NewSearchRequest(
+		m.c.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		m.getUserFilter(uid),
+		[]string{m.c.Schema.Avatar},
+		nil,
+	)
+
+	sr, err := m.ldap.Search(searchRequest)
+	if err != nil {
+		return nil, err
 	}
-	u := &userpb.User{
-		Id:          id,
-		Username:    sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.CN),
-		Groups:      groups,
-		Mail:        sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.Mail),
-		DisplayName: sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.DisplayName),
-		GidNumber:   gidNumber,
-		UidNumber:   uidNumber,
+	if len(sr.Entries) != 1 {
+		return nil, errtypes.NotFound(uid.OpaqueId)
 	}
 
-	return u, nil
+	photo := sr.Entries[0].GetEqualFoldRawAttributeValue(m.c.Schema.Avatar)
+	if len(photo) == 0 {
+		return nil, errtypes.NotFound("avatar of " + uid.OpaqueId)
+	}
+	return photo, nil
 }
 
 func (m *manager) GetUserByClaim(ctx context.Context, claim, value string) (*userpb.User, error) {
@@ -224,23 +277,23 @@ func (m *manager) GetUserByClaim(ctx context.Context, claim, value string) (*use
 		return nil, errors.New("ldap: invalid field " + claim)
 	}
 
-	log := appctx.GetLogger(ctx)
-	l, err := utils.GetLDAPConnection(&m.c.LDAPConn)
-	if err != nil {
-		return nil, err
+	key := "claim:" + claim + ":" + value
+	if u, ok := m.getCachedUser(key); ok {
+		return u, nil
 	}
-	defer l.Close()
+
+	log := appctx.GetLogger(ctx)
 
 	// Search for the given clientID
 	searchRequest := ldap.NewSearchRequest(
 		m.c.BaseDN,
 		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
 		m.getAttributeFilter(claim, value),
-		[]string{m.c.Schema.DN, m.c.Schema.UID, m.c.Schema.CN, m.c.Schema.Mail, m.c.Schema.DisplayName, m.c.Schema.UIDNumber, m.c.Schema.GIDNumber},
+		m.getUserAttributes(),
 		nil,
 	)
 
-	sr, err := l.Search(searchRequest)
+	sr, err := m.ldap.Search(searchRequest)
 	if err != nil {
 		return nil, err
 	}
@@ -251,62 +304,31 @@ func (m *manager) GetUserByClaim(ctx context.Context, claim, value string) (*use
 
 	log.Debug().Interface("entries", sr.Entries).Msg("entries")
 
-	id := &userpb.UserId{
-		Idp:      m.c.Idp,
-		OpaqueId: sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.UID),
-		Type:     userpb.UserType_USER_TYPE_PRIMARY,
-	}
-	groups, err := m.GetUserGroups(ctx, id)
+	u, err := m.getUserFromEntry(ctx, sr.Entries[0])
 	if err != nil {
 		return nil, err
 	}
-	gidNumber := m.c.Nobody
-	gidValue := sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.GIDNumber)
-	if gidValue != "" {
-		gidNumber, err = strconv.ParseInt(gidValue, 10, 64)
-		if err != nil {
-			return nil, err
-		}
-	}
-	uidNumber := m.c.Nobody
-	uidValue := sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.UIDNumber)
-	if uidValue != "" {
-		uidNumber, err = strconv.ParseInt(uidValue, 10, 64)
-		if err != nil {
-			return nil, err
-		}
-	}
-	u := &userpb.User{
-		Id:          id,
-		Username:    sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.CN),
-		Groups:      groups,
-		Mail:        sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.Mail),
-		DisplayName: sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.DisplayName),
-		GidNumber:   gidNumber,
-		UidNumber:   uidNumber,
-	}
-
+	m.setCachedUser(key, u)
 	return u, nil
-
 }
 
 func (m *manager) FindUsers(ctx context.Context, query string) ([]*userpb.User, error) {
-	l, err := utils.GetLDAPConnection(&m.c.LDAPConn)
-	if err != nil {
-		return nil, err
+	if m.findCache != nil {
+		if v, err := m.findCache.Get(query); err == nil {
+			return v.([]*userpb.User), nil
+		}
 	}
-	defer l.Close()
 
 	// Search for the given clientID
 	searchRequest := ldap.NewSearchRequest(
 		m.c.BaseDN,
 		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
 		m.getFindFilter(query),
-		[]string{m.c.Schema.DN, m.c.Schema.UID, m.c.Schema.CN, m.c.Schema.Mail, m.c.Schema.DisplayName, m.c.Schema.UIDNumber, m.c.Schema.GIDNumber},
+		m.getUserAttributes(),
 		nil,
 	)
 
-	sr, err := l.Search(searchRequest)
+	sr, err := m.ldap.Search(searchRequest)
 	if err != nil {
 		return nil, err
 	}
@@ -314,53 +336,64 @@ func (m *manager) FindUsers(ctx context.Context, query string) ([]*userpb.User,
 	users := []*userpb.User{}
 
 	for _, entry := range sr.Entries {
-		id := &userpb.UserId{
-			Idp:      m.c.Idp,
-			OpaqueId: entry.GetEqualFoldAttributeValue(m.c.Schema.UID),
-			Type:     userpb.UserType_USER_TYPE_PRIMARY,
-		}
-		groups, err := m.GetUserGroups(ctx, id)
+		user, err := m.getUserFromEntry(ctx, entry)
 		if err != nil {
 			return nil, err
 		}
-		gidNumber := m.c.Nobody
-		gidValue := sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.GIDNumber)
-		if gidValue != "" {
-			gidNumber, err = strconv.ParseInt(gidValue, 10, 64)
-			if err != nil {
-				return nil, err
-			}
-		}
-		uidNumber := m.c.Nobody
-		uidValue := sr.Entries[0].GetEqualFoldAttributeValue(m.c.Schema.UIDNumber)
-		if uidValue != "" {
-			uidNumber, err = strconv.ParseInt(uidValue, 10, 64)
-			if err != nil {
-				return nil, err
-			}
-		}
-		user := &userpb.User{
-			Id:          id,
-			Username:    entry.GetEqualFoldAttributeValue(m.c.Schema.CN),
-			Groups:      groups,
-			Mail:        entry.GetEqualFoldAttributeValue(m.c.Schema.Mail),
-			DisplayName: entry.GetEqualFoldAttributeValue(m.c.Schema.DisplayName),
-			GidNumber:   gidNumber,
-			UidNumber:   uidNumber,
-		}
 		users = append(users, user)
 	}
 
+	if m.findCache != nil {
+		_ = m.findCache.Set(query, users)
+	}
 	return users, nil
 }
 
 func (m *manager) GetUserGroups(ctx context.Context, uid *userpb.UserId) ([]string, error) {
-	l, err := utils.GetLDAPConnection(&m.c.LDAPConn)
+	if m.groupsCache != nil {
+		if v, err := m.groupsCache.Get(uid.OpaqueId); err == nil {
+			return v.([]string), nil
+		}
+	}
+
+	var groups []*groupEntry
+	var err error
+	if m.c.Schema.MemberOf != "" {
+		groups, err = m.getMemberOfGroups(uid)
+	} else {
+		groups, err = m.getFilteredGroups(uid)
+	}
 	if err != nil {
 		return []string{}, err
 	}
-	defer l.Close()
 
+	if m.c.NestedGroups {
+		groups, err = m.resolveNestedGroups(groups)
+		if err != nil {
+			return []string{}, err
+		}
+	}
+
+	names := make([]string, 0, len(groups))
+	for _, g := range groups {
+		// FIXME this makes the users groups use the cn, not an immutable id
+		names = append(names, g.name)
+	}
+
+	if m.groupsCache != nil {
+		_ = m.groupsCache.Set(uid.OpaqueId, names)
+	}
+	return names, nil
+}
+
+// groupEntry is a group the user is a member of.
+type groupEntry struct {
+	dn   string
+	name string
+}
+
+// getFilteredGroups looks up the groups of the user with the group filter.
+func (m *manager) getFilteredGroups(uid *userpb.UserId) ([]*groupEntry, error) {
 	// Search for the given clientID
 	searchRequest := ldap.NewSearchRequest(
 		m.c.BaseDN,
@@ -370,23 +403,154 @@ func (m *manager) GetUserGroups(ctx context.Context, uid *userpb.UserId) ([]stri
 		nil,
 	)
 
-	sr, err := l.Search(searchRequest)
+	sr, err := m.ldap.Search(searchRequest)
 	if err != nil {
-		return []string{}, err
+		return nil, err
 	}
 
-	groups := []string{}
-
+	groups := make([]*groupEntry, 0, len(sr.Entries))
 	for _, entry := range sr.Entries {
-		// FIXME this makes the users groups use the cn, not an immutable id
-		// FIXME 1. use the memberof or members attribute of a user to get the groups
-		// FIXME 2. ook up the id for each group
-		groups = append(groups, entry.GetEqualFoldAttributeValue(m.c.Schema.CN))
+		groups = append(groups, &groupEntry{dn: entry.DN, name: entry.GetEqualFoldAttributeValue(m.c.Schema.CN)})
+	}
+	return groups, nil
+}
+
+// getMemberOfGroups reads the groups of the user from its memberOf attribute.
+// The name of a group is the value of the first RDN of its DN.
+func (m *manager) getMemberOfGroups(uid *userpb.UserId) ([]*groupEntry, error) {
+	searchRequest := ldap.NewSearchRequest(
+		m.c.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		m.getUserFilter(uid),
+		[]string{m.c.Schema.MemberOf},
+		nil,
+	)
+
+	sr, err := m.ldap.Search(searchRequest)
+	if err != nil {
+		return nil, err
 	}
 
+	if len(sr.Entries) != 1 {
+		return nil, errtypes.NotFound(uid.OpaqueId)
+	}
+
+	groups := []*groupEntry{}
+	for _, dn := range sr.Entries[0].GetEqualFoldAttributeValues(m.c.Schema.MemberOf) {
+		name, err := getFirstRDNValue(dn)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, &groupEntry{dn: dn, name: name})
+	}
 	return groups, nil
 }
 
+// resolveNestedGroups adds the groups the given groups are members of, recursively.
+func (m *manager) resolveNestedGroups(groups []*groupEntry) ([]*groupEntry, error) {
+	seen := map[string]bool{}
+	for _, g := range groups {
+		seen[strings.ToLower(g.dn)] = true
+	}
+
+	for i := 0; i < len(groups); i++ {
+		if groups[i].dn == "" {
+			continue
+		}
+		searchRequest := ldap.NewSearchRequest(
+			m.c.BaseDN,
+			ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+			m.getGroupMemberFilter(groups[i].dn),
+			[]string{m.c.Schema.CN},
+			nil,
+		)
+
+		sr, err := m.ldap.Search(searchRequest)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range sr.Entries {
+			if seen[strings.ToLower(entry.DN)] {
+				continue
+			}
+			seen[strings.ToLower(entry.DN)] = true
+			groups = append(groups, &groupEntry{dn: entry.DN, name: entry.GetEqualFoldAttributeValue(m.c.Schema.CN)})
+		}
+	}
+	return groups, nil
+}
+
+func (m *manager) getUserFromEntry(ctx context.Context, entry *ldap.Entry) (*userpb.User, error) {
+	id := &userpb.UserId{
+		Idp:      m.c.Idp,
+		OpaqueId: entry.GetEqualFoldAttributeValue(m.c.Schema.UID),
+		Type:     userpb.UserType_USER_TYPE_PRIMARY,
+	}
+	groups, err := m.GetUserGroups(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	gidNumber := m.c.Nobody
+	gidValue := entry.GetEqualFoldAttributeValue(m.c.Schema.GIDNumber)
+	if gidValue != "" {
+		gidNumber, err = strconv.ParseInt(gidValue, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	uidNumber := m.c.Nobody
+	uidValue := entry.GetEqualFoldAttributeValue(m.c.Schema.UIDNumber)
+	if uidValue != "" {
+		uidNumber, err = strconv.ParseInt(uidValue, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	u := &userpb.User{
+		Id:          id,
+		Username:    entry.GetEqualFoldAttributeValue(m.c.Schema.CN),
+		Groups:      groups,
+		Mail:        entry.GetEqualFoldAttributeValue(m.c.Schema.Mail),
+		DisplayName: entry.GetEqualFoldAttributeValue(m.c.Schema.DisplayName),
+		GidNumber:   gidNumber,
+		UidNumber:   uidNumber,
+	}
+	return u, nil
+}
+
+func (m *manager) getUserAttributes() []string {
+	return []string{m.c.Schema.DN, m.c.Schema.UID, m.c.Schema.CN, m.c.Schema.Mail, m.c.Schema.DisplayName, m.c.Schema.UIDNumber, m.c.Schema.GIDNumber}
+}
+
+func (m *manager) getCachedUser(key string) (*userpb.User, bool) {
+	if m.userCache == nil {
+		return nil, false
+	}
+	v, err := m.userCache.Get(key)
+	if err != nil {
+		return nil, false
+	}
+	return v.(*userpb.User), true
+}
+
+func (m *manager) setCachedUser(key string, u *userpb.User) {
+	if m.userCache != nil {
+		_ = m.userCache.Set(key, u)
+	}
+}
+
+func getFirstRDNValue(dn string) (string, error) {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return "", errors.Wrap(err, "ldap: error parsing dn "+dn)
+	}
+	if len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) == 0 {
+		return "", errors.New("ldap: empty dn")
+	}
+	return parsed.RDNs[0].Attributes[0].Value, nil
+}
+
 func (m *manager) getUserFilter(uid *userpb.UserId) string {
 	b := bytes.Buffer{}
 	if err := m.userfilter.Execute(&b, uid); err != nil {
@@ -413,3 +577,7 @@ func (m *manager) getGroupFilter(uid *userpb.UserId) string {
 	}
 	return b.String()
 }
+
+func (m *manager) getGroupMemberFilter(dn string) string {
+	return strings.ReplaceAll(m.c.GroupMemberFilter, "{{dn}}", ldap.EscapeFilter(dn))
+}
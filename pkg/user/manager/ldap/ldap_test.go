@@ -19,7 +19,13 @@
 package ldap
 
 import (
+	"context"
+	"reflect"
 	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/go-ldap/ldap/v3"
 )
 
 func TestUserManager(t *testing.T) {
@@ -69,3 +75,264 @@ func TestUserManager(t *testing.T) {
 		t.Fatalf(err.Error())
 	}
 }
+
+// fakeLDAP answers searches by filter.
+type fakeLDAP struct {
+	results  map[string][]*ldap.Entry
+	searches int
+}
+
+func (f *fakeLDAP) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	f.searches++
+	return &ldap.SearchResult{Entries: f.results[req.Filter]}, nil
+}
+
+func TestNestedGroups(t *testing.T) {
+	mgr, err := New(map[string]interface{}{
+		"userfilter":    "(uid={{.OpaqueId}})",
+		"groupfilter":   "(memberUid={{.OpaqueId}})",
+		"nested_groups": true,
+		"cache_ttl":     60,
+		"schema": map[string]interface{}{
+			"uid": "uid",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := mgr.(*manager)
+
+	fake := &fakeLDAP{results: map[string][]*ldap.Entry{
+		"(memberUid=einstein)": {
+			ldap.NewEntry("cn=physics,ou=groups,dc=example,dc=org", map[string][]string{"cn": {"physics"}}),
+		},
+		"(member=cn=physics,ou=groups,dc=example,dc=org)": {
+			ldap.NewEntry("cn=science,ou=groups,dc=example,dc=org", map[string][]string{"cn": {"science"}}),
+		},
+		"(member=cn=science,ou=groups,dc=example,dc=org)": {
+			// cycles are ignored
+			ldap.NewEntry("cn=physics,ou=groups,dc=example,dc=org", map[string][]string{"cn": {"physics"}}),
+		},
+	}}
+	m.ldap = fake
+
+	uid := &userpb.UserId{OpaqueId: "einstein"}
+	groups, err := m.GetUserGroups(context.Background(), uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(groups, []string{"physics", "science"}) {
+		t.Fatalf("expected groups %v, got %v", []string{"physics", "science"}, groups)
+	}
+
+	searches := fake.searches
+	if _, err := m.GetUserGroups(context.Background(), uid); err != nil {
+		t.Fatal(err)
+	}
+	if fake.searches != searches {
+		t.Fatalf("expected groups to be cached, got %d searches instead of %d", fake.searches, searches)
+	}
+}
+
+func TestFindUsersCached(t *testing.T) {
+	mgr, err := New(map[string]interface{}{
+		"findfilter": "(uid={{query}}*)",
+		"cache_ttl":  60,
+		"schema": map[string]interface{}{
+			"uid": "uid",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := mgr.(*manager)
+	fake := &fakeLDAP{results: map[string][]*ldap.Entry{
+		"(uid=ein*)": {
+			ldap.NewEntry("uid=einstein,ou=users,dc=example,dc=org", map[string][]string{"uid": {"einstein"}}),
+		},
+	}}
+	m.ldap = fake
+
+	searches := 0
+	for i := 0; i < 2; i++ {
+		users, err := m.FindUsers(context.Background(), "ein")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(users) != 1 || users[0].Id.OpaqueId != "einstein" {
+			t.Fatalf("expected to find einstein, got %v", users)
+		}
+		if i == 0 {
+			searches = fake.searches
+		}
+	}
+	if fake.searches != searches {
+		t.Fatalf("expected search results to be cached, got %d searches instead of %d", fake.searches, searches)
+	}
+}
+
+func TestMemberOfGroups(t *testing.T) {
+	mgr, err := New(map[string]interface{}{
+		"userfilter": "(uid={{.OpaqueId}})",
+		"schema": map[string]interface{}{
+			"memberOf": "memberOf",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := mgr.(*manager)
+	m.ldap = &fakeLDAP{results: map[string][]*ldap.Entry{
+		"(uid=einstein)": {
+			ldap.NewEntry("uid=einstein,ou=users,dc=example,dc=org", map[string][]string{
+				"memberOf": {"cn=physics,ou=groups,dc=example,dc=org", "cn=sailing,ou=groups,dc=example,dc=org"},
+			}),
+		},
+	}}
+
+	groups, err := m.GetUserGroups(context.Background(), &userpb.UserId{OpaqueId: "einstein"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(groups, []string{"physics", "sailing"}) {
+		t.Fatalf("expected groups %v, got %v", []string{"physics", "sailing"}, groups)
+	}
+}
+
+func TestAvatar(t *testing.T) {
+	mgr, err := New(map[string]interface{}{
+		"userfilter": "(uid={{.OpaqueId}})",
+		"schema": map[string]interface{}{
+			"uid":    "uid",
+			"avatar": "jpegPhoto",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := mgr.(*manager)
+	m.ldap = &fakeLDAP{results: map[string][]*ldap.Entry{
+		"(uid=einstein)": {
+			ldap.NewEntry("uid=einstein,ou=users,dc=example,dc=org", map[string][]string{
+				"uid":       {"einstein"},
+				"jpegPhoto": {"\xff\xd8\xff\xe0"},
+			}),
+		},
+	}}
+
+	uid := &userpb.UserId{OpaqueId: "einstein"}
+	u, err := m.GetUser(context.Background(), uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Opaque != nil {
+		t.Fatalf("expected the avatar not to be part of the user, got %v", u.Opaque)
+	}
+
+	photo, err := m.GetUserAvatar(context.Background(), uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(photo) != "\xff\xd8\xff\xe0" {
+		t.Fatalf("expected the avatar of the user, got %q", photo)
+	}
+
+	_, err = m.GetUserAvatar(context.Background(), &userpb.UserId{OpaqueId: "marie"})
+	if _, ok := err.(errtypes.IsNotFound); !ok {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
+
+// fakeLDAPWriter records the changes and makes added entries searchable by cn.
+type fakeLDAPWriter struct {
+	fakeLDAP
+	added    []*ldap.AddRequest
+	modified []*ldap.ModifyRequest
+	deleted  []string
+}
+
+func (f *fakeLDAPWriter) Add(req *ldap.AddRequest) error {
+	f.added = append(f.added, req)
+	attrs := map[string][]string{}
+	for _, a := range req.Attributes {
+		attrs[a.Type] = a.Vals
+	}
+	f.results["(cn="+attrs["cn"][0]+")"] = []*ldap.Entry{ldap.NewEntry(req.DN, attrs)}
+	return nil
+}
+
+func (f *fakeLDAPWriter) Modify(req *ldap.ModifyRequest) error {
+	f.modified = append(f.modified, req)
+	return nil
+}
+
+func (f *fakeLDAPWriter) Del(req *ldap.DelRequest) error {
+	f.deleted = append(f.deleted, req.DN)
+	return nil
+}
+
+func TestProvisioning(t *testing.T) {
+	conf := map[string]interface{}{
+		"userfilter":      "(uid={{.OpaqueId}})",
+		"groupfilter":     "(memberUid={{.OpaqueId}})",
+		"attributefilter": "({{attr}}={{value}})",
+		"schema": map[string]interface{}{
+			"uid": "uid",
+		},
+	}
+	mgr, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := mgr.(*manager)
+	fake := &fakeLDAPWriter{fakeLDAP: fakeLDAP{results: map[string][]*ldap.Entry{}}}
+	m.ldap = fake
+
+	ctx := context.Background()
+	u := &userpb.User{Id: &userpb.UserId{OpaqueId: "marie"}, Username: "marie", Mail: "marie@example.org", DisplayName: "Marie Curie"}
+	if _, err := m.CreateUser(ctx, u, "radioactivity"); err == nil {
+		t.Fatal("expected an error without write_base_dn")
+	}
+
+	conf["write_base_dn"] = "ou=users,dc=example,dc=org"
+	if err := m.Configure(conf); err != nil {
+		t.Fatal(err)
+	}
+	m.ldap = fake
+
+	created, err := m.CreateUser(ctx, u, "radioactivity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Username != "marie" || created.Id.OpaqueId != "marie" || created.Mail != "marie@example.org" {
+		t.Fatalf("unexpected user %+v", created)
+	}
+	if len(fake.added) != 1 || fake.added[0].DN != "cn=marie,ou=users,dc=example,dc=org" {
+		t.Fatalf("unexpected add requests %+v", fake.added)
+	}
+	attrs := map[string][]string{}
+	for _, a := range fake.added[0].Attributes {
+		attrs[a.Type] = a.Vals
+	}
+	if attrs["sn"][0] != "Marie Curie" || attrs["userPassword"][0] != "radioactivity" || attrs["uid"][0] != "marie" {
+		t.Fatalf("unexpected attributes %v", attrs)
+	}
+
+	if _, err := m.CreateUser(ctx, u, "radioactivity"); err == nil {
+		t.Fatal("expected an error creating an existing user")
+	}
+
+	fake.results["(uid=marie)"] = fake.results["(cn=marie)"]
+	if err := m.SetPassword(ctx, u.Id, "polonium"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.modified) != 1 || fake.modified[0].Changes[0].Modification.Type != "userPassword" {
+		t.Fatalf("unexpected modify requests %+v", fake.modified)
+	}
+	if err := m.DeleteUser(ctx, u.Id); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fake.deleted, []string{"cn=marie,ou=users,dc=example,dc=org"}) {
+		t.Fatalf("unexpected delete requests %v", fake.deleted)
+	}
+}
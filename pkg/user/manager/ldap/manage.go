@@ -0,0 +1,207 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ldap
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+)
+
+// ids generated by the server can't be set when creating users
+var operationalAttributes = map[string]bool{
+	"entryuuid":             true,
+	"objectguid":            true,
+	"ms-ds-consistencyguid": true,
+	"nsuniqueid":            true,
+	"ipauniqueid":           true,
+}
+
+func (m *manager) getModifier() (utils.LDAPModifier, error) {
+	w, ok := m.ldap.(utils.LDAPModifier)
+	if !ok || m.c.WriteBaseDN == "" {
+		return nil, errtypes.NotSupported("ldap: provisioning users is not configured")
+	}
+	return w, nil
+}
+
+func (m *manager) getUserDN(uid *userpb.UserId) (string, error) {
+	searchRequest := ldap.NewSearchRequest(
+		m.c.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		m.getUserFilter(uid),
+		[]string{m.c.Schema.DN},
+		nil,
+	)
+
+	sr, err := m.ldap.Search(searchRequest)
+	if err != nil {
+		return "", err
+	}
+	if len(sr.Entries) != 1 {
+		return "", errtypes.NotFound(uid.OpaqueId)
+	}
+	return sr.Entries[0].DN, nil
+}
+
+// invalidate drops all cached users, a changed user may be cached under several keys.
+func (m *manager) invalidate() {
+	if m.userCache != nil {
+		_ = m.userCache.Purge()
+	}
+	if m.groupsCache != nil {
+		_ = m.groupsCache.Purge()
+	}
+}
+
+func (m *manager) CreateUser(ctx context.Context, u *userpb.User, password string) (*userpb.User, error) {
+	w, err := m.getModifier()
+	if err != nil {
+		return nil, err
+	}
+	if u.Username == "" {
+		return nil, errtypes.BadRequest("ldap: missing username")
+	}
+	if _, err := m.GetUserByClaim(ctx, "username", u.Username); err == nil {
+		return nil, errtypes.AlreadyExists(u.Username)
+	}
+
+	dn := m.c.Schema.CN + "=" + utils.EscapeLDAPDNValue(u.Username) + "," + m.c.WriteBaseDN
+	req := ldap.NewAddRequest(dn, nil)
+	req.Attribute("objectClass", m.c.UserObjectClasses)
+	req.Attribute(m.c.Schema.CN, []string{u.Username})
+	if m.requiresSurname() {
+		sn := u.DisplayName
+		if sn == "" {
+			sn = u.Username
+		}
+		req.Attribute("sn", []string{sn})
+	}
+	uid := u.Id.GetOpaqueId()
+	if uid != "" && !operationalAttributes[strings.ToLower(m.c.Schema.UID)] && !strings.EqualFold(m.c.Schema.UID, m.c.Schema.CN) {
+		req.Attribute(m.c.Schema.UID, []string{uid})
+	}
+	if u.Mail != "" {
+		req.Attribute(m.c.Schema.Mail, []string{u.Mail})
+	}
+	if u.DisplayName != "" {
+		req.Attribute(m.c.Schema.DisplayName, []string{u.DisplayName})
+	}
+	if u.UidNumber != 0 {
+		req.Attribute(m.c.Schema.UIDNumber, []string{strconv.FormatInt(u.UidNumber, 10)})
+	}
+	if u.GidNumber != 0 {
+		req.Attribute(m.c.Schema.GIDNumber, []string{strconv.FormatInt(u.GidNumber, 10)})
+	}
+	if password != "" {
+		req.Attribute(m.c.Schema.Password, []string{password})
+	}
+
+	if err := w.Add(req); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultEntryAlreadyExists) {
+			return nil, errtypes.AlreadyExists(u.Username)
+		}
+		return nil, errors.Wrap(err, "ldap: error creating user "+u.Username)
+	}
+	m.invalidate()
+
+	// read the user back to get the ids assigned by the server
+	return m.GetUserByClaim(ctx, "username", u.Username)
+}
+
+// requiresSurname returns whether the object classes of new users require the sn attribute.
+func (m *manager) requiresSurname() bool {
+	for _, c := range m.c.UserObjectClasses {
+		switch strings.ToLower(c) {
+		case "person", "organizationalperson", "inetorgperson":
+			return true
+		}
+	}
+	return false
+}
+
+func (m *manager) UpdateUser(ctx context.Context, u *userpb.User) (*userpb.User, error) {
+	w, err := m.getModifier()
+	if err != nil {
+		return nil, err
+	}
+	dn, err := m.getUserDN(u.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	req := ldap.NewModifyRequest(dn, nil)
+	if u.Mail != "" {
+		req.Replace(m.c.Schema.Mail, []string{u.Mail})
+	} else {
+		req.Replace(m.c.Schema.Mail, []string{})
+	}
+	if u.DisplayName != "" {
+		req.Replace(m.c.Schema.DisplayName, []string{u.DisplayName})
+	} else {
+		req.Replace(m.c.Schema.DisplayName, []string{})
+	}
+	if err := w.Modify(req); err != nil {
+		return nil, errors.Wrap(err, "ldap: error updating user "+u.Id.OpaqueId)
+	}
+	m.invalidate()
+
+	return m.GetUser(ctx, u.Id)
+}
+
+func (m *manager) SetPassword(ctx context.Context, uid *userpb.UserId, password string) error {
+	w, err := m.getModifier()
+	if err != nil {
+		return err
+	}
+	dn, err := m.getUserDN(uid)
+	if err != nil {
+		return err
+	}
+
+	req := ldap.NewModifyRequest(dn, nil)
+	req.Replace(m.c.Schema.Password, []string{password})
+	if err := w.Modify(req); err != nil {
+		return errors.Wrap(err, "ldap: error setting password of user "+uid.OpaqueId)
+	}
+	return nil
+}
+
+func (m *manager) DeleteUser(ctx context.Context, uid *userpb.UserId) error {
+	w, err := m.getModifier()
+	if err != nil {
+		return err
+	}
+	dn, err := m.getUserDN(uid)
+	if err != nil {
+		return err
+	}
+
+	if err := w.Del(ldap.NewDelRequest(dn, nil)); err != nil {
+		return errors.Wrap(err, "ldap: error deleting user "+uid.OpaqueId)
+	}
+	m.invalidate()
+	return nil
+}
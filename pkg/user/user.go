@@ -33,3 +33,31 @@ type Manager interface {
 	GetUserGroups(ctx context.Context, uid *userpb.UserId) ([]string, error)
 	FindUsers(ctx context.Context, query string) ([]*userpb.User, error)
 }
+
+// AvatarOpaqueKey is the key of the request opaque asking the user provider
+// to include the picture of the user, and of the user opaque holding it.
+const AvatarOpaqueKey = "avatar"
+
+// AvatarManager is implemented by user managers that can return the picture
+// of a user. Pictures are looked up on demand as they can be large.
+type AvatarManager interface {
+	// GetUserAvatar returns the picture of a user or a NotFound error if the
+	// user has none.
+	GetUserAvatar(ctx context.Context, uid *userpb.UserId) ([]byte, error)
+}
+
+// ManageableManager is implemented by user managers that can provision users.
+type ManageableManager interface {
+	Manager
+	// CreateUser creates a new user with the given password. A new id is
+	// generated if the user has none.
+	CreateUser(ctx context.Context, u *userpb.User, password string) (*userpb.User, error)
+	// UpdateUser changes the display name, mail and groups of the user with
+	// the id of u. Managers deriving the groups of a user from the group
+	// entries ignore the groups.
+	UpdateUser(ctx context.Context, u *userpb.User) (*userpb.User, error)
+	// SetPassword changes the password of a user.
+	SetPassword(ctx context.Context, uid *userpb.UserId, password string) error
+	// DeleteUser removes a user.
+	DeleteUser(ctx context.Context, uid *userpb.UserId) error
+}
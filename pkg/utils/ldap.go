@@ -23,6 +23,7 @@ import (
 	"crypto/x509"
 	"fmt"
 	"io/ioutil"
+	"strings"
 
 	"github.com/go-ldap/ldap/v3"
 	"github.com/pkg/errors"
@@ -37,6 +38,9 @@ type LDAPConn struct {
 	CACert       string `mapstructure:"cacert"`
 	BindUsername string `mapstructure:"bind_username"`
 	BindPassword string `mapstructure:"bind_password"`
+	PoolSize     int    `mapstructure:"pool_size"`
+	MaxConns     int    `mapstructure:"max_conns"`
+	PageSize     uint32 `mapstructure:"page_size"`
 }
 
 // GetLDAPConnection initializes an LDAPS connection and allows
@@ -68,3 +72,180 @@ func GetLDAPConnection(c *LDAPConn) (*ldap.Conn, error) {
 	}
 	return l, nil
 }
+
+// defaultLDAPPoolSize is the number of idle connections kept by an LDAPClient
+// if no pool size has been configured.
+const defaultLDAPPoolSize = 5
+
+// defaultLDAPMaxConns is the number of connections an LDAPClient opens at most
+// if no limit has been configured.
+const defaultLDAPMaxConns = 20
+
+// LDAPSearcher runs searches against an LDAP server.
+type LDAPSearcher interface {
+	Search(req *ldap.SearchRequest) (*ldap.SearchResult, error)
+}
+
+// LDAPModifier writes entries to an LDAP server.
+type LDAPModifier interface {
+	Add(req *ldap.AddRequest) error
+	Modify(req *ldap.ModifyRequest) error
+	Del(req *ldap.DelRequest) error
+}
+
+// LDAPClient keeps a pool of bound LDAP connections, replacing broken ones
+// transparently, and runs paged searches if a page size has been configured.
+// The number of open connections is capped, callers wait for a connection to
+// become available once the limit has been reached.
+type LDAPClient struct {
+	conf  *LDAPConn
+	conns chan *ldap.Conn
+	slots chan struct{}
+	dial  func(c *LDAPConn) (*ldap.Conn, error)
+}
+
+// NewLDAPClient returns an LDAPClient for the given connection parameters.
+func NewLDAPClient(c *LDAPConn) *LDAPClient {
+	size := c.PoolSize
+	if size <= 0 {
+		size = defaultLDAPPoolSize
+	}
+	max := c.MaxConns
+	if max <= 0 {
+		max = defaultLDAPMaxConns
+	}
+	if max < size {
+		max = size
+	}
+	return &LDAPClient{
+		conf:  c,
+		conns: make(chan *ldap.Conn, size),
+		slots: make(chan struct{}, max),
+		dial:  GetLDAPConnection,
+	}
+}
+
+// Search runs the search on a pooled connection. If the connection turns out
+// to be broken, the search is retried once on a new connection.
+func (c *LDAPClient) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	var sr *ldap.SearchResult
+	err := c.do(func(l *ldap.Conn) (err error) {
+		if c.conf.PageSize > 0 {
+			sr, err = l.SearchWithPaging(req, c.conf.PageSize)
+		} else {
+			sr, err = l.Search(req)
+		}
+		return err
+	})
+	return sr, err
+}
+
+// Add adds an entry.
+func (c *LDAPClient) Add(req *ldap.AddRequest) error {
+	return c.do(func(l *ldap.Conn) error { return l.Add(req) })
+}
+
+// Modify changes the attributes of an entry.
+func (c *LDAPClient) Modify(req *ldap.ModifyRequest) error {
+	return c.do(func(l *ldap.Conn) error { return l.Modify(req) })
+}
+
+// Del deletes an entry.
+func (c *LDAPClient) Del(req *ldap.DelRequest) error {
+	return c.do(func(l *ldap.Conn) error { return l.Del(req) })
+}
+
+// do runs f on a pooled connection, retrying it once on a new connection if
+// the pooled one turns out to be broken.
+func (c *LDAPClient) do(f func(l *ldap.Conn) error) error {
+	for retry := true; ; retry = false {
+		l, err := c.get()
+		if err != nil {
+			return err
+		}
+
+		err = f(l)
+		if err != nil && ldap.IsErrorWithCode(err, ldap.ErrorNetwork) {
+			c.release(l)
+			if retry {
+				continue
+			}
+			return err
+		}
+
+		c.put(l)
+		return err
+	}
+}
+
+// Close closes all the idle connections of the pool.
+func (c *LDAPClient) Close() {
+	for {
+		select {
+		case l := <-c.conns:
+			c.release(l)
+		default:
+			return
+		}
+	}
+}
+
+func (c *LDAPClient) get() (*ldap.Conn, error) {
+	for {
+		// prefer idle connections over opening new ones
+		select {
+		case l := <-c.conns:
+			if !l.IsClosing() {
+				return l, nil
+			}
+			c.release(l)
+			continue
+		default:
+		}
+
+		select {
+		case l := <-c.conns:
+			if !l.IsClosing() {
+				return l, nil
+			}
+			c.release(l)
+		case c.slots <- struct{}{}:
+			l, err := c.dial(c.conf)
+			if err != nil {
+				<-c.slots
+				return nil, err
+			}
+			return l, nil
+		}
+	}
+}
+
+func (c *LDAPClient) put(l *ldap.Conn) {
+	select {
+	case c.conns <- l:
+	default:
+		c.release(l)
+	}
+}
+
+// release closes the connection and frees its slot.
+func (c *LDAPClient) release(l *ldap.Conn) {
+	l.Close()
+	<-c.slots
+}
+
+// EscapeLDAPDNValue escapes an attribute value to be used in a DN as
+// described in RFC 4514.
+func EscapeLDAPDNValue(v string) string {
+	var b strings.Builder
+	for i, r := range v {
+		switch {
+		case strings.ContainsRune(`,+"\<>;=`, r),
+			r == '#' && i == 0,
+			r == ' ' && (i == 0 || i == len(v)-1):
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
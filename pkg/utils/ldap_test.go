@@ -0,0 +1,84 @@
+// Copyright 2018-2021 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package utils
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+func TestLDAPClientMaxConns(t *testing.T) {
+	c := NewLDAPClient(&LDAPConn{PoolSize: 1, MaxConns: 2})
+	dials := 0
+	c.dial = func(*LDAPConn) (*ldap.Conn, error) {
+		dials++
+		client, server := net.Pipe()
+		t.Cleanup(func() { server.Close() })
+		l := ldap.NewConn(client, false)
+		l.Start()
+		return l, nil
+	}
+
+	first, err := c.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(chan *ldap.Conn)
+	go func() {
+		l, _ := c.get()
+		got <- l
+	}()
+	select {
+	case <-got:
+		t.Fatal("expected the third connection to wait for a free one")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	c.put(first)
+	select {
+	case l := <-got:
+		if l != first {
+			t.Fatal("expected the returned connection to be reused")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the waiting caller to get the returned connection")
+	}
+
+	// the pool only keeps one idle connection, the second one is closed and
+	// makes room for a new one
+	c.put(second)
+	c.put(first)
+	if _, err := c.get(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.get(); err != nil {
+		t.Fatal(err)
+	}
+	if dials != 3 {
+		t.Fatalf("expected 3 dials, got %d", dials)
+	}
+}
@@ -83,6 +83,93 @@ func GetClientIP(r *http.Request) (string, error) {
 	return clientIP, nil
 }
 
+// ParseTrustedProxies parses the IP addresses and CIDR ranges of trusted proxies.
+func ParseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, p := range proxies {
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %s", p)
+			}
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %s: %w", p, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// IsTrustedProxy reports whether the given address belongs to a trusted proxy.
+func IsTrustedProxy(addr string, trusted []*net.IPNet) bool {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	ip := net.ParseIP(strings.TrimSpace(addr))
+	if ip == nil {
+		return false
+	}
+	for _, n := range trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTrustedClientIP retrieves the client IP from incoming requests. Unlike
+// GetClientIP, the X-Forwarded-For header is only considered for requests of
+// trusted proxies, and the last address in it which is no trusted proxy is
+// returned, as clients can prepend any address to the header.
+func GetTrustedClientIP(r *http.Request, trusted []*net.IPNet) (string, error) {
+	remote := r.RemoteAddr
+	if ip, _, err := net.SplitHostPort(remote); err == nil {
+		remote = ip
+	}
+	if net.ParseIP(remote) == nil {
+		return "", fmt.Errorf("invalid remote address %s", r.RemoteAddr)
+	}
+	if !IsTrustedProxy(remote, trusted) {
+		return remote, nil
+	}
+
+	var forwarded []string
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(h, ",")...)
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if ip == nil {
+			break
+		}
+		remote = ip.String()
+		if !IsTrustedProxy(remote, trusted) {
+			break
+		}
+	}
+	return remote, nil
+}
+
+// NumberedName adds a counter to the name of the file, before its extension,
+// eg. /a/file.txt becomes /a/file (2).txt for n = 2
+func NumberedName(p string, n int) string {
+	dir, name := path.Split(p)
+	ext := path.Ext(name)
+	if ext == name {
+		// hidden files like .bashrc have no extension
+		ext = ""
+	}
+	return path.Join(dir, fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(name, ext), n, ext))
+}
+
 // ToSnakeCase converts a CamelCase string to a snake_case string.
 func ToSnakeCase(str string) string {
 	snake := matchFirstCap.ReplaceAllString(str, "${1}_${2}")
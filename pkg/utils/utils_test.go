@@ -19,6 +19,7 @@
 package utils
 
 import (
+	"net/http"
 	"testing"
 
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
@@ -207,3 +208,59 @@ func TestParseStorageSpaceReference(t *testing.T) {
 		}
 	}
 }
+
+func TestGetTrustedClientIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.1", "192.168.0.0/16"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded string
+		out       string
+	}{
+		{"direct", "1.2.3.4:1234", "", "1.2.3.4"},
+		{"untrusted forwarder", "1.2.3.4:1234", "5.6.7.8", "1.2.3.4"},
+		{"trusted forwarder", "10.0.0.1:1234", "5.6.7.8", "5.6.7.8"},
+		{"spoofed entry", "10.0.0.1:1234", "9.9.9.9, 5.6.7.8", "5.6.7.8"},
+		{"proxy chain", "10.0.0.1:1234", "5.6.7.8, 192.168.1.1", "5.6.7.8"},
+		{"no header", "192.168.1.1:1234", "", "192.168.1.1"},
+	}
+	for _, tt := range tests {
+		r := &http.Request{RemoteAddr: tt.remote, Header: http.Header{}}
+		if tt.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		ip, err := GetTrustedClientIP(r, trusted)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if ip != tt.out {
+			t.Errorf("%s: got %s, expected %s", tt.name, ip, tt.out)
+		}
+	}
+
+	if _, err := ParseTrustedProxies([]string{"not-an-ip"}); err == nil {
+		t.Error("expected an error for an invalid proxy")
+	}
+}
+
+func TestNumberedName(t *testing.T) {
+	tests := []struct {
+		path     string
+		n        int
+		expected string
+	}{
+		{"/a/file.txt", 1, "/a/file (1).txt"},
+		{"/drop/report.pdf", 2, "/drop/report (2).pdf"},
+		{"/drop/archive.tar.gz", 3, "/drop/archive.tar (3).gz"},
+		{"/a/file", 1, "/a/file (1)"},
+		{"/a/.bashrc", 1, "/a/.bashrc (1)"},
+	}
+	for _, tt := range tests {
+		if got := NumberedName(tt.path, tt.n); got != tt.expected {
+			t.Errorf("NumberedName(%q, %d) = %q, expected %q", tt.path, tt.n, got, tt.expected)
+		}
+	}
+}